@@ -0,0 +1,30 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func triggerCutoff(env *environment, args []string) error {
+	fs := newFlagSet("cutoff trigger", env.stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	resp, err := env.admin.TransfersApi.TriggerCutoffProcessing(ctx)
+	if err != nil {
+		return fmt.Errorf("triggering cutoff: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("triggering cutoff: %s", resp.Status)
+	}
+	fmt.Fprintln(env.stdout, "cutoff processing finished")
+	return nil
+}
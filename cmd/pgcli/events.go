@@ -0,0 +1,75 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/moov-io/base"
+	"gocloud.dev/pubsub/kafkapubsub"
+
+	"github.com/moov-io/paygate/pkg/stream"
+)
+
+type event struct {
+	TransferID string          `json:"transferID"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func tailEvents(env *environment, args []string) error {
+	fs := newFlagSet("events tail", env.stderr)
+	brokers := fs.String("brokers", "localhost:9092", "Comma separated Kafka brokers")
+	topic := fs.String("topic", "", "Kafka topic PayGate publishes transfers to, pipeline.stream.kafka.topic")
+	group := fs.String("group", "", "Kafka consumer group, defaults to a new group so PayGate's consumers still receive every event")
+	count := fs.Int("count", 0, "Stop after this many events, zero keeps reading until interrupted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *topic == "" {
+		return errors.New("-topic is required")
+	}
+	if *group == "" {
+		*group = "pgcli-" + base.ID()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	sub, err := stream.KafkaSubscription(strings.Split(*brokers, ","), kafkapubsub.MinimalConfig(), *group, []string{*topic}, nil)
+	if err != nil {
+		return err
+	}
+	defer sub.Shutdown(context.Background())
+
+	enc := json.NewEncoder(env.stdout)
+	for received := 0; *count == 0 || received < *count; received++ {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		msg.Ack()
+
+		if err := enc.Encode(event{TransferID: msg.Metadata["transferID"], Body: msg.Body}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/moov-io/ach"
+)
+
+type fileSummary struct {
+	Filename             string         `json:"filename"`
+	ImmediateOrigin      string         `json:"immediateOrigin"`
+	ImmediateDestination string         `json:"immediateDestination"`
+	FileCreationDate     string         `json:"fileCreationDate"`
+	Batches              []batchSummary `json:"batches"`
+	EntryAddendaCount    int            `json:"entryAddendaCount"`
+	TotalDebit           int            `json:"totalDebit"`
+	TotalCredit          int            `json:"totalCredit"`
+}
+
+type batchSummary struct {
+	CompanyName            string `json:"companyName"`
+	CompanyIdentification  string `json:"companyIdentification"`
+	StandardEntryClassCode string `json:"standardEntryClassCode"`
+	EffectiveEntryDate     string `json:"effectiveEntryDate"`
+	Entries                int    `json:"entries"`
+	TotalDebit             int    `json:"totalDebit"`
+	TotalCredit            int    `json:"totalCredit"`
+}
+
+func inspectFiles(env *environment, args []string) error {
+	fs := newFlagSet("files inspect", env.stderr)
+	dir := fs.String("dir", filepath.Join("storage", "mergable"), "Directory of merged files, read when no files are given")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		matches, err := filepath.Glob(filepath.Join(*dir, "*.ach"))
+		if err != nil {
+			return err
+		}
+		paths = matches
+	}
+
+	summaries := make([]fileSummary, 0, len(paths))
+	for i := range paths {
+		file, err := ach.ReadFile(paths[i])
+		if err != nil {
+			return fmt.Errorf("reading %s: %v", paths[i], err)
+		}
+		summaries = append(summaries, summarizeFile(filepath.Base(paths[i]), file))
+	}
+	return env.print(summaries)
+}
+
+func summarizeFile(filename string, file *ach.File) fileSummary {
+	summary := fileSummary{
+		Filename:             filename,
+		ImmediateOrigin:      file.Header.ImmediateOrigin,
+		ImmediateDestination: file.Header.ImmediateDestination,
+		FileCreationDate:     file.Header.FileCreationDate,
+		Batches:              make([]batchSummary, 0, len(file.Batches)),
+		EntryAddendaCount:    file.Control.EntryAddendaCount,
+		TotalDebit:           file.Control.TotalDebitEntryDollarAmountInFile,
+		TotalCredit:          file.Control.TotalCreditEntryDollarAmountInFile,
+	}
+	for _, b := range file.Batches {
+		bh, bc := b.GetHeader(), b.GetControl()
+		summary.Batches = append(summary.Batches, batchSummary{
+			CompanyName:            bh.CompanyName,
+			CompanyIdentification:  bh.CompanyIdentification,
+			StandardEntryClassCode: bh.StandardEntryClassCode,
+			EffectiveEntryDate:     bh.EffectiveEntryDate,
+			Entries:                len(b.GetEntries()),
+			TotalDebit:             bc.TotalDebitEntryDollarAmount,
+			TotalCredit:            bc.TotalCreditEntryDollarAmount,
+		})
+	}
+	return summary
+}
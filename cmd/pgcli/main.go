@@ -0,0 +1,133 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// pgcli is a command line tool for operating PayGate. It calls the public and
+// admin HTTP servers and prints responses as JSON so they can be piped into
+// other tools.
+//
+//	pgcli -organization moov transfers list -status pending
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/moov-io/paygate"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	pg "github.com/moov-io/paygate/pkg/client/paygate"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+var (
+	flagAddress      = flag.String("address", util.Or(os.Getenv("PAYGATE_ADDRESS"), "http://localhost:8082"), "PayGate HTTP address")
+	flagAdminAddress = flag.String("admin.address", util.Or(os.Getenv("PAYGATE_ADMIN_ADDRESS"), "http://localhost:9092"), "PayGate admin HTTP address")
+	flagOrganization = flag.String("organization", os.Getenv("PAYGATE_ORGANIZATION"), "Organization to send requests as")
+	flagTimeout      = flag.Duration("timeout", 30*time.Second, "How long to wait for each command")
+)
+
+// command is a subcommand such as "transfers list". Subcommands are parsed with the standard
+// library's flag package, like the seed command of cmd/server, rather than adding a CLI framework.
+type command struct {
+	usage string
+	run   func(env *environment, args []string) error
+}
+
+var commands = map[string]command{
+	"transfers create":        {"Create a Transfer", createTransfer},
+	"transfers get":           {"Read a Transfer by its ID", getTransfer},
+	"transfers list":          {"List Transfers, reading every page", listTransfers},
+	"transfers wait":          {"Wait for a Transfer to have a status", waitForTransfer},
+	"micro-deposits initiate": {"Send micro-deposits to an account", initiateMicroDeposits},
+	"micro-deposits confirm":  {"Check amounts against an account's micro-deposits", confirmMicroDeposits},
+	"files inspect":           {"Print a summary of ACH files, such as merged files awaiting upload", inspectFiles},
+	"cutoff trigger":          {"Start cutoff processing on the admin server", triggerCutoff},
+	"events tail":             {"Print transfer events published to a Kafka topic", tailEvents},
+}
+
+// environment holds what commands need to run
+type environment struct {
+	client *pg.Client
+	admin  *admin.APIClient
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if err := run(flag.Args(), os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) < 2 {
+		usage()
+		return fmt.Errorf("missing command")
+	}
+	cmd, exists := commands[args[0]+" "+args[1]]
+	if !exists {
+		usage()
+		return fmt.Errorf("unknown command: %s %s", args[0], args[1])
+	}
+	return cmd.run(newEnvironment(stdout, stderr), args[2:])
+}
+
+func newEnvironment(stdout, stderr io.Writer) *environment {
+	conf := client.NewConfiguration()
+	conf.BasePath = *flagAddress
+	conf.UserAgent = fmt.Sprintf("pgcli/%s", paygate.Version)
+
+	adminConf := admin.NewConfiguration()
+	adminConf.BasePath = *flagAdminAddress
+	adminConf.UserAgent = conf.UserAgent
+
+	return &environment{
+		client: pg.New(client.NewAPIClient(conf), *flagOrganization),
+		admin:  admin.NewAPIClient(adminConf),
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: pgcli [flags] <command> [command flags]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-24s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// newFlagSet returns flags for a command which print the command's name in errors
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func (env *environment) print(v interface{}) error {
+	enc := json.NewEncoder(env.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// context returns a Context which is canceled after -timeout
+func (env *environment) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), *flagTimeout)
+}
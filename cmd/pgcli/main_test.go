@@ -0,0 +1,86 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestRun__unknown(t *testing.T) {
+	var stdout bytes.Buffer
+	if err := run([]string{"transfers", "bogus"}, &stdout, ioutil.Discard); err == nil {
+		t.Error("expected error")
+	}
+	if err := run(nil, &stdout, ioutil.Discard); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRun__listTransfers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transfers" || r.Header.Get("X-Organization") != "moov" {
+			t.Errorf("unexpected request: %s organization=%q", r.URL.Path, r.Header.Get("X-Organization"))
+		}
+		if r.URL.Query().Get("status") != "pending" {
+			t.Errorf("unexpected status: %q", r.URL.Query().Get("status"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]client.Transfer{{TransferID: "xfer"}})
+	}))
+	defer server.Close()
+
+	address, organization := *flagAddress, *flagOrganization
+	*flagAddress, *flagOrganization = server.URL, "moov"
+	defer func() { *flagAddress, *flagOrganization = address, organization }()
+
+	var stdout bytes.Buffer
+	if err := run([]string{"transfers", "list", "-status", "pending"}, &stdout, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var xfers []client.Transfer
+	if err := json.NewDecoder(&stdout).Decode(&xfers); err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != 1 || xfers[0].TransferID != "xfer" {
+		t.Errorf("unexpected transfers: %#v", xfers)
+	}
+}
+
+func TestRun__inspectFiles(t *testing.T) {
+	var stdout bytes.Buffer
+	path := filepath.Join("..", "..", "testdata", "ppd-debit.ach")
+	if err := run([]string{"files", "inspect", path}, &stdout, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	var summaries []fileSummary
+	if err := json.NewDecoder(&stdout).Decode(&summaries); err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 || summaries[0].Filename != "ppd-debit.ach" {
+		t.Fatalf("unexpected summaries: %#v", summaries)
+	}
+	if len(summaries[0].Batches) == 0 || summaries[0].TotalDebit == 0 {
+		t.Errorf("unexpected summary: %#v", summaries[0])
+	}
+
+	// read every file from a directory
+	stdout.Reset()
+	if err := run([]string{"files", "inspect", "-dir", t.TempDir()}, &stdout, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if v := strings.TrimSpace(stdout.String()); v != "[]" {
+		t.Errorf("unexpected output: %q", v)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func initiateMicroDeposits(env *environment, args []string) error {
+	fs := newFlagSet("micro-deposits initiate", env.stderr)
+	customerID := fs.String("customer", "", "customerID which owns the account")
+	accountID := fs.String("account", "", "accountID to send micro-deposits to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *customerID == "" || *accountID == "" {
+		return errors.New("-customer and -account are required")
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	micro, err := env.client.InitiateMicroDeposits(ctx, client.Destination{
+		CustomerID: *customerID,
+		AccountID:  *accountID,
+	})
+	if err != nil {
+		return err
+	}
+	return env.print(micro)
+}
+
+func confirmMicroDeposits(env *environment, args []string) error {
+	fs := newFlagSet("micro-deposits confirm", env.stderr)
+	accountID := fs.String("account", "", "accountID which received micro-deposits")
+	currency := fs.String("currency", "USD", "ISO 4217 currency code of the amounts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *accountID == "" || fs.NArg() == 0 {
+		return errors.New("usage: micro-deposits confirm -account <accountID> <amount>...")
+	}
+
+	var amounts []client.Amount
+	for _, arg := range fs.Args() {
+		value, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid amount %q: %v", arg, err)
+		}
+		amounts = append(amounts, client.Amount{
+			Currency: *currency,
+			Value:    int32(value),
+		})
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	if err := env.client.ConfirmMicroDeposits(ctx, *accountID, amounts); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "micro-deposits confirmed for accountID=%s\n", *accountID)
+	return nil
+}
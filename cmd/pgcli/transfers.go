@@ -0,0 +1,146 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	pg "github.com/moov-io/paygate/pkg/client/paygate"
+)
+
+func createTransfer(env *environment, args []string) error {
+	fs := newFlagSet("transfers create", env.stderr)
+	currency := fs.String("currency", "USD", "ISO 4217 currency code")
+	value := fs.Int("amount", 0, "Amount in the smallest unit of currency, 1204 is $12.04")
+	sourceCustomer := fs.String("source.customer", "", "customerID of the Source")
+	sourceAccount := fs.String("source.account", "", "accountID of the Source")
+	destCustomer := fs.String("destination.customer", "", "customerID of the Destination")
+	destAccount := fs.String("destination.account", "", "accountID of the Destination")
+	description := fs.String("description", "", "Description shown on the receiver's statement")
+	sameDay := fs.Bool("same-day", false, "Process the Transfer the same day if possible")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *value <= 0 {
+		return errors.New("-amount is required")
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	xfer, err := env.client.CreateTransfer(ctx, client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: *currency,
+			Value:    int32(*value),
+		},
+		Source: client.Source{
+			CustomerID: *sourceCustomer,
+			AccountID:  *sourceAccount,
+		},
+		Destination: client.Destination{
+			CustomerID: *destCustomer,
+			AccountID:  *destAccount,
+		},
+		Description: *description,
+		SameDay:     *sameDay,
+	})
+	if err != nil {
+		return err
+	}
+	return env.print(xfer)
+}
+
+func getTransfer(env *environment, args []string) error {
+	fs := newFlagSet("transfers get", env.stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: transfers get <transferID>")
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	xfer, err := env.client.GetTransfer(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return env.print(xfer)
+}
+
+func listTransfers(env *environment, args []string) error {
+	fs := newFlagSet("transfers list", env.stderr)
+	status := fs.String("status", "", "Only list Transfers with this status")
+	startDate := fs.String("start", "", "Only list Transfers created on or after this date (YYYY-MM-DD)")
+	endDate := fs.String("end", "", "Only list Transfers created before this date (YYYY-MM-DD)")
+	customerIDs := fs.String("customers", "", "Comma separated customerIDs to list Transfers for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := pg.TransferFilter{
+		Status: client.TransferStatus(*status),
+	}
+	if *customerIDs != "" {
+		filter.CustomerIDs = strings.Split(*customerIDs, ",")
+	}
+	var err error
+	if filter.StartDate, err = parseDate(*startDate); err != nil {
+		return fmt.Errorf("-start: %v", err)
+	}
+	if filter.EndDate, err = parseDate(*endDate); err != nil {
+		return fmt.Errorf("-end: %v", err)
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	xfers, err := env.client.ListTransfers(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if xfers == nil {
+		xfers = []client.Transfer{}
+	}
+	return env.print(xfers)
+}
+
+func waitForTransfer(env *environment, args []string) error {
+	fs := newFlagSet("transfers wait", env.stderr)
+	status := fs.String("status", string(client.PROCESSED), "Status to wait for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: transfers wait [-status processed] <transferID>")
+	}
+
+	ctx, cancel := env.context()
+	defer cancel()
+
+	xfer, err := env.client.WaitForTransferStatus(ctx, fs.Arg(0), client.TransferStatus(*status))
+	if err != nil {
+		return err
+	}
+	return env.print(xfer)
+}
+
+func parseDate(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Parse(base.ISO8601Format, v)
+	}
+	return t, nil
+}
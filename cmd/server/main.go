@@ -7,6 +7,8 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -21,17 +23,27 @@ import (
 	"github.com/moov-io/base/admin"
 	"github.com/moov-io/base/log"
 	"github.com/moov-io/paygate"
+	accountsservice "github.com/moov-io/paygate/pkg/accounts"
 	"github.com/moov-io/paygate/pkg/config"
 	configadmin "github.com/moov-io/paygate/pkg/config/admin"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/customers/blocks"
+	"github.com/moov-io/paygate/pkg/customers/ofac"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/health"
+	"github.com/moov-io/paygate/pkg/impersonation"
+	"github.com/moov-io/paygate/pkg/jobs"
 	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/reports"
+	"github.com/moov-io/paygate/pkg/seed"
+	"github.com/moov-io/paygate/pkg/tracenumbers"
 	"github.com/moov-io/paygate/pkg/transfers"
 	transferadmin "github.com/moov-io/paygate/pkg/transfers/admin"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/inbound"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/received"
 	"github.com/moov-io/paygate/pkg/upload"
 	"github.com/moov-io/paygate/pkg/util"
 	"github.com/moov-io/paygate/pkg/validation/microdeposits"
@@ -73,6 +85,23 @@ func main() {
 		}
 	}()
 
+	// Write sample data and exit when ran as 'paygate seed'
+	if flag.Arg(0) == "seed" {
+		if err := runSeed(cfg, db); err != nil {
+			cfg.Logger.LogErrorf("seed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Send list and report queries to a read replica
+	replica, err := database.NewReplica(cfg.Logger, cfg.Database)
+	if err != nil {
+		panic(fmt.Sprintf("error creating read replica: %v", err))
+	}
+	defer replica.Close()
+	go replica.Start(ctx)
+
 	// Listen for application termination.
 	errs := make(chan error)
 	go func() {
@@ -92,8 +121,12 @@ func main() {
 		}
 	}()
 
+	orgRepo := organization.NewRepo(db)
+
 	// Create HTTP handler
-	handler := mux.NewRouter(192.168.0.10)
+	handler := mux.NewRouter()
+	handler.Use(route.Localize(route.DefaultCatalog, organization.MessageOverrides(cfg, orgRepo)))
+	handler.Use(route.RateLimit(cfg), route.MaxBodySize(cfg), route.RequestSigning(cfg, database.NewSignatureStore(db)))
 	route.PingRoute(cfg.Logger, handler)
 
 	defer adminServer.Shutdown()
@@ -101,8 +134,23 @@ func main() {
 	// Register admin route for config marshaling
 	configadmin.RegisterRoutes(adminServer, cfg)
 
+	// Track each background loop and allow operators to trigger them
+	jobRegistry := jobs.NewRegistry()
+	jobRegistry.RegisterRoutes(adminServer)
+
+	// Report the row counts and sizes of tables which grow with each Transfer
+	dbStats := database.NewStatsCollector(cfg.Logger, db, cfg.Database)
+	dbStats.RegisterRoutes(adminServer)
+	dbStats.UseJobs(jobRegistry)
+	go dbStats.Start(ctx)
+
+	// Allocate trace numbers from a sequence shared by every instance
+	traceNumbers := tracenumbers.NewRepo(db)
+	tracenumbers.NewChecker(cfg).RegisterRoutes(adminServer)
+
 	// Find our fundflow strategy
-	fundflowStrategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI)
+	fundflowStrategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI, traceNumbers)
+	fundflowStrategy = fundflow.NewAgreementStrategy(fundflowStrategy, cfg.ODFI)
 
 	// Setup our transfer publisher
 	transferPublisher, err := pipeline.NewPublisher(cfg.Pipeline)
@@ -111,13 +159,35 @@ func main() {
 	}
 	defer transferPublisher.Shutdown(ctx)
 
+	// Publish messages saved alongside changes to Transfers
+	outboxCtx, stopOutbox := context.WithCancel(ctx)
+	defer stopOutbox()
+	outbox := pipeline.NewOutbox(cfg.Logger, db, transferPublisher)
+	outbox.RegisterRoutes(adminServer)
+	outbox.UseJobs(jobRegistry)
+	go outbox.Start(outboxCtx)
+
+	// Archive published messages after their retention
+	outboxArchiver, err := pipeline.NewOutboxArchiver(cfg.Logger, db, cfg.Pipeline.Outbox)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR setting up outbox archiver: %v", err))
+	}
+	outboxArchiver.UseJobs(jobRegistry)
+	go outboxArchiver.Start(outboxCtx)
+
 	transferSubscription, err := pipeline.NewSubscription(cfg)
 	if err != nil {
 		panic(fmt.Sprintf("ERROR setting up transfer subscription: %v", err))
 	}
 	defer transferSubscription.Shutdown(ctx)
 
-	agent, err := upload.New(cfg.Logger, cfg.ODFI)
+	uploadCredentials, err := upload.NewCredentialStore(db, cfg.ODFI)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR reading upload credentials: %v", err))
+	}
+	uploadCredentials.RegisterRoutes(cfg.Logger, adminServer)
+
+	agent, err := upload.NewWithCredentials(cfg.Logger, cfg.ODFI, uploadCredentials)
 	if err != nil {
 		// We don't want to crash the system on this failure. It's an important
 		// connection, but not strictly required as the issue may be resolved
@@ -127,11 +197,22 @@ func main() {
 	defer agent.Close()
 	adminServer.AddLivenessCheck(upload.Type(cfg.ODFI), agent.Ping)
 
-	merger, err := pipeline.NewMerging(cfg.Logger, cfg.Pipeline)
+	pipelineRepo := pipeline.NewRepo(db, cfg.ODFI.Cutoffs)
+	merger, err := pipeline.NewMerging(cfg.Logger, cfg.Pipeline, cfg.ODFI, traceNumbers, pipelineRepo)
 	if err != nil {
 		panic(fmt.Sprintf("ERROR setting up xfer merging: %v", err))
 	}
 
+	// Report work which hasn't made progress since before we started
+	recovery := pipeline.NewRecovery(cfg, pipelineRepo)
+	recovery.RegisterRoutes(adminServer)
+	if work, err := recovery.Scan(); err != nil {
+		cfg.Logger.LogErrorf("ERROR scanning for stuck work: %v", err)
+	} else {
+		cfg.Logger.Logf("found %d planned, %d written and %d unmerged stuck transfers with %d stuck micro-deposits",
+			len(work.Planned), len(work.Written), len(work.Unmerged), len(work.MicroDeposits))
+	}
+
 	cutoffs, err := schedule.ForCutoffTimes(cfg.ODFI.Cutoffs.Timezone, cfg.ODFI.Cutoffs.Windows)
 	if err != nil {
 		panic(fmt.Sprintf("ERROR setting up cutoff times: %v", err))
@@ -139,25 +220,73 @@ func main() {
 		cfg.Logger.Logf("registered %s cutoffs=%v", cfg.ODFI.Cutoffs.Timezone, strings.Join(cfg.ODFI.Cutoffs.Windows, ","))
 	}
 
-	pipelineRepo := pipeline.NewRepo(db)
 	xferAgg, err := pipeline.NewAggregator(cfg, agent, pipelineRepo, merger, transferSubscription, nil)
 	if err != nil {
 		panic(fmt.Sprintf("ERROR creating transfer aggregator: %v", err))
 	}
 	defer xferAgg.Shutdown()
+	xferAgg.UseShards(pipeline.NewShards(cfg.Logger, cfg.Pipeline.Sharding, db))
+
+	fileRetention, err := pipeline.NewFileRetention(cfg, pipelineRepo, agent)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR setting up file retention: %v", err))
+	}
+	xferAgg.UseFileRetention(fileRetention)
+	fileRetention.UseJobs(jobRegistry)
+	go fileRetention.Start(ctx)
+	fileRetention.RegisterRoutes(adminServer)
+
+	// Reports
+	reportsRepo := reports.NewRepo(db)
+	reportsRepo.UseReadReplica(replica)
+	dailyReporter, err := reports.NewDailyReporter(cfg, reportsRepo)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating daily reporter: %v", err))
+	}
+	xferAgg.OnCompletedCutoff(dailyReporter.HandleCutoff)
+	dailyReporter.RegisterRoutes(adminServer)
+
+	// Transfers
+	transfersRepo := transfers.NewRepo(db)
+	transfersRepo.UseReadReplica(replica)
+	defer transfersRepo.Close()
+	xferAgg.OnCompletedCutoff(transfers.NewEntryRecorder(cfg.Logger, transfersRepo).HandleCutoff)
+
+	// Received Transfers
+	var fieldEncryption *config.FieldEncryption
+	if cfg.RDFI != nil {
+		fieldEncryption = cfg.RDFI.Encryption
+	}
+	receivedRepo, err := received.NewEncryptedRepo(db, fieldEncryption)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR setting up received transfers encryption: %v", err))
+	}
+	receivedRepo.UseReadReplica(replica)
+	xferAgg.OnCompletedCutoff(received.NewReturnTracker(cfg, receivedRepo).HandleCutoff)
+
+	xferAgg.UseJobs(jobRegistry)
 	go xferAgg.Start(ctx, cutoffs)
 	xferAgg.RegisterRoutes(adminServer)
 
 	// Customers
-	customersClient := customers.NewClient(cfg.Logger, cfg.Customers, customers.HttpClient)
-	adminServer.AddLivenessCheck("customers", customersClient.Ping)
+	customersClient := customers.NewCachedClient(customers.NewClient(cfg.Logger, cfg.Customers, customers.HttpClient), cfg.Customers.Cache)
+	warmDependency(ctx, cfg, adminServer, "customers", customersClient.Ping)
+
+	ofacRepo := ofac.NewRepo(db)
+	customersClient = ofac.NewRecordingClient(customersClient, ofacRepo)
+	ofac.RegisterAdminRoutes(cfg, adminServer, ofacRepo)
+
+	blocksRepo := blocks.NewRepo(db)
+	fundflowStrategy = blocks.NewStrategy(fundflowStrategy, blocksRepo)
+	blocks.RegisterAdminRoutes(cfg, adminServer, blocksRepo)
 
 	// Setup
-	registerMicroDepositHealth(cfg, customersClient, adminServer)
+	registerMicroDepositHealth(ctx, cfg, customersClient, adminServer)
 
 	// Organization
-	orgRepo := organization.NewRepo(db)
 	organization.NewRouter(orgRepo).RegisterRoutes(handler)
+	organization.NewCalendarRouter(cfg).RegisterRoutes(handler)
+	handler.Use(organization.NetworkPolicy(cfg, orgRepo))
 
 	// Accounts
 	accountDecryptor, err := accounts.NewDecryptor(cfg.Customers.Accounts.Decryptor, customersClient)
@@ -166,14 +295,45 @@ func main() {
 	}
 
 	// Transfers
-	transfersRepo := transfers.NewRepo(db)
-	defer transfersRepo.Close()
-	transfers.NewRouter(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
-	transferadmin.RegisterRoutes(cfg, adminServer, transfersRepo)
+	transfersRouter := transfers.NewRouter(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy)
+	transfersRouter.RegisterRoutes(handler)
+	defer transfersRouter.Evidence.Close()
+	transferQueue := transfers.NewQueue(cfg, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy)
+	transferQueue.UseJobs(jobRegistry)
+	go transferQueue.Start(ctx)
+	availabilityWatcher := transfers.NewAvailabilityWatcher(cfg, transfersRepo)
+	availabilityWatcher.UseJobs(jobRegistry)
+	go availabilityWatcher.Start(ctx)
+	transferadmin.RegisterRoutes(cfg, adminServer, transfersRepo, transferPublisher, traceNumbers, transfersRouter.Evidence)
+
+	// Received Transfers, which are posted to the Accounts service when we're an RDFI
+	var accountsClient accountsservice.Client
+	if cfg.RDFI != nil {
+		accountsClient = accountsservice.NewClient(cfg.Logger, cfg.RDFI.Accounts, accountsservice.HttpClient)
+		warmDependency(ctx, cfg, adminServer, "accounts", accountsClient.Ping)
+	}
+	received.NewRouter(cfg, receivedRepo, accountsClient, transferPublisher, traceNumbers).RegisterRoutes(handler)
+
+	// Reports
+	reports.NewRouter(cfg, reportsRepo).RegisterRoutes(handler)
 
 	// Micro-Deposit Validation
 	microDepositRepo := microdeposits.NewRepo(db)
-	microdeposits.NewRouter(cfg, microDepositRepo, transfersRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
+	microdeposits.NewRouter(cfg, microDepositRepo, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
+	microDepositQueue := microdeposits.NewQueue(cfg, microDepositRepo, transfersRepo, orgRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher)
+	microDepositQueue.UseJobs(jobRegistry)
+	go microDepositQueue.Start(ctx)
+	verificationWatcher := microdeposits.NewWatcher(cfg, microDepositRepo, transfersRepo, orgRepo, customersClient)
+	verificationWatcher.UseJobs(jobRegistry)
+	go verificationWatcher.Start(ctx)
+
+	// Sample data
+	if cfg.Admin.EnableSeedEndpoint {
+		seed.New(cfg.Logger, orgRepo, transfersRepo).RegisterRoutes(adminServer)
+	}
+
+	// Read-only requests made as an organization from the admin server
+	impersonation.RegisterRoutes(cfg, adminServer, handler)
 
 	// Create main HTTP server
 	serve := &http.Server{
@@ -214,9 +374,23 @@ func main() {
 	fileProcessors := inbound.SetupProcessors(
 		inbound.NewCorrectionProcessor(cfg.Logger),
 		inbound.NewPrenoteProcessor(cfg.Logger),
-		inbound.NewReturnProcessor(cfg.Logger, transfersRepo),
+		inbound.NewReturnProcessor(cfg, transfersRepo),
 	)
-	inboundProcessor := inbound.NewPeriodicScheduler(cfg, agent, fileProcessors)
+	if cfg.RDFI != nil {
+		fileProcessors = append(fileProcessors, inbound.NewReceivedProcessor(cfg, accountsClient, receivedRepo, transferPublisher, traceNumbers))
+	}
+	inboundRepo := inbound.NewRepo(db)
+	quarantine, err := inbound.NewQuarantine(cfg, inboundRepo, fileProcessors)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating inbound quarantine: %v", err))
+	}
+	quarantine.RegisterRoutes(adminServer)
+
+	inboundProcessor, err := inbound.NewPeriodicScheduler(cfg, agent, fileProcessors, inboundRepo, quarantine)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating inbound processor: %v", err))
+	}
+	inboundProcessor.UseJobs(jobRegistry)
 	go func() {
 		if err := inboundProcessor.Start(); err != nil {
 			panic(fmt.Sprintf("ERROR with inbound processor: %v", err))
@@ -260,9 +434,32 @@ func validateTemplate(cfg config.ODFI) error {
 	return nil
 }
 
-func registerMicroDepositHealth(cfg *config.Config, client customers.Client, svc *admin.Server) {
+func registerMicroDepositHealth(ctx context.Context, cfg *config.Config, client customers.Client, svc *admin.Server) {
 	if micro := cfg.Validation.MicroDeposits; micro != nil {
-		check := customers.HealthChecker(client, micro.Source.Organization, micro.Source.CustomerID, micro.Source.AccountID)
-		svc.AddLivenessCheck("micro-deposits-account", check)
+		// Reading the source account also fills the customers cache ahead of the first micro-deposit
+		check := func() error {
+			return customers.HealthChecker(client, micro.Source.Organization, micro.Source.CustomerID, micro.Source.AccountID)()
+		}
+		warmDependency(ctx, cfg, svc, "micro-deposits-account", check)
+	}
+}
+
+// warmDependency shares the result of check between the liveness and readiness checks named name
+// and checks the dependency in the background until it succeeds, so PayGate only becomes ready
+// once its client has reached the dependency.
+func warmDependency(ctx context.Context, cfg *config.Config, svc *admin.Server, name string, check func() error) {
+	cache := health.NewCache(name, check, cfg.Admin.HealthCheckExpiration())
+	cache.RegisterChecks(svc)
+	go cache.Warm(ctx, cfg.Logger)
+}
+
+func runSeed(cfg *config.Config, db *sql.DB) error {
+	seeder := seed.New(cfg.Logger, organization.NewRepo(db), transfers.NewRepo(db))
+	result, err := seeder.Seed()
+	if err != nil {
+		return err
 	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
 }
@@ -0,0 +1,201 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package accounts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base/http/bind"
+	"github.com/moov-io/base/k8s"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+var (
+	HttpClient = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+)
+
+// Client reads and posts to the accounts held at our financial institution in a
+// Moov Accounts instance.
+type Client interface {
+	Ping() error
+
+	// SearchAccount returns the account with the given numbers, or nil if none exists.
+	SearchAccount(organization, routingNumber, accountNumber, accountType string) (*Account, error)
+
+	PostTransaction(organization, requestID string, lines []TransactionLine) (*Transaction, error)
+}
+
+type Account struct {
+	ID            string `json:"id"`
+	CustomerID    string `json:"customerID"`
+	Name          string `json:"name"`
+	AccountNumber string `json:"accountNumber"`
+	RoutingNumber string `json:"routingNumber"`
+	Status        string `json:"status"`
+	Type          string `json:"type"`
+}
+
+// TransactionLine moves Amount in or out of an account. Purpose is ACHCredit or ACHDebit.
+type TransactionLine struct {
+	AccountID string `json:"accountID"`
+	Purpose   string `json:"purpose"`
+	Amount    int    `json:"amount"`
+}
+
+type Transaction struct {
+	ID        string            `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Lines     []TransactionLine `json:"lines"`
+}
+
+const (
+	ACHCredit = "ACHCredit"
+	ACHDebit  = "ACHDebit"
+)
+
+type moovClient struct {
+	endpoint   string
+	httpClient *http.Client
+	logger     log.Logger
+	debug      bool
+}
+
+func (c *moovClient) Ping() error {
+	resp, err := c.do(context.TODO(), "GET", "/ping", nil, nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if resp == nil || err != nil {
+		return fmt.Errorf("accounts Ping: failed: %v", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("accounts Ping: got status: %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *moovClient) SearchAccount(organization, routingNumber, accountNumber, accountType string) (*Account, error) {
+	ctx, cancelFn := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFn()
+
+	params := url.Values{}
+	params.Set("routingNumber", routingNumber)
+	params.Set("number", accountNumber)
+	params.Set("type", accountType)
+
+	headers := map[string]string{"X-Organization": organization}
+	resp, err := c.do(ctx, "GET", "/accounts/search?"+params.Encode(), headers, nil)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if resp == nil || err != nil {
+		return nil, fmt.Errorf("search account: failed: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("search account: status=%s", resp.Status)
+	}
+	var acct Account
+	if err := json.NewDecoder(resp.Body).Decode(&acct); err != nil {
+		return nil, fmt.Errorf("search account: decode: %v", err)
+	}
+	return &acct, nil
+}
+
+func (c *moovClient) PostTransaction(organization, requestID string, lines []TransactionLine) (*Transaction, error) {
+	ctx, cancelFn := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFn()
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(map[string]interface{}{"lines": lines}); err != nil {
+		return nil, fmt.Errorf("post transaction: encode: %v", err)
+	}
+	headers := map[string]string{
+		"X-Organization": organization,
+		"X-Request-ID":   requestID,
+	}
+	resp, err := c.do(ctx, "POST", "/accounts/transactions", headers, &body)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if resp == nil || err != nil {
+		return nil, fmt.Errorf("post transaction: failed: %v", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("post transaction: status=%s", resp.Status)
+	}
+	var tx Transaction
+	if err := json.NewDecoder(resp.Body).Decode(&tx); err != nil {
+		return nil, fmt.Errorf("post transaction: decode: %v", err)
+	}
+	return &tx, nil
+}
+
+func (c *moovClient) do(ctx context.Context, method, path string, headers map[string]string, body *bytes.Buffer) (*http.Response, error) {
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, c.endpoint+path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	if c.debug {
+		c.logger.Logf("%s %s", method, path)
+	}
+	return c.httpClient.Do(req)
+}
+
+// NewClient returns a Client instance and will default to using the Accounts address in
+// moov's standard Kubernetes setup.
+//
+// endpoint is a DNS record responsible for routing us to an Accounts instance.
+// Example: http://accounts.apps.svc.cluster.local:8080
+func NewClient(logger log.Logger, cfg config.AccountsService, httpClient *http.Client) Client {
+	logger = logger.Set("client", log.String("accounts"))
+	endpoint := "http://localhost" + bind.HTTP("accounts")
+	if k8s.Inside() {
+		endpoint = "http://accounts.apps.svc.cluster.local:8080"
+	}
+	if cfg.Endpoint != "" {
+		endpoint = cfg.Endpoint
+	}
+	if cfg.Debug {
+		logger.Log("Debug logs enabled")
+	}
+
+	logger.Logf("using %s for Accounts address", endpoint)
+
+	return &moovClient{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		httpClient: httpClient,
+		logger:     logger,
+		debug:      cfg.Debug,
+	}
+}
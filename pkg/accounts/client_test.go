@@ -0,0 +1,85 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package accounts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func mockAccountsServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/accounts/search", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("number") != "12345" || r.Header.Get("X-Organization") != "moov" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(Account{
+			ID:            "acct",
+			AccountNumber: q.Get("number"),
+			RoutingNumber: q.Get("routingNumber"),
+			Type:          q.Get("type"),
+		})
+	})
+	mux.HandleFunc("/accounts/transactions", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Lines []TransactionLine `json:"lines"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Lines) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(Transaction{ID: r.Header.Get("X-Request-ID"), Lines: req.Lines})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := mockAccountsServer(t)
+	client := NewClient(log.NewNopLogger(), config.AccountsService{Endpoint: server.URL}, HttpClient)
+
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	acct, err := client.SearchAccount("moov", "053200019", "12345", "Checking")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct == nil || acct.ID != "acct" || acct.Type != "Checking" {
+		t.Errorf("unexpected account: %#v", acct)
+	}
+
+	acct, err = client.SearchAccount("moov", "053200019", "54321", "Checking")
+	if err != nil || acct != nil {
+		t.Errorf("unexpected account=%#v error=%v", acct, err)
+	}
+
+	tx, err := client.PostTransaction("moov", "request", []TransactionLine{
+		{AccountID: "acct", Purpose: ACHCredit, Amount: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.ID != "request" || len(tx.Lines) != 1 {
+		t.Errorf("unexpected transaction: %#v", tx)
+	}
+
+	if _, err := client.PostTransaction("moov", "request", nil); err == nil {
+		t.Error("expected error")
+	}
+}
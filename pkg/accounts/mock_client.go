@@ -0,0 +1,34 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package accounts
+
+type MockClient struct {
+	// Accounts are keyed by their account number
+	Accounts map[string]*Account
+
+	// Posted holds the lines of each posted transaction
+	Posted [][]TransactionLine
+
+	Err error
+}
+
+func (c *MockClient) Ping() error {
+	return c.Err
+}
+
+func (c *MockClient) SearchAccount(organization, routingNumber, accountNumber, accountType string) (*Account, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return c.Accounts[accountNumber], nil
+}
+
+func (c *MockClient) PostTransaction(organization, requestID string, lines []TransactionLine) (*Transaction, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	c.Posted = append(c.Posted, lines)
+	return &Transaction{ID: requestID, Lines: lines}, nil
+}
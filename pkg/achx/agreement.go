@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// AgreementError lists each rule of the ODFI's agreement which a file breaks.
+type AgreementError struct {
+	Violations []string
+}
+
+func (e *AgreementError) Error() string {
+	return fmt.Sprintf("violates the ODFI agreement: %s", strings.Join(e.Violations, ", "))
+}
+
+// CheckAgreement returns an *AgreementError when file breaks any rule of cfg. Effective entry
+// dates are compared against today.
+func CheckAgreement(cfg *config.Agreement, file *ach.File, odfiRoutingNumber string, today time.Time) error {
+	if cfg == nil || file == nil {
+		return nil
+	}
+	var violations []string
+	if cfg.MaxEntriesPerFile > 0 {
+		if n := AgreementEntries(file); n > cfg.MaxEntriesPerFile {
+			violations = append(violations, fmt.Sprintf("%d entries is more than the %d allowed in a file", n, cfg.MaxEntriesPerFile))
+		}
+	}
+
+	var latest string
+	if cfg.MaxEffectiveDays > 0 {
+		latest = base.NewTime(today).AddBankingDay(cfg.MaxEffectiveDays).Format("060102")
+	}
+	for _, batch := range file.Batches {
+		bh := batch.GetHeader()
+		if bh == nil {
+			continue
+		}
+		if cfg.Prohibits(bh.StandardEntryClassCode) {
+			violations = append(violations, fmt.Sprintf("batch #%d has prohibited SEC code %s", bh.BatchNumber, bh.StandardEntryClassCode))
+		}
+		if latest != "" && bh.EffectiveEntryDate > latest {
+			violations = append(violations, fmt.Sprintf("batch #%d is effective %s, more than %d banking days ahead", bh.BatchNumber, bh.EffectiveEntryDate, cfg.MaxEffectiveDays))
+		}
+		if cfg.DebitsOnly {
+			entries := batch.GetEntries()
+			for i := range entries {
+				if isOffsetEntry(entries[i]) || entries[i].RDFIIdentification == ABA8(odfiRoutingNumber) {
+					continue
+				}
+				if entries[i].CreditOrDebit() == "C" {
+					violations = append(violations, fmt.Sprintf("batch #%d credits an account at %s but only debits are allowed", bh.BatchNumber, entries[i].RDFIIdentification))
+					break
+				}
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &AgreementError{Violations: violations}
+	}
+	return nil
+}
+
+// AgreementEntries returns how many entries of file count towards Agreement.MaxEntriesPerFile,
+// which is every entry besides offsets to the settlement account.
+func AgreementEntries(file *ach.File) int {
+	var total int
+	for i := range file.Batches {
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			if !isOffsetEntry(entries[j]) {
+				total++
+			}
+		}
+	}
+	return total
+}
+
+// isOffsetEntry returns true for entries to the settlement account added by AddOffsets
+func isOffsetEntry(ed *ach.EntryDetail) bool {
+	return ed.IdentificationNumber == "OFFSET"
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func readAgreementFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func agreementViolations(t *testing.T, err error) []string {
+	t.Helper()
+
+	var agreementErr *AgreementError
+	if !errors.As(err, &agreementErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return agreementErr.Violations
+}
+
+func TestCheckAgreement(t *testing.T) {
+	now := time.Date(2020, time.October, 15, 10, 0, 0, 0, time.UTC)
+	file := readAgreementFile(t)
+
+	if err := CheckAgreement(nil, file, "076401251", now); err != nil {
+		t.Error(err)
+	}
+	cfg := &config.Agreement{
+		MaxEntriesPerFile:  1,
+		ProhibitedSECCodes: []string{"WEB"},
+		DebitsOnly:         true,
+		MaxEffectiveDays:   2,
+	}
+	if err := CheckAgreement(cfg, file, "076401251", now); err != nil {
+		t.Error(err)
+	}
+
+	// break every rule
+	cfg.MaxEntriesPerFile = 0
+	cfg.ProhibitedSECCodes = []string{"PPD"}
+	bh := file.Batches[0].GetHeader()
+	bh.EffectiveEntryDate = "201022" // 5 banking days ahead
+	file.Batches[0].GetEntries()[0].TransactionCode = ach.CheckingCredit
+
+	violations := agreementViolations(t, CheckAgreement(cfg, file, "076401251", now))
+	if len(violations) != 3 {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+	if !strings.Contains(violations[0], "prohibited SEC code PPD") {
+		t.Errorf("unexpected violation: %s", violations[0])
+	}
+	if !strings.Contains(violations[1], "more than 2 banking days ahead") {
+		t.Errorf("unexpected violation: %s", violations[1])
+	}
+	if !strings.Contains(violations[2], "only debits are allowed") {
+		t.Errorf("unexpected violation: %s", violations[2])
+	}
+
+	// credits to accounts at the ODFI are allowed
+	if err := CheckAgreement(&config.Agreement{DebitsOnly: true}, file, "053200019", now); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckAgreement__maxEntries(t *testing.T) {
+	file := readAgreementFile(t)
+
+	cfg := &config.Agreement{
+		MaxEntriesPerFile: 1,
+	}
+	if n := AgreementEntries(file); n != 1 {
+		t.Errorf("unexpected entries: %d", n)
+	}
+
+	offset := &config.Offset{
+		RoutingNumber: "076401251",
+		AccountNumber: "123456",
+		AccountType:   "checking",
+		Name:          "settlement",
+	}
+	if err := AddOffsets(file, offset, "076401251", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAgreement(cfg, file, "076401251", time.Now()); err != nil {
+		t.Errorf("offsets shouldn't count: %v", err)
+	}
+
+	file.Batches[0].AddEntry(file.Batches[0].GetEntries()[0])
+	violations := agreementViolations(t, CheckAgreement(cfg, file, "076401251", time.Now()))
+	if len(violations) != 1 || !strings.Contains(violations[0], "2 entries is more than the 1 allowed") {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+}
@@ -5,7 +5,9 @@
 package achx
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/moov-io/ach"
@@ -40,14 +42,20 @@ func makeBatchHeader(id string, options Options, xfer *client.Transfer, source S
 		batchHeader.CompanyName = options.FileConfig.CompanyName
 	}
 
-	// Set DiscretionaryData if it exists
+	// Set DiscretionaryData if it exists, preferring the Transfer's
 	if v, ok := source.Customer.Metadata["discretionary"]; ok {
 		batchHeader.CompanyDiscretionaryData = v
 	}
+	if xfer.CompanyDiscretionaryData != "" {
+		batchHeader.CompanyDiscretionaryData = xfer.CompanyDiscretionaryData
+	}
 
 	// Fill in the other fields
 	batchHeader.CompanyIdentification = options.CompanyIdentification
 	batchHeader.CompanyEntryDescription = xfer.Description // 10 character max
+	if xfer.CompanyEntryDescription != "" {
+		batchHeader.CompanyEntryDescription = xfer.CompanyEntryDescription
+	}
 
 	now := time.Now().In(options.CutoffTimezone)
 	if xfer.SameDay {
@@ -63,6 +71,66 @@ func makeBatchHeader(id string, options Options, xfer *client.Transfer, source S
 	return batchHeader
 }
 
+// IsSameDay returns true if the batch has a same-day "SDHHMM" company descriptive date.
+func IsSameDay(bh *ach.BatchHeader) bool {
+	return bh != nil && strings.HasPrefix(bh.CompanyDescriptiveDate, "SD")
+}
+
+// HasSameDay returns true if any batch of file is same-day.
+func HasSameDay(file *ach.File) bool {
+	if file == nil {
+		return false
+	}
+	for i := range file.Batches {
+		if IsSameDay(file.Batches[i].GetHeader()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lengths of the batch header and entry fields which can be overridden
+const (
+	companyEntryDescriptionLength  = 10
+	companyDiscretionaryDataLength = 20
+	individualNameLength           = 22
+)
+
+// ValidateCompanyEntryDescription returns an error if desc can't be used as the
+// Company Entry Description of a batch with the given SEC code.
+func ValidateCompanyEntryDescription(desc string, secCode string) error {
+	if desc == "" {
+		return nil
+	}
+	if StandardEntryClassCode(secCode) == ach.RCK {
+		return errors.New("RCK entries must use REDEPCHECK")
+	}
+	return validateBatchHeaderField(desc, companyEntryDescriptionLength)
+}
+
+// ValidateCompanyDiscretionaryData returns an error if data doesn't fit in a batch's
+// Company Discretionary Data.
+func ValidateCompanyDiscretionaryData(data string) error {
+	return validateBatchHeaderField(data, companyDiscretionaryDataLength)
+}
+
+// ValidateIndividualName returns an error if name doesn't fit in an entry's Individual Name.
+func ValidateIndividualName(name string) error {
+	return validateBatchHeaderField(name, individualNameLength)
+}
+
+func validateBatchHeaderField(v string, length int) error {
+	if len(v) > length {
+		return fmt.Errorf("%d characters is longer than %d", len(v), length)
+	}
+	for _, r := range v {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("unsupported character %q", r)
+		}
+	}
+	return nil
+}
+
 func createIdentificationNumber() string {
 	return base.ID()[:15]
 }
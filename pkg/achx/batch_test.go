@@ -45,4 +45,74 @@ func TestBatch__SameDay(t *testing.T) {
 	if !strings.HasPrefix(bh.CompanyDescriptiveDate, "SD") {
 		t.Errorf("CompanyDescriptiveDate=%q", bh.CompanyDescriptiveDate)
 	}
+	if !IsSameDay(bh) {
+		t.Error("expected same-day batch")
+	}
+
+	xfer.SameDay = false
+	if bh := makeBatchHeader("", opts, xfer, source); IsSameDay(bh) {
+		t.Errorf("unexpected same-day batch: %q", bh.CompanyDescriptiveDate)
+	}
+}
+
+func TestBatch__CompanyOverrides(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:     "987654320",
+		CutoffTimezone:        time.UTC,
+		CompanyIdentification: "Moov",
+	}
+	xfer := &client.Transfer{
+		Description: "Loan Pay",
+	}
+	source := Source{
+		Customer: customers.Customer{
+			FirstName: "John",
+			LastName:  "Doe",
+			Metadata: map[string]string{
+				"discretionary": "CUSTOMER",
+			},
+		},
+		Account: customers.Account{
+			RoutingNumber: opts.ODFIRoutingNumber,
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+	}
+	bh := makeBatchHeader("", opts, xfer, source)
+	if bh.CompanyEntryDescription != "Loan Pay" || bh.CompanyDiscretionaryData != "CUSTOMER" {
+		t.Errorf("CompanyEntryDescription=%q CompanyDiscretionaryData=%q", bh.CompanyEntryDescription, bh.CompanyDiscretionaryData)
+	}
+
+	xfer.CompanyEntryDescription = "REFUND"
+	xfer.CompanyDiscretionaryData = "ORDER 1001"
+	bh = makeBatchHeader("", opts, xfer, source)
+	if bh.CompanyEntryDescription != "REFUND" || bh.CompanyDiscretionaryData != "ORDER 1001" {
+		t.Errorf("CompanyEntryDescription=%q CompanyDiscretionaryData=%q", bh.CompanyEntryDescription, bh.CompanyDiscretionaryData)
+	}
+}
+
+func TestBatch__ValidateCompanyFields(t *testing.T) {
+	if err := ValidateCompanyEntryDescription("PAYROLL", ""); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateCompanyEntryDescription("PAYROLL", "RCK"); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateCompanyEntryDescription(strings.Repeat("A", 11), ""); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateCompanyDiscretionaryData(strings.Repeat("A", 20)); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateCompanyDiscretionaryData(strings.Repeat("A", 21)); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateCompanyDiscretionaryData("ORDER\n"); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateIndividualName(strings.Repeat("A", 22)); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateIndividualName(strings.Repeat("A", 23)); err == nil {
+		t.Error("expected error")
+	}
 }
@@ -0,0 +1,128 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// checkSECCodes are debits converted from paper checks and the largest amount
+// NACHA allows for each, in cents.
+var checkSECCodes = map[string]int32{
+	ach.ARC: 2500000,
+	ach.BOC: 2500000,
+	ach.POP: 2500000,
+	ach.RCK: 250000,
+}
+
+// StandardEntryClassCode returns the SEC code a Transfer is originated with.
+func StandardEntryClassCode(code string) string {
+	if code == "" {
+		return ach.PPD
+	}
+	return strings.ToUpper(code)
+}
+
+// ValidateStandardEntryClassCode returns an error if PayGate can't originate entries
+// with the SEC code.
+func ValidateStandardEntryClassCode(code string) error {
+	code = StandardEntryClassCode(code)
+	if _, ok := checkSECCodes[code]; ok {
+		return nil
+	}
+	switch code {
+	case ach.PPD, ach.WEB, ach.CCD, ach.CTX:
+		return nil
+	}
+	return fmt.Errorf("unsupported SEC code %s", code)
+}
+
+// ValidateCheck returns an error if check is missing or invalid for the SEC code.
+// Check details are only accepted on ARC, BOC, POP and RCK entries.
+func ValidateCheck(secCode string, check *client.CheckDetails, amount client.Amount) error {
+	secCode = StandardEntryClassCode(secCode)
+	max, ok := checkSECCodes[secCode]
+	if !ok {
+		if check != nil {
+			return fmt.Errorf("not allowed on %s entries", secCode)
+		}
+		return nil
+	}
+	if check == nil || check.SerialNumber == "" {
+		return fmt.Errorf("%s entries require a check serialNumber", secCode)
+	}
+	if amount.Value > max {
+		return fmt.Errorf("%s entries are limited to %d cents", secCode, max)
+	}
+	if secCode == ach.POP {
+		if len(check.SerialNumber) > 9 {
+			return errors.New("POP serialNumber is limited to 9 characters")
+		}
+		if check.TerminalCity == "" || len(check.TerminalCity) > 4 {
+			return errors.New("POP entries require a terminalCity of up to 4 characters")
+		}
+		if len(check.TerminalState) != 2 {
+			return errors.New("POP entries require a two letter terminalState")
+		}
+		return nil
+	}
+	if len(check.SerialNumber) > 15 {
+		return fmt.Errorf("%s serialNumber is limited to 15 characters", secCode)
+	}
+	if check.TerminalCity != "" || check.TerminalState != "" {
+		return fmt.Errorf("terminalCity and terminalState are only allowed on POP entries")
+	}
+	return nil
+}
+
+// createCheckBatch returns a batch with a single debit converted from a check.
+// These entries can't be balanced or have addenda records.
+func createCheckBatch(id string, options Options, xfer *client.Transfer, source Source, destination Destination) (ach.Batcher, error) {
+	secCode := StandardEntryClassCode(xfer.StandardEntryClassCode)
+	if options.ODFIRoutingNumber == source.Account.RoutingNumber {
+		return nil, fmt.Errorf("%s entries must debit the source account", secCode)
+	}
+	if xfer.Check == nil {
+		return nil, fmt.Errorf("%s entries require check details", secCode)
+	}
+
+	opts := options
+	opts.FileConfig.BalanceEntries = false
+	bh := makeBatchHeader(id, opts, xfer, source)
+	bh.StandardEntryClassCode = secCode
+	if secCode == ach.RCK {
+		bh.CompanyEntryDescription = "REDEPCHECK" // required by NACHA
+	}
+
+	batch, err := ach.NewBatch(bh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s batch: %v", secCode, err)
+	}
+
+	// Build the entry without addenda records
+	xf := *xfer
+	xf.Remittance = nil
+	opts.FileConfig.Addendum.Create05 = false
+	entry := createPPDEntry(id, opts, &xf, source, destination)
+	if secCode == ach.POP {
+		entry.SetPOPCheckSerialNumber(xfer.Check.SerialNumber)
+		entry.SetPOPTerminalCity(xfer.Check.TerminalCity)
+		entry.SetPOPTerminalState(strings.ToUpper(xfer.Check.TerminalState))
+	} else {
+		entry.SetCheckSerialNumber(xfer.Check.SerialNumber)
+	}
+	batch.AddEntry(entry)
+	batch.SetControl(ach.NewBatchControl())
+
+	if err := batch.Create(); err != nil {
+		return batch, err
+	}
+	return batch, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestCheck__ValidateCheck(t *testing.T) {
+	amount := client.Amount{Currency: "USD", Value: 1200}
+
+	if err := ValidateCheck("", nil, amount); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateCheck(ach.PPD, &client.CheckDetails{SerialNumber: "1005"}, amount); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateCheck(ach.ARC, nil, amount); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateCheck(ach.BOC, &client.CheckDetails{SerialNumber: "1005"}, amount); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateCheck(ach.BOC, &client.CheckDetails{SerialNumber: "1005", TerminalCity: "PHIL"}, amount); err == nil {
+		t.Error("expected error")
+	}
+
+	// RCK has a lower limit
+	if err := ValidateCheck(ach.RCK, &client.CheckDetails{SerialNumber: "1005"}, client.Amount{Value: 250001}); err == nil {
+		t.Error("expected error")
+	}
+
+	// POP requires terminal fields
+	check := &client.CheckDetails{SerialNumber: "1005", TerminalCity: "PHIL", TerminalState: "PA"}
+	if err := ValidateCheck(ach.POP, check, amount); err != nil {
+		t.Error(err)
+	}
+	check.TerminalState = ""
+	if err := ValidateCheck(ach.POP, check, amount); err == nil {
+		t.Error("expected error")
+	}
+
+	if err := ValidateStandardEntryClassCode("pop"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateStandardEntryClassCode(ach.WEB); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateStandardEntryClassCode("ctx"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateStandardEntryClassCode(ach.TEL); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCheck__ConstructFile(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber: "123456780",
+		CutoffTimezone:    time.UTC,
+		FileConfig: config.FileConfig{
+			BalanceEntries: true,
+			Addendum: config.Addendum{
+				Create05: true,
+			},
+		},
+		CompanyIdentification: "MOOVZZZZZZ",
+	}
+	source := Source{
+		Customer: customers.Customer{
+			FirstName: "Jane",
+			LastName:  "Doe",
+		},
+		Account: customers.Account{
+			RoutingNumber: "987654320",
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+		AccountNumber: "1234567",
+	}
+	destination := Destination{
+		Customer: customers.Customer{
+			FirstName: "John",
+			LastName:  "Doe",
+		},
+		Account: customers.Account{
+			RoutingNumber: opts.ODFIRoutingNumber,
+			Type:          customers.ACCOUNTTYPE_CHECKING,
+		},
+		AccountNumber: "7654321",
+	}
+
+	for _, secCode := range []string{ach.ARC, ach.BOC, ach.POP, ach.RCK} {
+		xfer := &client.Transfer{
+			Amount: client.Amount{
+				Currency: "USD",
+				Value:    1247,
+			},
+			Description:            "check",
+			StandardEntryClassCode: secCode,
+			Check: &client.CheckDetails{
+				SerialNumber:  "1005",
+				TerminalCity:  "PHIL",
+				TerminalState: "pa",
+			},
+		}
+		if secCode != ach.POP {
+			xfer.Check.TerminalCity, xfer.Check.TerminalState = "", ""
+		}
+
+		file, err := ConstructFile(base.ID(), opts, xfer, source, destination)
+		if err != nil {
+			t.Fatalf("%s: %v", secCode, err)
+		}
+		if len(file.Batches) != 1 || file.Batches[0].GetHeader().StandardEntryClassCode != secCode {
+			t.Fatalf("%s: unexpected batches: %#v", secCode, file.Batches)
+		}
+
+		// one debit without an offset or addenda
+		entries := file.Batches[0].GetEntries()
+		if len(entries) != 1 || entries[0].TransactionCode != ach.CheckingDebit || len(entries[0].Addenda05) != 0 {
+			t.Errorf("%s: unexpected entries: %#v", secCode, entries)
+		}
+		if secCode == ach.POP {
+			if entries[0].POPTerminalStateField() != "PA" {
+				t.Errorf("unexpected terminal state: %q", entries[0].POPTerminalStateField())
+			}
+		} else if entries[0].IdentificationNumber != "1005" {
+			t.Errorf("%s: unexpected serial number: %q", secCode, entries[0].IdentificationNumber)
+		}
+	}
+
+	// check entries can't be credits
+	xfer := &client.Transfer{
+		Amount:                 client.Amount{Currency: "USD", Value: 1247},
+		Description:            "check",
+		StandardEntryClassCode: ach.ARC,
+		Check:                  &client.CheckDetails{SerialNumber: "1005"},
+	}
+	if _, err := ConstructFile(base.ID(), opts, xfer, Source(destination), Destination(source)); err == nil {
+		t.Error("expected error")
+	}
+}
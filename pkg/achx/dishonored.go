@@ -0,0 +1,196 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// dishonoredReturnCodes are sent by an ODFI to dishonor a return it received.
+var dishonoredReturnCodes = map[string]bool{
+	"R61": true, // Misrouted Return
+	"R67": true, // Duplicate Return
+	"R68": true, // Untimely Return
+	"R69": true, // Field Error(s)
+	"R70": true, // Permissible Return Entry Not Accepted
+}
+
+// contestedDishonoredReturnCodes are sent by an RDFI to contest a dishonored return.
+var contestedDishonoredReturnCodes = map[string]bool{
+	"R71": true, // Misrouted Dishonored Return
+	"R72": true, // Untimely Dishonored Return
+	"R73": true, // Timely Original Return
+	"R74": true, // Corrected Return
+	"R75": true, // Return Not a Duplicate
+	"R76": true, // No Errors Found
+}
+
+// dishonoredAddendaInformationLength is the size of the free-form field which ends
+// the Addenda99 of a dishonored return.
+const dishonoredAddendaInformationLength = 21
+
+// dishonoredAddendaReserved is the blank field in front of the return trace number of a
+// dishonored return's Addenda99.
+const dishonoredAddendaReserved = "   "
+
+// IsDishonoredReturnCode returns true for the R61 and R67 through R70 codes an ODFI
+// uses to dishonor a return.
+func IsDishonoredReturnCode(code string) bool {
+	return dishonoredReturnCodes[strings.ToUpper(code)]
+}
+
+// IsContestedDishonoredReturnCode returns true for the R71 through R76 codes an RDFI
+// uses to contest a dishonored return.
+func IsContestedDishonoredReturnCode(code string) bool {
+	return contestedDishonoredReturnCodes[strings.ToUpper(code)]
+}
+
+// Dishonor holds what's needed to dishonor a return entry received by the ODFI.
+type Dishonor struct {
+	// ReturnCode is one of the dishonored return codes, R61 or R67 through R70.
+	ReturnCode string
+
+	// Header and Entry are the return being dishonored and its batch header.
+	Header *ach.BatchHeader
+	Entry  *ach.EntryDetail
+
+	// SettlementDate is the Julian day the ACH operator wrote into the return's batch
+	// header. The ach package doesn't read it, so it's provided separately.
+	SettlementDate string
+
+	// AddendaInformation is an optional note of up to 21 characters.
+	AddendaInformation string
+}
+
+// ValidateDishonor returns an error if a dishonored return can't be created from dishonor.
+func ValidateDishonor(dishonor Dishonor) error {
+	if !IsDishonoredReturnCode(dishonor.ReturnCode) {
+		return fmt.Errorf("unexpected dishonored return code %q", dishonor.ReturnCode)
+	}
+	if dishonor.Header == nil || dishonor.Entry == nil || dishonor.Entry.Addenda99 == nil {
+		return errors.New("missing return entry")
+	}
+	if code := dishonor.Entry.Addenda99.ReturnCode; IsDishonoredReturnCode(code) || IsContestedDishonoredReturnCode(code) {
+		return fmt.Errorf("%s returns can't be dishonored", code)
+	}
+	if v := dishonor.SettlementDate; v != "" {
+		if n, err := strconv.Atoi(v); err != nil || len(v) != 3 || n < 1 || n > 366 {
+			return fmt.Errorf("invalid settlement date %q", v)
+		}
+	}
+	if err := validateBatchHeaderField(dishonor.AddendaInformation, dishonoredAddendaInformationLength); err != nil {
+		return fmt.Errorf("addenda information: %v", err)
+	}
+	return nil
+}
+
+// DishonoredReturn creates a file which sends a return entry back to the RDFI of the
+// original entry. Its Addenda99 references the original entry's trace number and the
+// trace number, settlement date and code of the return being dishonored.
+func DishonoredReturn(id string, options Options, dishonor Dishonor) (*ach.File, error) {
+	if err := ValidateDishonor(dishonor); err != nil {
+		return nil, err
+	}
+	returned := dishonor.Entry
+
+	file, now := ach.NewFile(), time.Now().In(options.CutoffTimezone)
+	file.ID = id
+	file.Control = ach.NewFileControl()
+
+	file.Header.ID = id
+	file.Header.ImmediateOrigin = determineOrigin(options)
+	file.Header.ImmediateOriginName = options.Gateway.OriginName
+	file.Header.ImmediateDestinationName = options.Gateway.DestinationName
+	file.Header.FileCreationDate = now.Format("060102") // YYMMDD
+	file.Header.FileCreationTime = now.Format("1504")   // HHMM
+
+	// Copy the original entry's batch details from the return
+	bh := ach.NewBatchHeader()
+	bh.ID = id
+	bh.ServiceClassCode = dishonor.Header.ServiceClassCode
+	bh.StandardEntryClassCode = dishonor.Header.StandardEntryClassCode
+	bh.CompanyName = dishonor.Header.CompanyName
+	bh.CompanyIdentification = dishonor.Header.CompanyIdentification
+	bh.CompanyEntryDescription = dishonor.Header.CompanyEntryDescription
+	bh.EffectiveEntryDate = options.EffectiveEntryDate.Format("060102") // Date to be posted, YYMMDD
+	bh.ODFIIdentification = ABA8(options.ODFIRoutingNumber)
+
+	ed := ach.NewEntryDetail()
+	ed.ID = id
+	ed.TransactionCode = returned.TransactionCode
+	ed.RDFIIdentification = returned.Addenda99.OriginalDFIField()
+	ed.CheckDigit = strconv.Itoa(ed.CalculateCheckDigit(ed.RDFIIdentification))
+	ed.DFIAccountNumber = returned.DFIAccountNumber
+	ed.Amount = returned.Amount
+	ed.IdentificationNumber = returned.IdentificationNumber
+	ed.IndividualName = returned.IndividualName
+	ed.DiscretionaryData = returned.DiscretionaryData
+	ed.TraceNumber = TraceNumber(options.ODFIRoutingNumber)
+	ed.Category = ach.CategoryDishonoredReturn
+
+	addenda := ach.NewAddenda99()
+	addenda.ReturnCode = strings.ToUpper(dishonor.ReturnCode)
+	addenda.OriginalTrace = returned.Addenda99.OriginalTrace
+	addenda.OriginalDFI = returned.Addenda99.OriginalDFI
+	addenda.AddendaInformation = dishonoredAddendaReserved +
+		returned.TraceNumberField() +
+		fmt.Sprintf("%-3s", dishonor.SettlementDate) +
+		strings.TrimPrefix(returned.Addenda99.ReturnCode, "R") +
+		dishonor.AddendaInformation
+	addenda.TraceNumber = ed.TraceNumber
+	ed.Addenda99 = addenda
+	ed.AddendaRecordIndicator = 1
+
+	file.Header.ImmediateDestination = util.Or(options.Gateway.Destination, ed.RDFIIdentification+ed.CheckDigit)
+
+	batch, err := ach.NewBatch(bh)
+	if err != nil {
+		return nil, err
+	}
+	batch.AddEntry(ed)
+	batch.SetControl(ach.NewBatchControl())
+	if err := batch.Create(); err != nil {
+		return nil, fmt.Errorf("dishonored return batch: %v", err)
+	}
+	if options.TraceNumbers != nil {
+		if err := assignTraceNumbers(options.TraceNumbers, options.ODFIRoutingNumber, batch); err != nil {
+			return nil, fmt.Errorf("dishonored return batch: %v", err)
+		}
+	}
+	file.AddBatch(batch)
+
+	if err := file.Create(); err != nil {
+		return file, err
+	}
+	return file, file.Validate()
+}
+
+// RestoreDishonoredAddenda puts back the reserved field which ach.Addenda99.Parse trims
+// from the AddendaInformation of dishonored returns, so their Addenda99 records keep the
+// same layout after a file is read and written again.
+func RestoreDishonoredAddenda(file *ach.File) {
+	if file == nil {
+		return
+	}
+	for i := range file.Batches {
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			addenda := entries[j].Addenda99
+			if addenda == nil || !IsDishonoredReturnCode(addenda.ReturnCode) {
+				continue
+			}
+			if addenda.AddendaInformation != "" && !strings.HasPrefix(addenda.AddendaInformation, dishonoredAddendaReserved) {
+				addenda.AddendaInformation = dishonoredAddendaReserved + addenda.AddendaInformation
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+)
+
+func readReturn(t *testing.T) Dishonor {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Dishonor{
+		ReturnCode:     "R68",
+		Header:         file.Batches[0].GetHeader(),
+		Entry:          file.Batches[0].GetEntries()[0],
+		SettlementDate: "102",
+	}
+}
+
+func TestDishonoredReturn(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:  "091400606",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.Now(time.UTC),
+	}
+	dishonor := readReturn(t)
+	dishonor.AddendaInformation = "UNTIMELY"
+
+	file, err := DishonoredReturn(base.ID(), opts, dishonor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Header.ImmediateDestination != "091000019" {
+		t.Errorf("ImmediateDestination=%q", file.Header.ImmediateDestination)
+	}
+
+	entries := file.Batches[0].GetEntries()
+	if len(entries) != 1 || entries[0].Category != ach.CategoryDishonoredReturn {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+	ed := entries[0]
+	if ed.RDFIIdentification != "09100001" || ed.Amount != 12354 || ed.TransactionCode != dishonor.Entry.TransactionCode {
+		t.Errorf("unexpected entry: %#v", ed)
+	}
+
+	// 4-6 code, 7-21 original trace, 28-35 original RDFI, 39-53 return trace,
+	// 54-56 return settlement date, 57-58 return reason code and 59-79 information
+	record := ed.Addenda99.String()
+	if v := record[3:6]; v != "R68" {
+		t.Errorf("code=%q", v)
+	}
+	if v := record[6:21]; v != "091400600000001" {
+		t.Errorf("original trace=%q", v)
+	}
+	if v := record[27:35]; v != "09100001" {
+		t.Errorf("original RDFI=%q", v)
+	}
+	if v := record[35:38]; v != "   " {
+		t.Errorf("reserved=%q", v)
+	}
+	if v := record[38:53]; v != "091000017611242" {
+		t.Errorf("return trace=%q", v)
+	}
+	if v := record[53:58]; v != "10201" {
+		t.Errorf("settlement date and return code=%q", v)
+	}
+	if v := strings.TrimSpace(record[58:79]); v != "UNTIMELY" {
+		t.Errorf("information=%q", v)
+	}
+	if v := record[79:94]; v != ed.TraceNumber {
+		t.Errorf("trace=%q", v)
+	}
+
+	// the layout is kept after reading the file again
+	var buf bytes.Buffer
+	if err := ach.NewWriter(&buf).Write(file); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ach.NewReader(&buf).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	RestoreDishonoredAddenda(&read)
+	if v := read.Batches[0].GetEntries()[0].Addenda99.String(); v != record {
+		t.Errorf("got %q\nexpected %q", v, record)
+	}
+}
+
+func TestDishonoredReturn__TraceNumbers(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:  "091400606",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.Now(time.UTC),
+		TraceNumbers:       &sequentialTraceNumbers{next: 41},
+	}
+	file, err := DishonoredReturn(base.ID(), opts, readReturn(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed := file.Batches[0].GetEntries()[0]
+	if ed.TraceNumber != "091400600000042" || ed.Addenda99.TraceNumber != ed.TraceNumber {
+		t.Errorf("TraceNumber=%q addenda=%q", ed.TraceNumber, ed.Addenda99.TraceNumber)
+	}
+}
+
+func TestDishonoredReturn__invalid(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:  "091400606",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.Now(time.UTC),
+	}
+
+	dishonor := readReturn(t)
+	dishonor.ReturnCode = "R01"
+	if _, err := DishonoredReturn(base.ID(), opts, dishonor); err == nil {
+		t.Error("expected error")
+	}
+
+	dishonor = readReturn(t)
+	dishonor.SettlementDate = "400"
+	if _, err := DishonoredReturn(base.ID(), opts, dishonor); err == nil {
+		t.Error("expected error")
+	}
+
+	dishonor = readReturn(t)
+	dishonor.AddendaInformation = strings.Repeat("A", 22)
+	if _, err := DishonoredReturn(base.ID(), opts, dishonor); err == nil {
+		t.Error("expected error")
+	}
+
+	// contested dishonored returns can't be dishonored again
+	dishonor = readReturn(t)
+	dishonor.Entry.Addenda99.ReturnCode = "R73"
+	if err := ValidateDishonor(dishonor); err == nil {
+		t.Error("expected error")
+	}
+
+	if !IsContestedDishonoredReturnCode("r76") || IsContestedDishonoredReturnCode("R68") {
+		t.Error("unexpected contested dishonored return codes")
+	}
+}
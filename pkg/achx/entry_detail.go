@@ -28,3 +28,12 @@ func determineTransactionCode(options Options, srcAcct customers.Account) int {
 	}
 	return 0 // invalid, represents a logic bug
 }
+
+// IsDebitTransactionCode returns true for the forward debit codes of each account type.
+func IsDebitTransactionCode(code int) bool {
+	switch code {
+	case ach.CheckingDebit, ach.SavingsDebit, ach.GLDebit, ach.LoanDebit:
+		return true
+	}
+	return false
+}
@@ -52,3 +52,12 @@ func TestEntryDetail_TransactionCodeDebit(t *testing.T) {
 		t.Errorf("unexpected TransactionCode=%d", n)
 	}
 }
+
+func TestEntryDetail_IsDebitTransactionCode(t *testing.T) {
+	if !IsDebitTransactionCode(ach.CheckingDebit) || !IsDebitTransactionCode(ach.SavingsDebit) {
+		t.Error("expected debit")
+	}
+	if IsDebitTransactionCode(ach.CheckingCredit) || IsDebitTransactionCode(ach.CheckingReturnNOCDebit) {
+		t.Error("expected non-debit")
+	}
+}
@@ -45,6 +45,9 @@ type Options struct {
 	// the file config.
 	// TODO(adam): Should this have another fallback of data from the Customer object?
 	CompanyIdentification string
+
+	// TraceNumbers allocates the trace numbers of entries, when nil they're random.
+	TraceNumbers TraceNumbers
 }
 
 func ConstructFile(id string, options Options, xfer *client.Transfer, source Source, destination Destination) (*ach.File, error) {
@@ -67,13 +70,25 @@ func ConstructFile(id string, options Options, xfer *client.Transfer, source Sou
 	file.Header.FileCreationDate = now.Format("060102") // YYMMDD
 	file.Header.FileCreationTime = now.Format("1504")   // HHMM
 
-	b, err := createPPDBatch(id, options, xfer, source, destination)
+	secCode := StandardEntryClassCode(xfer.StandardEntryClassCode)
+	var b ach.Batcher
+	var err error
+	if _, ok := checkSECCodes[secCode]; ok {
+		b, err = createCheckBatch(id, options, xfer, source, destination)
+	} else {
+		b, err = createPPDBatch(id, options, xfer, source, destination)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("createBatch: PPD: %v", err)
+		return nil, fmt.Errorf("createBatch: %s: %v", secCode, err)
 	}
 	if b == nil {
 		return file, errors.New("nil Batcher created")
 	}
+	if options.TraceNumbers != nil {
+		if err := assignTraceNumbers(options.TraceNumbers, options.ODFIRoutingNumber, b); err != nil {
+			return nil, fmt.Errorf("createBatch: %s: %v", secCode, err)
+		}
+	}
 	file.AddBatch(b)
 
 	if err := file.Create(); err != nil {
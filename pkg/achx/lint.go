@@ -0,0 +1,150 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+// LintRules are the NACHA rule sets an ACH file can be linted with.
+type LintRules string
+
+const (
+	// StrictRules enforces every NACHA rule, including a routing number as the file's origin.
+	StrictRules LintRules = "strict"
+
+	// RelaxedRules skips checking the file's origin, destination and trace numbers, which some
+	// ODFIs assign themselves, and allows files without batches.
+	RelaxedRules LintRules = "relaxed"
+)
+
+// ParseLintRules returns the LintRules named by s, defaulting to StrictRules when empty.
+func ParseLintRules(s string) (LintRules, error) {
+	switch LintRules(s) {
+	case "", StrictRules:
+		return StrictRules, nil
+	case RelaxedRules:
+		return RelaxedRules, nil
+	}
+	return "", fmt.Errorf("unknown rules %q", s)
+}
+
+func (rules LintRules) validateOpts() *ach.ValidateOpts {
+	if rules == RelaxedRules {
+		return &ach.ValidateOpts{
+			BypassOriginValidation:      true,
+			BypassDestinationValidation: true,
+			CustomTraceNumbers:          true,
+			AllowZeroBatches:            true,
+		}
+	}
+	return &ach.ValidateOpts{
+		RequireABAOrigin: true,
+	}
+}
+
+// Kinds of problems Lint finds in a file.
+const (
+	LintRecord    = "record"
+	LintBatch     = "batch"
+	LintImbalance = "imbalance"
+	LintChecksum  = "checksum"
+	LintFile      = "file"
+)
+
+// Lint reads the ACH file in r and returns every problem found with its records and batches
+// rather than stopping at the first. The file's totals are only checked once all of its records
+// can be read, otherwise every batch with a problem would also show up as an imbalance.
+func Lint(r io.Reader, rules LintRules) admin.FileValidation {
+	reader := ach.NewReader(r)
+	reader.SetValidation(rules.validateOpts())
+
+	file, err := reader.Read()
+	result := admin.FileValidation{
+		Rules:      string(rules),
+		BatchCount: int32(len(file.Batches) + len(file.IATBatches)),
+		EntryCount: int32(countEntries(&file)),
+	}
+	if err != nil {
+		var el base.ErrorList
+		if errors.As(err, &el) {
+			for i := range el {
+				result.Errors = append(result.Errors, lintError(el[i]))
+			}
+		} else {
+			result.Errors = append(result.Errors, lintError(err))
+		}
+	} else if err := file.Validate(); err != nil {
+		result.Errors = append(result.Errors, lintError(err))
+	}
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+func lintError(err error) admin.FileValidationError {
+	out := admin.FileValidationError{
+		Kind: LintRecord,
+	}
+
+	var parseErr *base.ParseError
+	if errors.As(err, &parseErr) {
+		out.Line = int32(parseErr.Line)
+		out.Record = parseErr.Record
+		err = parseErr.Err
+	}
+	out.Message = err.Error()
+
+	var batchErr *ach.BatchError
+	var fileErr ach.FileError
+	var controlErr ach.ErrFileCalculatedControlEquality
+	switch {
+	case errors.As(err, &batchErr):
+		out.BatchNumber = int32(batchErr.BatchNumber)
+		out.Field = batchErr.FieldName
+		out.Kind = controlKind(batchErr.FieldName, LintBatch)
+
+	case errors.As(err, &controlErr):
+		out.Field = controlErr.Field
+		out.Kind = controlKind(controlErr.Field, LintImbalance)
+
+	case errors.As(err, &fileErr):
+		out.Field = fileErr.FieldName
+		out.Kind = LintFile
+
+	case out.Record == "":
+		// missing or duplicate headers and controls aren't read as part of a record
+		out.Kind = LintFile
+	}
+	return out
+}
+
+// controlKind returns the kind of problem with a control record's field, or fallback
+// when the field isn't a total or hash.
+func controlKind(field, fallback string) string {
+	switch field {
+	case "EntryHash":
+		return LintChecksum
+	case "TotalDebitEntryDollarAmount", "TotalCreditEntryDollarAmount", "EntryAddendaCount", "BatchCount":
+		return LintImbalance
+	}
+	return fallback
+}
+
+func countEntries(file *ach.File) int {
+	var total int
+	for i := range file.Batches {
+		total += len(file.Batches[i].GetEntries())
+	}
+	for i := range file.IATBatches {
+		total += len(file.IATBatches[i].GetEntries())
+	}
+	return total
+}
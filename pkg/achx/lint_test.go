@@ -0,0 +1,129 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+func readLintFile(t *testing.T) []string {
+	t.Helper()
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimRight(string(bs), "\n"), "\n")
+}
+
+// replace overwrites the characters of line starting at the 1-indexed position
+func replace(line string, position int, value string) string {
+	return line[:position-1] + value + line[position-1+len(value):]
+}
+
+func lint(lines []string, rules LintRules) admin.FileValidation {
+	return Lint(strings.NewReader(strings.Join(lines, "\n")), rules)
+}
+
+func TestParseLintRules(t *testing.T) {
+	if rules, err := ParseLintRules(""); err != nil || rules != StrictRules {
+		t.Errorf("rules=%q error=%v", rules, err)
+	}
+	if rules, err := ParseLintRules("relaxed"); err != nil || rules != RelaxedRules {
+		t.Errorf("rules=%q error=%v", rules, err)
+	}
+	if _, err := ParseLintRules("lenient"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestLint(t *testing.T) {
+	result := lint(readLintFile(t), StrictRules)
+	if !result.Valid || len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %#v", result.Errors)
+	}
+	if result.Rules != "strict" || result.BatchCount != 1 || result.EntryCount != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
+
+func TestLint__imbalance(t *testing.T) {
+	lines := readLintFile(t)
+	lines[3] = replace(lines[3], 21, "000000010000") // BatchControl TotalDebitEntryDollarAmount
+
+	result := lint(lines, StrictRules)
+	if result.Valid || len(result.Errors) != 1 {
+		t.Fatalf("unexpected errors: %#v", result.Errors)
+	}
+	err := result.Errors[0]
+	if err.Kind != LintImbalance || err.Line != 4 || err.BatchNumber != 1 || err.Field != "TotalDebitEntryDollarAmount" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestLint__checksum(t *testing.T) {
+	lines := readLintFile(t)
+	lines[3] = replace(lines[3], 11, "0005320002") // BatchControl EntryHash
+
+	result := lint(lines, StrictRules)
+	if result.Valid || len(result.Errors) != 1 {
+		t.Fatalf("unexpected errors: %#v", result.Errors)
+	}
+	if err := result.Errors[0]; err.Kind != LintChecksum || err.Field != "EntryHash" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestLint__records(t *testing.T) {
+	lines := readLintFile(t)
+	lines[2] = replace(lines[2], 2, "99")  // EntryDetail TransactionCode
+	lines[4] = replace(lines[4], 2, "ABC") // FileControl BatchCount
+
+	result := lint(lines, RelaxedRules)
+	if result.Valid || len(result.Errors) < 2 {
+		t.Fatalf("unexpected errors: %#v", result.Errors)
+	}
+	if err := result.Errors[0]; err.Kind != LintRecord || err.Line != 3 || err.Record != "EntryDetail" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+	if err := result.Errors[len(result.Errors)-1]; err.Line != 5 || err.Record != "FileControl" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestLint__rules(t *testing.T) {
+	lines := readLintFile(t)
+	lines[0] = replace(lines[0], 14, "ORIGIN1234") // FileHeader ImmediateOrigin
+
+	result := lint(lines, StrictRules)
+	if result.Valid || len(result.Errors) == 0 {
+		t.Fatal("expected errors")
+	}
+	if err := result.Errors[0]; err.Record != "FileHeader" || err.Line != 1 {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	result = lint(lines, RelaxedRules)
+	if !result.Valid {
+		t.Errorf("unexpected errors: %#v", result.Errors)
+	}
+}
+
+func TestLint__missingControl(t *testing.T) {
+	lines := readLintFile(t)
+
+	result := lint(lines[:4], StrictRules)
+	if result.Valid || len(result.Errors) != 1 {
+		t.Fatalf("unexpected errors: %#v", result.Errors)
+	}
+	if err := result.Errors[0]; err.Kind != LintFile {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
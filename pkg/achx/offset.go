@@ -0,0 +1,210 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// offsetSECCodes are batches which can hold an offset entry to a settlement account.
+// Batches of other SEC codes are followed by a CCD batch holding their offset.
+var offsetSECCodes = map[string]bool{
+	ach.PPD: true,
+	ach.CCD: true,
+}
+
+// AddOffsets balances file with entries to the settlement account in cfg. Each batch gets
+// an entry offsetting its debits and credits, or when cfg.PerFile is set one batch is
+// appended which offsets the entire file. Offset entries use trace numbers from traces
+// when it's non-nil.
+func AddOffsets(file *ach.File, cfg *config.Offset, odfiRoutingNumber string, traces TraceNumbers) error {
+	if file == nil || cfg == nil || len(file.Batches) == 0 {
+		return nil
+	}
+	routingNumber := util.Or(cfg.RoutingNumber, odfiRoutingNumber)
+
+	if cfg.PerFile {
+		var net int
+		for i := range file.Batches {
+			net += batchNet(file.Batches[i])
+		}
+		if net != 0 {
+			trace, err := offsetTraceNumber(traces, odfiRoutingNumber)
+			if err != nil {
+				return err
+			}
+			last := file.Batches[len(file.Batches)-1]
+			batch, err := offsetBatch(last.GetHeader(), nextBatchNumber(file), offsetEntry(cfg, routingNumber, net, trace))
+			if err != nil {
+				return err
+			}
+			file.AddBatch(batch)
+		}
+		return file.Create()
+	}
+
+	var batches []ach.Batcher
+	for i := range file.Batches {
+		batch := file.Batches[i]
+		batches = append(batches, batch)
+
+		net := batchNet(batch)
+		if net == 0 {
+			continue
+		}
+		if offsetSECCodes[batch.GetHeader().StandardEntryClassCode] {
+			trace, err := nextTraceNumber(batch)
+			if traces != nil {
+				// allocated trace numbers are after every entry already in the batch
+				trace, err = offsetTraceNumber(traces, odfiRoutingNumber)
+			}
+			if err != nil {
+				return err
+			}
+			entry := offsetEntry(cfg, routingNumber, net, trace)
+			// entries read from a file don't have a category, which must match within a batch
+			entry.Category = batch.GetEntries()[0].Category
+			batch.AddEntry(entry)
+			batch.GetHeader().ServiceClassCode = ach.MixedDebitsAndCredits
+			if err := batch.Create(); err != nil {
+				return fmt.Errorf("offset batch %d: %v", batch.GetHeader().BatchNumber, err)
+			}
+		} else {
+			trace, err := offsetTraceNumber(traces, odfiRoutingNumber)
+			if err != nil {
+				return err
+			}
+			offset, err := offsetBatch(batch.GetHeader(), 0, offsetEntry(cfg, routingNumber, net, trace))
+			if err != nil {
+				return err
+			}
+			batches = append(batches, offset)
+		}
+	}
+	file.Batches = batches
+	for i := range file.Batches {
+		file.Batches[i].GetHeader().BatchNumber = i + 1
+	}
+	return file.Create()
+}
+
+// batchNet returns the credits minus debits of a batch's entries.
+func batchNet(batch ach.Batcher) int {
+	var net int
+	entries := batch.GetEntries()
+	for i := range entries {
+		if entries[i].CreditOrDebit() == "D" {
+			net -= entries[i].Amount
+		} else {
+			net += entries[i].Amount
+		}
+	}
+	return net
+}
+
+// offsetEntry returns the entry to the settlement account which balances net.
+func offsetEntry(cfg *config.Offset, routingNumber string, net int, trace string) *ach.EntryDetail {
+	ed := ach.NewEntryDetail()
+	ed.RDFIIdentification = ABA8(routingNumber)
+	ed.CheckDigit = ABACheckDigit(routingNumber)
+	ed.DFIAccountNumber = cfg.AccountNumber
+	ed.IndividualName = cfg.Name
+	ed.IdentificationNumber = "OFFSET"
+	ed.TraceNumber = trace
+	ed.Category = ach.CategoryForward
+
+	savings := strings.EqualFold(cfg.AccountType, "savings")
+	if net > 0 {
+		// debit the settlement account for the credits we originated
+		ed.Amount = net
+		ed.TransactionCode = ach.CheckingDebit
+		if savings {
+			ed.TransactionCode = ach.SavingsDebit
+		}
+	} else {
+		ed.Amount = -net
+		ed.TransactionCode = ach.CheckingCredit
+		if savings {
+			ed.TransactionCode = ach.SavingsCredit
+		}
+	}
+	return ed
+}
+
+// offsetBatch returns a CCD batch holding entry with the company details of header.
+func offsetBatch(header *ach.BatchHeader, batchNumber int, entry *ach.EntryDetail) (ach.Batcher, error) {
+	bh := ach.NewBatchHeader()
+	bh.ServiceClassCode = ach.CreditsOnly
+	if IsDebitTransactionCode(entry.TransactionCode) {
+		bh.ServiceClassCode = ach.DebitsOnly
+	}
+	bh.StandardEntryClassCode = ach.CCD
+	bh.CompanyName = header.CompanyName
+	bh.CompanyIdentification = header.CompanyIdentification
+	bh.CompanyEntryDescription = "OFFSET"
+	bh.CompanyDescriptiveDate = header.CompanyDescriptiveDate
+	bh.EffectiveEntryDate = header.EffectiveEntryDate
+	bh.ODFIIdentification = header.ODFIIdentification
+	bh.BatchNumber = batchNumber
+
+	batch, err := ach.NewBatch(bh)
+	if err != nil {
+		return nil, fmt.Errorf("offset batch: %v", err)
+	}
+	batch.AddEntry(entry)
+	batch.SetControl(ach.NewBatchControl())
+	if err := batch.Create(); err != nil {
+		return nil, fmt.Errorf("offset batch: %v", err)
+	}
+	return batch, nil
+}
+
+// nextTraceNumber returns a trace number after every entry in batch so entries stay in
+// ascending order.
+func nextTraceNumber(batch ach.Batcher) (string, error) {
+	var max int64
+	entries := batch.GetEntries()
+	for i := range entries {
+		n, err := strconv.ParseInt(entries[i].TraceNumber, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("offset: invalid trace number %q: %v", entries[i].TraceNumber, err)
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return fmt.Sprintf("%015d", max+1), nil
+}
+
+// offsetTraceNumber allocates a trace number from traces, or returns a random one when nil.
+func offsetTraceNumber(traces TraceNumbers, odfiRoutingNumber string) (string, error) {
+	if traces == nil {
+		return TraceNumber(odfiRoutingNumber), nil
+	}
+	numbers, err := traces.Next(odfiRoutingNumber, 1)
+	if err != nil {
+		return "", fmt.Errorf("offset: allocating trace number: %v", err)
+	}
+	if len(numbers) != 1 {
+		return "", fmt.Errorf("offset: allocated %d trace numbers", len(numbers))
+	}
+	return numbers[0], nil
+}
+
+func nextBatchNumber(file *ach.File) int {
+	var max int
+	for i := range file.Batches {
+		if n := file.Batches[i].GetHeader().BatchNumber; n > max {
+			max = n
+		}
+	}
+	return max + 1
+}
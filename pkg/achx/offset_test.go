@@ -0,0 +1,113 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func readOffsetTestFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestOffsets__perBatch(t *testing.T) {
+	file := readOffsetTestFile(t)
+	cfg := &config.Offset{
+		AccountNumber: "123456",
+		AccountType:   "Checking",
+		Name:          "Settlement",
+	}
+	if err := AddOffsets(file, cfg, "076401251", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.Batches) != 1 {
+		t.Fatalf("unexpected batches: %d", len(file.Batches))
+	}
+	batch := file.Batches[0]
+	if code := batch.GetHeader().ServiceClassCode; code != ach.MixedDebitsAndCredits {
+		t.Errorf("unexpected service class code: %d", code)
+	}
+	entries := batch.GetEntries()
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries: %d", len(entries))
+	}
+	offset := entries[1]
+	if offset.TransactionCode != ach.CheckingCredit || offset.Amount != 10500 {
+		t.Errorf("offset: transaction code %d of %d", offset.TransactionCode, offset.Amount)
+	}
+	if offset.RDFIIdentification != "07640125" || offset.DFIAccountNumber != "123456" {
+		t.Errorf("offset: RDFI %s account %s", offset.RDFIIdentification, offset.DFIAccountNumber)
+	}
+	if offset.TraceNumber != "076401255655292" {
+		t.Errorf("offset trace number: %s", offset.TraceNumber)
+	}
+	if file.Control.TotalCreditEntryDollarAmountInFile != file.Control.TotalDebitEntryDollarAmountInFile {
+		t.Errorf("unbalanced file: %#v", file.Control)
+	}
+
+	// balanced batches aren't offset again
+	if err := AddOffsets(file, cfg, "076401251", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(file.Batches[0].GetEntries()); n != 2 {
+		t.Errorf("unexpected entries: %d", n)
+	}
+}
+
+func TestOffsets__perFile(t *testing.T) {
+	file := readOffsetTestFile(t)
+	cfg := &config.Offset{
+		RoutingNumber: "121042882",
+		AccountNumber: "123456",
+		AccountType:   "Savings",
+		Name:          "Settlement",
+		PerFile:       true,
+	}
+	if err := AddOffsets(file, cfg, "076401251", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.Batches) != 2 {
+		t.Fatalf("unexpected batches: %d", len(file.Batches))
+	}
+	header := file.Batches[1].GetHeader()
+	if header.StandardEntryClassCode != ach.CCD || header.ServiceClassCode != ach.CreditsOnly || header.BatchNumber != 2 {
+		t.Errorf("unexpected offset batch: %#v", header)
+	}
+	offset := file.Batches[1].GetEntries()[0]
+	if offset.TransactionCode != ach.SavingsCredit || offset.Amount != 10500 || offset.RDFIIdentification != "12104288" {
+		t.Errorf("unexpected offset: %#v", offset)
+	}
+	if file.Control.TotalCreditEntryDollarAmountInFile != file.Control.TotalDebitEntryDollarAmountInFile {
+		t.Errorf("unbalanced file: %#v", file.Control)
+	}
+}
+
+func TestOffsets__disabled(t *testing.T) {
+	file := readOffsetTestFile(t)
+	if err := AddOffsets(file, nil, "076401251", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(file.Batches[0].GetEntries()); n != 1 {
+		t.Errorf("unexpected entries: %d", n)
+	}
+}
@@ -12,17 +12,28 @@ import (
 	"github.com/moov-io/paygate/pkg/client"
 )
 
+// createPPDBatch creates a batch of PPD entries, or WEB, CCD and CTX entries which share their
+// layout. WEB entries carry a payment type instead of discretionary data and CTX entries carry
+// the count of their addenda records in front of the receiver's name.
 func createPPDBatch(id string, options Options, xfer *client.Transfer, source Source, destination Destination) (ach.Batcher, error) {
+	secCode := StandardEntryClassCode(xfer.StandardEntryClassCode)
+
 	bh := makeBatchHeader(id, options, xfer, source)
-	bh.StandardEntryClassCode = ach.PPD
+	bh.StandardEntryClassCode = secCode
 
 	// Create PPD batch
 	batch, err := ach.NewBatch(bh)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create PPD batch: %v", err)
+		return nil, fmt.Errorf("failed to create %s batch: %v", secCode, err)
 	}
 
 	entry := createPPDEntry(id, options, xfer, source, destination)
+	if secCode == ach.WEB {
+		entry.SetPaymentType(webPaymentType(xfer))
+	}
+	if secCode == ach.CTX {
+		setCTXReceiver(entry)
+	}
 	batch.AddEntry(entry)
 
 	if options.FileConfig.BalanceEntries {
@@ -30,6 +41,12 @@ func createPPDBatch(id string, options Options, xfer *client.Transfer, source So
 		if err != nil {
 			return nil, fmt.Errorf("problem balancing entry: %#v", err)
 		}
+		if secCode == ach.WEB {
+			balance.SetPaymentType(webPaymentType(xfer))
+		}
+		if secCode == ach.CTX {
+			setCTXReceiver(balance)
+		}
 		batch.AddEntry(balance)
 	}
 
@@ -41,6 +58,23 @@ func createPPDBatch(id string, options Options, xfer *client.Transfer, source So
 	return batch, nil
 }
 
+// webPaymentType returns R for debits under a mandate, which the receiver authorized to recur,
+// and S for single entries.
+func webPaymentType(xfer *client.Transfer) string {
+	if xfer.MandateID != "" {
+		return "R"
+	}
+	return "S"
+}
+
+// setCTXReceiver moves the receiver's name of a CTX entry behind the count of its Addenda05
+// records, which CTX entries hold in the individual name field.
+func setCTXReceiver(ed *ach.EntryDetail) {
+	name := ed.IndividualName
+	ed.SetCATXAddendaRecords(len(ed.Addenda05))
+	ed.SetCATXReceivingCompany(name)
+}
+
 func createPPDEntry(id string, options Options, xfer *client.Transfer, src Source, dst Destination) *ach.EntryDetail {
 	ed := ach.NewEntryDetail()
 	ed.ID = id
@@ -68,8 +102,20 @@ func createPPDEntry(id string, options Options, xfer *client.Transfer, src Sourc
 		ed.IndividualName = fmt.Sprintf("%s %s", src.Customer.FirstName, src.Customer.LastName)
 	}
 
-	// Add the Addenda05 record if we're configured to do so
-	if options.FileConfig.Addendum.Create05 {
+	// Add remittance from the Transfer, otherwise the Addenda05 record if we're configured to do so
+	if records := RemittanceInformation(xfer.Remittance); len(records) > 0 {
+		ed.AddendaRecordIndicator = 1
+
+		for i := range records {
+			addenda05 := ach.NewAddenda05()
+			addenda05.ID = id
+			addenda05.PaymentRelatedInformation = records[i]
+			addenda05.SequenceNumber = i + 1
+			addenda05.EntryDetailSequenceNumber = 1
+
+			ed.AddAddenda05(addenda05)
+		}
+	} else if options.FileConfig.Addendum.Create05 {
 		ed.AddendaRecordIndicator = 1
 
 		addenda05 := ach.NewAddenda05()
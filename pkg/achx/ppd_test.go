@@ -5,8 +5,11 @@
 package achx
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	customers "github.com/moov-io/customers/pkg/client"
 	"github.com/moov-io/paygate/pkg/client"
@@ -117,3 +120,153 @@ func TestPPD__offset(t *testing.T) {
 		t.Errorf("offset.Addenda05[0].PaymentRelatedInformation: %q", offset.Addenda05[0].PaymentRelatedInformation)
 	}
 }
+
+func TestPPD__webBatch(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber: "123456780",
+		CutoffTimezone:    time.UTC,
+		FileConfig: config.FileConfig{
+			BalanceEntries: true,
+		},
+		CompanyIdentification: "MOOVZZZZZZ",
+	}
+	src := Source{
+		Customer:      customers.Customer{FirstName: "Jane", LastName: "Doe"},
+		Account:       customers.Account{RoutingNumber: "987654320", Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "1234567",
+	}
+	dst := Destination{
+		Customer:      customers.Customer{FirstName: "John", LastName: "Doe"},
+		Account:       customers.Account{RoutingNumber: opts.ODFIRoutingNumber, Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "7654321",
+	}
+	xfer := &client.Transfer{
+		Description:            "gym",
+		Amount:                 client.Amount{Currency: "USD", Value: 2500},
+		StandardEntryClassCode: ach.WEB,
+		MandateID:              base.ID(),
+	}
+
+	batch, err := createPPDBatch(base.ID(), opts, xfer, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sec := batch.GetHeader().StandardEntryClassCode; sec != ach.WEB {
+		t.Errorf("unexpected SEC code: %s", sec)
+	}
+	for _, ed := range batch.GetEntries() {
+		if ed.PaymentTypeField() != "R" {
+			t.Errorf("unexpected payment type: %q", ed.DiscretionaryData)
+		}
+	}
+
+	// WEB entries without a mandate are single entries
+	xfer.MandateID = ""
+	batch, err = createPPDBatch(base.ID(), opts, xfer, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ed := batch.GetEntries()[0]; ed.PaymentTypeField() != "S" {
+		t.Errorf("unexpected payment type: %q", ed.DiscretionaryData)
+	}
+}
+
+func TestPPD__ccdBatch(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:     "123456780",
+		CutoffTimezone:        time.UTC,
+		CompanyIdentification: "MOOVZZZZZZ",
+	}
+	src := Source{
+		Customer:      customers.Customer{FirstName: "Acme", LastName: "Corp"},
+		Account:       customers.Account{RoutingNumber: opts.ODFIRoutingNumber, Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "1234567",
+	}
+	dst := Destination{
+		Customer:      customers.Customer{FirstName: "Widget", LastName: "Supply"},
+		Account:       customers.Account{RoutingNumber: "987654320", Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "7654321",
+	}
+	xfer := &client.Transfer{
+		Description:            "invoice",
+		Amount:                 client.Amount{Currency: "USD", Value: 2500},
+		StandardEntryClassCode: ach.CCD,
+		Remittance:             &client.Remittance{InvoiceNumbers: []string{"1001"}},
+	}
+
+	batch, err := createPPDBatch(base.ID(), opts, xfer, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := batch.(*ach.BatchCCD); !ok {
+		t.Fatalf("unexpected batch: %T", batch)
+	}
+	ed := batch.GetEntries()[0]
+	if ed.IndividualName != "Widget Supply" || len(ed.Addenda05) != 1 || ed.Addenda05[0].PaymentRelatedInformation != "INV 1001" {
+		t.Errorf("unexpected entry: %#v", ed)
+	}
+}
+
+func TestPPD__ctxBatch(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber: "123456780",
+		CutoffTimezone:    time.UTC,
+		FileConfig: config.FileConfig{
+			BalanceEntries: true,
+		},
+		CompanyIdentification: "MOOVZZZZZZ",
+	}
+	src := Source{
+		Customer:      customers.Customer{FirstName: "Acme", LastName: "Corp"},
+		Account:       customers.Account{RoutingNumber: opts.ODFIRoutingNumber, Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "1234567",
+	}
+	dst := Destination{
+		Customer:      customers.Customer{FirstName: "Widget", LastName: "Supply Company"},
+		Account:       customers.Account{RoutingNumber: "987654320", Type: customers.ACCOUNTTYPE_CHECKING},
+		AccountNumber: "7654321",
+	}
+	xfer := &client.Transfer{
+		Description:            "invoices",
+		Amount:                 client.Amount{Currency: "USD", Value: 2500},
+		StandardEntryClassCode: ach.CTX,
+		Remittance: &client.Remittance{
+			InvoiceNumbers: []string{"1001", "1002"},
+			Memo:           strings.Repeat("M", 200),
+		},
+	}
+	if err := ValidateRemittance(xfer.Remittance, ach.CTX); err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := createPPDBatch(base.ID(), opts, xfer, src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := batch.(*ach.BatchCTX); !ok {
+		t.Fatalf("unexpected batch: %T", batch)
+	}
+	entries := batch.GetEntries()
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+
+	// the remittance is split across addenda records which are counted in front of the receiver
+	ed := entries[0]
+	if len(ed.Addenda05) != 3 || ed.CATXAddendaRecordsField() != "0003" || ed.CATXReceivingCompanyField() != "Widget Supply Co" {
+		t.Errorf("unexpected entry: %q with %d addenda", ed.IndividualName, len(ed.Addenda05))
+	}
+	for i := range ed.Addenda05 {
+		if ed.Addenda05[i].SequenceNumber != i+1 {
+			t.Errorf("addenda #%d has sequence number %d", i, ed.Addenda05[i].SequenceNumber)
+		}
+	}
+	if info := ed.Addenda05[0].PaymentRelatedInformation; !strings.HasPrefix(info, "INV 1001,1002 MMM") || len(info) != 80 {
+		t.Errorf("unexpected PaymentRelatedInformation: %q", info)
+	}
+
+	// the balancing entry has no remittance
+	if balance := entries[1]; len(balance.Addenda05) != 0 || balance.CATXAddendaRecordsField() != "0000" {
+		t.Errorf("unexpected balance entry: %q with %d addenda", balance.IndividualName, len(balance.Addenda05))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// paymentRelatedInformationLength is the size of an Addenda05 record's free-form field.
+const paymentRelatedInformationLength = 80
+
+// maxAddenda05 is how many Addenda05 records NACHA allows on an entry of each SEC code.
+var maxAddenda05 = map[string]int{
+	ach.ARC: 0,
+	ach.BOC: 0,
+	ach.CCD: 1,
+	ach.POP: 0,
+	ach.PPD: 1,
+	ach.RCK: 0,
+	ach.WEB: 1,
+	ach.CTX: 9999,
+}
+
+// RemittanceInformation returns the PaymentRelatedInformation of each Addenda05 record
+// needed to send rem. Invoice numbers are listed first, followed by the memo.
+func RemittanceInformation(rem *client.Remittance) []string {
+	if rem == nil {
+		return nil
+	}
+	var parts []string
+	if len(rem.InvoiceNumbers) > 0 {
+		parts = append(parts, "INV "+strings.Join(rem.InvoiceNumbers, ","))
+	}
+	if memo := strings.TrimSpace(rem.Memo); memo != "" {
+		parts = append(parts, memo)
+	}
+	info := strings.Join(parts, " ")
+
+	var records []string
+	for len(info) > paymentRelatedInformationLength {
+		records = append(records, info[:paymentRelatedInformationLength])
+		info = info[paymentRelatedInformationLength:]
+	}
+	if info != "" {
+		records = append(records, info)
+	}
+	return records
+}
+
+// ValidateRemittance returns an error if rem can't be sent in the Addenda05 records
+// allowed on an entry with the given SEC code.
+func ValidateRemittance(rem *client.Remittance, secCode string) error {
+	if rem == nil {
+		return nil
+	}
+	for i := range rem.InvoiceNumbers {
+		if rem.InvoiceNumbers[i] == "" || strings.ContainsAny(rem.InvoiceNumbers[i], ", ") {
+			return fmt.Errorf("invalid invoice number %q", rem.InvoiceNumbers[i])
+		}
+	}
+	records := RemittanceInformation(rem)
+	if len(records) == 0 {
+		return errors.New("missing invoiceNumbers or memo")
+	}
+	for i := range records {
+		for _, r := range records[i] {
+			if r < ' ' || r > '~' {
+				return fmt.Errorf("unsupported character %q", r)
+			}
+		}
+	}
+	if max := maxAddenda05[secCode]; len(records) > max {
+		return fmt.Errorf("%s entries allow %d characters of remittance, got %d", secCode, max*paymentRelatedInformationLength, len(strings.Join(records, "")))
+	}
+	return nil
+}
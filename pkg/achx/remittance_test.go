@@ -0,0 +1,92 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestRemittanceInformation(t *testing.T) {
+	if records := RemittanceInformation(nil); len(records) != 0 {
+		t.Errorf("unexpected records: %v", records)
+	}
+
+	rem := &client.Remittance{
+		InvoiceNumbers: []string{"INV-1001", "INV-1002"},
+		Memo:           " March services ",
+	}
+	records := RemittanceInformation(rem)
+	if len(records) != 1 || records[0] != "INV INV-1001,INV-1002 March services" {
+		t.Errorf("unexpected records: %q", records)
+	}
+
+	rem.Memo = strings.Repeat("A", 100)
+	records = RemittanceInformation(rem)
+	if len(records) != 2 || len(records[0]) != 80 || len(records[1]) != 42 {
+		t.Errorf("unexpected records: %q", records)
+	}
+}
+
+func TestValidateRemittance(t *testing.T) {
+	if err := ValidateRemittance(nil, ach.PPD); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateRemittance(&client.Remittance{Memo: "March services"}, ach.PPD); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateRemittance(&client.Remittance{}, ach.PPD); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateRemittance(&client.Remittance{InvoiceNumbers: []string{"1001,1002"}}, ach.PPD); err == nil {
+		t.Error("expected error")
+	}
+	if err := ValidateRemittance(&client.Remittance{Memo: "café"}, ach.PPD); err == nil {
+		t.Error("expected error")
+	}
+
+	// only CTX entries can have more than one record
+	long := &client.Remittance{Memo: strings.Repeat("A", 81)}
+	if err := ValidateRemittance(long, ach.PPD); err == nil || !strings.Contains(err.Error(), "allow 80 characters") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateRemittance(long, ach.CTX); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPPD__remittance(t *testing.T) {
+	xfer := &client.Transfer{
+		Description: "PAYROLL",
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    10000,
+		},
+		Remittance: &client.Remittance{
+			InvoiceNumbers: []string{"1001"},
+		},
+	}
+	src := Source{
+		Account:       customers.Account{RoutingNumber: "987654320"},
+		AccountNumber: "98765",
+	}
+	dst := Destination{
+		Account:       customers.Account{RoutingNumber: "123456780"},
+		AccountNumber: "12345",
+	}
+
+	ed := createPPDEntry(base.ID(), Options{ODFIRoutingNumber: "987654320"}, xfer, src, dst)
+	if ed.AddendaRecordIndicator != 1 || len(ed.Addenda05) != 1 {
+		t.Fatalf("unexpected addenda: %#v", ed.Addenda05)
+	}
+	if v := ed.Addenda05[0].PaymentRelatedInformation; v != "INV 1001" {
+		t.Errorf("unexpected PaymentRelatedInformation: %q", v)
+	}
+}
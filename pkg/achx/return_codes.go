@@ -0,0 +1,79 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type returnAdvice struct {
+	action     string
+	reinitiate bool
+}
+
+// defaultReturnAction is recommended for return codes without their own advice.
+const defaultReturnAction = "Contact your ODFI about this return before sending the entry again."
+
+// returnCodeAdvice holds what an originator should do after a return. Reinitiation follows the
+// NACHA rules: only insufficient and uncollected funds can be sent again unchanged, other returns
+// need the account corrected or a new authorization from the customer.
+var returnCodeAdvice = map[string]returnAdvice{
+	"R01": {action: "Reinitiate up to two times within 180 days of the original settlement date, or ask the customer for another account.", reinitiate: true},
+	"R02": {action: "Ask the customer for a new account. Entries to this account will be returned."},
+	"R03": {action: "Verify the account number and name with the customer, then create a new Transfer with the corrected account."},
+	"R04": {action: "Verify the account number with the customer, then create a new Transfer with the corrected account."},
+	"R05": {action: "Get a new authorization from the customer before debiting their account again."},
+	"R06": {action: "Contact your ODFI, which asked for this entry to be returned."},
+	"R07": {action: "Stop debiting this account unless the customer gives a new authorization."},
+	"R08": {action: "Contact the customer. Only send the entry again with a new authorization from them."},
+	"R09": {action: "Reinitiate up to two times within 180 days of the original settlement date, or ask the customer for another account.", reinitiate: true},
+	"R10": {action: "Get a new authorization from the customer before debiting their account again."},
+	"R11": {action: "Correct the entry to match the customer's authorization and create a new Transfer within 60 days of the return's settlement date."},
+	"R12": {action: "Ask the customer for the routing number of the financial institution which bought their branch."},
+	"R13": {action: "Verify the routing number with the customer, then create a new Transfer with the corrected account."},
+	"R14": {action: "Stop sending entries to this account and contact the customer's representative."},
+	"R15": {action: "Stop sending entries to this account."},
+	"R16": {action: "Contact the customer, entries to this account are blocked by their financial institution."},
+	"R17": {action: "Correct the fields described in the return and create a new Transfer."},
+	"R18": {action: "Contact your ODFI, the entry's effective date was invalid."},
+	"R19": {action: "Correct the amount and create a new Transfer."},
+	"R20": {action: "Ask the customer for a checking or savings account which accepts ACH entries."},
+	"R21": {action: "Verify the company identification with the customer."},
+	"R22": {action: "Verify the customer's identification number with them."},
+	"R23": {action: "Contact the customer before sending them funds again."},
+	"R24": {action: "Check whether the Transfer was already sent before creating it again."},
+	"R26": {action: "Contact your ODFI, a mandatory field of the entry was missing."},
+	"R28": {action: "Verify the routing number with the customer, then create a new Transfer with the corrected account."},
+	"R29": {action: "Get a new authorization from the customer before debiting their account again."},
+	"R31": {action: "Contact the customer, their financial institution returned the entry with their agreement."},
+	"R34": {action: "Ask the customer for an account at another financial institution."},
+	"R37": {action: "Contact the customer, the check was also presented for payment."},
+	"R38": {action: "Contact the customer, they stopped payment on the check."},
+	"R39": {action: "Contact the customer, the check can't be converted into an ACH entry."},
+	"R51": {action: "Get a new authorization from the customer before debiting their account again."},
+}
+
+// ReturnCode explains a NACHA return code along with what the originator should do about it.
+// Nil is returned for unknown codes.
+func ReturnCode(code string) *client.ReturnCode {
+	rc := ach.LookupReturnCode(strings.ToUpper(code))
+	if rc == nil {
+		return nil
+	}
+	out := &client.ReturnCode{
+		Code:        rc.Code,
+		Reason:      rc.Reason,
+		Description: rc.Description,
+		Action:      defaultReturnAction,
+	}
+	if advice, ok := returnCodeAdvice[rc.Code]; ok {
+		out.Action = advice.action
+		out.Reinitiate = advice.reinitiate
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"testing"
+
+	"github.com/moov-io/ach"
+)
+
+func TestReturnCode(t *testing.T) {
+	rc := ReturnCode("r01")
+	if rc == nil || rc.Code != "R01" || rc.Reason == "" || rc.Description == "" {
+		t.Fatalf("unexpected return code: %#v", rc)
+	}
+	if !rc.Reinitiate || rc.Action == defaultReturnAction {
+		t.Errorf("unexpected advice: %#v", rc)
+	}
+
+	rc = ReturnCode("R02")
+	if rc == nil || rc.Reinitiate {
+		t.Errorf("unexpected return code: %#v", rc)
+	}
+
+	if rc := ReturnCode("R99"); rc != nil {
+		t.Errorf("unexpected return code: %#v", rc)
+	}
+}
+
+func TestReturnCode__advice(t *testing.T) {
+	for code := range returnCodeAdvice {
+		if ach.LookupReturnCode(code) == nil {
+			t.Errorf("%s isn't a NACHA return code", code)
+		}
+	}
+}
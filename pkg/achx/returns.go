@@ -0,0 +1,113 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// ReturnEntry creates a file which sends a forward entry we received as the RDFI back to
+// its ODFI with the given return code. The return keeps the original batch's company
+// details and references the entry by its trace number. It's originated by the entry's
+// RDFI, which can be any of our RDFI routing numbers rather than options.ODFIRoutingNumber.
+func ReturnEntry(id string, options Options, bh *ach.BatchHeader, entry *ach.EntryDetail, code string) (*ach.File, error) {
+	if bh == nil || entry == nil {
+		return nil, errors.New("missing entry to return")
+	}
+	if ach.LookupReturnCode(strings.ToUpper(code)) == nil {
+		return nil, fmt.Errorf("unknown return code %q", code)
+	}
+	txCode, err := returnTransactionCode(entry.TransactionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	file, now := ach.NewFile(), time.Now().In(options.CutoffTimezone)
+	file.ID = id
+	file.Control = ach.NewFileControl()
+
+	file.Header.ID = id
+	file.Header.ImmediateOrigin = determineOrigin(options)
+	file.Header.ImmediateOriginName = options.Gateway.OriginName
+	file.Header.ImmediateDestinationName = options.Gateway.DestinationName
+	file.Header.FileCreationDate = now.Format("060102") // YYMMDD
+	file.Header.FileCreationTime = now.Format("1504")   // HHMM
+
+	// Copy the original batch details into the return
+	header := ach.NewBatchHeader()
+	header.ID = id
+	header.ServiceClassCode = bh.ServiceClassCode
+	header.StandardEntryClassCode = bh.StandardEntryClassCode
+	header.CompanyName = bh.CompanyName
+	header.CompanyIdentification = bh.CompanyIdentification
+	header.CompanyEntryDescription = bh.CompanyEntryDescription
+	header.EffectiveEntryDate = options.EffectiveEntryDate.Format("060102") // Date to be posted, YYMMDD
+	header.ODFIIdentification = entry.RDFIIdentification
+
+	ed := ach.NewEntryDetail()
+	ed.ID = id
+	ed.TransactionCode = txCode
+	ed.RDFIIdentification = bh.ODFIIdentification
+	ed.CheckDigit = strconv.Itoa(ed.CalculateCheckDigit(ed.RDFIIdentification))
+	ed.DFIAccountNumber = entry.DFIAccountNumber
+	ed.Amount = entry.Amount
+	ed.IdentificationNumber = entry.IdentificationNumber
+	ed.IndividualName = entry.IndividualName
+	ed.DiscretionaryData = entry.DiscretionaryData
+	ed.TraceNumber = TraceNumber(entry.RDFIIdentification)
+	ed.Category = ach.CategoryReturn
+
+	addenda := ach.NewAddenda99()
+	addenda.ReturnCode = strings.ToUpper(code)
+	addenda.OriginalTrace = entry.TraceNumber
+	addenda.OriginalDFI = bh.ODFIIdentification
+	addenda.TraceNumber = ed.TraceNumber
+	ed.Addenda99 = addenda
+	ed.AddendaRecordIndicator = 1
+
+	file.Header.ImmediateDestination = util.Or(options.Gateway.Destination, ed.RDFIIdentification+ed.CheckDigit)
+
+	batch, err := ach.NewBatch(header)
+	if err != nil {
+		return nil, err
+	}
+	batch.AddEntry(ed)
+	batch.SetControl(ach.NewBatchControl())
+	if err := batch.Create(); err != nil {
+		return nil, fmt.Errorf("return batch: %v", err)
+	}
+	if options.TraceNumbers != nil {
+		if err := assignTraceNumbers(options.TraceNumbers, entry.RDFIIdentification, batch); err != nil {
+			return nil, fmt.Errorf("return batch: %v", err)
+		}
+	}
+	file.AddBatch(batch)
+
+	if err := file.Create(); err != nil {
+		return file, err
+	}
+	return file, file.Validate()
+}
+
+// returnTransactionCode returns the code used to return a forward entry, which for each
+// account type is one less than the forward credit or debit code.
+func returnTransactionCode(code int) (int, error) {
+	switch code {
+	case
+		ach.CheckingCredit, ach.CheckingDebit,
+		ach.SavingsCredit, ach.SavingsDebit,
+		ach.GLCredit, ach.GLDebit,
+		ach.LoanCredit, ach.LoanDebit:
+		return code - 1, nil
+	}
+	return 0, fmt.Errorf("unable to return transaction code %d", code)
+}
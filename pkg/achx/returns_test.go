@@ -0,0 +1,126 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+)
+
+func TestReturnEntry(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bh, entry := file.Batches[0].GetHeader(), file.Batches[0].GetEntries()[0]
+
+	opts := Options{
+		ODFIRoutingNumber:  "053200019",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.Now(time.UTC),
+	}
+	returned, err := ReturnEntry(base.ID(), opts, bh, entry, "R03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returned.Header.ImmediateDestination != "076401251" {
+		t.Errorf("ImmediateDestination=%q", returned.Header.ImmediateDestination)
+	}
+
+	header := returned.Batches[0].GetHeader()
+	if header.CompanyName != bh.CompanyName || header.ODFIIdentification != "05320001" {
+		t.Errorf("unexpected batch header: %#v", header)
+	}
+
+	entries := returned.Batches[0].GetEntries()
+	if len(entries) != 1 || entries[0].Category != ach.CategoryReturn {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+	ed := entries[0]
+	if ed.TransactionCode != ach.CheckingReturnNOCDebit || ed.RDFIIdentification != "07640125" || ed.Amount != entry.Amount {
+		t.Errorf("unexpected entry: %#v", ed)
+	}
+	if ed.Addenda99 == nil || ed.Addenda99.ReturnCode != "R03" || ed.Addenda99.OriginalTrace != entry.TraceNumber {
+		t.Errorf("unexpected addenda: %#v", ed.Addenda99)
+	}
+}
+
+func TestReturnEntry__RDFIRoutingNumber(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bh, entry := file.Batches[0].GetHeader(), file.Batches[0].GetEntries()[0]
+
+	// the entry was received on an RDFI routing number which isn't the ODFI's
+	opts := Options{
+		ODFIRoutingNumber:  "231380104",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.Now(time.UTC),
+	}
+	returned, err := ReturnEntry(base.ID(), opts, bh, entry, "R03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := returned.Batches[0].GetHeader(); header.ODFIIdentification != "05320001" {
+		t.Errorf("ODFIIdentification=%q", header.ODFIIdentification)
+	}
+	ed := returned.Batches[0].GetEntries()[0]
+	if !strings.HasPrefix(ed.TraceNumber, "05320001") || ed.Addenda99.TraceNumber != ed.TraceNumber {
+		t.Errorf("TraceNumber=%q addenda=%q", ed.TraceNumber, ed.Addenda99.TraceNumber)
+	}
+}
+
+func TestReturnEntry__TraceNumbers(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bh, entry := file.Batches[0].GetHeader(), file.Batches[0].GetEntries()[0]
+
+	opts := Options{
+		ODFIRoutingNumber:  "231380104",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.Now(time.UTC),
+		TraceNumbers:       &sequentialTraceNumbers{next: 41},
+	}
+	returned, err := ReturnEntry(base.ID(), opts, bh, entry, "R03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed := returned.Batches[0].GetEntries()[0]
+	if ed.TraceNumber != "053200010000042" || ed.Addenda99.TraceNumber != ed.TraceNumber {
+		t.Errorf("TraceNumber=%q addenda=%q", ed.TraceNumber, ed.Addenda99.TraceNumber)
+	}
+}
+
+func TestReturnEntryErr(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bh, entry := file.Batches[0].GetHeader(), file.Batches[0].GetEntries()[0]
+
+	opts := Options{
+		ODFIRoutingNumber: "053200019",
+		CutoffTimezone:    time.UTC,
+	}
+	if _, err := ReturnEntry(base.ID(), opts, bh, entry, "R99"); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := ReturnEntry(base.ID(), opts, nil, entry, "R03"); err == nil {
+		t.Error("expected error")
+	}
+
+	entry.TransactionCode = ach.CheckingPrenoteDebit
+	if _, err := ReturnEntry(base.ID(), opts, bh, entry, "R03"); err == nil {
+		t.Error("expected error")
+	}
+}
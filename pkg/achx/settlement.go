@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/util"
+)
+
+// SettlementDate returns the latest effective entry date of the batches in files as YYYY-MM-DD,
+// which is the day their entries are expected to settle. It's empty when no batch has one.
+func SettlementDate(files []*ach.File) string {
+	var latest time.Time
+	for i := range files {
+		if files[i] == nil {
+			continue
+		}
+		for j := range files[i].Batches {
+			bh := files[i].Batches[j].GetHeader()
+			if bh == nil {
+				continue
+			}
+			when, err := time.Parse("060102", bh.EffectiveEntryDate)
+			if err != nil {
+				continue
+			}
+			if when.After(latest) {
+				latest = when
+			}
+		}
+	}
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.Format(util.YYMMDDTimeFormat)
+}
+
+// AddBankingDays returns the banking day days after date, both as YYYY-MM-DD. It's empty
+// when date isn't a valid date.
+func AddBankingDays(date string, days int) string {
+	when, err := time.Parse(util.YYMMDDTimeFormat, date)
+	if err != nil {
+		return ""
+	}
+	day := base.NewTime(when)
+	for i := 0; i < days; i++ {
+		day = day.AddBankingDay(1)
+	}
+	return day.Format(util.YYMMDDTimeFormat)
+}
+
+// BankingDaysBetween returns how many banking days are between two YYYY-MM-DD dates, which
+// is zero when to isn't after from.
+func BankingDaysBetween(from, to string) int {
+	start, err := time.Parse(util.YYMMDDTimeFormat, from)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(util.YYMMDDTimeFormat, to)
+	if err != nil {
+		return 0
+	}
+	var days int
+	for day := base.NewTime(start); day.Before(end); days++ {
+		day = day.AddBankingDay(1)
+	}
+	return days
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"testing"
+
+	"github.com/moov-io/ach"
+)
+
+func TestSettlementDate(t *testing.T) {
+	if date := SettlementDate(nil); date != "" {
+		t.Errorf("unexpected date: %q", date)
+	}
+
+	batch := func(effectiveEntryDate string) ach.Batcher {
+		bh := ach.NewBatchHeader()
+		bh.EffectiveEntryDate = effectiveEntryDate
+		return ach.NewBatchPPD(bh)
+	}
+	first, second := ach.NewFile(), ach.NewFile()
+	first.AddBatch(batch("201110"))
+	second.AddBatch(batch("201112"))
+	second.AddBatch(batch(""))
+
+	if date := SettlementDate([]*ach.File{first, second}); date != "2020-11-12" {
+		t.Errorf("unexpected date: %q", date)
+	}
+}
+
+func TestAddBankingDays(t *testing.T) {
+	// Friday before Veterans Day on Wednesday
+	if date := AddBankingDays("2020-11-06", 3); date != "2020-11-12" {
+		t.Errorf("unexpected date: %q", date)
+	}
+	if date := AddBankingDays("2020-11-06", 0); date != "2020-11-06" {
+		t.Errorf("unexpected date: %q", date)
+	}
+	if date := AddBankingDays("", 3); date != "" {
+		t.Errorf("unexpected date: %q", date)
+	}
+}
+
+func TestBankingDaysBetween(t *testing.T) {
+	if n := BankingDaysBetween("2020-11-06", "2020-11-12"); n != 3 {
+		t.Errorf("unexpected days: %d", n)
+	}
+	if n := BankingDaysBetween("2020-11-12", "2020-11-06"); n != 0 {
+		t.Errorf("unexpected days: %d", n)
+	}
+	if n := BankingDaysBetween("", "2020-11-06"); n != 0 {
+		t.Errorf("unexpected days: %d", n)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// PreviewStatement returns the descriptors of xfer's entry as the receiver's bank reads them from
+// the file ConstructFile would create, cut to the length of each NACHA field. The receiver is the
+// destination customer of credits and the source customer of debits.
+func PreviewStatement(options Options, xfer *client.Transfer, source Source, destination Destination) client.StatementPreview {
+	secCode := StandardEntryClassCode(xfer.StandardEntryClassCode)
+
+	bh := makeBatchHeader(xfer.TransferID, options, xfer, source)
+	if secCode == ach.RCK {
+		bh.CompanyEntryDescription = "REDEPCHECK" // required by NACHA
+	}
+	ed := createPPDEntry(xfer.TransferID, options, xfer, source, destination)
+	individualName := fieldValue(ed.IndividualNameField())
+	if secCode == ach.CTX {
+		name := ed.IndividualName
+		setCTXReceiver(ed)
+		individualName = fieldValue(ed.CATXReceivingCompanyField())
+		ed.IndividualName = name
+	}
+
+	preview := client.StatementPreview{
+		StandardEntryClassCode:   secCode,
+		CompanyName:              fieldValue(bh.CompanyNameField()),
+		CompanyEntryDescription:  fieldValue(bh.CompanyEntryDescriptionField()),
+		CompanyDescriptiveDate:   fieldValue(bh.CompanyDescriptiveDateField()),
+		CompanyDiscretionaryData: fieldValue(bh.CompanyDiscretionaryDataField()),
+		IndividualName:           individualName,
+		EffectiveEntryDate:       options.EffectiveEntryDate.Format("2006-01-02"),
+	}
+	for _, field := range []struct {
+		name, value, written string
+	}{
+		{"companyName", bh.CompanyName, preview.CompanyName},
+		{"companyEntryDescription", bh.CompanyEntryDescription, preview.CompanyEntryDescription},
+		{"companyDiscretionaryData", bh.CompanyDiscretionaryData, preview.CompanyDiscretionaryData},
+		{"individualName", ed.IndividualName, preview.IndividualName},
+	} {
+		if strings.TrimSpace(field.value) != field.written {
+			preview.Truncated = append(preview.Truncated, field.name)
+		}
+	}
+	return preview
+}
+
+// fieldValue removes the padding of a fixed width field
+func fieldValue(field string) string {
+	return strings.TrimSpace(field)
+}
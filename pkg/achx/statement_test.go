@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestPreviewStatement(t *testing.T) {
+	opts := Options{
+		ODFIRoutingNumber:  "987654320",
+		CutoffTimezone:     time.UTC,
+		EffectiveEntryDate: base.NewTime(time.Date(2020, time.November, 12, 10, 0, 0, 0, time.UTC)),
+	}
+	xfer := &client.Transfer{
+		Description: "Monthly payroll",
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    10000,
+		},
+	}
+	src := Source{
+		Customer: customers.Customer{FirstName: "Acme", LastName: "Corporation Incorporated"},
+		Account:  customers.Account{RoutingNumber: "987654320"},
+	}
+	dst := Destination{
+		Customer: customers.Customer{FirstName: "Jane", LastName: "Doe"},
+		Account:  customers.Account{RoutingNumber: "123456780"},
+	}
+
+	// credits show the destination's name
+	preview := PreviewStatement(opts, xfer, src, dst)
+	if preview.StandardEntryClassCode != ach.PPD || preview.EffectiveEntryDate != "2020-11-12" {
+		t.Errorf("unexpected preview: %#v", preview)
+	}
+	if preview.CompanyName != "Acme Corporation" {
+		t.Errorf("CompanyName=%q", preview.CompanyName)
+	}
+	if preview.CompanyEntryDescription != "Monthly pa" {
+		t.Errorf("CompanyEntryDescription=%q", preview.CompanyEntryDescription)
+	}
+	if preview.IndividualName != "Jane Doe" {
+		t.Errorf("IndividualName=%q", preview.IndividualName)
+	}
+	if len(preview.Truncated) != 2 || preview.Truncated[0] != "companyName" || preview.Truncated[1] != "companyEntryDescription" {
+		t.Errorf("Truncated=%v", preview.Truncated)
+	}
+
+	// debits show the source's name and overrides are preferred
+	opts.FileConfig = config.FileConfig{CompanyName: "Acme"}
+	xfer.CompanyEntryDescription = "PAYROLL"
+	xfer.CompanyDiscretionaryData = "Invoice 1234"
+	xfer.SameDay = true
+	preview = PreviewStatement(opts, xfer, Source{Customer: dst.Customer, Account: dst.Account}, Destination{Customer: src.Customer, Account: src.Account})
+	if preview.CompanyName != "Acme" || preview.CompanyEntryDescription != "PAYROLL" || preview.CompanyDiscretionaryData != "Invoice 1234" {
+		t.Errorf("unexpected preview: %#v", preview)
+	}
+	if preview.IndividualName != "Jane Doe" || preview.CompanyDescriptiveDate[:2] != "SD" || len(preview.Truncated) != 0 {
+		t.Errorf("unexpected preview: %#v", preview)
+	}
+
+	// RCK entries always use REDEPCHECK
+	xfer.StandardEntryClassCode = ach.RCK
+	if preview = PreviewStatement(opts, xfer, src, dst); preview.CompanyEntryDescription != "REDEPCHECK" {
+		t.Errorf("CompanyEntryDescription=%q", preview.CompanyEntryDescription)
+	}
+
+	// CTX entries have 16 characters for the receiver's name
+	xfer.StandardEntryClassCode = ach.CTX
+	preview = PreviewStatement(opts, xfer, Source{Customer: src.Customer, Account: dst.Account}, Destination{Customer: dst.Customer, Account: src.Account})
+	if preview.IndividualName != "Acme Corporation" || len(preview.Truncated) != 1 || preview.Truncated[0] != "individualName" {
+		t.Errorf("unexpected preview: %#v", preview)
+	}
+}
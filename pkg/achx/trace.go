@@ -9,8 +9,36 @@ import (
 	"fmt"
 	"math/big"
 	"unicode/utf8"
+
+	"github.com/moov-io/ach"
 )
 
+// TraceNumbers allocates trace numbers which are unique for an ODFI's routing number.
+type TraceNumbers interface {
+	// Next returns n ascending trace numbers for routingNumber
+	Next(routingNumber string, n int) ([]string, error)
+}
+
+// assignTraceNumbers replaces the trace numbers of batch's entries with ones from traces
+// and rebuilds the batch so addenda records refer to the new trace numbers.
+func assignTraceNumbers(traces TraceNumbers, routingNumber string, batch ach.Batcher) error {
+	entries := batch.GetEntries()
+	numbers, err := traces.Next(routingNumber, len(entries))
+	if err != nil {
+		return fmt.Errorf("allocating trace numbers: %v", err)
+	}
+	if len(numbers) != len(entries) {
+		return fmt.Errorf("allocated %d trace numbers for %d entries", len(numbers), len(entries))
+	}
+	for i := range entries {
+		entries[i].TraceNumber = numbers[i]
+		if entries[i].Addenda99 != nil {
+			entries[i].Addenda99.TraceNumber = numbers[i]
+		}
+	}
+	return batch.Create()
+}
+
 // TraceNumber returns a trace number from a given routing number
 // and uses a hidden random generator. These values are not expected
 // to be cryptographically secure.
@@ -5,7 +5,10 @@
 package achx
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
 )
 
 func TestTrace__ABA(t *testing.T) {
@@ -34,3 +37,45 @@ func TestTraceNumber(t *testing.T) {
 		}
 	}
 }
+
+// sequentialTraceNumbers allocates trace numbers like a trace number sequence would
+type sequentialTraceNumbers struct {
+	next int
+}
+
+func (s *sequentialTraceNumbers) Next(routingNumber string, n int) ([]string, error) {
+	var out []string
+	for i := 0; i < n; i++ {
+		s.next++
+		out = append(out, fmt.Sprintf("%s%07d", ABA8(routingNumber), s.next))
+	}
+	return out, nil
+}
+
+func TestTrace__assignTraceNumbers(t *testing.T) {
+	file := readOffsetTestFile(t)
+	traces := &sequentialTraceNumbers{next: 41}
+
+	if err := assignTraceNumbers(traces, "076401251", file.Batches[0]); err != nil {
+		t.Fatal(err)
+	}
+	if trace := file.Batches[0].GetEntries()[0].TraceNumber; trace != "076401250000042" {
+		t.Errorf("unexpected trace number: %s", trace)
+	}
+
+	// offsets are allocated from the same sequence
+	cfg := &config.Offset{
+		AccountNumber: "123456",
+		AccountType:   "Checking",
+		Name:          "Settlement",
+	}
+	if err := AddOffsets(file, cfg, "076401251", traces); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if trace := file.Batches[0].GetEntries()[1].TraceNumber; trace != "076401250000043" {
+		t.Errorf("unexpected offset trace number: %s", trace)
+	}
+}
@@ -50,6 +50,16 @@ type APIClient struct {
 
 	AdminApi *AdminApiService
 
+	CustomersApi *CustomersApiService
+
+	InboundApi *InboundApiService
+
+	LoggingApi *LoggingApiService
+
+	ReportsApi *ReportsApiService
+
+	SeedApi *SeedApiService
+
 	TransfersApi *TransfersApiService
 }
 
@@ -70,6 +80,11 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 
 	// API Services
 	c.AdminApi = (*AdminApiService)(&c.common)
+	c.CustomersApi = (*CustomersApiService)(&c.common)
+	c.InboundApi = (*InboundApiService)(&c.common)
+	c.LoggingApi = (*LoggingApiService)(&c.common)
+	c.ReportsApi = (*ReportsApiService)(&c.common)
+	c.SeedApi = (*SeedApiService)(&c.common)
 	c.TransfersApi = (*TransfersApiService)(&c.common)
 
 	return c
@@ -0,0 +1,30 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// AccountBlock struct for AccountBlock
+type AccountBlock struct {
+	BlockID    string                `json:"blockID"`
+	CustomerID string                `json:"customerID"`
+	AccountID  string                `json:"accountID"`
+	Direction  AccountBlockDirection `json:"direction"`
+	// Why the account is blocked, which is saved for auditing
+	Reason string `json:"reason"`
+	// User who placed the block
+	CreatedBy string    `json:"createdBy"`
+	Created   time.Time `json:"created"`
+	// User who removed the block
+	RemovedBy string     `json:"removedBy,omitempty"`
+	Removed   *time.Time `json:"removed,omitempty"`
+}
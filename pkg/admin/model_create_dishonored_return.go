@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// CreateDishonoredReturn struct for CreateDishonoredReturn
+type CreateDishonoredReturn struct {
+	// Reason the return is dishonored
+	ReturnCode string `json:"returnCode"`
+	// Julian day from the batch header of the return being dishonored, which is written by the ACH operator
+	SettlementDate string `json:"settlementDate,omitempty"`
+	// Optional note for the RDFI
+	AddendaInformation string `json:"addendaInformation,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// DailySummary struct for DailySummary
+type DailySummary struct {
+	Date          string `json:"date,omitempty"`
+	Organization  string `json:"organization,omitempty"`
+	TransferCount int32  `json:"transferCount,omitempty"`
+	EntryCount    int32  `json:"entryCount,omitempty"`
+	// Total of debit entries in cents
+	DebitTotal int64 `json:"debitTotal,omitempty"`
+	// Total of credit entries in cents
+	CreditTotal int64 `json:"creditTotal,omitempty"`
+	// Files uploaded during the cutoff windows which contained these Transfers
+	Filenames []string `json:"filenames,omitempty"`
+	// Transfers which failed that day
+	RejectedCount int32 `json:"rejectedCount,omitempty"`
+}
@@ -0,0 +1,25 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// DishonoredReturn struct for DishonoredReturn
+type DishonoredReturn struct {
+	TransferID string `json:"transferID"`
+	// Dishonored return code
+	ReturnCode string `json:"returnCode"`
+	// Trace number of the dishonored return entry
+	TraceNumber string `json:"traceNumber"`
+	// Trace number of the Transfer's original entry
+	OriginalTraceNumber string `json:"originalTraceNumber"`
+	// Trace number of the return being dishonored
+	ReturnTraceNumber string `json:"returnTraceNumber"`
+	// Code of the return being dishonored
+	ReturnReasonCode string `json:"returnReasonCode"`
+}
@@ -13,4 +13,14 @@ package admin
 type Error struct {
 	// An error message describing the problem intended for humans.
 	Error string `json:"error"`
+	// Machine-readable code for the problem which clients can branch on.
+	Code string `json:"code"`
+	// An error message describing the problem intended for humans. Always equal to error.
+	Message string `json:"message"`
+	// Request field which caused the problem, if a single field is responsible.
+	Field string `json:"field,omitempty"`
+	// Invalid fields of the request
+	Fields []FieldError `json:"fields,omitempty"`
+	// If the request can be retried without changes, for example after a timeout.
+	Retriable bool `json:"retriable"`
 }
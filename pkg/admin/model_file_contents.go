@@ -0,0 +1,19 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// FileContents struct for FileContents
+type FileContents struct {
+	Filename string `json:"filename,omitempty"`
+	// Batches of the file in order of their BatchNumber
+	Batches []FileBatch `json:"batches,omitempty"`
+	// Each attempt to upload the file, oldest first
+	Uploads []UploadAttempt `json:"uploads,omitempty"`
+}
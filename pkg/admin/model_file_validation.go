@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// FileValidation struct for FileValidation
+type FileValidation struct {
+	// If no problems were found with the file
+	Valid bool `json:"valid,omitempty"`
+	// Rules the file was checked with
+	Rules string `json:"rules,omitempty"`
+	// Number of batches which could be read
+	BatchCount int32 `json:"batchCount,omitempty"`
+	// Number of entries in the batches which could be read
+	EntryCount int32 `json:"entryCount,omitempty"`
+	// Each problem found, in the order of the file
+	Errors []FileValidationError `json:"errors,omitempty"`
+}
@@ -0,0 +1,25 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// FileValidationError struct for FileValidationError
+type FileValidationError struct {
+	// record for fields which don't match the specification, batch for other problems with a batch, imbalance for counts and totals which don't match their control record, checksum for entry hashes which don't match and file for missing or misplaced records.
+	Kind string `json:"kind,omitempty"`
+	// Line of the record with the problem
+	Line int32 `json:"line,omitempty"`
+	// Type of record with the problem
+	Record string `json:"record,omitempty"`
+	// Number of the batch with the problem
+	BatchNumber int32 `json:"batchNumber,omitempty"`
+	// Name of the field with the problem
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message,omitempty"`
+}
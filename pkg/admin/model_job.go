@@ -0,0 +1,29 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// Job struct for Job
+type Job struct {
+	Name string `json:"name,omitempty"`
+	// When the last run finished
+	LastRun time.Time `json:"lastRun,omitempty"`
+	// Error of the last run, empty when it succeeded
+	LastError string `json:"lastError,omitempty"`
+	// When the job runs next
+	NextRun time.Time `json:"nextRun,omitempty"`
+	// True when the job hasn't run long after its next run
+	Overdue bool `json:"overdue,omitempty"`
+	// True when the last run succeeded and the job isn't overdue
+	Healthy bool `json:"healthy,omitempty"`
+}
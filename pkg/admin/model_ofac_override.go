@@ -0,0 +1,30 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// RevealTransferEntries struct for RevealTransferEntries
+
+import (
+	"time"
+)
+
+// OfacOverride struct for OfacOverride
+type OfacOverride struct {
+	OverrideID string `json:"overrideID"`
+	// ID of the allowed entity in watchman
+	EntityID string `json:"entityID"`
+	// Match score of the search which was overridden
+	Match float32 `json:"match"`
+	// User who allowed the match
+	UserID string `json:"userID"`
+	// Why the match was allowed, which is saved for auditing
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// RevealTransferEntries struct for RevealTransferEntries
+
+import (
+	"time"
+)
+
+// OfacSearch struct for OfacSearch
+type OfacSearch struct {
+	Organization string `json:"organization"`
+	CustomerID   string `json:"customerID"`
+	// ID of the matched entity in watchman
+	EntityID string `json:"entityID"`
+	// Name of the matched entity on the SDN list
+	SdnName string `json:"sdnName"`
+	// Match score of the customer against the entity
+	Match float32 `json:"match"`
+	// When Moov Customers searched watchman
+	SearchedAt time.Time     `json:"searchedAt"`
+	Override   *OfacOverride `json:"override,omitempty"`
+}
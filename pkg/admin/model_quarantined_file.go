@@ -0,0 +1,28 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// QuarantinedFile struct for QuarantinedFile
+type QuarantinedFile struct {
+	QuarantineID string `json:"quarantineID"`
+	Filename     string `json:"filename"`
+	// Remote directory the file was downloaded from
+	Kind string `json:"kind"`
+	// Latest problem parsing the file
+	Error   string                `json:"error"`
+	Status  QuarantinedFileStatus `json:"status"`
+	Created time.Time             `json:"created"`
+	// When the file was last retried
+	Retried *time.Time `json:"retried,omitempty"`
+}
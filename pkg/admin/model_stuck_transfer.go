@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// StuckTransfer struct for StuckTransfer
+type StuckTransfer struct {
+	TransferID string `json:"transferID,omitempty"`
+	// Directory the Transfer was merged from
+	CutoffDir string `json:"cutoffDir,omitempty"`
+	// Merged file the Transfer was written into
+	MergedFilename string    `json:"mergedFilename,omitempty"`
+	Since          time.Time `json:"since,omitempty"`
+}
@@ -0,0 +1,27 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// StuckWork struct for StuckWork
+type StuckWork struct {
+	// Work without progress since this time is stuck
+	StuckBefore time.Time `json:"stuckBefore,omitempty"`
+	// Transfers picked up by a cutoff which were never written into a merged file
+	Planned []StuckTransfer `json:"planned,omitempty"`
+	// Transfers in a merged file which wasn't confirmed as uploaded
+	Written []StuckTransfer `json:"written,omitempty"`
+	// PENDING Transfers which were never picked up by a cutoff
+	Unmerged      []StuckTransfer     `json:"unmerged,omitempty"`
+	MicroDeposits []StuckMicroDeposit `json:"microDeposits,omitempty"`
+}
@@ -0,0 +1,22 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// TraceNumberReport struct for TraceNumberReport
+type TraceNumberReport struct {
+	Date string `json:"date,omitempty"`
+	// Merged files uploaded on the date
+	Filenames  []string `json:"filenames,omitempty"`
+	EntryCount int32    `json:"entryCount,omitempty"`
+	// Trace numbers found on more than one entry
+	Duplicates []string `json:"duplicates,omitempty"`
+	// Ranges of trace numbers skipped between allocated trace numbers
+	Gaps []TraceNumberGap `json:"gaps,omitempty"`
+}
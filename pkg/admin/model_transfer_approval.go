@@ -0,0 +1,25 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// TransferApproval struct for TransferApproval
+type TransferApproval struct {
+	TransferID string `json:"transferID"`
+	// User who created the Transfer
+	RequestedBy string    `json:"requestedBy"`
+	Requested   time.Time `json:"requested"`
+	// User who approved the Transfer
+	ApprovedBy string     `json:"approvedBy,omitempty"`
+	Approved   *time.Time `json:"approved,omitempty"`
+}
@@ -0,0 +1,28 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// TransferEntry An EntryDetail record of a Transfer as it was merged into an uploaded file
+type TransferEntry struct {
+	// Name of the uploaded file containing this entry
+	Filename string `json:"filename"`
+	// BatchNumber of the batch containing this entry
+	BatchNumber int32  `json:"batchNumber"`
+	TraceNumber string `json:"traceNumber"`
+	// The 94 character NACHA EntryDetail record
+	EntryDetail string `json:"entryDetail"`
+	// NACHA records of each addenda on the entry
+	Addenda    []string  `json:"addenda"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
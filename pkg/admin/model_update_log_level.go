@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// UpdateLogLevel struct for UpdateLogLevel
+type UpdateLogLevel struct {
+	// Service or package to change. Leave empty to change the default level.
+	Module string `json:"module,omitempty"`
+	// New level. An empty level removes a module's override.
+	Level string `json:"level,omitempty"`
+	// Optional duration after which the previous level is restored.
+	Duration string `json:"duration,omitempty"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// UploadAttempt struct for UploadAttempt
+type UploadAttempt struct {
+	Filename string `json:"filename,omitempty"`
+	// Hex encoded SHA-256 hash of the uploaded contents
+	FileHash string `json:"fileHash,omitempty"`
+	// Size of the uploaded contents in bytes
+	Size int64 `json:"size,omitempty"`
+	// Remote directory the file was uploaded into
+	OutboundPath string `json:"outboundPath,omitempty"`
+	// Error of the upload, empty when it succeeded
+	Error string `json:"error,omitempty"`
+	// If odfi.verification matched the remote copy against the uploaded contents
+	Verified bool `json:"verified,omitempty"`
+	// When the upload was attempted
+	AttemptedAt time.Time `json:"attemptedAt,omitempty"`
+}
@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// UploadCredentials struct for UploadCredentials
+type UploadCredentials struct {
+	// Password for FTP or SFTP
+	Password string `json:"password,omitempty"`
+	// PEM encoded, or base64 encoded PEM, private key for SFTP
+	ClientPrivateKey string `json:"clientPrivateKey,omitempty"`
+	// Passphrase which decrypts clientPrivateKey
+	ClientPrivateKeyPassphrase string `json:"clientPrivateKeyPassphrase,omitempty"`
+}
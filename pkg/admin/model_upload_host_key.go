@@ -0,0 +1,25 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  Refer to the [client endpoints](https://moov-io.github.io/paygate/) for customr facing operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// UploadHostKey struct for UploadHostKey
+type UploadHostKey struct {
+	// known_hosts or authorized_keys entry of the SFTP server's public key
+	HostPublicKey string `json:"hostPublicKey,omitempty"`
+	// SHA256 fingerprint of hostPublicKey
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// User who pinned the host key, empty for the host key of the config
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
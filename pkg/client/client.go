@@ -50,8 +50,12 @@ type APIClient struct {
 
 	ConfigurationApi *ConfigurationApiService
 
+	MandatesApi *MandatesApiService
+
 	MonitorApi *MonitorApiService
 
+	ReportsApi *ReportsApiService
+
 	TransfersApi *TransfersApiService
 
 	ValidationApi *ValidationApiService
@@ -74,7 +78,9 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 
 	// API Services
 	c.ConfigurationApi = (*ConfigurationApiService)(&c.common)
+	c.MandatesApi = (*MandatesApiService)(&c.common)
 	c.MonitorApi = (*MonitorApiService)(&c.common)
+	c.ReportsApi = (*ReportsApiService)(&c.common)
 	c.TransfersApi = (*TransfersApiService)(&c.common)
 	c.ValidationApi = (*ValidationApiService)(&c.common)
 
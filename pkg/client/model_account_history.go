@@ -0,0 +1,32 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// AccountHistory Details of an Account as read from Moov Customers when Transfers were originated
+type AccountHistory struct {
+	AccountID  string `json:"accountID"`
+	CustomerID string `json:"customerID"`
+	// Name of the Customer holding the Account
+	HolderName          string `json:"holderName"`
+	RoutingNumber       string `json:"routingNumber"`
+	MaskedAccountNumber string `json:"maskedAccountNumber"`
+	// Type of the Account, such as checking or savings
+	Type string `json:"type"`
+	// First Transfer originated with these details
+	TransferID string `json:"transferID"`
+	// When PayGate first read these details
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	// When PayGate first read the next details of the Account
+	EffectiveUntil *time.Time `json:"effectiveUntil,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// BankingCalendar Cutoff times, banking holidays and settlement dates of the ODFI's banking calendar
+type BankingCalendar struct {
+	// IANA timezone of the cutoff windows and dates
+	Timezone string `json:"timezone"`
+	// Times of day, as HH:MM, when Transfers are merged and uploaded to the ODFI
+	CutoffWindows []string `json:"cutoffWindows"`
+	// Next cutoff window on a banking day
+	NextCutoff time.Time `json:"nextCutoff"`
+	// Settlement date, as YYYY-MM-DD, of a same-day Transfer created now
+	SameDaySettlementDate string `json:"sameDaySettlementDate"`
+	// Settlement date, as YYYY-MM-DD, of a Transfer created now
+	SettlementDate string `json:"settlementDate"`
+	// Upcoming weekdays, as YYYY-MM-DD, which aren't banking days
+	Holidays []string `json:"holidays"`
+}
@@ -0,0 +1,20 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// CheckDetails The paper check a Transfer was converted from
+type CheckDetails struct {
+	// Serial number of the check, up to 15 characters or 9 for POP
+	SerialNumber string `json:"serialNumber"`
+	// Abbreviated city of the point-of-purchase terminal, up to 4 characters and required for POP
+	TerminalCity string `json:"terminalCity,omitempty"`
+	// State of the point-of-purchase terminal, required for POP
+	TerminalState string `json:"terminalState,omitempty"`
+}
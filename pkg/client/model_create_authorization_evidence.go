@@ -0,0 +1,20 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// CreateAuthorizationEvidence Evidence of a receiver's authorization, either an uploaded document or a link to one kept elsewhere.
+type CreateAuthorizationEvidence struct {
+	// Base64 encoded PDF, PNG or JPEG document. Uploads need transfers.evidence to be configured and are limited by http.maxBodySize.
+	Content string `json:"content,omitempty"`
+	// Name of the uploaded document
+	Filename string `json:"filename,omitempty"`
+	// Link to evidence kept elsewhere, used instead of content
+	Url string `json:"url,omitempty"`
+}
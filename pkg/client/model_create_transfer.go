@@ -17,5 +17,17 @@ type CreateTransfer struct {
 	// Brief description of the transaction, this will appear on the receiving entity’s financial statement.
 	Description string `json:"description"`
 	// When set to true this indicates the transfer should be processed the same day if possible.
-	SameDay bool `json:"sameDay,omitempty"`
+	SameDay    bool        `json:"sameDay,omitempty"`
+	Remittance *Remittance `json:"remittance,omitempty"`
+	// NACHA SEC code of the Transfer's entry. WEB is for entries authorized online and CCD or CTX for entries to businesses. ARC, BOC, POP and RCK are debits converted from checks and require check details.
+	StandardEntryClassCode string        `json:"standardEntryClassCode,omitempty"`
+	Check                  *CheckDetails `json:"check,omitempty"`
+	// Overrides the batch's Company Entry Description, which receivers see on statements. Defaults to the Transfer's description.
+	CompanyEntryDescription string `json:"companyEntryDescription,omitempty"`
+	// Overrides the batch's Company Discretionary Data. Defaults to the source Customer's "discretionary" metadata.
+	CompanyDiscretionaryData string `json:"companyDiscretionaryData,omitempty"`
+	// Identifier of the Transfer in the integrator's own system, unique within the organization. Creating a Transfer with an externalID which was already used returns the existing Transfer instead.
+	ExternalID string `json:"externalID,omitempty"`
+	// Active Mandate of the source account which authorizes this debit. Required on PPD and WEB debits.
+	MandateID string `json:"mandateID,omitempty"`
 }
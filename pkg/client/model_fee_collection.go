@@ -0,0 +1,20 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// FeeCollection How fees are collected into the fee account. With none, the default, fees are only recorded on each Transfer. entry adds a batch to the Transfer's file debiting the source account for the fee and posting is recorded for the ledger to move the fee between accounts. Fees of Transfers whose source account is at the ODFI are always postings.
+type FeeCollection string
+
+// List of FeeCollection
+const (
+	NONE    FeeCollection = "none"
+	ENTRY   FeeCollection = "entry"
+	POSTING FeeCollection = "posting"
+)
@@ -0,0 +1,18 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// FeeRule Fee of one type of Transfer. Credits pay out to an account outside the ODFI and debits collect from one.
+type FeeRule struct {
+	// Fee in cents charged on every Transfer.
+	Flat int32 `json:"flat,omitempty"`
+	// Percent of the Transfer's amount added to the flat fee.
+	Percentage float32 `json:"percentage,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// MicroDepositTransfer A Transfer created from micro-deposits. Accounts are left out as the source is the ODFI's account.
+type MicroDepositTransfer struct {
+	TransferID string         `json:"transferID"`
+	Amount     Amount         `json:"amount"`
+	Status     TransferStatus `json:"status"`
+	// Trace numbers of the ACH entries for this Transfer
+	TraceNumbers []string `json:"traceNumbers"`
+	// When the file containing this Transfer was uploaded to the ODFI
+	ProcessedAt *time.Time  `json:"processedAt,omitempty"`
+	ReturnCode  *ReturnCode `json:"returnCode,omitempty"`
+}
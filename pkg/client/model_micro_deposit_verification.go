@@ -0,0 +1,25 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// MicroDepositVerification Verification state of an account from its micro-deposits
+type MicroDepositVerification struct {
+	// accountID identifier from Customers service
+	AccountID string `json:"accountID"`
+	// Micro-deposits initiated for the account, empty when the account is unverified
+	MicroDepositID string            `json:"microDepositID,omitempty"`
+	State          VerificationState `json:"state"`
+	// When the account can no longer be verified with these micro-deposits
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
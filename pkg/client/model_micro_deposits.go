@@ -24,4 +24,6 @@ type MicroDeposits struct {
 	Status      TransferStatus `json:"status"`
 	ProcessedAt *time.Time     `json:"processedAt,omitempty"`
 	Created     time.Time      `json:"created"`
+	// Details of each Transfer created from this micro-deposit, in the order of transferIDs
+	Transfers []MicroDepositTransfer `json:"transfers,omitempty"`
 }
@@ -12,5 +12,32 @@ package client
 // OrganizationConfiguration struct for OrganizationConfiguration
 type OrganizationConfiguration struct {
 	// This field corresponds to the CompanyIdentification value in an ACH BatchHeader record.
-	CompanyIdentification string `json:"companyIdentification"`
+	CompanyIdentification string           `json:"companyIdentification"`
+	BatchingStrategy      BatchingStrategy `json:"batchingStrategy,omitempty"`
+	// Lowest OFAC match score which blocks transfers with a customer of this organization, overriding customers.onboarding.ofac.matchThreshold when set.
+	OfacMatchThreshold float32 `json:"ofacMatchThreshold,omitempty"`
+	// CIDR ranges which this organization's transfers can be created from. Transfers created from other addresses are rejected with 403 Forbidden.
+	AllowedNetworks []string `json:"allowedNetworks,omitempty"`
+	// Reject every API call of this organization from outside allowedNetworks, not only creating transfers.
+	RestrictAllRequests bool `json:"restrictAllRequests,omitempty"`
+	// Company Entry Description of this organization's micro-deposit entries, overriding validation.microDeposits.description. Limited to 10 characters.
+	MicroDepositEntryDescription string `json:"microDepositEntryDescription,omitempty"`
+	// Individual Name of micro-deposit entries posted to the account being verified, instead of the customer's name. Limited to 22 characters.
+	MicroDepositIndividualName string `json:"microDepositIndividualName,omitempty"`
+	// Banking days after settlement that Transfers debiting a customer's account are held before their funds are available, overriding transfers.availability.debitHoldDays when set.
+	DebitHoldDays int32 `json:"debitHoldDays,omitempty"`
+	// Banking days after settlement that Transfers crediting a customer's account are held before their funds are available, overriding transfers.availability.creditHoldDays when set.
+	CreditHoldDays int32 `json:"creditHoldDays,omitempty"`
+	// Name of this organization shown to the receivers of its Transfers, such as in emails and pages sent with micro-deposit verification links. Limited to 100 characters.
+	DisplayName string `json:"displayName,omitempty"`
+	// Email address receivers of this organization's Transfers can contact for support.
+	SupportEmail string `json:"supportEmail,omitempty"`
+	// HTTPS URL of this organization's logo shown to the receivers of its Transfers.
+	LogoURL string `json:"logoURL,omitempty"`
+	// Error messages by language and error code which replace PayGate's messages for this organization's requests, such as messages.es.not_found. Languages are en and es.
+	Messages       map[string]map[string]string `json:"messages,omitempty"`
+	Fees           *FeeSchedule                 `json:"fees,omitempty"`
+	HoldingAccount *Destination                 `json:"holdingAccount,omitempty"`
+	// Incremented on each update. Updates must include the current version, either here or in an If-Match header, and fail with 409 Conflict when the configuration was changed since it was read.
+	Version int64 `json:"version,omitempty"`
 }
@@ -0,0 +1,27 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// OriginatorDueDiligence Know your business details of an organization as the originator of its transfers
+type OriginatorDueDiligence struct {
+	// Legal structure of the business, such as llc, corporation or soleProprietorship
+	BusinessType string `json:"businessType,omitempty"`
+	// Token of the business's EIN or owner's SSN from a tokenization service. Raw tax IDs are rejected.
+	TaxIDToken string               `json:"taxIDToken,omitempty"`
+	Address    *DueDiligenceAddress `json:"address,omitempty"`
+	// Website of the business
+	Website string `json:"website,omitempty"`
+	// When the due-diligence was last updated
+	Updated *time.Time `json:"updated,omitempty"`
+}
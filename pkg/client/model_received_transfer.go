@@ -0,0 +1,47 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// ReceivedTransfer A forward entry another financial institution originated to one of our routing numbers
+type ReceivedTransfer struct {
+	// receivedTransferID to uniquely identify this ReceivedTransfer
+	ReceivedTransferID string                 `json:"receivedTransferID"`
+	Amount             Amount                 `json:"amount"`
+	Status             ReceivedTransferStatus `json:"status"`
+	// NACHA transaction code of the entry, which determines if it's a credit or debit and the account type
+	TransactionCode        int32  `json:"transactionCode"`
+	StandardEntryClassCode string `json:"standardEntryClassCode"`
+	// Name of the originator from the batch header
+	CompanyName             string `json:"companyName"`
+	CompanyIdentification   string `json:"companyIdentification"`
+	CompanyEntryDescription string `json:"companyEntryDescription"`
+	// First 8 digits of the routing number which originated the entry
+	OdfiIdentification string `json:"odfiIdentification"`
+	// Our routing number the entry was addressed to
+	RdfiRoutingNumber string `json:"rdfiRoutingNumber"`
+	IndividualName    string `json:"individualName"`
+	// ID of the account in the Accounts service the entry was posted to. Empty when the entry was returned.
+	AccountID string `json:"accountID,omitempty"`
+	// ID of the transaction in the Accounts service the entry was posted with
+	TransactionID string      `json:"transactionID,omitempty"`
+	TraceNumber   string      `json:"traceNumber"`
+	ReturnCode    *ReturnCode `json:"returnCode,omitempty"`
+	// Trace number of the return PayGate originated for the entry
+	ReturnTraceNumber string `json:"returnTraceNumber,omitempty"`
+	// When a return of the entry needs to be uploaded by, which is the last cutoff window of the second banking day after settlement
+	ReturnDeadline time.Time `json:"returnDeadline"`
+	// When the return was uploaded
+	ReturnedAt *time.Time `json:"returnedAt,omitempty"`
+	Created    time.Time  `json:"created"`
+}
@@ -17,4 +17,8 @@ type ReturnCode struct {
 	Reason string `json:"reason"`
 	// Long form explanation of return code
 	Description string `json:"description"`
+	// What the originator should do about the return
+	Action string `json:"action"`
+	// The same entry can be sent again without changes or a new authorization from the customer
+	Reinitiate bool `json:"reinitiate"`
 }
@@ -0,0 +1,29 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// StatementPreview Descriptors of a Transfer's entry as the receiver's bank reads them
+type StatementPreview struct {
+	StandardEntryClassCode string `json:"standardEntryClassCode"`
+	// Company Name of the batch, up to 16 characters
+	CompanyName string `json:"companyName"`
+	// Company Entry Description of the batch, up to 10 characters
+	CompanyEntryDescription string `json:"companyEntryDescription"`
+	// Company Descriptive Date of the batch, SDHHMM for same-day entries
+	CompanyDescriptiveDate string `json:"companyDescriptiveDate,omitempty"`
+	// Company Discretionary Data of the batch, up to 20 characters
+	CompanyDiscretionaryData string `json:"companyDiscretionaryData,omitempty"`
+	// Individual Name of the receiver on the entry, up to 22 characters
+	IndividualName string `json:"individualName"`
+	// Date the entry settles if the Transfer is created now
+	EffectiveEntryDate string `json:"effectiveEntryDate"`
+	// Fields which were cut to fit their NACHA length
+	Truncated []string `json:"truncated,omitempty"`
+}
@@ -29,4 +29,29 @@ type Transfer struct {
 	ProcessedAt  *time.Time  `json:"processedAt,omitempty"`
 	Created      time.Time   `json:"created"`
 	TraceNumbers []string    `json:"traceNumbers"`
+	Remittance   *Remittance `json:"remittance,omitempty"`
+	// NACHA SEC code of the Transfer's entry. WEB is for entries authorized online and CCD or CTX for entries to businesses. ARC, BOC, POP and RCK are debits converted from checks and require check details.
+	StandardEntryClassCode string        `json:"standardEntryClassCode,omitempty"`
+	Check                  *CheckDetails `json:"check,omitempty"`
+	// Overrides the batch's Company Entry Description, which receivers see on statements. Defaults to the Transfer's description.
+	CompanyEntryDescription string `json:"companyEntryDescription,omitempty"`
+	// Overrides the batch's Company Discretionary Data. Defaults to the source Customer's "discretionary" metadata.
+	CompanyDiscretionaryData string `json:"companyDiscretionaryData,omitempty"`
+	// Identifier of the Transfer in the integrator's own system, unique within the organization. Creating a Transfer with an externalID which was already used returns the existing Transfer instead.
+	ExternalID string `json:"externalID,omitempty"`
+	// transferID of the original Transfer when this Transfer reinitiates one returned for insufficient or uncollected funds (R01 or R09).
+	RetryOf string `json:"retryOf,omitempty"`
+	// How many times the original Transfer has been reinitiated, including this Transfer.
+	RetryAttempt int32 `json:"retryAttempt,omitempty"`
+	// Date, as YYYY-MM-DD, the Transfer's entries are expected to settle based on their effective entry date, same-day and the banking calendar.
+	ExpectedSettlementDate string `json:"expectedSettlementDate,omitempty"`
+	// Date, as YYYY-MM-DD, the Transfer's entries settle once they're uploaded to the ODFI. Entries uploaded after their effective entry date settle on the banking day they're uploaded.
+	ActualSettlementDate string `json:"actualSettlementDate,omitempty"`
+	// Date, as YYYY-MM-DD, the Transfer's funds are treated as collected. It's the settlement date plus the banking days debits or credits are held for by the organization's availability policy.
+	AvailableOn string       `json:"availableOn,omitempty"`
+	Fee         *TransferFee `json:"fee,omitempty"`
+	// groupID of the split payment this Transfer is part of.
+	GroupID string `json:"groupID,omitempty"`
+	// Mandate which authorized this debit.
+	MandateID string `json:"mandateID,omitempty"`
 }
@@ -0,0 +1,19 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// TransferFee Fee charged on a Transfer by its organization's fee schedule.
+type TransferFee struct {
+	Amount     Amount        `json:"amount"`
+	Collection FeeCollection `json:"collection"`
+	Account    *Destination  `json:"account,omitempty"`
+	// Trace number of the fee's debit entry when it's collected as an entry.
+	TraceNumber string `json:"traceNumber,omitempty"`
+}
@@ -0,0 +1,27 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// TransferFile The uploaded file a Transfer was merged into
+type TransferFile struct {
+	Filename string `json:"filename"`
+	// BatchNumber of each batch in the file containing entries of the Transfer
+	BatchNumbers []int32 `json:"batchNumbers"`
+	// Trace numbers of the Transfer's entries in the file
+	TraceNumbers []string `json:"traceNumbers"`
+	// When the file was first uploaded
+	UploadedAt time.Time `json:"uploadedAt"`
+	// If an upload of the file was verified against its remote copy with odfi.verification
+	Acknowledged bool `json:"acknowledged"`
+}
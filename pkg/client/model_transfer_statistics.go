@@ -0,0 +1,22 @@
+/*
+ * Paygate API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.  An organization is a value used to isolate models from each other. This can be set to a \"user ID\" from your authentication service or any value your system has to identify.  There are also [admin endpoints](https://moov-io.github.io/paygate/admin/) for back-office operations.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package client
+
+// TransferStatistics Count of Transfers created this month by status
+type TransferStatistics struct {
+	Total      int32 `json:"total"`
+	Pending    int32 `json:"pending"`
+	Processed  int32 `json:"processed"`
+	Reviewable int32 `json:"reviewable"`
+	Canceled   int32 `json:"canceled"`
+	Failed     int32 `json:"failed"`
+	// Sum of Transfer amounts in the smallest unit of the currency, excluding canceled and failed Transfers
+	Volume int64 `json:"volume"`
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package paygate wraps the generated PayGate client with methods that handle
+// idempotency keys, retries and pagination for callers.
+package paygate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// Client calls PayGate on behalf of a single organization. Requests which fail
+// with a 5xx, 429 or network error are retried with exponential backoff.
+type Client struct {
+	underlying   *client.APIClient
+	organization string
+
+	retries      int
+	backoff      time.Duration
+	pollInterval time.Duration
+}
+
+// Option changes the behavior of a Client.
+type Option func(*Client)
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry. The delay doubles for each retry afterwards.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithPollInterval sets how often WaitForTransferStatus reads the Transfer.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = interval
+	}
+}
+
+// New returns a Client which sends every request as organization.
+func New(underlying *client.APIClient, organization string, opts ...Option) *Client {
+	c := &Client{
+		underlying:   underlying,
+		organization: organization,
+		retries:      3,
+		backoff:      250 * time.Millisecond,
+		pollInterval: 5 * time.Second,
+	}
+	for i := range opts {
+		opts[i](c)
+	}
+	return c
+}
+
+// Error is returned for responses outside of the 2xx range. Problem holds the
+// error PayGate returned, if the response body contained one.
+type Error struct {
+	StatusCode int
+	Problem    client.Error
+}
+
+func (e *Error) Error() string {
+	if e.Problem.Error != "" {
+		return fmt.Sprintf("paygate: %d %s: %s", e.StatusCode, e.Problem.Code, e.Problem.Error)
+	}
+	return fmt.Sprintf("paygate: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retriable returns true if the request can be sent again without changes.
+func (e *Error) Retriable() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests || e.Problem.Retriable
+}
+
+// IsNotFound returns true if err is a 404 Not Found from PayGate.
+func IsNotFound(err error) bool {
+	var perr *Error
+	return errors.As(err, &perr) && perr.StatusCode == http.StatusNotFound
+}
+
+// retry calls fn until it succeeds, returns an error which can't be retried or
+// the retries are used up.
+func (c *Client) retry(ctx context.Context, fn func(attempt int) (*http.Response, error)) error {
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := fn(attempt)
+		if err == nil && resp != nil && resp.StatusCode < http.StatusMultipleChoices {
+			return nil
+		}
+		err = responseError(resp, err)
+
+		var perr *Error
+		if errors.As(err, &perr) && !perr.Retriable() {
+			return err
+		}
+		if attempt >= c.retries {
+			return err
+		}
+
+		wait := delay
+		if d := retryAfter(resp); d > 0 {
+			wait = d
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// responseError converts the error returned by the generated client into an
+// *Error when PayGate responded.
+func responseError(resp *http.Response, err error) error {
+	if resp == nil {
+		if err == nil {
+			err = errors.New("paygate: no response")
+		}
+		return err
+	}
+	if resp.StatusCode < http.StatusMultipleChoices {
+		return err // the response body couldn't be decoded
+	}
+	perr := &Error{StatusCode: resp.StatusCode}
+	var oerr client.GenericOpenAPIError
+	if errors.As(err, &oerr) {
+		json.Unmarshal(oerr.Body(), &perr.Problem)
+	}
+	return perr
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
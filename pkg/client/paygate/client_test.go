@@ -0,0 +1,209 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package paygate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(func() { server.Close() })
+
+	conf := client.NewConfiguration()
+	conf.BasePath = server.URL
+
+	return New(client.NewAPIClient(conf), "moov", WithRetries(3, time.Millisecond), WithPollInterval(time.Millisecond))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestClient__CreateTransfer(t *testing.T) {
+	var keys []string
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Organization") != "moov" {
+			t.Errorf("unexpected organization: %q", r.Header.Get("X-Organization"))
+		}
+		keys = append(keys, r.Header.Get("X-Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, http.StatusOK, client.Transfer{TransferID: "xfer", Status: client.PENDING})
+	})
+
+	xfer, err := c.CreateTransfer(context.Background(), client.CreateTransfer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xfer.TransferID != "xfer" {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("unexpected idempotency keys: %v", keys)
+	}
+}
+
+func TestClient__CreateTransferSeenBefore(t *testing.T) {
+	calls := 0
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusPreconditionFailed)
+	})
+
+	_, err := c.CreateTransfer(context.Background(), client.CreateTransfer{})
+	if err != ErrTransferMaybeCreated {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClient__Error(t *testing.T) {
+	calls := 0
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, http.StatusBadRequest, client.Error{
+			Error: "invalid amount",
+			Code:  "validation_failed",
+			Field: "amount",
+		})
+	})
+
+	_, err := c.CreateTransfer(context.Background(), client.CreateTransfer{})
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if perr.StatusCode != http.StatusBadRequest || perr.Problem.Code != "validation_failed" || perr.Problem.Field != "amount" {
+		t.Errorf("unexpected error: %#v", perr)
+	}
+	if calls != 1 {
+		t.Errorf("retried %d times", calls-1)
+	}
+
+	// 5xx responses are retried until they run out
+	calls = 0
+	c = testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if _, err := c.GetTransfer(context.Background(), "xfer"); err == nil {
+		t.Error("expected error")
+	}
+	if calls != 4 {
+		t.Errorf("unexpected calls: %d", calls)
+	}
+}
+
+func TestClient__WaitForTransferStatus(t *testing.T) {
+	statuses := []client.TransferStatus{client.PENDING, client.PENDING, client.PROCESSED}
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		writeJSON(w, http.StatusOK, client.Transfer{TransferID: "xfer", Status: status})
+	})
+
+	xfer, err := c.WaitForTransferStatus(context.Background(), "xfer", client.PROCESSED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xfer.Status != client.PROCESSED {
+		t.Errorf("unexpected status: %s", xfer.Status)
+	}
+
+	// a different final status stops waiting
+	statuses = []client.TransferStatus{client.FAILED}
+	if _, err := c.WaitForTransferStatus(context.Background(), "xfer", client.PROCESSED); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestClient__EachTransfer(t *testing.T) {
+	total := 250
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		if r.URL.Query().Get("status") != "pending" {
+			t.Errorf("unexpected status: %q", r.URL.Query().Get("status"))
+		}
+
+		var page []client.Transfer
+		for i := skip; i < total && i < skip+count; i++ {
+			page = append(page, client.Transfer{TransferID: fmt.Sprintf("%d", i)})
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	xfers, err := c.ListTransfers(context.Background(), TransferFilter{
+		Status:   client.PENDING,
+		PageSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != total {
+		t.Fatalf("got %d transfers", len(xfers))
+	}
+	for i := range xfers {
+		if xfers[i].TransferID != fmt.Sprintf("%d", i) {
+			t.Fatalf("unexpected transfer at %d: %#v", i, xfers[i])
+		}
+	}
+}
+
+func TestClient__ConfirmMicroDeposits(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/accounts/acct/micro-deposits/confirm" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var req client.ConfirmMicroDeposits
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		writeJSON(w, http.StatusOK, client.MicroDepositConfirmation{
+			AccountID:         "acct",
+			Confirmed:         len(req.Amounts) == 2 && req.Amounts[1].Value == 12,
+			AttemptsRemaining: 2,
+		})
+	})
+	ctx := context.Background()
+
+	err := c.ConfirmMicroDeposits(ctx, "acct", []client.Amount{
+		{Currency: "USD", Value: 7},
+		{Currency: "USD", Value: 12},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = c.ConfirmMicroDeposits(ctx, "acct", []client.Amount{
+		{Currency: "USD", Value: 7},
+		{Currency: "USD", Value: 13},
+	})
+	if err != ErrAmountsMismatch {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
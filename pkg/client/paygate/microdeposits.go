@@ -0,0 +1,58 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package paygate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// ErrAmountsMismatch is returned by ConfirmMicroDeposits when the amounts don't
+// match the micro-deposits sent to the account.
+var ErrAmountsMismatch = errors.New("paygate: micro-deposit amounts do not match")
+
+// InitiateMicroDeposits sends micro-deposits to destination.
+func (c *Client) InitiateMicroDeposits(ctx context.Context, destination client.Destination) (*client.MicroDeposits, error) {
+	req := client.CreateMicroDeposits{
+		Destination: destination,
+	}
+	var micro client.MicroDeposits
+	err := c.retry(ctx, func(_ int) (*http.Response, error) {
+		m, resp, err := c.underlying.ValidationApi.InitiateMicroDeposits(ctx, c.organization, req)
+		micro = m
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &micro, nil
+}
+
+// ConfirmMicroDeposits checks amounts, usually entered by the account holder, against
+// the micro-deposits PayGate sent to accountID. The order of amounts doesn't matter.
+//
+// PayGate allows a limited number of attempts for each account, after which an error
+// is returned.
+func (c *Client) ConfirmMicroDeposits(ctx context.Context, accountID string, amounts []client.Amount) error {
+	req := client.ConfirmMicroDeposits{
+		Amounts: amounts,
+	}
+	var confirmation client.MicroDepositConfirmation
+	err := c.retry(ctx, func(_ int) (*http.Response, error) {
+		conf, resp, err := c.underlying.ValidationApi.ConfirmAccountMicroDeposits(ctx, accountID, c.organization, req)
+		confirmation = conf
+		return resp, err
+	})
+	if err != nil {
+		return err
+	}
+	if !confirmation.Confirmed {
+		return ErrAmountsMismatch
+	}
+	return nil
+}
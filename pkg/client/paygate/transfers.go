@@ -0,0 +1,171 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package paygate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// ErrTransferMaybeCreated is returned by CreateTransfer when PayGate rejected a retry
+// because it had already received the idempotency key. The earlier attempt may have
+// created the Transfer, so look for it before creating another.
+var ErrTransferMaybeCreated = errors.New("paygate: transfer may have been created by an earlier attempt")
+
+// CreateTransfer creates a Transfer. Every attempt is sent with the same idempotency
+// key so a retried request can't create the Transfer twice.
+func (c *Client) CreateTransfer(ctx context.Context, req client.CreateTransfer) (*client.Transfer, error) {
+	opts := &client.AddTransferOpts{
+		XIdempotencyKey: optional.NewString(base.ID()),
+	}
+	var xfer client.Transfer
+	seenBefore := false
+	err := c.retry(ctx, func(attempt int) (*http.Response, error) {
+		x, resp, err := c.underlying.TransfersApi.AddTransfer(ctx, c.organization, req, opts)
+		if attempt > 0 && resp != nil && resp.StatusCode == http.StatusPreconditionFailed {
+			seenBefore = true
+		}
+		xfer = x
+		return resp, err
+	})
+	if err != nil {
+		if seenBefore {
+			return nil, ErrTransferMaybeCreated
+		}
+		return nil, err
+	}
+	return &xfer, nil
+}
+
+// GetTransfer reads a Transfer by its ID.
+func (c *Client) GetTransfer(ctx context.Context, transferID string) (*client.Transfer, error) {
+	var xfer client.Transfer
+	err := c.retry(ctx, func(_ int) (*http.Response, error) {
+		x, resp, err := c.underlying.TransfersApi.GetTransferByID(ctx, transferID, c.organization, nil)
+		xfer = x
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &xfer, nil
+}
+
+// WaitForTransferStatus reads the Transfer until it has status, reaches a different
+// final status or ctx is done.
+func (c *Client) WaitForTransferStatus(ctx context.Context, transferID string, status client.TransferStatus) (*client.Transfer, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		xfer, err := c.GetTransfer(ctx, transferID)
+		if err != nil {
+			return nil, err
+		}
+		if xfer.Status == status {
+			return xfer, nil
+		}
+		if finalStatus(xfer.Status) {
+			return xfer, fmt.Errorf("paygate: transferID=%s is %s, not %s", transferID, xfer.Status, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return xfer, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func finalStatus(status client.TransferStatus) bool {
+	switch status {
+	case client.CANCELED, client.FAILED, client.PROCESSED:
+		return true
+	}
+	return false
+}
+
+// TransferFilter limits which Transfers are listed.
+type TransferFilter struct {
+	Status      client.TransferStatus
+	StartDate   time.Time
+	EndDate     time.Time
+	CustomerIDs []string
+
+	// PageSize is how many Transfers are read with each request. PayGate
+	// returns at most 200 and defaults to 100.
+	PageSize int
+}
+
+// maxSkip is the largest skip PayGate accepts, listing further returns the same page.
+const maxSkip = 10000
+
+// EachTransfer calls fn for every Transfer matching filter, reading one page at a
+// time. Listing stops at the first error fn returns.
+func (c *Client) EachTransfer(ctx context.Context, filter TransferFilter, fn func(client.Transfer) error) error {
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	opts := &client.GetTransfersOpts{
+		Count: optional.NewInt32(int32(pageSize)),
+	}
+	if filter.Status != "" {
+		opts.Status = optional.NewInterface(filter.Status)
+	}
+	if !filter.StartDate.IsZero() {
+		opts.StartDate = optional.NewTime(filter.StartDate)
+	}
+	if !filter.EndDate.IsZero() {
+		opts.EndDate = optional.NewTime(filter.EndDate)
+	}
+	if len(filter.CustomerIDs) > 0 {
+		opts.CustomerIDs = optional.NewString(strings.Join(filter.CustomerIDs, ","))
+	}
+
+	for skip := 0; ; skip += pageSize {
+		if skip > maxSkip {
+			return fmt.Errorf("paygate: can't list more than %d transfers, narrow the filter", maxSkip)
+		}
+		opts.Skip = optional.NewInt32(int32(skip))
+
+		var page []client.Transfer
+		err := c.retry(ctx, func(_ int) (*http.Response, error) {
+			xfers, resp, err := c.underlying.TransfersApi.GetTransfers(ctx, c.organization, opts)
+			page = xfers
+			return resp, err
+		})
+		if err != nil {
+			return err
+		}
+		for i := range page {
+			if err := fn(page[i]); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+	}
+}
+
+// ListTransfers returns every Transfer matching filter.
+func (c *Client) ListTransfers(ctx context.Context, filter TransferFilter) ([]client.Transfer, error) {
+	var out []client.Transfer
+	err := c.EachTransfer(ctx, filter, func(xfer client.Transfer) error {
+		out = append(out, xfer)
+		return nil
+	})
+	return out, err
+}
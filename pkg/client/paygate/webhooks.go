@@ -0,0 +1,167 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package paygate
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+const (
+	// WebhookSignatureHeader holds the hex encoded HMAC-SHA256 signature of a webhook.
+	WebhookSignatureHeader = "X-Signature"
+
+	// WebhookTimestampHeader holds the unix time (in seconds) a webhook was signed at.
+	WebhookTimestampHeader = "X-Signature-Timestamp"
+
+	// DefaultWebhookTolerance is how far a webhook's timestamp can be from now and still be accepted.
+	DefaultWebhookTolerance = 5 * time.Minute
+)
+
+// Event types PayGate sends to webhooks
+const (
+	EventTransferAvailable        = "transfers.available"
+	EventMicroDepositVerification = "micro-deposits.verification"
+)
+
+var (
+	ErrMissingSignature = errors.New("paygate: missing webhook signature")
+	ErrInvalidSignature = errors.New("paygate: invalid webhook signature")
+	ErrStaleWebhook     = errors.New("paygate: webhook timestamp is outside of the tolerance")
+)
+
+// SignWebhook returns the signature PayGate sends with a webhook body. The signed payload
+// is the timestamp and body separated by a newline.
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the signature headers of a webhook against its body. Webhooks signed
+// more than tolerance from now are rejected so captured requests can't be replayed later.
+// Signatures are compared in constant time.
+func VerifyWebhook(secret string, headers http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, signature := headers.Get(WebhookTimestampHeader), headers.Get(WebhookSignatureHeader)
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("paygate: invalid %s header: %v", WebhookTimestampHeader, err)
+	}
+	if diff := time.Since(time.Unix(sec, 0)); diff > tolerance || diff < -tolerance {
+		return ErrStaleWebhook
+	}
+	expected := SignWebhook(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// TransferAvailableEvent is sent once the funds of a processed Transfer are available.
+type TransferAvailableEvent struct {
+	Transfer client.Transfer `json:"transfer"`
+}
+
+// MicroDepositVerificationEvent is sent for each change of an account's verification state.
+// Branding is set when the organization configured any, for emails and pages sent to the
+// account holder such as the link to confirm their micro-deposits.
+type MicroDepositVerificationEvent struct {
+	PreviousState client.VerificationState        `json:"previousState"`
+	Verification  client.MicroDepositVerification `json:"verification"`
+	Branding      *Branding                       `json:"branding,omitempty"`
+}
+
+// Branding is how an organization presents itself to the receivers of its Transfers.
+type Branding struct {
+	DisplayName  string `json:"displayName,omitempty"`
+	SupportEmail string `json:"supportEmail,omitempty"`
+	LogoURL      string `json:"logoURL,omitempty"`
+}
+
+// NewBranding returns the branding of cfg, or nil when it has none.
+func NewBranding(cfg *client.OrganizationConfiguration) *Branding {
+	if cfg == nil || (cfg.DisplayName == "" && cfg.SupportEmail == "" && cfg.LogoURL == "") {
+		return nil
+	}
+	return &Branding{
+		DisplayName:  cfg.DisplayName,
+		SupportEmail: cfg.SupportEmail,
+		LogoURL:      cfg.LogoURL,
+	}
+}
+
+// WebhookEvent is a decoded webhook. The field matching Type is set, events of types this
+// client doesn't know only have Type and Organization.
+type WebhookEvent struct {
+	Type         string
+	Organization string
+
+	TransferAvailable        *TransferAvailableEvent
+	MicroDepositVerification *MicroDepositVerificationEvent
+}
+
+// DecodeWebhookEvent decodes the body of a webhook into its typed event.
+func DecodeWebhookEvent(body []byte) (*WebhookEvent, error) {
+	var envelope struct {
+		Type         string `json:"type"`
+		Organization string `json:"organization"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("paygate: decoding webhook: %v", err)
+	}
+	if envelope.Type == "" {
+		return nil, errors.New("paygate: webhook is missing its type")
+	}
+
+	event := &WebhookEvent{
+		Type:         envelope.Type,
+		Organization: envelope.Organization,
+	}
+	var err error
+	switch event.Type {
+	case EventTransferAvailable:
+		event.TransferAvailable = &TransferAvailableEvent{}
+		err = json.Unmarshal(body, event.TransferAvailable)
+	case EventMicroDepositVerification:
+		event.MicroDepositVerification = &MicroDepositVerificationEvent{}
+		err = json.Unmarshal(body, event.MicroDepositVerification)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("paygate: decoding %s webhook: %v", event.Type, err)
+	}
+	return event, nil
+}
+
+// ReadWebhook reads the body of a webhook request, verifies its signature with secret and
+// returns the decoded event. Handlers should respond with a 2xx status only when it returns
+// no error, PayGate sends webhooks again until they're accepted.
+func ReadWebhook(r *http.Request, secret string, tolerance time.Duration) (*WebhookEvent, error) {
+	if r.Body == nil {
+		return nil, errors.New("paygate: empty webhook body")
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("paygate: reading webhook: %v", err)
+	}
+	if err := VerifyWebhook(secret, r.Header, body, tolerance); err != nil {
+		return nil, err
+	}
+	return DecodeWebhookEvent(body)
+}
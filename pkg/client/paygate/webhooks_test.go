@@ -0,0 +1,120 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package paygate
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func signedHeaders(secret string, signedAt time.Time, body []byte) http.Header {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	headers := make(http.Header)
+	headers.Set(WebhookTimestampHeader, timestamp)
+	headers.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+	return headers
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"type":"transfers.available","organization":"moov"}`)
+	now := time.Now()
+
+	if err := VerifyWebhook(testSecret, signedHeaders(testSecret, now, body), body, DefaultWebhookTolerance); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyWebhook(testSecret, make(http.Header), body, DefaultWebhookTolerance); err != ErrMissingSignature {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := VerifyWebhook("other secret", signedHeaders(testSecret, now, body), body, DefaultWebhookTolerance); err != ErrInvalidSignature {
+		t.Errorf("unexpected error: %v", err)
+	}
+	tampered := []byte(`{"type":"transfers.available","organization":"evil"}`)
+	if err := VerifyWebhook(testSecret, signedHeaders(testSecret, now, body), tampered, DefaultWebhookTolerance); err != ErrInvalidSignature {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// webhooks signed outside of the tolerance are rejected
+	old := now.Add(-10 * time.Minute)
+	if err := VerifyWebhook(testSecret, signedHeaders(testSecret, old, body), body, DefaultWebhookTolerance); err != ErrStaleWebhook {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := VerifyWebhook(testSecret, signedHeaders(testSecret, old, body), body, time.Hour); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	headers := signedHeaders(testSecret, now, body)
+	headers.Set(WebhookTimestampHeader, "yesterday")
+	if err := VerifyWebhook(testSecret, headers, body, DefaultWebhookTolerance); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDecodeWebhookEvent(t *testing.T) {
+	event, err := DecodeWebhookEvent([]byte(`{"type":"transfers.available","organization":"moov","transfer":{"transferID":"e0d54e15","availableOn":"2020-11-17"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventTransferAvailable || event.Organization != "moov" || event.TransferAvailable == nil {
+		t.Fatalf("unexpected event: %#v", event)
+	}
+	if xfer := event.TransferAvailable.Transfer; xfer.TransferID != "e0d54e15" || xfer.AvailableOn != "2020-11-17" {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+
+	event, err = DecodeWebhookEvent([]byte(`{"type":"micro-deposits.verification","organization":"moov","previousState":"micro-deposits-sent","verification":{"accountID":"a1","state":"awaiting-confirmation"},"branding":{"displayName":"Acme Payroll"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.MicroDepositVerification == nil || event.MicroDepositVerification.PreviousState != client.VERIFICATION_MICRO_DEPOSITS_SENT {
+		t.Fatalf("unexpected event: %#v", event)
+	}
+	if v := event.MicroDepositVerification.Verification; v.AccountID != "a1" || v.State != client.VERIFICATION_AWAITING_CONFIRMATION {
+		t.Errorf("unexpected verification: %#v", v)
+	}
+	if b := event.MicroDepositVerification.Branding; b == nil || b.DisplayName != "Acme Payroll" {
+		t.Errorf("unexpected branding: %#v", b)
+	}
+
+	// unknown types are returned without a payload
+	event, err = DecodeWebhookEvent([]byte(`{"type":"transfers.future","organization":"moov"}`))
+	if err != nil || event.Type != "transfers.future" || event.TransferAvailable != nil || event.MicroDepositVerification != nil {
+		t.Errorf("event=%#v error=%v", event, err)
+	}
+
+	if _, err := DecodeWebhookEvent([]byte(`{"organization":"moov"}`)); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := DecodeWebhookEvent([]byte(`not json`)); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestReadWebhook(t *testing.T) {
+	body := []byte(`{"type":"transfers.available","organization":"moov","transfer":{"transferID":"e0d54e15"}}`)
+
+	req := httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+	req.Header = signedHeaders(testSecret, time.Now(), body)
+	event, err := ReadWebhook(req, testSecret, DefaultWebhookTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.TransferAvailable == nil || event.TransferAvailable.Transfer.TransferID != "e0d54e15" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+
+	req = httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+	if _, err := ReadWebhook(req, testSecret, DefaultWebhookTolerance); err != ErrMissingSignature {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
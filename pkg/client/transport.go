@@ -0,0 +1,241 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// This file isn't generated by OpenAPI Generator. It's kept when the client is regenerated.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoff is the delay before the first retry when TransportOptions.Backoff is zero.
+	DefaultRetryBackoff = 250 * time.Millisecond
+
+	// DefaultMaxRetryBackoff caps delays between retries when TransportOptions.MaxBackoff is zero.
+	DefaultMaxRetryBackoff = 30 * time.Second
+
+	idempotencyKeyHeader = "X-Idempotency-Key"
+)
+
+// Attempt describes one try at sending a request, which is passed to an Interceptor.
+type Attempt struct {
+	Request *http.Request
+
+	// Response is nil when Err is set
+	Response *http.Response
+	Err      error
+
+	// Number is zero for the first attempt and counts up for each retry
+	Number   int
+	Duration time.Duration
+
+	// Retrying is true when the request will be sent again
+	Retrying bool
+}
+
+// Interceptor is called after each attempt of a request, e.g. for logging or metrics.
+// It must not read or close the response body.
+type Interceptor func(attempt Attempt)
+
+// TransportOptions configures the http.RoundTripper returned by NewTransport.
+type TransportOptions struct {
+	// Retries is how many times a request is sent again after a 429 Too Many Requests.
+	// Idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE or those with an X-Idempotency-Key)
+	// are also retried after a 5xx response or network error.
+	Retries int
+
+	// Backoff is the delay before the first retry which doubles for each retry afterwards.
+	// A Retry-After header in the response is used instead when it's present.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between retries, including those from Retry-After.
+	MaxBackoff time.Duration
+
+	// Timeout limits each attempt of a request, zero doesn't limit them. Override it for one
+	// call with WithTimeout. Deadlines of the request's context apply across every attempt.
+	Timeout time.Duration
+
+	Interceptor Interceptor
+
+	// Transport sends each attempt, http.DefaultTransport is used when it's nil.
+	Transport http.RoundTripper
+}
+
+// NewHTTPClient returns an http.Client for Configuration.HTTPClient which retries requests
+// and limits how long they take according to opts.
+func NewHTTPClient(opts TransportOptions) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(opts),
+	}
+}
+
+// NewTransport returns an http.RoundTripper which retries requests and limits how long
+// they take according to opts.
+func NewTransport(opts TransportOptions) http.RoundTripper {
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultRetryBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxRetryBackoff
+	}
+	return &retryTransport{opts: opts}
+}
+
+type timeoutKey struct{}
+
+// WithTimeout returns a context which limits each attempt of the calls made with it to
+// timeout, instead of TransportOptions.Timeout.
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+type retryTransport struct {
+	opts TransportOptions
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	timeout := t.opts.Timeout
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		timeout = d
+	}
+
+	delay := t.opts.Backoff
+	for number := 0; ; number++ {
+		attempt, err := rewindRequest(req, number)
+		if err != nil {
+			return nil, err
+		}
+		cancel := func() {}
+		if timeout > 0 {
+			var attemptCtx context.Context
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+			attempt = attempt.WithContext(attemptCtx)
+		}
+
+		start := time.Now()
+		resp, err := t.opts.Transport.RoundTrip(attempt)
+		retrying := number < t.opts.Retries && ctx.Err() == nil && retriable(req, resp, err)
+		if t.opts.Interceptor != nil {
+			t.opts.Interceptor(Attempt{
+				Request:  attempt,
+				Response: resp,
+				Err:      err,
+				Number:   number,
+				Duration: time.Since(start),
+				Retrying: retrying,
+			})
+		}
+		if !retrying {
+			if resp != nil {
+				// the attempt's context needs to live until the body is read
+				resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			} else {
+				cancel()
+			}
+			return resp, err
+		}
+
+		wait := delay
+		if d := retryAfter(resp, time.Now()); d > 0 {
+			wait = d
+		}
+		if wait > t.opts.MaxBackoff {
+			wait = t.opts.MaxBackoff
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		cancel()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// rewindRequest returns req for the first attempt and a copy with a fresh body for retries.
+func rewindRequest(req *http.Request, number int) (*http.Request, error) {
+	if number == 0 {
+		return req, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// retriable returns true when the request can be sent again. Only idempotent requests are
+// retried after errors which could happen after PayGate received them.
+func retriable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false // the body can't be sent again
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if err == nil && (resp == nil || resp.StatusCode < http.StatusInternalServerError) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(idempotencyKeyHeader) != ""
+}
+
+// retryAfter reads the Retry-After header of resp as seconds or an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return when.Sub(now)
+	}
+	return 0
+}
+
+// cancelBody releases the context of an attempt once its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
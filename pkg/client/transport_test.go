@@ -0,0 +1,150 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransport__retries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "ping" {
+			t.Errorf("unexpected body: %q", body)
+		}
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("pong"))
+		}
+	}))
+	defer server.Close()
+
+	var attempts []Attempt
+	client := NewHTTPClient(TransportOptions{
+		Retries: 3,
+		Backoff: time.Millisecond,
+		Interceptor: func(attempt Attempt) {
+			attempts = append(attempts, attempt)
+		},
+	})
+
+	req, _ := http.NewRequest("PUT", server.URL, bytes.NewReader([]byte("ping")))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "pong" {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("got %d attempts", len(attempts))
+	}
+	if !attempts[0].Retrying || attempts[0].Response.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("unexpected first attempt: %#v", attempts[0])
+	}
+	if last := attempts[2]; last.Retrying || last.Number != 2 || last.Response.StatusCode != http.StatusOK {
+		t.Errorf("unexpected last attempt: %#v", last)
+	}
+}
+
+func TestTransport__nonIdempotent(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(TransportOptions{Retries: 2, Backoff: time.Millisecond})
+
+	// POST requests could have been processed already
+	req, _ := http.NewRequest("POST", server.URL, bytes.NewReader([]byte("{}")))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("got %d calls", n)
+	}
+
+	// unless they carry an idempotency key
+	atomic.StoreInt32(&calls, 0)
+	req, _ = http.NewRequest("POST", server.URL, bytes.NewReader([]byte("{}")))
+	req.Header.Set("X-Idempotency-Key", "key")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("got %d calls", n)
+	}
+}
+
+func TestTransport__timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(TransportOptions{Timeout: 10 * time.Millisecond})
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Error("expected error")
+	}
+
+	// a longer timeout for one call
+	client = NewHTTPClient(TransportOptions{Timeout: time.Millisecond})
+	ctx := WithTimeout(context.Background(), 5*time.Second)
+	req, _ = http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	resp := &http.Response{Header: make(http.Header)}
+
+	if d := retryAfter(resp, now); d != 0 {
+		t.Errorf("unexpected delay: %v", d)
+	}
+	resp.Header.Set("Retry-After", "3")
+	if d := retryAfter(resp, now); d != 3*time.Second {
+		t.Errorf("unexpected delay: %v", d)
+	}
+	resp.Header.Set("Retry-After", now.Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d := retryAfter(resp, now); d != time.Minute {
+		t.Errorf("unexpected delay: %v", d)
+	}
+	resp.Header.Set("Retry-After", "soon")
+	if d := retryAfter(resp, now); d != 0 {
+		t.Errorf("unexpected delay: %v", d)
+	}
+}
@@ -4,7 +4,77 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultHealthCheckTTL is how long the result of checking a dependency is reused
+const DefaultHealthCheckTTL = 10 * time.Second
+
 type Admin struct {
 	BindAddress           string
 	DisableConfigEndpoint bool
+
+	// EnableSeedEndpoint registers POST /seed for creating sample data in demo environments.
+	EnableSeedEndpoint bool
+
+	// Impersonation allows admin users to make read-only requests on behalf of an organization.
+	Impersonation *Impersonation
+
+	// HealthCheckTTL is how long GET /live and GET /ready reuse the result of checking
+	// Moov Customers and Accounts before they're checked again.
+	HealthCheckTTL time.Duration
+}
+
+func (cfg Admin) Validate() error {
+	if cfg.HealthCheckTTL < 0 {
+		return errors.New("negative healthCheckTTL")
+	}
+	if err := cfg.Impersonation.Validate(); err != nil {
+		return fmt.Errorf("impersonation: %v", err)
+	}
+	return nil
+}
+
+// HealthCheckExpiration returns how long the result of checking a dependency is reused.
+func (cfg Admin) HealthCheckExpiration() time.Duration {
+	if cfg.HealthCheckTTL == 0 {
+		return DefaultHealthCheckTTL
+	}
+	return cfg.HealthCheckTTL
+}
+
+// Impersonation lists the users, by their X-User-ID header, who can make read-only requests
+// of the API as any organization from the admin server.
+type Impersonation struct {
+	Users []string
+
+	// RevealMicroDepositAmounts allows impersonated requests to read micro-deposit amounts
+	// with ?revealAmounts=true. Otherwise they're masked as they'd verify the account.
+	RevealMicroDepositAmounts bool
+}
+
+func (cfg *Impersonation) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Users) == 0 {
+		return errors.New("no users")
+	}
+	return nil
+}
+
+// Allowed returns true if userID can impersonate organizations.
+func (cfg *Impersonation) Allowed(userID string) bool {
+	if cfg == nil || userID == "" {
+		return false
+	}
+	for i := range cfg.Users {
+		if cfg.Users[i] == userID {
+			return true
+		}
+	}
+	return false
 }
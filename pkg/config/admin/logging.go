@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+type logLevelRequest struct {
+	// Module is a service or package (e.g. pkg/upload) to change. Empty changes the default level.
+	Module string `json:"module"`
+	Level  string `json:"level"`
+
+	// Duration optionally reverts the change after it has passed (e.g. 30m)
+	Duration string `json:"duration"`
+}
+
+type logLevelResponse struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+func logLevels(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.LogLevels == nil {
+			route.Problem(w, route.Disabled.New("log levels are not configured"))
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			if err := updateLogLevel(cfg, r); err != nil {
+				route.Problem(w, err)
+				return
+			}
+		default:
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		level, modules := cfg.LogLevels.Levels()
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(logLevelResponse{
+			Level:   level,
+			Modules: modules,
+		})
+	}
+}
+
+func updateLogLevel(cfg *config.Config, r *http.Request) error {
+	var req logLevelRequest
+	if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+		return err
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration %q", req.Duration)
+		}
+		duration = d
+	}
+
+	previous := cfg.LogLevels.Level(req.Module)
+	if err := cfg.LogLevels.Set(req.Module, req.Level); err != nil {
+		return err
+	}
+
+	logger := cfg.Logger.With(log.Fields{
+		"module":   log.String(req.Module),
+		"logLevel": log.String(req.Level),
+	})
+	logger.Warn().Logf("changed log level from %q", previous)
+
+	if duration > 0 {
+		time.AfterFunc(duration, func() {
+			// Only revert if nobody has changed the level since
+			if !strings.EqualFold(cfg.LogLevels.Level(req.Module), req.Level) {
+				return
+			}
+			cfg.LogLevels.Set(req.Module, previous)
+			logger.Warn().Logf("reverted log level to %q after %v", previous, duration)
+		})
+	}
+	return nil
+}
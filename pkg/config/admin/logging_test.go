@@ -0,0 +1,84 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+)
+
+func TestLogLevelRoute(t *testing.T) {
+	cfg, err := config.FromFile(filepath.Join("..", "testdata", "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, _ := testclient.Admin(t)
+	RegisterRoutes(svc, cfg)
+
+	address := "http://" + svc.BindAddr() + "/logging/level"
+	put := func(t *testing.T, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("PUT", address, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := put(t, `{"module": "pkg/upload", "level": "debug"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("bogus HTTP status: %s", resp.Status)
+	}
+	var levels logLevelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&levels); err != nil {
+		t.Fatal(err)
+	}
+	if levels.Modules["upload"] != "debug" {
+		t.Errorf("unexpected levels: %#v", levels)
+	}
+
+	// unknown level
+	resp = put(t, `{"level": "trace"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %s", resp.Status)
+	}
+
+	// revert after the duration
+	resp = put(t, `{"level": "warn", "duration": "10ms"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("bogus HTTP status: %s", resp.Status)
+	}
+	if level := cfg.LogLevels.Level(""); level != "warn" {
+		t.Errorf("level=%q", level)
+	}
+	for i := 0; i < 50 && cfg.LogLevels.Level("") == "warn"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if level := cfg.LogLevels.Level(""); level != "" {
+		t.Errorf("expected revert, level=%q", level)
+	}
+
+	// GET returns the current levels
+	resp, err = http.DefaultClient.Get(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("bogus HTTP status: %s", resp.Status)
+	}
+}
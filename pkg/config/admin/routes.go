@@ -14,6 +14,8 @@ import (
 
 // RegisterRoutes will add HTTP handlers for PayGate's admin HTTP server
 func RegisterRoutes(svc *admin.Server, cfg *config.Config) {
+	svc.AddHandler("/logging/level", logLevels(cfg))
+
 	if cfg.Admin.DisableConfigEndpoint {
 		return
 	}
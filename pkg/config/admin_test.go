@@ -0,0 +1,53 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdmin__HealthCheckTTL(t *testing.T) {
+	cfg := Admin{}
+	if ttl := cfg.HealthCheckExpiration(); ttl != DefaultHealthCheckTTL {
+		t.Errorf("unexpected ttl: %v", ttl)
+	}
+
+	cfg.HealthCheckTTL = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.HealthCheckTTL = time.Minute
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if ttl := cfg.HealthCheckExpiration(); ttl != time.Minute {
+		t.Errorf("unexpected ttl: %v", ttl)
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	var cfg *Impersonation
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Allowed("jane") {
+		t.Error("expected impersonation to be disabled")
+	}
+
+	cfg = &Impersonation{}
+	if err := (Admin{Impersonation: cfg}).Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Users = []string{"jane"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.Allowed("jane") || cfg.Allowed("john") || cfg.Allowed("") {
+		t.Error("unexpected users allowed")
+	}
+}
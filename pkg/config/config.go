@@ -9,17 +9,20 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/moov-io/base/http/bind"
 
+	kitlog "github.com/go-kit/kit/log"
 	"github.com/moov-io/base/log"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Logger  log.Logger `yaml:"-" json:"-"`
-	Logging Logging
+	Logger    log.Logger `yaml:"-" json:"-"`
+	LogLevels *LogLevels `yaml:"-" json:"-"`
+	Logging   Logging
 
 	Http  HTTP
 	Admin Admin
@@ -28,16 +31,26 @@ type Config struct {
 	Database     Database
 
 	ODFI       ODFI
+	RDFI       *RDFI
 	Pipeline   Pipeline
 	Transfers  Transfers
 	Validation Validation
 
 	Customers Customers
+	Reports   Reports
+
+	Features Features
 }
 
 type Logging struct {
 	Format string
-	Level  string
+
+	// Level is the minimum level of logs to print. Logs without a level are info.
+	// Options: debug, info, warn, error or fatal
+	Level string
+
+	// Modules overrides Level for logs whose "service" or "package" matches a key.
+	Modules map[string]string
 }
 
 func Empty() *Config {
@@ -94,11 +107,14 @@ func Read(data []byte) (*Config, error) {
 }
 
 func setupLogger(cfg *Config) *Config {
+	var writer kitlog.Logger
 	if strings.EqualFold(cfg.Logging.Format, "json") {
-		cfg.Logger = log.NewJSONLogger()
+		writer = kitlog.NewJSONLogger(kitlog.NewSyncWriter(os.Stderr))
 	} else {
-		cfg.Logger = log.NewDefaultLogger()
+		writer = kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
 	}
+	cfg.LogLevels = newLogLevels(cfg.Logging)
+	cfg.Logger = log.NewLogger(newLevelFilter(writer, cfg.LogLevels))
 
 	return cfg
 }
@@ -110,9 +126,27 @@ func (cfg *Config) Validate() error {
 		return errors.New("missing Config")
 	}
 
+	if err := cfg.Database.Validate(); err != nil {
+		return fmt.Errorf("database: %v", err)
+	}
+	if err := cfg.Logging.Validate(); err != nil {
+		return fmt.Errorf("logging: %v", err)
+	}
+	if err := cfg.Http.Validate(); err != nil {
+		return fmt.Errorf("http: %v", err)
+	}
+	if err := cfg.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin: %v", err)
+	}
+	if err := cfg.Organization.Validate(); err != nil {
+		return fmt.Errorf("organization: %v", err)
+	}
 	if err := cfg.ODFI.Validate(); err != nil {
 		return fmt.Errorf("odfi: %v", err)
 	}
+	if err := cfg.RDFI.Validate(); err != nil {
+		return fmt.Errorf("rdfi: %v", err)
+	}
 	if err := cfg.Pipeline.Validate(); err != nil {
 		return fmt.Errorf("pipeline: %v", err)
 	}
@@ -126,6 +160,12 @@ func (cfg *Config) Validate() error {
 	if err := cfg.Customers.Validate(); err != nil {
 		return fmt.Errorf("customers: %v", err)
 	}
+	if err := cfg.Reports.Validate(); err != nil {
+		return fmt.Errorf("reports: %v", err)
+	}
+	if err := cfg.Features.Validate(); err != nil {
+		return fmt.Errorf("features: %v", err)
+	}
 
 	return nil
 }
@@ -6,21 +6,128 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 type Customers struct {
-	Endpoint string
-	Accounts Accounts
-	Debug    bool
+	Endpoint   string
+	Accounts   Accounts
+	Cache      *CustomersCache
+	Onboarding *Onboarding
+	Debug      bool
 }
 
 func (cfg Customers) Validate() error {
 	if err := cfg.Accounts.Decryptor.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.Cache.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Onboarding.Validate(); err != nil {
+		return fmt.Errorf("onboarding: %v", err)
+	}
 	return nil
 }
 
+const (
+	DefaultCustomersCacheTTL  = 1 * time.Minute
+	DefaultCustomersCacheSize = 1000
+)
+
+// CustomersCache keeps customers and accounts read from Moov Customers in memory for TTL,
+// evicting the least recently used once Size are held.
+type CustomersCache struct {
+	TTL  time.Duration
+	Size int
+}
+
+func (cfg *CustomersCache) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.TTL < 0 || cfg.Size < 0 {
+		return errors.New("cache: negative ttl or size")
+	}
+	return nil
+}
+
+// Expiration returns how long cached records are used for.
+func (cfg *CustomersCache) Expiration() time.Duration {
+	if cfg.TTL == 0 {
+		return DefaultCustomersCacheTTL
+	}
+	return cfg.TTL
+}
+
+// Capacity returns the most records which are cached.
+func (cfg *CustomersCache) Capacity() int {
+	if cfg.Size == 0 {
+		return DefaultCustomersCacheSize
+	}
+	return cfg.Size
+}
+
+const (
+	DefaultOFACMatchThreshold = 0.99
+	DefaultOFACMaxAge         = 7 * 24 * time.Hour
+)
+
+// Onboarding requires customers of a transfer to have completed onboarding in Moov Customers
+// beyond having an acceptable status.
+type Onboarding struct {
+	// RequireDisclaimers rejects transfers while either customer has unaccepted disclaimers.
+	RequireDisclaimers bool
+
+	// OFAC rejects transfers when either customer's latest OFAC search matched too closely.
+	OFAC *OFACCheck
+}
+
+func (cfg *Onboarding) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.OFAC.Validate()
+}
+
+type OFACCheck struct {
+	// MatchThreshold is the lowest match score which blocks a customer, between 0 and 1.
+	MatchThreshold float32
+
+	// MaxAge is how old an OFAC search can be before it's refreshed prior to checking it.
+	MaxAge time.Duration
+}
+
+func (cfg *OFACCheck) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MatchThreshold < 0 || cfg.MatchThreshold > 1 {
+		return errors.New("ofac: matchThreshold must be between 0 and 1")
+	}
+	if cfg.MaxAge < 0 {
+		return errors.New("ofac: negative maxAge")
+	}
+	return nil
+}
+
+// Threshold returns the lowest match score which blocks a customer.
+func (cfg *OFACCheck) Threshold() float32 {
+	if cfg == nil || cfg.MatchThreshold == 0 {
+		return DefaultOFACMatchThreshold
+	}
+	return cfg.MatchThreshold
+}
+
+// StaleAfter returns how old an OFAC search can be before it's refreshed.
+func (cfg *OFACCheck) StaleAfter() time.Duration {
+	if cfg == nil || cfg.MaxAge == 0 {
+		return DefaultOFACMaxAge
+	}
+	return cfg.MaxAge
+}
+
 type Accounts struct {
 	Decryptor Decryptor
 }
@@ -6,6 +6,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestCustomers_validate(t *testing.T) {
@@ -22,3 +23,33 @@ func TestCustomers_validate(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestCustomers__Onboarding(t *testing.T) {
+	cfg := Customers{
+		Onboarding: &Onboarding{
+			OFAC: &OFACCheck{MatchThreshold: 1.5},
+		},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Onboarding.OFAC = &OFACCheck{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if v := cfg.Onboarding.OFAC.Threshold(); v != DefaultOFACMatchThreshold {
+		t.Errorf("unexpected threshold: %v", v)
+	}
+	if v := cfg.Onboarding.OFAC.StaleAfter(); v != DefaultOFACMaxAge {
+		t.Errorf("unexpected max age: %v", v)
+	}
+
+	cfg.Onboarding.OFAC = &OFACCheck{MatchThreshold: 0.9, MaxAge: time.Hour}
+	if v := cfg.Onboarding.OFAC.Threshold(); v != 0.9 {
+		t.Errorf("unexpected threshold: %v", v)
+	}
+	if v := cfg.Onboarding.OFAC.StaleAfter(); v != time.Hour {
+		t.Errorf("unexpected max age: %v", v)
+	}
+}
@@ -5,7 +5,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/util"
 )
@@ -13,6 +16,65 @@ import (
 type Database struct {
 	SQLite *SQLite
 	MySQL  *MySQL
+
+	// InMemory keeps every table in an in-memory SQLite database which is discarded
+	// on shutdown. It's intended for demos and local development.
+	InMemory bool
+
+	Pool *DatabasePool
+
+	// ReadReplica is a read-only MySQL replica for list, search and report queries.
+	ReadReplica *ReadReplica
+
+	// SlowQueryThreshold is the duration after which repository queries are logged.
+	// A zero value disables logging, but query durations are always recorded.
+	SlowQueryThreshold time.Duration
+
+	// StatsInterval is how often the row counts and sizes of tables are collected.
+	StatsInterval time.Duration
+}
+
+func (cfg Database) Validate() error {
+	if cfg.SlowQueryThreshold < 0 {
+		return errors.New("negative slowQueryThreshold")
+	}
+	if cfg.StatsInterval < 0 {
+		return errors.New("negative statsInterval")
+	}
+	if err := cfg.Pool.Validate(); err != nil {
+		return fmt.Errorf("pool: %v", err)
+	}
+	if cfg.ReadReplica != nil && (cfg.MySQL == nil || cfg.InMemory) {
+		return errors.New("readReplica requires a mysql database")
+	}
+	if err := cfg.ReadReplica.Validate(); err != nil {
+		return fmt.Errorf("readReplica: %v", err)
+	}
+	return nil
+}
+
+// DatabasePool overrides the connection pool settings of *sql.DB. Zero values
+// keep the driver's defaults.
+type DatabasePool struct {
+	MaxOpenConnections    int
+	MaxIdleConnections    int
+	ConnectionMaxLifetime time.Duration
+}
+
+func (cfg *DatabasePool) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxOpenConnections < 0 || cfg.MaxIdleConnections < 0 {
+		return errors.New("negative connection limit")
+	}
+	if cfg.MaxOpenConnections > 0 && cfg.MaxIdleConnections > cfg.MaxOpenConnections {
+		return errors.New("maxIdleConnections is larger than maxOpenConnections")
+	}
+	if cfg.ConnectionMaxLifetime < 0 {
+		return errors.New("negative connectionMaxLifetime")
+	}
+	return nil
 }
 
 type SQLite struct {
@@ -33,3 +95,36 @@ func (cfg *MySQL) GetPassword() string {
 	}
 	return util.Or(pass, cfg.Password)
 }
+
+// ReadReplica is a MySQL replica of the primary database. Queries which can lag
+// behind writes are sent to it while it's reachable and to the primary otherwise.
+type ReadReplica struct {
+	Address  string
+	Username string
+	Password string
+	Database string
+
+	// HealthCheckInterval is how often the replica is pinged to decide where reads go.
+	HealthCheckInterval time.Duration
+}
+
+func (cfg *ReadReplica) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Address == "" {
+		return errors.New("missing address")
+	}
+	if cfg.HealthCheckInterval < 0 {
+		return errors.New("negative healthCheckInterval")
+	}
+	return nil
+}
+
+func (cfg *ReadReplica) GetPassword() string {
+	pass := os.Getenv("MYSQL_REPLICA_PASSWORD")
+	if cfg == nil {
+		return pass
+	}
+	return util.Or(pass, cfg.Password)
+}
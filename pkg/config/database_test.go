@@ -0,0 +1,66 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatabase__Validate(t *testing.T) {
+	cfg := Database{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.SlowQueryThreshold = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.SlowQueryThreshold = time.Second
+
+	cfg.StatsInterval = -1 * time.Minute
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.StatsInterval = time.Minute
+
+	cfg.Pool = &DatabasePool{MaxOpenConnections: 10, MaxIdleConnections: 5, ConnectionMaxLifetime: time.Minute}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Pool.MaxIdleConnections = 20
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Pool.MaxIdleConnections = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDatabase__ReadReplica(t *testing.T) {
+	cfg := Database{
+		ReadReplica: &ReadReplica{Address: "tcp(replica:3306)"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.MySQL = &MySQL{Address: "tcp(primary:3306)"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.ReadReplica.HealthCheckInterval = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.ReadReplica = &ReadReplica{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+)
+
+// Features gates behaviors which are rolled out to organizations gradually. A Feature which
+// isn't configured is enabled for everyone, so the rest of the config decides as before.
+type Features struct {
+	// AsyncTransfers accepts Transfers with 202 Accepted when transfers.async is configured.
+	AsyncTransfers *Feature
+
+	// AsyncMicroDeposits accepts micro-deposits with 202 Accepted when
+	// validation.microDeposits.async is configured.
+	AsyncMicroDeposits *Feature
+
+	// SameDayTransfers allows Transfers to be created with sameDay.
+	SameDayTransfers *Feature
+
+	// SameDayMicroDeposits originates micro-deposits as same-day entries when
+	// validation.microDeposits.sameDay is set.
+	SameDayMicroDeposits *Feature
+}
+
+func (cfg Features) Validate() error {
+	if err := cfg.AsyncTransfers.Validate(); err != nil {
+		return fmt.Errorf("asyncTransfers: %v", err)
+	}
+	if err := cfg.AsyncMicroDeposits.Validate(); err != nil {
+		return fmt.Errorf("asyncMicroDeposits: %v", err)
+	}
+	if err := cfg.SameDayTransfers.Validate(); err != nil {
+		return fmt.Errorf("sameDayTransfers: %v", err)
+	}
+	if err := cfg.SameDayMicroDeposits.Validate(); err != nil {
+		return fmt.Errorf("sameDayMicroDeposits: %v", err)
+	}
+	return nil
+}
+
+// Feature is enabled for the pilot Organizations, or for every organization except those
+// in ExcludedOrganizations once Enabled is set.
+type Feature struct {
+	Enabled bool
+
+	Organizations         []string
+	ExcludedOrganizations []string
+}
+
+func (cfg *Feature) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Organizations {
+		if contains(cfg.ExcludedOrganizations, cfg.Organizations[i]) {
+			return fmt.Errorf("organization %s is both included and excluded", cfg.Organizations[i])
+		}
+	}
+	return nil
+}
+
+// EnabledFor returns true if the Feature applies to organization.
+func (cfg *Feature) EnabledFor(organization string) bool {
+	if cfg == nil {
+		return true
+	}
+	if contains(cfg.ExcludedOrganizations, organization) {
+		return false
+	}
+	return cfg.Enabled || contains(cfg.Organizations, organization)
+}
+
+func contains(organizations []string, organization string) bool {
+	for i := range organizations {
+		if organizations[i] == organization {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestFeatures__Validate(t *testing.T) {
+	cfg := Features{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.SameDayTransfers = &Feature{
+		Organizations:         []string{"moov", "acme"},
+		ExcludedOrganizations: []string{"acme"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.SameDayTransfers.ExcludedOrganizations = nil
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFeature__EnabledFor(t *testing.T) {
+	var feature *Feature
+	if !feature.EnabledFor("moov") {
+		t.Error("expected unconfigured feature to be enabled")
+	}
+
+	// pilot organizations
+	feature = &Feature{
+		Organizations: []string{"moov"},
+	}
+	if !feature.EnabledFor("moov") || feature.EnabledFor("acme") {
+		t.Errorf("unexpected feature: %#v", feature)
+	}
+
+	// everyone except excluded organizations
+	feature = &Feature{
+		Enabled:               true,
+		ExcludedOrganizations: []string{"acme"},
+	}
+	if !feature.EnabledFor("moov") || feature.EnabledFor("acme") {
+		t.Errorf("unexpected feature: %#v", feature)
+	}
+
+	// disabled for everyone
+	feature = &Feature{}
+	if feature.EnabledFor("moov") {
+		t.Errorf("unexpected feature: %#v", feature)
+	}
+}
@@ -4,6 +4,159 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
 type HTTP struct {
 	BindAddress string
+
+	// MaxBodySize is the largest request body in bytes that's read. Defaults to 1MB.
+	MaxBodySize int64
+
+	// RateLimit throttles requests to the public HTTP server. Requests are
+	// not limited when it's empty.
+	RateLimit *RateLimit
+
+	// TrustedProxies are CIDR ranges of load balancers whose X-Forwarded-For header is
+	// read to find the address of clients.
+	TrustedProxies []string
+
+	// Signing requires HMAC signatures on requests from the listed organizations.
+	Signing *RequestSigning
+}
+
+func (cfg HTTP) Validate() error {
+	if cfg.MaxBodySize < 0 {
+		return errors.New("negative maxBodySize")
+	}
+	if err := cfg.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("rateLimit: %v", err)
+	}
+	for i := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cfg.TrustedProxies[i]); err != nil {
+			return fmt.Errorf("trustedProxies: %v", err)
+		}
+	}
+	if err := cfg.Signing.Validate(); err != nil {
+		return fmt.Errorf("signing: %v", err)
+	}
+	return nil
+}
+
+// TrustedProxy returns true if ip is within one of TrustedProxies.
+func (cfg HTTP) TrustedProxy(ip net.IP) bool {
+	for i := range cfg.TrustedProxies {
+		if _, network, err := net.ParseCIDR(cfg.TrustedProxies[i]); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimit holds token bucket limits applied to each organization and each user.
+type RateLimit struct {
+	Organization *Limit
+	User         *Limit
+}
+
+func (cfg *RateLimit) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if err := cfg.Organization.Validate(); err != nil {
+		return fmt.Errorf("organization: %v", err)
+	}
+	if err := cfg.User.Validate(); err != nil {
+		return fmt.Errorf("user: %v", err)
+	}
+	return nil
+}
+
+// Limit allows RequestsPerSecond on average with bursts of up to Burst requests.
+type Limit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+func (cfg *Limit) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.RequestsPerSecond <= 0 {
+		return errors.New("requestsPerSecond must be positive")
+	}
+	if cfg.Burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+	return nil
+}
+
+// RequestSigning holds the shared secrets of organizations which sign their requests
+// instead of using OAuth.
+type RequestSigning struct {
+	// Tolerance is how far a request's timestamp can be from the current time. Defaults to 5 minutes.
+	Tolerance time.Duration
+
+	Organizations []SigningOrganization
+}
+
+// SigningOrganization lists the secrets an organization signs requests with. Several
+// secrets are accepted at once so they can be rotated without downtime.
+type SigningOrganization struct {
+	Organization string
+	Secrets      []string
+}
+
+func (cfg *RequestSigning) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Tolerance < 0 {
+		return errors.New("negative tolerance")
+	}
+	seen := make(map[string]bool)
+	for i := range cfg.Organizations {
+		org := cfg.Organizations[i]
+		if org.Organization == "" {
+			return errors.New("missing organization")
+		}
+		if seen[org.Organization] {
+			return fmt.Errorf("duplicate organization %s", org.Organization)
+		}
+		seen[org.Organization] = true
+		if len(org.Secrets) == 0 {
+			return fmt.Errorf("organization %s has no secrets", org.Organization)
+		}
+		for j := range org.Secrets {
+			if len(org.Secrets[j]) < 32 {
+				return fmt.Errorf("organization %s has a secret shorter than 32 characters", org.Organization)
+			}
+		}
+	}
+	return nil
+}
+
+// Window returns how far timestamps can be from the current time.
+func (cfg *RequestSigning) Window() time.Duration {
+	if cfg == nil || cfg.Tolerance <= 0 {
+		return 5 * time.Minute
+	}
+	return cfg.Tolerance
+}
+
+// Secrets returns the secrets of orgID, which are empty when it doesn't sign requests.
+func (cfg *RequestSigning) Secrets(orgID string) []string {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Organizations {
+		if cfg.Organizations[i].Organization == orgID {
+			return cfg.Organizations[i].Secrets
+		}
+	}
+	return nil
 }
@@ -0,0 +1,104 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTP__Validate(t *testing.T) {
+	cfg := HTTP{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.MaxBodySize = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.MaxBodySize = 1024
+
+	cfg.RateLimit = &RateLimit{
+		Organization: &Limit{RequestsPerSecond: 10, Burst: 20},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.RateLimit.User = &Limit{RequestsPerSecond: 0.5}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.RateLimit.User = &Limit{Burst: 5}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHTTP__TrustedProxies(t *testing.T) {
+	cfg := HTTP{
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.TrustedProxy(net.ParseIP("10.1.2.3")) || cfg.TrustedProxy(net.ParseIP("192.0.2.1")) {
+		t.Error("unexpected trusted proxies")
+	}
+
+	cfg.TrustedProxies = []string{"10.0.0.1"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHTTP__Signing(t *testing.T) {
+	var cfg *RequestSigning
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Window() != 5*time.Minute || len(cfg.Secrets("foo")) != 0 {
+		t.Error("unexpected nil signing")
+	}
+
+	secret := strings.Repeat("a", 32)
+	cfg = &RequestSigning{
+		Tolerance: time.Minute,
+		Organizations: []SigningOrganization{
+			{Organization: "foo", Secrets: []string{secret}},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Window() != time.Minute {
+		t.Errorf("unexpected tolerance: %v", cfg.Window())
+	}
+	if s := cfg.Secrets("foo"); len(s) != 1 || s[0] != secret {
+		t.Errorf("unexpected secrets: %v", s)
+	}
+	if s := cfg.Secrets("bar"); len(s) != 0 {
+		t.Errorf("unexpected secrets: %v", s)
+	}
+
+	cfg.Organizations = append(cfg.Organizations, SigningOrganization{Organization: "foo", Secrets: []string{secret}})
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Organizations[1] = SigningOrganization{Organization: "bar", Secrets: []string{"short"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Organizations[1].Secrets = nil
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/moov-io/base/log"
+)
+
+// Debug marks logs which are only printed once the level is lowered to debug.
+//
+//	logger.With(config.Debug).Logf("uploading %s", filename)
+const Debug = log.Level("debug")
+
+// logLevels orders the levels set by moov-io/base/log
+var logLevels = map[string]int{
+	"debug": -1,
+	"info":  0,
+	"warn":  1,
+	"error": 2,
+	"fatal": 3,
+}
+
+func (cfg Logging) Validate() error {
+	if _, ok := parseLogLevel(cfg.Level); !ok {
+		return fmt.Errorf("unknown level %q", cfg.Level)
+	}
+	for module, level := range cfg.Modules {
+		if _, ok := parseLogLevel(level); !ok {
+			return fmt.Errorf("unknown level %q for module %s", level, module)
+		}
+	}
+	return nil
+}
+
+func parseLogLevel(level string) (int, bool) {
+	if level == "" {
+		return 0, true
+	}
+	n, ok := logLevels[strings.ToLower(level)]
+	return n, ok
+}
+
+// moduleName normalizes a module so "pkg/upload" and "Upload" both match logs
+// with package=upload.
+func moduleName(module string) string {
+	return strings.TrimPrefix(strings.ToLower(module), "pkg/")
+}
+
+// LogLevels holds the levels used to drop logs. It's shared with the admin server
+// so levels can be changed without a restart.
+type LogLevels struct {
+	mu      sync.RWMutex
+	level   int
+	names   Logging
+	modules map[string]int
+}
+
+func newLogLevels(cfg Logging) *LogLevels {
+	levels := &LogLevels{
+		names: Logging{
+			Level:   strings.ToLower(cfg.Level),
+			Modules: make(map[string]string),
+		},
+		modules: make(map[string]int),
+	}
+	levels.level, _ = parseLogLevel(cfg.Level)
+	for module, level := range cfg.Modules {
+		levels.setModule(module, level)
+	}
+	return levels
+}
+
+// Levels returns the current default level and per-module overrides.
+func (l *LogLevels) Levels() (string, map[string]string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	modules := make(map[string]string, len(l.names.Modules))
+	for k, v := range l.names.Modules {
+		modules[k] = v
+	}
+	return l.names.Level, modules
+}
+
+// Level returns the level for module, or the default level when module is empty.
+func (l *LogLevels) Level(module string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if module == "" {
+		return l.names.Level
+	}
+	return l.names.Modules[moduleName(module)]
+}
+
+// Set changes the level for module, or the default level when module is empty.
+// An empty level removes a module's override.
+func (l *LogLevels) Set(module, level string) error {
+	if _, ok := parseLogLevel(level); !ok {
+		return fmt.Errorf("unknown level %q", level)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if module == "" {
+		l.level, _ = parseLogLevel(level)
+		l.names.Level = strings.ToLower(level)
+		return nil
+	}
+	l.setModule(module, level)
+	return nil
+}
+
+// setModule updates a module's level. Callers must hold l.mu
+func (l *LogLevels) setModule(module, level string) {
+	module = moduleName(module)
+	if level == "" {
+		delete(l.modules, module)
+		delete(l.names.Modules, module)
+		return
+	}
+	l.modules[module], _ = parseLogLevel(level)
+	l.names.Modules[module] = strings.ToLower(level)
+}
+
+// allowed returns if a log of level is printed for module.
+func (l *LogLevels) allowed(level int, modules []string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	min := l.level
+	for i := range modules {
+		if n, ok := l.modules[moduleName(modules[i])]; ok {
+			min = n
+		}
+	}
+	return level >= min
+}
+
+// levelFilter drops logs below the configured level. Per-module levels are matched
+// against the "service" and "package" keys of each log.
+type levelFilter struct {
+	next   kitlog.Logger
+	levels *LogLevels
+}
+
+func newLevelFilter(next kitlog.Logger, levels *LogLevels) kitlog.Logger {
+	return &levelFilter{
+		next:   next,
+		levels: levels,
+	}
+}
+
+func (f *levelFilter) Log(keyvals ...interface{}) error {
+	level := 0
+	var modules []string
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case "level":
+			level, _ = parseLogLevel(fmt.Sprintf("%v", keyvals[i+1]))
+		case "service", "package":
+			modules = append(modules, fmt.Sprintf("%v", keyvals[i+1]))
+		}
+	}
+	if !f.levels.allowed(level, modules) {
+		return nil
+	}
+	return f.next.Log(keyvals...)
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/moov-io/base/log"
+)
+
+func TestLogging__Validate(t *testing.T) {
+	cfg := Logging{Level: "warn", Modules: map[string]string{"xferaggregator": "info"}}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Level = "verbose"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Level = ""
+	cfg.Modules["main"] = "loud"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestLogging__levelFilter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger(newLevelFilter(kitlog.NewLogfmtLogger(&buf), newLogLevels(Logging{
+		Level: "warn",
+		Modules: map[string]string{
+			"XferAggregator": "info",
+			"noisy":          "error",
+		},
+	})))
+
+	logger.Log("info message")
+	logger.Warn().Log("warn message")
+	logger.Set("service", log.String("XferAggregator")).Log("aggregator info")
+	logger.Set("service", log.String("noisy")).Warn().Log("noisy warn")
+	logger.Set("service", log.String("noisy")).Error().Log("noisy error")
+
+	out := buf.String()
+	if strings.Contains(out, "info message") || strings.Contains(out, "noisy warn") {
+		t.Errorf("expected logs to be dropped:\n%s", out)
+	}
+	for _, msg := range []string{"warn message", "aggregator info", "noisy error"} {
+		if !strings.Contains(out, msg) {
+			t.Errorf("missing %q:\n%s", msg, out)
+		}
+	}
+}
+
+func TestLogLevels__Set(t *testing.T) {
+	var buf bytes.Buffer
+	levels := newLogLevels(Logging{})
+	logger := log.NewLogger(newLevelFilter(kitlog.NewLogfmtLogger(&buf), levels))
+	upload := logger.Set("package", log.String("upload"))
+
+	upload.With(Debug).Log("before")
+	if err := levels.Set("pkg/upload", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	upload.With(Debug).Log("upload debug")
+	logger.With(Debug).Log("other debug")
+
+	level, modules := levels.Levels()
+	if level != "" || modules["upload"] != "debug" {
+		t.Errorf("level=%q modules=%#v", level, modules)
+	}
+
+	// remove the override
+	if err := levels.Set("upload", ""); err != nil {
+		t.Fatal(err)
+	}
+	upload.With(Debug).Log("after")
+
+	if err := levels.Set("", "trace"); err == nil {
+		t.Error("expected error")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "upload debug") {
+		t.Errorf("missing debug log:\n%s", out)
+	}
+	for _, msg := range []string{"before", "other debug", "after"} {
+		if strings.Contains(out, msg) {
+			t.Errorf("unexpected %q:\n%s", msg, out)
+		}
+	}
+}
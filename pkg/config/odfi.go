@@ -42,6 +42,10 @@ type ODFI struct {
 	OutboundPath string
 	ReturnPath   string
 
+	// PathSets are additional labeled sets of remote directories. Some ODFIs expect
+	// same-day and standard files in different directories.
+	PathSets []PathSet
+
 	// AllowedIPs is a comma separated list of IP addresses and CIDR ranges
 	// where connections are allowed. If this value is non-empty remote servers
 	// not within these ranges will not be connected to.
@@ -49,8 +53,31 @@ type ODFI struct {
 
 	OutboundFilenameTemplate string
 
-	FTP  *FTP
-	SFTP *SFTP
+	FTP   *FTP
+	SFTP  *SFTP
+	AS2   *AS2
+	HTTPS *HTTPS
+
+	// Credentials keeps the FTP or SFTP password and client private key encrypted so they
+	// can be rotated from the admin server.
+	Credentials *UploadCredentials
+
+	// Faults injects latency and errors into FTP and SFTP calls. Only use this
+	// to verify retries and alerting in test environments.
+	Faults *Faults
+
+	// Verification compares each uploaded file against the copy on the remote server.
+	Verification *Verification
+
+	// ResumableUploads continues interrupted uploads from the bytes already stored
+	// on the remote server instead of starting over.
+	ResumableUploads *ResumableUploads
+
+	// Throttle limits when and how fast files are transferred with the remote server.
+	Throttle *Throttle
+
+	// Agreement holds extra rules of the originator agreement with the ODFI.
+	Agreement *Agreement
 
 	Inbound Inbound
 
@@ -86,9 +113,105 @@ func (cfg *ODFI) Validate() error {
 	if err := cfg.FileConfig.Validate(); err != nil {
 		return fmt.Errorf("odfi config: %v", err)
 	}
+	if err := cfg.AS2.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.HTTPS.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if cfg.AS2 != nil && cfg.Verification != nil {
+		return errors.New("odfi config: verification isn't supported with as2, the MDN of each upload is verified instead")
+	}
+	if err := cfg.Faults.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.Verification.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.ResumableUploads.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.Throttle.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.Agreement.Validate(); err != nil {
+		return fmt.Errorf("odfi config: agreement: %v", err)
+	}
+	if err := cfg.SFTP.Validate(); err != nil {
+		return fmt.Errorf("odfi config: sftp: %v", err)
+	}
+	if cfg.Credentials != nil && cfg.FTP == nil && cfg.SFTP == nil {
+		return errors.New("odfi config: credentials are only used with ftp or sftp")
+	}
+	if err := cfg.Credentials.Validate(); err != nil {
+		return fmt.Errorf("odfi config: credentials: %v", err)
+	}
+	if err := cfg.Inbound.Validate(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
+	if err := cfg.validatePathSets(); err != nil {
+		return fmt.Errorf("odfi config: %v", err)
+	}
 	return nil
 }
 
+// validatePathSets checks each PathSet has a unique label and that no two sets
+// download files from the same directory.
+func (cfg *ODFI) validatePathSets() error {
+	labels := make(map[string]bool)
+	downloads := map[string]bool{
+		cfg.InboundPath: true,
+		cfg.ReturnPath:  true,
+	}
+	sameDay := false
+	for i := range cfg.PathSets {
+		set := cfg.PathSets[i]
+		if set.Label == "" {
+			return fmt.Errorf("path set %d: missing label", i)
+		}
+		if labels[set.Label] {
+			return fmt.Errorf("path set %s: duplicate label", set.Label)
+		}
+		labels[set.Label] = true
+
+		for _, path := range []string{set.InboundPath, set.ReturnPath} {
+			if downloads[path] {
+				return fmt.Errorf("path set %s: %q is already downloaded from", set.Label, path)
+			}
+			downloads[path] = true
+		}
+		if set.SameDay {
+			if sameDay {
+				return fmt.Errorf("path set %s: only one path set can receive same-day files", set.Label)
+			}
+			sameDay = true
+		}
+	}
+	return nil
+}
+
+// PathSet is a labeled set of remote directories used alongside the ODFI's InboundPath,
+// OutboundPath and ReturnPath.
+type PathSet struct {
+	Label string
+
+	InboundPath  string
+	OutboundPath string
+	ReturnPath   string
+
+	// SameDay uploads merged files with same-day batches into this OutboundPath.
+	SameDay bool
+}
+
+// ODFI returns a copy of cfg which exchanges files with the directories of this set.
+func (set PathSet) ODFI(cfg ODFI) ODFI {
+	cfg.InboundPath = set.InboundPath
+	cfg.OutboundPath = set.OutboundPath
+	cfg.ReturnPath = set.ReturnPath
+	cfg.PathSets = nil
+	return cfg
+}
+
 type Gateway struct {
 	Origin          string
 	OriginName      string
@@ -166,11 +289,33 @@ type SFTP struct {
 	ClientPrivateKey string
 	HostPublicKey    string
 
+	// ClientPrivateKeyFile is read each time the agent connects instead of ClientPrivateKey,
+	// and ClientPrivateKeyPassphrase decrypts either of them when they're protected.
+	ClientPrivateKeyFile       string
+	ClientPrivateKeyPassphrase string
+
+	// StrictHostKeyChecking refuses to connect unless HostPublicKey, either an authorized_keys
+	// or known_hosts entry, matches the key of the server.
+	StrictHostKeyChecking bool
+
 	DialTimeout           time.Duration
 	MaxConnectionsPerFile int
 	MaxPacketSize         int
 }
 
+func (cfg *SFTP) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.ClientPrivateKey != "" && cfg.ClientPrivateKeyFile != "" {
+		return errors.New("only one of clientPrivateKey and clientPrivateKeyFile can be set")
+	}
+	if cfg.StrictHostKeyChecking && cfg.HostPublicKey == "" {
+		return errors.New("strictHostKeyChecking requires hostPublicKey")
+	}
+	return nil
+}
+
 func (cfg *SFTP) Timeout() time.Duration {
 	if cfg == nil || cfg.DialTimeout == 0*time.Second {
 		return 10 * time.Second
@@ -198,12 +343,332 @@ func (cfg *SFTP) String() string {
 	buf.WriteString(fmt.Sprintf("Username=%s, ", cfg.Username))
 	buf.WriteString(fmt.Sprintf("Password=%s, ", mask.Password(cfg.Password)))
 	buf.WriteString(fmt.Sprintf("ClientPrivateKey:%v, ", cfg.ClientPrivateKey != ""))
-	buf.WriteString(fmt.Sprintf("HostPublicKey:%v}, ", cfg.HostPublicKey != ""))
+	buf.WriteString(fmt.Sprintf("ClientPrivateKeyFile=%s, ", cfg.ClientPrivateKeyFile))
+	buf.WriteString(fmt.Sprintf("ClientPrivateKeyPassphrase:%v, ", cfg.ClientPrivateKeyPassphrase != ""))
+	buf.WriteString(fmt.Sprintf("HostPublicKey:%v, ", cfg.HostPublicKey != ""))
+	buf.WriteString(fmt.Sprintf("StrictHostKeyChecking=%v}, ", cfg.StrictHostKeyChecking))
+	return buf.String()
+}
+
+// UploadCredentials encrypts the FTP or SFTP password, client private key and its passphrase with a keeper.
+// When it's set those values in the config are ciphertext from the keeper, and credentials
+// rotated from the admin server are saved encrypted in the database.
+type UploadCredentials struct {
+	Symmetric *Symmetric
+}
+
+func (cfg *UploadCredentials) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Symmetric == nil || cfg.Symmetric.KeyURI == "" {
+		return errors.New("missing symmetric keyURI")
+	}
+	return nil
+}
+
+// AS2 exchanges files with the ODFI over signed and encrypted AS2 messages.
+type AS2 struct {
+	// URL of the ODFI's AS2 server which receives our files.
+	URL string
+
+	// From is our AS2 identifier and To is the ODFI's identifier.
+	From string
+	To   string
+
+	// CertificateFile and PrivateKeyFile are PEM encoded files for signing our messages
+	// and decrypting what the ODFI sends. PartnerCertificateFile is the ODFI's certificate
+	// which files are encrypted for and its signatures are verified with.
+	CertificateFile        string
+	PrivateKeyFile         string
+	PartnerCertificateFile string
+
+	// BindAddress is where an HTTP server listens for files the ODFI sends.
+	// Messages sent to /return are saved as return files and all others as inbound files.
+	BindAddress string
+
+	// StorageDirectory holds received files until they're processed and deleted.
+	StorageDirectory string
+
+	// RequestTimeout is how long to wait for the ODFI's MDN after sending a file.
+	RequestTimeout time.Duration
+}
+
+func (cfg *AS2) Timeout() time.Duration {
+	if cfg == nil || cfg.RequestTimeout == 0*time.Second {
+		return 60 * time.Second
+	}
+	return cfg.RequestTimeout
+}
+
+func (cfg *AS2) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.URL == "" {
+		return errors.New("as2: missing url")
+	}
+	if cfg.From == "" || cfg.To == "" {
+		return errors.New("as2: missing from or to identifier")
+	}
+	if cfg.CertificateFile == "" || cfg.PrivateKeyFile == "" || cfg.PartnerCertificateFile == "" {
+		return errors.New("as2: missing certificate, private key or partner certificate file")
+	}
+	if cfg.StorageDirectory == "" {
+		return errors.New("as2: missing storageDirectory")
+	}
+	return nil
+}
+
+// HTTPS exchanges files with an ODFI's REST API. Remote paths are appended to URL, so files
+// are uploaded with POST {url}/{outboundPath}/{filename} and inbound files are listed with
+// GET {url}/{inboundPath}.
+type HTTPS struct {
+	URL string
+
+	// CAFile is a PEM encoded file of certificates which are trusted for the ODFI's server.
+	CAFile string
+
+	// ClientCertificateFile and ClientKeyFile are a PEM encoded certificate and private key
+	// presented to the ODFI for mutual TLS.
+	ClientCertificateFile string
+	ClientKeyFile         string
+
+	// OAuth2 requests access tokens for each call with the client credentials grant.
+	OAuth2 *OAuth2
+
+	// RequestTimeout is how long each call to the ODFI's API can take.
+	RequestTimeout time.Duration
+}
+
+func (cfg *HTTPS) Timeout() time.Duration {
+	if cfg == nil || cfg.RequestTimeout == 0*time.Second {
+		return 30 * time.Second
+	}
+	return cfg.RequestTimeout
+}
+
+func (cfg *HTTPS) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.URL == "" {
+		return errors.New("https: missing url")
+	}
+	if (cfg.ClientCertificateFile == "") != (cfg.ClientKeyFile == "") {
+		return errors.New("https: both clientCertificateFile and clientKeyFile are required for mutual TLS")
+	}
+	if err := cfg.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("https: %v", err)
+	}
+	return nil
+}
+
+func (cfg *HTTPS) String() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("HTTPS{URL=%s, ", cfg.URL))
+	buf.WriteString(fmt.Sprintf("ClientCertificate:%v, ", cfg.ClientCertificateFile != ""))
+	buf.WriteString(fmt.Sprintf("OAuth2:%v}", cfg.OAuth2 != nil))
 	return buf.String()
 }
 
+type OAuth2 struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+func (cfg *OAuth2) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.TokenURL == "" {
+		return errors.New("oauth2: missing tokenURL")
+	}
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return errors.New("oauth2: missing clientID or clientSecret")
+	}
+	return nil
+}
+
+type Faults struct {
+	// MaxLatency is the upper bound of a random delay added before each call.
+	MaxLatency time.Duration
+
+	// PartialUploadRate is the probability (0 to 1) an upload is cut short.
+	PartialUploadRate float64
+
+	// ConnectionResetRate is the probability (0 to 1) a call fails with a connection reset.
+	ConnectionResetRate float64
+}
+
+func (cfg *Faults) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxLatency < 0 {
+		return errors.New("faults: negative maxLatency")
+	}
+	if cfg.PartialUploadRate < 0 || cfg.PartialUploadRate > 1 {
+		return fmt.Errorf("faults: partialUploadRate=%v is not between 0 and 1", cfg.PartialUploadRate)
+	}
+	if cfg.ConnectionResetRate < 0 || cfg.ConnectionResetRate > 1 {
+		return fmt.Errorf("faults: connectionResetRate=%v is not between 0 and 1", cfg.ConnectionResetRate)
+	}
+	return nil
+}
+
+type Verification struct {
+	// Checksum downloads each uploaded file and compares its SHA-256 hash. Otherwise
+	// only the size of the remote file is compared.
+	Checksum bool
+
+	// Retries is how many times a file which fails verification is uploaded again.
+	Retries int
+}
+
+func (cfg *Verification) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Retries < 0 {
+		return errors.New("verification: negative retries")
+	}
+	return nil
+}
+
+const (
+	DefaultUploadChunkSize  = 1024 * 1024 // 1MB
+	DefaultUploadMaxResumes = 3
+)
+
+type ResumableUploads struct {
+	// ChunkSize is how many bytes are written at once. Interrupted uploads resume
+	// from the last chunk the remote server stored completely.
+	ChunkSize int64
+
+	// MaxResumes is how many times an interrupted upload is resumed before failing.
+	MaxResumes int
+}
+
+func (cfg *ResumableUploads) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.ChunkSize < 0 {
+		return errors.New("resumable uploads: negative chunkSize")
+	}
+	if cfg.MaxResumes < 0 {
+		return errors.New("resumable uploads: negative maxResumes")
+	}
+	return nil
+}
+
+func (cfg *ResumableUploads) Chunk() int64 {
+	if cfg == nil || cfg.ChunkSize == 0 {
+		return DefaultUploadChunkSize
+	}
+	return cfg.ChunkSize
+}
+
+func (cfg *ResumableUploads) Resumes() int {
+	if cfg == nil || cfg.MaxResumes == 0 {
+		return DefaultUploadMaxResumes
+	}
+	return cfg.MaxResumes
+}
+
+type Throttle struct {
+	// Timezone is the IANA timezone of Windows. Cutoffs.Timezone is used when empty.
+	Timezone string
+
+	// Windows are 24-hour "15:04-15:04" ranges when files can be uploaded or downloaded.
+	// Ranges which end before they start continue past midnight. Files are transferred
+	// at any time when no windows are set.
+	Windows []string
+
+	// BytesPerSecond limits the bandwidth of each connection to the remote server.
+	BytesPerSecond int64
+}
+
+func (cfg *Throttle) Location(fallback *time.Location) *time.Location {
+	if cfg == nil || cfg.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+func (cfg *Throttle) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Timezone != "" {
+		if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+			return fmt.Errorf("throttle: unknown timezone=%q", cfg.Timezone)
+		}
+	}
+	for i := range cfg.Windows {
+		parts := strings.Split(cfg.Windows[i], "-")
+		if len(parts) != 2 {
+			return fmt.Errorf("throttle: window %q is not a start-end range", cfg.Windows[i])
+		}
+		for _, p := range parts {
+			if _, err := time.Parse("15:04", strings.TrimSpace(p)); err != nil {
+				return fmt.Errorf("throttle: window %q: %v", cfg.Windows[i], err)
+			}
+		}
+	}
+	if cfg.BytesPerSecond < 0 {
+		return errors.New("throttle: negative bytesPerSecond")
+	}
+	return nil
+}
+
 type Inbound struct {
 	Interval time.Duration
+
+	// Workers is how many inbound and return files are downloaded and parsed at once.
+	Workers int
+
+	// Archive copies each processed file into a bucket.
+	Archive *InboundArchive
+}
+
+func (cfg Inbound) Validate() error {
+	if cfg.Workers < 0 {
+		return errors.New("inbound: negative workers")
+	}
+	if err := cfg.Archive.Validate(); err != nil {
+		return fmt.Errorf("inbound: %v", err)
+	}
+	return nil
+}
+
+func (cfg Inbound) Concurrency() int {
+	if cfg.Workers == 0 {
+		return 1
+	}
+	return cfg.Workers
+}
+
+type InboundArchive struct {
+	// BucketURI is a gocloud.dev/blob URL such as s3://bucket or file:///var/paygate/archive
+	BucketURI string
+}
+
+func (cfg *InboundArchive) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BucketURI == "" {
+		return errors.New("archive: missing bucketURI")
+	}
+	return nil
 }
 
 type FileConfig struct {
@@ -212,12 +677,58 @@ type FileConfig struct {
 	BalanceEntries bool
 	Addendum       Addendum
 	CompanyName    string
+
+	// Offset balances each merged file with entries to the ODFI's settlement account.
+	Offset *Offset
 }
 
 func (cfg FileConfig) Validate() error {
 	if err := cfg.BatchHeader.Validate(); err != nil {
 		return fmt.Errorf("file config: %v", err)
 	}
+	if err := cfg.Offset.Validate(); err != nil {
+		return fmt.Errorf("file config: %v", err)
+	}
+	return nil
+}
+
+// Offset is the settlement account which merged files are balanced against.
+type Offset struct {
+	// RoutingNumber of the settlement account, which defaults to the ODFI's routing number.
+	RoutingNumber string
+
+	AccountNumber string
+
+	// AccountType is either Checking or Savings
+	AccountType string
+
+	// Name is written as the IndividualName of each offset entry.
+	Name string
+
+	// PerFile appends one offsetting batch to each file instead of an offset per batch.
+	PerFile bool
+}
+
+func (cfg *Offset) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.RoutingNumber != "" {
+		if err := ach.CheckRoutingNumber(cfg.RoutingNumber); err != nil {
+			return fmt.Errorf("offset: %v", err)
+		}
+	}
+	if cfg.AccountNumber == "" {
+		return errors.New("offset: missing accountNumber")
+	}
+	switch strings.ToLower(cfg.AccountType) {
+	case "checking", "savings":
+	default:
+		return fmt.Errorf("offset: unknown accountType %q", cfg.AccountType)
+	}
+	if cfg.Name == "" {
+		return errors.New("offset: missing name")
+	}
 	return nil
 }
 
@@ -255,3 +766,53 @@ type Storage struct {
 type Local struct {
 	Directory string
 }
+
+// Agreement holds rules an ODFI imposes on top of NACHA's in their agreement with the originator.
+// Transfers which break them are rejected when they're created, and those created before a rule
+// was added are failed when their files are merged.
+type Agreement struct {
+	// MaxEntriesPerFile limits the entries of each uploaded file. Merged files are split to stay
+	// under it. Offset entries to the settlement account aren't counted.
+	MaxEntriesPerFile int
+
+	// ProhibitedSECCodes are Standard Entry Class codes, such as WEB, which can't be originated.
+	ProhibitedSECCodes []string
+
+	// DebitsOnly rejects Transfers which credit accounts at other financial institutions.
+	DebitsOnly bool
+
+	// MaxEffectiveDays is the most banking days an entry's effective date can be after today.
+	MaxEffectiveDays int
+}
+
+func (cfg *Agreement) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxEntriesPerFile < 0 {
+		return errors.New("negative maxEntriesPerFile")
+	}
+	if cfg.MaxEffectiveDays < 0 {
+		return errors.New("negative maxEffectiveDays")
+	}
+	for i := range cfg.ProhibitedSECCodes {
+		code := cfg.ProhibitedSECCodes[i]
+		if len(code) != 3 || strings.ToUpper(code) != code {
+			return fmt.Errorf("invalid SEC code %q", code)
+		}
+	}
+	return nil
+}
+
+// Prohibits returns true if entries of the SEC code can't be originated.
+func (cfg *Agreement) Prohibits(secCode string) bool {
+	if cfg == nil {
+		return false
+	}
+	for i := range cfg.ProhibitedSECCodes {
+		if strings.EqualFold(cfg.ProhibitedSECCodes[i], secCode) {
+			return true
+		}
+	}
+	return false
+}
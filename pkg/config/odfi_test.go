@@ -5,7 +5,9 @@
 package config
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCutoffs_Location(t *testing.T) {
@@ -34,3 +36,376 @@ func TestODFI__Validate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestODFI__Credentials(t *testing.T) {
+	cfg := &ODFI{
+		RoutingNumber: "987654320",
+		Cutoffs: Cutoffs{
+			Timezone: "America/New_York",
+			Windows:  []string{"16:30"},
+		},
+		FileConfig: FileConfig{
+			BatchHeader: BatchHeader{
+				CompanyIdentification: "MoovZZZZZZ",
+			},
+		},
+		Credentials: &UploadCredentials{
+			Symmetric: &Symmetric{KeyURI: "base64key://MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI="},
+		},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "only used with ftp or sftp") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.SFTP = &SFTP{Hostname: "sftp.bank.com:22"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Credentials.Symmetric = nil
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSFTP__Validate(t *testing.T) {
+	var cfg *SFTP
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &SFTP{ClientPrivateKey: "key", ClientPrivateKeyFile: "id_rsa"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.ClientPrivateKey = ""
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.StrictHostKeyChecking = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "requires hostPublicKey") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cfg.HostPublicKey = "sftp.bank.com ssh-ed25519 AAAA"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFaults__Validate(t *testing.T) {
+	var cfg *Faults
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &Faults{PartialUploadRate: 0.5, ConnectionResetRate: 0.1}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.PartialUploadRate = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.PartialUploadRate = 0
+	cfg.ConnectionResetRate = -0.1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestVerification__Validate(t *testing.T) {
+	var cfg *Verification
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &Verification{Checksum: true, Retries: 2}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Retries = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestResumableUploads(t *testing.T) {
+	var cfg *ResumableUploads
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Chunk() != DefaultUploadChunkSize || cfg.Resumes() != DefaultUploadMaxResumes {
+		t.Errorf("unexpected defaults: %d and %d", cfg.Chunk(), cfg.Resumes())
+	}
+
+	cfg = &ResumableUploads{ChunkSize: 4096, MaxResumes: 5}
+	if cfg.Chunk() != 4096 || cfg.Resumes() != 5 {
+		t.Errorf("unexpected chunk=%d resumes=%d", cfg.Chunk(), cfg.Resumes())
+	}
+
+	cfg.ChunkSize = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestThrottle__Validate(t *testing.T) {
+	var cfg *Throttle
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if loc := cfg.Location(time.UTC); loc != time.UTC {
+		t.Errorf("unexpected fallback location: %v", loc)
+	}
+
+	cfg = &Throttle{
+		Timezone:       "America/New_York",
+		Windows:        []string{"08:00-17:30", "22:00-02:00"},
+		BytesPerSecond: 1024,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if loc := cfg.Location(time.UTC); loc == nil || loc.String() != "America/New_York" {
+		t.Errorf("unexpected location: %v", loc)
+	}
+
+	cfg.Windows = []string{"08:00"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Windows = []string{"08:00-25:00"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Windows = nil
+	cfg.BytesPerSecond = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAgreement__Validate(t *testing.T) {
+	var cfg *Agreement
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Prohibits("WEB") {
+		t.Error("nil agreement prohibits nothing")
+	}
+
+	cfg = &Agreement{
+		MaxEntriesPerFile:  1000,
+		ProhibitedSECCodes: []string{"WEB", "TEL"},
+		DebitsOnly:         true,
+		MaxEffectiveDays:   2,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.Prohibits("TEL") || !cfg.Prohibits("web") || cfg.Prohibits("PPD") {
+		t.Errorf("unexpected prohibited SEC codes: %v", cfg.ProhibitedSECCodes)
+	}
+
+	cfg.ProhibitedSECCodes = []string{"web"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.ProhibitedSECCodes = nil
+	cfg.MaxEntriesPerFile = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.MaxEntriesPerFile = 0
+	cfg.MaxEffectiveDays = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestInbound(t *testing.T) {
+	cfg := Inbound{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Concurrency(); n != 1 {
+		t.Errorf("unexpected default workers: %d", n)
+	}
+
+	cfg.Workers = 4
+	if n := cfg.Concurrency(); n != 4 {
+		t.Errorf("unexpected workers: %d", n)
+	}
+
+	cfg.Workers = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Workers = 0
+	cfg.Archive = &InboundArchive{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Archive.BucketURI = "mem://"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestODFI__PathSets(t *testing.T) {
+	cfg := &ODFI{
+		RoutingNumber: "987654320",
+		InboundPath:   "inbound/",
+		OutboundPath:  "outbound/",
+		ReturnPath:    "returned/",
+		PathSets: []PathSet{
+			{
+				Label:        "same-day",
+				InboundPath:  "sd/inbound/",
+				OutboundPath: "sd/outbound/",
+				ReturnPath:   "sd/returned/",
+				SameDay:      true,
+			},
+		},
+	}
+	if err := cfg.validatePathSets(); err != nil {
+		t.Fatal(err)
+	}
+
+	set := cfg.PathSets[0].ODFI(*cfg)
+	if set.OutboundPath != "sd/outbound/" || set.RoutingNumber != "987654320" || len(set.PathSets) != 0 {
+		t.Errorf("unexpected config: %#v", set)
+	}
+	if cfg.OutboundPath != "outbound/" {
+		t.Errorf("original config changed: %s", cfg.OutboundPath)
+	}
+
+	// duplicate labels
+	cfg.PathSets = append(cfg.PathSets, PathSet{Label: "same-day", InboundPath: "a/", ReturnPath: "b/"})
+	if err := cfg.validatePathSets(); err == nil {
+		t.Error("expected error")
+	}
+
+	// downloading from the default directories again
+	cfg.PathSets[1] = PathSet{Label: "other", InboundPath: "inbound/", ReturnPath: "b/"}
+	if err := cfg.validatePathSets(); err == nil {
+		t.Error("expected error")
+	}
+
+	// two same-day sets
+	cfg.PathSets[1] = PathSet{Label: "other", InboundPath: "a/", ReturnPath: "b/", SameDay: true}
+	if err := cfg.validatePathSets(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.PathSets[1] = PathSet{Label: ""}
+	if err := cfg.validatePathSets(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAS2__Validate(t *testing.T) {
+	var cfg *AS2
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Timeout() != 60*time.Second {
+		t.Errorf("unexpected timeout: %v", cfg.Timeout())
+	}
+
+	cfg = &AS2{
+		URL:                    "https://as2.bank.com/receive",
+		From:                   "paygate",
+		To:                     "bank",
+		CertificateFile:        "as2.crt",
+		PrivateKeyFile:         "as2.key",
+		PartnerCertificateFile: "bank.crt",
+		StorageDirectory:       "/var/paygate/as2",
+		RequestTimeout:         10 * time.Second,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Timeout() != 10*time.Second {
+		t.Errorf("unexpected timeout: %v", cfg.Timeout())
+	}
+
+	cfg.PartnerCertificateFile = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.PartnerCertificateFile = "bank.crt"
+	cfg.To = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHTTPS__Validate(t *testing.T) {
+	var cfg *HTTPS
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Timeout() != 30*time.Second {
+		t.Errorf("unexpected timeout: %v", cfg.Timeout())
+	}
+
+	cfg = &HTTPS{
+		URL: "https://api.bank.com/ach",
+		OAuth2: &OAuth2{
+			TokenURL:     "https://api.bank.com/oauth2/token",
+			ClientID:     "paygate",
+			ClientSecret: "secret",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if s := cfg.String(); strings.Contains(s, "secret") {
+		t.Errorf("unexpected client secret: %s", s)
+	}
+
+	cfg.ClientCertificateFile = "client.crt"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.ClientKeyFile = "client.key"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.OAuth2.ClientSecret = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestOffset__Validate(t *testing.T) {
+	var cfg *Offset
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &Offset{
+		AccountNumber: "123456",
+		AccountType:   "Checking",
+		Name:          "Settlement",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.RoutingNumber = "12345"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.RoutingNumber = "121042882"
+	cfg.AccountType = "loan"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -4,7 +4,54 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Organization struct {
 	Header  string
 	Default string
+
+	// DueDiligence requires organizations to save due-diligence details of themselves
+	// as the originator before they can create transfers.
+	DueDiligence *DueDiligence
+}
+
+func (cfg Organization) Validate() error {
+	if err := cfg.DueDiligence.Validate(); err != nil {
+		return fmt.Errorf("dueDiligence: %v", err)
+	}
+	return nil
+}
+
+// DueDiligence fields which can be required of organizations.
+const (
+	DueDiligenceBusinessType = "businessType"
+	DueDiligenceTaxIDToken   = "taxIDToken"
+	DueDiligenceAddress      = "address"
+	DueDiligenceWebsite      = "website"
+)
+
+type DueDiligence struct {
+	// RequiredFields must be present in an organization's due-diligence before it
+	// can create transfers. Options: businessType, taxIDToken, address or website
+	RequiredFields []string
+}
+
+func (cfg *DueDiligence) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.RequiredFields) == 0 {
+		return errors.New("missing requiredFields")
+	}
+	for i := range cfg.RequiredFields {
+		switch cfg.RequiredFields[i] {
+		case DueDiligenceBusinessType, DueDiligenceTaxIDToken, DueDiligenceAddress, DueDiligenceWebsite:
+		default:
+			return fmt.Errorf("unknown required field %q", cfg.RequiredFields[i])
+		}
+	}
+	return nil
 }
@@ -0,0 +1,31 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestOrganization__Validate(t *testing.T) {
+	cfg := Organization{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.DueDiligence = &DueDiligence{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.DueDiligence.RequiredFields = []string{"businessType", "ssn"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.DueDiligence.RequiredFields = []string{"businessType", "taxIDToken", "address", "website"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
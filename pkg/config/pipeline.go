@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"text/template"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/util"
 )
@@ -23,6 +24,20 @@ Credits: ${{ .CreditTotal | printf "%.2f" }}
 Batches: {{ .BatchCount }}
 Total Entries: {{ .EntryCount }}
 `))
+
+	// DefaultEmailTemplates are the default email templates by language.
+	DefaultEmailTemplates = map[string]*template.Template{
+		"en": DefaultEmailTemplate,
+		"es": template.Must(template.New("email-es").Parse(`
+Se ha {{ if eq .Verb "upload" }}subido{{ else }}descargado{{ end }} un archivo{{ if .Hostname }}{{ if eq .Verb "upload" }} a{{ else }} de{{end}} {{ .Hostname }}{{end}} - {{ .Filename }}
+Nombre: {{ .CompanyName }}
+Débitos:  ${{ .DebitTotal | printf "%.2f" }}
+Créditos: ${{ .CreditTotal | printf "%.2f" }}
+
+Lotes: {{ .BatchCount }}
+Total de entradas: {{ .EntryCount }}
+`)),
+	}
 )
 
 type Pipeline struct {
@@ -30,6 +45,11 @@ type Pipeline struct {
 	Output        *Output
 	Merging       *Merging
 	AuditTrail    *AuditTrail
+	FileRetention *FileRetention
+	Duplicates    *Duplicates
+	Recovery      *Recovery
+	Sharding      *Sharding
+	Outbox        *Outbox
 	Stream        *StreamPipeline
 	Notifications *PipelineNotifications
 }
@@ -47,6 +67,21 @@ func (cfg Pipeline) Validate() error {
 	if err := cfg.AuditTrail.Validate(); err != nil {
 		return fmt.Errorf("audit-trail: %v", err)
 	}
+	if err := cfg.FileRetention.Validate(); err != nil {
+		return fmt.Errorf("file retention: %v", err)
+	}
+	if err := cfg.Duplicates.Validate(); err != nil {
+		return fmt.Errorf("duplicates: %v", err)
+	}
+	if err := cfg.Recovery.Validate(); err != nil {
+		return fmt.Errorf("recovery: %v", err)
+	}
+	if err := cfg.Sharding.Validate(); err != nil {
+		return fmt.Errorf("sharding: %v", err)
+	}
+	if err := cfg.Outbox.Validate(); err != nil {
+		return fmt.Errorf("outbox: %v", err)
+	}
 	if err := cfg.Stream.Validate(); err != nil {
 		return fmt.Errorf("stream: %v", err)
 	}
@@ -118,6 +153,217 @@ func (cfg *AuditTrail) Validate() error {
 	return nil
 }
 
+// DefaultFileRetentionInterval is how often retained files older than their retention are deleted
+const DefaultFileRetentionInterval = 1 * time.Hour
+
+// FileRetention keeps each uploaded file as it was sent to the ODFI, unlike the encrypted
+// AuditTrail, so it can be uploaded again when the ODFI hasn't received it.
+type FileRetention struct {
+	// BucketURI is a gocloud.dev/blob URL such as s3://bucket or file:///var/paygate/outbound
+	BucketURI string
+
+	// Retention is how long files are kept after they're first uploaded
+	Retention time.Duration
+
+	// Interval is how often files older than Retention are deleted
+	Interval time.Duration
+}
+
+func (cfg *FileRetention) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BucketURI == "" {
+		return errors.New("missing bucketURI")
+	}
+	if cfg.Retention <= 0 {
+		return errors.New("retention must be positive")
+	}
+	if cfg.Interval < 0 {
+		return errors.New("negative interval")
+	}
+	return nil
+}
+
+// ExpireInterval returns how often files older than Retention are deleted.
+func (cfg *FileRetention) ExpireInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0 {
+		return DefaultFileRetentionInterval
+	}
+	return cfg.Interval
+}
+
+// DefaultDuplicatesLookback is how long uploaded files are compared against
+const DefaultDuplicatesLookback = 72 * time.Hour
+
+// Duplicates compares each file before it's uploaded against the files uploaded
+// recently. Files with the same entries and effective dates are flagged, or not
+// uploaded when Block is set. Entries found in a recent file are always flagged.
+type Duplicates struct {
+	Lookback time.Duration
+	Block    bool
+}
+
+func (cfg *Duplicates) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Lookback < 0 {
+		return errors.New("negative lookback")
+	}
+	return nil
+}
+
+// Since returns the earliest upload time files are compared against.
+func (cfg *Duplicates) Since(now time.Time) time.Time {
+	if cfg == nil || cfg.Lookback == 0 {
+		return now.Add(-1 * DefaultDuplicatesLookback)
+	}
+	return now.Add(-1 * cfg.Lookback)
+}
+
+// DefaultStuckAfter is how long work can go without progress before it's reported as stuck
+const DefaultStuckAfter = 24 * time.Hour
+
+// DefaultRecoveryBatchSize is how many micro-deposits are read per query when scanning for stuck work
+const DefaultRecoveryBatchSize = 1000
+
+// Recovery reports transfers, merged files and micro-deposits which haven't made
+// progress within StuckAfter.
+type Recovery struct {
+	StuckAfter time.Duration
+
+	// BatchSize is how many rows are read per query when paging through micro-deposits
+	BatchSize int
+}
+
+func (cfg *Recovery) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.StuckAfter < 0 {
+		return errors.New("negative stuckAfter")
+	}
+	if cfg.BatchSize < 0 {
+		return errors.New("negative batchSize")
+	}
+	return nil
+}
+
+// StuckBefore returns the time work without progress since is reported as stuck.
+func (cfg *Recovery) StuckBefore(now time.Time) time.Time {
+	if cfg == nil || cfg.StuckAfter == 0 {
+		return now.Add(-1 * DefaultStuckAfter)
+	}
+	return now.Add(-1 * cfg.StuckAfter)
+}
+
+// Limit returns how many rows are read per query when scanning for stuck work.
+func (cfg *Recovery) Limit() int {
+	if cfg == nil || cfg.BatchSize == 0 {
+		return DefaultRecoveryBatchSize
+	}
+	return cfg.BatchSize
+}
+
+// DefaultOutboxArchiveInterval is how often published messages older than their retention are archived
+const DefaultOutboxArchiveInterval = 1 * time.Hour
+
+// Outbox controls how long the messages published to the stream are kept for replays.
+type Outbox struct {
+	// Retention is how long messages are kept after they're created. Older messages which
+	// were published are copied into the Archive bucket, when configured, and deleted.
+	Retention time.Duration
+
+	// Interval is how often messages older than Retention are archived
+	Interval time.Duration
+
+	Archive *OutboxArchive
+}
+
+func (cfg *Outbox) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Retention <= 0 {
+		return errors.New("retention must be positive")
+	}
+	if cfg.Interval < 0 {
+		return errors.New("negative interval")
+	}
+	if cfg.Archive != nil && cfg.Archive.BucketURI == "" {
+		return errors.New("archive: missing bucketURI")
+	}
+	return nil
+}
+
+// ArchiveInterval returns how often messages older than Retention are archived.
+func (cfg *Outbox) ArchiveInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0 {
+		return DefaultOutboxArchiveInterval
+	}
+	return cfg.Interval
+}
+
+type OutboxArchive struct {
+	// BucketURI is a gocloud.dev/blob URL such as s3://bucket or file:///var/paygate/outbox
+	BucketURI string
+}
+
+// DefaultShardLease is how long a shard is owned by an instance without being renewed
+const DefaultShardLease = 1 * time.Minute
+
+// Sharding partitions Transfers across PayGate instances which each merge and upload
+// the Transfers of the shards they own. Ownership is coordinated through the database.
+type Sharding struct {
+	// Shards is the total number of shards Transfers are partitioned into
+	Shards int
+
+	// By is the key Transfers are partitioned on, either "organization" (default) or "routingNumber"
+	By string
+
+	// InstanceID identifies this instance in the coordination tables and defaults to its hostname
+	InstanceID string
+
+	// LeaseDuration is how long a shard is owned without renewal
+	LeaseDuration time.Duration
+}
+
+func (cfg *Sharding) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Shards < 1 {
+		return errors.New("shards must be positive")
+	}
+	switch cfg.By {
+	case "", "organization", "routingNumber":
+	default:
+		return fmt.Errorf("unknown by: %q", cfg.By)
+	}
+	if cfg.LeaseDuration < 0 {
+		return errors.New("negative leaseDuration")
+	}
+	return nil
+}
+
+// Instance returns the ID this instance owns shards as.
+func (cfg *Sharding) Instance() string {
+	if cfg.InstanceID != "" {
+		return cfg.InstanceID
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// Lease returns how long a shard is owned without renewal.
+func (cfg *Sharding) Lease() time.Duration {
+	if cfg.LeaseDuration == 0 {
+		return DefaultShardLease
+	}
+	return cfg.LeaseDuration
+}
+
 type StreamPipeline struct {
 	InMem *InMemPipeline
 	Kafka *KafkaPipeline
@@ -162,6 +408,9 @@ func (cfg *PipelineNotifications) Validate() error {
 		if e.From == "" || len(e.To) == 0 || e.ConnectionURI == "" || e.CompanyName == "" {
 			return errors.New("email: missing configs")
 		}
+		if _, exists := DefaultEmailTemplates[e.Language]; e.Language != "" && !exists {
+			return fmt.Errorf("email: unsupported language %q", e.Language)
+		}
 	}
 	if cfg.PagerDuty != nil && cfg.PagerDuty.ApiKey == "" {
 		return errors.New("pagerduty: missing api key")
@@ -186,15 +435,28 @@ type Email struct {
 
 	Template    string
 	CompanyName string
+
+	// Language is the language of the default template and subject, either en or es.
+	Language string
 }
 
 func (e *Email) Tmpl() *template.Template {
 	if e == nil || e.Template == "" {
+		if tmpl, exists := DefaultEmailTemplates[e.lang()]; exists {
+			return tmpl
+		}
 		return DefaultEmailTemplate
 	}
 	return template.Must(template.New("custom-email").Parse(e.Template))
 }
 
+func (e *Email) lang() string {
+	if e == nil {
+		return ""
+	}
+	return e.Language
+}
+
 type PagerDuty struct {
 	ApiKey     string
 	From       string
@@ -0,0 +1,80 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/ach"
+)
+
+// RDFI enables receiving entries which other financial institutions originate
+// to accounts held with us. Received credits and debits are posted to the Accounts
+// service and entries for unknown accounts are returned.
+type RDFI struct {
+	// RoutingNumbers are the ABA routing numbers we receive entries for. Entries
+	// in inbound files addressed to other routing numbers are skipped.
+	RoutingNumbers []string
+
+	// Organization is the organization received transfers are listed under.
+	Organization string
+
+	Accounts AccountsService
+
+	// Encryption optionally encrypts the individual names and entry details of
+	// received transfers in the database.
+	Encryption *FieldEncryption
+}
+
+// AccountsService is the Moov Accounts instance which holds our accounts.
+type AccountsService struct {
+	Endpoint string
+	Debug    bool
+}
+
+func (cfg *RDFI) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.RoutingNumbers) == 0 {
+		return errors.New("missing routingNumbers")
+	}
+	for i := range cfg.RoutingNumbers {
+		if err := ach.CheckRoutingNumber(cfg.RoutingNumbers[i]); err != nil {
+			return fmt.Errorf("routingNumber %s: %v", cfg.RoutingNumbers[i], err)
+		}
+	}
+	if cfg.Organization == "" {
+		return errors.New("missing organization")
+	}
+	if err := cfg.Encryption.Validate(); err != nil {
+		return fmt.Errorf("encryption: %v", err)
+	}
+	return nil
+}
+
+// FieldEncryption encrypts fields which hold personal information. Encrypted fields
+// which are searched also store an HMAC-SHA256 hash of their value for exact-match lookups.
+type FieldEncryption struct {
+	Symmetric *Symmetric
+
+	// HashKey is the secret used to hash searchable fields. Changing it breaks
+	// lookups of previously saved values.
+	HashKey string
+}
+
+func (cfg *FieldEncryption) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Symmetric == nil || cfg.Symmetric.KeyURI == "" {
+		return errors.New("missing symmetric keyURI")
+	}
+	if cfg.HashKey == "" {
+		return errors.New("missing hashKey")
+	}
+	return nil
+}
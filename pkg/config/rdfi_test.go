@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestRDFI__Validate(t *testing.T) {
+	var cfg *RDFI
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &RDFI{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.RoutingNumbers = []string{"123456789"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.RoutingNumbers = []string{"053200019"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Organization = "moov"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFieldEncryption__Validate(t *testing.T) {
+	cfg := &RDFI{
+		RoutingNumbers: []string{"053200019"},
+		Organization:   "moov",
+		Encryption:     &FieldEncryption{},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Encryption.Symmetric = &Symmetric{KeyURI: "base64key://MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI="}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Encryption.HashKey = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
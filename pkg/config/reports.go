@@ -0,0 +1,35 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+)
+
+type Reports struct {
+	Daily *DailyReports
+}
+
+func (cfg Reports) Validate() error {
+	if err := cfg.Daily.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type DailyReports struct {
+	// Email will send each organization's daily summary to the recipients after every cutoff.
+	Email *Email
+}
+
+func (cfg *DailyReports) Validate() error {
+	if cfg == nil || cfg.Email == nil {
+		return nil
+	}
+	if e := cfg.Email; e.From == "" || len(e.To) == 0 || e.ConnectionURI == "" {
+		return errors.New("daily: email: missing configs")
+	}
+	return nil
+}
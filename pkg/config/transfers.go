@@ -5,19 +5,302 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/client"
 )
 
 type Transfers struct {
 	Limits Limits
+
+	// Async accepts Transfers with 202 Accepted and originates them from a queue.
+	Async *TransfersAsync
+
+	// Reveal allows users to read the full account numbers of uploaded entries.
+	Reveal *RevealAccountNumbers
+
+	// Approvals requires a second user to approve large Transfers before they're uploaded.
+	Approvals *TransferApprovals
+
+	// Retries reinitiates Transfers returned for insufficient or uncollected funds.
+	Retries *TransferRetries
+
+	// Availability holds the funds of settled Transfers for a number of banking days.
+	Availability *TransferAvailability
+
+	// Evidence stores documents proving receivers authorized their debits.
+	Evidence *TransferEvidence
 }
 
 func (cfg Transfers) Validate() error {
 	if err := cfg.Limits.Validate(); err != nil {
 		return fmt.Errorf("limits: %v", err)
 	}
+	if err := cfg.Async.Validate(); err != nil {
+		return fmt.Errorf("async: %v", err)
+	}
+	if err := cfg.Reveal.Validate(); err != nil {
+		return fmt.Errorf("reveal: %v", err)
+	}
+	if err := cfg.Approvals.Validate(); err != nil {
+		return fmt.Errorf("approvals: %v", err)
+	}
+	if err := cfg.Retries.Validate(); err != nil {
+		return fmt.Errorf("retries: %v", err)
+	}
+	if err := cfg.Availability.Validate(); err != nil {
+		return fmt.Errorf("availability: %v", err)
+	}
+	if err := cfg.Evidence.Validate(); err != nil {
+		return fmt.Errorf("evidence: %v", err)
+	}
+	return nil
+}
+
+const (
+	DefaultTransferWorkers  = 4
+	DefaultTransferInterval = 1 * time.Second
+)
+
+// TransfersAsync has Workers originate queued Transfers, checking the queue every Interval.
+type TransfersAsync struct {
+	Workers  int
+	Interval time.Duration
+}
+
+func (cfg *TransfersAsync) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Workers < 0 || cfg.Interval < 0 {
+		return errors.New("negative workers or interval")
+	}
+	return nil
+}
+
+func (cfg *TransfersAsync) Concurrency() int {
+	if cfg == nil || cfg.Workers == 0 {
+		return DefaultTransferWorkers
+	}
+	return cfg.Workers
+}
+
+func (cfg *TransfersAsync) PollInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0 {
+		return DefaultTransferInterval
+	}
+	return cfg.Interval
+}
+
+// RevealAccountNumbers lists the users, by their X-User-ID header, who can read the full
+// account numbers in the entries of uploaded Transfers from the admin server.
+type RevealAccountNumbers struct {
+	Users []string
+}
+
+func (cfg *RevealAccountNumbers) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.Users) == 0 {
+		return errors.New("no users")
+	}
+	return nil
+}
+
+// Allowed returns true if userID can read full account numbers.
+func (cfg *RevealAccountNumbers) Allowed(userID string) bool {
+	if cfg == nil || userID == "" {
+		return false
+	}
+	for i := range cfg.Users {
+		if cfg.Users[i] == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// TransferApprovals holds Transfers with an amount above Amount (in cents) until a second user
+// approves them from the admin server. Approvers lists the users, by their X-User-ID header,
+// who can approve Transfers created by someone else.
+type TransferApprovals struct {
+	Amount    int64
+	Approvers []string
+}
+
+func (cfg *TransferApprovals) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Amount < 0 {
+		return errors.New("negative amount")
+	}
+	if len(cfg.Approvers) == 0 {
+		return errors.New("no approvers")
+	}
+	return nil
+}
+
+// Required returns true if a Transfer of amount needs to be approved.
+func (cfg *TransferApprovals) Required(amount int64) bool {
+	return cfg != nil && amount > cfg.Amount
+}
+
+// Allowed returns true if userID can approve Transfers.
+func (cfg *TransferApprovals) Allowed(userID string) bool {
+	if cfg == nil || userID == "" {
+		return false
+	}
+	for i := range cfg.Approvers {
+		if cfg.Approvers[i] == userID {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// MaxRetryAttempts is how often NACHA rules allow an entry returned R01 or R09 to be reinitiated.
+	MaxRetryAttempts = 2
+
+	DefaultRetryDelay = 24 * time.Hour
+)
+
+// TransferRetries reinitiates Transfers returned for insufficient (R01) or uncollected (R09)
+// funds up to Attempts times. Each retry is originated from the transfer queue Delay after
+// the return was processed.
+type TransferRetries struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+func (cfg *TransferRetries) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Attempts < 0 || cfg.Attempts > MaxRetryAttempts {
+		return fmt.Errorf("attempts=%d must be between 0 and %d", cfg.Attempts, MaxRetryAttempts)
+	}
+	if cfg.Delay < 0 {
+		return errors.New("negative delay")
+	}
+	return nil
+}
+
+// MaxAttempts returns how often a returned Transfer is reinitiated, which is zero when
+// retries are disabled.
+func (cfg *TransferRetries) MaxAttempts() int {
+	if cfg == nil {
+		return 0
+	}
+	if cfg.Attempts == 0 {
+		return MaxRetryAttempts
+	}
+	return cfg.Attempts
+}
+
+func (cfg *TransferRetries) RetryDelay() time.Duration {
+	if cfg == nil || cfg.Delay == 0 {
+		return DefaultRetryDelay
+	}
+	return cfg.Delay
+}
+
+const (
+	// MaxHoldDays is the longest availability hold, in banking days, of a Transfer's funds.
+	MaxHoldDays = 30
+
+	DefaultAvailabilityWebhookInterval = 1 * time.Minute
+)
+
+// TransferAvailability treats the funds of Transfers debiting a customer's account as collected
+// DebitHoldDays banking days after they settle, and of Transfers crediting a customer's account
+// CreditHoldDays after. Organizations can override either in their configuration.
+type TransferAvailability struct {
+	DebitHoldDays  int
+	CreditHoldDays int
+
+	// Webhook receives a POST for each Transfer once its funds are available
+	Webhook *AvailabilityWebhook
+}
+
+func (cfg *TransferAvailability) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.DebitHoldDays < 0 || cfg.DebitHoldDays > MaxHoldDays {
+		return fmt.Errorf("debitHoldDays=%d must be between 0 and %d", cfg.DebitHoldDays, MaxHoldDays)
+	}
+	if cfg.CreditHoldDays < 0 || cfg.CreditHoldDays > MaxHoldDays {
+		return fmt.Errorf("creditHoldDays=%d must be between 0 and %d", cfg.CreditHoldDays, MaxHoldDays)
+	}
+	if err := cfg.Webhook.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// HoldDays returns how many banking days after settlement the funds of a debit or
+// credit are available, which is zero when no policy is configured.
+func (cfg *TransferAvailability) HoldDays(debit bool) int {
+	if cfg == nil {
+		return 0
+	}
+	if debit {
+		return cfg.DebitHoldDays
+	}
+	return cfg.CreditHoldDays
+}
+
+// AvailabilityWebhook checks for Transfers whose funds became available every Interval.
+type AvailabilityWebhook struct {
+	Endpoint string
+	Interval time.Duration
+
+	// Secret signs each webhook with HMAC-SHA256 so receivers can verify it was sent by PayGate
+	Secret string
+}
+
+func (cfg *AvailabilityWebhook) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Endpoint == "" {
+		return errors.New("missing webhook endpoint")
+	}
+	if cfg.Interval < 0 {
+		return errors.New("negative webhook interval")
+	}
+	if cfg.Secret != "" && len(cfg.Secret) < MinWebhookSecretLength {
+		return fmt.Errorf("webhook secret is shorter than %d characters", MinWebhookSecretLength)
+	}
+	return nil
+}
+
+func (cfg *AvailabilityWebhook) PollInterval() time.Duration {
+	if cfg.Interval == 0 {
+		return DefaultAvailabilityWebhookInterval
+	}
+	return cfg.Interval
+}
+
+// TransferEvidence saves the documents uploaded as evidence of a receiver's authorization in a
+// bucket, so they can be read from the admin server when a debit is returned as unauthorized.
+type TransferEvidence struct {
+	// BucketURI is a gocloud.dev/blob URL such as s3://bucket or file:///var/paygate/evidence
+	BucketURI string
+}
+
+func (cfg *TransferEvidence) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BucketURI == "" {
+		return errors.New("missing bucketURI")
+	}
 	return nil
 }
 
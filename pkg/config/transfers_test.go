@@ -5,7 +5,9 @@
 package config
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/client"
 )
@@ -62,3 +64,170 @@ func TestFixedLimits__Validate(t *testing.T) {
 	}
 
 }
+
+func TestTransfersAsync(t *testing.T) {
+	cfg := Transfers{Async: &TransfersAsync{}}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Async.Concurrency(); n != DefaultTransferWorkers {
+		t.Errorf("unexpected workers: %d", n)
+	}
+	if d := cfg.Async.PollInterval(); d != DefaultTransferInterval {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg.Async.Interval = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRevealAccountNumbers(t *testing.T) {
+	var cfg *RevealAccountNumbers
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Allowed("jane") {
+		t.Error("expected reveals to be disabled")
+	}
+
+	cfg = &RevealAccountNumbers{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Users = []string{"jane"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.Allowed("jane") || cfg.Allowed("john") || cfg.Allowed("") {
+		t.Error("unexpected users allowed")
+	}
+}
+
+func TestTransferApprovals(t *testing.T) {
+	var cfg *TransferApprovals
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Required(1e9) || cfg.Allowed("jane") {
+		t.Error("expected approvals to be disabled")
+	}
+
+	cfg = &TransferApprovals{Amount: 100000}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Approvers = []string{"jane"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Required(100000) || !cfg.Required(100001) {
+		t.Error("unexpected required amounts")
+	}
+	if !cfg.Allowed("jane") || cfg.Allowed("john") || cfg.Allowed("") {
+		t.Error("unexpected approvers")
+	}
+
+	cfg.Amount = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestTransferRetries(t *testing.T) {
+	var cfg *TransferRetries
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.MaxAttempts(); n != 0 {
+		t.Errorf("expected retries to be disabled: %d", n)
+	}
+
+	cfg = &TransferRetries{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.MaxAttempts(); n != MaxRetryAttempts {
+		t.Errorf("unexpected attempts: %d", n)
+	}
+	if d := cfg.RetryDelay(); d != DefaultRetryDelay {
+		t.Errorf("unexpected delay: %v", d)
+	}
+
+	cfg.Attempts, cfg.Delay = 1, 2*time.Hour
+	if cfg.MaxAttempts() != 1 || cfg.RetryDelay() != 2*time.Hour {
+		t.Errorf("unexpected retries: %#v", cfg)
+	}
+
+	// NACHA rules don't allow more than two retries
+	cfg.Attempts = 3
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Attempts, cfg.Delay = 2, -1*time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestTransferAvailability(t *testing.T) {
+	var cfg *TransferAvailability
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.HoldDays(true); n != 0 {
+		t.Errorf("unexpected hold: %d", n)
+	}
+
+	cfg = &TransferAvailability{DebitHoldDays: 3}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.HoldDays(true) != 3 || cfg.HoldDays(false) != 0 {
+		t.Errorf("unexpected availability: %#v", cfg)
+	}
+
+	cfg.CreditHoldDays = MaxHoldDays + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.CreditHoldDays = 0
+	cfg.Webhook = &AvailabilityWebhook{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Webhook.Endpoint = "http://localhost:8080/webhooks"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.Webhook.PollInterval(); d != DefaultAvailabilityWebhookInterval {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg.Webhook.Secret = "short"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Webhook.Secret = strings.Repeat("s", MinWebhookSecretLength)
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTransferEvidence(t *testing.T) {
+	var cfg *TransferEvidence
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	cfg = &TransferEvidence{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.BucketURI = "mem://"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -6,6 +6,8 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 type Validation struct {
@@ -28,6 +30,17 @@ type MicroDeposits struct {
 	Description string
 
 	SameDay bool
+
+	// DebitSweep builds the two credits and the debit of their sum into one PPD
+	// batch so the micro-deposits net to zero for the originator in a single file.
+	DebitSweep bool
+
+	// Async accepts micro-deposits with 202 Accepted and initiates them from a queue.
+	Async *MicroDepositsAsync
+
+	// Verification configures when micro-deposits expire and where changes of an
+	// account's verification state are sent.
+	Verification *MicroDepositVerification
 }
 
 func (cfg *MicroDeposits) Validate() error {
@@ -37,9 +50,133 @@ func (cfg *MicroDeposits) Validate() error {
 	if err := cfg.Source.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.Async.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Verification.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+const (
+	DefaultMicroDepositWorkers  = 2
+	DefaultMicroDepositInterval = 5 * time.Second
+)
+
+// MicroDepositsAsync has Workers initiate queued micro-deposits, checking the queue every Interval.
+type MicroDepositsAsync struct {
+	Workers  int
+	Interval time.Duration
+}
+
+func (cfg *MicroDepositsAsync) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Workers < 0 || cfg.Interval < 0 {
+		return errors.New("micro-deposits: negative async workers or interval")
+	}
+	return nil
+}
+
+func (cfg *MicroDepositsAsync) Concurrency() int {
+	if cfg.Workers == 0 {
+		return DefaultMicroDepositWorkers
+	}
+	return cfg.Workers
+}
+
+func (cfg *MicroDepositsAsync) PollInterval() time.Duration {
+	if cfg.Interval == 0 {
+		return DefaultMicroDepositInterval
+	}
+	return cfg.Interval
+}
+
+const (
+	DefaultMicroDepositExpiry          = 7 * 24 * time.Hour
+	DefaultMicroDepositAttempts        = 3
+	DefaultMicroDepositWebhookInterval = 1 * time.Minute
+
+	// MinWebhookSecretLength is the shortest secret webhooks are signed with
+	MinWebhookSecretLength = 32
+)
+
+// MicroDepositVerification reports processed micro-deposits as expired once ExpiresAfter
+// passes without the account being validated.
+type MicroDepositVerification struct {
+	ExpiresAfter time.Duration
+
+	// MaxAttempts is how many times amounts can be entered to confirm an account's micro-deposits
+	MaxAttempts int
+
+	// Webhook receives a POST for each change of verification state
+	Webhook *MicroDepositWebhook
+}
+
+func (cfg *MicroDepositVerification) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.ExpiresAfter < 0 {
+		return errors.New("micro-deposits: negative verification expiresAfter")
+	}
+	if cfg.MaxAttempts < 0 {
+		return errors.New("micro-deposits: negative verification maxAttempts")
+	}
+	if err := cfg.Webhook.Validate(); err != nil {
+		return err
+	}
 	return nil
 }
 
+func (cfg *MicroDepositVerification) Expiry() time.Duration {
+	if cfg == nil || cfg.ExpiresAfter == 0 {
+		return DefaultMicroDepositExpiry
+	}
+	return cfg.ExpiresAfter
+}
+
+func (cfg *MicroDepositVerification) Attempts() int {
+	if cfg == nil || cfg.MaxAttempts == 0 {
+		return DefaultMicroDepositAttempts
+	}
+	return cfg.MaxAttempts
+}
+
+// MicroDepositWebhook checks micro-deposits for new verification states every Interval.
+type MicroDepositWebhook struct {
+	Endpoint string
+	Interval time.Duration
+
+	// Secret signs each webhook with HMAC-SHA256 so receivers can verify it was sent by PayGate
+	Secret string
+}
+
+func (cfg *MicroDepositWebhook) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Endpoint == "" {
+		return errors.New("micro-deposits: missing webhook endpoint")
+	}
+	if cfg.Interval < 0 {
+		return errors.New("micro-deposits: negative webhook interval")
+	}
+	if cfg.Secret != "" && len(cfg.Secret) < MinWebhookSecretLength {
+		return fmt.Errorf("micro-deposits: webhook secret is shorter than %d characters", MinWebhookSecretLength)
+	}
+	return nil
+}
+
+func (cfg *MicroDepositWebhook) PollInterval() time.Duration {
+	if cfg.Interval == 0 {
+		return DefaultMicroDepositWebhookInterval
+	}
+	return cfg.Interval
+}
+
 type Source struct {
 	CustomerID   string
 	AccountID    string
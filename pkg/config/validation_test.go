@@ -5,7 +5,9 @@
 package config
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidation(t *testing.T) {
@@ -23,3 +25,71 @@ func TestMicroDeposits(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestMicroDepositsAsync(t *testing.T) {
+	cfg := &MicroDepositsAsync{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.Concurrency(); n != DefaultMicroDepositWorkers {
+		t.Errorf("unexpected workers: %d", n)
+	}
+	if d := cfg.PollInterval(); d != DefaultMicroDepositInterval {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg.Interval = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestMicroDepositVerification(t *testing.T) {
+	var cfg *MicroDepositVerification
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.Expiry(); d != DefaultMicroDepositExpiry {
+		t.Errorf("unexpected expiry: %v", d)
+	}
+	if n := cfg.Attempts(); n != DefaultMicroDepositAttempts {
+		t.Errorf("unexpected attempts: %d", n)
+	}
+
+	cfg = &MicroDepositVerification{
+		ExpiresAfter: 48 * time.Hour,
+		Webhook:      &MicroDepositWebhook{},
+	}
+	if d := cfg.Expiry(); d != 48*time.Hour {
+		t.Errorf("unexpected expiry: %v", d)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Webhook.Endpoint = "http://localhost:8080/webhook"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.Webhook.PollInterval(); d != DefaultMicroDepositWebhookInterval {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg.Webhook.Secret = "short"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Webhook.Secret = strings.Repeat("s", MinWebhookSecretLength)
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.MaxAttempts = 5
+	if n := cfg.Attempts(); n != 5 {
+		t.Errorf("unexpected attempts: %d", n)
+	}
+	cfg.MaxAttempts = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
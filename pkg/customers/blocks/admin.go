@@ -0,0 +1,190 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package blocks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovadmin "github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for placing and removing blocks on Customer Accounts
+func RegisterAdminRoutes(cfg *config.Config, svc *moovadmin.Server, repo Repository) {
+	svc.AddHandler("/customers/{customerID}/accounts/{accountID}/blocks", accountBlocks(cfg, repo))
+	svc.AddHandler("/customers/{customerID}/accounts/{accountID}/blocks/{blockID}", removeBlock(cfg, repo))
+}
+
+func accountBlocks(cfg *config.Config, repo Repository) http.HandlerFunc {
+	get, create := getBlocks(cfg, repo), createBlock(cfg, repo)
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			get(w, r)
+		case http.MethodPost:
+			create(w, r)
+		default:
+			route.NewResponder(cfg, w, r).Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+		}
+	}
+}
+
+func getBlocks(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if responder.OrganizationID == "" {
+			responder.Problem(route.MissingOrganization.New("missing organization"))
+			return
+		}
+
+		customerID, accountID := route.ReadPathID("customerID", r), route.ReadPathID("accountID", r)
+		blocks, err := repo.getBlocks(responder.OrganizationID, customerID, accountID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if blocks == nil {
+			blocks = []admin.AccountBlock{}
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(blocks)
+		})
+	}
+}
+
+func createBlock(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if responder.OrganizationID == "" {
+			responder.Problem(route.MissingOrganization.New("missing organization"))
+			return
+		}
+
+		var request admin.CreateAccountBlock
+		if err := route.DecodeJSON(r, &request, route.DisallowUnknownFields); err != nil {
+			responder.Problem(err)
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+
+		verr := &route.ValidationError{}
+		if userID == "" {
+			verr.Add("X-User-ID", "missing")
+		}
+		switch request.Direction {
+		case admin.DEBITS, admin.CREDITS, admin.BOTH:
+		default:
+			verr.Add("direction", "%q isn't debits, credits or both", request.Direction)
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			verr.Add("reason", "missing")
+		}
+		if err := verr.Err(); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		block := &admin.AccountBlock{
+			BlockID:    base.ID(),
+			CustomerID: route.ReadPathID("customerID", r),
+			AccountID:  route.ReadPathID("accountID", r),
+			Direction:  request.Direction,
+			Reason:     request.Reason,
+			CreatedBy:  userID,
+			Created:    time.Now(),
+		}
+		if err := repo.createBlock(responder.OrganizationID, block); err != nil {
+			responder.Problem(route.Internal.New("saving account block: %v", err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"customerID": log.String(block.CustomerID),
+			"accountID":  log.String(block.AccountID),
+			"blockID":    log.String(block.BlockID),
+			"direction":  log.String(string(block.Direction)),
+			"userID":     log.String(userID),
+			"reason":     log.String(block.Reason),
+		}).Log("blocked account")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(block)
+		})
+	}
+}
+
+func removeBlock(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodDelete {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		if responder.OrganizationID == "" {
+			responder.Problem(route.MissingOrganization.New("missing organization"))
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+		if userID == "" {
+			verr := &route.ValidationError{}
+			verr.Add("X-User-ID", "missing")
+			responder.Problem(verr.Err())
+			return
+		}
+
+		customerID, accountID := route.ReadPathID("customerID", r), route.ReadPathID("accountID", r)
+		blockID := route.ReadPathID("blockID", r)
+		block, err := repo.getBlock(responder.OrganizationID, blockID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if block == nil || block.CustomerID != customerID || block.AccountID != accountID {
+			responder.Problem(route.NotFound.New("blockID=%s not found", blockID))
+			return
+		}
+		if block.Removed != nil {
+			responder.Problem(route.Conflict.New("blockID=%s was removed by userID=%q", blockID, block.RemovedBy))
+			return
+		}
+
+		if err := repo.removeBlock(responder.OrganizationID, blockID, userID); err != nil {
+			if errors.Is(err, errBlockRemoved) {
+				responder.Problem(route.Conflict.Wrap(err))
+				return
+			}
+			responder.Problem(route.Internal.New("removing account block: %v", err))
+			return
+		}
+		block, err = repo.getBlock(responder.OrganizationID, blockID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"customerID": log.String(customerID),
+			"accountID":  log.String(accountID),
+			"blockID":    log.String(blockID),
+			"userID":     log.String(userID),
+		}).Log("removed account block")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(block)
+		})
+	}
+}
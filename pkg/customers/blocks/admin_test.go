@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package blocks
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+)
+
+func TestAdmin__AccountBlocks(t *testing.T) {
+	repo := setupSQLiteDB(t)
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(config.Empty(), svc, repo)
+
+	customerID, accountID := base.ID(), base.ID()
+
+	blocks, resp, err := c.CustomersApi.GetAccountBlocks(context.TODO(), customerID, accountID, "moov", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(blocks) != 0 {
+		t.Fatalf("unexpected blocks: %#v", blocks)
+	}
+
+	// a reason is required
+	req := admin.CreateAccountBlock{Direction: admin.DEBITS}
+	_, resp, _ = c.CustomersApi.CreateAccountBlock(context.TODO(), customerID, accountID, "moov", "jane", req, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// the direction has to be known
+	req = admin.CreateAccountBlock{Direction: "sideways", Reason: "stop payment"}
+	_, resp, _ = c.CustomersApi.CreateAccountBlock(context.TODO(), customerID, accountID, "moov", "jane", req, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	req.Direction = admin.DEBITS
+	block, resp, err := c.CustomersApi.CreateAccountBlock(context.TODO(), customerID, accountID, "moov", "jane", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if block.BlockID == "" || block.AccountID != accountID || block.CreatedBy != "jane" || block.Reason != req.Reason {
+		t.Errorf("unexpected block: %#v", block)
+	}
+
+	blocks, resp, err = c.CustomersApi.GetAccountBlocks(context.TODO(), customerID, accountID, "moov", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(blocks) != 1 || blocks[0].BlockID != block.BlockID {
+		t.Fatalf("unexpected blocks: %#v", blocks)
+	}
+
+	// blocks of other accounts aren't found
+	_, resp, _ = c.CustomersApi.RemoveAccountBlock(context.TODO(), customerID, base.ID(), block.BlockID, "moov", "john", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	removed, resp, err := c.CustomersApi.RemoveAccountBlock(context.TODO(), customerID, accountID, block.BlockID, "moov", "john", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if removed.RemovedBy != "john" || removed.Removed == nil {
+		t.Errorf("unexpected block: %#v", removed)
+	}
+
+	_, resp, _ = c.CustomersApi.RemoveAccountBlock(context.TODO(), customerID, accountID, block.BlockID, "moov", "john", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package blocks
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+var errBlockRemoved = errors.New("block was already removed")
+
+type Repository interface {
+	getBlocks(organization, customerID, accountID string) ([]admin.AccountBlock, error)
+	getBlock(organization, blockID string) (*admin.AccountBlock, error)
+
+	// getActiveBlocks returns the blocks of accountIDs which haven't been removed
+	getActiveBlocks(accountIDs ...string) ([]admin.AccountBlock, error)
+
+	createBlock(organization string, block *admin.AccountBlock) error
+	removeBlock(organization, blockID, userID string) error
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+const blockColumns = `block_id, customer_id, account_id, direction, reason, created_by, created_at, removed_by, removed_at`
+
+func scanBlock(row interface{ Scan(...interface{}) error }) (*admin.AccountBlock, error) {
+	var block admin.AccountBlock
+	var removedBy *string
+	err := row.Scan(&block.BlockID, &block.CustomerID, &block.AccountID, &block.Direction, &block.Reason,
+		&block.CreatedBy, &block.Created, &removedBy, &block.Removed)
+	if err != nil {
+		return nil, err
+	}
+	if removedBy != nil {
+		block.RemovedBy = *removedBy
+	}
+	return &block, nil
+}
+
+func (r *sqlRepo) queryBlocks(name string, query string, args ...interface{}) ([]admin.AccountBlock, error) {
+	var out []admin.AccountBlock
+	err := database.QueryRows(r.db, name, query, args, func(rows *sql.Rows) error {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return err
+		}
+		out = append(out, *block)
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) getBlocks(organization, customerID, accountID string) ([]admin.AccountBlock, error) {
+	defer database.MeasureQuery("blocks", "getBlocks")()
+
+	query := `select ` + blockColumns + ` from account_blocks
+where organization = ? and customer_id = ? and account_id = ? order by created_at asc;`
+	return r.queryBlocks("account blocks", query, organization, customerID, accountID)
+}
+
+func (r *sqlRepo) getBlock(organization, blockID string) (*admin.AccountBlock, error) {
+	defer database.MeasureQuery("blocks", "getBlock")()
+
+	query := `select ` + blockColumns + ` from account_blocks where organization = ? and block_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	block, err := scanBlock(stmt.QueryRow(organization, blockID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return block, nil
+}
+
+func (r *sqlRepo) getActiveBlocks(accountIDs ...string) ([]admin.AccountBlock, error) {
+	if len(accountIDs) == 0 {
+		return nil, nil
+	}
+	defer database.MeasureQuery("blocks", "getActiveBlocks")()
+
+	query := fmt.Sprintf(`select `+blockColumns+` from account_blocks
+where account_id in (%s) and removed_at is null order by created_at asc;`, database.Placeholders(len(accountIDs)))
+	return r.queryBlocks("active account blocks", query, database.StringArgs(accountIDs)...)
+}
+
+func (r *sqlRepo) createBlock(organization string, block *admin.AccountBlock) error {
+	defer database.MeasureQuery("blocks", "createBlock")()
+
+	query := `insert into account_blocks (block_id, organization, customer_id, account_id, direction, reason, created_by, created_at)
+values (?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(block.BlockID, organization, block.CustomerID, block.AccountID, block.Direction, block.Reason, block.CreatedBy, block.Created)
+	return err
+}
+
+func (r *sqlRepo) removeBlock(organization, blockID, userID string) error {
+	defer database.MeasureQuery("blocks", "removeBlock")()
+
+	query := `update account_blocks set removed_by = ?, removed_at = ?
+where organization = ? and block_id = ? and removed_at is null;`
+	res, err := r.db.Exec(query, userID, time.Now(), organization, blockID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBlockRemoved
+	}
+	return nil
+}
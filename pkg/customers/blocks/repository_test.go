@@ -0,0 +1,110 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package blocks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func mockBlock(customerID, accountID string, direction admin.AccountBlockDirection) *admin.AccountBlock {
+	return &admin.AccountBlock{
+		BlockID:    base.ID(),
+		CustomerID: customerID,
+		AccountID:  accountID,
+		Direction:  direction,
+		Reason:     "stop payment",
+		CreatedBy:  "jane",
+		Created:    time.Now().Truncate(time.Second),
+	}
+}
+
+func TestRepository__Blocks(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID, accountID := base.ID(), base.ID()
+
+		if blocks, err := repo.getBlocks("moov", customerID, accountID); len(blocks) != 0 || err != nil {
+			t.Fatalf("blocks=%#v error=%v", blocks, err)
+		}
+
+		debits := mockBlock(customerID, accountID, admin.DEBITS)
+		if err := repo.createBlock("moov", debits); err != nil {
+			t.Fatal(err)
+		}
+		credits := mockBlock(customerID, accountID, admin.CREDITS)
+		credits.Created = debits.Created.Add(time.Second)
+		if err := repo.createBlock("moov", credits); err != nil {
+			t.Fatal(err)
+		}
+
+		blocks, err := repo.getBlocks("moov", customerID, accountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blocks) != 2 || blocks[0].BlockID != debits.BlockID || blocks[1].Direction != admin.CREDITS {
+			t.Fatalf("unexpected blocks: %#v", blocks)
+		}
+		if blocks[0].Reason != "stop payment" || blocks[0].CreatedBy != "jane" || blocks[0].Removed != nil {
+			t.Errorf("unexpected block: %#v", blocks[0])
+		}
+
+		// other organizations don't see the blocks
+		if block, err := repo.getBlock("other", debits.BlockID); block != nil || err != nil {
+			t.Errorf("block=%#v error=%v", block, err)
+		}
+
+		if err := repo.removeBlock("moov", debits.BlockID, "john"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.removeBlock("moov", debits.BlockID, "john"); err != errBlockRemoved {
+			t.Errorf("expected removed error: %v", err)
+		}
+		block, err := repo.getBlock("moov", debits.BlockID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.RemovedBy != "john" || block.Removed == nil {
+			t.Errorf("unexpected block: %#v", block)
+		}
+
+		// removed blocks aren't active
+		active, err := repo.getActiveBlocks(accountID, base.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(active) != 1 || active[0].BlockID != credits.BlockID {
+			t.Errorf("unexpected active blocks: %#v", active)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package blocks
+
+import (
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// NewStrategy wraps strategy to reject Transfers which debit or credit a blocked Account.
+// Transfers created before the block are canceled when their file is merged instead.
+func NewStrategy(strategy fundflow.Strategy, repo Repository) fundflow.Strategy {
+	if strategy == nil || repo == nil {
+		return strategy
+	}
+	return &blockingStrategy{
+		Strategy: strategy,
+		repo:     repo,
+	}
+}
+
+type blockingStrategy struct {
+	fundflow.Strategy
+
+	repo Repository
+}
+
+func (s *blockingStrategy) Originate(companyID string, xfer *client.Transfer, source fundflow.Source, destination fundflow.Destination) ([]*ach.File, error) {
+	blocks, err := s.repo.getActiveBlocks(xfer.Source.AccountID, xfer.Destination.AccountID)
+	if err != nil {
+		return nil, route.Internal.New("reading account blocks: %v", err)
+	}
+	for i := range blocks {
+		if blocks[i].AccountID == xfer.Source.AccountID && blocks[i].Direction != admin.CREDITS {
+			return nil, route.Forbidden.New("debits of accountID=%s are blocked", xfer.Source.AccountID)
+		}
+		if blocks[i].AccountID == xfer.Destination.AccountID && blocks[i].Direction != admin.DEBITS {
+			return nil, route.Forbidden.New("credits of accountID=%s are blocked", xfer.Destination.AccountID)
+		}
+	}
+	return s.Strategy.Originate(companyID, xfer, source, destination)
+}
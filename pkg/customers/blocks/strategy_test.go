@@ -0,0 +1,69 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package blocks
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/x/route"
+)
+
+func TestStrategy__Originate(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	strategy := NewStrategy(&fundflow.MockStrategy{Files: []*ach.File{ach.NewFile()}}, repo)
+
+	sourceAccountID, destinationAccountID := base.ID(), base.ID()
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  destinationAccountID,
+		},
+	}
+	originate := func() error {
+		_, err := strategy.Originate("company", xfer, fundflow.Source{}, fundflow.Destination{})
+		return err
+	}
+	if err := originate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// debits of the destination don't block credits to it
+	if err := repo.createBlock("moov", mockBlock(xfer.Destination.CustomerID, destinationAccountID, admin.DEBITS)); err != nil {
+		t.Fatal(err)
+	}
+	if err := originate(); err != nil {
+		t.Fatal(err)
+	}
+
+	block := mockBlock(xfer.Source.CustomerID, sourceAccountID, admin.BOTH)
+	if err := repo.createBlock("moov", block); err != nil {
+		t.Fatal(err)
+	}
+	err := originate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if status := route.ErrorCodeOf(err).Status; status != http.StatusForbidden {
+		t.Errorf("unexpected status %d: %v", status, err)
+	}
+
+	if err := repo.removeBlock("moov", block.BlockID, "john"); err != nil {
+		t.Fatal(err)
+	}
+	if err := originate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package customers
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheLookups = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "customers_cache_lookups",
+		Help: "Counter of customer and account reads served from or missing the cache",
+	}, []string{"kind", "result"})
+)
+
+// NewCachedClient wraps client with a read-through cache of customers and accounts.
+// Without cfg client is returned as-is.
+//
+// Moov Customers doesn't notify PayGate of changes, so cached records are only refreshed
+// once they expire. Decrypted account numbers and OFAC searches are never cached.
+func NewCachedClient(client Client, cfg *config.CustomersCache) Client {
+	if cfg == nil || client == nil {
+		return client
+	}
+	return &cachedClient{
+		Client: client,
+		cache:  newLRU(cfg.Capacity(), cfg.Expiration()),
+	}
+}
+
+type cachedClient struct {
+	Client
+
+	cache *lru
+}
+
+func (c *cachedClient) Lookup(organization, customerID, requestID string) (*moovcustomers.Customer, error) {
+	key := fmt.Sprintf("customer/%s/%s", organization, customerID)
+	if v, ok := c.cache.get(key); ok {
+		cacheLookups.With("kind", "customer", "result", "hit").Add(1)
+		return v.(*moovcustomers.Customer), nil
+	}
+	cacheLookups.With("kind", "customer", "result", "miss").Add(1)
+
+	cust, err := c.Client.Lookup(organization, customerID, requestID)
+	if err == nil && cust != nil {
+		c.cache.add(key, cust)
+	}
+	return cust, err
+}
+
+func (c *cachedClient) FindAccount(organization, customerID, accountID string) (*moovcustomers.Account, error) {
+	key := fmt.Sprintf("account/%s/%s/%s", organization, customerID, accountID)
+	if v, ok := c.cache.get(key); ok {
+		cacheLookups.With("kind", "account", "result", "hit").Add(1)
+		return v.(*moovcustomers.Account), nil
+	}
+	cacheLookups.With("kind", "account", "result", "miss").Add(1)
+
+	account, err := c.Client.FindAccount(organization, customerID, accountID)
+	if err == nil && account != nil {
+		c.cache.add(key, account)
+	}
+	return account, err
+}
+
+// lru holds up to size values for ttl, evicting the least recently used value when full.
+type lru struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elm, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elm.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elm)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elm)
+	return entry.value, true
+}
+
+func (c *lru) add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if elm, ok := c.entries[key]; ok {
+		entry := elm.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(elm)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
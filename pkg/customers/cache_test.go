@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package customers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestCachedClient(t *testing.T) {
+	customerID, accountID := base.ID(), base.ID()
+	mock := &MockClient{
+		Customers: []*moovcustomers.Customer{{CustomerID: customerID}},
+		Accounts: map[string]*moovcustomers.Account{
+			accountID: {AccountID: accountID},
+		},
+	}
+	client := NewCachedClient(mock, &config.CustomersCache{})
+
+	if cust, err := client.Lookup("moov", customerID, ""); err != nil || cust == nil {
+		t.Fatalf("customer=%#v error=%v", cust, err)
+	}
+	if acct, err := client.FindAccount("moov", customerID, accountID); err != nil || acct == nil {
+		t.Fatalf("account=%#v error=%v", acct, err)
+	}
+
+	// cached records are returned while the Customers service fails
+	mock.Err = errors.New("bad error")
+	if cust, err := client.Lookup("moov", customerID, ""); err != nil || cust.CustomerID != customerID {
+		t.Errorf("customer=%#v error=%v", cust, err)
+	}
+	if acct, err := client.FindAccount("moov", customerID, accountID); err != nil || acct.AccountID != accountID {
+		t.Errorf("account=%#v error=%v", acct, err)
+	}
+
+	// other organizations aren't served from the cache
+	if _, err := client.Lookup("other", customerID, ""); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := client.DecryptAccount("moov", customerID, accountID); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCachedClient__nil(t *testing.T) {
+	mock := &MockClient{}
+	if client := NewCachedClient(mock, nil); client != mock {
+		t.Errorf("unexpected client: %#v", client)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	cache := newLRU(2, time.Minute)
+	cache.add("a", 1)
+	cache.add("b", 2)
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("expected a")
+	}
+
+	// b is evicted as the least recently used
+	cache.add("c", 3)
+	if _, ok := cache.get("b"); ok {
+		t.Error("unexpected b")
+	}
+	if v, ok := cache.get("c"); !ok || v.(int) != 3 {
+		t.Errorf("c=%v", v)
+	}
+
+	// expired values are dropped
+	cache = newLRU(2, -1*time.Second)
+	cache.add("a", 1)
+	if _, ok := cache.get("a"); ok {
+		t.Error("unexpected a")
+	}
+}
@@ -29,6 +29,8 @@ type Client interface {
 
 	LatestOFACSearch(organization, customerID, requestID string) (*OfacSearch, error)
 	RefreshOFACSearch(organization, customerID, requestID string) (*OfacSearch, error)
+
+	Disclaimers(organization, customerID, requestID string) ([]moovcustomers.Disclaimer, error)
 }
 
 type moovClient struct {
@@ -169,6 +171,26 @@ func (c *moovClient) RefreshOFACSearch(organization, customerID, requestID strin
 	}, nil
 }
 
+func (c *moovClient) Disclaimers(organization, customerID, requestID string) ([]moovcustomers.Disclaimer, error) {
+	ctx, cancelFn := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFn()
+
+	disclaimers, resp, err := c.underlying.CustomersApi.GetCustomerDisclaimers(ctx, customerID, &moovcustomers.GetCustomerDisclaimersOpts{
+		XRequestID:    optional.NewString(requestID),
+		XOrganization: optional.NewString(organization),
+	})
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if resp == nil || err != nil {
+		return nil, fmt.Errorf("get disclaimers: %v", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("get disclaimers: status=%s", resp.Status)
+	}
+	return disclaimers, nil
+}
+
 // NewClient returns an Client instance and will default to using the Customers address in
 // moov's standard Kubernetes setup.
 //
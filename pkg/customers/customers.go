@@ -21,4 +21,7 @@ type OfacSearch struct {
 	SdnName   string
 	Match     float32
 	CreatedAt time.Time
+
+	// Overridden is true when an admin allowed the match to the entity
+	Overridden bool
 }
@@ -14,6 +14,9 @@ type MockClient struct {
 	Transit   *moovcustomers.TransitAccountNumber
 	Result    *OfacSearch
 
+	// CustomerDisclaimers are returned for every customer
+	CustomerDisclaimers []moovcustomers.Disclaimer
+
 	Err error
 }
 
@@ -63,3 +66,10 @@ func (c *MockClient) RefreshOFACSearch(organization, customerID, requestID strin
 	}
 	return c.Result, nil
 }
+
+func (c *MockClient) Disclaimers(organization, customerID, requestID string) ([]moovcustomers.Disclaimer, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return c.CustomerDisclaimers, nil
+}
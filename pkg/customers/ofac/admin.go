@@ -0,0 +1,148 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovadmin "github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for reviewing and overriding OFAC searches
+func RegisterAdminRoutes(cfg *config.Config, svc *moovadmin.Server, repo Repository) {
+	svc.AddHandler("/customers/ofac-searches", getSearches(cfg, repo))
+	svc.AddHandler("/customers/{customerID}/ofac-override", createOverride(cfg, repo))
+}
+
+func getSearches(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		var minMatch float32
+		if v := r.URL.Query().Get("minMatch"); v != "" {
+			n, err := strconv.ParseFloat(v, 32)
+			if err != nil || n < 0 || n > 1 {
+				verr := &route.ValidationError{}
+				verr.Add("minMatch", "%q isn't between 0 and 1", v)
+				responder.Problem(verr.Err())
+				return
+			}
+			minMatch = float32(n)
+		}
+
+		searches, err := repo.getSearches(minMatch)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if searches == nil {
+			searches = []admin.OfacSearch{}
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(searches)
+		})
+	}
+}
+
+func createOverride(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		if responder.OrganizationID == "" {
+			responder.Problem(route.MissingOrganization.New("missing organization"))
+			return
+		}
+
+		var request admin.CreateOfacOverride
+		if err := route.DecodeJSON(r, &request, route.DisallowUnknownFields); err != nil {
+			responder.Problem(err)
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+
+		verr := &route.ValidationError{}
+		if userID == "" {
+			verr.Add("X-User-ID", "missing")
+		}
+		if request.EntityID == "" {
+			verr.Add("entityID", "missing")
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			verr.Add("reason", "missing")
+		}
+		if err := verr.Err(); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		customerID := route.ReadPathID("customerID", r)
+		search, err := repo.getSearch(responder.OrganizationID, customerID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if search == nil {
+			responder.Problem(route.NotFound.New("no OFAC search for customerID=%s", customerID))
+			return
+		}
+		if search.EntityID != request.EntityID {
+			responder.Problem(route.InvalidRequest.New("latest OFAC search of customerID=%s matched entityID=%s", customerID, search.EntityID))
+			return
+		}
+		if search.Override != nil {
+			responder.Problem(route.Conflict.New("entityID=%s is already overridden", search.EntityID))
+			return
+		}
+
+		override := &admin.OfacOverride{
+			OverrideID: base.ID(),
+			EntityID:   search.EntityID,
+			Match:      search.Match,
+			UserID:     userID,
+			Reason:     request.Reason,
+			Created:    time.Now(),
+		}
+		if err := repo.createOverride(responder.OrganizationID, customerID, override); err != nil {
+			if database.UniqueViolation(err) {
+				responder.Problem(route.Conflict.New("entityID=%s is already overridden", search.EntityID))
+				return
+			}
+			responder.Problem(route.Internal.New("saving OFAC override: %v", err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"customerID": log.String(customerID),
+			"entityID":   log.String(search.EntityID),
+			"userID":     log.String(userID),
+			"reason":     log.String(request.Reason),
+		}).Log("overrode OFAC match")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(override)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/antihax/optional"
+)
+
+func TestAdmin__OFACOverride(t *testing.T) {
+	repo := setupSQLiteDB(t)
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(config.Empty(), svc, repo)
+
+	customerID := base.ID()
+	if err := repo.saveSearch("moov", customerID, mockSearch(0.95)); err != nil {
+		t.Fatal(err)
+	}
+
+	searches, resp, err := c.CustomersApi.GetOfacSearches(context.TODO(), &admin.GetOfacSearchesOpts{
+		MinMatch: optional.NewFloat32(0.9),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(searches) != 1 || searches[0].CustomerID != customerID || searches[0].Override != nil {
+		t.Fatalf("unexpected searches: %#v", searches)
+	}
+
+	// the entity has to match the latest search
+	req := admin.CreateOfacOverride{EntityID: "other", Reason: "date of birth doesn't match"}
+	_, resp, _ = c.CustomersApi.CreateOfacOverride(context.TODO(), customerID, "moov", "jane", req, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// a reason is required
+	req = admin.CreateOfacOverride{EntityID: "1241421"}
+	_, resp, _ = c.CustomersApi.CreateOfacOverride(context.TODO(), customerID, "moov", "jane", req, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	req.Reason = "date of birth doesn't match"
+	override, resp, err := c.CustomersApi.CreateOfacOverride(context.TODO(), customerID, "moov", "jane", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if override.OverrideID == "" || override.UserID != "jane" || override.Reason != req.Reason {
+		t.Errorf("unexpected override: %#v", override)
+	}
+
+	_, resp, _ = c.CustomersApi.CreateOfacOverride(context.TODO(), customerID, "moov", "jane", req, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// searches of other customers aren't found
+	_, resp, _ = c.CustomersApi.CreateOfacOverride(context.TODO(), base.ID(), "moov", "jane", req, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	searches, resp, err = c.CustomersApi.GetOfacSearches(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(searches) != 1 || searches[0].Override == nil || searches[0].Override.OverrideID != override.OverrideID {
+		t.Errorf("unexpected searches: %#v", searches)
+	}
+}
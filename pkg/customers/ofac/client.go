@@ -0,0 +1,58 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/customers"
+)
+
+// NewRecordingClient wraps client to save each OFAC search returned by Moov Customers, which
+// are reviewed with the admin routes. Searches matching an overridden entity are marked as
+// Overridden so they don't block transfers.
+func NewRecordingClient(client customers.Client, repo Repository) customers.Client {
+	if client == nil || repo == nil {
+		return client
+	}
+	return &recordingClient{
+		Client: client,
+		repo:   repo,
+	}
+}
+
+type recordingClient struct {
+	customers.Client
+
+	repo Repository
+}
+
+func (c *recordingClient) LatestOFACSearch(organization, customerID, requestID string) (*customers.OfacSearch, error) {
+	search, err := c.Client.LatestOFACSearch(organization, customerID, requestID)
+	if err != nil || search == nil {
+		return search, err
+	}
+	return search, c.record(organization, customerID, search)
+}
+
+func (c *recordingClient) RefreshOFACSearch(organization, customerID, requestID string) (*customers.OfacSearch, error) {
+	search, err := c.Client.RefreshOFACSearch(organization, customerID, requestID)
+	if err != nil || search == nil {
+		return search, err
+	}
+	return search, c.record(organization, customerID, search)
+}
+
+func (c *recordingClient) record(organization, customerID string, search *customers.OfacSearch) error {
+	if err := c.repo.saveSearch(organization, customerID, search); err != nil {
+		return err
+	}
+	override, err := c.repo.getOverride(organization, customerID, search.EntityId)
+	if err != nil {
+		return fmt.Errorf("reading OFAC override: %v", err)
+	}
+	search.Overridden = override != nil
+	return nil
+}
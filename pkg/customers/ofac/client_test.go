@@ -0,0 +1,58 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/customers"
+)
+
+func TestRecordingClient(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	mock := &customers.MockClient{Result: mockSearch(0.95)}
+	client := NewRecordingClient(mock, repo)
+
+	customerID := base.ID()
+	search, err := client.LatestOFACSearch("moov", customerID, "requestID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.Overridden {
+		t.Errorf("unexpected search: %#v", search)
+	}
+	if saved, err := repo.getSearch("moov", customerID); err != nil || saved == nil {
+		t.Fatalf("search=%#v error=%v", saved, err)
+	}
+
+	override := &admin.OfacOverride{
+		OverrideID: base.ID(),
+		EntityID:   search.EntityId,
+		Match:      search.Match,
+		UserID:     "jane",
+		Reason:     "date of birth doesn't match",
+		Created:    time.Now(),
+	}
+	if err := repo.createOverride("moov", customerID, override); err != nil {
+		t.Fatal(err)
+	}
+	search, err = client.RefreshOFACSearch("moov", customerID, "requestID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !search.Overridden {
+		t.Errorf("unexpected search: %#v", search)
+	}
+
+	// errors from Moov Customers are returned as-is
+	mock.Err = errors.New("bad error")
+	if _, err := client.LatestOFACSearch("moov", customerID, "requestID"); err != mock.Err {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+type Repository interface {
+	// saveSearch replaces the saved search of a customer
+	saveSearch(organization, customerID string, search *customers.OfacSearch) error
+
+	getSearch(organization, customerID string) (*admin.OfacSearch, error)
+	getSearches(minMatch float32) ([]admin.OfacSearch, error)
+
+	getOverride(organization, customerID, entityID string) (*admin.OfacOverride, error)
+	createOverride(organization, customerID string, override *admin.OfacOverride) error
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) saveSearch(organization, customerID string, search *customers.OfacSearch) error {
+	defer database.MeasureQuery("ofac", "saveSearch")()
+
+	args := []interface{}{search.EntityId, search.SdnName, search.Match, search.CreatedAt, time.Now(), organization, customerID}
+
+	query := `update ofac_searches set entity_id = ?, sdn_name = ?, ofac_match = ?, searched_at = ?, updated_at = ?
+where organization = ? and customer_id = ?;`
+	res, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("updating OFAC search: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	// MySQL doesn't count rows which were unchanged, so an existing row can still conflict
+	insert := `insert into ofac_searches (entity_id, sdn_name, ofac_match, searched_at, updated_at, organization, customer_id)
+values (?, ?, ?, ?, ?, ?, ?);`
+	if _, err := r.db.Exec(insert, args...); err != nil {
+		if database.UniqueViolation(err) {
+			if _, err := r.db.Exec(query, args...); err != nil {
+				return fmt.Errorf("updating OFAC search: %v", err)
+			}
+			return nil
+		}
+		return fmt.Errorf("saving OFAC search: %v", err)
+	}
+	return nil
+}
+
+const searchColumns = `s.organization, s.customer_id, s.entity_id, s.sdn_name, s.ofac_match, s.searched_at,
+o.override_id, o.ofac_match, o.user_id, o.reason, o.created_at
+from ofac_searches as s left join ofac_overrides as o
+on o.organization = s.organization and o.customer_id = s.customer_id and o.entity_id = s.entity_id`
+
+func scanSearch(row interface{ Scan(...interface{}) error }) (*admin.OfacSearch, error) {
+	var search admin.OfacSearch
+	var sdnName, overrideID, userID, reason *string
+	var overrideMatch *float32
+	var created *time.Time
+	err := row.Scan(&search.Organization, &search.CustomerID, &search.EntityID, &sdnName, &search.Match, &search.SearchedAt,
+		&overrideID, &overrideMatch, &userID, &reason, &created)
+	if err != nil {
+		return nil, err
+	}
+	if sdnName != nil {
+		search.SdnName = *sdnName
+	}
+	if overrideID != nil && overrideMatch != nil && userID != nil && reason != nil && created != nil {
+		search.Override = &admin.OfacOverride{
+			OverrideID: *overrideID,
+			EntityID:   search.EntityID,
+			Match:      *overrideMatch,
+			UserID:     *userID,
+			Reason:     *reason,
+			Created:    *created,
+		}
+	}
+	return &search, nil
+}
+
+func (r *sqlRepo) getSearch(organization, customerID string) (*admin.OfacSearch, error) {
+	defer database.MeasureQuery("ofac", "getSearch")()
+
+	query := `select ` + searchColumns + ` where s.organization = ? and s.customer_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	search, err := scanSearch(stmt.QueryRow(organization, customerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return search, nil
+}
+
+func (r *sqlRepo) getSearches(minMatch float32) ([]admin.OfacSearch, error) {
+	defer database.MeasureQuery("ofac", "getSearches")()
+
+	query := `select ` + searchColumns + ` where s.ofac_match >= ? order by s.ofac_match desc, s.searched_at desc;`
+
+	var out []admin.OfacSearch
+	err := database.QueryRows(r.db, "OFAC searches", query, []interface{}{minMatch}, func(rows *sql.Rows) error {
+		search, err := scanSearch(rows)
+		if err != nil {
+			return err
+		}
+		out = append(out, *search)
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) getOverride(organization, customerID, entityID string) (*admin.OfacOverride, error) {
+	defer database.MeasureQuery("ofac", "getOverride")()
+
+	query := `select override_id, ofac_match, user_id, reason, created_at from ofac_overrides
+where organization = ? and customer_id = ? and entity_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	override := admin.OfacOverride{EntityID: entityID}
+	err = stmt.QueryRow(organization, customerID, entityID).Scan(&override.OverrideID, &override.Match, &override.UserID, &override.Reason, &override.Created)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &override, nil
+}
+
+func (r *sqlRepo) createOverride(organization, customerID string, override *admin.OfacOverride) error {
+	defer database.MeasureQuery("ofac", "createOverride")()
+
+	query := `insert into ofac_overrides (override_id, organization, customer_id, entity_id, ofac_match, user_id, reason, created_at)
+values (?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(override.OverrideID, organization, customerID, override.EntityID, override.Match, override.UserID, override.Reason, override.Created)
+	return err
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package ofac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func mockSearch(match float32) *customers.OfacSearch {
+	return &customers.OfacSearch{
+		EntityId:  "1241421",
+		SdnName:   "Jane Doe",
+		Match:     match,
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+}
+
+func TestRepository__Searches(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID := base.ID()
+
+		if search, err := repo.getSearch("moov", customerID); search != nil || err != nil {
+			t.Fatalf("search=%#v error=%v", search, err)
+		}
+
+		if err := repo.saveSearch("moov", customerID, mockSearch(0.5)); err != nil {
+			t.Fatal(err)
+		}
+		// saving again replaces the search
+		if err := repo.saveSearch("moov", customerID, mockSearch(0.95)); err != nil {
+			t.Fatal(err)
+		}
+		search, err := repo.getSearch("moov", customerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if search.CustomerID != customerID || search.EntityID != "1241421" || search.Match < 0.94 || search.Override != nil {
+			t.Errorf("unexpected search: %#v", search)
+		}
+
+		searches, err := repo.getSearches(0.9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(searches) != 1 || searches[0].CustomerID != customerID {
+			t.Errorf("unexpected searches: %#v", searches)
+		}
+		if searches, err := repo.getSearches(0.99); err != nil || len(searches) != 0 {
+			t.Errorf("searches=%#v error=%v", searches, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__Overrides(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		customerID := base.ID()
+
+		if override, err := repo.getOverride("moov", customerID, "1241421"); override != nil || err != nil {
+			t.Fatalf("override=%#v error=%v", override, err)
+		}
+		if err := repo.saveSearch("moov", customerID, mockSearch(0.95)); err != nil {
+			t.Fatal(err)
+		}
+
+		override := &admin.OfacOverride{
+			OverrideID: base.ID(),
+			EntityID:   "1241421",
+			Match:      0.95,
+			UserID:     "jane",
+			Reason:     "date of birth doesn't match",
+			Created:    time.Now().Truncate(time.Second),
+		}
+		if err := repo.createOverride("moov", customerID, override); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.getOverride("moov", customerID, "1241421")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.OverrideID != override.OverrideID || found.UserID != "jane" || found.Reason != override.Reason {
+			t.Errorf("unexpected override: %#v", found)
+		}
+
+		search, err := repo.getSearch("moov", customerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if search.Override == nil || search.Override.OverrideID != override.OverrideID {
+			t.Errorf("unexpected search: %#v", search)
+		}
+
+		// the entity can only be overridden once
+		override.OverrideID = base.ID()
+		if err := repo.createOverride("moov", customerID, override); err == nil || !database.UniqueViolation(err) {
+			t.Errorf("expected unique violation: %v", err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package customers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// ErrOnboarding is wrapped by errors from CheckOnboarding for customers which haven't
+// completed onboarding, rather than errors reading them from Moov Customers.
+var ErrOnboarding = errors.New("customer hasn't completed onboarding")
+
+// CheckOnboarding returns an error wrapping ErrOnboarding when customerID has unaccepted
+// disclaimers or their latest OFAC search matched a sanctioned entity at or above the
+// threshold. Searches older than the configured age are refreshed first and overridden
+// matches are allowed.
+//
+// ofacThreshold is an organization's match threshold, zero uses the configured threshold.
+func CheckOnboarding(cfg *config.Onboarding, client Client, organization, customerID, requestID string, ofacThreshold float32) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.RequireDisclaimers {
+		disclaimers, err := client.Disclaimers(organization, customerID, requestID)
+		if err != nil {
+			return err
+		}
+		for i := range disclaimers {
+			if disclaimers[i].AcceptedAt.IsZero() {
+				return fmt.Errorf("%w: customerID=%s has not accepted disclaimerID=%s", ErrOnboarding, customerID, disclaimers[i].DisclaimerID)
+			}
+		}
+	}
+	if cfg.OFAC != nil {
+		search, err := client.LatestOFACSearch(organization, customerID, requestID)
+		if err != nil {
+			return err
+		}
+		if search == nil || time.Since(search.CreatedAt) > cfg.OFAC.StaleAfter() {
+			if search, err = client.RefreshOFACSearch(organization, customerID, requestID); err != nil {
+				return err
+			}
+		}
+		if search == nil {
+			return fmt.Errorf("%w: customerID=%s has no OFAC search", ErrOnboarding, customerID)
+		}
+		if ofacThreshold <= 0 {
+			ofacThreshold = cfg.OFAC.Threshold()
+		}
+		if search.Match >= ofacThreshold && !search.Overridden {
+			return fmt.Errorf("%w: customerID=%s matched OFAC entityID=%s (%s) with %.2f", ErrOnboarding, customerID, search.EntityId, search.SdnName, search.Match)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package customers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestCheckOnboarding(t *testing.T) {
+	client := &MockClient{
+		CustomerDisclaimers: []moovcustomers.Disclaimer{
+			{DisclaimerID: "accepted", AcceptedAt: time.Now()},
+			{DisclaimerID: "pending"},
+		},
+		Result: &OfacSearch{
+			EntityId:  "1241421",
+			SdnName:   "Jane Doe",
+			Match:     0.95,
+			CreatedAt: time.Now(),
+		},
+	}
+	if err := CheckOnboarding(nil, client, "moov", "customerID", "requestID", 0); err != nil {
+		t.Error(err)
+	}
+
+	cfg := &config.Onboarding{RequireDisclaimers: true}
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); !errors.Is(err, ErrOnboarding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	client.CustomerDisclaimers[1].AcceptedAt = time.Now()
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); err != nil {
+		t.Error(err)
+	}
+
+	cfg.OFAC = &config.OFACCheck{}
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); err != nil {
+		t.Error(err)
+	}
+	cfg.OFAC.MatchThreshold = 0.9
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); !errors.Is(err, ErrOnboarding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// an organization's threshold replaces the configured one
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0.97); err != nil {
+		t.Error(err)
+	}
+
+	client.Result.Overridden = true
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); err != nil {
+		t.Error(err)
+	}
+
+	client.Result = nil
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); !errors.Is(err, ErrOnboarding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// errors from Moov Customers aren't onboarding failures
+	client.Err = errors.New("bad error")
+	if err := CheckOnboarding(cfg, client, "moov", "customerID", "requestID", 0); err == nil || errors.Is(err, ErrOnboarding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
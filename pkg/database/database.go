@@ -17,13 +17,26 @@ import (
 // New establishes a database connection according to the type and environmental
 // variables for that specific database.
 func New(ctx context.Context, logger log.Logger, cfg config.Database) (*sql.DB, error) {
+	setSlowQueryLogging(logger, cfg.SlowQueryThreshold)
+
+	var db *sql.DB
+	var err error
+	if cfg.InMemory {
+		logger.Log("setting up in-memory database provider, data will not be persisted")
+		return inMemoryConnection(ctx, logger)
+	}
 	if cfg.MySQL != nil {
 		logger.Log("setting up mysql database provider")
-		return mysqlConnection(logger, cfg.MySQL.Username, cfg.MySQL.GetPassword(), cfg.MySQL.Address, cfg.MySQL.Database).Connect(ctx)
+		db, err = mysqlConnection(logger, cfg.MySQL.Username, cfg.MySQL.GetPassword(), cfg.MySQL.Address, cfg.MySQL.Database).Connect(ctx)
+	} else {
+		logger.Log("setting up sqlite database provider")
+		db, err = sqliteConnection(logger, cfg.SQLite.Path).Connect(ctx)
 	}
-
-	logger.Log("setting up sqlite database provider")
-	return sqliteConnection(logger, cfg.SQLite.Path).Connect(ctx)
+	if err != nil {
+		return db, err
+	}
+	setupPool(db, cfg.Pool)
+	return db, nil
 }
 
 func execsql(name, raw string) *migrator.MigrationNoTx {
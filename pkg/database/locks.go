@@ -0,0 +1,92 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base"
+)
+
+// ErrLocked is returned when a lock is held by another caller.
+var ErrLocked = errors.New("lock is held")
+
+// Locker holds named locks around work which mustn't interleave. Locks are taken without
+// waiting and expire after their TTL, so a lock left by a crashed instance is released.
+type Locker interface {
+	TryLock(name string, ttl time.Duration) (Unlock, error)
+}
+
+// Unlock releases a lock taken with TryLock.
+type Unlock func() error
+
+// NewLocker returns a Locker whose locks are rows in the locks table, so they're shared
+// by every PayGate instance using db.
+func NewLocker(db *sql.DB) Locker {
+	return &sqlLocker{db: db}
+}
+
+type sqlLocker struct {
+	db *sql.DB
+}
+
+func (l *sqlLocker) TryLock(name string, ttl time.Duration) (Unlock, error) {
+	defer MeasureQuery("database", "TryLock")()
+
+	now := time.Now()
+	if _, err := l.db.Exec(`delete from locks where name = ? and expires_at < ?;`, name, now); err != nil {
+		return nil, err
+	}
+
+	owner := base.ID()
+	query := `insert into locks (name, owner, expires_at) values (?, ?, ?);`
+	if _, err := l.db.Exec(query, name, owner, now.Add(ttl)); err != nil {
+		if UniqueViolation(err) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return func() error {
+		_, err := l.db.Exec(`delete from locks where name = ? and owner = ?;`, name, owner)
+		return err
+	}, nil
+}
+
+// NewInMemoryLocker returns a Locker for a single process, intended for tests.
+func NewInMemoryLocker() Locker {
+	return &memoryLocker{
+		locks: make(map[string]time.Time),
+	}
+}
+
+type memoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]time.Time
+}
+
+func (l *memoryLocker) TryLock(name string, ttl time.Duration) (Unlock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if expires, exists := l.locks[name]; exists && now.Before(expires) {
+		return nil, ErrLocked
+	}
+	expires := now.Add(ttl)
+	l.locks[name] = expires
+
+	return func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.locks[name] == expires {
+			delete(l.locks, name)
+		}
+		return nil
+	}, nil
+}
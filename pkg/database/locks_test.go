@@ -0,0 +1,53 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocker(t *testing.T) {
+	check := func(t *testing.T, locker Locker) {
+		unlock, err := locker.TryLock("accounts/123", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := locker.TryLock("accounts/123", time.Minute); err != ErrLocked {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if other, err := locker.TryLock("accounts/456", time.Minute); err != nil {
+			t.Fatal(err)
+		} else {
+			other()
+		}
+
+		if err := unlock(); err != nil {
+			t.Fatal(err)
+		}
+		unlock, err = locker.TryLock("accounts/123", -1*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unlock()
+
+		// expired locks are taken over
+		if unlock, err := locker.TryLock("accounts/123", time.Minute); err != nil {
+			t.Fatal(err)
+		} else {
+			unlock()
+		}
+	}
+
+	sqliteDB := CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, NewLocker(sqliteDB.DB))
+
+	mysqlDB := CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, NewLocker(mysqlDB.DB))
+
+	check(t, NewInMemoryLocker())
+}
@@ -105,6 +105,398 @@ var (
 			"rename_transfers_namespace_to_organization",
 			`alter table transfers rename column namespace to organization;`,
 		),
+		execsql(
+			"create_cutoff_summaries",
+			`create table cutoff_summaries(organization varchar(40) not null, report_date varchar(10) not null, cutoff_at datetime not null, transfer_count integer not null, entry_count integer not null, debit_total bigint not null, credit_total bigint not null, filenames text not null, created_at datetime not null);`,
+		),
+		execsql(
+			"create_cutoff_summaries__report_date_idx",
+			`create index cutoff_summaries_report_date_idx on cutoff_summaries (report_date);`,
+		),
+		execsql(
+			"create_transfers__organization_created_at_idx",
+			`create index transfers_organization_created_at_idx on transfers (organization, created_at);`,
+		),
+		execsql(
+			"create_transfer_entries",
+			`create table transfer_entries(transfer_id varchar(40) not null, filename varchar(100) not null, batch_number integer not null, trace_number varchar(20) not null, entry_detail varchar(94) not null, addenda text not null, created_at datetime not null);`,
+		),
+		execsql(
+			"create_transfer_entries_unique_idx",
+			`create unique index transfer_entries_idx on transfer_entries (transfer_id, trace_number);`,
+		),
+		execsql(
+			"add_remittance__to__transfers",
+			`alter table transfers add column remittance text;`,
+		),
+		execsql(
+			"add_standard_entry_class_code__to__transfers",
+			`alter table transfers add column standard_entry_class_code varchar(3);`,
+		),
+		execsql(
+			"add_check_details__to__transfers",
+			`alter table transfers add column check_details text;`,
+		),
+		execsql(
+			"add_company_entry_description__to__transfers",
+			`alter table transfers add column company_entry_description varchar(10);`,
+		),
+		execsql(
+			"add_company_discretionary_data__to__transfers",
+			`alter table transfers add column company_discretionary_data varchar(20);`,
+		),
+		execsql(
+			"create_transfer_returns",
+			`create table transfer_returns(transfer_id varchar(40) not null, batch_header varchar(94) not null, entry_detail varchar(94) not null, addenda varchar(94) not null, dishonored_return_code varchar(3), created_at datetime not null);`,
+		),
+		execsql(
+			"create_received_transfers",
+			`create table received_transfers(received_transfer_id varchar(40) primary key not null, organization varchar(40) not null, amount_currency varchar(3) not null, amount_value integer not null, status varchar(10) not null, transaction_code integer not null, standard_entry_class_code varchar(3) not null, company_name varchar(16) not null, company_identification varchar(10) not null, company_entry_description varchar(10) not null, odfi_identification varchar(8) not null, rdfi_routing_number varchar(9) not null, individual_name varchar(22) not null, account_id varchar(40), transaction_id varchar(40), trace_number varchar(15) not null, return_code varchar(3), return_trace_number varchar(15), created_at datetime not null);`,
+		),
+		execsql(
+			"add_batch_header__to__received_transfers",
+			`alter table received_transfers add column batch_header varchar(94);`,
+		),
+		execsql(
+			"add_entry_detail__to__received_transfers",
+			`alter table received_transfers add column entry_detail varchar(94);`,
+		),
+		execsql(
+			"add_settlement_date__to__received_transfers",
+			`alter table received_transfers add column settlement_date datetime;`,
+		),
+		execsql(
+			"add_return_deadline__to__received_transfers",
+			`alter table received_transfers add column return_deadline datetime;`,
+		),
+		execsql(
+			"add_returned_at__to__received_transfers",
+			`alter table received_transfers add column returned_at datetime;`,
+		),
+		execsql(
+			"create_uploaded_files",
+			`create table uploaded_files(filename varchar(100) not null, file_hash varchar(64) not null, entry_count integer not null, debit_total bigint not null, credit_total bigint not null, uploaded_at datetime not null);`,
+		),
+		execsql(
+			"create_uploaded_files__file_hash_idx",
+			`create index uploaded_files_file_hash_idx on uploaded_files (file_hash);`,
+		),
+		execsql(
+			"create_uploaded_entries",
+			`create table uploaded_entries(filename varchar(100) not null, entry_hash varchar(64) not null, uploaded_at datetime not null);`,
+		),
+		execsql(
+			"create_uploaded_entries__entry_hash_idx",
+			`create index uploaded_entries_entry_hash_idx on uploaded_entries (entry_hash);`,
+		),
+		execsql(
+			"create_file_archive",
+			`create table file_archive(filename varchar(100) not null, kind varchar(10) not null, file_hash varchar(64) not null, size bigint not null, archive_key text, processed_at datetime not null);`,
+		),
+		execsql(
+			"create_file_archive__file_hash_idx",
+			`create index file_archive_file_hash_idx on file_archive (file_hash);`,
+		),
+		execsql(
+			"create_quarantined_files",
+			`create table quarantined_files(quarantine_id varchar(40) primary key not null, filename varchar(100) not null, kind varchar(10) not null, file_hash varchar(64) not null, contents mediumtext not null, error text not null, status varchar(12) not null, created_at datetime not null, retried_at datetime);`,
+		),
+		execsql(
+			"create_quarantined_files__file_hash_idx",
+			`create index quarantined_files_file_hash_idx on quarantined_files (file_hash);`,
+		),
+		execsql(
+			"add_batching_strategy__to__organization_configs",
+			`alter table organization_configs add column batching_strategy varchar(12);`,
+		),
+		execsql(
+			"create_trace_sequences",
+			`create table trace_sequences(routing_number varchar(8) primary key not null, last_sequence bigint not null, updated_at datetime not null);`,
+		),
+		execsql(
+			"add_version__to__organization_configs",
+			`alter table organization_configs add column version bigint not null default 1;`,
+		),
+		execsql(
+			"create_pipeline_outbox",
+			`create table pipeline_outbox(message_id varchar(40) primary key not null, transfer_id varchar(40) not null, kind varchar(10) not null, body mediumtext not null, created_at datetime not null, dispatched_at datetime);`,
+		),
+		execsql(
+			"create_pipeline_outbox__dispatched_at_idx",
+			`create index pipeline_outbox_dispatched_at_idx on pipeline_outbox (dispatched_at);`,
+		),
+		execsql(
+			"create_merged_transfers",
+			`create table merged_transfers(transfer_id varchar(40) primary key not null, cutoff_dir varchar(200) not null, merged_filename varchar(200), state varchar(10) not null, updated_at datetime not null);`,
+		),
+		execsql(
+			"create_merged_transfers__state_idx",
+			`create index merged_transfers_state_idx on merged_transfers (state);`,
+		),
+		execsql(
+			"create_pipeline_shards",
+			`create table pipeline_shards(shard integer primary key not null, owner varchar(100) not null, lease_expires_at datetime not null);`,
+		),
+		execsql(
+			"create_pipeline_instances",
+			`create table pipeline_instances(instance_id varchar(100) primary key not null, heartbeat_at datetime not null);`,
+		),
+		execsql(
+			"create_locks",
+			`create table locks(name varchar(100) primary key not null, owner varchar(40) not null, expires_at datetime not null);`,
+		),
+		execsql(
+			"create_micro_deposit_queue",
+			`create table micro_deposit_queue(micro_deposit_id varchar(40) primary key not null, organization varchar(40) not null, attempts integer not null, claimed_at datetime, created_at datetime not null);`,
+		),
+		execsql(
+			"create_transfer_queue",
+			`create table transfer_queue(transfer_id varchar(40) primary key not null, organization varchar(40) not null, attempts integer not null, claimed_at datetime, created_at datetime not null);`,
+		),
+		execsql(
+			"create_micro_deposits__status_created_at_idx",
+			`create index micro_deposits_status_created_at_idx on micro_deposits (status, deleted_at, created_at);`,
+		),
+		execsql(
+			"create_micro_deposit_transfers__micro_deposit_id_idx",
+			`create index micro_deposit_transfers_micro_deposit_id_idx on micro_deposit_transfers (micro_deposit_id);`,
+		),
+		execsql(
+			"add_organization__to__micro_deposits",
+			`alter table micro_deposits add column organization varchar(40);`,
+		),
+		execsql(
+			"add_notified_state__to__micro_deposits",
+			`alter table micro_deposits add column notified_state varchar(30);`,
+		),
+		execsql(
+			"create_account_number_reveals",
+			`create table account_number_reveals(reveal_id varchar(40) primary key not null, transfer_id varchar(40) not null, user_id varchar(100) not null, reason varchar(200) not null, created_at datetime not null);`,
+		),
+		execsql(
+			"widen_encrypted_fields__on__received_transfers",
+			`alter table received_transfers modify individual_name varchar(255) not null, modify entry_detail varchar(255);`,
+		),
+		execsql(
+			"add_individual_name_hash__to__received_transfers",
+			`alter table received_transfers add column individual_name_hash varchar(64);`,
+		),
+		execsql(
+			"create_received_transfers__organization_individual_name_hash_idx",
+			`create index received_transfers_organization_individual_name_hash_idx on received_transfers (organization, individual_name_hash);`,
+		),
+		execsql(
+			"create_organization_due_diligence",
+			`create table organization_due_diligence(organization varchar(40) primary key not null, business_type varchar(40) not null, tax_id_token varchar(100) not null, address1 varchar(100), address2 varchar(100), city varchar(60), state varchar(2), postal_code varchar(10), country varchar(2), website varchar(200) not null, updated_at datetime not null);`,
+		),
+		execsql(
+			"add_ofac_match_threshold__to__organization_configs",
+			`alter table organization_configs add column ofac_match_threshold float;`,
+		),
+		execsql(
+			"create_ofac_searches",
+			`create table ofac_searches(organization varchar(40) not null, customer_id varchar(40) not null, entity_id varchar(40) not null, sdn_name varchar(200), ofac_match float not null, searched_at datetime not null, updated_at datetime not null, primary key (organization, customer_id));`,
+		),
+		execsql(
+			"create_ofac_overrides",
+			`create table ofac_overrides(override_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, entity_id varchar(40) not null, ofac_match float not null, user_id varchar(40) not null, reason varchar(500) not null, created_at datetime not null);`,
+		),
+		execsql(
+			"create_ofac_overrides__organization_customer_id_entity_id_idx",
+			`create unique index ofac_overrides_organization_customer_id_entity_id_idx on ofac_overrides (organization, customer_id, entity_id);`,
+		),
+		execsql(
+			"create_account_history",
+			`create table account_history(history_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null, holder_name varchar(100) not null, routing_number varchar(10) not null, masked_account_number varchar(20) not null, account_type varchar(20) not null, transfer_id varchar(40) not null, effective_from datetime not null);`,
+		),
+		execsql(
+			"create_account_history__organization_account_id_idx",
+			`create index account_history_organization_account_id_idx on account_history (organization, account_id);`,
+		),
+		execsql(
+			"add_held_at__to__pipeline_outbox",
+			`alter table pipeline_outbox add column held_at datetime;`,
+		),
+		execsql(
+			"create_transfer_approvals",
+			`create table transfer_approvals(transfer_id varchar(40) primary key not null, organization varchar(40) not null, requested_by varchar(100) not null, requested_at datetime not null, approved_by varchar(100), approved_at datetime);`,
+		),
+		execsql(
+			"add_allowed_networks__to__organization_configs",
+			`alter table organization_configs add column allowed_networks varchar(1000);`,
+		),
+		execsql(
+			"add_restrict_all_requests__to__organization_configs",
+			`alter table organization_configs add column restrict_all_requests boolean not null default false;`,
+		),
+		execsql(
+			"add_micro_deposit_entry_description__to__organization_configs",
+			`alter table organization_configs add column micro_deposit_entry_description varchar(10);`,
+		),
+		execsql(
+			"add_micro_deposit_individual_name__to__organization_configs",
+			`alter table organization_configs add column micro_deposit_individual_name varchar(22);`,
+		),
+		execsql(
+			"add_retry_of__to__transfers",
+			`alter table transfers add column retry_of varchar(40);`,
+		),
+		execsql(
+			"add_retry_attempt__to__transfers",
+			`alter table transfers add column retry_attempt integer;`,
+		),
+		execsql(
+			"add_available_at__to__transfer_queue",
+			`alter table transfer_queue add column available_at datetime;`,
+		),
+		execsql(
+			"create_account_blocks",
+			`create table account_blocks(block_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null, direction varchar(10) not null, reason varchar(500) not null, created_by varchar(40) not null, created_at datetime not null, removed_by varchar(40), removed_at datetime);`,
+		),
+		execsql(
+			"create_account_blocks__account_id_idx",
+			`create index account_blocks_account_id_idx on account_blocks (account_id);`,
+		),
+		execsql(
+			"add_expected_settlement_date__to__transfers",
+			`alter table transfers add column expected_settlement_date varchar(10);`,
+		),
+		execsql(
+			"add_actual_settlement_date__to__transfers",
+			`alter table transfers add column actual_settlement_date varchar(10);`,
+		),
+		execsql(
+			"add_available_on__to__transfers",
+			`alter table transfers add column available_on varchar(10);`,
+		),
+		execsql(
+			"add_funds_available_at__to__transfers",
+			`alter table transfers add column funds_available_at datetime;`,
+		),
+		execsql(
+			"add_debit_hold_days__to__organization_configs",
+			`alter table organization_configs add column debit_hold_days integer;`,
+		),
+		execsql(
+			"add_credit_hold_days__to__organization_configs",
+			`alter table organization_configs add column credit_hold_days integer;`,
+		),
+		execsql(
+			"add_external_id__to__transfers",
+			`alter table transfers add column external_id varchar(100);`,
+		),
+		execsql(
+			"create_transfers__organization_external_id_idx",
+			`create unique index transfers_organization_external_id_idx on transfers (organization, external_id);`,
+		),
+		execsql(
+			"create_upload_credentials",
+			`create table upload_credentials(credential_id varchar(40) primary key not null, password text, client_private_key text, rotated_by varchar(100) not null, created_at datetime not null);`,
+		),
+		execsql(
+			"add_client_private_key_passphrase__to__upload_credentials",
+			`alter table upload_credentials add column client_private_key_passphrase text;`,
+		),
+		execsql(
+			"create_upload_host_keys",
+			`create table upload_host_keys(host_key_id varchar(40) primary key not null, host_public_key text not null, updated_by varchar(100) not null, created_at datetime not null);`,
+		),
+		execsql(
+			"add_outbound_path__to__file_archive",
+			`alter table file_archive add column outbound_path text;`,
+		),
+		execsql(
+			"add_error__to__file_archive",
+			`alter table file_archive add column error text;`,
+		),
+		execsql(
+			"create_file_archive__filename_idx",
+			`create index file_archive_filename_idx on file_archive (filename);`,
+		),
+		execsql(
+			"add_verified__to__file_archive",
+			`alter table file_archive add column verified boolean not null default false;`,
+		),
+		execsql(
+			"create_transfer_entries__filename_idx",
+			`create index transfer_entries_filename_idx on transfer_entries (filename);`,
+		),
+		execsql(
+			"add_display_name__to__organization_configs",
+			`alter table organization_configs add column display_name varchar(100);`,
+		),
+		execsql(
+			"add_support_email__to__organization_configs",
+			`alter table organization_configs add column support_email varchar(254);`,
+		),
+		execsql(
+			"add_logo_url__to__organization_configs",
+			`alter table organization_configs add column logo_url text;`,
+		),
+		execsql(
+			"add_messages__to__organization_configs",
+			`alter table organization_configs add column messages text;`,
+		),
+		execsql(
+			"add_fees__to__organization_configs",
+			`alter table organization_configs add column fees text;`,
+		),
+		execsql(
+			"create_transfer_fees",
+			`create table transfer_fees(transfer_id varchar(40) primary key not null, organization varchar(40) not null, amount_currency varchar(3) not null, amount_value integer not null, collection varchar(10) not null, customer_id varchar(40), account_id varchar(40), trace_number varchar(20), created_at datetime not null);`,
+		),
+		execsql(
+			"add_group_id__to__transfers",
+			`alter table transfers add column group_id varchar(40);`,
+		),
+		execsql(
+			"create_transfers__organization_group_id_idx",
+			`create index transfers_organization_group_id_idx on transfers (organization, group_id);`,
+		),
+		execsql(
+			"add_holding_account__to__organization_configs",
+			`alter table organization_configs add column holding_account text;`,
+		),
+		execsql(
+			"add_mandate_id__to__transfers",
+			`alter table transfers add column mandate_id varchar(40);`,
+		),
+		execsql(
+			"create_mandates",
+			`create table mandates(mandate_id varchar(40) primary key not null, organization varchar(40) not null, customer_id varchar(40) not null, account_id varchar(40) not null, standard_entry_class_code varchar(3) not null, authorized_at datetime not null, max_amount_currency varchar(3), max_amount_value integer, status varchar(10) not null, revoked_at datetime, revocation_reason text, created_at datetime not null);`,
+		),
+		execsql(
+			"create_mandates__organization_customer_id_idx",
+			`create index mandates_organization_customer_id_idx on mandates (organization, customer_id);`,
+		),
+		execsql(
+			"create_authorization_evidence",
+			`create table authorization_evidence(evidence_id varchar(40) primary key not null, organization varchar(40) not null, mandate_id varchar(40), transfer_id varchar(40), content_type varchar(40), filename varchar(255), size integer, url text, created_at datetime not null);`,
+		),
+		execsql(
+			"create_authorization_evidence__mandate_id_idx",
+			`create index authorization_evidence_mandate_id_idx on authorization_evidence (mandate_id);`,
+		),
+		execsql(
+			"create_authorization_evidence__transfer_id_idx",
+			`create index authorization_evidence_transfer_id_idx on authorization_evidence (transfer_id);`,
+		),
+		execsql(
+			"add_sequence_id__to__pipeline_outbox",
+			`alter table pipeline_outbox add column sequence_id bigint not null auto_increment unique;`,
+		),
+		execsql(
+			"create_request_signatures",
+			`create table request_signatures(organization varchar(40) not null, signature varchar(64) not null, expires_at datetime not null, primary key (organization, signature));`,
+		),
+		execsql(
+			"create_request_signatures__expires_at_idx",
+			`create index request_signatures_expires_at_idx on request_signatures (expires_at);`,
+		),
+		execsql(
+			"add_confirmation_attempts__to__micro_deposits",
+			`alter table micro_deposits add column confirmation_attempts int not null default 0;`,
+		),
 	)
 )
 
@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	kitprom "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprom "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queryDurations = kitprom.NewHistogramFrom(stdprom.HistogramOpts{
+		Name:    "database_query_duration_seconds",
+		Help:    "Duration of repository methods in seconds",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"repository", "method"})
+
+	slowQueries = kitprom.NewCounterFrom(stdprom.CounterOpts{
+		Name: "database_slow_queries",
+		Help: "Count of repository methods which exceeded the slow query threshold",
+	}, []string{"repository", "method"})
+
+	slowQueryMu        sync.RWMutex
+	slowQueryLogger    log.Logger = log.NewNopLogger()
+	slowQueryThreshold time.Duration
+)
+
+// setSlowQueryLogging configures logging of repository methods which take longer
+// than threshold. A zero threshold disables logging.
+func setSlowQueryLogging(logger log.Logger, threshold time.Duration) {
+	slowQueryMu.Lock()
+	defer slowQueryMu.Unlock()
+
+	slowQueryLogger = logger.Set("service", log.String("database"))
+	slowQueryThreshold = threshold
+}
+
+// MeasureQuery records the duration of a repository method. Callers should defer the
+// returned func at the start of each method:
+//
+//	defer database.MeasureQuery("transfers", "getTransfers")()
+func MeasureQuery(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		diff := time.Since(start)
+		queryDurations.With("repository", repository, "method", method).Observe(diff.Seconds())
+
+		slowQueryMu.RLock()
+		logger, threshold := slowQueryLogger, slowQueryThreshold
+		slowQueryMu.RUnlock()
+
+		if threshold > 0 && diff >= threshold {
+			slowQueries.With("repository", repository, "method", method).Add(1)
+			logger.Warn().With(log.Fields{
+				"repository": log.String(repository),
+				"method":     log.String(method),
+			}).Logf("slow query took %v", diff)
+		}
+	}
+}
+
+func setupPool(db *sql.DB, cfg *config.DatabasePool) {
+	if db == nil || cfg == nil {
+		return
+	}
+	if cfg.MaxOpenConnections > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConnections)
+	}
+	if cfg.MaxIdleConnections > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConnections)
+	}
+	if cfg.ConnectionMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnectionMaxLifetime)
+	}
+}
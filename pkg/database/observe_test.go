@@ -0,0 +1,47 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestMeasureQuery(t *testing.T) {
+	buf, logger := log.NewBufferLogger()
+	setSlowQueryLogging(logger, time.Millisecond)
+	defer setSlowQueryLogging(log.NewNopLogger(), 0)
+
+	MeasureQuery("test", "fast")()
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log: %s", buf.String())
+	}
+
+	done := MeasureQuery("test", "slow")
+	time.Sleep(5 * time.Millisecond)
+	done()
+	if out := buf.String(); !strings.Contains(out, "slow query") || !strings.Contains(out, "method=slow") {
+		t.Errorf("unexpected log: %s", out)
+	}
+}
+
+func TestSetupPool(t *testing.T) {
+	db := CreateTestSqliteDB(t)
+	defer db.Close()
+
+	setupPool(db.DB, nil)
+	setupPool(db.DB, &config.DatabasePool{
+		MaxOpenConnections:    5,
+		ConnectionMaxLifetime: time.Minute,
+	})
+	if n := db.DB.Stats().MaxOpenConnections; n != 5 {
+		t.Errorf("unexpected MaxOpenConnections=%d", n)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Preparer is implemented by *sql.DB and *sql.Tx so helpers can run inside
+// or outside of a transaction.
+type Preparer interface {
+	Prepare(query string) (*sql.Stmt, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Placeholders returns n comma separated bind parameters for use in an
+// "in (...)" clause. Callers should not build a query when n is zero.
+func Placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return "?" + strings.Repeat(",?", n-1)
+}
+
+// StringArgs converts values into query arguments.
+func StringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i := range values {
+		args[i] = values[i]
+	}
+	return args
+}
+
+// QueryRows prepares and executes query, calling fn for each row returned. The
+// statement and rows are always closed and errors are prefixed with name.
+func QueryRows(db Preparer, name string, query string, args []interface{}, fn func(*sql.Rows) error) error {
+	return QueryRowsContext(context.Background(), db, name, query, args, fn)
+}
+
+// QueryRowsContext is QueryRows which cancels the query once ctx is done, such as when
+// the client of an HTTP request disconnects.
+func QueryRowsContext(ctx context.Context, db Preparer, name string, query string, args []interface{}, fn func(*sql.Rows) error) error {
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%s prepare: %v", name, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("%s query: %v", name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return fmt.Errorf("%s scan: %v", name, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: rows.Err=%v", name, err)
+	}
+	return nil
+}
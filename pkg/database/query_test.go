@@ -0,0 +1,82 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	if v := Placeholders(0); v != "" {
+		t.Errorf("got %q", v)
+	}
+	if v := Placeholders(1); v != "?" {
+		t.Errorf("got %q", v)
+	}
+	if v := Placeholders(3); v != "?,?,?" {
+		t.Errorf("got %q", v)
+	}
+}
+
+func TestQueryRows(t *testing.T) {
+	db := CreateTestSqliteDB(t)
+	defer db.Close()
+
+	ids := []string{"a", "b", "c"}
+	for i := range ids {
+		if _, err := db.DB.Exec(`insert into transfer_trace_numbers (transfer_id, trace_number) values (?, ?);`, ids[i], "123"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := fmt.Sprintf(`select transfer_id from transfer_trace_numbers where transfer_id in (%s) order by transfer_id asc;`, Placeholders(2))
+	var found []string
+	err := QueryRows(db.DB, "test", query, StringArgs(ids[:2]), func(rows *sql.Rows) error {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		found = append(found, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0] != "a" || found[1] != "b" {
+		t.Errorf("unexpected rows: %v", found)
+	}
+
+	// errors from fn are wrapped with the query name
+	err = QueryRows(db.DB, "test", query, StringArgs(ids[:2]), func(rows *sql.Rows) error {
+		return errors.New("bad row")
+	})
+	if err == nil || !strings.Contains(err.Error(), "test scan: bad row") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := QueryRows(db.DB, "test", "select missing from nowhere;", nil, nil); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestQueryRowsContext(t *testing.T) {
+	db := CreateTestSqliteDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := QueryRowsContext(ctx, db.DB, "test", `select transfer_id from transfer_trace_numbers;`, nil, func(rows *sql.Rows) error {
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
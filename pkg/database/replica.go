@@ -0,0 +1,127 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	kitprom "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprom "github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultReplicaHealthCheckInterval is used when config.ReadReplica.HealthCheckInterval is zero.
+const defaultReplicaHealthCheckInterval = 10 * time.Second
+
+var (
+	replicaUp = kitprom.NewGaugeFrom(stdprom.GaugeOpts{
+		Name: "database_read_replica_up",
+		Help: "1 when reads are sent to the read replica and 0 when they fall back to the primary",
+	}, nil)
+)
+
+// Replica sends queries which can lag behind writes, such as those of list, search and
+// report endpoints, to a read replica. A nil Replica, or one whose replica is unreachable,
+// reads from the primary instead.
+type Replica struct {
+	db       *sql.DB
+	logger   log.Logger
+	interval time.Duration
+
+	healthy int32
+}
+
+// NewReplica connects to the read replica of cfg, or returns nil when there's none. A replica
+// which can't be reached yet isn't an error as reads fall back to the primary until it's up.
+func NewReplica(logger log.Logger, cfg config.Database) (*Replica, error) {
+	if cfg.ReadReplica == nil {
+		return nil, nil
+	}
+	logger = logger.Set("service", log.String("database")).Set("replica", log.String(cfg.ReadReplica.Address))
+
+	conn := mysqlConnection(logger, cfg.ReadReplica.Username, cfg.ReadReplica.GetPassword(), cfg.ReadReplica.Address, cfg.ReadReplica.Database)
+	db, err := sql.Open("mysql", conn.dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(maxActiveMySQLConnections)
+	setupPool(db, cfg.Pool)
+
+	r := &Replica{
+		db:       db,
+		logger:   logger,
+		interval: cfg.ReadReplica.HealthCheckInterval,
+	}
+	if r.interval <= 0 {
+		r.interval = defaultReplicaHealthCheckInterval
+	}
+	if err := r.check(); err != nil {
+		logger.Warn().Logf("read replica is unreachable, reading from the primary: %v", err)
+	}
+	return r, nil
+}
+
+// Reader returns the read replica while it's reachable and primary otherwise.
+func (r *Replica) Reader(primary *sql.DB) *sql.DB {
+	if r == nil || atomic.LoadInt32(&r.healthy) == 0 {
+		return primary
+	}
+	return r.db
+}
+
+// Start pings the read replica until ctx is done, moving reads between it and the primary.
+func (r *Replica) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.check()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Replica) check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	r.setHealthy(err)
+	return err
+}
+
+func (r *Replica) setHealthy(err error) {
+	var healthy int32
+	if err == nil {
+		healthy = 1
+	}
+	if prev := atomic.SwapInt32(&r.healthy, healthy); prev != healthy {
+		if err != nil {
+			r.logger.Warn().Logf("read replica is unreachable, reading from the primary: %v", err)
+		} else {
+			r.logger.Info().Log("reading from the read replica")
+		}
+	}
+	replicaUp.Set(float64(healthy))
+}
+
+func (r *Replica) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
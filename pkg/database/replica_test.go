@@ -0,0 +1,50 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestReplica(t *testing.T) {
+	primary, replicaDB := CreateTestSqliteDB(t), CreateTestSqliteDB(t)
+	defer primary.Close()
+
+	// without a replica everything is read from the primary
+	var replica *Replica
+	if db := replica.Reader(primary.DB); db != primary.DB {
+		t.Error("expected primary")
+	}
+	if r, err := NewReplica(log.NewNopLogger(), config.Database{}); r != nil || err != nil {
+		t.Fatalf("replica=%#v error=%v", r, err)
+	}
+
+	buf, logger := log.NewBufferLogger()
+	replica = &Replica{db: replicaDB.DB, logger: logger, interval: time.Second}
+	if err := replica.check(); err != nil {
+		t.Fatal(err)
+	}
+	if db := replica.Reader(primary.DB); db != replicaDB.DB {
+		t.Error("expected replica")
+	}
+
+	// reads fall back to the primary once the replica is unreachable
+	replicaDB.Close()
+	if err := replica.check(); err == nil {
+		t.Fatal("expected error")
+	}
+	if db := replica.Reader(primary.DB); db != primary.DB {
+		t.Error("expected primary")
+	}
+	if out := buf.String(); !strings.Contains(out, "read replica is unreachable") {
+		t.Errorf("unexpected log: %s", out)
+	}
+}
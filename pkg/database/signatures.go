@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// SignatureStore remembers the signatures of accepted requests in the request_signatures
+// table, so a signed request is only accepted once by every PayGate instance using db.
+type SignatureStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewSignatureStore(db *sql.DB) *SignatureStore {
+	return &SignatureStore{db: db}
+}
+
+// Add records signature for orgID until expires. It returns false if signature was already recorded.
+func (s *SignatureStore) Add(orgID, signature string, expires time.Time) (bool, error) {
+	defer MeasureQuery("database", "AddSignature")()
+
+	if err := s.sweep(time.Now()); err != nil {
+		return false, err
+	}
+	query := `insert into request_signatures (organization, signature, expires_at) values (?, ?, ?);`
+	if _, err := s.db.Exec(query, orgID, signature, expires); err != nil {
+		if UniqueViolation(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// sweep deletes expired signatures at most once a minute. Their timestamps are too old to be
+// accepted, so they can't be replayed afterwards.
+func (s *SignatureStore) sweep(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) < time.Minute {
+		return nil
+	}
+	if _, err := s.db.Exec(`delete from request_signatures where expires_at < ?;`, now); err != nil {
+		return err
+	}
+	s.lastSweep = now
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignatureStore(t *testing.T) {
+	check := func(t *testing.T, store *SignatureStore) {
+		expires := time.Now().Add(time.Minute)
+		if added, err := store.Add("foo", "abc123", expires); !added || err != nil {
+			t.Fatalf("added=%v error=%v", added, err)
+		}
+		if added, err := store.Add("foo", "abc123", expires); added || err != nil {
+			t.Fatalf("added=%v error=%v", added, err)
+		}
+		// signatures are kept per organization
+		if added, err := store.Add("bar", "abc123", expires); !added || err != nil {
+			t.Fatalf("added=%v error=%v", added, err)
+		}
+
+		// expired signatures are swept
+		if added, err := store.Add("foo", "def456", time.Now().Add(-1*time.Second)); !added || err != nil {
+			t.Fatalf("added=%v error=%v", added, err)
+		}
+		store.lastSweep = time.Time{}
+		if added, err := store.Add("foo", "def456", expires); !added || err != nil {
+			t.Fatalf("added=%v error=%v", added, err)
+		}
+	}
+
+	sqliteDB := CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, NewSignatureStore(sqliteDB.DB))
+
+	mysqlDB := CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, NewSignatureStore(mysqlDB.DB))
+}
@@ -84,6 +84,407 @@ var (
 			"rename_transfers_namespace_to_organization",
 			`alter table transfers rename column namespace to organization;`,
 		),
+		execsql(
+			"create_cutoff_summaries",
+			`create table cutoff_summaries(organization, report_date, cutoff_at datetime, transfer_count integer, entry_count integer, debit_total integer, credit_total integer, filenames, created_at datetime);`,
+		),
+		execsql(
+			"create_cutoff_summaries__report_date_idx",
+			`create index cutoff_summaries_report_date_idx on cutoff_summaries (report_date);`,
+		),
+		execsql(
+			"create_transfers__organization_created_at_idx",
+			`create index transfers_organization_created_at_idx on transfers (organization, created_at);`,
+		),
+		execsql(
+			"create_transfer_entries",
+			`create table transfer_entries(transfer_id, filename, batch_number integer, trace_number, entry_detail, addenda, created_at datetime, unique(transfer_id, trace_number));`,
+		),
+		execsql(
+			"add_remittance__to__transfers",
+			`alter table transfers add column remittance text;`,
+		),
+		execsql(
+			"add_standard_entry_class_code__to__transfers",
+			`alter table transfers add column standard_entry_class_code;`,
+		),
+		execsql(
+			"add_check_details__to__transfers",
+			`alter table transfers add column check_details text;`,
+		),
+		execsql(
+			"add_company_entry_description__to__transfers",
+			`alter table transfers add column company_entry_description;`,
+		),
+		execsql(
+			"add_company_discretionary_data__to__transfers",
+			`alter table transfers add column company_discretionary_data;`,
+		),
+		execsql(
+			"create_transfer_returns",
+			`create table transfer_returns(transfer_id, batch_header, entry_detail, addenda, dishonored_return_code, created_at datetime);`,
+		),
+		execsql(
+			"create_received_transfers",
+			`create table received_transfers(received_transfer_id primary key, organization, amount_currency, amount_value, status, transaction_code integer, standard_entry_class_code, company_name, company_identification, company_entry_description, odfi_identification, rdfi_routing_number, individual_name, account_id, transaction_id, trace_number, return_code, return_trace_number, created_at datetime);`,
+		),
+		execsql(
+			"add_batch_header__to__received_transfers",
+			`alter table received_transfers add column batch_header;`,
+		),
+		execsql(
+			"add_entry_detail__to__received_transfers",
+			`alter table received_transfers add column entry_detail;`,
+		),
+		execsql(
+			"add_settlement_date__to__received_transfers",
+			`alter table received_transfers add column settlement_date datetime;`,
+		),
+		execsql(
+			"add_return_deadline__to__received_transfers",
+			`alter table received_transfers add column return_deadline datetime;`,
+		),
+		execsql(
+			"add_returned_at__to__received_transfers",
+			`alter table received_transfers add column returned_at datetime;`,
+		),
+		execsql(
+			"create_uploaded_files",
+			`create table uploaded_files(filename, file_hash, entry_count integer, debit_total integer, credit_total integer, uploaded_at datetime);`,
+		),
+		execsql(
+			"create_uploaded_files__file_hash_idx",
+			`create index uploaded_files_file_hash_idx on uploaded_files (file_hash);`,
+		),
+		execsql(
+			"create_uploaded_entries",
+			`create table uploaded_entries(filename, entry_hash, uploaded_at datetime);`,
+		),
+		execsql(
+			"create_uploaded_entries__entry_hash_idx",
+			`create index uploaded_entries_entry_hash_idx on uploaded_entries (entry_hash);`,
+		),
+		execsql(
+			"create_file_archive",
+			`create table file_archive(filename, kind, file_hash, size integer, archive_key, processed_at datetime);`,
+		),
+		execsql(
+			"create_file_archive__file_hash_idx",
+			`create index file_archive_file_hash_idx on file_archive (file_hash);`,
+		),
+		execsql(
+			"create_quarantined_files",
+			`create table quarantined_files(quarantine_id primary key, filename, kind, file_hash, contents, error, status, created_at datetime, retried_at datetime);`,
+		),
+		execsql(
+			"create_quarantined_files__file_hash_idx",
+			`create index quarantined_files_file_hash_idx on quarantined_files (file_hash);`,
+		),
+		execsql(
+			"add_batching_strategy__to__organization_configs",
+			`alter table organization_configs add column batching_strategy;`,
+		),
+		execsql(
+			"create_trace_sequences",
+			`create table trace_sequences(routing_number primary key, last_sequence integer, updated_at datetime);`,
+		),
+		execsql(
+			"add_version__to__organization_configs",
+			`alter table organization_configs add column version integer not null default 1;`,
+		),
+		execsql(
+			"create_pipeline_outbox",
+			`create table pipeline_outbox(message_id primary key, transfer_id, kind, body, created_at datetime, dispatched_at datetime);`,
+		),
+		execsql(
+			"create_pipeline_outbox__dispatched_at_idx",
+			`create index pipeline_outbox_dispatched_at_idx on pipeline_outbox (dispatched_at);`,
+		),
+		execsql(
+			"create_merged_transfers",
+			`create table merged_transfers(transfer_id primary key, cutoff_dir, merged_filename, state, updated_at datetime);`,
+		),
+		execsql(
+			"create_merged_transfers__state_idx",
+			`create index merged_transfers_state_idx on merged_transfers (state);`,
+		),
+		execsql(
+			"create_pipeline_shards",
+			`create table pipeline_shards(shard integer primary key, owner, lease_expires_at datetime);`,
+		),
+		execsql(
+			"create_pipeline_instances",
+			`create table pipeline_instances(instance_id primary key, heartbeat_at datetime);`,
+		),
+		execsql(
+			"create_locks",
+			`create table locks(name primary key, owner, expires_at datetime);`,
+		),
+		execsql(
+			"create_micro_deposit_queue",
+			`create table micro_deposit_queue(micro_deposit_id primary key, organization, attempts integer, claimed_at datetime, created_at datetime);`,
+		),
+		execsql(
+			"create_transfer_queue",
+			`create table transfer_queue(transfer_id primary key, organization, attempts integer, claimed_at datetime, created_at datetime);`,
+		),
+		execsql(
+			"create_micro_deposits__status_created_at_idx",
+			`create index micro_deposits_status_created_at_idx on micro_deposits (status, deleted_at, created_at);`,
+		),
+		execsql(
+			"create_micro_deposit_transfers__micro_deposit_id_idx",
+			`create index micro_deposit_transfers_micro_deposit_id_idx on micro_deposit_transfers (micro_deposit_id);`,
+		),
+		execsql(
+			"add_organization__to__micro_deposits",
+			`alter table micro_deposits add column organization;`,
+		),
+		execsql(
+			"add_notified_state__to__micro_deposits",
+			`alter table micro_deposits add column notified_state;`,
+		),
+		execsql(
+			"create_account_number_reveals",
+			`create table account_number_reveals(reveal_id primary key, transfer_id, user_id, reason, created_at datetime);`,
+		),
+		execsql(
+			"add_individual_name_hash__to__received_transfers",
+			`alter table received_transfers add column individual_name_hash;`,
+		),
+		execsql(
+			"create_received_transfers__organization_individual_name_hash_idx",
+			`create index received_transfers_organization_individual_name_hash_idx on received_transfers (organization, individual_name_hash);`,
+		),
+		execsql(
+			"create_organization_due_diligence",
+			`create table organization_due_diligence(organization primary key, business_type, tax_id_token, address1, address2, city, state, postal_code, country, website, updated_at datetime);`,
+		),
+		execsql(
+			"add_ofac_match_threshold__to__organization_configs",
+			`alter table organization_configs add column ofac_match_threshold;`,
+		),
+		execsql(
+			"create_ofac_searches",
+			`create table ofac_searches(organization, customer_id, entity_id, sdn_name, ofac_match, searched_at datetime, updated_at datetime, primary key (organization, customer_id));`,
+		),
+		execsql(
+			"create_ofac_overrides",
+			`create table ofac_overrides(override_id primary key, organization, customer_id, entity_id, ofac_match, user_id, reason, created_at datetime);`,
+		),
+		execsql(
+			"create_ofac_overrides__organization_customer_id_entity_id_idx",
+			`create unique index ofac_overrides_organization_customer_id_entity_id_idx on ofac_overrides (organization, customer_id, entity_id);`,
+		),
+		execsql(
+			"create_account_history",
+			`create table account_history(history_id primary key, organization, customer_id, account_id, holder_name, routing_number, masked_account_number, account_type, transfer_id, effective_from datetime);`,
+		),
+		execsql(
+			"create_account_history__organization_account_id_idx",
+			`create index account_history_organization_account_id_idx on account_history (organization, account_id);`,
+		),
+		execsql(
+			"add_held_at__to__pipeline_outbox",
+			`alter table pipeline_outbox add column held_at datetime;`,
+		),
+		execsql(
+			"create_transfer_approvals",
+			`create table transfer_approvals(transfer_id primary key, organization, requested_by, requested_at datetime, approved_by, approved_at datetime);`,
+		),
+		execsql(
+			"add_allowed_networks__to__organization_configs",
+			`alter table organization_configs add column allowed_networks;`,
+		),
+		execsql(
+			"add_restrict_all_requests__to__organization_configs",
+			`alter table organization_configs add column restrict_all_requests boolean not null default false;`,
+		),
+		execsql(
+			"add_micro_deposit_entry_description__to__organization_configs",
+			`alter table organization_configs add column micro_deposit_entry_description;`,
+		),
+		execsql(
+			"add_micro_deposit_individual_name__to__organization_configs",
+			`alter table organization_configs add column micro_deposit_individual_name;`,
+		),
+		execsql(
+			"add_retry_of__to__transfers",
+			`alter table transfers add column retry_of;`,
+		),
+		execsql(
+			"add_retry_attempt__to__transfers",
+			`alter table transfers add column retry_attempt integer;`,
+		),
+		execsql(
+			"add_available_at__to__transfer_queue",
+			`alter table transfer_queue add column available_at datetime;`,
+		),
+		execsql(
+			"create_account_blocks",
+			`create table account_blocks(block_id primary key, organization, customer_id, account_id, direction, reason, created_by, created_at datetime, removed_by, removed_at datetime);`,
+		),
+		execsql(
+			"create_account_blocks__account_id_idx",
+			`create index account_blocks_account_id_idx on account_blocks (account_id);`,
+		),
+		execsql(
+			"add_expected_settlement_date__to__transfers",
+			`alter table transfers add column expected_settlement_date;`,
+		),
+		execsql(
+			"add_actual_settlement_date__to__transfers",
+			`alter table transfers add column actual_settlement_date;`,
+		),
+		execsql(
+			"add_available_on__to__transfers",
+			`alter table transfers add column available_on;`,
+		),
+		execsql(
+			"add_funds_available_at__to__transfers",
+			`alter table transfers add column funds_available_at datetime;`,
+		),
+		execsql(
+			"add_debit_hold_days__to__organization_configs",
+			`alter table organization_configs add column debit_hold_days;`,
+		),
+		execsql(
+			"add_credit_hold_days__to__organization_configs",
+			`alter table organization_configs add column credit_hold_days;`,
+		),
+		execsql(
+			"add_external_id__to__transfers",
+			`alter table transfers add column external_id;`,
+		),
+		execsql(
+			"create_transfers__organization_external_id_idx",
+			`create unique index transfers_organization_external_id_idx on transfers (organization, external_id);`,
+		),
+		execsql(
+			"create_upload_credentials",
+			`create table upload_credentials(credential_id primary key, password, client_private_key, rotated_by, created_at datetime);`,
+		),
+		execsql(
+			"add_client_private_key_passphrase__to__upload_credentials",
+			`alter table upload_credentials add column client_private_key_passphrase;`,
+		),
+		execsql(
+			"create_upload_host_keys",
+			`create table upload_host_keys(host_key_id primary key, host_public_key, updated_by, created_at datetime);`,
+		),
+		execsql(
+			"add_outbound_path__to__file_archive",
+			`alter table file_archive add column outbound_path;`,
+		),
+		execsql(
+			"add_error__to__file_archive",
+			`alter table file_archive add column error;`,
+		),
+		execsql(
+			"create_file_archive__filename_idx",
+			`create index file_archive_filename_idx on file_archive (filename);`,
+		),
+		execsql(
+			"add_verified__to__file_archive",
+			`alter table file_archive add column verified boolean not null default false;`,
+		),
+		execsql(
+			"create_transfer_entries__filename_idx",
+			`create index transfer_entries_filename_idx on transfer_entries (filename);`,
+		),
+		execsql(
+			"add_display_name__to__organization_configs",
+			`alter table organization_configs add column display_name;`,
+		),
+		execsql(
+			"add_support_email__to__organization_configs",
+			`alter table organization_configs add column support_email;`,
+		),
+		execsql(
+			"add_logo_url__to__organization_configs",
+			`alter table organization_configs add column logo_url;`,
+		),
+		execsql(
+			"add_messages__to__organization_configs",
+			`alter table organization_configs add column messages;`,
+		),
+		execsql(
+			"add_fees__to__organization_configs",
+			`alter table organization_configs add column fees;`,
+		),
+		execsql(
+			"create_transfer_fees",
+			`create table transfer_fees(transfer_id primary key, organization, amount_currency, amount_value integer, collection, customer_id, account_id, trace_number, created_at datetime);`,
+		),
+		execsql(
+			"add_group_id__to__transfers",
+			`alter table transfers add column group_id;`,
+		),
+		execsql(
+			"create_transfers__organization_group_id_idx",
+			`create index transfers_organization_group_id_idx on transfers (organization, group_id);`,
+		),
+		execsql(
+			"add_holding_account__to__organization_configs",
+			`alter table organization_configs add column holding_account;`,
+		),
+		execsql(
+			"add_mandate_id__to__transfers",
+			`alter table transfers add column mandate_id;`,
+		),
+		execsql(
+			"create_mandates",
+			`create table mandates(mandate_id primary key, organization, customer_id, account_id, standard_entry_class_code, authorized_at datetime, max_amount_currency, max_amount_value integer, status, revoked_at datetime, revocation_reason, created_at datetime);`,
+		),
+		execsql(
+			"create_mandates__organization_customer_id_idx",
+			`create index mandates_organization_customer_id_idx on mandates (organization, customer_id);`,
+		),
+		execsql(
+			"create_authorization_evidence",
+			`create table authorization_evidence(evidence_id primary key, organization, mandate_id, transfer_id, content_type, filename, size integer, url, created_at datetime);`,
+		),
+		execsql(
+			"create_authorization_evidence__mandate_id_idx",
+			`create index authorization_evidence_mandate_id_idx on authorization_evidence (mandate_id);`,
+		),
+		execsql(
+			"create_authorization_evidence__transfer_id_idx",
+			`create index authorization_evidence_transfer_id_idx on authorization_evidence (transfer_id);`,
+		),
+		// SQLite can't add an autoincrement column, so pipeline_outbox is copied into a table with sequence_id
+		execsql(
+			"create_pipeline_outbox_sequenced",
+			`create table pipeline_outbox_sequenced(sequence_id integer primary key autoincrement, message_id unique not null, transfer_id, kind, body, created_at datetime, dispatched_at datetime, held_at datetime);`,
+		),
+		execsql(
+			"copy_pipeline_outbox__to__pipeline_outbox_sequenced",
+			`insert into pipeline_outbox_sequenced (message_id, transfer_id, kind, body, created_at, dispatched_at, held_at) select message_id, transfer_id, kind, body, created_at, dispatched_at, held_at from pipeline_outbox order by created_at asc, rowid asc;`,
+		),
+		execsql(
+			"drop_pipeline_outbox",
+			`drop table pipeline_outbox;`,
+		),
+		execsql(
+			"rename_pipeline_outbox_sequenced_to_pipeline_outbox",
+			`alter table pipeline_outbox_sequenced rename to pipeline_outbox;`,
+		),
+		execsql(
+			"recreate_pipeline_outbox__dispatched_at_idx",
+			`create index pipeline_outbox_dispatched_at_idx on pipeline_outbox (dispatched_at);`,
+		),
+		execsql(
+			"create_request_signatures",
+			`create table request_signatures(organization, signature, expires_at datetime, primary key (organization, signature));`,
+		),
+		execsql(
+			"create_request_signatures__expires_at_idx",
+			`create index request_signatures_expires_at_idx on request_signatures (expires_at);`,
+		),
+		execsql(
+			"add_confirmation_attempts__to__micro_deposits",
+			`alter table micro_deposits add column confirmation_attempts integer not null default 0;`,
+		),
 	)
 )
 
@@ -158,6 +559,21 @@ func sqliteConnection(logger log.Logger, path string) *sqlite {
 	}
 }
 
+// inMemoryConnection returns a migrated SQLite database which only exists in memory.
+// The pool is limited to one connection that is never closed, otherwise the database
+// would be dropped along with its last connection.
+func inMemoryConnection(ctx context.Context, logger log.Logger) (*sql.DB, error) {
+	path := fmt.Sprintf("file:paygate-%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := sqliteConnection(logger, path).Connect(ctx)
+	if err != nil {
+		return db, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	return db, nil
+}
+
 func getSqlitePath() string {
 	path := os.Getenv("SQLITE_DB_PATH")
 	if path == "" || strings.Contains(path, "..") {
@@ -6,10 +6,13 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"runtime"
 	"testing"
 
+	"github.com/moov-io/paygate/pkg/config"
+
 	"github.com/moov-io/base/log"
 )
 
@@ -52,3 +55,36 @@ func TestSqliteUniqueViolation(t *testing.T) {
 		t.Error("should have matched unique violation")
 	}
 }
+
+func TestSQLite__InMemory(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	db, err := New(ctx, log.NewNopLogger(), config.Database{InMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`insert into organization_configs (organization, company_identification) values (?, ?);`, "org", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	var companyID string
+	if err := db.QueryRow(`select company_identification from organization_configs where organization = ?;`, "org").Scan(&companyID); err != nil {
+		t.Fatal(err)
+	}
+	if companyID != "foo" {
+		t.Errorf("companyID=%q", companyID)
+	}
+
+	// a second database shouldn't see our rows
+	other, err := New(ctx, log.NewNopLogger(), config.Database{InMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	if err := other.QueryRow(`select company_identification from organization_configs where organization = ?;`, "org").Scan(&companyID); err != sql.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
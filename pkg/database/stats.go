@@ -0,0 +1,204 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/jobs"
+
+	kitprom "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprom "github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultStatsInterval is how often table statistics are collected when
+// config.Database.StatsInterval is zero.
+const DefaultStatsInterval = 5 * time.Minute
+
+// StatsTables are the tables which grow with each Transfer. pipeline_outbox holds the events
+// published to the stream until they're archived.
+var StatsTables = []string{
+	"transfers",
+	"transfer_entries",
+	"micro_deposits",
+	"pipeline_outbox",
+	"received_transfers",
+	"file_archive",
+}
+
+var (
+	tableRows = kitprom.NewGaugeFrom(stdprom.GaugeOpts{
+		Name: "database_table_rows",
+		Help: "Gauge of rows in each table, estimated by InnoDB on mysql",
+	}, []string{"table"})
+
+	tableSize = kitprom.NewGaugeFrom(stdprom.GaugeOpts{
+		Name: "database_table_size_bytes",
+		Help: "Gauge of the approximate data and index size of each table, only reported on mysql",
+	}, []string{"table"})
+
+	databaseSize = kitprom.NewGaugeFrom(stdprom.GaugeOpts{
+		Name: "database_size_bytes",
+		Help: "Gauge of the approximate size of the whole database",
+	}, nil)
+)
+
+// TableStats are the size of one table.
+type TableStats struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+
+	// Size is the approximate bytes of the table's data and indexes, which SQLite doesn't report
+	Size int64 `json:"size,omitempty"`
+}
+
+// Stats are the sizes of StatsTables and the whole database.
+type Stats struct {
+	Checked time.Time `json:"checked"`
+
+	// Size is the approximate bytes of every table in the database
+	Size int64 `json:"size"`
+
+	Tables []TableStats `json:"tables"`
+}
+
+// StatsCollector periodically reads the size of StatsTables into the database_table_rows,
+// database_table_size_bytes and database_size_bytes metrics so operators are warned before
+// tables grow too large.
+type StatsCollector struct {
+	logger   log.Logger
+	db       *sql.DB
+	mysql    bool
+	interval time.Duration
+
+	job *jobs.Job
+
+	mu     sync.RWMutex
+	latest *Stats
+}
+
+func NewStatsCollector(logger log.Logger, db *sql.DB, cfg config.Database) *StatsCollector {
+	c := &StatsCollector{
+		logger:   logger.Set("service", log.String("database")),
+		db:       db,
+		mysql:    cfg.MySQL != nil && !cfg.InMemory,
+		interval: cfg.StatsInterval,
+	}
+	if c.interval <= 0 {
+		c.interval = DefaultStatsInterval
+	}
+	return c
+}
+
+// UseJobs records the runs of the StatsCollector as the "database.stats" job of reg.
+func (c *StatsCollector) UseJobs(reg *jobs.Registry) {
+	c.job = reg.Register("database.stats")
+}
+
+// Start collects statistics immediately and then every interval until ctx is done.
+func (c *StatsCollector) Start(ctx context.Context) {
+	c.run()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	c.job.Scheduled(time.Now().Add(c.interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			c.run()
+			c.job.Scheduled(time.Now().Add(c.interval))
+
+		case <-c.job.Triggered():
+			c.run()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *StatsCollector) run() {
+	_, err := c.Collect()
+	if err != nil {
+		c.logger.LogErrorf("ERROR collecting table statistics: %v", err)
+	}
+	c.job.Done(err)
+}
+
+// Latest returns the most recently collected Stats, or nil before the first collection.
+func (c *StatsCollector) Latest() *Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.latest
+}
+
+// Collect reads the size of each table in StatsTables and of the whole database, then
+// updates the metrics and Latest.
+func (c *StatsCollector) Collect() (*Stats, error) {
+	defer MeasureQuery("database", "collectStats")()
+
+	stats := &Stats{
+		Checked: time.Now(),
+	}
+	for _, table := range StatsTables {
+		ts, err := c.tableStats(table)
+		if err != nil {
+			return nil, fmt.Errorf("problem reading %s: %v", table, err)
+		}
+		stats.Tables = append(stats.Tables, ts)
+
+		tableRows.With("table", table).Set(float64(ts.Rows))
+		if c.mysql {
+			tableSize.With("table", table).Set(float64(ts.Size))
+		}
+	}
+	size, err := c.databaseSize()
+	if err != nil {
+		return nil, fmt.Errorf("problem reading database size: %v", err)
+	}
+	stats.Size = size
+	databaseSize.Set(float64(size))
+
+	c.mu.Lock()
+	c.latest = stats
+	c.mu.Unlock()
+
+	return stats, nil
+}
+
+// tableStats uses InnoDB's estimates on mysql instead of counting rows, which scans the
+// whole table. SQLite counts rows as it doesn't keep statistics of each table.
+func (c *StatsCollector) tableStats(table string) (TableStats, error) {
+	ts := TableStats{
+		Table: table,
+	}
+	if c.mysql {
+		query := `select coalesce(table_rows, 0), coalesce(data_length, 0) + coalesce(index_length, 0)
+from information_schema.tables where table_schema = database() and table_name = ?;`
+		err := c.db.QueryRow(query, table).Scan(&ts.Rows, &ts.Size)
+		return ts, err
+	}
+	// table is one of StatsTables, not user input
+	err := c.db.QueryRow(fmt.Sprintf(`select count(*) from %s;`, table)).Scan(&ts.Rows)
+	return ts, err
+}
+
+func (c *StatsCollector) databaseSize() (int64, error) {
+	query := `select page_count * page_size from pragma_page_count(), pragma_page_size();`
+	if c.mysql {
+		query = `select coalesce(sum(data_length + index_length), 0) from information_schema.tables where table_schema = database();`
+	}
+	var size int64
+	err := c.db.QueryRow(query).Scan(&size)
+	return size, err
+}
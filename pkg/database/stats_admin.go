@@ -0,0 +1,41 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (c *StatsCollector) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/db/stats", c.getStats())
+}
+
+func (c *StatsCollector) getStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodGet {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		stats := c.Latest()
+		if stats == nil {
+			var err error
+			if stats, err = c.Collect(); err != nil {
+				c.logger.LogErrorf("ERROR collecting table statistics: %v", err)
+				route.Problem(w, route.Internal.Wrap(err))
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(stats)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestStatsCollector(t *testing.T) {
+	check := func(t *testing.T, db *sql.DB, cfg config.Database) {
+		c := NewStatsCollector(log.NewNopLogger(), db, cfg)
+		if c.interval != DefaultStatsInterval {
+			t.Errorf("unexpected interval: %v", c.interval)
+		}
+		if c.Latest() != nil {
+			t.Fatal("expected no stats before collecting")
+		}
+		stats, err := c.Collect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats.Tables) != len(StatsTables) || stats.Size <= 0 {
+			t.Errorf("unexpected stats: %#v", stats)
+		}
+		if c.Latest() != stats {
+			t.Error("expected latest stats")
+		}
+	}
+
+	t.Run("sqlite", func(t *testing.T) {
+		sqliteDB := CreateTestSqliteDB(t)
+		defer sqliteDB.Close()
+		check(t, sqliteDB.DB, config.Database{})
+
+		if _, err := sqliteDB.DB.Exec(`insert into transfers (transfer_id, organization) values ('xfer', 'org');`); err != nil {
+			t.Fatal(err)
+		}
+		c := NewStatsCollector(log.NewNopLogger(), sqliteDB.DB, config.Database{})
+		stats, err := c.Collect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts := stats.Tables[0]; ts.Table != "transfers" || ts.Rows != 1 || ts.Size != 0 {
+			t.Errorf("unexpected transfers: %#v", ts)
+		}
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		mysqlDB := CreateTestMySQLDB(t)
+		defer mysqlDB.Close()
+		check(t, mysqlDB.DB, config.Database{MySQL: &config.MySQL{}})
+	})
+}
+
+func TestStatsCollector__admin(t *testing.T) {
+	db := CreateTestSqliteDB(t)
+	defer db.Close()
+
+	svc, client := testclient.Admin(t)
+	c := NewStatsCollector(log.NewNopLogger(), db.DB, config.Database{})
+	c.RegisterRoutes(svc)
+
+	stats, resp, err := client.AdminApi.GetDatabaseStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(stats.Tables) != len(StatsTables) || stats.Tables[0].Table != "transfers" || stats.Checked.IsZero() {
+		t.Errorf("unexpected stats: %#v", stats)
+	}
+	if c.Latest() == nil {
+		t.Error("expected stats to be collected")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestWithTx(t *testing.T) {
+	db := CreateTestSqliteDB(t)
+	defer db.Close()
+
+	insert := func(tx *sql.Tx, transferID string) error {
+		_, err := tx.Exec(`insert into transfer_trace_numbers (transfer_id, trace_number) values (?, ?);`, transferID, "123")
+		return err
+	}
+	count := func() int {
+		var n int
+		if err := db.DB.QueryRow(`select count(*) from transfer_trace_numbers;`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	err := WithTx(context.Background(), db.DB, func(tx *sql.Tx) error {
+		if err := insert(tx, "a"); err != nil {
+			return err
+		}
+		return insert(tx, "b")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("got %d rows", n)
+	}
+
+	// errors roll back every write
+	failure := errors.New("bad write")
+	err = WithTx(context.Background(), db.DB, func(tx *sql.Tx) error {
+		if err := insert(tx, "c"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := count(); n != 2 {
+		t.Errorf("got %d rows", n)
+	}
+
+	// and so do panics
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic")
+			}
+		}()
+		WithTx(context.Background(), db.DB, func(tx *sql.Tx) error {
+			insert(tx, "d")
+			panic("crashed")
+		})
+	}()
+	if n := count(); n != 2 {
+		t.Errorf("got %d rows", n)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/base/log"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// maxWarmupBackoff is the longest Warm waits between failed checks
+	maxWarmupBackoff = time.Minute
+)
+
+var (
+	dependencyUp = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "dependency_up",
+		Help: "1 when the last health check of each dependency succeeded and 0 otherwise",
+	}, []string{"dependency"})
+)
+
+// Cache shares the result of a dependency's health check between the liveness and readiness
+// checks for TTL, so probes from each load balancer don't call the dependency. Concurrent
+// checks of an expired result wait on a single call.
+//
+// A Cache is only ready once its check has succeeded, which Warm retries at startup so requests
+// aren't routed to PayGate before its clients have reached the dependency.
+type Cache struct {
+	name  string
+	check func() error
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	checked   time.Time
+	err       error
+	succeeded bool
+}
+
+func NewCache(name string, check func() error, ttl time.Duration) *Cache {
+	return &Cache{
+		name:  name,
+		check: check,
+		ttl:   ttl,
+	}
+}
+
+// RegisterChecks adds the Cache as the liveness and readiness check named after its dependency.
+func (c *Cache) RegisterChecks(svc *admin.Server) {
+	svc.AddLivenessCheck(c.name, c.Check)
+	svc.AddReadinessCheck(c.name, c.Ready)
+}
+
+// Check returns the cached result of the health check, checking the dependency again once
+// the result is older than TTL.
+func (c *Cache) Check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checked.IsZero() && time.Since(c.checked) < c.ttl {
+		return c.err
+	}
+	c.err = c.check()
+	c.checked = time.Now()
+	if c.err == nil {
+		c.succeeded = true
+		dependencyUp.With("dependency", c.name).Set(1)
+	} else {
+		dependencyUp.With("dependency", c.name).Set(0)
+	}
+	return c.err
+}
+
+// Ready returns nil once a check of the dependency has succeeded and the latest error
+// before then. Later failures are left to the liveness check.
+func (c *Cache) Ready() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.succeeded {
+		return nil
+	}
+	if c.err != nil {
+		return fmt.Errorf("%s hasn't succeeded yet: %v", c.name, c.err)
+	}
+	return fmt.Errorf("%s hasn't been checked yet", c.name)
+}
+
+// Warm checks the dependency until it succeeds or ctx is done, waiting twice as long after each
+// failure up to a minute. It's called at startup to warm up connections and caches.
+func (c *Cache) Warm(ctx context.Context, logger log.Logger) {
+	logger = logger.Set("dependency", log.String(c.name))
+
+	backoff := time.Second
+	for {
+		err := c.Check()
+		if err == nil {
+			logger.Log("dependency is ready")
+			return
+		}
+		logger.Warn().Logf("dependency isn't ready, checking again in %v: %v", backoff, err)
+
+		select {
+		case <-time.After(backoff):
+			// Check again even when the failure is still cached
+			c.expire()
+			if backoff *= 2; backoff > maxWarmupBackoff {
+				backoff = maxWarmupBackoff
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cache) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checked = time.Time{}
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+)
+
+type countingCheck struct {
+	mu    sync.Mutex
+	calls int
+	errs  []error
+}
+
+func (c *countingCheck) check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *countingCheck) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls
+}
+
+func TestCache(t *testing.T) {
+	check := &countingCheck{errs: []error{errors.New("connection refused")}}
+	cache := NewCache("customers", check.check, time.Minute)
+
+	if err := cache.Ready(); err == nil || !strings.Contains(err.Error(), "hasn't been checked yet") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// failures are cached too
+	for i := 0; i < 3; i++ {
+		if err := cache.Check(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if n := check.count(); n != 1 {
+		t.Errorf("checked %d times", n)
+	}
+	if err := cache.Ready(); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cache.expire()
+	if err := cache.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Ready(); err != nil {
+		t.Fatal(err)
+	}
+
+	// stays ready after later failures
+	check.errs = []error{errors.New("timeout")}
+	cache.expire()
+	if err := cache.Check(); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := cache.Ready(); err != nil {
+		t.Errorf("expected ready: %v", err)
+	}
+}
+
+func TestCache__Warm(t *testing.T) {
+	check := &countingCheck{errs: []error{errors.New("connection refused")}}
+	cache := NewCache("customers", check.check, time.Minute)
+
+	cache.Warm(context.Background(), log.NewNopLogger())
+	if n := check.count(); n != 2 {
+		t.Errorf("checked %d times", n)
+	}
+	if err := cache.Ready(); err != nil {
+		t.Fatal(err)
+	}
+
+	// canceled warm-ups return without succeeding
+	check = &countingCheck{errs: []error{errors.New("a"), errors.New("b")}}
+	cache = NewCache("accounts", check.check, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cache.Warm(ctx, log.NewNopLogger())
+	if err := cache.Ready(); err == nil {
+		t.Error("expected error")
+	}
+}
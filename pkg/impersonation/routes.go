@@ -0,0 +1,101 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package impersonation
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// UserHeader holds the user of the organization a request is made as. Requests without
+	// it are made as the organization alone.
+	UserHeader = "X-Impersonated-User-ID"
+
+	// ReasonHeader explains why an admin user is impersonating an organization, it's
+	// required and logged with each request.
+	ReasonHeader = "X-Impersonation-Reason"
+
+	prefix = "/impersonate/{organization}"
+)
+
+// RegisterRoutes adds GET /impersonate/{organization}/... to the admin server, which makes the
+// request for the rest of the path against handler (the public API) as organization.
+func RegisterRoutes(cfg *config.Config, svc *admin.Server, handler http.Handler) {
+	svc.Subrouter(prefix).PathPrefix("/").HandlerFunc(impersonate(cfg, handler))
+}
+
+func impersonate(cfg *config.Config, handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if cfg.Admin.Impersonation == nil {
+			route.Problem(w, route.Disabled.New("impersonating organizations is disabled via config"))
+			return
+		}
+		// Only reads are allowed so an organization's data isn't changed by someone else
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		adminUserID := moovhttp.GetUserID(r)
+		if !cfg.Admin.Impersonation.Allowed(adminUserID) {
+			route.Problem(w, route.Forbidden.New("userID=%q can not impersonate organizations", adminUserID))
+			return
+		}
+		reason := strings.TrimSpace(r.Header.Get(ReasonHeader))
+		if reason == "" {
+			route.Problem(w, route.InvalidRequest.New("missing %s header", ReasonHeader))
+			return
+		}
+
+		organization := mux.Vars(r)["organization"]
+		userID := r.Header.Get(UserHeader)
+		path := util.Or(strings.TrimPrefix(r.URL.Path, "/impersonate/"+organization), "/")
+
+		req := r.Clone(route.WithImpersonator(r.Context(), adminUserID))
+		req.URL.Path, req.URL.RawPath = path, ""
+		req.RequestURI = req.URL.RequestURI()
+		req.Header.Del(UserHeader)
+		req.Header.Del(ReasonHeader)
+		req.Header.Set(util.Or(cfg.Organization.Header, "X-Organization"), organization)
+		if userID != "" {
+			req.Header.Set("X-User-ID", userID)
+		} else {
+			req.Header.Del("X-User-ID")
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(sw, req)
+
+		cfg.Logger.With(log.Fields{
+			"impersonator": log.String(adminUserID),
+			"organization": log.String(organization),
+			"userID":       log.String(userID),
+			"reason":       log.String(reason),
+			"status":       log.Int(sw.status),
+		}).Logf("impersonated %s %s", req.Method, req.URL.RequestURI())
+	}
+}
+
+// statusWriter records the status written by the impersonated handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package impersonation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/gorilla/mux"
+)
+
+type seen struct {
+	Organization string `json:"organization"`
+	UserID       string `json:"userID"`
+	Impersonator string `json:"impersonator"`
+	Status       string `json:"status"`
+}
+
+func setupServer(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+
+	handler := mux.NewRouter()
+	handler.Methods("GET").Path("/transfers").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(seen{
+			Organization: route.FindOrganization(cfg.Organization, r),
+			UserID:       r.Header.Get("X-User-ID"),
+			Impersonator: route.Impersonator(r),
+			Status:       r.URL.Query().Get("status"),
+		})
+	})
+
+	svc, _ := testclient.Admin(t)
+	RegisterRoutes(cfg, svc, handler)
+
+	return "http://" + svc.BindAddr()
+}
+
+func impersonateRequest(t *testing.T, method, address string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-User-ID", "jane")
+	req.Header.Set(UserHeader, "john")
+	req.Header.Set(ReasonHeader, "ticket 1234")
+	return req
+}
+
+func TestImpersonation(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Admin.Impersonation = &config.Impersonation{
+		Users: []string{"jane"},
+	}
+	address := setupServer(t, cfg)
+
+	req := impersonateRequest(t, "GET", address+"/impersonate/moov/transfers?status=failed")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bs, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("bogus HTTP status: %d: %s", resp.StatusCode, bs)
+	}
+	var got seen
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Organization != "moov" || got.UserID != "john" || got.Impersonator != "jane" || got.Status != "failed" {
+		t.Errorf("unexpected request: %#v", got)
+	}
+}
+
+func TestImpersonation__rejected(t *testing.T) {
+	cfg := config.Empty()
+	address := setupServer(t, cfg)
+
+	check := func(t *testing.T, req *http.Request, status int, message string) {
+		t.Helper()
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		bs, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != status || !strings.Contains(string(bs), message) {
+			t.Errorf("unexpected response: %d: %s", resp.StatusCode, bs)
+		}
+	}
+
+	// disabled
+	check(t, impersonateRequest(t, "GET", address+"/impersonate/moov/transfers"), http.StatusBadRequest, "disabled")
+
+	cfg.Admin.Impersonation = &config.Impersonation{
+		Users: []string{"jane"},
+	}
+
+	// read-only
+	check(t, impersonateRequest(t, "POST", address+"/impersonate/moov/transfers"), http.StatusBadRequest, "invalid method")
+
+	// only allowed users
+	req := impersonateRequest(t, "GET", address+"/impersonate/moov/transfers")
+	req.Header.Set("X-User-ID", "john")
+	check(t, req, http.StatusForbidden, "can not impersonate")
+
+	// with a reason
+	req = impersonateRequest(t, "GET", address+"/impersonate/moov/transfers")
+	req.Header.Del(ReasonHeader)
+	check(t, req, http.StatusBadRequest, ReasonHeader)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (r *Registry) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/jobs", r.getJobs())
+	svc.AddHandler("/jobs/{jobName}/trigger", r.triggerJob())
+	svc.AddLivenessCheck("jobs", r.Ping)
+}
+
+func (r *Registry) getJobs() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if req.Method != http.MethodGet {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", req.Method))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(r.Statuses(time.Now()))
+	}
+}
+
+func (r *Registry) triggerJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if req.Method != http.MethodPost {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", req.Method))
+			return
+		}
+
+		name := route.ReadPathID("jobName", req)
+		job := r.Get(name)
+		if job == nil {
+			route.Problem(w, route.NotFound.New("job %q not found", name))
+			return
+		}
+		job.Trigger()
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/testclient"
+)
+
+func TestAdmin__jobs(t *testing.T) {
+	svc, c := testclient.Admin(t)
+
+	reg := NewRegistry()
+	reg.RegisterRoutes(svc)
+
+	job := reg.Register("transfers.queue")
+	job.Scheduled(time.Now().Add(time.Minute))
+	job.Done(errors.New("bad thing"))
+
+	jobs, resp, err := c.AdminApi.GetJobs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(jobs) != 1 || jobs[0].Name != "transfers.queue" || jobs[0].LastError != "bad thing" || jobs[0].NextRun.IsZero() {
+		t.Errorf("unexpected jobs: %#v", jobs)
+	}
+
+	resp, err = c.AdminApi.TriggerJob(context.Background(), "transfers.queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+	select {
+	case <-job.Triggered():
+	default:
+		t.Error("expected trigger")
+	}
+
+	resp, err = c.AdminApi.TriggerJob(context.Background(), "missing")
+	if err == nil {
+		t.Error("expected error")
+	}
+	if resp != nil && resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+}
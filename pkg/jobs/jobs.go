@@ -0,0 +1,181 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// overdueAfter is how long a job can go past its next run before its loop is considered stuck.
+const overdueAfter = 15 * time.Minute
+
+// Registry tracks each background loop of PayGate so operators can see when they last ran,
+// whether they failed and when they run next, and trigger them from the admin server.
+type Registry struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Register returns the Job named name, adding it to the Registry the first time. A nil
+// Registry returns a nil Job, whose methods do nothing.
+func (r *Registry) Register(name string) *Job {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[name]; ok {
+		return job
+	}
+	job := &Job{
+		name:    name,
+		trigger: make(chan struct{}, 1),
+	}
+	r.jobs[name] = job
+	return job
+}
+
+// Get returns the Job named name or nil if it wasn't registered.
+func (r *Registry) Get(name string) *Job {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.jobs[name]
+}
+
+// Statuses returns the Status of every Job sorted by name.
+func (r *Registry) Statuses(now time.Time) []Status {
+	out := make([]Status, 0)
+	if r == nil {
+		return out
+	}
+	r.mu.RLock()
+	for _, job := range r.jobs {
+		out = append(out, job.Status(now))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Ping returns an error when a Job is overdue, which means its loop is stuck. Jobs whose last
+// run failed are still considered alive as they often fail on problems with other services.
+func (r *Registry) Ping() error {
+	var overdue []string
+	for _, status := range r.Statuses(time.Now()) {
+		if status.Overdue {
+			overdue = append(overdue, status.Name)
+		}
+	}
+	if len(overdue) > 0 {
+		return fmt.Errorf("overdue jobs: %s", strings.Join(overdue, ", "))
+	}
+	return nil
+}
+
+// Job records the runs of a background loop. Loops should call Scheduled with each next
+// run, Done after each run and run again when Triggered receives.
+type Job struct {
+	name    string
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+	nextRun time.Time
+}
+
+// Triggered receives when the Job is triggered from the admin server.
+func (j *Job) Triggered() <-chan struct{} {
+	if j == nil {
+		return nil
+	}
+	return j.trigger
+}
+
+// Trigger asks the Job's loop to run as soon as it can. Triggers made while one is
+// pending are combined into a single run.
+func (j *Job) Trigger() {
+	if j == nil {
+		return
+	}
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Scheduled records when the Job runs next.
+func (j *Job) Scheduled(next time.Time) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.nextRun = next
+}
+
+// Done records a run of the Job which finished now with err.
+func (j *Job) Done(err error) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastRun = time.Now()
+	j.lastErr = err
+}
+
+// Status is the latest state of a Job.
+type Status struct {
+	Name      string     `json:"name"`
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	NextRun   *time.Time `json:"nextRun,omitempty"`
+	// Overdue is true when the Job hasn't run long after its next run
+	Overdue bool `json:"overdue"`
+	// Healthy is true when the last run succeeded and the Job isn't overdue
+	Healthy bool `json:"healthy"`
+}
+
+func (j *Job) Status(now time.Time) Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := Status{
+		Name: j.name,
+	}
+	if !j.lastRun.IsZero() {
+		lastRun := j.lastRun
+		status.LastRun = &lastRun
+	}
+	if j.lastErr != nil {
+		status.LastError = j.lastErr.Error()
+	}
+	if !j.nextRun.IsZero() {
+		nextRun := j.nextRun
+		status.NextRun = &nextRun
+		status.Overdue = now.Sub(nextRun) > overdueAfter && j.lastRun.Before(nextRun)
+	}
+	status.Healthy = j.lastErr == nil && !status.Overdue
+	return status
+}
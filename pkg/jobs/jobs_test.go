@@ -0,0 +1,125 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+
+	queue := reg.Register("transfers.queue")
+	if queue == nil || reg.Register("transfers.queue") != queue {
+		t.Fatal("expected the same job")
+	}
+	reg.Register("inbound.downloads")
+
+	if reg.Get("transfers.queue") != queue {
+		t.Error("unexpected job")
+	}
+	if reg.Get("missing") != nil {
+		t.Error("expected nil job")
+	}
+
+	statuses := reg.Statuses(time.Now())
+	if len(statuses) != 2 || statuses[0].Name != "inbound.downloads" || statuses[1].Name != "transfers.queue" {
+		t.Errorf("unexpected statuses: %#v", statuses)
+	}
+}
+
+func TestRegistry__nil(t *testing.T) {
+	var reg *Registry
+	job := reg.Register("transfers.queue")
+	if job != nil {
+		t.Fatal("expected nil job")
+	}
+
+	// nil Jobs do nothing
+	job.Scheduled(time.Now())
+	job.Trigger()
+	job.Done(errors.New("bad thing"))
+	if job.Triggered() != nil {
+		t.Error("expected nil channel")
+	}
+
+	if statuses := reg.Statuses(time.Now()); len(statuses) != 0 {
+		t.Errorf("unexpected statuses: %#v", statuses)
+	}
+	if err := reg.Ping(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJob__status(t *testing.T) {
+	reg := NewRegistry()
+	job := reg.Register("transfers.queue")
+
+	status := job.Status(time.Now())
+	if status.LastRun != nil || status.NextRun != nil || !status.Healthy {
+		t.Errorf("unexpected status: %#v", status)
+	}
+
+	next := time.Now().Add(time.Minute)
+	job.Scheduled(next)
+	job.Done(errors.New("bad thing"))
+
+	status = job.Status(time.Now())
+	if status.LastRun == nil || status.LastError != "bad thing" || status.Healthy || status.Overdue {
+		t.Errorf("unexpected status: %#v", status)
+	}
+	if status.NextRun == nil || !status.NextRun.Equal(next) {
+		t.Errorf("unexpected next run: %v", status.NextRun)
+	}
+
+	job.Done(nil)
+	if status := job.Status(time.Now()); status.LastError != "" || !status.Healthy {
+		t.Errorf("unexpected status: %#v", status)
+	}
+}
+
+func TestJob__overdue(t *testing.T) {
+	reg := NewRegistry()
+	job := reg.Register("transfers.queue")
+	job.Scheduled(time.Now().Add(time.Minute))
+
+	later := time.Now().Add(time.Minute + overdueAfter + time.Second)
+	if status := job.Status(later); !status.Overdue || status.Healthy {
+		t.Errorf("unexpected status: %#v", status)
+	}
+
+	// failed runs are unhealthy but keep the instance alive
+	job.Done(errors.New("bad thing"))
+	if err := reg.Ping(); err != nil {
+		t.Error(err)
+	}
+
+	stuck := reg.Register("inbound.downloads")
+	stuck.Scheduled(time.Now().Add(-1 * time.Hour))
+	if err := reg.Ping(); err == nil || err.Error() != "overdue jobs: inbound.downloads" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestJob__trigger(t *testing.T) {
+	job := NewRegistry().Register("transfers.queue")
+
+	// triggers made while one is pending are combined
+	job.Trigger()
+	job.Trigger()
+
+	select {
+	case <-job.Triggered():
+	default:
+		t.Fatal("expected trigger")
+	}
+	select {
+	case <-job.Triggered():
+		t.Fatal("unexpected trigger")
+	default:
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// maxDisplayNameLength is the longest display name shown to receivers
+const maxDisplayNameLength = 100
+
+// validateBranding adds an error to verr for each invalid branding field of cfg, prefixing
+// their names with prefix.
+func validateBranding(verr *route.ValidationError, prefix string, cfg client.OrganizationConfiguration) {
+	if err := validateDisplayName(cfg.DisplayName); err != nil {
+		verr.Add(prefix+"displayName", "%v", err)
+	}
+	if err := validateSupportEmail(cfg.SupportEmail); err != nil {
+		verr.Add(prefix+"supportEmail", "%v", err)
+	}
+	if err := validateLogoURL(cfg.LogoURL); err != nil {
+		verr.Add(prefix+"logoURL", "%v", err)
+	}
+}
+
+func validateDisplayName(name string) error {
+	if len(name) > maxDisplayNameLength {
+		return fmt.Errorf("longer than %d characters", maxDisplayNameLength)
+	}
+	if name != "" && strings.TrimSpace(name) == "" {
+		return errors.New("blank")
+	}
+	return nil
+}
+
+func validateSupportEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return fmt.Errorf("invalid email address: %v", err)
+	}
+	if addr.Address != email {
+		return errors.New("must be only an email address")
+	}
+	return nil
+}
+
+func validateLogoURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.New("must be an https URL")
+	}
+	return nil
+}
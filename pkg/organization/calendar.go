@@ -0,0 +1,113 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const (
+	defaultCalendarDays = 90
+	maxCalendarDays     = 366
+)
+
+// CalendarRouter serves the ODFI's banking calendar so clients can show users when
+// the funds of a Transfer will arrive.
+type CalendarRouter struct {
+	GetBankingCalendar http.HandlerFunc
+}
+
+func NewCalendarRouter(cfg *config.Config) *CalendarRouter {
+	return &CalendarRouter{
+		GetBankingCalendar: getBankingCalendar(cfg),
+	}
+}
+
+func (router *CalendarRouter) RegisterRoutes(r *mux.Router) {
+	r.Methods("GET").Path("/configuration/banking-calendar").HandlerFunc(router.GetBankingCalendar)
+}
+
+func getBankingCalendar(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := defaultCalendarDays
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxCalendarDays {
+				verr := &route.ValidationError{}
+				verr.Add("days", "%q isn't between 1 and %d", v, maxCalendarDays)
+				route.Problem(w, verr.Err())
+				return
+			}
+			days = n
+		}
+
+		calendar := bankingCalendar(cfg.ODFI, time.Now(), days)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(calendar)
+	}
+}
+
+// bankingCalendar returns the cutoffs and settlement dates of a Transfer created at now
+// along with the holidays of the following days.
+func bankingCalendar(cfg config.ODFI, now time.Time, days int) client.BankingCalendar {
+	loc := cfg.Cutoffs.Location()
+	now = now.In(loc)
+
+	windows := make([]string, len(cfg.Cutoffs.Windows))
+	copy(windows, cfg.Cutoffs.Windows)
+	sort.Strings(windows)
+
+	calendar := client.BankingCalendar{
+		Timezone:              loc.String(),
+		CutoffWindows:         windows,
+		NextCutoff:            nextCutoff(windows, now),
+		SameDaySettlementDate: fundflow.EffectiveEntryDate(cfg, now, true).Format(util.YYMMDDTimeFormat),
+		SettlementDate:        fundflow.EffectiveEntryDate(cfg, now, false).Format(util.YYMMDDTimeFormat),
+		Holidays:              []string{},
+	}
+	for i := 0; i < days; i++ {
+		day := base.NewTime(now.AddDate(0, 0, i))
+		if !day.IsWeekend() && !day.IsBankingDay() {
+			calendar.Holidays = append(calendar.Holidays, day.Format(util.YYMMDDTimeFormat))
+		}
+	}
+	return calendar
+}
+
+// nextCutoff returns the first of windows after now on a banking day. Cutoffs aren't
+// processed on weekends or holidays.
+func nextCutoff(windows []string, now time.Time) time.Time {
+	for i := 0; i < 14; i++ {
+		day := base.NewTime(now.AddDate(0, 0, i))
+		if !day.IsBankingDay() {
+			continue
+		}
+		for j := range windows {
+			when, err := time.Parse("15:04", windows[j])
+			if err != nil {
+				continue
+			}
+			cutoff := time.Date(day.Year(), day.Month(), day.Day(), when.Hour(), when.Minute(), 0, 0, now.Location())
+			if cutoff.After(now) {
+				return cutoff
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func calendarConfig() config.ODFI {
+	return config.ODFI{
+		Cutoffs: config.Cutoffs{
+			Timezone: "America/New_York",
+			Windows:  []string{"16:20", "10:30"},
+		},
+	}
+}
+
+func TestBankingCalendar(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+
+	// Monday after both cutoffs, the day before Veterans Day
+	now := time.Date(2020, time.November, 9, 17, 0, 0, 0, loc)
+	calendar := bankingCalendar(calendarConfig(), now, 30)
+
+	require.Equal(t, "America/New_York", calendar.Timezone)
+	require.Equal(t, []string{"10:30", "16:20"}, calendar.CutoffWindows)
+	require.True(t, calendar.NextCutoff.Equal(time.Date(2020, time.November, 10, 10, 30, 0, 0, loc)))
+	require.Equal(t, "2020-11-10", calendar.SameDaySettlementDate)
+	require.Equal(t, "2020-11-12", calendar.SettlementDate)
+	require.Equal(t, []string{"2020-11-11", "2020-11-26"}, calendar.Holidays)
+
+	// the next cutoff skips holidays
+	now = time.Date(2020, time.November, 10, 16, 30, 0, 0, loc)
+	calendar = bankingCalendar(calendarConfig(), now, 1)
+	require.True(t, calendar.NextCutoff.Equal(time.Date(2020, time.November, 12, 10, 30, 0, 0, loc)))
+	require.Empty(t, calendar.Holidays)
+}
+
+func TestRouter__GetBankingCalendar(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI = calendarConfig()
+
+	router := mux.NewRouter()
+	NewCalendarRouter(cfg).RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/configuration/banking-calendar?days=366", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var calendar client.BankingCalendar
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&calendar))
+	require.Equal(t, []string{"10:30", "16:20"}, calendar.CutoffWindows)
+	require.NotEmpty(t, calendar.Holidays)
+	require.True(t, calendar.NextCutoff.After(time.Now()))
+
+	req = httptest.NewRequest("GET", "/configuration/banking-calendar?days=0", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+func getDueDiligence(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organization := route.GetHeaderValue("X-Organization", r)
+		if organization == "" {
+			route.Problem(w, route.MissingOrganization.New("missing organization"))
+			return
+		}
+
+		dd, err := repo.GetDueDiligence(organization)
+		if err != nil {
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+		if dd == nil {
+			dd = &client.OriginatorDueDiligence{}
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(dd)
+	}
+}
+
+func updateDueDiligence(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organization := route.GetHeaderValue("X-Organization", r)
+		if organization == "" {
+			route.Problem(w, route.MissingOrganization.New("missing organization"))
+			return
+		}
+		var body client.OriginatorDueDiligence
+		if err := route.DecodeJSON(r, &body, route.DisallowUnknownFields); err != nil {
+			route.Problem(w, err)
+			return
+		}
+		if err := validateDueDiligence(&body); err != nil {
+			route.Problem(w, err)
+			return
+		}
+
+		if err := repo.UpdateDueDiligence(organization, &body); err != nil {
+			route.Problem(w, route.Internal.New("problem updating due-diligence - error=%v", err))
+			return
+		}
+		dd, err := repo.GetDueDiligence(organization)
+		if err != nil {
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(dd)
+	}
+}
+
+// rawTaxID matches an unformatted or formatted EIN (12-3456789) or SSN (123-45-6789), which
+// must be tokenized before they're sent to PayGate.
+var rawTaxID = regexp.MustCompile(`^(\d{9}|\d{2}-\d{7}|\d{3}-\d{2}-\d{4})$`)
+
+// validateDueDiligence trims every field of dd and checks the ones which are present.
+func validateDueDiligence(dd *client.OriginatorDueDiligence) error {
+	dd.BusinessType = strings.TrimSpace(dd.BusinessType)
+	dd.TaxIDToken = strings.TrimSpace(dd.TaxIDToken)
+	dd.Website = strings.TrimSpace(dd.Website)
+	dd.Updated = nil
+
+	verr := &route.ValidationError{}
+	if rawTaxID.MatchString(dd.TaxIDToken) {
+		verr.Add("taxIDToken", "raw tax IDs aren't accepted, send a token")
+	}
+	if dd.Website != "" {
+		u, err := url.Parse(dd.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.Add("website", "invalid URL %q", dd.Website)
+		}
+	}
+	if addr := dd.Address; addr != nil {
+		for _, field := range []*string{&addr.Address1, &addr.Address2, &addr.City, &addr.State, &addr.PostalCode, &addr.Country} {
+			*field = strings.TrimSpace(*field)
+		}
+		addr.State, addr.Country = strings.ToUpper(addr.State), strings.ToUpper(addr.Country)
+
+		if addr.Address1 == "" {
+			verr.Add("address.address1", "missing address1")
+		}
+		if addr.City == "" {
+			verr.Add("address.city", "missing city")
+		}
+		if len(addr.State) != 2 {
+			verr.Add("address.state", "state must be two characters")
+		}
+		if addr.PostalCode == "" {
+			verr.Add("address.postalCode", "missing postalCode")
+		}
+		if len(addr.Country) != 2 {
+			verr.Add("address.country", "country must be two characters")
+		}
+	}
+	return verr.Err()
+}
+
+// MissingDueDiligence returns the required fields which dd doesn't have.
+func MissingDueDiligence(cfg *config.DueDiligence, dd *client.OriginatorDueDiligence) []string {
+	if cfg == nil {
+		return nil
+	}
+	if dd == nil {
+		dd = &client.OriginatorDueDiligence{}
+	}
+	var missing []string
+	for _, field := range cfg.RequiredFields {
+		var present bool
+		switch field {
+		case config.DueDiligenceBusinessType:
+			present = dd.BusinessType != ""
+		case config.DueDiligenceTaxIDToken:
+			present = dd.TaxIDToken != ""
+		case config.DueDiligenceAddress:
+			present = dd.Address != nil
+		case config.DueDiligenceWebsite:
+			present = dd.Website != ""
+		}
+		if !present {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// CheckDueDiligence returns a Forbidden error when the organization is missing any
+// due-diligence fields PayGate requires of originators.
+func CheckDueDiligence(cfg *config.DueDiligence, repo Repository, orgID string) error {
+	if cfg == nil {
+		return nil
+	}
+	dd, err := repo.GetDueDiligence(orgID)
+	if err != nil {
+		return route.Internal.New("reading due-diligence: %v", err)
+	}
+	if missing := MissingDueDiligence(cfg, dd); len(missing) > 0 {
+		return route.Forbidden.New("organization is missing due-diligence: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
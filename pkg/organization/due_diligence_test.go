@@ -0,0 +1,154 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRepository__DueDiligence(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		if dd, err := repo.GetDueDiligence(orgID); err != nil || dd != nil {
+			t.Fatalf("unexpected due-diligence=%#v error=%v", dd, err)
+		}
+
+		dd := &client.OriginatorDueDiligence{
+			BusinessType: "llc",
+			TaxIDToken:   "tok_123",
+		}
+		if err := repo.UpdateDueDiligence(orgID, dd); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.GetDueDiligence(orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.BusinessType != "llc" || found.TaxIDToken != "tok_123" || found.Address != nil || found.Updated == nil {
+			t.Errorf("unexpected due-diligence: %#v", found)
+		}
+
+		dd.Address = &client.DueDiligenceAddress{
+			Address1:   "123 Main St",
+			City:       "Iowa City",
+			State:      "IA",
+			PostalCode: "52240",
+			Country:    "US",
+		}
+		dd.Website = "https://moov.io"
+		if err := repo.UpdateDueDiligence(orgID, dd); err != nil {
+			t.Fatal(err)
+		}
+		found, err = repo.GetDueDiligence(orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Address == nil || found.Address.City != "Iowa City" || found.Address.Address2 != "" || found.Website != "https://moov.io" {
+			t.Errorf("unexpected due-diligence: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRouter__DueDiligence(t *testing.T) {
+	repo := NewInMemoryRepo()
+	router := mux.NewRouter()
+	NewRouter(repo).RegisterRoutes(router)
+
+	update := func(dd client.OriginatorDueDiligence) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(dd)
+
+		req := httptest.NewRequest("PUT", "/configuration/due-diligence", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update(client.OriginatorDueDiligence{
+		TaxIDToken: "123-45-6789",
+		Website:    "moov.io",
+		Address:    &client.DueDiligenceAddress{Address1: "123 Main St"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected HTTP status %d: %s", w.Code, w.Body.String())
+	}
+	for _, field := range []string{"taxIDToken", "website", "address.city", "address.state"} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("missing %s error: %s", field, w.Body.String())
+		}
+	}
+
+	w = update(client.OriginatorDueDiligence{
+		BusinessType: " llc ",
+		TaxIDToken:   "tok_123",
+		Website:      "https://moov.io",
+		Address: &client.DueDiligenceAddress{
+			Address1:   "123 Main St",
+			City:       "Iowa City",
+			State:      "ia",
+			PostalCode: "52240",
+			Country:    "us",
+		},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected HTTP status %d: %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/configuration/due-diligence", nil)
+	req.Header.Set("X-Organization", "moov")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	var dd client.OriginatorDueDiligence
+	if err := json.NewDecoder(w.Body).Decode(&dd); err != nil {
+		t.Fatal(err)
+	}
+	if dd.BusinessType != "llc" || dd.Address == nil || dd.Address.State != "IA" || dd.Address.Country != "US" {
+		t.Errorf("unexpected due-diligence: %#v", dd)
+	}
+}
+
+func TestCheckDueDiligence(t *testing.T) {
+	repo := &MockRepository{}
+	if err := CheckDueDiligence(nil, repo, "moov"); err != nil {
+		t.Error(err)
+	}
+
+	cfg := &config.DueDiligence{
+		RequiredFields: []string{"businessType", "address"},
+	}
+	if missing := MissingDueDiligence(cfg, nil); len(missing) != 2 {
+		t.Errorf("unexpected missing fields: %v", missing)
+	}
+	if err := CheckDueDiligence(cfg, repo, "moov"); err == nil {
+		t.Error("expected error")
+	}
+
+	repo.DueDiligence = &client.OriginatorDueDiligence{
+		BusinessType: "llc",
+		Address:      &client.DueDiligenceAddress{},
+	}
+	if err := CheckDueDiligence(cfg, repo, "moov"); err != nil {
+		t.Error(err)
+	}
+}
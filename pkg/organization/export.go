@@ -0,0 +1,138 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// exportConfig returns every setting of an organization as one document, which
+// importConfig accepts unchanged so configuration can be copied across environments.
+func exportConfig(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organization := route.GetHeaderValue("X-Organization", r)
+		if organization == "" {
+			route.Problem(w, route.MissingOrganization.New("missing organization"))
+			return
+		}
+
+		cfg, err := repo.GetConfig(organization)
+		if err != nil {
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+		doc := client.ConfigurationDocument{}
+		if cfg != nil {
+			doc.Transfers = *cfg
+			doc.Transfers.Version = 0 // versions are specific to this environment
+		}
+		writeDocument(w, r, doc)
+	}
+}
+
+func importConfig(repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organization := route.GetHeaderValue("X-Organization", r)
+		if organization == "" {
+			route.Problem(w, route.MissingOrganization.New("missing organization"))
+			return
+		}
+
+		var doc client.ConfigurationDocument
+		var err error
+		if route.IsYAML(r.Header.Get("Content-Type")) {
+			err = route.DecodeYAML(r, &doc, route.DisallowUnknownFields)
+		} else {
+			err = route.DecodeJSON(r, &doc, route.DisallowUnknownFields)
+		}
+		if err != nil {
+			route.Problem(w, err)
+			return
+		}
+		if err := validateDocument(doc); err != nil {
+			route.Problem(w, err)
+			return
+		}
+
+		// Documents replace the current configuration
+		current, err := repo.GetConfig(organization)
+		if err != nil {
+			route.Problem(w, route.Internal.New("problem importing config - error=%v", err))
+			return
+		}
+		doc.Transfers.Version = 0
+		if current != nil {
+			doc.Transfers.Version = current.Version
+		}
+
+		cfg, err := repo.UpdateConfig(organization, &doc.Transfers)
+		if err != nil {
+			if errors.Is(err, ErrVersionConflict) {
+				route.Problem(w, route.Conflict.New("%v, export the current version and retry", err))
+				return
+			}
+			route.Problem(w, route.Internal.New("problem importing config - error=%v", err))
+			return
+		}
+		cfg.Version = 0
+		writeDocument(w, r, client.ConfigurationDocument{Transfers: *cfg})
+	}
+}
+
+func validateDocument(doc client.ConfigurationDocument) error {
+	verr := &route.ValidationError{}
+	if doc.Transfers.CompanyIdentification == "" {
+		verr.Add("transfers.companyIdentification", "missing")
+	}
+	if err := validateBatchingStrategy(doc.Transfers.BatchingStrategy); err != nil {
+		verr.Add("transfers.batchingStrategy", "%v", err)
+	}
+	if err := validateOFACMatchThreshold(doc.Transfers.OfacMatchThreshold); err != nil {
+		verr.Add("transfers.ofacMatchThreshold", "%v", err)
+	}
+	if err := validateAllowedNetworks(doc.Transfers.AllowedNetworks); err != nil {
+		verr.Add("transfers.allowedNetworks", "%v", err)
+	}
+	if doc.Transfers.RestrictAllRequests && len(doc.Transfers.AllowedNetworks) == 0 {
+		verr.Add("transfers.restrictAllRequests", "requires allowedNetworks")
+	}
+	if err := achx.ValidateCompanyEntryDescription(doc.Transfers.MicroDepositEntryDescription, ach.PPD); err != nil {
+		verr.Add("transfers.microDepositEntryDescription", "%v", err)
+	}
+	if err := achx.ValidateIndividualName(doc.Transfers.MicroDepositIndividualName); err != nil {
+		verr.Add("transfers.microDepositIndividualName", "%v", err)
+	}
+	if err := validateHoldDays(doc.Transfers.DebitHoldDays); err != nil {
+		verr.Add("transfers.debitHoldDays", "%v", err)
+	}
+	if err := validateHoldDays(doc.Transfers.CreditHoldDays); err != nil {
+		verr.Add("transfers.creditHoldDays", "%v", err)
+	}
+	validateBranding(verr, "transfers.", doc.Transfers)
+	validateMessages(verr, "transfers.", doc.Transfers.Messages)
+	validateFees(verr, "transfers.", doc.Transfers.Fees)
+	validateHoldingAccount(verr, "transfers.", doc.Transfers.HoldingAccount)
+	return verr.Err()
+}
+
+func writeDocument(w http.ResponseWriter, r *http.Request, doc client.ConfigurationDocument) {
+	if route.WantsYAML(r) {
+		if err := route.WriteYAML(w, http.StatusOK, doc); err != nil {
+			route.Problem(w, route.Internal.Wrap(err))
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(doc)
+}
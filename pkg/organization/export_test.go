@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportConfig(t *testing.T) {
+	repo := NewInMemoryRepo()
+	repo.UpdateConfig("moov", &client.OrganizationConfiguration{CompanyIdentification: "MOOVZZZZZZ"})
+
+	router := mux.NewRouter()
+	NewRouter(repo).RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/configuration/export", nil)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc client.ConfigurationDocument
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&doc))
+	require.Equal(t, "MOOVZZZZZZ", doc.Transfers.CompanyIdentification)
+
+	// export as YAML and import it into another organization
+	req = httptest.NewRequest("GET", "/configuration/export?format=yaml", nil)
+	req.Header.Set("X-Organization", "moov")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "transfers:\n  companyIdentification: MOOVZZZZZZ\n", w.Body.String())
+
+	req = httptest.NewRequest("PUT", "/configuration/export", strings.NewReader(w.Body.String()))
+	req.Header.Set("X-Organization", "other")
+	req.Header.Set("Content-Type", "application/yaml")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	cfg, err := repo.GetConfig("other")
+	require.NoError(t, err)
+	require.Equal(t, "MOOVZZZZZZ", cfg.CompanyIdentification)
+}
+
+func TestImportConfig__invalid(t *testing.T) {
+	router := mux.NewRouter()
+	NewRouter(NewInMemoryRepo()).RegisterRoutes(router)
+
+	for _, body := range []string{
+		`{"transfers": {}}`,
+		`{"transfers": {"companyIdentification": "MOOVZZZZZZ"}, "limits": {}}`,
+	} {
+		req := httptest.NewRequest("PUT", "/configuration/export", strings.NewReader(body))
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+
+		var problem client.Error
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+		require.Len(t, problem.Fields, 1)
+	}
+}
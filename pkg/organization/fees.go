@@ -0,0 +1,42 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// validateFees adds an error to verr for each invalid field of fees, prefixing their names
+// with prefix. Fees collected as entries or postings need an account to collect them into.
+func validateFees(verr *route.ValidationError, prefix string, fees *client.FeeSchedule) {
+	if fees == nil {
+		return
+	}
+	validateFeeRule(verr, prefix+"fees.credit.", fees.Credit)
+	validateFeeRule(verr, prefix+"fees.debit.", fees.Debit)
+
+	switch fees.Collection {
+	case "", client.NONE:
+	case client.ENTRY, client.POSTING:
+		if fees.Account == nil || fees.Account.CustomerID == "" || fees.Account.AccountID == "" {
+			verr.Add(prefix+"fees.account", "required when fees are collected as %s", fees.Collection)
+		}
+	default:
+		verr.Add(prefix+"fees.collection", "unknown collection %q", fees.Collection)
+	}
+}
+
+func validateFeeRule(verr *route.ValidationError, prefix string, rule *client.FeeRule) {
+	if rule == nil {
+		return
+	}
+	if rule.Flat < 0 {
+		verr.Add(prefix+"flat", "negative")
+	}
+	if rule.Percentage < 0 || rule.Percentage > 100 {
+		verr.Add(prefix+"percentage", "must be between 0 and 100")
+	}
+}
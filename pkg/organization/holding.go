@@ -0,0 +1,24 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// validateHoldingAccount adds an error to verr when the holding account set in a configuration
+// is missing its customerID or accountID.
+func validateHoldingAccount(verr *route.ValidationError, prefix string, account *client.Destination) {
+	if account == nil {
+		return
+	}
+	if account.CustomerID == "" {
+		verr.Add(prefix+"holdingAccount.customerID", "missing")
+	}
+	if account.AccountID == "" {
+		verr.Add(prefix+"holdingAccount.accountID", "missing")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// NewInMemoryRepo returns a Repository which keeps organization configs in memory. It's
+// intended for tests and demos where no database is available.
+func NewInMemoryRepo() Repository {
+	return &memoryRepo{
+		configs:      make(map[string]client.OrganizationConfiguration),
+		dueDiligence: make(map[string]client.OriginatorDueDiligence),
+	}
+}
+
+type memoryRepo struct {
+	mu           sync.RWMutex
+	configs      map[string]client.OrganizationConfiguration
+	dueDiligence map[string]client.OriginatorDueDiligence
+}
+
+func (r *memoryRepo) GetConfig(orgID string) (*client.OrganizationConfiguration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if cfg, ok := r.configs[orgID]; ok {
+		cfg.AllowedNetworks = append([]string(nil), cfg.AllowedNetworks...)
+		cfg.Messages = copyMessages(cfg.Messages)
+		cfg.Fees = copyFees(cfg.Fees)
+		cfg.HoldingAccount = copyDestination(cfg.HoldingAccount)
+		return &cfg, nil
+	}
+	return nil, nil
+}
+
+func (r *memoryRepo) UpdateConfig(orgID string, cfg *client.OrganizationConfiguration) (*client.OrganizationConfiguration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current := r.configs[orgID]; current.Version != cfg.Version {
+		return nil, ErrVersionConflict
+	}
+	out := *cfg
+	out.AllowedNetworks = append([]string(nil), cfg.AllowedNetworks...)
+	out.Messages = copyMessages(cfg.Messages)
+	out.Fees = copyFees(cfg.Fees)
+	out.HoldingAccount = copyDestination(cfg.HoldingAccount)
+	out.Version++
+	r.configs[orgID] = out
+	return &out, nil
+}
+
+func (r *memoryRepo) GetDueDiligence(orgID string) (*client.OriginatorDueDiligence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if dd, ok := r.dueDiligence[orgID]; ok {
+		return &dd, nil
+	}
+	return nil, nil
+}
+
+func (r *memoryRepo) UpdateDueDiligence(orgID string, dd *client.OriginatorDueDiligence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := *dd
+	if dd.Address != nil {
+		address := *dd.Address
+		out.Address = &address
+	}
+	now := time.Now()
+	out.Updated = &now
+	r.dueDiligence[orgID] = out
+	return nil
+}
+
+func copyMessages(messages map[string]map[string]string) map[string]map[string]string {
+	if messages == nil {
+		return nil
+	}
+	out := make(map[string]map[string]string, len(messages))
+	for lang, codes := range messages {
+		out[lang] = make(map[string]string, len(codes))
+		for code, msg := range codes {
+			out[lang][code] = msg
+		}
+	}
+	return out
+}
+
+func copyFees(fees *client.FeeSchedule) *client.FeeSchedule {
+	if fees == nil {
+		return nil
+	}
+	out := *fees
+	if fees.Credit != nil {
+		credit := *fees.Credit
+		out.Credit = &credit
+	}
+	if fees.Debit != nil {
+		debit := *fees.Debit
+		out.Debit = &debit
+	}
+	out.Account = copyDestination(fees.Account)
+	return &out
+}
+
+func copyDestination(dst *client.Destination) *client.Destination {
+	if dst == nil {
+		return nil
+	}
+	out := *dst
+	return &out
+}
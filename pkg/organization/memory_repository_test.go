@@ -0,0 +1,32 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repo := NewInMemoryRepo()
+	orgID := base.ID()
+
+	if cfg, err := repo.GetConfig(orgID); cfg != nil || err != nil {
+		t.Fatalf("cfg=%#v  error=%v", cfg, err)
+	}
+
+	if _, err := repo.UpdateConfig(orgID, &client.OrganizationConfiguration{CompanyIdentification: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := repo.GetConfig(orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg == nil || cfg.CompanyIdentification != "foo" {
+		t.Fatalf("unexpected config: %#v", cfg)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// maxMessageLength is the longest error message an organization can override
+const maxMessageLength = 500
+
+// MessageOverrides returns the error messages each organization configured to replace
+// route.DefaultCatalog, for use with route.Localize.
+func MessageOverrides(cfg *config.Config, repo Repository) func(r *http.Request) route.Catalog {
+	return func(r *http.Request) route.Catalog {
+		orgID := route.FindOrganization(cfg.Organization, r)
+		if orgID == "" {
+			return nil
+		}
+		orgConfig, err := repo.GetConfig(orgID)
+		if err != nil || orgConfig == nil {
+			return nil
+		}
+		return route.Catalog(orgConfig.Messages)
+	}
+}
+
+// validateMessages adds an error to verr for each unsupported language, unknown error code
+// or invalid message of messages, prefixing their names with prefix.
+func validateMessages(verr *route.ValidationError, prefix string, messages map[string]map[string]string) {
+	for lang, codes := range messages {
+		if !supportedLanguage(lang) {
+			verr.Add(prefix+"messages."+lang, "unsupported language")
+			continue
+		}
+		for code, msg := range codes {
+			field := prefix + "messages." + lang + "." + code
+			switch {
+			case !knownErrorCode(code):
+				verr.Add(field, "unknown error code")
+			case strings.TrimSpace(msg) == "":
+				verr.Add(field, "blank")
+			case len(msg) > maxMessageLength:
+				verr.Add(field, "longer than %d characters", maxMessageLength)
+			}
+		}
+	}
+}
+
+func supportedLanguage(lang string) bool {
+	for _, tag := range route.Languages {
+		if base, _ := tag.Base(); base.String() == lang {
+			return true
+		}
+	}
+	return false
+}
+
+func knownErrorCode(code string) bool {
+	for i := range route.ErrorCodes {
+		if route.ErrorCodes[i].Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageOverrides(t *testing.T) {
+	repo := NewInMemoryRepo()
+	_, err := repo.UpdateConfig("moov", &client.OrganizationConfiguration{
+		Messages: map[string]map[string]string{
+			"es": {"not_found": "No encontramos eso"},
+		},
+	})
+	require.NoError(t, err)
+
+	overrides := MessageOverrides(config.Empty(), repo)
+
+	req := httptest.NewRequest("GET", "/transfers/foo", nil)
+	req.Header.Set("X-Organization", "moov")
+	catalog := overrides(req)
+	require.Equal(t, "No encontramos eso", catalog["es"]["not_found"])
+
+	req.Header.Set("X-Organization", "other")
+	require.Empty(t, overrides(req))
+
+	req.Header.Del("X-Organization")
+	require.Empty(t, overrides(req))
+}
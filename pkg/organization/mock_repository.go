@@ -7,8 +7,9 @@ package organization
 import "github.com/moov-io/paygate/pkg/client"
 
 type MockRepository struct {
-	Config *client.OrganizationConfiguration
-	Err    error
+	Config       *client.OrganizationConfiguration
+	DueDiligence *client.OriginatorDueDiligence
+	Err          error
 }
 
 func (r *MockRepository) GetConfig(orgID string) (*client.OrganizationConfiguration, error) {
@@ -24,3 +25,18 @@ func (r *MockRepository) UpdateConfig(orgID string, cfg *client.OrganizationConf
 	}
 	return cfg, nil
 }
+
+func (r *MockRepository) GetDueDiligence(orgID string) (*client.OriginatorDueDiligence, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.DueDiligence, nil
+}
+
+func (r *MockRepository) UpdateDueDiligence(orgID string, dd *client.OriginatorDueDiligence) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.DueDiligence = dd
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+var (
+	networkPolicyRejections = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "http_network_policy_rejections",
+		Help: "Counter of HTTP requests rejected for coming from outside an organization's allowed networks",
+	}, []string{"scope"})
+)
+
+// NetworkPolicy returns middleware which rejects requests from addresses outside an
+// organization's allowedNetworks with 403 Forbidden. Only creating transfers is restricted
+// unless the organization also set restrictAllRequests.
+func NetworkPolicy(cfg *config.Config, repo Repository) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			orgID := route.FindOrganization(cfg.Organization, r)
+			if orgID == "" || r.URL.Path == "/ping" || route.Impersonator(r) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			orgConfig, err := repo.GetConfig(orgID)
+			if err != nil {
+				route.Problem(w, route.Internal.New("reading allowed networks: %v", err))
+				return
+			}
+			if orgConfig == nil || len(orgConfig.AllowedNetworks) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scope := "all"
+			if !orgConfig.RestrictAllRequests {
+				if !creatingTransfer(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				scope = "transfers"
+			}
+
+			ip := route.ClientIP(cfg.Http, r)
+			if allowedAddress(orgConfig.AllowedNetworks, ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			networkPolicyRejections.With("scope", scope).Add(1)
+			cfg.Logger.With(log.Fields{
+				"organization": log.String(orgID),
+				"address":      log.String(ip.String()),
+			}).Logf("rejected %s %s from outside allowed networks", r.Method, r.URL.Path)
+
+			route.Problem(w, route.Forbidden.New("address %v is not allowed for organization=%s", ip, orgID))
+		})
+	}
+}
+
+func creatingTransfer(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			return tmpl == "/transfers"
+		}
+	}
+	return r.URL.Path == "/transfers"
+}
+
+func allowedAddress(networks []string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for i := range networks {
+		if _, network, err := net.ParseCIDR(networks[i]); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkPolicy(t *testing.T) {
+	repo := &MockRepository{
+		Config: &client.OrganizationConfiguration{
+			AllowedNetworks: []string{"203.0.113.0/24"},
+		},
+	}
+
+	cfg := config.Empty()
+	router := mux.NewRouter()
+	router.Use(NetworkPolicy(cfg, repo))
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	router.Methods("POST").Path("/transfers").HandlerFunc(ok)
+	router.Methods("GET").Path("/transfers").HandlerFunc(ok)
+
+	send := func(method, remoteAddr string) int {
+		req := httptest.NewRequest(method, "/transfers", nil)
+		req.Header.Set("X-Organization", "moov")
+		req.RemoteAddr = remoteAddr
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, send("POST", "203.0.113.10:4123"))
+	require.Equal(t, http.StatusForbidden, send("POST", "192.0.2.1:4123"))
+
+	// only creating transfers is restricted by default
+	require.Equal(t, http.StatusOK, send("GET", "192.0.2.1:4123"))
+
+	repo.Config.RestrictAllRequests = true
+	require.Equal(t, http.StatusForbidden, send("GET", "192.0.2.1:4123"))
+	require.Equal(t, http.StatusOK, send("GET", "203.0.113.10:4123"))
+
+	// organizations without networks aren't restricted
+	repo.Config = nil
+	require.Equal(t, http.StatusOK, send("POST", "192.0.2.1:4123"))
+}
@@ -6,14 +6,31 @@ package organization
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
 )
 
+// ErrVersionConflict is returned from UpdateConfig when the version of the config doesn't
+// match the stored version, meaning it was changed since the caller read it.
+var ErrVersionConflict = errors.New("configuration was changed by another request")
+
 type Repository interface {
 	GetConfig(orgID string) (*client.OrganizationConfiguration, error)
+
+	// UpdateConfig saves cfg when its Version matches the stored config, or is zero when
+	// there's no config. The returned config has the incremented Version.
 	UpdateConfig(orgID string, cfg *client.OrganizationConfiguration) (*client.OrganizationConfiguration, error)
+
+	GetDueDiligence(orgID string) (*client.OriginatorDueDiligence, error)
+
+	// UpdateDueDiligence replaces the due-diligence of an organization.
+	UpdateDueDiligence(orgID string, dd *client.OriginatorDueDiligence) error
 }
 
 func NewRepo(db *sql.DB) Repository {
@@ -32,7 +49,12 @@ func (r *sqlRepo) Close() error {
 }
 
 func (r *sqlRepo) GetConfig(orgID string) (*client.OrganizationConfiguration, error) {
-	query := `select company_identification from organization_configs where organization = ? limit 1;`
+	defer database.MeasureQuery("organization", "GetConfig")()
+
+	query := `select company_identification, batching_strategy, ofac_match_threshold, allowed_networks, restrict_all_requests,
+micro_deposit_entry_description, micro_deposit_individual_name, debit_hold_days, credit_hold_days,
+display_name, support_email, logo_url, messages, fees, holding_account, version
+from organization_configs where organization = ? limit 1;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
@@ -40,26 +62,224 @@ func (r *sqlRepo) GetConfig(orgID string) (*client.OrganizationConfiguration, er
 	defer stmt.Close()
 
 	var cfg client.OrganizationConfiguration
-	if err := stmt.QueryRow(orgID).Scan(&cfg.CompanyIdentification); err != nil {
+	var strategy *string
+	var threshold *float64
+	var networks, description, individualName *string
+	var debitHold, creditHold *int32
+	var displayName, supportEmail, logoURL, messages, fees, holdingAccount *string
+	err = stmt.QueryRow(orgID).Scan(&cfg.CompanyIdentification, &strategy, &threshold, &networks, &cfg.RestrictAllRequests, &description, &individualName,
+		&debitHold, &creditHold, &displayName, &supportEmail, &logoURL, &messages, &fees, &holdingAccount, &cfg.Version)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if strategy != nil {
+		cfg.BatchingStrategy = client.BatchingStrategy(*strategy)
+	}
+	if threshold != nil {
+		cfg.OfacMatchThreshold = float32(*threshold)
+	}
+	if networks != nil && *networks != "" {
+		cfg.AllowedNetworks = strings.Split(*networks, ",")
+	}
+	cfg.MicroDepositEntryDescription = stringValue(description)
+	cfg.MicroDepositIndividualName = stringValue(individualName)
+	if debitHold != nil {
+		cfg.DebitHoldDays = *debitHold
+	}
+	if creditHold != nil {
+		cfg.CreditHoldDays = *creditHold
+	}
+	cfg.DisplayName = stringValue(displayName)
+	cfg.SupportEmail = stringValue(supportEmail)
+	cfg.LogoURL = stringValue(logoURL)
+	if messages != nil && *messages != "" {
+		if err := json.Unmarshal([]byte(*messages), &cfg.Messages); err != nil {
+			return nil, fmt.Errorf("reading messages: %v", err)
+		}
+	}
+	if fees != nil && *fees != "" {
+		if err := json.Unmarshal([]byte(*fees), &cfg.Fees); err != nil {
+			return nil, fmt.Errorf("reading fees: %v", err)
+		}
+	}
+	if holdingAccount != nil && *holdingAccount != "" {
+		if err := json.Unmarshal([]byte(*holdingAccount), &cfg.HoldingAccount); err != nil {
+			return nil, fmt.Errorf("reading holding account: %v", err)
+		}
+	}
 	return &cfg, nil
 }
 
 func (r *sqlRepo) UpdateConfig(orgID string, cfg *client.OrganizationConfiguration) (*client.OrganizationConfiguration, error) {
-	query := `replace into organization_configs (organization, company_identification) values (?, ?);`
+	defer database.MeasureQuery("organization", "UpdateConfig")()
+
+	var threshold *float32
+	if cfg.OfacMatchThreshold > 0 {
+		threshold = &cfg.OfacMatchThreshold
+	}
+	networks := nullable(strings.Join(cfg.AllowedNetworks, ","))
+	description, individualName := nullable(cfg.MicroDepositEntryDescription), nullable(cfg.MicroDepositIndividualName)
+	var debitHold, creditHold *int32
+	if cfg.DebitHoldDays > 0 {
+		debitHold = &cfg.DebitHoldDays
+	}
+	if cfg.CreditHoldDays > 0 {
+		creditHold = &cfg.CreditHoldDays
+	}
+	displayName, supportEmail, logoURL := nullable(cfg.DisplayName), nullable(cfg.SupportEmail), nullable(cfg.LogoURL)
+	var messages *string
+	if len(cfg.Messages) > 0 {
+		bs, err := json.Marshal(cfg.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("config: encoding messages: %v", err)
+		}
+		messages = nullable(string(bs))
+	}
+	var fees *string
+	if cfg.Fees != nil {
+		bs, err := json.Marshal(cfg.Fees)
+		if err != nil {
+			return nil, fmt.Errorf("config: encoding fees: %v", err)
+		}
+		fees = nullable(string(bs))
+	}
+	var holdingAccount *string
+	if cfg.HoldingAccount != nil {
+		bs, err := json.Marshal(cfg.HoldingAccount)
+		if err != nil {
+			return nil, fmt.Errorf("config: encoding holding account: %v", err)
+		}
+		holdingAccount = nullable(string(bs))
+	}
+
+	if cfg.Version == 0 {
+		query := `insert into organization_configs (organization, company_identification, batching_strategy, ofac_match_threshold, allowed_networks, restrict_all_requests,
+micro_deposit_entry_description, micro_deposit_individual_name, debit_hold_days, credit_hold_days, display_name, support_email, logo_url, messages, fees, holding_account, version)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1);`
+		stmt, err := r.db.Prepare(query)
+		if err != nil {
+			return nil, fmt.Errorf("config: organization does not belong: %v", err)
+		}
+		defer stmt.Close()
+
+		if _, err := stmt.Exec(orgID, cfg.CompanyIdentification, cfg.BatchingStrategy, threshold, networks, cfg.RestrictAllRequests, description, individualName, debitHold, creditHold, displayName, supportEmail, logoURL, messages, fees, holdingAccount); err != nil {
+			if database.UniqueViolation(err) {
+				return nil, ErrVersionConflict
+			}
+			return nil, fmt.Errorf("config: issue updating config: %v", err)
+		}
+	} else {
+		query := `update organization_configs set company_identification = ?, batching_strategy = ?, ofac_match_threshold = ?, allowed_networks = ?,
+restrict_all_requests = ?, micro_deposit_entry_description = ?, micro_deposit_individual_name = ?,
+debit_hold_days = ?, credit_hold_days = ?, display_name = ?, support_email = ?, logo_url = ?, messages = ?, fees = ?, holding_account = ?, version = version + 1
+where organization = ? and version = ?;`
+		stmt, err := r.db.Prepare(query)
+		if err != nil {
+			return nil, fmt.Errorf("config: organization does not belong: %v", err)
+		}
+		defer stmt.Close()
+
+		res, err := stmt.Exec(cfg.CompanyIdentification, cfg.BatchingStrategy, threshold, networks, cfg.RestrictAllRequests, description, individualName, debitHold, creditHold, displayName, supportEmail, logoURL, messages, fees, holdingAccount, orgID, cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("config: issue updating config: %v", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, ErrVersionConflict
+		}
+	}
+
+	out := *cfg
+	out.Version++
+	return &out, nil
+}
+
+func (r *sqlRepo) GetDueDiligence(orgID string) (*client.OriginatorDueDiligence, error) {
+	defer database.MeasureQuery("organization", "GetDueDiligence")()
+
+	query := `select business_type, tax_id_token, address1, address2, city, state, postal_code, country, website, updated_at
+from organization_due_diligence where organization = ? limit 1;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
-		return nil, fmt.Errorf("config: organization does not belong: %v", err)
+		return nil, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(orgID, cfg.CompanyIdentification)
+	var dd client.OriginatorDueDiligence
+	var address1, address2, city, state, postalCode, country *string
+	var updated time.Time
+	err = stmt.QueryRow(orgID).Scan(&dd.BusinessType, &dd.TaxIDToken, &address1, &address2, &city, &state, &postalCode, &country, &dd.Website, &updated)
 	if err != nil {
-		return nil, fmt.Errorf("config: issue updating config: %v", err)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if address1 != nil {
+		dd.Address = &client.DueDiligenceAddress{
+			Address1:   *address1,
+			Address2:   stringValue(address2),
+			City:       stringValue(city),
+			State:      stringValue(state),
+			PostalCode: stringValue(postalCode),
+			Country:    stringValue(country),
+		}
+	}
+	dd.Updated = &updated
+	return &dd, nil
+}
+
+func (r *sqlRepo) UpdateDueDiligence(orgID string, dd *client.OriginatorDueDiligence) error {
+	defer database.MeasureQuery("organization", "UpdateDueDiligence")()
+
+	address := dd.Address
+	if address == nil {
+		address = &client.DueDiligenceAddress{}
+	}
+	args := []interface{}{
+		dd.BusinessType, dd.TaxIDToken,
+		nullable(address.Address1), nullable(address.Address2), nullable(address.City),
+		nullable(address.State), nullable(address.PostalCode), nullable(address.Country),
+		dd.Website, time.Now(), orgID,
+	}
+
+	query := `update organization_due_diligence set business_type = ?, tax_id_token = ?, address1 = ?, address2 = ?, city = ?,
+state = ?, postal_code = ?, country = ?, website = ?, updated_at = ? where organization = ?;`
+	res, err := r.db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("updating due-diligence: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	// MySQL doesn't count rows which were unchanged, so an existing row can still conflict
+	insert := `insert into organization_due_diligence (business_type, tax_id_token, address1, address2, city, state, postal_code, country, website, updated_at, organization)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	if _, err := r.db.Exec(insert, args...); err != nil {
+		if database.UniqueViolation(err) {
+			if _, err := r.db.Exec(query, args...); err != nil {
+				return fmt.Errorf("updating due-diligence: %v", err)
+			}
+			return nil
+		}
+		return fmt.Errorf("saving due-diligence: %v", err)
+	}
+	return nil
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
 	}
-	return cfg, nil
+	return *s
 }
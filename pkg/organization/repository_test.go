@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
 )
 
@@ -73,3 +74,131 @@ func TestRepository__GetConfig(t *testing.T) {
 	check(t, setupSQLiteDB(t))
 	check(t, setupMySQLeDB(t))
 }
+
+func TestRepository__UpdateConfig(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+
+		// configs written before batching strategies have none
+		writeConfig(t, orgID, Config{CompanyIdentification: "foo"}, repo)
+		cfg, err := repo.GetConfig(orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.BatchingStrategy != "" {
+			t.Errorf("BatchingStrategy=%q", cfg.BatchingStrategy)
+		}
+
+		if cfg.OfacMatchThreshold != 0 {
+			t.Errorf("OfacMatchThreshold=%v", cfg.OfacMatchThreshold)
+		}
+		if len(cfg.AllowedNetworks) != 0 || cfg.RestrictAllRequests {
+			t.Errorf("unexpected network policy: %#v", cfg)
+		}
+
+		cfg.BatchingStrategy = client.CONSOLIDATED
+		cfg.OfacMatchThreshold = 0.85
+		cfg.AllowedNetworks = []string{"203.0.113.0/24", "2001:db8::/32"}
+		cfg.RestrictAllRequests = true
+		cfg.MicroDepositEntryDescription = "ACCTVERIFY"
+		cfg.MicroDepositIndividualName = "ACME VERIFY"
+		cfg.DebitHoldDays = 3
+		cfg.DisplayName = "Acme Payroll"
+		cfg.SupportEmail = "support@acme.com"
+		cfg.LogoURL = "https://acme.com/logo.png"
+		cfg.Messages = map[string]map[string]string{
+			"es": {"not_found": "No encontramos eso"},
+		}
+		cfg.Fees = &client.FeeSchedule{
+			Debit:      &client.FeeRule{Flat: 25, Percentage: 0.5},
+			Collection: client.POSTING,
+			Account:    &client.Destination{CustomerID: "fees", AccountID: "revenue"},
+		}
+		cfg.HoldingAccount = &client.Destination{CustomerID: "marketplace", AccountID: "holding"}
+		if _, err := repo.UpdateConfig(orgID, cfg); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err = repo.GetConfig(orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.CompanyIdentification != "foo" || cfg.BatchingStrategy != client.CONSOLIDATED {
+			t.Errorf("unexpected config: %#v", cfg)
+		}
+		if cfg.OfacMatchThreshold < 0.849 || cfg.OfacMatchThreshold > 0.851 {
+			t.Errorf("OfacMatchThreshold=%v", cfg.OfacMatchThreshold)
+		}
+		if len(cfg.AllowedNetworks) != 2 || cfg.AllowedNetworks[1] != "2001:db8::/32" || !cfg.RestrictAllRequests {
+			t.Errorf("unexpected network policy: %#v", cfg)
+		}
+		if cfg.MicroDepositEntryDescription != "ACCTVERIFY" || cfg.MicroDepositIndividualName != "ACME VERIFY" {
+			t.Errorf("unexpected micro-deposit descriptor: %#v", cfg)
+		}
+		if cfg.DebitHoldDays != 3 || cfg.CreditHoldDays != 0 {
+			t.Errorf("unexpected availability policy: %#v", cfg)
+		}
+		if cfg.DisplayName != "Acme Payroll" || cfg.SupportEmail != "support@acme.com" || cfg.LogoURL != "https://acme.com/logo.png" {
+			t.Errorf("unexpected branding: %#v", cfg)
+		}
+		if msg := cfg.Messages["es"]["not_found"]; msg != "No encontramos eso" {
+			t.Errorf("unexpected messages: %#v", cfg.Messages)
+		}
+		if cfg.Fees == nil || cfg.Fees.Debit.Flat != 25 || cfg.Fees.Credit != nil || cfg.Fees.Account.AccountID != "revenue" {
+			t.Errorf("unexpected fees: %#v", cfg.Fees)
+		}
+		if cfg.HoldingAccount == nil || cfg.HoldingAccount.AccountID != "holding" {
+			t.Errorf("unexpected holding account: %#v", cfg.HoldingAccount)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__UpdateConfigVersion(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+
+		cfg, err := repo.UpdateConfig(orgID, &client.OrganizationConfiguration{CompanyIdentification: "foo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Version != 1 {
+			t.Errorf("Version=%d", cfg.Version)
+		}
+
+		// creating the config again conflicts with the existing one
+		if _, err := repo.UpdateConfig(orgID, &client.OrganizationConfiguration{CompanyIdentification: "bar"}); err != ErrVersionConflict {
+			t.Errorf("expected conflict: %v", err)
+		}
+
+		cfg.CompanyIdentification = "bar"
+		updated, err := repo.UpdateConfig(orgID, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != 2 {
+			t.Errorf("Version=%d", updated.Version)
+		}
+
+		// stale versions aren't written
+		cfg.CompanyIdentification = "baz"
+		if _, err := repo.UpdateConfig(orgID, cfg); err != ErrVersionConflict {
+			t.Errorf("expected conflict: %v", err)
+		}
+		cfg, err = repo.GetConfig(orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.CompanyIdentification != "bar" || cfg.Version != 2 {
+			t.Errorf("unexpected config: %#v", cfg)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
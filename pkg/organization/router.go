@@ -8,44 +8,65 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
-	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/x/route"
 )
 
 type Router struct {
 	GetConfig    http.HandlerFunc
 	UpdateConfig http.HandlerFunc
+
+	ExportConfig http.HandlerFunc
+	ImportConfig http.HandlerFunc
+
+	GetDueDiligence    http.HandlerFunc
+	UpdateDueDiligence http.HandlerFunc
 }
 
 func NewRouter(orgRepo Repository) *Router {
 	return &Router{
 		GetConfig:    getConfig(orgRepo),
 		UpdateConfig: updateConfig(orgRepo),
+		ExportConfig: exportConfig(orgRepo),
+		ImportConfig: importConfig(orgRepo),
+
+		GetDueDiligence:    getDueDiligence(orgRepo),
+		UpdateDueDiligence: updateDueDiligence(orgRepo),
 	}
 }
 
 func (router *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("PUT").Path("/configuration/transfers").HandlerFunc(router.UpdateConfig)
 	r.Methods("GET").Path("/configuration/transfers").HandlerFunc(router.GetConfig)
+	r.Methods("PUT").Path("/configuration/export").HandlerFunc(router.ImportConfig)
+	r.Methods("GET").Path("/configuration/export").HandlerFunc(router.ExportConfig)
+	r.Methods("PUT").Path("/configuration/due-diligence").HandlerFunc(router.UpdateDueDiligence)
+	r.Methods("GET").Path("/configuration/due-diligence").HandlerFunc(router.GetDueDiligence)
 }
 
 func getConfig(repo Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		organization := route.GetHeaderValue("X-Organization", r)
 		if organization == "" {
-			moovhttp.Problem(w, errors.New("missing organization"))
+			route.Problem(w, route.MissingOrganization.New("missing organization"))
 			return
 		}
 
 		cfg, err := repo.GetConfig(organization)
 		if err != nil {
-			moovhttp.Problem(w, err)
+			route.Problem(w, route.Internal.Wrap(err))
 			return
 		}
+		setETag(w, cfg)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(cfg)
 	}
@@ -55,21 +76,117 @@ func updateConfig(repo Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		organization := route.GetHeaderValue("X-Organization", r)
 		if organization == "" {
-			moovhttp.Problem(w, errors.New("missing organization"))
+			route.Problem(w, route.MissingOrganization.New("missing organization"))
 			return
 		}
 		var body client.OrganizationConfiguration
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			moovhttp.Problem(w, err)
+		if err := route.DecodeJSON(r, &body, route.DisallowUnknownFields); err != nil {
+			route.Problem(w, err)
 			return
 		}
+		verr := &route.ValidationError{}
+		if err := validateBatchingStrategy(body.BatchingStrategy); err != nil {
+			verr.Add("batchingStrategy", "%v", err)
+		}
+		if err := validateOFACMatchThreshold(body.OfacMatchThreshold); err != nil {
+			verr.Add("ofacMatchThreshold", "%v", err)
+		}
+		if err := validateAllowedNetworks(body.AllowedNetworks); err != nil {
+			verr.Add("allowedNetworks", "%v", err)
+		}
+		if body.RestrictAllRequests && len(body.AllowedNetworks) == 0 {
+			verr.Add("restrictAllRequests", "requires allowedNetworks")
+		}
+		if err := achx.ValidateCompanyEntryDescription(body.MicroDepositEntryDescription, ach.PPD); err != nil {
+			verr.Add("microDepositEntryDescription", "%v", err)
+		}
+		if err := achx.ValidateIndividualName(body.MicroDepositIndividualName); err != nil {
+			verr.Add("microDepositIndividualName", "%v", err)
+		}
+		if err := validateHoldDays(body.DebitHoldDays); err != nil {
+			verr.Add("debitHoldDays", "%v", err)
+		}
+		if err := validateHoldDays(body.CreditHoldDays); err != nil {
+			verr.Add("creditHoldDays", "%v", err)
+		}
+		validateBranding(verr, "", body)
+		validateMessages(verr, "", body.Messages)
+		validateFees(verr, "", body.Fees)
+		validateHoldingAccount(verr, "", body.HoldingAccount)
+		if err := verr.Err(); err != nil {
+			route.Problem(w, err)
+			return
+		}
+		if header := r.Header.Get("If-Match"); header != "" {
+			version, err := parseETag(header)
+			if err != nil {
+				route.Problem(w, route.InvalidRequest.Wrap(err))
+				return
+			}
+			body.Version = version
+		}
 
 		cfg, err := repo.UpdateConfig(organization, &body)
 		if err != nil {
-			moovhttp.Problem(w, fmt.Errorf("problem updating config - error=%v", err))
+			if errors.Is(err, ErrVersionConflict) {
+				route.Problem(w, route.Conflict.New("%v, read the current version and retry", err))
+				return
+			}
+			route.Problem(w, route.Internal.New("problem updating config - error=%v", err))
 			return
 		}
+		setETag(w, cfg)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(cfg)
 	}
 }
+
+func validateBatchingStrategy(strategy client.BatchingStrategy) error {
+	switch strategy {
+	case "", client.PER_TRANSFER, client.CONSOLIDATED:
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %q", strategy)
+}
+
+func validateOFACMatchThreshold(threshold float32) error {
+	if threshold < 0 || threshold > 1 {
+		return fmt.Errorf("%v isn't between 0 and 1", threshold)
+	}
+	return nil
+}
+
+func validateHoldDays(days int32) error {
+	if days < 0 || days > config.MaxHoldDays {
+		return fmt.Errorf("%d isn't between 0 and %d", days, config.MaxHoldDays)
+	}
+	return nil
+}
+
+func validateAllowedNetworks(networks []string) error {
+	for i := range networks {
+		if _, _, err := net.ParseCIDR(networks[i]); err != nil {
+			return err
+		}
+	}
+	if n := len(strings.Join(networks, ",")); n > 1000 {
+		return fmt.Errorf("%d characters is over the limit of 1000", n)
+	}
+	return nil
+}
+
+// setETag returns the version of cfg as its ETag so clients can send it back in If-Match.
+func setETag(w http.ResponseWriter, cfg *client.OrganizationConfiguration) {
+	if cfg != nil && cfg.Version > 0 {
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, cfg.Version))
+	}
+}
+
+func parseETag(header string) (int64, error) {
+	tag := strings.Trim(strings.TrimPrefix(strings.TrimSpace(header), "W/"), `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header %q", header)
+	}
+	return version, nil
+}
@@ -142,3 +142,340 @@ func TestUpdateConfigMissingCompany(t *testing.T) {
 
 	require.Equal(t, w.Code, http.StatusBadRequest)
 }
+
+func TestUpdateConfigBatchingStrategy(t *testing.T) {
+	update := func(strategy client.BatchingStrategy) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			BatchingStrategy:      strategy,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update(client.CONSOLIDATED)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response client.OrganizationConfiguration
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	require.Equal(t, client.CONSOLIDATED, response.BatchingStrategy)
+
+	w = update("weekly")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateConfigOFACMatchThreshold(t *testing.T) {
+	update := func(threshold float32) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			OfacMatchThreshold:    threshold,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update(0.9)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response client.OrganizationConfiguration
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Equal(t, float32(0.9), response.OfacMatchThreshold)
+
+	w = update(1.5)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateConfigAllowedNetworks(t *testing.T) {
+	update := func(networks []string, restrictAll bool) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			AllowedNetworks:       networks,
+			RestrictAllRequests:   restrictAll,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update([]string{"203.0.113.0/24"}, true)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update([]string{"203.0.113.1"}, false)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = update(nil, true)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateConfigMicroDepositDescriptor(t *testing.T) {
+	update := func(description, individualName string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification:        base.ID(),
+			MicroDepositEntryDescription: description,
+			MicroDepositIndividualName:   individualName,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update("ACCTVERIFY", "ACME VERIFY")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update("ACCOUNT VERIFY", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = update("", "ACME CORPORATION VERIFICATION")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateConfigHoldDays(t *testing.T) {
+	update := func(debitDays, creditDays int32) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			DebitHoldDays:         debitDays,
+			CreditHoldDays:        creditDays,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update(3, 1)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update(-1, 0)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = update(0, 31)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateConfigBranding(t *testing.T) {
+	update := func(displayName, supportEmail, logoURL string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			DisplayName:           displayName,
+			SupportEmail:          supportEmail,
+			LogoURL:               logoURL,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update("Acme Payroll", "support@acme.com", "https://acme.com/logo.png")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update("", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update("   ", "", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = update("", "Acme <support@acme.com>", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = update("", "support", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = update("", "", "http://acme.com/logo.png")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "logoURL")
+}
+
+func TestUpdateConfigMessages(t *testing.T) {
+	update := func(messages map[string]map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			Messages:              messages,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update(map[string]map[string]string{
+		"en": {"not_found": "We couldn't find that"},
+		"es": {"not_found": "No encontramos eso"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update(map[string]map[string]string{"fr": {"not_found": "Introuvable"}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "messages.fr")
+
+	w = update(map[string]map[string]string{"es": {"missing": "Falta"}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "messages.es.missing")
+
+	w = update(map[string]map[string]string{"es": {"not_found": "  "}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateConfigFees(t *testing.T) {
+	update := func(fees *client.FeeSchedule) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: base.ID(),
+			Fees:                  fees,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		w := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		NewRouter(&MockRepository{}).RegisterRoutes(router)
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update(&client.FeeSchedule{
+		Credit:     &client.FeeRule{Flat: 25},
+		Debit:      &client.FeeRule{Percentage: 1.5},
+		Collection: client.ENTRY,
+		Account:    &client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = update(&client.FeeSchedule{Credit: &client.FeeRule{Flat: -1}, Debit: &client.FeeRule{Percentage: 101}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "fees.credit.flat")
+	require.Contains(t, w.Body.String(), "fees.debit.percentage")
+
+	w = update(&client.FeeSchedule{Collection: client.POSTING})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "fees.account")
+
+	w = update(&client.FeeSchedule{Collection: "invoice"})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "fees.collection")
+}
+
+func TestUpdateConfigHoldingAccount(t *testing.T) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+		CompanyIdentification: base.ID(),
+		HoldingAccount:        &client.Destination{CustomerID: base.ID()},
+	})
+	req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+	req.Header.Set("X-Organization", "moov")
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	NewRouter(&MockRepository{}).RegisterRoutes(router)
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "holdingAccount.accountID")
+}
+
+func TestUpdateConfigVersions(t *testing.T) {
+	router := mux.NewRouter()
+	NewRouter(NewInMemoryRepo()).RegisterRoutes(router)
+
+	update := func(companyID string, version int64, ifMatch string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		json.NewEncoder(&body).Encode(&client.OrganizationConfiguration{
+			CompanyIdentification: companyID,
+			Version:               version,
+		})
+		req := httptest.NewRequest("PUT", "/configuration/transfers", &body)
+		req.Header.Set("X-Organization", "moov")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		w.Flush()
+		return w
+	}
+
+	w := update("foo", 0, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	// updates without the current version conflict
+	w = update("bar", 0, "")
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	w = update("bar", 0, `"1"`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = update("baz", 1, "")
+	require.Equal(t, http.StatusConflict, w.Code)
+
+	w = update("baz", 2, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var cfg client.OrganizationConfiguration
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&cfg))
+	require.Equal(t, int64(3), cfg.Version)
+
+	w = update("baz", 0, "latest")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	// reads return the current version
+	req := httptest.NewRequest("GET", "/configuration/transfers", nil)
+	req.Header.Set("X-Organization", "moov")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+
+	"github.com/moov-io/base/log"
+)
+
+const (
+	dailyReportDateFormat = "2006-01-02"
+)
+
+// DailySummary is the origination activity of an organization on a given day.
+// Dollar amounts are in cents.
+type DailySummary struct {
+	Date          string   `json:"date"`
+	Organization  string   `json:"organization"`
+	TransferCount int      `json:"transferCount"`
+	EntryCount    int      `json:"entryCount"`
+	DebitTotal    int      `json:"debitTotal"`
+	CreditTotal   int      `json:"creditTotal"`
+	Filenames     []string `json:"filenames"`
+	RejectedCount int      `json:"rejectedCount"`
+}
+
+var (
+	dailyEmailTemplate = template.Must(template.New("daily").Funcs(template.FuncMap{
+		"dollars": func(cents int) float64 {
+			return float64(cents) / 100.0
+		},
+	}).Parse(`Origination summary for {{ .Date }}
+{{ range .Summaries }}
+Organization: {{ .Organization }}
+Transfers: {{ .TransferCount }}
+Entries:   {{ .EntryCount }}
+Debits:    ${{ dollars .DebitTotal | printf "%.2f" }}
+Credits:   ${{ dollars .CreditTotal | printf "%.2f" }}
+Rejected:  {{ .RejectedCount }}
+Files:     {{ range $i, $f := .Filenames }}{{ if $i }}, {{ end }}{{ $f }}{{ end }}
+{{ end }}`))
+)
+
+type sender interface {
+	Send(subject, body string) error
+}
+
+// DailyReporter records a summary of each cutoff window per organization and
+// optionally emails the day's combined summaries.
+type DailyReporter struct {
+	logger   log.Logger
+	location *time.Location
+	repo     Repository
+	mailer   sender
+}
+
+func NewDailyReporter(cfg *config.Config, repo Repository) (*DailyReporter, error) {
+	reporter := &DailyReporter{
+		logger:   cfg.Logger.Set("service", log.String("daily-reports")),
+		location: cfg.ODFI.Cutoffs.Location(),
+		repo:     repo,
+	}
+	if reporter.location == nil {
+		reporter.location = time.UTC
+	}
+	if cfg.Reports.Daily != nil && cfg.Reports.Daily.Email != nil {
+		mailer, err := notify.NewEmail(cfg.Reports.Daily.Email)
+		if err != nil {
+			return nil, fmt.Errorf("daily reports: %v", err)
+		}
+		reporter.mailer = mailer
+	}
+	return reporter, nil
+}
+
+// HandleCutoff is a pipeline.CompletedCutoffCallback which saves a summary of the
+// uploaded Transfers for each organization.
+func (dr *DailyReporter) HandleCutoff(cutoff pipeline.CompletedCutoff) error {
+	if len(cutoff.Transfers) == 0 {
+		return nil
+	}
+
+	transferIDs := make([]string, len(cutoff.Transfers))
+	for i := range cutoff.Transfers {
+		transferIDs[i] = cutoff.Transfers[i].TransferID
+	}
+	orgs, err := dr.repo.getTransferOrganizations(transferIDs)
+	if err != nil {
+		return fmt.Errorf("daily reports: finding organizations: %v", err)
+	}
+
+	when := cutoff.When.In(dr.location)
+	summaries := make(map[string]*DailySummary)
+	for i := range cutoff.Transfers {
+		xfer := cutoff.Transfers[i]
+		orgID, ok := orgs[xfer.TransferID]
+		if !ok {
+			continue // not a Transfer we know about
+		}
+		summary, exists := summaries[orgID]
+		if !exists {
+			summary = &DailySummary{
+				Date:         when.Format(dailyReportDateFormat),
+				Organization: orgID,
+			}
+			summaries[orgID] = summary
+		}
+		// only list the files holding this organization's entries
+		for _, filename := range xfer.Filenames {
+			if !containsFilename(summary.Filenames, filename) {
+				summary.Filenames = append(summary.Filenames, filename)
+			}
+		}
+		summary.TransferCount++
+		summary.EntryCount += xfer.EntryCount
+		summary.DebitTotal += xfer.DebitTotal
+		summary.CreditTotal += xfer.CreditTotal
+	}
+	for orgID, summary := range summaries {
+		if err := dr.repo.saveCutoffSummary(summary, when); err != nil {
+			return fmt.Errorf("daily reports: saving organization=%s summary: %v", orgID, err)
+		}
+	}
+	dr.logger.Logf("saved cutoff summaries for %d organizations", len(summaries))
+
+	return dr.sendEmail(when)
+}
+
+func containsFilename(filenames []string, filename string) bool {
+	for i := range filenames {
+		if filenames[i] == filename {
+			return true
+		}
+	}
+	return false
+}
+
+func (dr *DailyReporter) sendEmail(when time.Time) error {
+	if dr.mailer == nil {
+		return nil
+	}
+	// The summaries of this cutoff were just saved and a read replica might not have them yet
+	summaries, err := dr.repo.getLatestDailySummaries(when)
+	if err != nil {
+		return fmt.Errorf("daily reports: reading summaries: %v", err)
+	}
+	body, err := marshalDailyEmail(when, summaries)
+	if err != nil {
+		return fmt.Errorf("daily reports: rendering email: %v", err)
+	}
+	subject := fmt.Sprintf("Origination summary for %s", when.Format(dailyReportDateFormat))
+	return dr.mailer.Send(subject, body)
+}
+
+func marshalDailyEmail(when time.Time, summaries []*DailySummary) (string, error) {
+	var buf bytes.Buffer
+	err := dailyEmailTemplate.Execute(&buf, struct {
+		Date      string
+		Summaries []*DailySummary
+	}{
+		Date:      when.Format(dailyReportDateFormat),
+		Summaries: summaries,
+	})
+	return buf.String(), err
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (dr *DailyReporter) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/reports/daily/{date}", dr.getDailySummaries())
+}
+
+func (dr *DailyReporter) getDailySummaries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodGet {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		date, err := time.ParseInLocation(dailyReportDateFormat, route.ReadPathID("date", r), dr.location)
+		if err != nil {
+			route.Problem(w, route.InvalidRequest.New("invalid date: %v", err))
+			return
+		}
+
+		summaries, err := dr.repo.getDailySummaries(date)
+		if err != nil {
+			dr.logger.LogErrorf("ERROR reading daily summaries: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(summaries)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+)
+
+type mockSender struct {
+	subject, body string
+	err           error
+}
+
+func (s *mockSender) Send(subject, body string) error {
+	s.subject, s.body = subject, body
+	return s.err
+}
+
+func TestDailyReporter__HandleCutoff(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	mailer := &mockSender{}
+	reporter := &DailyReporter{
+		logger:   log.NewNopLogger(),
+		location: time.UTC,
+		repo:     repo,
+		mailer:   mailer,
+	}
+
+	now := time.Now().UTC()
+	x1 := writeTransfer(t, "org1", repo, now)
+	x2 := writeTransfer(t, "org1", repo, now)
+	x3 := writeTransfer(t, "org2", repo, now)
+
+	cutoff := pipeline.CompletedCutoff{
+		When:      now,
+		Filenames: []string{"20200529-1530-987654320.ach", "20200529-1530-987654320-2.ach"},
+		Transfers: []pipeline.ProcessedTransfer{
+			{TransferID: x1.TransferID, EntryCount: 1, DebitTotal: 1245, Filenames: []string{"20200529-1530-987654320.ach"}},
+			{TransferID: x2.TransferID, EntryCount: 2, CreditTotal: 500, Filenames: []string{"20200529-1530-987654320.ach"}},
+			{TransferID: x3.TransferID, EntryCount: 1, DebitTotal: 100, Filenames: []string{"20200529-1530-987654320-2.ach"}},
+			{TransferID: "unknown", EntryCount: 1, DebitTotal: 100},
+		},
+	}
+	if err := reporter.HandleCutoff(cutoff); err != nil {
+		t.Fatal(err)
+	}
+	// second cutoff window on the same day
+	cutoff.Transfers = cutoff.Transfers[:1]
+	if err := reporter.HandleCutoff(cutoff); err != nil {
+		t.Fatal(err)
+	}
+
+	summaries, err := repo.getDailySummaries(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("unexpected summaries: %#v", summaries)
+	}
+	if s := summaries[0]; s.Organization != "org1" || s.TransferCount != 3 || s.EntryCount != 4 || s.DebitTotal != 2490 || s.CreditTotal != 500 {
+		t.Errorf("unexpected org1 summary: %#v", s)
+	} else if len(s.Filenames) != 2 || s.Filenames[0] != "20200529-1530-987654320.ach" || s.Filenames[1] != "20200529-1530-987654320.ach" {
+		t.Errorf("unexpected filenames: %v", s.Filenames)
+	}
+	// other organizations' files aren't listed
+	if s := summaries[1]; s.Organization != "org2" || s.TransferCount != 1 || s.DebitTotal != 100 {
+		t.Errorf("unexpected org2 summary: %#v", s)
+	} else if len(s.Filenames) != 1 || s.Filenames[0] != "20200529-1530-987654320-2.ach" {
+		t.Errorf("unexpected filenames: %v", s.Filenames)
+	}
+
+	if !strings.HasPrefix(mailer.subject, "Origination summary for ") {
+		t.Errorf("unexpected subject: %q", mailer.subject)
+	}
+	if !strings.Contains(mailer.body, "Organization: org1") || !strings.Contains(mailer.body, "Debits:    $24.90") {
+		t.Errorf("unexpected body:\n%s", mailer.body)
+	}
+}
+
+// laggingRepo reads daily summaries like a read replica which hasn't caught up with the primary
+type laggingRepo struct {
+	*sqlRepo
+}
+
+func (r *laggingRepo) getDailySummaries(date time.Time) ([]*DailySummary, error) {
+	return nil, nil
+}
+
+func TestDailyReporter__HandleCutoffLaggingReplica(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	mailer := &mockSender{}
+	reporter := &DailyReporter{
+		logger:   log.NewNopLogger(),
+		location: time.UTC,
+		repo:     &laggingRepo{sqlRepo: repo},
+		mailer:   mailer,
+	}
+
+	now := time.Now().UTC()
+	xfer := writeTransfer(t, "org1", repo, now)
+	cutoff := pipeline.CompletedCutoff{
+		When: now,
+		Transfers: []pipeline.ProcessedTransfer{
+			{TransferID: xfer.TransferID, EntryCount: 1, DebitTotal: 1245},
+		},
+	}
+	if err := reporter.HandleCutoff(cutoff); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(mailer.body, "Organization: org1") || !strings.Contains(mailer.body, "Debits:    $12.45") {
+		t.Errorf("unexpected body:\n%s", mailer.body)
+	}
+}
+
+func TestDailyReporter__HandleCutoffEmpty(t *testing.T) {
+	mailer := &mockSender{}
+	reporter := &DailyReporter{
+		logger:   log.NewNopLogger(),
+		location: time.UTC,
+		repo:     setupSQLiteDB(t),
+		mailer:   mailer,
+	}
+	if err := reporter.HandleCutoff(pipeline.CompletedCutoff{When: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if mailer.body != "" {
+		t.Errorf("unexpected email: %q", mailer.body)
+	}
+}
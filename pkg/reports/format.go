@@ -0,0 +1,137 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// transferWriter encodes Transfers into a report one row at a time.
+type transferWriter interface {
+	ContentType() string
+
+	Write(xfer *client.Transfer) error
+
+	// Flush writes any buffered data to the underlying io.Writer.
+	Flush() error
+
+	// Close finishes the report. No calls to Write should be made afterwards.
+	Close() error
+}
+
+func newTransferWriter(format string, w io.Writer) (transferWriter, error) {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		return newCSVWriter(w)
+	case "json":
+		return &jsonWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown report format %q", format)
+}
+
+var (
+	csvHeaders = []string{
+		"transferID", "status", "currency", "amount",
+		"sourceCustomerID", "sourceAccountID", "destinationCustomerID", "destinationAccountID",
+		"description", "sameDay", "returnCode", "processedAt", "created",
+		"expectedSettlementDate", "actualSettlementDate",
+	}
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(csvHeaders); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (cw *csvWriter) Write(xfer *client.Transfer) error {
+	var returnCode, processedAt string
+	if xfer.ReturnCode != nil {
+		returnCode = xfer.ReturnCode.Code
+	}
+	if xfer.ProcessedAt != nil {
+		processedAt = xfer.ProcessedAt.Format(time.RFC3339)
+	}
+	return cw.w.Write([]string{
+		xfer.TransferID,
+		string(xfer.Status),
+		xfer.Amount.Currency,
+		strconv.Itoa(int(xfer.Amount.Value)),
+		xfer.Source.CustomerID,
+		xfer.Source.AccountID,
+		xfer.Destination.CustomerID,
+		xfer.Destination.AccountID,
+		xfer.Description,
+		strconv.FormatBool(xfer.SameDay),
+		returnCode,
+		processedAt,
+		xfer.Created.Format(time.RFC3339),
+		xfer.ExpectedSettlementDate,
+		xfer.ActualSettlementDate,
+	})
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+// jsonWriter produces a JSON array of Transfer objects without buffering
+// the entire array in memory.
+type jsonWriter struct {
+	w       io.Writer
+	written int
+}
+
+func (jw *jsonWriter) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (jw *jsonWriter) Write(xfer *client.Transfer) error {
+	prefix := ","
+	if jw.written == 0 {
+		prefix = "["
+	}
+	if _, err := io.WriteString(jw.w, prefix); err != nil {
+		return err
+	}
+	jw.written++
+	return json.NewEncoder(jw.w).Encode(xfer)
+}
+
+func (jw *jsonWriter) Flush() error {
+	return nil
+}
+
+func (jw *jsonWriter) Close() error {
+	if jw.written == 0 {
+		_, err := io.WriteString(jw.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(jw.w, "]\n")
+	return err
+}
@@ -0,0 +1,383 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+type Repository interface {
+	// iterateTransfers calls fn with each Transfer of an organization created within
+	// the given window. Rows are read from an open cursor so callers can stream
+	// results without holding the full report in memory.
+	iterateTransfers(orgID string, params reportParams, fn func(*client.Transfer) error) error
+
+	getTransferOrganizations(transferIDs []string) (map[string]string, error)
+	saveCutoffSummary(summary *DailySummary, cutoffAt time.Time) error
+	getDailySummaries(date time.Time) ([]*DailySummary, error)
+	// getLatestDailySummaries reads daily summaries from the primary, so ones saved by a
+	// cutoff are included before they reach a read replica.
+	getLatestDailySummaries(date time.Time) ([]*DailySummary, error)
+
+	// getStatistics aggregates an organization's Transfers created since monthStart,
+	// pending micro-deposits and Transfers returned since returnsSince.
+	getStatistics(orgID string, monthStart, returnsSince time.Time) (*client.Statistics, error)
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+
+	// replica serves reports when a read replica is configured
+	replica *database.Replica
+}
+
+// UseReadReplica sends the queries of reports and statistics to replica while it's reachable.
+// Daily summaries are still saved to and emailed from the primary right after each cutoff.
+func (r *sqlRepo) UseReadReplica(replica *database.Replica) {
+	r.replica = replica
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) iterateTransfers(orgID string, params reportParams, fn func(*client.Transfer) error) error {
+	defer database.MeasureQuery("reports", "iterateTransfers")()
+
+	query := `select transfer_id, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, processed_at, created_at, expected_settlement_date, actual_settlement_date
+from transfers
+where organization = ? and created_at >= ? and created_at < ? and deleted_at is null`
+	args := []interface{}{orgID, params.StartDate, params.EndDate}
+	if params.SettlementStart != "" {
+		query += ` and coalesce(actual_settlement_date, expected_settlement_date) >= ?`
+		args = append(args, params.SettlementStart)
+	}
+	if params.SettlementEnd != "" {
+		query += ` and coalesce(actual_settlement_date, expected_settlement_date) <= ?`
+		args = append(args, params.SettlementEnd)
+	}
+	query += ` order by created_at asc;`
+
+	stmt, err := r.replica.Reader(r.db).Prepare(query)
+	if err != nil {
+		return fmt.Errorf("report prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return fmt.Errorf("report query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var returnCode, expectedSettlement, actualSettlement *string
+		var processedAt *time.Time
+		xfer := &client.Transfer{}
+		if err := rows.Scan(
+			&xfer.TransferID,
+			&xfer.Amount.Currency,
+			&xfer.Amount.Value,
+			&xfer.Source.CustomerID,
+			&xfer.Source.AccountID,
+			&xfer.Destination.CustomerID,
+			&xfer.Destination.AccountID,
+			&xfer.Description,
+			&xfer.Status,
+			&xfer.SameDay,
+			&returnCode,
+			&processedAt,
+			&xfer.Created,
+			&expectedSettlement,
+			&actualSettlement,
+		); err != nil {
+			return fmt.Errorf("report scan: %v", err)
+		}
+		xfer.ProcessedAt = processedAt
+		if expectedSettlement != nil {
+			xfer.ExpectedSettlementDate = *expectedSettlement
+		}
+		if actualSettlement != nil {
+			xfer.ActualSettlementDate = *actualSettlement
+		}
+		if returnCode != nil {
+			xfer.ReturnCode = achx.ReturnCode(*returnCode)
+		}
+		if err := fn(xfer); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// getTransferOrganizations returns the organization of each transferID provided.
+func (r *sqlRepo) getTransferOrganizations(transferIDs []string) (map[string]string, error) {
+	defer database.MeasureQuery("reports", "getTransferOrganizations")()
+
+	out := make(map[string]string)
+	if len(transferIDs) == 0 {
+		return out, nil
+	}
+
+	query := fmt.Sprintf(`select transfer_id, organization from transfers where transfer_id in (%s);`, database.Placeholders(len(transferIDs)))
+	err := database.QueryRows(r.db, "getTransferOrganizations", query, database.StringArgs(transferIDs), func(rows *sql.Rows) error {
+		var transferID, orgID string
+		if err := rows.Scan(&transferID, &orgID); err != nil {
+			return err
+		}
+		out[transferID] = orgID
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) saveCutoffSummary(summary *DailySummary, cutoffAt time.Time) error {
+	defer database.MeasureQuery("reports", "saveCutoffSummary")()
+
+	query := `insert into cutoff_summaries (organization, report_date, cutoff_at, transfer_count, entry_count, debit_total, credit_total, filenames, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		summary.Organization,
+		summary.Date,
+		cutoffAt,
+		summary.TransferCount,
+		summary.EntryCount,
+		summary.DebitTotal,
+		summary.CreditTotal,
+		strings.Join(summary.Filenames, ","),
+		time.Now(),
+	)
+	return err
+}
+
+func (r *sqlRepo) getDailySummaries(date time.Time) ([]*DailySummary, error) {
+	defer database.MeasureQuery("reports", "getDailySummaries")()
+
+	return r.dailySummaries(r.replica.Reader(r.db), date)
+}
+
+func (r *sqlRepo) getLatestDailySummaries(date time.Time) ([]*DailySummary, error) {
+	defer database.MeasureQuery("reports", "getLatestDailySummaries")()
+
+	return r.dailySummaries(r.db, date)
+}
+
+// dailySummaries combines each cutoff summary on the given date by organization. Rejected
+// counts are read from transfers which failed that day.
+func (r *sqlRepo) dailySummaries(db *sql.DB, date time.Time) ([]*DailySummary, error) {
+	reportDate := date.Format(dailyReportDateFormat)
+
+	query := `select organization, transfer_count, entry_count, debit_total, credit_total, filenames from cutoff_summaries where report_date = ? order by cutoff_at asc;`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(reportDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make(map[string]*DailySummary)
+	for rows.Next() {
+		var orgID, filenames string
+		var transferCount, entryCount, debitTotal, creditTotal int
+		if err := rows.Scan(&orgID, &transferCount, &entryCount, &debitTotal, &creditTotal, &filenames); err != nil {
+			return nil, fmt.Errorf("getDailySummaries scan: %v", err)
+		}
+		summary, ok := summaries[orgID]
+		if !ok {
+			summary = &DailySummary{
+				Date:         reportDate,
+				Organization: orgID,
+				Filenames:    make([]string, 0),
+			}
+			summaries[orgID] = summary
+		}
+		summary.TransferCount += transferCount
+		summary.EntryCount += entryCount
+		summary.DebitTotal += debitTotal
+		summary.CreditTotal += creditTotal
+		if filenames != "" {
+			summary.Filenames = append(summary.Filenames, strings.Split(filenames, ",")...)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := countRejected(db, date, summaries); err != nil {
+		return nil, err
+	}
+
+	out := make([]*DailySummary, 0, len(summaries))
+	for _, summary := range summaries {
+		out = append(out, summary)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Organization < out[j].Organization })
+	return out, nil
+}
+
+func countRejected(db *sql.DB, date time.Time, summaries map[string]*DailySummary) error {
+	query := `select organization, count(*) from transfers where status = ? and created_at >= ? and created_at < ? and deleted_at is null group by organization;`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	rows, err := stmt.Query(client.FAILED, start, start.Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orgID string
+		var count int
+		if err := rows.Scan(&orgID, &count); err != nil {
+			return fmt.Errorf("countRejected scan: %v", err)
+		}
+		summary, ok := summaries[orgID]
+		if !ok {
+			summary = &DailySummary{
+				Date:         date.Format(dailyReportDateFormat),
+				Organization: orgID,
+				Filenames:    make([]string, 0),
+			}
+			summaries[orgID] = summary
+		}
+		summary.RejectedCount = count
+	}
+	return rows.Err()
+}
+
+func (r *sqlRepo) getStatistics(orgID string, monthStart, returnsSince time.Time) (*client.Statistics, error) {
+	defer database.MeasureQuery("reports", "getStatistics")()
+
+	stats := &client.Statistics{
+		Month: monthStart.Format(statisticsMonthFormat),
+		Returns: client.ReturnStatistics{
+			Codes: make([]client.ReturnCodeCount, 0),
+		},
+	}
+	if err := r.countTransfers(orgID, monthStart, &stats.Transfers); err != nil {
+		return nil, fmt.Errorf("counting transfers: %v", err)
+	}
+	if err := r.countPendingVerifications(orgID, &stats.PendingVerifications); err != nil {
+		return nil, fmt.Errorf("counting micro-deposits: %v", err)
+	}
+	if err := r.countReturns(orgID, returnsSince, &stats.Returns); err != nil {
+		return nil, fmt.Errorf("counting returns: %v", err)
+	}
+	return stats, nil
+}
+
+func (r *sqlRepo) countTransfers(orgID string, since time.Time, out *client.TransferStatistics) error {
+	query := `select status, count(*), coalesce(sum(amount_value), 0) from transfers
+where organization = ? and created_at >= ? and deleted_at is null
+group by status;`
+	stmt, err := r.replica.Reader(r.db).Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(orgID, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status client.TransferStatus
+		var count int32
+		var volume int64
+		if err := rows.Scan(&status, &count, &volume); err != nil {
+			return fmt.Errorf("countTransfers scan: %v", err)
+		}
+		out.Total += count
+		switch status {
+		case client.PENDING:
+			out.Pending = count
+		case client.PROCESSED:
+			out.Processed = count
+		case client.REVIEWABLE:
+			out.Reviewable = count
+		case client.CANCELED:
+			out.Canceled = count
+		case client.FAILED:
+			out.Failed = count
+		}
+		if status != client.CANCELED && status != client.FAILED {
+			out.Volume += volume
+		}
+	}
+	return rows.Err()
+}
+
+func (r *sqlRepo) countPendingVerifications(orgID string, out *int32) error {
+	query := `select count(distinct md.micro_deposit_id) from micro_deposits as md
+inner join micro_deposit_transfers as mdt on md.micro_deposit_id = mdt.micro_deposit_id
+inner join transfers as t on mdt.transfer_id = t.transfer_id
+where t.organization = ? and md.status = ? and md.deleted_at is null;`
+	stmt, err := r.replica.Reader(r.db).Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.QueryRow(orgID, client.PENDING).Scan(out)
+}
+
+func (r *sqlRepo) countReturns(orgID string, since time.Time, out *client.ReturnStatistics) error {
+	query := `select return_code, count(*) from transfers
+where organization = ? and return_code is not null and return_code <> '' and last_updated_at >= ? and deleted_at is null
+group by return_code order by count(*) desc, return_code asc;`
+	stmt, err := r.replica.Reader(r.db).Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(orgID, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code client.ReturnCodeCount
+		if err := rows.Scan(&code.Code, &code.Count); err != nil {
+			return fmt.Errorf("countReturns scan: %v", err)
+		}
+		out.Total += code.Count
+		out.Codes = append(out.Codes, code)
+	}
+	return rows.Err()
+}
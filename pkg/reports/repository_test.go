@@ -0,0 +1,215 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func writeTransfer(t *testing.T, orgID string, repo *sqlRepo, created time.Time) *client.Transfer {
+	t.Helper()
+
+	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, created_at, last_updated_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := repo.db.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	xfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      client.Amount{Currency: "USD", Value: 1245},
+		Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Description: "payroll",
+		Status:      client.PROCESSED,
+		Created:     created,
+	}
+	_, err = stmt.Exec(xfer.TransferID, orgID, xfer.Amount.Currency, xfer.Amount.Value,
+		xfer.Source.CustomerID, xfer.Source.AccountID, xfer.Destination.CustomerID, xfer.Destination.AccountID,
+		xfer.Description, xfer.Status, xfer.SameDay, "R01", created, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xfer
+}
+
+func TestRepository__iterateTransfers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		old := writeTransfer(t, orgID, repo, time.Now().Add(-72*time.Hour))
+		recent := writeTransfer(t, orgID, repo, time.Now())
+		writeTransfer(t, base.ID(), repo, time.Now()) // other organization
+
+		params, _ := readReportParams(&http.Request{})
+		var found []*client.Transfer
+		err := repo.iterateTransfers(orgID, params, func(xfer *client.Transfer) error {
+			found = append(found, xfer)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 2 {
+			t.Fatalf("got %d transfers", len(found))
+		}
+		if found[0].TransferID != old.TransferID || found[1].TransferID != recent.TransferID {
+			t.Errorf("unexpected order: %s then %s", found[0].TransferID, found[1].TransferID)
+		}
+		if found[0].ReturnCode == nil || found[0].ReturnCode.Code != "R01" {
+			t.Errorf("unexpected ReturnCode: %#v", found[0].ReturnCode)
+		}
+
+		// only include the recent Transfer
+		params.StartDate = time.Now().Add(-24 * time.Hour)
+		found = nil
+		err = repo.iterateTransfers(orgID, params, func(xfer *client.Transfer) error {
+			found = append(found, xfer)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 || found[0].TransferID != recent.TransferID {
+			t.Errorf("unexpected transfers: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__iterateTransfersDates(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		endOfDay := writeTransfer(t, orgID, repo, time.Date(2020, time.April, 8, 23, 30, 0, 0, time.UTC))
+		nextDay := writeTransfer(t, orgID, repo, time.Date(2020, time.April, 9, 0, 30, 0, 0, time.UTC))
+		if _, err := repo.db.Exec(`update transfers set expected_settlement_date = ? where transfer_id = ?;`, "2020-04-10", endOfDay.TransferID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.db.Exec(`update transfers set expected_settlement_date = ?, actual_settlement_date = ? where transfer_id = ?;`, "2020-04-10", "2020-04-13", nextDay.TransferID); err != nil {
+			t.Fatal(err)
+		}
+
+		iterate := func(query string) []*client.Transfer {
+			u, _ := url.Parse("http://localhost:8082/reports/transfers?" + query)
+			params, err := readReportParams(&http.Request{URL: u})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var found []*client.Transfer
+			err = repo.iterateTransfers(orgID, params, func(xfer *client.Transfer) error {
+				found = append(found, xfer)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return found
+		}
+
+		// the whole end date is included
+		if found := iterate("start=2020-04-08&end=2020-04-08"); len(found) != 1 || found[0].TransferID != endOfDay.TransferID {
+			t.Errorf("unexpected transfers: %#v", found)
+		} else if found[0].ExpectedSettlementDate != "2020-04-10" || found[0].ActualSettlementDate != "" {
+			t.Errorf("unexpected settlement dates: %#v", found[0])
+		}
+
+		// Transfers which were uploaded are filtered by their actual settlement date
+		if found := iterate("settlementStart=2020-04-10&settlementEnd=2020-04-10"); len(found) != 1 || found[0].TransferID != endOfDay.TransferID {
+			t.Errorf("unexpected transfers: %#v", found)
+		}
+		if found := iterate("settlementStart=2020-04-11"); len(found) != 1 || found[0].TransferID != nextDay.TransferID {
+			t.Errorf("unexpected transfers: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__getStatistics(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		now := time.Now()
+
+		writeTransfer(t, orgID, repo, now)
+		writeTransfer(t, orgID, repo, now)
+		failed := writeTransfer(t, orgID, repo, now)
+		if _, err := repo.db.Exec(`update transfers set status = ? where transfer_id = ?;`, client.FAILED, failed.TransferID); err != nil {
+			t.Fatal(err)
+		}
+		writeTransfer(t, orgID, repo, now.Add(-60*24*time.Hour)) // previous month
+		writeTransfer(t, base.ID(), repo, now)                   // other organization
+
+		// pending micro-deposit
+		microDepositID := base.ID()
+		if _, err := repo.db.Exec(`insert into micro_deposits (micro_deposit_id, destination_customer_id, destination_account_id, status, created_at) values (?, ?, ?, ?, ?);`,
+			microDepositID, base.ID(), base.ID(), client.PENDING, now); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.db.Exec(`insert into micro_deposit_transfers (micro_deposit_id, transfer_id) values (?, ?);`, microDepositID, failed.TransferID); err != nil {
+			t.Fatal(err)
+		}
+
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		if now.Sub(monthStart) < time.Hour {
+			monthStart = now.Add(-time.Hour) // keep our fixtures inside the window
+		}
+		stats, err := repo.getStatistics(orgID, monthStart, now.Add(-recentReturnsWindow))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Transfers.Total != 3 || stats.Transfers.Processed != 2 || stats.Transfers.Failed != 1 {
+			t.Errorf("unexpected transfer statistics: %#v", stats.Transfers)
+		}
+		if stats.Transfers.Volume != 2*1245 {
+			t.Errorf("unexpected volume: %d", stats.Transfers.Volume)
+		}
+		if stats.PendingVerifications != 1 {
+			t.Errorf("unexpected pending verifications: %d", stats.PendingVerifications)
+		}
+		if stats.Returns.Total != 3 || len(stats.Returns.Codes) != 1 || stats.Returns.Codes[0].Code != "R01" {
+			t.Errorf("unexpected returns: %#v", stats.Returns)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
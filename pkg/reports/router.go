@@ -0,0 +1,163 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const (
+	// flushEvery is how many rows are written before the response is flushed
+	// to the client as a chunk.
+	flushEvery = 500
+)
+
+type Router struct {
+	GetTransfersReport http.HandlerFunc
+	GetStatistics      http.HandlerFunc
+}
+
+func NewRouter(cfg *config.Config, repo Repository) *Router {
+	return &Router{
+		GetTransfersReport: GetTransfersReport(cfg, repo),
+		GetStatistics:      GetStatistics(cfg, repo),
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("GET").Path("/reports/transfers").HandlerFunc(c.GetTransfersReport)
+	r.Methods("GET").Path("/statistics").HandlerFunc(c.GetStatistics)
+}
+
+type reportParams struct {
+	// StartDate and EndDate bound when Transfers were created. EndDate is exclusive.
+	StartDate time.Time
+	EndDate   time.Time
+
+	// SettlementStart and SettlementEnd optionally bound, as inclusive YYYY-MM-DD dates,
+	// when Transfers settle. Transfers which aren't uploaded yet use their expected settlement date.
+	SettlementStart string
+	SettlementEnd   string
+
+	Format string
+}
+
+func readReportParams(r *http.Request) (reportParams, error) {
+	params := reportParams{
+		StartDate: time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Now().Add(24 * time.Hour),
+	}
+	if r == nil || r.URL == nil {
+		return params, nil
+	}
+	q := r.URL.Query()
+	if v := q.Get("start"); v != "" {
+		params.StartDate = util.FirstParsedTime(v, base.ISO8601Format, util.YYMMDDTimeFormat)
+		if params.StartDate.IsZero() {
+			return params, fmt.Errorf("invalid start %q", v)
+		}
+	}
+	if v := q.Get("end"); v != "" {
+		// end includes the whole day or second given, so query up to the next one
+		if when, err := time.Parse(util.YYMMDDTimeFormat, v); err == nil {
+			params.EndDate = when.Add(24 * time.Hour)
+		} else if when, err := time.Parse(base.ISO8601Format, v); err == nil {
+			params.EndDate = when.Add(time.Second)
+		} else {
+			return params, fmt.Errorf("invalid end %q", v)
+		}
+	}
+	if !params.EndDate.After(params.StartDate) {
+		return params, errors.New("end is before start")
+	}
+	if v := q.Get("settlementStart"); v != "" {
+		if _, err := time.Parse(util.YYMMDDTimeFormat, v); err != nil {
+			return params, fmt.Errorf("invalid settlementStart %q", v)
+		}
+		params.SettlementStart = v
+	}
+	if v := q.Get("settlementEnd"); v != "" {
+		if _, err := time.Parse(util.YYMMDDTimeFormat, v); err != nil {
+			return params, fmt.Errorf("invalid settlementEnd %q", v)
+		}
+		params.SettlementEnd = v
+	}
+	if params.SettlementStart != "" && params.SettlementEnd != "" && params.SettlementEnd < params.SettlementStart {
+		return params, errors.New("settlementEnd is before settlementStart")
+	}
+	switch params.Format = strings.ToLower(q.Get("format")); params.Format {
+	case "", "csv", "json":
+	default:
+		return params, fmt.Errorf("unknown report format %q", params.Format)
+	}
+	return params, nil
+}
+
+func GetTransfersReport(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		params, err := readReportParams(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			writer, err := newTransferWriter(params.Format, w)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			w.Header().Set("Content-Type", writer.ContentType())
+			w.WriteHeader(http.StatusOK)
+
+			rows := 0
+			err = repo.iterateTransfers(responder.OrganizationID, params, func(xfer *client.Transfer) error {
+				if err := writer.Write(xfer); err != nil {
+					return err
+				}
+				if rows++; rows%flushEvery == 0 {
+					if err := writer.Flush(); err != nil {
+						return err
+					}
+					flush(w)
+				}
+				return nil
+			})
+			if err != nil {
+				// Headers are already sent, so all we can do is log and cut the report short.
+				responder.Logger().LogErrorf("ERROR writing transfers report after %d rows: %v", rows, err)
+				return
+			}
+			if err := writer.Close(); err != nil {
+				responder.Logger().LogErrorf("ERROR closing transfers report: %v", err)
+			}
+		})
+	}
+}
+
+// flush sends any buffered response data to the client as a chunk.
+func flush(w http.ResponseWriter) {
+	if ww, ok := w.(*moovhttp.ResponseWriter); ok {
+		w = ww.ResponseWriter
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
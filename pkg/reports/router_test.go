@@ -0,0 +1,131 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/gorilla/mux"
+)
+
+func TestReports__readReportParams(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8082/reports/transfers?start=2020-04-06&end=2020-04-08&format=JSON")
+	params, err := readReportParams(&http.Request{URL: u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.StartDate.Format("2006-01-02") != "2020-04-06" {
+		t.Errorf("unexpected StartDate: %v", params.StartDate)
+	}
+	// a date-only end includes that whole day
+	if params.EndDate.Format("2006-01-02") != "2020-04-09" {
+		t.Errorf("unexpected EndDate: %v", params.EndDate)
+	}
+	if params.Format != "json" {
+		t.Errorf("unexpected Format: %q", params.Format)
+	}
+
+	u, _ = url.Parse("http://localhost:8082/reports/transfers?end=2020-04-08T15:04:05Z&settlementStart=2020-04-07&settlementEnd=2020-04-10")
+	params, err = readReportParams(&http.Request{URL: u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.EndDate.Equal(time.Date(2020, time.April, 8, 15, 4, 6, 0, time.UTC)) {
+		t.Errorf("unexpected EndDate: %v", params.EndDate)
+	}
+	if params.SettlementStart != "2020-04-07" || params.SettlementEnd != "2020-04-10" {
+		t.Errorf("unexpected settlement dates: %q to %q", params.SettlementStart, params.SettlementEnd)
+	}
+
+	u, _ = url.Parse("http://localhost:8082/reports/transfers?start=2020-04-08&end=2020-04-06")
+	if _, err := readReportParams(&http.Request{URL: u}); err == nil {
+		t.Error("expected error")
+	}
+	u, _ = url.Parse("http://localhost:8082/reports/transfers?format=xml")
+	if _, err := readReportParams(&http.Request{URL: u}); err == nil {
+		t.Error("expected error")
+	}
+	u, _ = url.Parse("http://localhost:8082/reports/transfers?settlementStart=2020-04-08&settlementEnd=2020-04-06")
+	if _, err := readReportParams(&http.Request{URL: u}); err == nil {
+		t.Error("expected error")
+	}
+	u, _ = url.Parse("http://localhost:8082/reports/transfers?settlementEnd=04/06/2020")
+	if _, err := readReportParams(&http.Request{URL: u}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func getReport(t *testing.T, repo Repository, format string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/reports/transfers?format="+format, nil)
+	req.Header.Set("X-Organization", "organization")
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus HTTP status: %d: %s", w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestRouter__GetTransfersReportCSV(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	for i := 0; i < flushEvery+5; i++ {
+		writeTransfer(t, "organization", repo, time.Now())
+	}
+
+	w := getReport(t, repo, "csv")
+	if v := w.Header().Get("Content-Type"); v != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected Content-Type: %q", v)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(records); n != flushEvery+6 {
+		t.Fatalf("got %d rows", n)
+	}
+	if records[0][0] != "transferID" || records[1][10] != "R01" {
+		t.Errorf("unexpected rows: %v %v", records[0], records[1])
+	}
+}
+
+func TestRouter__GetTransfersReportJSON(t *testing.T) {
+	repo := setupSQLiteDB(t)
+
+	w := getReport(t, repo, "json")
+	var xfers []client.Transfer
+	if err := json.NewDecoder(w.Body).Decode(&xfers); err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != 0 {
+		t.Errorf("unexpected transfers: %#v", xfers)
+	}
+
+	writeTransfer(t, "organization", repo, time.Now())
+	writeTransfer(t, "organization", repo, time.Now())
+
+	w = getReport(t, repo, "json")
+	if err := json.NewDecoder(w.Body).Decode(&xfers); err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != 2 {
+		t.Errorf("got %d transfers", len(xfers))
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+const (
+	statisticsMonthFormat = "2006-01"
+
+	// recentReturnsWindow is how far back returned Transfers are counted.
+	recentReturnsWindow = 30 * 24 * time.Hour
+)
+
+func GetStatistics(cfg *config.Config, repo Repository) http.HandlerFunc {
+	location := cfg.ODFI.Cutoffs.Location()
+	if location == nil {
+		location = time.UTC
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		now := time.Now().In(location)
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+
+		stats, err := repo.getStatistics(responder.OrganizationID, monthStart, now.Add(-recentReturnsWindow))
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(stats)
+		})
+	}
+}
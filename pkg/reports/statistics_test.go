@@ -0,0 +1,47 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reports
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestRouter__GetStatistics(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	writeTransfer(t, "organization", repo, time.Now())
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/statistics", nil)
+	req.Header.Set("X-Organization", "organization")
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus HTTP status: %d: %s", w.Code, w.Body.String())
+	}
+
+	var stats client.Statistics
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Month != time.Now().UTC().Format(statisticsMonthFormat) {
+		t.Errorf("unexpected month: %q", stats.Month)
+	}
+	if stats.Returns.Codes == nil {
+		t.Error("expected empty codes array")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+// RegisterRoutes will add HTTP handlers for paygate's admin HTTP server
+func (s *Seeder) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/seed", s.seed())
+}
+
+func (s *Seeder) seed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		result, err := s.Seed()
+		if err != nil {
+			s.logger.LogErrorf("problem seeding: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+	}
+}
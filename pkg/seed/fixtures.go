@@ -0,0 +1,48 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type organizationFixture struct {
+	Name                  string
+	CompanyIdentification string
+	Transfers             []transferFixture
+}
+
+type transferFixture struct {
+	Amount      client.Amount
+	Description string
+	Status      client.TransferStatus
+	SameDay     bool
+	ReturnCode  string
+}
+
+// fixtures is the sample dataset written by Seed. Organization names are prefixed
+// onto a random ID so seeding can be repeated against the same database.
+var fixtures = []organizationFixture{
+	{
+		Name:                  "acme",
+		CompanyIdentification: "1234567890",
+		Transfers: []transferFixture{
+			{Amount: client.Amount{Currency: "USD", Value: 125000}, Description: "payroll", Status: client.PENDING},
+			{Amount: client.Amount{Currency: "USD", Value: 125000}, Description: "payroll", Status: client.PROCESSED},
+			{Amount: client.Amount{Currency: "USD", Value: 4599}, Description: "invoice 1042", Status: client.PROCESSED, SameDay: true},
+			{Amount: client.Amount{Currency: "USD", Value: 2500}, Description: "refund", Status: client.CANCELED},
+			{Amount: client.Amount{Currency: "USD", Value: 18000}, Description: "rent", Status: client.FAILED, ReturnCode: "R01"},
+		},
+	},
+	{
+		Name:                  "globex",
+		CompanyIdentification: "9876543210",
+		Transfers: []transferFixture{
+			{Amount: client.Amount{Currency: "USD", Value: 990000}, Description: "vendor payment", Status: client.REVIEWABLE},
+			{Amount: client.Amount{Currency: "USD", Value: 1500}, Description: "subscription", Status: client.PENDING},
+			{Amount: client.Amount{Currency: "USD", Value: 1500}, Description: "subscription", Status: client.FAILED, ReturnCode: "R03"},
+		},
+	},
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+// Result lists the objects created by Seed.
+type Result struct {
+	Organizations []Organization `json:"organizations"`
+}
+
+type Organization struct {
+	OrganizationID string   `json:"organizationID"`
+	TransferIDs    []string `json:"transferIDs"`
+}
+
+// Seeder writes a sample dataset of organizations and their Transfers.
+//
+// Customers and their accounts are owned by the Customers service, so Transfers
+// reference random customer and account IDs.
+type Seeder struct {
+	logger   log.Logger
+	orgRepo  organization.Repository
+	xferRepo transfers.Repository
+}
+
+func New(logger log.Logger, orgRepo organization.Repository, xferRepo transfers.Repository) *Seeder {
+	return &Seeder{
+		logger:   logger.Set("service", log.String("seed")),
+		orgRepo:  orgRepo,
+		xferRepo: xferRepo,
+	}
+}
+
+func (s *Seeder) Seed() (*Result, error) {
+	result := &Result{}
+	for i := range fixtures {
+		org, err := s.seedOrganization(fixtures[i])
+		if err != nil {
+			return result, fmt.Errorf("seed %s: %v", fixtures[i].Name, err)
+		}
+		result.Organizations = append(result.Organizations, *org)
+	}
+	return result, nil
+}
+
+func (s *Seeder) seedOrganization(fixture organizationFixture) (*Organization, error) {
+	org := &Organization{
+		OrganizationID: fmt.Sprintf("%s-%s", fixture.Name, strings.ToLower(base.ID()[:8])),
+	}
+	_, err := s.orgRepo.UpdateConfig(org.OrganizationID, &client.OrganizationConfiguration{
+		CompanyIdentification: fixture.CompanyIdentification,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range fixture.Transfers {
+		transferID, err := s.seedTransfer(org.OrganizationID, fixture.Transfers[i])
+		if err != nil {
+			return nil, err
+		}
+		org.TransferIDs = append(org.TransferIDs, transferID)
+	}
+	s.logger.Logf("seeded organization=%s with %d transfers", org.OrganizationID, len(org.TransferIDs))
+	return org, nil
+}
+
+func (s *Seeder) seedTransfer(orgID string, fixture transferFixture) (string, error) {
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     fixture.Amount,
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description: fixture.Description,
+		Status:      client.PENDING,
+		SameDay:     fixture.SameDay,
+	}
+	if err := s.xferRepo.WriteUserTransfer(orgID, xfer); err != nil {
+		return "", fmt.Errorf("writing transfer: %v", err)
+	}
+	if fixture.Status != client.PENDING {
+		if err := s.xferRepo.UpdateTransferStatus(xfer.TransferID, fixture.Status); err != nil {
+			return "", fmt.Errorf("updating transferID=%s status: %v", xfer.TransferID, err)
+		}
+	}
+	if fixture.ReturnCode != "" {
+		if err := s.xferRepo.SaveReturnCode(xfer.TransferID, fixture.ReturnCode); err != nil {
+			return "", fmt.Errorf("saving transferID=%s return code: %v", xfer.TransferID, err)
+		}
+	}
+	return xfer.TransferID, nil
+}
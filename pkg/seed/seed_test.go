@@ -0,0 +1,96 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+func TestSeeder(t *testing.T) {
+	orgRepo, xferRepo := organization.NewInMemoryRepo(), transfers.NewInMemoryRepo()
+	seeder := New(log.NewNopLogger(), orgRepo, xferRepo)
+
+	result, err := seeder.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Organizations) != len(fixtures) {
+		t.Fatalf("unexpected organizations: %#v", result.Organizations)
+	}
+
+	for i, org := range result.Organizations {
+		cfg, err := orgRepo.GetConfig(org.OrganizationID)
+		if err != nil || cfg == nil {
+			t.Fatalf("organization=%s config=%#v error=%v", org.OrganizationID, cfg, err)
+		}
+		if cfg.CompanyIdentification != fixtures[i].CompanyIdentification {
+			t.Errorf("unexpected CompanyIdentification=%q", cfg.CompanyIdentification)
+		}
+		for j, transferID := range org.TransferIDs {
+			xfer, err := xferRepo.GetTransfer(context.Background(), transferID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixture := fixtures[i].Transfers[j]
+			if xfer.Status != fixture.Status {
+				t.Errorf("transferID=%s status=%s expected %s", transferID, xfer.Status, fixture.Status)
+			}
+			if fixture.ReturnCode != "" && (xfer.ReturnCode == nil || xfer.ReturnCode.Code != fixture.ReturnCode) {
+				t.Errorf("transferID=%s unexpected return code: %#v", transferID, xfer.ReturnCode)
+			}
+		}
+	}
+
+	// seeding again creates new organizations
+	again, err := seeder.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Organizations[0].OrganizationID == result.Organizations[0].OrganizationID {
+		t.Error("expected a new organization")
+	}
+}
+
+func TestSeeder__route(t *testing.T) {
+	seeder := New(log.NewNopLogger(), organization.NewInMemoryRepo(), transfers.NewInMemoryRepo())
+
+	svc, _ := testclient.Admin(t)
+	seeder.RegisterRoutes(svc)
+
+	resp, err := http.DefaultClient.Get("http://" + svc.BindAddr() + "/seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	resp, err = http.DefaultClient.Post("http://"+svc.BindAddr()+"/seed", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	var result Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Organizations) == 0 || len(result.Organizations[0].TransferIDs) == 0 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tracenumbers
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// Report is the result of checking trace numbers in files uploaded on a day.
+type Report struct {
+	Date       string   `json:"date"`
+	Filenames  []string `json:"filenames"`
+	EntryCount int      `json:"entryCount"`
+
+	// Duplicates are trace numbers found on more than one entry
+	Duplicates []string `json:"duplicates"`
+
+	// Gaps are ranges of trace numbers which were skipped between allocated trace numbers,
+	// typically from transfers which were canceled or failed before being merged.
+	Gaps []Gap `json:"gaps"`
+}
+
+// Gap is a range of missing trace numbers between two found trace numbers.
+type Gap struct {
+	After   string `json:"after"`
+	Before  string `json:"before"`
+	Missing int64  `json:"missing"`
+}
+
+// Check finds duplicate and skipped trace numbers across the forward entries of files.
+// Return entries aren't checked as their trace numbers aren't allocated from a sequence.
+func Check(files []*ach.File) *Report {
+	report := &Report{
+		Duplicates: []string{},
+		Gaps:       []Gap{},
+	}
+
+	seen := make(map[string]int)
+	for i := range files {
+		for j := range files[i].Batches {
+			entries := files[i].Batches[j].GetEntries()
+			for k := range entries {
+				if entries[k].Addenda99 != nil {
+					continue
+				}
+				report.EntryCount++
+				seen[entries[k].TraceNumber]++
+			}
+		}
+	}
+
+	// group each sequence by the ODFI's routing number
+	sequences := make(map[string][]int64)
+	for trace, count := range seen {
+		if count > 1 {
+			report.Duplicates = append(report.Duplicates, trace)
+		}
+		if len(trace) != 15 {
+			continue
+		}
+		seq, err := strconv.ParseInt(trace[8:], 10, 64)
+		if err != nil {
+			continue
+		}
+		sequences[trace[:8]] = append(sequences[trace[:8]], seq)
+	}
+	sort.Strings(report.Duplicates)
+
+	var prefixes []string
+	for prefix := range sequences {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		seqs := sequences[prefix]
+		sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+		for i := 1; i < len(seqs); i++ {
+			if missing := seqs[i] - seqs[i-1] - 1; missing > 0 {
+				report.Gaps = append(report.Gaps, Gap{
+					After:   fmt.Sprintf("%s%07d", prefix, seqs[i-1]),
+					Before:  fmt.Sprintf("%s%07d", prefix, seqs[i]),
+					Missing: missing,
+				})
+			}
+		}
+	}
+	return report
+}
+
+// Checker reads merged files which were uploaded on a day and checks their trace numbers.
+type Checker struct {
+	cfg    *config.Config
+	logger log.Logger
+
+	// storageDir holds a directory for each cutoff named by when it was merged,
+	// see pipeline.XferMerging
+	storageDir string
+}
+
+func NewChecker(cfg *config.Config) *Checker {
+	dir := "storage"
+	if cfg.Pipeline.Merging != nil && cfg.Pipeline.Merging.Directory != "" {
+		dir = cfg.Pipeline.Merging.Directory
+	}
+	return &Checker{
+		cfg:        cfg,
+		logger:     cfg.Logger,
+		storageDir: dir,
+	}
+}
+
+// CheckDate reads every file uploaded on the day of when and checks their trace numbers.
+func (c *Checker) CheckDate(when time.Time) (*Report, error) {
+	pattern := filepath.Join(c.storageDir, when.Format("20060102")+"-*", "uploaded", "*.ach")
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("problem with %s glob: %v", pattern, err)
+	}
+
+	var files []*ach.File
+	var filenames []string
+	for i := range matches {
+		file, err := ach.ReadFile(matches[i])
+		if err != nil {
+			return nil, fmt.Errorf("problem reading %s: %v", matches[i], err)
+		}
+		files = append(files, file)
+		filenames = append(filenames, filepath.Base(matches[i]))
+	}
+
+	report := Check(files)
+	report.Date = when.Format(checkDateFormat)
+	report.Filenames = filenames
+	if report.Filenames == nil {
+		report.Filenames = []string{}
+	}
+	if len(report.Duplicates) > 0 {
+		c.logger.LogErrorf("found %d duplicate trace numbers in files uploaded on %s", len(report.Duplicates), report.Date)
+	}
+	return report, nil
+}
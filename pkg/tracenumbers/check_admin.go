@@ -0,0 +1,49 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tracenumbers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+const (
+	checkDateFormat = "2006-01-02"
+)
+
+func (c *Checker) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/reports/trace-numbers/{date}", c.checkTraceNumbers())
+}
+
+func (c *Checker) checkTraceNumbers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodGet {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		date, err := time.Parse(checkDateFormat, route.ReadPathID("date", r))
+		if err != nil {
+			route.Problem(w, route.InvalidRequest.New("invalid date: %v", err))
+			return
+		}
+
+		report, err := c.CheckDate(date)
+		if err != nil {
+			c.logger.LogErrorf("ERROR checking trace numbers: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tracenumbers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+)
+
+func readTestFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "two-micro-deposits.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestCheck(t *testing.T) {
+	file := readTestFile(t)
+
+	report := Check([]*ach.File{file})
+	if report.EntryCount != 6 || len(report.Duplicates) != 0 {
+		t.Errorf("unexpected report: %#v", report)
+	}
+	if len(report.Gaps) != 1 {
+		t.Fatalf("unexpected gaps: %#v", report.Gaps)
+	}
+	gap := report.Gaps[0]
+	if gap.After != "121042886829040" || gap.Before != "121042889211556" || gap.Missing != 2382515 {
+		t.Errorf("unexpected gap: %#v", gap)
+	}
+
+	// the same file uploaded twice duplicates every trace number
+	report = Check([]*ach.File{file, file})
+	if len(report.Duplicates) != 6 || len(report.Gaps) != 1 {
+		t.Errorf("unexpected report: %#v", report)
+	}
+}
+
+func TestChecker__routes(t *testing.T) {
+	dir := internal.TestDir(t)
+	uploaded := filepath.Join(dir, "20200529-153000", "uploaded")
+	if err := os.MkdirAll(uploaded, 0777); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(uploaded, "merged.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ach.NewWriter(f).Write(readTestFile(t)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	cfg := config.Empty()
+	cfg.Pipeline.Merging = &config.Merging{
+		Directory: dir,
+	}
+	svc, c := testclient.Admin(t)
+	NewChecker(cfg).RegisterRoutes(svc)
+
+	report, resp, err := c.ReportsApi.GetTraceNumberReport(context.TODO(), "2020-05-29")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if report.EntryCount != 6 || len(report.Filenames) != 1 || len(report.Gaps) != 1 {
+		t.Errorf("unexpected report: %#v", report)
+	}
+
+	// other days have no files
+	report, resp, err = c.ReportsApi.GetTraceNumberReport(context.TODO(), "2020-05-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if report.EntryCount != 0 || len(report.Filenames) != 0 {
+		t.Errorf("unexpected report: %#v", report)
+	}
+
+	_, resp, err = c.ReportsApi.GetTraceNumberReport(context.TODO(), "invalid")
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tracenumbers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// sequenceModulus wraps sequences into the seven digits of a trace number after the
+// eight digit routing number.
+const sequenceModulus = 10000000
+
+// Repository allocates trace numbers from a sequence per ODFI routing number which is
+// shared by every PayGate instance using the database and never resets between days.
+type Repository interface {
+	achx.TraceNumbers
+
+	Close() error
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) Next(routingNumber string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if n >= sequenceModulus {
+		return nil, fmt.Errorf("trace numbers: %d exceeds the sequence", n)
+	}
+	prefix := achx.ABA8(routingNumber)
+	if prefix == "" {
+		return nil, fmt.Errorf("invalid routing number %q", routingNumber)
+	}
+
+	last, err := r.reserve(prefix, n)
+	if err != nil && database.UniqueViolation(err) {
+		// another instance created the sequence first, so reserve from it
+		last, err = r.reserve(prefix, n)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trace numbers: %v", err)
+	}
+
+	out := make([]string, n)
+	for i := range out {
+		seq := last - int64(n-1-i)
+		out[i] = fmt.Sprintf("%s%07d", prefix, seq)
+	}
+	return out, nil
+}
+
+// reserve increments the sequence of prefix by n and returns its new value. The update
+// locks the sequence until the transaction commits so no other caller reserves the same values.
+//
+// A reservation which doesn't fit before sequenceModulus restarts the sequence at zero, so
+// every reserved range is contiguous and never straddles the wrap.
+func (r *sqlRepo) reserve(prefix string, n int) (int64, error) {
+	defer database.MeasureQuery("tracenumbers", "reserve")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+
+	query := `update trace_sequences set last_sequence = case when last_sequence % ? + ? >= ? then ? else last_sequence % ? + ? end, updated_at = ? where routing_number = ?;`
+	res, err := tx.Exec(query, sequenceModulus, n, sequenceModulus, n-1, sequenceModulus, n, now, prefix)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		query = `insert into trace_sequences (routing_number, last_sequence, updated_at) values (?, ?, ?);`
+		if _, err := tx.Exec(query, prefix, n, now); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	var sequence int64
+	query = `select last_sequence from trace_sequences where routing_number = ? limit 1;`
+	if err := tx.QueryRow(query, prefix).Scan(&sequence); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return 0, errors.New("missing sequence")
+		}
+		return 0, err
+	}
+	return sequence, tx.Commit()
+}
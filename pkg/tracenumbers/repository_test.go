@@ -0,0 +1,146 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tracenumbers
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlRepo{db: db.DB}
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlRepo{db: db.DB}
+}
+
+func TestRepository__Next(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		traces, err := repo.Next("121042882", 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 3 || traces[0] != "121042880000001" || traces[2] != "121042880000003" {
+			t.Errorf("unexpected trace numbers: %v", traces)
+		}
+
+		// each routing number has its own sequence
+		traces, err = repo.Next("231380104", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 1 || traces[0] != "231380100000001" {
+			t.Errorf("unexpected trace numbers: %v", traces)
+		}
+
+		traces, err = repo.Next("121042882", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 1 || traces[0] != "121042880000004" {
+			t.Errorf("unexpected trace numbers: %v", traces)
+		}
+
+		if _, err := repo.Next("invalid", 1); err == nil {
+			t.Error("expected error")
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__NextWrap(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		if _, err := repo.Next("121042882", 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.db.Exec(`update trace_sequences set last_sequence = ?;`, sequenceModulus-3); err != nil {
+			t.Fatal(err)
+		}
+
+		// the last values before the wrap are still used when the range fits
+		traces, err := repo.Next("121042882", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 2 || traces[0] != "121042889999998" || traces[1] != "121042889999999" {
+			t.Errorf("unexpected trace numbers: %v", traces)
+		}
+
+		// a range which doesn't fit restarts at zero rather than straddling the wrap
+		if _, err := repo.db.Exec(`update trace_sequences set last_sequence = ?;`, sequenceModulus-2); err != nil {
+			t.Fatal(err)
+		}
+		traces, err = repo.Next("121042882", 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 3 || traces[0] != "121042880000000" || traces[2] != "121042880000002" {
+			t.Errorf("unexpected trace numbers: %v", traces)
+		}
+		traces, err = repo.Next("121042882", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(traces) != 1 || traces[0] != "121042880000003" {
+			t.Errorf("unexpected trace numbers: %v", traces)
+		}
+
+		if _, err := repo.Next("121042882", sequenceModulus); err == nil {
+			t.Error("expected error")
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__NextConcurrent(t *testing.T) {
+	// concurrent callers act like separate PayGate instances sharing a database
+	repo := setupMySQLeDB(t)
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			traces, err := repo.Next("121042882", 5)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, trace := range traces {
+				if seen[trace] {
+					t.Errorf("duplicate trace number: %s", trace)
+				}
+				seen[trace] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 50 {
+		t.Errorf("allocated %d trace numbers", len(seen))
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// accountHistory returns the details of acct which are kept for compliance reviews and disputes.
+func accountHistory(transferID string, cust moovcustomers.Customer, acct moovcustomers.Account, now time.Time) client.AccountHistory {
+	return client.AccountHistory{
+		AccountID:           acct.AccountID,
+		CustomerID:          cust.CustomerID,
+		HolderName:          strings.TrimSpace(fmt.Sprintf("%s %s", cust.FirstName, cust.LastName)),
+		RoutingNumber:       acct.RoutingNumber,
+		MaskedAccountNumber: acct.MaskedAccountNumber,
+		Type:                string(acct.Type),
+		TransferID:          transferID,
+		EffectiveFrom:       now,
+	}
+}
+
+// sameAccountDetails returns true when a and b only differ by when they were read.
+func sameAccountDetails(a, b client.AccountHistory) bool {
+	return a.CustomerID == b.CustomerID &&
+		a.HolderName == b.HolderName &&
+		a.RoutingNumber == b.RoutingNumber &&
+		a.MaskedAccountNumber == b.MaskedAccountNumber &&
+		a.Type == b.Type
+}
+
+// transferAccountHistory returns the details of both accounts of a Transfer. They're saved
+// with the Transfer when they changed since the accounts were last used.
+func transferAccountHistory(transfer *client.Transfer, source fundflow.Source, destination fundflow.Destination) []client.AccountHistory {
+	now := time.Now()
+	return []client.AccountHistory{
+		accountHistory(transfer.TransferID, source.Customer, source.Account, now),
+		accountHistory(transfer.TransferID, destination.Customer, destination.Account, now),
+	}
+}
+
+func GetAccountHistory(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		accountID := route.ReadPathID("accountID", r)
+		history, err := repo.getAccountHistory(r.Context(), responder.OrganizationID, accountID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if len(history) == 0 {
+			responder.Problem(route.NotFound.New("no history for accountID=%s", accountID))
+			return
+		}
+		for i := 0; i < len(history)-1; i++ {
+			until := history[i+1].EffectiveFrom
+			history[i].EffectiveUntil = &until
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(history)
+		})
+	}
+}
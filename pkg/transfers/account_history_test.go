@@ -0,0 +1,139 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRepository__AccountHistory(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID, accountID := base.ID(), base.ID()
+		now := time.Now().Truncate(time.Second)
+
+		if history, err := repo.getAccountHistory(context.Background(), orgID, accountID); err != nil || len(history) != 0 {
+			t.Fatalf("history=%#v error=%v", history, err)
+		}
+
+		details := client.AccountHistory{
+			AccountID:           accountID,
+			CustomerID:          base.ID(),
+			HolderName:          "Jane Doe",
+			RoutingNumber:       "987654320",
+			MaskedAccountNumber: "****34",
+			Type:                "checking",
+			TransferID:          base.ID(),
+			EffectiveFrom:       now.Add(-1 * time.Hour),
+		}
+		if err := repo.saveAccountHistory(orgID, details); err != nil {
+			t.Fatal(err)
+		}
+
+		// unchanged details aren't saved again
+		details.TransferID, details.EffectiveFrom = base.ID(), now.Add(-1*time.Minute)
+		if err := repo.saveAccountHistory(orgID, details); err != nil {
+			t.Fatal(err)
+		}
+
+		details.HolderName, details.EffectiveFrom = "Jane Smith", now
+		if err := repo.saveAccountHistory(orgID, details); err != nil {
+			t.Fatal(err)
+		}
+
+		history, err := repo.getAccountHistory(context.Background(), orgID, accountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("unexpected history: %#v", history)
+		}
+		if history[0].HolderName != "Jane Doe" || history[1].HolderName != "Jane Smith" {
+			t.Errorf("unexpected history: %#v", history)
+		}
+		if history[1].TransferID != details.TransferID {
+			t.Errorf("unexpected transferID: %s", history[1].TransferID)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRouter__GetAccountHistory(t *testing.T) {
+	repo := NewInMemoryRepo()
+	customersClient := mockCustomersClient()
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, customersClient, mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	_, resp, err := c.TransfersApi.GetAccountHistory(context.TODO(), destinationAccountID, "organization", nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected not found: %v", err)
+	}
+	resp.Body.Close()
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// the holder changes their name before the next transfer
+	customersClient.Customers[1].LastName = "Smith"
+	if _, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	history, resp, err := c.TransfersApi.GetAccountHistory(context.TODO(), destinationAccountID, "organization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(history) != 2 {
+		t.Fatalf("unexpected history: %#v", history)
+	}
+	if history[0].HolderName != "Jane Doe" || history[0].TransferID != xfer.TransferID || history[0].EffectiveUntil == nil {
+		t.Errorf("unexpected history: %#v", history[0])
+	}
+	if history[1].HolderName != "Jane Smith" || history[1].EffectiveUntil != nil {
+		t.Errorf("unexpected history: %#v", history[1])
+	}
+	if history[1].RoutingNumber != "987654320" || history[1].MaskedAccountNumber != "****34" {
+		t.Errorf("unexpected history: %#v", history[1])
+	}
+}
@@ -5,8 +5,6 @@
 package admin
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 
@@ -29,29 +27,41 @@ func updateTransferStatus(cfg *config.Config, repo transfers.Repository) http.Ha
 		var request struct {
 			Status client.TransferStatus `json:"status"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		if err := route.DecodeJSON(r, &request, route.DisallowUnknownFields); err != nil {
 			responder.Problem(err)
 			return
 		}
 
 		transferID := getTransferID(r)
-		existing, err := repo.GetTransfer(transferID)
+		existing, err := repo.GetTransfer(r.Context(), transferID)
 		if err != nil {
-			responder.Problem(fmt.Errorf("initial read: %v", err))
+			responder.Problem(route.Internal.New("initial read: %v", err))
 			return
 		}
 		if existing == nil {
-			responder.Problem(errors.New("transfer not found"))
+			responder.Problem(route.NotFound.New("transfer not found"))
 			return
 		}
 		if err := validStatusTransistion(existing.TransferID, existing.Status, request.Status); err != nil {
 			responder.Problem(err)
 			return
 		}
+		if existing.Status == client.REVIEWABLE && request.Status == client.PENDING {
+			// Transfers held by transfers.approvals are only released by an approver
+			approval, err := repo.GetTransferApproval(transferID)
+			if err != nil {
+				responder.Problem(route.Internal.Wrap(err))
+				return
+			}
+			if approval != nil && approval.Approved == nil {
+				responder.Problem(route.Conflict.New("transfer=%s needs to be approved", transferID))
+				return
+			}
+		}
 
 		// Perform the DB update since it's an allowed transition
 		if err := repo.UpdateTransferStatus(transferID, request.Status); err != nil {
-			responder.Problem(err)
+			responder.Problem(route.Internal.Wrap(err))
 			return
 		}
 		cfg.Logger.With(log.Fields{
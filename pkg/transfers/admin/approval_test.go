@@ -44,7 +44,7 @@ func TestAdmin__updateTransferStatus(t *testing.T) {
 
 	cfg := config.Empty()
 	svc, c := testclient.Admin(t)
-	RegisterRoutes(cfg, svc, repo)
+	RegisterRoutes(cfg, svc, repo, nil, nil, nil)
 
 	req := admin.UpdateTransferStatus{
 		Status: admin.CANCELED,
@@ -58,6 +58,36 @@ func TestAdmin__updateTransferStatus(t *testing.T) {
 
 }
 
+func TestAdmin__updateTransferStatusNeedsApproval(t *testing.T) {
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID: base.ID(),
+				Status:     client.REVIEWABLE,
+				Created:    time.Now(),
+			},
+		},
+		Approval: &admin.TransferApproval{
+			RequestedBy: "jane",
+			Requested:   time.Now(),
+		},
+	}
+
+	cfg := config.Empty()
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(cfg, svc, repo, nil, nil, nil)
+
+	// held transfers are only released by an approver
+	req := admin.UpdateTransferStatus{
+		Status: admin.PENDING,
+	}
+	resp, _ := c.TransfersApi.UpdateTransferStatus(context.TODO(), "transferID", "organization", req, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+}
+
 func TestAdmin__validStatusTransistion(t *testing.T) {
 	transferID := base.ID()
 
@@ -0,0 +1,142 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// createDishonoredReturn sends the latest return received for a Transfer back to the RDFI
+// of the original entry. Once uploaded the Transfer is marked as PROCESSED like any other
+// file, and a contested dishonored return from the RDFI will fail it again.
+func createDishonoredReturn(cfg *config.Config, repo transfers.Repository, pub pipeline.XferPublisher, traceNumbers achx.TraceNumbers) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		var request admin.CreateDishonoredReturn
+		if err := route.DecodeJSON(r, &request, route.DisallowUnknownFields); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		transferID := getTransferID(r)
+		xfer, err := repo.GetTransfer(r.Context(), transferID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(route.Internal.New("initial read: %v", err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("transfer not found"))
+			return
+		}
+		returned, err := repo.GetReturnEntry(transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if returned == nil {
+			responder.Problem(route.NotFound.New("no return received for transfer"))
+			return
+		}
+		if returned.DishonoredReturnCode != "" {
+			responder.Problem(route.InvalidRequest.New("return already dishonored with %s", returned.DishonoredReturnCode))
+			return
+		}
+
+		dishonor := achx.Dishonor{
+			ReturnCode:         request.ReturnCode,
+			Header:             returned.Header,
+			Entry:              returned.Entry,
+			SettlementDate:     request.SettlementDate,
+			AddendaInformation: request.AddendaInformation,
+		}
+		if err := validateDishonor(dishonor); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		file, err := achx.DishonoredReturn(base.ID(), dishonorOptions(cfg.ODFI, traceNumbers), dishonor)
+		if err != nil {
+			responder.Problem(route.Internal.New("creating dishonored return: %v", err))
+			return
+		}
+		files := []*ach.File{file}
+		if err := transfers.SaveTraceNumbers(repo, xfer, files); err != nil {
+			responder.Problem(route.Internal.New("saving trace numbers: %v", err))
+			return
+		}
+		if err := pipeline.PublishFiles(pub, xfer, files); err != nil {
+			responder.Problem(route.Internal.New("publishing dishonored return: %v", err))
+			return
+		}
+		if err := repo.SaveDishonoredReturnCode(transferID, request.ReturnCode); err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+
+		ed := file.Batches[0].GetEntries()[0]
+		responder.Logger().With(log.Fields{
+			"transferID":  log.String(transferID),
+			"returnCode":  log.String(ed.Addenda99.ReturnCode),
+			"traceNumber": log.String(ed.TraceNumber),
+		}).Log("dishonored transfer return")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(admin.DishonoredReturn{
+				TransferID:          transferID,
+				ReturnCode:          ed.Addenda99.ReturnCode,
+				TraceNumber:         ed.TraceNumber,
+				OriginalTraceNumber: ed.Addenda99.OriginalTrace,
+				ReturnTraceNumber:   returned.Entry.TraceNumber,
+				ReturnReasonCode:    returned.Entry.Addenda99.ReturnCode,
+			})
+		})
+	}
+}
+
+func validateDishonor(dishonor achx.Dishonor) error {
+	verr := &route.ValidationError{}
+	if !achx.IsDishonoredReturnCode(dishonor.ReturnCode) {
+		verr.Add("returnCode", "unexpected dishonored return code %q", dishonor.ReturnCode)
+		return verr.Err()
+	}
+	if err := achx.ValidateDishonor(dishonor); err != nil {
+		return route.InvalidRequest.Wrap(err)
+	}
+	return nil
+}
+
+// dishonorOptions returns how dishonored returns are originated, which like transfers
+// settle on the next banking day and take trace numbers from the ODFI's sequence.
+func dishonorOptions(cfg config.ODFI, traceNumbers achx.TraceNumbers) achx.Options {
+	when := base.NewTime(time.Now().In(cfg.Cutoffs.Location()))
+	return achx.Options{
+		ODFIRoutingNumber:  cfg.RoutingNumber,
+		Gateway:            cfg.Gateway,
+		FileConfig:         cfg.FileConfig,
+		CutoffTimezone:     cfg.Cutoffs.Location(),
+		EffectiveEntryDate: when.AddBankingDay(1),
+		TraceNumbers:       traceNumbers,
+	}
+}
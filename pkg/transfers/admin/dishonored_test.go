@@ -0,0 +1,115 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+)
+
+func TestAdmin__createDishonoredReturn(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	returned := file.Batches[0].GetEntries()[0]
+
+	repo := transfers.NewInMemoryRepo()
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     client.Amount{Currency: "USD", Value: int32(returned.Amount)},
+		Status:     client.FAILED,
+	}
+	if err := repo.WriteUserTransfer(base.ID(), xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "091400606"
+	pub := pipeline.NewMockPublisher()
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(cfg, svc, repo, pub, nil, nil)
+
+	req := admin.CreateDishonoredReturn{
+		ReturnCode:     "R68",
+		SettlementDate: "102",
+	}
+
+	// no return received yet
+	_, resp, err := c.TransfersApi.CreateDishonoredReturn(context.TODO(), xfer.TransferID, req, nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	if err := repo.SaveReturnEntry(xfer.TransferID, file.Batches[0].GetHeader(), returned); err != nil {
+		t.Fatal(err)
+	}
+	dishonored, resp, err := c.TransfersApi.CreateDishonoredReturn(context.TODO(), xfer.TransferID, req, nil)
+	if err != nil {
+		t.Fatalf("%v %s", err, err.(admin.GenericOpenAPIError).Body())
+	}
+	resp.Body.Close()
+	if dishonored.ReturnCode != "R68" || dishonored.ReturnReasonCode != "R01" || dishonored.ReturnTraceNumber != returned.TraceNumber {
+		t.Errorf("unexpected dishonored return: %#v", dishonored)
+	}
+	if dishonored.OriginalTraceNumber != returned.Addenda99.OriginalTrace {
+		t.Errorf("OriginalTraceNumber=%q", dishonored.OriginalTraceNumber)
+	}
+
+	published, ok := pub.Xfers[xfer.TransferID]
+	if !ok || published.File == nil {
+		t.Fatalf("dishonored return wasn't published: %#v", pub.Xfers)
+	}
+	if ed := published.File.Batches[0].GetEntries()[0]; ed.TraceNumber != dishonored.TraceNumber {
+		t.Errorf("unexpected entry: %#v", ed)
+	}
+
+	// the return can only be dishonored once
+	if _, _, err := c.TransfersApi.CreateDishonoredReturn(context.TODO(), xfer.TransferID, req, nil); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAdmin__createDishonoredReturnInvalid(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{{TransferID: base.ID()}},
+		Return: &transfers.ReturnEntry{
+			Header: file.Batches[0].GetHeader(),
+			Entry:  file.Batches[0].GetEntries()[0],
+		},
+	}
+
+	cfg := config.Empty()
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(cfg, svc, repo, pipeline.NewMockPublisher(), nil, nil)
+
+	req := admin.CreateDishonoredReturn{
+		ReturnCode: "R01",
+	}
+	_, resp, err := c.TransfersApi.CreateDishonoredReturn(context.TODO(), base.ID(), req, nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected error: %v", err)
+	}
+	if oerr, ok := err.(admin.GenericOpenAPIError); ok {
+		if model, ok := oerr.Model().(admin.Error); ok && model.Field != "returnCode" {
+			t.Errorf("unexpected error: %#v", model)
+		}
+	}
+}
@@ -6,11 +6,20 @@ package admin
 
 import (
 	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 )
 
 // RegisterRoutes will add HTTP handlers for paygate's admin HTTP server
-func RegisterRoutes(cfg *config.Config, svc *admin.Server, repo transfers.Repository) {
+func RegisterRoutes(cfg *config.Config, svc *admin.Server, repo transfers.Repository, pub pipeline.XferPublisher, traceNumbers achx.TraceNumbers, evidence *transfers.EvidenceStorage) {
 	svc.AddHandler("/transfers/{transferId}/status", updateTransferStatus(cfg, repo))
+	svc.AddHandler("/transfers/{transferID}/dishonored-return", createDishonoredReturn(cfg, repo, pub, traceNumbers))
+	svc.AddHandler("/transfers/{transferID}/ach/reveal", transfers.RevealTransferEntries(cfg, repo))
+	svc.AddHandler("/transfers/{transferID}/approve", transfers.ApproveTransfer(cfg, repo))
+	svc.AddHandler("/transfers/{transferID}/evidence", transfers.GetTransferEvidence(cfg, repo))
+	svc.AddHandler("/evidence/{evidenceID}/content", transfers.GetEvidenceContent(cfg, repo, evidence))
+	svc.AddHandler("/files/{filename}/contents", transfers.GetFileContents(cfg, repo))
+	svc.AddHandler("/files/validate", validateFile(cfg))
 }
@@ -0,0 +1,56 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// maxValidateFileSize is the largest ACH file which can be validated, about 100k records.
+const maxValidateFileSize = 10 * 1024 * 1024
+
+func validateFile(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		rules, err := achx.ParseLintRules(r.URL.Query().Get("rules"))
+		if err != nil {
+			responder.Problem(route.InvalidRequest.Wrap(err))
+			return
+		}
+
+		contents, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxValidateFileSize))
+		if err != nil {
+			if strings.Contains(err.Error(), "request body too large") {
+				err = route.ErrBodyTooLarge
+			}
+			responder.Problem(err)
+			return
+		}
+		if len(bytes.TrimSpace(contents)) == 0 {
+			responder.Problem(route.InvalidRequest.New("missing ACH file"))
+			return
+		}
+
+		result := achx.Lint(bytes.NewReader(contents), rules)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(result)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers"
+)
+
+func TestAdmin__validateFile(t *testing.T) {
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(config.Empty(), svc, &transfers.MockRepository{}, nil, nil, nil)
+
+	result, resp, err := c.TransfersApi.ValidateFile(context.TODO(), string(bs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !result.Valid || result.Rules != "strict" || result.EntryCount != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// Debit total out of balance with the batch control
+	contents := strings.Replace(string(bs), "82250000010005320001000000010500", "82250000010005320001000000010000", 1)
+	result, resp, err = c.TransfersApi.ValidateFile(context.TODO(), contents, &admin.ValidateFileOpts{
+		Rules: optional.NewString("relaxed"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if result.Valid || result.Rules != "relaxed" || len(result.Errors) != 1 || result.Errors[0].Kind != "imbalance" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// Unknown rules
+	_, resp, err = c.TransfersApi.ValidateFile(context.TODO(), string(bs), &admin.ValidateFileOpts{
+		Rules: optional.NewString("lenient"),
+	})
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected error: %v", err)
+	}
+	resp.Body.Close()
+}
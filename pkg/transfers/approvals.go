@@ -0,0 +1,84 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+var errApprovalConflict = errors.New("transfer was already approved or is no longer REVIEWABLE")
+
+// ApproveTransfer releases a Transfer which was held for approval by transfers.approvals.
+// Approvers can't approve Transfers they created, so every held Transfer is seen by two users
+// who are both saved and logged. It's served from the admin HTTP server.
+func ApproveTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		if cfg.Transfers.Approvals == nil {
+			responder.Problem(route.Disabled.New("transfer approvals are disabled via config"))
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+		if !cfg.Transfers.Approvals.Allowed(userID) {
+			responder.Problem(route.Forbidden.New("userID=%q can not approve transfers", userID))
+			return
+		}
+
+		transferID := getTransferID(r)
+		approval, err := repo.GetTransferApproval(transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if approval == nil {
+			responder.Problem(route.NotFound.New("transferID=%s doesn't need approval", transferID))
+			return
+		}
+		if approval.Approved != nil {
+			responder.Problem(route.Conflict.New("transferID=%s was approved by userID=%q", transferID, approval.ApprovedBy))
+			return
+		}
+		if approval.RequestedBy == userID {
+			responder.Problem(route.Forbidden.New("userID=%q can not approve their own transfer", userID))
+			return
+		}
+
+		if err := repo.approveTransfer(transferID, userID); err != nil {
+			if errors.Is(err, errApprovalConflict) {
+				responder.Problem(route.Conflict.Wrap(err))
+				return
+			}
+			responder.Problem(route.Internal.New("saving approval: %v", err))
+			return
+		}
+		approval, err = repo.GetTransferApproval(transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"transferID":  log.String(transferID),
+			"requestedBy": log.String(approval.RequestedBy),
+			"approvedBy":  log.String(approval.ApprovedBy),
+		}).Log("approved transfer")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(approval)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRepository__TransferApprovals(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		xfer := &client.Transfer{
+			TransferID: base.ID(),
+			Amount: client.Amount{
+				Currency: "USD",
+				Value:    500000,
+			},
+			Description: "payroll",
+			Status:      client.REVIEWABLE,
+			Created:     time.Now(),
+		}
+		msgs := []pipeline.OutboxMessage{pipeline.CancelMessage(xfer.TransferID)}
+		if err := repo.createReviewableTransfer(orgID, xfer, "jane", origination{traceNumbers: []string{"121042880000001"}, msgs: msgs}); err != nil {
+			t.Fatal(err)
+		}
+
+		approval, err := repo.GetTransferApproval(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if approval == nil || approval.RequestedBy != "jane" || approval.Approved != nil {
+			t.Fatalf("unexpected approval: %#v", approval)
+		}
+
+		if err := repo.approveTransfer(xfer.TransferID, "john"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.approveTransfer(xfer.TransferID, "john"); err != errApprovalConflict {
+			t.Errorf("expected conflict: %v", err)
+		}
+
+		approval, err = repo.GetTransferApproval(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if approval.ApprovedBy != "john" || approval.Approved == nil {
+			t.Errorf("unexpected approval: %#v", approval)
+		}
+		found, err := repo.GetTransfer(context.Background(), xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.PENDING {
+			t.Errorf("unexpected status: %s", found.Status)
+		}
+
+		// Transfers which didn't need approval have none
+		if approval, err := repo.GetTransferApproval(base.ID()); err != nil || approval != nil {
+			t.Errorf("approval=%#v error=%v", approval, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRepository__HeldOutbox(t *testing.T) {
+	repo := setupSQLiteDB(t)
+
+	orgID := base.ID()
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    500000,
+		},
+		Status:  client.REVIEWABLE,
+		Created: time.Now(),
+	}
+	msgs := []pipeline.OutboxMessage{pipeline.CancelMessage(xfer.TransferID)}
+	if err := repo.createReviewableTransfer(orgID, xfer, "jane", origination{msgs: msgs}); err != nil {
+		t.Fatal(err)
+	}
+
+	pub := pipeline.NewMockPublisher()
+	outbox := pipeline.NewOutbox(log.NewNopLogger(), repo.db, pub)
+	if n, err := outbox.Dispatch(); err != nil || n != 0 {
+		t.Fatalf("dispatched %d held messages: %v", n, err)
+	}
+
+	if err := repo.approveTransfer(xfer.TransferID, "john"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := outbox.Dispatch(); err != nil || n != 1 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+	if _, ok := pub.Cancels[xfer.TransferID]; !ok {
+		t.Error("expected released message")
+	}
+}
+
+func TestRouter__ApprovalRequired(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	cfg := config.Empty()
+	cfg.Transfers.Approvals = &config.TransferApprovals{
+		Amount:    100000,
+		Approvers: []string{"jane", "john"},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    250000,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+
+	// the creator needs to be known
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	c.GetConfig().AddDefaultHeader("X-User-ID", "jane")
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.Status != client.REVIEWABLE {
+		t.Errorf("unexpected status: %s", xfer.Status)
+	}
+	approval, err := repo.GetTransferApproval(xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approval == nil || approval.RequestedBy != "jane" {
+		t.Errorf("unexpected approval: %#v", approval)
+	}
+
+	// smaller transfers aren't held
+	opts.Amount.Value = 100000
+	xfer, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.Status != client.PENDING {
+		t.Errorf("unexpected status: %s", xfer.Status)
+	}
+
+	// fees count towards the approval amount
+	feesRepo := &organization.MockRepository{
+		Config: &client.OrganizationConfiguration{
+			Fees: &client.FeeSchedule{Credit: &client.FeeRule{Flat: 25}},
+		},
+	}
+	r = mux.NewRouter()
+	NewRouter(cfg, repo, feesRepo, mockCustomersClient(), mockDecryptor, mockStrategy).RegisterRoutes(r)
+	c = testclient.New(t, r)
+	c.GetConfig().AddDefaultHeader("X-User-ID", "jane")
+
+	xfer, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.Status != client.REVIEWABLE || xfer.Fee == nil || xfer.Fee.Amount.Value != 25 {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+}
+
+func TestApproveTransfer(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	cfg := config.Empty()
+	cfg.Transfers.Approvals = &config.TransferApprovals{
+		Amount:    100000,
+		Approvers: []string{"jane", "john"},
+	}
+
+	svc, c := testclient.Admin(t)
+	svc.AddHandler("/transfers/{transferID}/approve", ApproveTransfer(cfg, repo))
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    500000,
+		},
+		Status:  client.REVIEWABLE,
+		Created: time.Now(),
+	}
+	msgs := []pipeline.OutboxMessage{pipeline.CancelMessage(xfer.TransferID)}
+	if err := repo.createReviewableTransfer("organization", xfer, "jane", origination{msgs: msgs}); err != nil {
+		t.Fatal(err)
+	}
+
+	// users who aren't approvers are rejected
+	_, resp, _ := c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "mallory", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// creators can't approve their own transfer
+	_, resp, _ = c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "jane", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	approval, resp, err := c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "john", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if approval.RequestedBy != "jane" || approval.ApprovedBy != "john" || approval.Approved == nil {
+		t.Errorf("unexpected approval: %#v", approval)
+	}
+	if len(repo.outbox) != 1 {
+		t.Errorf("expected released messages: %#v", repo.outbox)
+	}
+
+	_, resp, _ = c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "john", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// transfers which didn't need approval aren't found
+	_, resp, _ = c.TransfersApi.ApproveTransfer(context.TODO(), base.ID(), "john", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
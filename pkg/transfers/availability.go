@@ -0,0 +1,230 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/client/paygate"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/jobs"
+	"github.com/moov-io/paygate/pkg/util"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	availabilityWebhooks = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "transfer_availability_webhooks",
+		Help: "Counter of funds availability webhooks by whether they were delivered",
+	}, []string{"result"})
+)
+
+// holdDays returns how many banking days after settlement the funds of a debit or credit
+// are available. The organization's configuration overrides transfers.availability.
+func holdDays(cfg *config.TransferAvailability, orgConfig *client.OrganizationConfiguration, debit bool) int {
+	if orgConfig != nil {
+		if debit && orgConfig.DebitHoldDays > 0 {
+			return int(orgConfig.DebitHoldDays)
+		}
+		if !debit && orgConfig.CreditHoldDays > 0 {
+			return int(orgConfig.CreditHoldDays)
+		}
+	}
+	return cfg.HoldDays(debit)
+}
+
+// availableTransfer is a processed Transfer whose funds are available, but weren't sent to the webhook.
+type availableTransfer struct {
+	transferID string
+	orgID      string
+}
+
+// availabilityEvent is the body POSTed to the webhook once a Transfer's funds are available.
+type availabilityEvent struct {
+	Type         string           `json:"type"`
+	Organization string           `json:"organization"`
+	Transfer     *client.Transfer `json:"transfer"`
+}
+
+const (
+	availabilityEventType = paygate.EventTransferAvailable
+
+	availabilityBatchSize = 100
+)
+
+// AvailabilityWatcher checks for processed Transfers whose availableOn date has come and
+// POSTs each of them to the configured webhook.
+//
+// Transfers are recorded as available before the webhook is called, so only one instance
+// sends each of them. Transfers the webhook doesn't accept are sent again on the next check.
+type AvailabilityWatcher struct {
+	cfg      *config.TransferAvailability
+	logger   log.Logger
+	location *time.Location
+
+	repo Repository
+
+	client *http.Client
+
+	job *jobs.Job
+}
+
+// NewAvailabilityWatcher returns nil unless an availability webhook is configured.
+func NewAvailabilityWatcher(cfg *config.Config, repo Repository) *AvailabilityWatcher {
+	if cfg.Transfers.Availability == nil || cfg.Transfers.Availability.Webhook == nil {
+		return nil
+	}
+	location := cfg.ODFI.Cutoffs.Location()
+	if location == nil {
+		location = time.UTC
+	}
+	return &AvailabilityWatcher{
+		cfg:      cfg.Transfers.Availability,
+		logger:   cfg.Logger.Set("service", log.String("transfer-availability")),
+		location: location,
+		repo:     repo,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// UseJobs records the runs of the AvailabilityWatcher as the "transfers.availability" job of reg.
+func (wt *AvailabilityWatcher) UseJobs(reg *jobs.Registry) {
+	if wt == nil {
+		return
+	}
+	wt.job = reg.Register("transfers.availability")
+}
+
+// Start checks for Transfers whose funds became available until ctx is canceled.
+func (wt *AvailabilityWatcher) Start(ctx context.Context) {
+	if wt == nil {
+		return
+	}
+	interval := wt.cfg.Webhook.PollInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	wt.job.Scheduled(time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			wt.run()
+			wt.job.Scheduled(time.Now().Add(interval))
+
+		case <-wt.job.Triggered():
+			wt.run()
+
+		case <-ctx.Done():
+			wt.logger.Log("transfer availability watcher shutdown")
+			return
+		}
+	}
+}
+
+func (wt *AvailabilityWatcher) run() {
+	_, err := wt.Process(time.Now())
+	if err != nil {
+		wt.logger.LogErrorf("ERROR checking transfer availability: %v", err)
+	}
+	wt.job.Done(err)
+}
+
+// Process sends a webhook for each Transfer whose funds are available at now and returns
+// how many were sent.
+func (wt *AvailabilityWatcher) Process(now time.Time) (int, error) {
+	today := base.NewTime(now.In(wt.location)).Format(util.YYMMDDTimeFormat)
+	items, err := wt.repo.getAvailableTransfers(today, availabilityBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var sent int
+	for i := range items {
+		if wt.process(items[i]) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (wt *AvailabilityWatcher) process(item availableTransfer) bool {
+	logger := wt.logger.Set("transferID", log.String(item.transferID))
+
+	transfer, err := wt.repo.GetUserTransfer(context.Background(), item.transferID, item.orgID)
+	if err != nil || transfer == nil {
+		if err != nil {
+			logger.LogErrorf("ERROR getting transfer: %v", err)
+		}
+		return false
+	}
+
+	// Another instance could find the same Transfer, only the one which records it sends the webhook
+	if updated, err := wt.repo.setFundsAvailable(item.transferID, true); err != nil || !updated {
+		if err != nil {
+			logger.LogErrorf("ERROR recording funds availability: %v", err)
+		}
+		return false
+	}
+
+	err = wt.send(availabilityEvent{
+		Type:         availabilityEventType,
+		Organization: item.orgID,
+		Transfer:     transfer,
+	})
+	if err != nil {
+		logger.LogErrorf("ERROR sending availability webhook: %v", err)
+		availabilityWebhooks.With("result", "failed").Add(1)
+
+		if _, err := wt.repo.setFundsAvailable(item.transferID, false); err != nil {
+			logger.LogErrorf("ERROR reverting funds availability: %v", err)
+		}
+		return false
+	}
+	availabilityWebhooks.With("result", "sent").Add(1)
+	logger.Logf("sent availability webhook for funds available on %s", transfer.AvailableOn)
+	return true
+}
+
+func (wt *AvailabilityWatcher) send(event availabilityEvent) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(event); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", wt.cfg.Webhook.Endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Organization", event.Organization)
+	if secret := wt.cfg.Webhook.Secret; secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(paygate.WebhookTimestampHeader, timestamp)
+		req.Header.Set(paygate.WebhookSignatureHeader, paygate.SignWebhook(secret, timestamp, body.Bytes()))
+	}
+
+	resp, err := wt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/client/paygate"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestHoldDays(t *testing.T) {
+	if n := holdDays(nil, nil, true); n != 0 {
+		t.Errorf("unexpected hold: %d", n)
+	}
+
+	cfg := &config.TransferAvailability{DebitHoldDays: 3, CreditHoldDays: 1}
+	if n := holdDays(cfg, nil, true); n != 3 {
+		t.Errorf("unexpected hold: %d", n)
+	}
+	if n := holdDays(cfg, &client.OrganizationConfiguration{}, false); n != 1 {
+		t.Errorf("unexpected hold: %d", n)
+	}
+
+	// organizations override the config
+	orgConfig := &client.OrganizationConfiguration{DebitHoldDays: 5}
+	if n := holdDays(cfg, orgConfig, true); n != 5 {
+		t.Errorf("unexpected hold: %d", n)
+	}
+	if n := holdDays(nil, orgConfig, false); n != 0 {
+		t.Errorf("unexpected hold: %d", n)
+	}
+}
+
+func writeAvailableTransfer(t *testing.T, repo Repository, orgID string, availableOn string) *client.Transfer {
+	t.Helper()
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Description: "test transfer",
+		Status:      client.PROCESSED,
+		AvailableOn: availableOn,
+		Created:     time.Now(),
+	}
+	if err := repo.WriteUserTransfer(orgID, xfer); err != nil {
+		t.Fatal(err)
+	}
+	return xfer
+}
+
+func TestRepository__AvailableTransfers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		xfer := writeAvailableTransfer(t, repo, orgID, "2020-11-12")
+
+		if items, err := repo.getAvailableTransfers("2020-11-11", 10); err != nil || len(items) != 0 {
+			t.Fatalf("items=%#v error=%v", items, err)
+		}
+		items, err := repo.getAvailableTransfers("2020-11-12", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].transferID != xfer.TransferID || items[0].orgID != orgID {
+			t.Fatalf("unexpected transfers: %#v", items)
+		}
+
+		if updated, err := repo.setFundsAvailable(xfer.TransferID, true); err != nil || !updated {
+			t.Fatalf("updated=%v error=%v", updated, err)
+		}
+		if updated, err := repo.setFundsAvailable(xfer.TransferID, true); err != nil || updated {
+			t.Fatalf("updated=%v error=%v", updated, err)
+		}
+		if items, err := repo.getAvailableTransfers("2020-11-12", 10); err != nil || len(items) != 0 {
+			t.Fatalf("items=%#v error=%v", items, err)
+		}
+
+		if updated, err := repo.setFundsAvailable(xfer.TransferID, false); err != nil || !updated {
+			t.Fatalf("updated=%v error=%v", updated, err)
+		}
+		if items, err := repo.getAvailableTransfers("2020-11-13", 10); err != nil || len(items) != 1 {
+			t.Fatalf("items=%#v error=%v", items, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestAvailabilityWatcher__nil(t *testing.T) {
+	watcher := NewAvailabilityWatcher(config.Empty(), &MockRepository{})
+	if watcher != nil {
+		t.Fatalf("unexpected AvailabilityWatcher: %#v", watcher)
+	}
+	watcher.Start(context.Background())
+}
+
+func TestAvailabilityWatcher__Process(t *testing.T) {
+	status := http.StatusOK
+	var events []availabilityEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event availabilityEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events = append(events, event)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	check := func(t *testing.T, repo Repository) {
+		events = nil
+		status = http.StatusOK
+
+		cfg := config.Empty()
+		cfg.Transfers.Availability = &config.TransferAvailability{
+			Webhook: &config.AvailabilityWebhook{
+				Endpoint: server.URL,
+			},
+		}
+		watcher := NewAvailabilityWatcher(cfg, repo)
+
+		orgID := base.ID()
+		xfer := writeAvailableTransfer(t, repo, orgID, "2020-11-12")
+		now := time.Date(2020, time.November, 12, 10, 0, 0, 0, time.UTC)
+
+		// the webhook fails, so the Transfer is sent again
+		status = http.StatusInternalServerError
+		if n, err := watcher.Process(now); err != nil || n != 0 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		status = http.StatusOK
+		if n, err := watcher.Process(now); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		if len(events) != 2 || events[1].Type != availabilityEventType || events[1].Organization != orgID {
+			t.Fatalf("unexpected events: %#v", events)
+		}
+		if events[1].Transfer == nil || events[1].Transfer.TransferID != xfer.TransferID {
+			t.Errorf("unexpected transfer: %#v", events[1].Transfer)
+		}
+
+		// each Transfer is sent once
+		if n, err := watcher.Process(now); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestAvailabilityWatcher__signed(t *testing.T) {
+	secret := strings.Repeat("s", config.MinWebhookSecretLength)
+
+	var events []*paygate.WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := paygate.ReadWebhook(r, secret, paygate.DefaultWebhookTolerance)
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	cfg := config.Empty()
+	cfg.Transfers.Availability = &config.TransferAvailability{
+		Webhook: &config.AvailabilityWebhook{
+			Endpoint: server.URL,
+			Secret:   secret,
+		},
+	}
+	repo := NewInMemoryRepo()
+	watcher := NewAvailabilityWatcher(cfg, repo)
+
+	xfer := writeAvailableTransfer(t, repo, base.ID(), "2020-11-12")
+	if n, err := watcher.Process(time.Date(2020, time.November, 12, 10, 0, 0, 0, time.UTC)); err != nil || n != 1 {
+		t.Fatalf("n=%d error=%v", n, err)
+	}
+	if len(events) != 1 || events[0].TransferAvailable == nil || events[0].TransferAvailable.Transfer.TransferID != xfer.TransferID {
+		t.Errorf("unexpected events: %#v", events)
+	}
+}
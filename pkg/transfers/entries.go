@@ -0,0 +1,324 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/moov-io/base/log"
+)
+
+// EntryRecorder saves the EntryDetail records of each uploaded Transfer as they
+// appear in the merged files, which are needed for audits and disputes.
+type EntryRecorder struct {
+	logger log.Logger
+	repo   Repository
+}
+
+func NewEntryRecorder(logger log.Logger, repo Repository) *EntryRecorder {
+	return &EntryRecorder{
+		logger: logger.Set("service", log.String("transfer-entries")),
+		repo:   repo,
+	}
+}
+
+// HandleCutoff is a pipeline.CompletedCutoffCallback which matches entries of the
+// uploaded files to Transfers by their trace numbers.
+func (er *EntryRecorder) HandleCutoff(cutoff pipeline.CompletedCutoff) error {
+	uploaded := uploadedEntries(cutoff)
+	if len(uploaded) == 0 {
+		return nil
+	}
+
+	var el base.ErrorList
+	saved := 0
+	for i := range cutoff.Transfers {
+		transferID := cutoff.Transfers[i].TransferID
+		traceNumbers, err := er.repo.getTraceNumbers(context.Background(), transferID)
+		if err != nil {
+			el.Add(fmt.Errorf("transferID=%s reading trace numbers: %v", transferID, err))
+			continue
+		}
+		var entries []client.TransferEntry
+		for j := range traceNumbers {
+			if entry, ok := uploaded[traceNumbers[j]]; ok {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		if err := er.repo.saveTransferEntries(transferID, entries); err != nil {
+			el.Add(fmt.Errorf("transferID=%s saving entries: %v", transferID, err))
+			continue
+		}
+		saved += len(entries)
+	}
+	er.logger.Logf("saved %d entries from %d uploaded files", saved, len(cutoff.Files))
+
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+// uploadedEntries returns each entry of the uploaded files keyed by its trace number.
+func uploadedEntries(cutoff pipeline.CompletedCutoff) map[string]client.TransferEntry {
+	out := make(map[string]client.TransferEntry)
+	for i := range cutoff.Files {
+		file := cutoff.Files[i].File
+		if file == nil {
+			continue
+		}
+		for j := range file.Batches {
+			batchNumber := file.Batches[j].GetHeader().BatchNumber
+			entries := file.Batches[j].GetEntries()
+			for k := range entries {
+				out[entries[k].TraceNumber] = client.TransferEntry{
+					Filename:    cutoff.Files[i].Filename,
+					BatchNumber: int32(batchNumber),
+					TraceNumber: entries[k].TraceNumber,
+					EntryDetail: entries[k].String(),
+					Addenda:     addendaRecords(entries[k]),
+					UploadedAt:  cutoff.When,
+				}
+			}
+		}
+	}
+	return out
+}
+
+func addendaRecords(ed *ach.EntryDetail) []string {
+	out := make([]string, 0)
+	if ed.Addenda02 != nil {
+		out = append(out, ed.Addenda02.String())
+	}
+	for i := range ed.Addenda05 {
+		out = append(out, ed.Addenda05[i].String())
+	}
+	if ed.Addenda98 != nil {
+		out = append(out, ed.Addenda98.String())
+	}
+	if ed.Addenda99 != nil {
+		out = append(out, ed.Addenda99.String())
+	}
+	return out
+}
+
+func GetTransferEntries(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		xfer, err := repo.GetUserTransfer(r.Context(), transferID, responder.OrganizationID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("transfer not found"))
+			return
+		}
+
+		entries, err := repo.getTransferEntries(r.Context(), transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		for i := range entries {
+			entries[i].EntryDetail = maskAccountNumber(entries[i].EntryDetail)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(entries)
+		})
+	}
+}
+
+func GetTransferFile(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		transferID := getTransferID(r)
+		xfer, err := repo.GetUserTransfer(r.Context(), transferID, responder.OrganizationID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("transfer not found"))
+			return
+		}
+
+		file, err := repo.getTransferFile(r.Context(), transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if file == nil {
+			responder.Problem(route.NotFound.New("transfer hasn't been uploaded"))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(file)
+		})
+	}
+}
+
+// newTransferFile returns the first file entries were merged into, or nil when there are none.
+func newTransferFile(entries []client.TransferEntry) *client.TransferFile {
+	if len(entries) == 0 {
+		return nil
+	}
+	file := &client.TransferFile{
+		Filename:     entries[0].Filename,
+		BatchNumbers: make([]int32, 0),
+		TraceNumbers: make([]string, 0),
+		UploadedAt:   entries[0].UploadedAt,
+	}
+	for i := range entries {
+		if entries[i].Filename != file.Filename {
+			continue
+		}
+		if !containsBatchNumber(file.BatchNumbers, entries[i].BatchNumber) {
+			file.BatchNumbers = append(file.BatchNumbers, entries[i].BatchNumber)
+		}
+		file.TraceNumbers = append(file.TraceNumbers, entries[i].TraceNumber)
+	}
+	return file
+}
+
+func containsBatchNumber(batchNumbers []int32, batchNumber int32) bool {
+	for i := range batchNumbers {
+		if batchNumbers[i] == batchNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// maskAccountNumber replaces all but the last four characters of the DFIAccountNumber
+// in an EntryDetail record with asterisks, keeping the record 94 characters long.
+func maskAccountNumber(record string) string {
+	if len(record) != 94 {
+		return record
+	}
+	// DFIAccountNumber is left justified in positions 13 through 29
+	field := []byte(record[12:29])
+	end := len(strings.TrimRight(string(field), " "))
+	for i := 0; i < end-4; i++ {
+		field[i] = '*'
+	}
+	return record[:12] + string(field) + record[29:]
+}
+
+// RevealTransferEntries returns the entries of a Transfer with their full account numbers
+// for users allowed in transfers.reveal. Each reveal is saved with the user and their reason
+// before any entries are returned. It's served from the admin HTTP server.
+func RevealTransferEntries(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		if cfg.Transfers.Reveal == nil {
+			responder.Problem(route.Disabled.New("revealing account numbers is disabled via config"))
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+		if !cfg.Transfers.Reveal.Allowed(userID) {
+			responder.Problem(route.Forbidden.New("userID=%q can not reveal account numbers", userID))
+			return
+		}
+
+		var request admin.RevealTransferEntries
+		if err := route.DecodeJSON(r, &request, route.DisallowUnknownFields); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			verr := &route.ValidationError{}
+			verr.Add("reason", "missing")
+			responder.Problem(verr.Err())
+			return
+		}
+
+		transferID := getTransferID(r)
+		xfer, err := repo.GetTransfer(r.Context(), transferID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("transfer not found"))
+			return
+		}
+		entries, err := repo.getTransferEntries(r.Context(), transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if err := repo.recordAccountNumberReveal(transferID, userID, request.Reason); err != nil {
+			responder.Problem(route.Internal.New("saving reveal: %v", err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"transferID": log.String(transferID),
+			"reason":     log.String(request.Reason),
+		}).Log("revealed account numbers")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(entries)
+		})
+	}
+}
+
+// GetFileContents returns which Transfers and micro-deposits were merged into each batch of
+// an uploaded file along with every attempt to upload it. It's served from the admin HTTP server.
+func GetFileContents(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		filename := route.ReadPathID("filename", r)
+		contents, err := repo.getFileContents(r.Context(), filename)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if contents == nil {
+			responder.Problem(route.NotFound.New("file %s not found", filename))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(contents)
+		})
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/gorilla/mux"
+)
+
+func recordUploadedFile(t *testing.T, repo Repository, transferID string) *ach.EntryDetail {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := file.Batches[0].GetEntries()[0]
+	if err := repo.saveTraceNumbers(transferID, []string{entry.TraceNumber}); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := NewEntryRecorder(log.NewNopLogger(), repo)
+	err = recorder.HandleCutoff(pipeline.CompletedCutoff{
+		When:      time.Now(),
+		Filenames: []string{"20200102-987654320-1.ach"},
+		Files: []pipeline.UploadedFile{
+			{Filename: "20200102-987654320-1.ach", File: file},
+		},
+		Transfers: []pipeline.ProcessedTransfer{
+			{TransferID: transferID},
+			{TransferID: base.ID()}, // not in any file
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestEntryRecorder(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		xfer := writeTransfer(t, base.ID(), repo)
+		entry := recordUploadedFile(t, repo, xfer.TransferID)
+
+		entries, err := repo.getTransferEntries(context.Background(), xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("unexpected entries: %#v", entries)
+		}
+		if e := entries[0]; e.Filename != "20200102-987654320-1.ach" || e.BatchNumber != 1 || e.TraceNumber != entry.TraceNumber {
+			t.Errorf("unexpected entry: %#v", e)
+		}
+		if entries[0].EntryDetail != entry.String() || len(entries[0].Addenda) != 0 {
+			t.Errorf("unexpected records: %#v", entries[0])
+		}
+
+		if entries, _ := repo.getTransferEntries(context.Background(), base.ID()); len(entries) != 0 {
+			t.Errorf("unexpected entries: %#v", entries)
+		}
+	}
+
+	t.Run("SQLite", func(t *testing.T) {
+		check(t, setupSQLiteDB(t))
+	})
+	t.Run("Memory", func(t *testing.T) {
+		check(t, NewInMemoryRepo())
+	})
+}
+
+func TestRouter__GetTransferEntries(t *testing.T) {
+	orgID := base.ID()
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, orgID, repo)
+	entry := recordUploadedFile(t, repo, xfer.TransferID)
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	entries, resp, err := c.TransfersApi.GetTransferEntries(context.TODO(), xfer.TransferID, orgID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(entries) != 1 || entries[0].EntryDetail != maskAccountNumber(entry.String()) {
+		t.Errorf("unexpected entries: %#v", entries)
+	}
+
+	// another organization can't read the entries
+	_, resp, err = c.TransfersApi.GetTransferEntries(context.TODO(), xfer.TransferID, base.ID(), nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+}
+
+func TestEntries__maskAccountNumber(t *testing.T) {
+	ed := ach.NewEntryDetail()
+	ed.TransactionCode = ach.CheckingCredit
+	ed.RDFIIdentification = "23138010"
+	ed.CheckDigit = "4"
+	ed.DFIAccountNumber = "12345678"
+	ed.Amount = 100
+	ed.IndividualName = "Jane Doe"
+	ed.TraceNumber = "121042880000001"
+
+	record := ed.String()
+	masked := maskAccountNumber(record)
+	if len(masked) != 94 || masked[12:29] != "****5678         " {
+		t.Errorf("unexpected record: %q", masked)
+	}
+	if masked[:12] != record[:12] || masked[29:] != record[29:] {
+		t.Errorf("unexpected changes: %q", masked)
+	}
+
+	// short account numbers are left as they are
+	ed.DFIAccountNumber = "123"
+	if record := ed.String(); maskAccountNumber(record) != record {
+		t.Errorf("unexpected record: %q", maskAccountNumber(record))
+	}
+	if out := maskAccountNumber("short"); out != "short" {
+		t.Errorf("unexpected record: %q", out)
+	}
+}
+
+func TestAdmin__RevealTransferEntries(t *testing.T) {
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, base.ID(), repo)
+	entry := recordUploadedFile(t, repo, xfer.TransferID)
+
+	cfg := config.Empty()
+	svc, c := testclient.Admin(t)
+	svc.AddHandler("/transfers/{transferID}/ach/reveal", RevealTransferEntries(cfg, repo))
+
+	req := admin.RevealTransferEntries{Reason: "dispute"}
+
+	// disabled by default
+	_, resp, err := c.TransfersApi.RevealTransferEntries(context.TODO(), xfer.TransferID, "jane", req, nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	cfg.Transfers.Reveal = &config.RevealAccountNumbers{Users: []string{"jane"}}
+	if _, resp, err := c.TransfersApi.RevealTransferEntries(context.TODO(), xfer.TransferID, "john", req, nil); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected error: %v", err)
+	}
+	if _, _, err := c.TransfersApi.RevealTransferEntries(context.TODO(), xfer.TransferID, "jane", admin.RevealTransferEntries{Reason: " "}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(repo.reveals[xfer.TransferID]) != 0 {
+		t.Fatalf("unexpected reveals: %v", repo.reveals)
+	}
+
+	entries, resp, err := c.TransfersApi.RevealTransferEntries(context.TODO(), xfer.TransferID, "jane", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(entries) != 1 || entries[0].EntryDetail != entry.String() || strings.Contains(entries[0].EntryDetail, "*") {
+		t.Errorf("unexpected entries: %#v", entries)
+	}
+	if users := repo.reveals[xfer.TransferID]; len(users) != 1 || users[0] != "jane" {
+		t.Errorf("unexpected reveals: %v", users)
+	}
+}
+
+func TestRepository__recordAccountNumberReveal(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	transferID := base.ID()
+	if err := repo.recordAccountNumberReveal(transferID, "jane", "dispute"); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID, reason string
+	query := `select user_id, reason from account_number_reveals where transfer_id = ?;`
+	if err := repo.db.QueryRow(query, transferID).Scan(&userID, &reason); err != nil {
+		t.Fatal(err)
+	}
+	if userID != "jane" || reason != "dispute" {
+		t.Errorf("userID=%q reason=%q", userID, reason)
+	}
+}
+
+func TestRepository__getFileContents(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		xfer := writeTransfer(t, base.ID(), repo)
+		entry := recordUploadedFile(t, repo, xfer.TransferID)
+
+		contents, err := repo.getFileContents(context.Background(), "20200102-987654320-1.ach")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contents == nil || len(contents.Batches) != 1 || contents.Batches[0].BatchNumber != 1 {
+			t.Fatalf("unexpected contents: %#v", contents)
+		}
+		if e := contents.Batches[0].Entries; len(e) != 1 || e[0].TransferID != xfer.TransferID || e[0].TraceNumber != entry.TraceNumber {
+			t.Errorf("unexpected entries: %#v", e)
+		}
+
+		file, err := repo.getTransferFile(context.Background(), xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file == nil || file.Filename != "20200102-987654320-1.ach" || len(file.BatchNumbers) != 1 || file.TraceNumbers[0] != entry.TraceNumber {
+			t.Errorf("unexpected file: %#v", file)
+		}
+
+		if contents, err := repo.getFileContents(context.Background(), "other.ach"); contents != nil || err != nil {
+			t.Errorf("contents=%#v error=%v", contents, err)
+		}
+		if file, err := repo.getTransferFile(context.Background(), base.ID()); file != nil || err != nil {
+			t.Errorf("file=%#v error=%v", file, err)
+		}
+	}
+
+	t.Run("SQLite", func(t *testing.T) {
+		check(t, setupSQLiteDB(t))
+	})
+	t.Run("Memory", func(t *testing.T) {
+		check(t, NewInMemoryRepo())
+	})
+}
+
+func TestRepository__getFileContentsUploads(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	xfer := writeTransfer(t, base.ID(), repo)
+	recordUploadedFile(t, repo, xfer.TransferID)
+
+	microDepositID := base.ID()
+	if _, err := repo.db.Exec(`insert into micro_deposit_transfers (micro_deposit_id, transfer_id) values (?, ?);`, microDepositID, xfer.TransferID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first upload failed and the second was verified
+	uploadedAt := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+	query := `insert into file_archive (filename, kind, file_hash, size, outbound_path, error, verified, processed_at) values (?, 'outbound', 'abc', 100, 'outbound/', ?, ?, ?);`
+	if _, err := repo.db.Exec(query, "20200102-987654320-1.ach", "connection reset", false, uploadedAt.Add(-1*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.db.Exec(query, "20200102-987654320-1.ach", nil, true, uploadedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := repo.getFileContents(context.Background(), "20200102-987654320-1.ach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := contents.Batches[0].Entries; len(e) != 1 || e[0].MicroDepositID != microDepositID {
+		t.Errorf("unexpected entries: %#v", e)
+	}
+	if len(contents.Uploads) != 2 || contents.Uploads[0].Error != "connection reset" || !contents.Uploads[1].Verified {
+		t.Errorf("unexpected uploads: %#v", contents.Uploads)
+	}
+
+	file, err := repo.getTransferFile(context.Background(), xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !file.Acknowledged || !file.UploadedAt.Equal(uploadedAt) {
+		t.Errorf("unexpected file: %#v", file)
+	}
+}
+
+func TestRouter__GetTransferFile(t *testing.T) {
+	orgID := base.ID()
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, orgID, repo)
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	// nothing until the Transfer is uploaded
+	_, resp, err := c.TransfersApi.GetTransferFile(context.TODO(), xfer.TransferID, orgID, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	entry := recordUploadedFile(t, repo, xfer.TransferID)
+	file, resp, err := c.TransfersApi.GetTransferFile(context.TODO(), xfer.TransferID, orgID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if file.Filename != "20200102-987654320-1.ach" || len(file.TraceNumbers) != 1 || file.TraceNumbers[0] != entry.TraceNumber {
+		t.Errorf("unexpected file: %#v", file)
+	}
+
+	// another organization can't read the file
+	_, resp, err = c.TransfersApi.GetTransferFile(context.TODO(), xfer.TransferID, base.ID(), nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+}
+
+func TestAdmin__GetFileContents(t *testing.T) {
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, base.ID(), repo)
+	recordUploadedFile(t, repo, xfer.TransferID)
+
+	svc, c := testclient.Admin(t)
+	svc.AddHandler("/files/{filename}/contents", GetFileContents(config.Empty(), repo))
+
+	contents, resp, err := c.TransfersApi.GetFileContents(context.TODO(), "20200102-987654320-1.ach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(contents.Batches) != 1 || contents.Batches[0].Entries[0].TransferID != xfer.TransferID {
+		t.Errorf("unexpected contents: %#v", contents)
+	}
+
+	if _, resp, err := c.TransfersApi.GetFileContents(context.TODO(), "other.ach"); err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected error: %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+// maxEvidenceFilenameLength is the size of the filename column
+const maxEvidenceFilenameLength = 255
+
+// evidenceContentTypes are the documents accepted as evidence, as detected by http.DetectContentType
+var evidenceContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// EvidenceStorage keeps documents proving receivers authorized their debits in the bucket of
+// config.TransferEvidence. A nil EvidenceStorage accepts links to evidence kept elsewhere, but
+// not uploads.
+type EvidenceStorage struct {
+	bucket *blob.Bucket
+}
+
+func NewEvidenceStorage(cfg *config.TransferEvidence) (*EvidenceStorage, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	bucket, err := blob.OpenBucket(context.Background(), cfg.BucketURI)
+	if err != nil {
+		return nil, fmt.Errorf("opening evidence bucket: %v", err)
+	}
+	return &EvidenceStorage{bucket: bucket}, nil
+}
+
+func (s *EvidenceStorage) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.bucket.Close()
+}
+
+func evidencePath(evidenceID string) string {
+	return fmt.Sprintf("evidence/%s", evidenceID)
+}
+
+func (s *EvidenceStorage) save(ctx context.Context, evidence *client.AuthorizationEvidence, content []byte) error {
+	w, err := s.bucket.NewWriter(ctx, evidencePath(evidence.EvidenceID), &blob.WriterOptions{
+		ContentType: evidence.ContentType,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, copyErr := w.Write(content)
+	closeErr := w.Close()
+
+	if copyErr != nil || closeErr != nil {
+		return fmt.Errorf("copyErr=%v closeErr=%v", copyErr, closeErr)
+	}
+	return nil
+}
+
+func (s *EvidenceStorage) read(ctx context.Context, evidenceID string) ([]byte, error) {
+	return s.bucket.ReadAll(ctx, evidencePath(evidenceID))
+}
+
+func getEvidenceID(r *http.Request) string {
+	return route.ReadPathID("evidenceID", r)
+}
+
+// AddMandateEvidence attaches a document or link proving the receiver authorized a mandate.
+func AddMandateEvidence(cfg *config.Config, repo Repository, storage *EvidenceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		mandate, err := readMandate(r.Context(), repo, responder.OrganizationID, getMandateID(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		attachEvidence(responder, r, repo, storage, &client.AuthorizationEvidence{
+			MandateID: mandate.MandateID,
+		})
+	}
+}
+
+// AddTransferEvidence attaches a document or link proving the receiver authorized a Transfer,
+// such as one-time debits which don't reference a mandate.
+func AddTransferEvidence(cfg *config.Config, repo Repository, storage *EvidenceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		xfer, err := repo.GetUserTransfer(r.Context(), getTransferID(r), responder.OrganizationID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("transfer not found"))
+			return
+		}
+		attachEvidence(responder, r, repo, storage, &client.AuthorizationEvidence{
+			TransferID: xfer.TransferID,
+		})
+	}
+}
+
+// attachEvidence reads the evidence in the request body, saves an uploaded document in storage
+// and then records evidence.
+func attachEvidence(responder *route.Responder, r *http.Request, repo Repository, storage *EvidenceStorage, evidence *client.AuthorizationEvidence) {
+	var req client.CreateAuthorizationEvidence
+	if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+		responder.Problem(fmt.Errorf("adding evidence: problem reading request body: %w", err))
+		return
+	}
+	content, err := readEvidenceRequest(req)
+	if err != nil {
+		responder.Problem(fmt.Errorf("adding evidence: invalid evidence: %w", err))
+		return
+	}
+
+	evidence.EvidenceID = base.ID()
+	evidence.Url = req.Url
+	evidence.Created = time.Now()
+	if len(content) > 0 {
+		if storage == nil {
+			responder.Problem(route.Disabled.New("adding evidence: uploads are disabled as transfers.evidence isn't configured"))
+			return
+		}
+		evidence.ContentType = detectContentType(content)
+		evidence.Filename = req.Filename
+		evidence.Size = int64(len(content))
+		if err := storage.save(r.Context(), evidence, content); err != nil {
+			responder.Problem(route.Internal.New("adding evidence: error saving document: %v", err))
+			return
+		}
+	}
+	if err := repo.createEvidence(responder.OrganizationID, evidence); err != nil {
+		responder.Problem(route.Internal.New("adding evidence: %v", err))
+		return
+	}
+	responder.Logger().With(log.Fields{
+		"evidenceID": log.String(evidence.EvidenceID),
+		"mandateID":  log.String(evidence.MandateID),
+		"transferID": log.String(evidence.TransferID),
+	}).Log("added authorization evidence")
+
+	responder.Respond(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(evidence)
+	})
+}
+
+// readEvidenceRequest returns the decoded document of req, which is empty for links.
+func readEvidenceRequest(req client.CreateAuthorizationEvidence) ([]byte, error) {
+	verr := &route.ValidationError{}
+	var content []byte
+	switch {
+	case req.Content == "" && req.Url == "":
+		verr.Add("content", "missing content or url")
+
+	case req.Content != "" && req.Url != "":
+		verr.Add("url", "can't be used with content")
+
+	case req.Url != "":
+		if u, err := url.Parse(req.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.Add("url", "%q isn't an http or https URL", req.Url)
+		}
+
+	default:
+		decoded, err := base64.StdEncoding.DecodeString(req.Content)
+		if err != nil {
+			verr.Add("content", "invalid base64: %v", err)
+		} else if contentType := detectContentType(decoded); !evidenceContentTypes[contentType] {
+			verr.Add("content", "%s isn't a PDF, JPEG or PNG document", contentType)
+		}
+		content = decoded
+	}
+	if len(req.Filename) > maxEvidenceFilenameLength {
+		verr.Add("filename", "longer than %d characters", maxEvidenceFilenameLength)
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// detectContentType returns the media type of content without parameters such as its charset
+func detectContentType(content []byte) string {
+	contentType := http.DetectContentType(content)
+	if idx := strings.Index(contentType, ";"); idx > 0 {
+		contentType = contentType[:idx]
+	}
+	return contentType
+}
+
+// GetTransferEvidence lists the evidence of a Transfer and its mandate from the admin server,
+// such as when the Transfer is returned as unauthorized.
+func GetTransferEvidence(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+		if userID == "" {
+			responder.Problem(route.InvalidRequest.New("missing X-User-ID"))
+			return
+		}
+
+		transferID := getTransferID(r)
+		xfer, err := repo.GetTransfer(r.Context(), transferID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("transfer not found"))
+			return
+		}
+		evidence, err := repo.getTransferEvidence(r.Context(), transferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if evidence == nil {
+			evidence = []client.AuthorizationEvidence{}
+		}
+		responder.Logger().Set("transferID", log.String(transferID)).Log("listed authorization evidence")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(evidence)
+		})
+	}
+}
+
+// GetEvidenceContent returns an uploaded document from the admin server. Each read is logged
+// with the user reading it.
+func GetEvidenceContent(cfg *config.Config, repo Repository, storage *EvidenceStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+		if userID == "" {
+			responder.Problem(route.InvalidRequest.New("missing X-User-ID"))
+			return
+		}
+		if storage == nil {
+			responder.Problem(route.Disabled.New("evidence documents are disabled as transfers.evidence isn't configured"))
+			return
+		}
+
+		evidenceID := getEvidenceID(r)
+		evidence, err := repo.getEvidence(r.Context(), evidenceID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if evidence == nil {
+			responder.Problem(route.NotFound.New("evidenceID=%s not found", evidenceID))
+			return
+		}
+		if evidence.ContentType == "" {
+			responder.Problem(route.InvalidRequest.New("evidenceID=%s links to %s and has no document", evidenceID, evidence.Url))
+			return
+		}
+		content, err := storage.read(r.Context(), evidenceID)
+		if err != nil {
+			responder.Problem(route.Internal.New("reading evidenceID=%s: %v", evidenceID, err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"evidenceID": log.String(evidenceID),
+			"mandateID":  log.String(evidence.MandateID),
+			"transferID": log.String(evidence.TransferID),
+		}).Log("read authorization evidence")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", evidence.ContentType)
+			if evidence.Filename != "" {
+				w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": evidence.Filename}))
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(content)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/gorilla/mux"
+)
+
+var pdfContent = []byte("%PDF-1.4\n%signed authorization\n")
+
+func TestEvidence__readRequest(t *testing.T) {
+	content, err := readEvidenceRequest(client.CreateAuthorizationEvidence{
+		Content:  base64.StdEncoding.EncodeToString(pdfContent),
+		Filename: "authorization.pdf",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(pdfContent) {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	content, err = readEvidenceRequest(client.CreateAuthorizationEvidence{Url: "https://example.com/signup/123"})
+	if err != nil || len(content) != 0 {
+		t.Fatalf("content=%q: %v", content, err)
+	}
+
+	invalid := []client.CreateAuthorizationEvidence{
+		{},
+		{Content: base64.StdEncoding.EncodeToString(pdfContent), Url: "https://example.com/signup/123"},
+		{Content: "not base64!"},
+		{Content: base64.StdEncoding.EncodeToString([]byte("plain text"))},
+		{Url: "file:///etc/passwd"},
+		{Url: "https://"},
+	}
+	for i := range invalid {
+		if _, err := readEvidenceRequest(invalid[i]); err == nil {
+			t.Errorf("#%d expected error", i)
+		}
+	}
+}
+
+func TestRouter__evidence(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	cfg.Transfers.Evidence = &config.TransferEvidence{BucketURI: "mem://"}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, &organization.MockRepository{}, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+	t.Cleanup(func() { router.Evidence.Close() })
+
+	c := testclient.New(t, r)
+
+	mandate, resp, err := c.MandatesApi.CreateMandate(context.TODO(), "organization", client.CreateMandate{
+		CustomerID:   sourceCustomerID,
+		AccountID:    sourceAccountID,
+		AuthorizedAt: time.Now().Add(-24 * time.Hour),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	uploaded, resp, err := c.MandatesApi.AddMandateEvidence(context.TODO(), mandate.MandateID, "organization", client.CreateAuthorizationEvidence{
+		Content:  base64.StdEncoding.EncodeToString(pdfContent),
+		Filename: "authorization.pdf",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if uploaded.MandateID != mandate.MandateID || uploaded.ContentType != "application/pdf" || uploaded.Size != int64(len(pdfContent)) {
+		t.Errorf("unexpected evidence: %#v", uploaded)
+	}
+
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", client.CreateTransfer{
+		Amount: client.Amount{Currency: "USD", Value: 1245},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "gym membership",
+		MandateID:   mandate.MandateID,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	linked, resp, err := c.TransfersApi.AddTransferEvidence(context.TODO(), xfer.TransferID, "organization", client.CreateAuthorizationEvidence{
+		Url: "https://example.com/signup/123",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if linked.TransferID != xfer.TransferID || linked.ContentType != "" {
+		t.Errorf("unexpected evidence: %#v", linked)
+	}
+
+	// other organizations can't attach evidence
+	_, resp, err = c.TransfersApi.AddTransferEvidence(context.TODO(), xfer.TransferID, "other", client.CreateAuthorizationEvidence{
+		Url: "https://example.com/signup/123",
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	svc, ac := testclient.Admin(t)
+	svc.AddHandler("/transfers/{transferID}/evidence", GetTransferEvidence(cfg, repo))
+	svc.AddHandler("/evidence/{evidenceID}/content", GetEvidenceContent(cfg, repo, router.Evidence))
+
+	evidence, resp, err := ac.TransfersApi.GetTransferEvidence(context.TODO(), xfer.TransferID, "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(evidence) != 2 || evidence[0].EvidenceID != uploaded.EvidenceID || evidence[1].EvidenceID != linked.EvidenceID {
+		t.Errorf("unexpected evidence: %#v", evidence)
+	}
+
+	if _, resp, err := ac.TransfersApi.GetTransferEvidence(context.TODO(), xfer.TransferID, ""); err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	fd, resp, err := ac.TransfersApi.GetEvidenceContent(context.TODO(), uploaded.EvidenceID, "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	bs, err := ioutil.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	if string(bs) != string(pdfContent) {
+		t.Errorf("unexpected content: %q", bs)
+	}
+
+	// links have no document to read
+	if _, resp, err := ac.TransfersApi.GetEvidenceContent(context.TODO(), linked.EvidenceID, "jane"); err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected error: %v", err)
+	}
+}
+
+func TestRouter__evidenceUploadsDisabled(t *testing.T) {
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, "organization", repo)
+
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), repo, &organization.MockRepository{}, mockCustomersClient(), mockDecryptor, mockStrategy).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	_, resp, err := c.TransfersApi.AddTransferEvidence(context.TODO(), xfer.TransferID, "organization", client.CreateAuthorizationEvidence{
+		Content: base64.StdEncoding.EncodeToString(pdfContent),
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	// links are still accepted
+	_, resp, err = c.TransfersApi.AddTransferEvidence(context.TODO(), xfer.TransferID, "organization", client.CreateAuthorizationEvidence{
+		Url: "https://example.com/signup/123",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// feeEntryDescription is the Company Entry Description of fee batches
+const feeEntryDescription = "FEE"
+
+// computeFee returns the fee in cents of a Transfer for amount by the rule of fees matching
+// its direction. It's zero without a matching rule. Fees which would take the amount and fee
+// together beyond the range of amounts are rejected.
+func computeFee(fees *client.FeeSchedule, amount client.Amount, debit bool) (int32, error) {
+	if fees == nil {
+		return 0, nil
+	}
+	rule := fees.Credit
+	if debit {
+		rule = fees.Debit
+	}
+	if rule == nil {
+		return 0, nil
+	}
+	percent := math.Round(float64(amount.Value) * float64(rule.Percentage) / 100)
+	if percent > math.MaxInt32 {
+		return 0, fmt.Errorf("fee of %v%% on %d is too large", rule.Percentage, amount.Value)
+	}
+	fee := int64(rule.Flat) + int64(percent)
+	if fee+int64(amount.Value) > math.MaxInt32 {
+		return 0, fmt.Errorf("fee of %d on %d is too large", fee, amount.Value)
+	}
+	return int32(fee), nil
+}
+
+// transferFee returns the fee transfer will be charged by the organization's fee schedule, so
+// limits and approvals are checked against the amount and fee together. The destination account
+// is only read when the organization charges fees.
+func transferFee(cfg *config.Config, orgRepo organization.Repository, customersClient customers.Client, orgID string, transfer *client.Transfer) (int32, error) {
+	orgConfig, err := orgRepo.GetConfig(orgID)
+	if err != nil {
+		return 0, route.Internal.New("getting org config: error getting config: %v", err)
+	}
+	if orgConfig == nil || orgConfig.Fees == nil {
+		return 0, nil
+	}
+	account, err := customersClient.FindAccount(orgID, transfer.Destination.CustomerID, transfer.Destination.AccountID)
+	if err != nil || account == nil {
+		return 0, route.Unavailable.New("error getting destination accountID=%s: %v", transfer.Destination.AccountID, err)
+	}
+	// Transfers crediting our ODFI debit the source customer's account
+	debit := account.RoutingNumber == cfg.ODFI.RoutingNumber
+	return computeFee(orgConfig.Fees, transfer.Amount, debit)
+}
+
+// withFee returns a copy of transfer whose amount includes fee, for checking limits.
+func withFee(transfer *client.Transfer, fee int32) *client.Transfer {
+	xfer := *transfer
+	xfer.Amount.Value += fee
+	return &xfer
+}
+
+// chargeFee returns the fee of transfer by the organization's fee schedule, or nil when
+// there's no fee. Fees collected as entries are added as another batch to the first of
+// files, which debits the source account and credits the fee account at the ODFI.
+//
+// Sources at the ODFI can't be debited with an entry, so their fees are collected as postings.
+func chargeFee(
+	cfg *config.Config,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	orgID, companyID string,
+	orgConfig *client.OrganizationConfiguration,
+	transfer *client.Transfer,
+	source fundflow.Source,
+	debit bool,
+	files []*ach.File,
+) (*client.TransferFee, error) {
+	if orgConfig == nil || orgConfig.Fees == nil {
+		return nil, nil
+	}
+	fees := orgConfig.Fees
+	value, err := computeFee(fees, transfer.Amount, debit)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: %v", err)
+	}
+	if value <= 0 {
+		return nil, nil
+	}
+	fee := &client.TransferFee{
+		Amount: client.Amount{
+			Currency: transfer.Amount.Currency,
+			Value:    value,
+		},
+		Collection: fees.Collection,
+	}
+	if fee.Collection == "" {
+		fee.Collection = client.NONE
+	}
+	if fee.Collection == client.NONE {
+		return fee, nil
+	}
+	if fees.Account == nil {
+		return nil, fmt.Errorf("creating transfer: fees are collected as %s without a fee account", fee.Collection)
+	}
+	account := *fees.Account
+	fee.Account = &account
+
+	if fee.Collection == client.ENTRY && source.Account.RoutingNumber == cfg.ODFI.RoutingNumber {
+		fee.Collection = client.POSTING
+	}
+	if fee.Collection == client.POSTING {
+		return fee, nil
+	}
+
+	destination, err := GetFundflowDestination(customersClient, accountDecryptor, account, orgID)
+	if err != nil {
+		return nil, route.Unavailable.New("creating transfer: error getting fee account: %v", err)
+	}
+	if destination.Account.RoutingNumber != cfg.ODFI.RoutingNumber {
+		return nil, fmt.Errorf("creating transfer: fee accountID=%s isn't at routing number %s", account.AccountID, cfg.ODFI.RoutingNumber)
+	}
+	feeTransfer := &client.Transfer{
+		TransferID:              transfer.TransferID,
+		Amount:                  fee.Amount,
+		Source:                  transfer.Source,
+		Destination:             account,
+		Description:             fmt.Sprintf("Fee for transfer %s", transfer.TransferID),
+		SameDay:                 transfer.SameDay,
+		CompanyEntryDescription: feeEntryDescription,
+	}
+	feeFiles, err := fundStrategy.Originate(companyID, feeTransfer, source, destination)
+	if err != nil {
+		return nil, fmt.Errorf("creating transfer: error originating fee: %w", err)
+	}
+	if len(files) == 0 || len(feeFiles) == 0 {
+		return nil, fmt.Errorf("creating transfer: no files to add fee entry to")
+	}
+	if err := appendBatches(files[0], feeFiles); err != nil {
+		return nil, fmt.Errorf("creating transfer: error adding fee entry: %v", err)
+	}
+	if traces := traceNumbers(feeFiles); len(traces) > 0 {
+		fee.TraceNumber = traces[0]
+	}
+	return fee, nil
+}
+
+// appendBatches moves the batches of others to the end of file.
+func appendBatches(file *ach.File, others []*ach.File) error {
+	for i := range others {
+		file.Batches = append(file.Batches, others[i].Batches...)
+	}
+	for i := range file.Batches {
+		file.Batches[i].GetHeader().BatchNumber = i + 1
+		if err := file.Batches[i].Create(); err != nil {
+			return fmt.Errorf("batch %d: %v", i+1, err)
+		}
+	}
+	return file.Create()
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+)
+
+func TestFees__computeFee(t *testing.T) {
+	fees := &client.FeeSchedule{
+		Credit: &client.FeeRule{Flat: 25},
+		Debit:  &client.FeeRule{Flat: 10, Percentage: 0.5},
+	}
+	amount := client.Amount{Currency: "USD", Value: 12345}
+
+	if fee, err := computeFee(fees, amount, false); fee != 25 || err != nil {
+		t.Errorf("credit fee=%d error=%v", fee, err)
+	}
+	if fee, err := computeFee(fees, amount, true); fee != 72 || err != nil { // 10 + 61.725
+		t.Errorf("debit fee=%d error=%v", fee, err)
+	}
+	fees.Credit = nil
+	if fee, err := computeFee(fees, amount, false); fee != 0 || err != nil {
+		t.Errorf("credit fee=%d error=%v", fee, err)
+	}
+	if fee, err := computeFee(nil, amount, true); fee != 0 || err != nil {
+		t.Errorf("fee=%d error=%v", fee, err)
+	}
+
+	// fees beyond the range of amounts are rejected
+	fees.Debit = &client.FeeRule{Flat: math.MaxInt32}
+	if fee, err := computeFee(fees, amount, true); err == nil {
+		t.Errorf("expected error, fee=%d", fee)
+	}
+	fees.Debit = &client.FeeRule{Percentage: 1e9}
+	if fee, err := computeFee(fees, amount, true); err == nil {
+		t.Errorf("expected error, fee=%d", fee)
+	}
+}
+
+func TestFees__transferFee(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	customersClient := mockCustomersClient()
+	orgRepo := &organization.MockRepository{}
+
+	transfer := &client.Transfer{
+		Amount:      client.Amount{Currency: "USD", Value: 5000},
+		Destination: client.Destination{CustomerID: destinationCustomerID, AccountID: destinationAccountID},
+	}
+	if fee, err := transferFee(cfg, orgRepo, customersClient, "organization", transfer); fee != 0 || err != nil {
+		t.Errorf("fee=%d error=%v", fee, err)
+	}
+
+	// the destination is at our ODFI, so the source is debited
+	orgRepo.Config = &client.OrganizationConfiguration{
+		Fees: &client.FeeSchedule{
+			Credit: &client.FeeRule{Flat: 25},
+			Debit:  &client.FeeRule{Flat: 100},
+		},
+	}
+	fee, err := transferFee(cfg, orgRepo, customersClient, "organization", transfer)
+	if fee != 100 || err != nil {
+		t.Errorf("fee=%d error=%v", fee, err)
+	}
+	if xfer := withFee(transfer, fee); xfer.Amount.Value != 5100 || transfer.Amount.Value != 5000 {
+		t.Errorf("unexpected amounts: %d and %d", xfer.Amount.Value, transfer.Amount.Value)
+	}
+}
+
+func TestFees__chargeFee(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI, nil)
+
+	feeAccountID := base.ID()
+	customersClient := mockCustomersClient()
+	customersClient.Accounts[sourceAccountID].RoutingNumber = "123456780"
+	customersClient.Accounts[feeAccountID] = &moovcustomers.Account{
+		AccountID:     feeAccountID,
+		RoutingNumber: "987654320",
+		Status:        moovcustomers.ACCOUNTSTATUS_VALIDATED,
+		Type:          moovcustomers.ACCOUNTTYPE_CHECKING,
+	}
+	orgConfig := &client.OrganizationConfiguration{
+		Fees: &client.FeeSchedule{
+			Debit:      &client.FeeRule{Flat: 100},
+			Collection: client.ENTRY,
+			Account: &client.Destination{
+				CustomerID: destinationCustomerID,
+				AccountID:  feeAccountID,
+			},
+		},
+	}
+
+	// debit the source customer at another FI
+	transfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    5000,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	source, err := GetFundflowSource(customersClient, mockDecryptor, transfer.Source, "organization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination, err := GetFundflowDestination(customersClient, mockDecryptor, transfer.Destination, "organization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := strategy.Originate("MOOV", transfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := traceNumbers(files)
+
+	fee, err := chargeFee(cfg, customersClient, mockDecryptor, strategy, "organization", "MOOV", orgConfig, transfer, source, true, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee == nil || fee.Amount.Value != 100 || fee.Collection != client.ENTRY || fee.Account.AccountID != feeAccountID {
+		t.Fatalf("unexpected fee: %#v", fee)
+	}
+	if len(files[0].Batches) != 2 {
+		t.Fatalf("unexpected %d batches", len(files[0].Batches))
+	}
+	bh := files[0].Batches[1].GetHeader()
+	entries := files[0].Batches[1].GetEntries()
+	if bh.BatchNumber != 2 || bh.CompanyEntryDescription != feeEntryDescription || len(entries) != 1 {
+		t.Fatalf("unexpected fee batch: %#v", bh)
+	}
+	if entries[0].Amount != 100 || entries[0].TraceNumber != fee.TraceNumber || entries[0].TraceNumber == traces[0] {
+		t.Errorf("unexpected fee entry: %#v", entries[0])
+	}
+	if err := files[0].Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// fees of sources at the ODFI are posted
+	source.Account.RoutingNumber = "987654320"
+	orgConfig.Fees.Credit = &client.FeeRule{Percentage: 1}
+	files, err = strategy.Originate("MOOV", transfer, source, fundflow.Destination{Account: moovcustomers.Account{RoutingNumber: "123456780"}, AccountNumber: "12345"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err = chargeFee(cfg, customersClient, mockDecryptor, strategy, "organization", "MOOV", orgConfig, transfer, source, false, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee == nil || fee.Amount.Value != 50 || fee.Collection != client.POSTING || fee.TraceNumber != "" || len(files[0].Batches) != 1 {
+		t.Errorf("unexpected fee: %#v", fee)
+	}
+
+	// fees which aren't collected are only recorded
+	orgConfig.Fees.Collection = ""
+	fee, err = chargeFee(cfg, customersClient, mockDecryptor, strategy, "organization", "MOOV", orgConfig, transfer, source, false, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee == nil || fee.Collection != client.NONE || fee.Account != nil {
+		t.Errorf("unexpected fee: %#v", fee)
+	}
+
+	// no fee
+	orgConfig.Fees.Credit = nil
+	if fee, err := chargeFee(cfg, customersClient, mockDecryptor, strategy, "organization", "MOOV", orgConfig, transfer, source, false, files); fee != nil || err != nil {
+		t.Errorf("fee=%#v error=%v", fee, err)
+	}
+}
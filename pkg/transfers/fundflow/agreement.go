@@ -0,0 +1,51 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// NewAgreementStrategy wraps strategy to reject Transfers whose files break the rules of
+// the ODFI's agreement. Transfers created before a rule was added are failed when their
+// file is merged instead.
+func NewAgreementStrategy(strategy Strategy, cfg config.ODFI) Strategy {
+	if strategy == nil || cfg.Agreement == nil {
+		return strategy
+	}
+	return &agreementStrategy{
+		Strategy: strategy,
+		cfg:      cfg,
+	}
+}
+
+type agreementStrategy struct {
+	Strategy
+
+	cfg config.ODFI
+}
+
+func (s *agreementStrategy) Originate(companyID string, xfer *client.Transfer, source Source, destination Destination) ([]*ach.File, error) {
+	files, err := s.Strategy.Originate(companyID, xfer, source, destination)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if loc := s.cfg.Cutoffs.Location(); loc != nil {
+		now = now.In(loc)
+	}
+	for i := range files {
+		if err := achx.CheckAgreement(s.cfg.Agreement, files[i], s.cfg.RoutingNumber, now); err != nil {
+			return nil, route.Forbidden.Wrap(err)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+)
+
+func TestAgreementStrategy(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &MockStrategy{Files: []*ach.File{file}}
+
+	cfg := config.ODFI{RoutingNumber: "076401251"}
+	if strategy := NewAgreementStrategy(mock, cfg); strategy != mock {
+		t.Errorf("unexpected strategy without an agreement: %T", strategy)
+	}
+
+	cfg.Agreement = &config.Agreement{
+		ProhibitedSECCodes: []string{"WEB"},
+	}
+	strategy := NewAgreementStrategy(mock, cfg)
+	files, err := strategy.Originate("", &client.Transfer{}, Source{}, Destination{})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("files=%d error=%v", len(files), err)
+	}
+
+	cfg.Agreement.ProhibitedSECCodes = []string{"PPD"}
+	strategy = NewAgreementStrategy(mock, cfg)
+	_, err = strategy.Originate("", &client.Transfer{}, Source{}, Destination{})
+	if code := route.ErrorCodeOf(err); code != route.Forbidden {
+		t.Errorf("unexpected error code %s: %v", code.Code, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
@@ -33,16 +34,20 @@ import (
 // These transfers involve one file with an optional return from the RDFI which should trigger
 // a reversal in the accounting ledger.
 type FirstParty struct {
-	cfg         config.ODFI
-	logger      log.Logger
-	timeService stime.TimeService
+	cfg          config.ODFI
+	logger       log.Logger
+	timeService  stime.TimeService
+	traceNumbers achx.TraceNumbers
 }
 
-func NewFirstPerson(logger log.Logger, cfg config.ODFI) Strategy {
+// NewFirstPerson returns a FirstParty Strategy. Entries are given trace numbers from
+// traceNumbers, or random trace numbers when it's nil.
+func NewFirstPerson(logger log.Logger, cfg config.ODFI, traceNumbers achx.TraceNumbers) Strategy {
 	return &FirstParty{
-		cfg:         cfg,
-		logger:      logger,
-		timeService: stime.NewSystemTimeService(),
+		cfg:          cfg,
+		logger:       logger,
+		timeService:  stime.NewSystemTimeService(),
+		traceNumbers: traceNumbers,
 	}
 }
 
@@ -81,6 +86,7 @@ func (fp *FirstParty) Originate(companyID string, xfer *client.Transfer, src Sou
 		CutoffTimezone:        fp.cfg.Cutoffs.Location(),
 		EffectiveEntryDate:    calculateEffectiveEntryDate(fp.cfg, fp.timeService, xfer.SameDay),
 		CompanyIdentification: companyID,
+		TraceNumbers:          fp.traceNumbers,
 	}
 	// Balance entries from transfers which appear to not be "account validation" (aka micro-deposits).
 	// Right now we're doing this by checking the amount which obviously isn't ideal.
@@ -100,7 +106,13 @@ func (fp *FirstParty) HandleReturn(returned *ach.File, xfer *client.Transfer) ([
 }
 
 func calculateEffectiveEntryDate(cfg config.ODFI, ss stime.TimeService, sameDay bool) base.Time {
-	when := base.NewTime(ss.Now().In(cfg.Cutoffs.Location()))
+	return EffectiveEntryDate(cfg, ss.Now(), sameDay)
+}
+
+// EffectiveEntryDate returns the date entries of a Transfer created at now are settled,
+// moving past the last cutoff window of the day and banking holidays.
+func EffectiveEntryDate(cfg config.ODFI, now time.Time, sameDay bool) base.Time {
+	when := base.NewTime(now.In(cfg.Cutoffs.Location()))
 	afterCutoffs := afterCutoffWindows(cfg.Cutoffs, when)
 
 	// If we're after-hours then handle the transfer's settlement for later on
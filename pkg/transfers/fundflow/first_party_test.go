@@ -21,7 +21,7 @@ func TestOriginate__DebitCheck(t *testing.T) {
 	cfg := config.Empty()
 	cfg.ODFI.RoutingNumber = "987654320"
 
-	fp := NewFirstPerson(cfg.Logger, cfg.ODFI)
+	fp := NewFirstPerson(cfg.Logger, cfg.ODFI, nil)
 
 	companyID := "MOOV"
 	xfer := &client.Transfer{}
@@ -46,7 +46,7 @@ func TestOriginate__DebitCheck(t *testing.T) {
 
 func TestOriginate__RoutingNumberErr(t *testing.T) {
 	cfg := config.Empty() // leave off RoutingNumber for first test
-	fp := NewFirstPerson(log.NewNopLogger(), cfg.ODFI)
+	fp := NewFirstPerson(log.NewNopLogger(), cfg.ODFI, nil)
 
 	src := Source{
 		Account: customers.Account{
@@ -84,7 +84,7 @@ func TestOriginateFull(t *testing.T) {
 	cfg := config.Empty()
 	cfg.ODFI.RoutingNumber = "987654320"
 
-	fp := NewFirstPerson(cfg.Logger, cfg.ODFI)
+	fp := NewFirstPerson(cfg.Logger, cfg.ODFI, nil)
 
 	companyID := "MOOV"
 	xfer := &client.Transfer{
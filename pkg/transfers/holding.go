@@ -0,0 +1,107 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// holdingTotals are the amounts in cents moved in and out of a holding account. Collections are
+// Transfers into the holding account and payouts are Transfers from it.
+type holdingTotals struct {
+	collected          int64
+	pendingCollections int64
+	paidOut            int64
+}
+
+// add counts xfer towards the totals of the holding account accountID
+func (t *holdingTotals) add(accountID string, xfer *client.Transfer) {
+	value := int64(xfer.Amount.Value)
+	if xfer.Destination.AccountID == accountID {
+		switch xfer.Status {
+		case client.PROCESSED:
+			t.collected += value
+		case client.PENDING, client.REVIEWABLE:
+			t.pendingCollections += value
+		}
+	}
+	if xfer.Source.AccountID == accountID && xfer.Status != client.FAILED && xfer.Status != client.CANCELED {
+		t.paidOut += value
+	}
+}
+
+// available is what can still be paid out. Pending collections aren't included as they
+// could be returned.
+func (t holdingTotals) available() int64 {
+	return t.collected - t.paidOut
+}
+
+func GetHoldingBalance(cfg *config.Config, repo Repository, orgRepo organization.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
+		if err != nil {
+			responder.Problem(route.Internal.New("getting holding balance: error getting config: %v", err))
+			return
+		}
+		if orgConfig == nil || orgConfig.HoldingAccount == nil {
+			responder.Problem(route.NotFound.New("getting holding balance: no holdingAccount is configured"))
+			return
+		}
+		account := *orgConfig.HoldingAccount
+
+		totals, err := repo.getHoldingTotals(r.Context(), responder.OrganizationID, account.AccountID)
+		if err != nil {
+			responder.Problem(route.Internal.New("getting holding balance: %v", err))
+			return
+		}
+		balance := client.HoldingBalance{
+			Account:            account,
+			Collected:          holdingAmount(totals.collected),
+			PendingCollections: holdingAmount(totals.pendingCollections),
+			PaidOut:            holdingAmount(totals.paidOut),
+			Available:          holdingAmount(totals.available()),
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(balance)
+		})
+	}
+}
+
+func holdingAmount(value int64) client.Amount {
+	return client.Amount{
+		Currency: "USD",
+		Value:    int32(value),
+	}
+}
+
+// checkHoldingPayout rejects payouts from the organization's holding account of more than its
+// available balance. Transfers from other accounts aren't checked.
+func checkHoldingPayout(ctx context.Context, repo Repository, orgRepo organization.Repository, orgID string, source client.Source, amount int64) error {
+	orgConfig, err := orgRepo.GetConfig(orgID)
+	if err != nil {
+		return route.Internal.New("getting org config: error getting config: %v", err)
+	}
+	if orgConfig == nil || orgConfig.HoldingAccount == nil || orgConfig.HoldingAccount.AccountID != source.AccountID {
+		return nil
+	}
+	totals, err := repo.getHoldingTotals(ctx, orgID, source.AccountID)
+	if err != nil {
+		return route.Internal.New("reading holding balance: %v", err)
+	}
+	if available := totals.available(); amount > available {
+		return route.InvalidRequest.New("payout of %d is more than the holding account's available balance of %d", amount, available)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHolding__totals(t *testing.T) {
+	holding := base.ID()
+	xfer := func(source, destination string, value int32, status client.TransferStatus) *client.Transfer {
+		return &client.Transfer{
+			Amount:      client.Amount{Currency: "USD", Value: value},
+			Source:      client.Source{AccountID: source},
+			Destination: client.Destination{AccountID: destination},
+			Status:      status,
+		}
+	}
+
+	var totals holdingTotals
+	totals.add(holding, xfer(base.ID(), holding, 1000, client.PROCESSED))
+	totals.add(holding, xfer(base.ID(), holding, 500, client.PENDING))
+	totals.add(holding, xfer(base.ID(), holding, 200, client.FAILED))
+	totals.add(holding, xfer(holding, base.ID(), 300, client.PENDING))
+	totals.add(holding, xfer(holding, base.ID(), 400, client.CANCELED))
+	totals.add(holding, xfer(base.ID(), base.ID(), 800, client.PROCESSED))
+
+	if totals.collected != 1000 || totals.pendingCollections != 500 || totals.paidOut != 300 {
+		t.Errorf("unexpected totals: %#v", totals)
+	}
+	if n := totals.available(); n != 700 {
+		t.Errorf("available=%d", n)
+	}
+}
+
+func TestRouter__holdingBalance(t *testing.T) {
+	repo := NewInMemoryRepo()
+	orgRepo := &organization.MockRepository{}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	// organizations without a holding account
+	_, resp, err := c.TransfersApi.GetHoldingBalance(context.TODO(), "organization", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	orgRepo.Config = &client.OrganizationConfiguration{
+		HoldingAccount: &client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+	}
+	collect := client.CreateTransfer{
+		Amount: client.Amount{Currency: "USD", Value: 1000},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: *orgRepo.Config.HoldingAccount,
+		Description: "order 1001",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", collect, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	balance, resp, err := c.TransfersApi.GetHoldingBalance(context.TODO(), "organization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if balance.PendingCollections.Value != 1000 || balance.Available.Value != 0 {
+		t.Errorf("unexpected balance: %#v", balance)
+	}
+
+	// payouts can't be more than what's collected
+	payout := client.CreateTransfer{
+		Amount: client.Amount{Currency: "USD", Value: 600},
+		Source: client.Source{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Description: "seller payout",
+	}
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", payout, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", payout, nil); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	balance, resp, err = c.TransfersApi.GetHoldingBalance(context.TODO(), "organization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if balance.Collected.Value != 1000 || balance.PaidOut.Value != 600 || balance.Available.Value != 400 {
+		t.Errorf("unexpected balance: %#v", balance)
+	}
+	if balance.Account.AccountID != destinationAccountID {
+		t.Errorf("unexpected account: %#v", balance.Account)
+	}
+}
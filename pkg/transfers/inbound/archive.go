@@ -0,0 +1,181 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+var (
+	filesSkipped = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "inbound_files_skipped",
+		Help: "Counter of downloaded files skipped because they were already processed",
+	}, []string{"kind"})
+)
+
+// archiver records each processed file so files left on the remote server aren't
+// processed again, and optionally copies them into a bucket.
+type archiver struct {
+	logger log.Logger
+	repo   Repository
+	bucket *blob.Bucket
+}
+
+func newArchiver(logger log.Logger, repo Repository, cfg *config.InboundArchive) (*archiver, error) {
+	arc := &archiver{
+		logger: logger,
+		repo:   repo,
+	}
+	if cfg != nil {
+		bucket, err := blob.OpenBucket(context.Background(), cfg.BucketURI)
+		if err != nil {
+			return nil, fmt.Errorf("opening archive bucket: %v", err)
+		}
+		arc.bucket = bucket
+	}
+	return arc, nil
+}
+
+// skipProcessed marks downloaded files whose contents were processed before so they
+// are not processed again.
+func (arc *archiver) skipProcessed(agent upload.Agent, dl *downloadedFiles) error {
+	paths, err := dl.files()
+	if err != nil {
+		return err
+	}
+	for i := range paths {
+		hash, _, err := hashFile(paths[i])
+		if err != nil {
+			return err
+		}
+		prev, err := arc.repo.getArchivedFile(hash)
+		if err != nil {
+			return fmt.Errorf("problem reading archived file: %v", err)
+		}
+		if prev == nil {
+			continue
+		}
+		if dl.skip == nil {
+			dl.skip = make(map[string]bool)
+		}
+		dl.skip[paths[i]] = true
+
+		kind := fileKind(agent, dl, paths[i])
+		filesSkipped.With("kind", kind).Add(1)
+		arc.logger.Logf("skipping %s file %s which was processed as %s at %v", kind, filepath.Base(paths[i]), prev.Filename, prev.ProcessedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// record saves each processed file in the archive.
+func (arc *archiver) record(agent upload.Agent, dl *downloadedFiles) error {
+	var el base.ErrorList
+	for i := range dl.processed {
+		if err := arc.recordFile(agent, dl, dl.processed[i]); err != nil {
+			el.Add(fmt.Errorf("archiving %s: %v", filepath.Base(dl.processed[i]), err))
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (arc *archiver) recordFile(agent upload.Agent, dl *downloadedFiles, path string) error {
+	hash, size, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	file := ArchivedFile{
+		Filename:    filepath.Base(path),
+		Kind:        fileKind(agent, dl, path),
+		FileHash:    hash,
+		Size:        size,
+		ProcessedAt: now,
+	}
+	if arc.bucket != nil {
+		file.ArchiveKey = fmt.Sprintf("%s/%s/%s", file.Kind, now.Format("2006-01-02"), file.Filename)
+		if err := arc.copyToBucket(file.ArchiveKey, path); err != nil {
+			return err
+		}
+	}
+	return arc.repo.saveArchivedFile(file)
+}
+
+func (arc *archiver) copyToBucket(key, path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	w, err := arc.bucket.NewWriter(context.Background(), key, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, fd); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (arc *archiver) Close() error {
+	if arc == nil || arc.bucket == nil {
+		return nil
+	}
+	return arc.bucket.Close()
+}
+
+// fileKind returns "return" for files downloaded from the agent's ReturnPath and "inbound" otherwise.
+func fileKind(agent upload.Agent, dl *downloadedFiles, path string) string {
+	if filepath.Dir(path) == filepath.Join(dl.dir, agent.ReturnPath()) {
+		return "return"
+	}
+	return "inbound"
+}
+
+func hashFile(path string) (string, int64, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, fd)
+	if err != nil {
+		return "", 0, fmt.Errorf("hashing %s: %v", filepath.Base(path), err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func hashContents(bs []byte) (string, int64) {
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:]), int64(len(bs))
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestArchiver(t *testing.T) {
+	agent := &upload.MockAgent{}
+	dir := testDir(t)
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	returnPath := filepath.Join(dir, agent.ReturnPath(), "return-WEB.ach")
+	os.MkdirAll(filepath.Dir(returnPath), 0777)
+	if err := ioutil.WriteFile(returnPath, bs, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &MockRepository{}
+	arc, err := newArchiver(log.NewNopLogger(), repo, &config.InboundArchive{BucketURI: "mem://"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arc.Close()
+
+	// first download processes the file
+	dl := &downloadedFiles{dir: dir}
+	if err := arc.skipProcessed(agent, dl); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessFiles(dl, SetupProcessors(&MockProcessor{}), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(dl.processed) != 1 {
+		t.Fatalf("unexpected processed files: %v", dl.processed)
+	}
+	if err := arc.record(agent, dl); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Files) != 1 {
+		t.Fatalf("unexpected archived files: %#v", repo.Files)
+	}
+	archived := repo.Files[0]
+	if archived.Filename != "return-WEB.ach" || archived.Kind != "return" || archived.Size != int64(len(bs)) {
+		t.Errorf("unexpected archived file: %#v", archived)
+	}
+	copied, err := arc.bucket.ReadAll(context.Background(), archived.ArchiveKey)
+	if err != nil || len(copied) != len(bs) {
+		t.Errorf("unexpected archived copy (%d bytes): %v", len(copied), err)
+	}
+
+	// the same file downloaded again is skipped
+	dl = &downloadedFiles{dir: dir}
+	if err := arc.skipProcessed(agent, dl); err != nil {
+		t.Fatal(err)
+	}
+	if !dl.skip[returnPath] {
+		t.Errorf("expected %s to be skipped", returnPath)
+	}
+	if err := ProcessFiles(dl, SetupProcessors(&MockProcessor{}), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(dl.processed) != 0 {
+		t.Errorf("unexpected processed files: %v", dl.processed)
+	}
+}
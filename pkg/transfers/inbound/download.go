@@ -51,6 +51,46 @@ type downloaderImpl struct {
 // These are designed to be deleted after all files are processed.
 type downloadedFiles struct {
 	dir string
+
+	// skip are paths of files which were processed by an earlier download
+	skip map[string]bool
+
+	// processed are paths of files every processor handled without an error
+	processed []string
+
+	// unparseable are files which couldn't be read as ACH files
+	unparseable []unparseableFile
+}
+
+type unparseableFile struct {
+	path string
+	err  error
+}
+
+// files returns the paths of downloaded files in dir and its sub-directories.
+func (d *downloadedFiles) files() ([]string, error) {
+	fds, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", d.dir, err)
+	}
+	var paths []string
+	for i := range fds {
+		path := filepath.Join(d.dir, fds[i].Name())
+		if !fds[i].IsDir() {
+			paths = append(paths, path)
+			continue
+		}
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+		for j := range infos {
+			if !infos[j].IsDir() {
+				paths = append(paths, filepath.Join(path, infos[j].Name()))
+			}
+		}
+	}
+	return paths, nil
 }
 
 func (d *downloadedFiles) deleteFiles() error {
@@ -109,7 +149,7 @@ func (dl *downloaderImpl) CopyFilesFromRemote(agent upload.Agent) (*downloadedFi
 	files, err := agent.GetInboundFiles()
 	dl.logger.Logf("found %d inbound files", len(files))
 	if err != nil {
-		return out, fmt.Errorf("problem downloading inbound files: %v", err)
+		return out, fmt.Errorf("problem downloading inbound files: %w", err)
 	}
 	filesDownloaded.With("kind", "inbound").Add(float64(len(files)))
 	if err := dl.writeFiles(filepath.Join(out.dir, agent.InboundPath()), files); err != nil {
@@ -120,7 +160,7 @@ func (dl *downloaderImpl) CopyFilesFromRemote(agent upload.Agent) (*downloadedFi
 	files, err = agent.GetReturnFiles()
 	dl.logger.Logf("found %d return files", len(files))
 	if err != nil {
-		return out, fmt.Errorf("problem downloading return files: %v", err)
+		return out, fmt.Errorf("problem downloading return files: %w", err)
 	}
 	filesDownloaded.With("kind", "return").Add(float64(len(files)))
 	if err := dl.writeFiles(filepath.Join(out.dir, agent.ReturnPath()), files); err != nil {
@@ -0,0 +1,111 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+)
+
+type MockRepository struct {
+	Files []ArchivedFile
+
+	Quarantined []mockQuarantinedFile
+
+	Err error
+}
+
+type mockQuarantinedFile struct {
+	file     admin.QuarantinedFile
+	fileHash string
+	contents []byte
+}
+
+func (r *MockRepository) getArchivedFile(fileHash string) (*ArchivedFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := len(r.Files) - 1; i >= 0; i-- {
+		if r.Files[i].FileHash == fileHash {
+			return &r.Files[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) saveArchivedFile(file ArchivedFile) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Files = append(r.Files, file)
+	return nil
+}
+
+func (r *MockRepository) getQuarantinedFiles() ([]*admin.QuarantinedFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var files []*admin.QuarantinedFile
+	for i := range r.Quarantined {
+		if r.Quarantined[i].file.Status == admin.QUARANTINED {
+			file := r.Quarantined[i].file
+			files = append(files, &file)
+		}
+	}
+	return files, nil
+}
+
+func (r *MockRepository) getQuarantinedFile(quarantineID string) (*admin.QuarantinedFile, []byte, error) {
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	for i := range r.Quarantined {
+		if r.Quarantined[i].file.QuarantineID == quarantineID {
+			file := r.Quarantined[i].file
+			return &file, r.Quarantined[i].contents, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func (r *MockRepository) getQuarantinedFileByHash(fileHash string) (*admin.QuarantinedFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := len(r.Quarantined) - 1; i >= 0; i-- {
+		if r.Quarantined[i].fileHash == fileHash {
+			file := r.Quarantined[i].file
+			return &file, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) saveQuarantinedFile(file admin.QuarantinedFile, fileHash string, contents []byte) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Quarantined = append(r.Quarantined, mockQuarantinedFile{
+		file:     file,
+		fileHash: fileHash,
+		contents: contents,
+	})
+	return nil
+}
+
+func (r *MockRepository) updateQuarantinedFile(quarantineID string, status admin.QuarantinedFileStatus, errMsg string, retried time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Quarantined {
+		if r.Quarantined[i].file.QuarantineID == quarantineID {
+			r.Quarantined[i].file.Status = status
+			r.Quarantined[i].file.Error = errMsg
+			r.Quarantined[i].file.Retried = &retried
+		}
+	}
+	return nil
+}
@@ -4,6 +4,10 @@
 
 package inbound
 
+import (
+	"github.com/moov-io/paygate/pkg/jobs"
+)
+
 type MockScheduler struct {
 	Err error
 }
@@ -13,3 +17,5 @@ func (s *MockScheduler) Start() error {
 }
 
 func (s *MockScheduler) Shutdown() {}
+
+func (s *MockScheduler) UseJobs(reg *jobs.Registry) {}
@@ -6,7 +6,6 @@ package inbound
 
 import (
 	"fmt"
-	"io/ioutil"
 	"path/filepath"
 
 	"github.com/moov-io/ach"
@@ -39,31 +38,78 @@ func (pcs Processors) HandleAll(file *ach.File) error {
 	return el
 }
 
-func ProcessFiles(dl *downloadedFiles, fileProcessors Processors) error {
+type parsedFile struct {
+	file *ach.File
+	err  error
+}
+
+// ProcessFiles parses each downloaded file with up to workers files parsed at once. Files
+// are handled in order and at most workers parsed files are held in memory.
+func ProcessFiles(dl *downloadedFiles, fileProcessors Processors, workers int) error {
 	var el base.ErrorList
-	fds, err := ioutil.ReadDir(dl.dir)
+	paths, err := dl.files()
 	if err != nil {
-		return fmt.Errorf("reading %s: %v", dl.dir, err)
+		return err
 	}
-	for i := range fds {
-		file, err := ach.ReadFile(filepath.Join(dl.dir, fds[i].Name()))
-		if err != nil {
-			// Some return files don't contain FileHeader info, but can be processed as there
-			// are batches with entries. Let's continue to process those, but skip other errors.
-			if !base.Has(err, ach.ErrFileHeader) {
-				el.Add(fmt.Errorf("problem opening %s: %v", fds[i].Name(), err))
-				continue
-			}
+	var pending []string
+	for i := range paths {
+		if !dl.skip[paths[i]] {
+			pending = append(pending, paths[i])
+		}
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]chan parsedFile, len(pending))
+	for i := range results {
+		results[i] = make(chan parsedFile, 1)
+	}
+	slots := make(chan struct{}, workers)
+	go func() {
+		for i := range pending {
+			slots <- struct{}{}
+			go func(i int) {
+				file, err := ach.ReadFile(pending[i])
+				results[i] <- parsedFile{file: file, err: err}
+			}(i)
 		}
-		if err := fileProcessors.HandleAll(file); err != nil {
-			el.Add(fmt.Errorf("processing %s error: %v", fds[i].Name(), err))
+	}()
+
+	for i := range pending {
+		res := <-results[i]
+		if isUnparseable(res.err) {
+			// Files which aren't ACH files are set aside so the remaining files are still processed
+			<-slots
+			dl.unparseable = append(dl.unparseable, unparseableFile{path: pending[i], err: res.err})
 			continue
 		}
+		err := handleParsed(fileProcessors, filepath.Base(pending[i]), res)
+		<-slots // release after handling so parsed files don't pile up
+		if err != nil {
+			el.Add(err)
+		} else {
+			dl.processed = append(dl.processed, pending[i])
+		}
 	}
 
 	if el.Empty() {
 		return nil
 	}
 	return el
+}
 
+// isUnparseable returns true for errors reading a file which prevent it from being processed.
+//
+// Some return files don't contain FileHeader info, but can be processed as there
+// are batches with entries. Let's continue to process those.
+func isUnparseable(err error) bool {
+	return err != nil && !base.Has(err, ach.ErrFileHeader)
+}
+
+func handleParsed(fileProcessors Processors, name string, res parsedFile) error {
+	if err := fileProcessors.HandleAll(res.file); err != nil {
+		return fmt.Errorf("processing %s error: %v", name, err)
+	}
+	return nil
 }
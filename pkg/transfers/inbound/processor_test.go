@@ -5,9 +5,12 @@
 package inbound
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"testing"
+
+	"github.com/moov-io/ach"
 )
 
 func TestProcessor__process(t *testing.T) {
@@ -21,7 +24,41 @@ func TestProcessor__process(t *testing.T) {
 	// By reading a file without ACH FileHeaders we still want to try and process
 	// Batches inside of it if any are found, so reading this kind of file shouldn't
 	// return an error from reading the file.
-	if err := ProcessFiles(&downloadedFiles{dir: dir}, processors); err != nil {
+	if err := ProcessFiles(&downloadedFiles{dir: dir}, processors, 1); err != nil {
 		t.Error(err)
 	}
 }
+
+type countingProcessor struct {
+	handled int
+}
+
+func (pc *countingProcessor) Type() string {
+	return "counting"
+}
+
+func (pc *countingProcessor) Handle(file *ach.File) error {
+	pc.handled++
+	return nil
+}
+
+func TestProcessor__processConcurrently(t *testing.T) {
+	dir := testDir(t)
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.ach", i)), bs, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counter := &countingProcessor{}
+	if err := ProcessFiles(&downloadedFiles{dir: dir}, SetupProcessors(counter), 4); err != nil {
+		t.Fatal(err)
+	}
+	if counter.handled != 10 {
+		t.Errorf("handled %d files", counter.handled)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	filesQuarantined = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "inbound_files_quarantined",
+		Help: "Counter of downloaded files quarantined because they couldn't be parsed",
+	}, []string{"kind"})
+)
+
+// Quarantine keeps downloaded files which couldn't be parsed so they can be inspected
+// and retried later from the admin HTTP server.
+type Quarantine struct {
+	cfg    *config.Config
+	logger log.Logger
+
+	repo       Repository
+	processors Processors
+	notifier   notify.Sender
+}
+
+func NewQuarantine(cfg *config.Config, repo Repository, processors Processors) (*Quarantine, error) {
+	notifier, err := notify.NewMultiSender(cfg.Logger, cfg.Pipeline.Notifications)
+	if err != nil {
+		return nil, err
+	}
+	return &Quarantine{
+		cfg:        cfg,
+		logger:     cfg.Logger,
+		repo:       repo,
+		processors: processors,
+		notifier:   notifier,
+	}, nil
+}
+
+// quarantineFiles saves each unparseable file from a download and sends an alert for it.
+// Files already in quarantine are not saved or alerted on again.
+func (q *Quarantine) quarantineFiles(agent upload.Agent, dl *downloadedFiles) error {
+	if q == nil {
+		return nil
+	}
+	var el base.ErrorList
+	for i := range dl.unparseable {
+		if err := q.quarantineFile(agent, dl, dl.unparseable[i]); err != nil {
+			el.Add(fmt.Errorf("quarantining %s: %v", filepath.Base(dl.unparseable[i].path), err))
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (q *Quarantine) quarantineFile(agent upload.Agent, dl *downloadedFiles, unparseable unparseableFile) error {
+	hash, _, err := hashFile(unparseable.path)
+	if err != nil {
+		return err
+	}
+	prev, err := q.repo.getQuarantinedFileByHash(hash)
+	if err != nil {
+		return err
+	}
+	if prev != nil {
+		q.logger.Logf("%s is already quarantined as %s", filepath.Base(unparseable.path), prev.QuarantineID)
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(unparseable.path)
+	if err != nil {
+		return err
+	}
+	file := admin.QuarantinedFile{
+		QuarantineID: base.ID(),
+		Filename:     filepath.Base(unparseable.path),
+		Kind:         fileKind(agent, dl, unparseable.path),
+		Error:        unparseable.err.Error(),
+		Status:       admin.QUARANTINED,
+		Created:      time.Now(),
+	}
+	if err := q.repo.saveQuarantinedFile(file, hash, contents); err != nil {
+		return err
+	}
+	filesQuarantined.With("kind", file.Kind).Add(1)
+	q.logger.LogErrorf("quarantined %s file %s as %s: %v", file.Kind, file.Filename, file.QuarantineID, unparseable.err)
+
+	msg := &notify.Message{
+		Direction: notify.Download,
+		Filename:  file.Filename,
+		Hostname:  agent.Hostname(),
+	}
+	if err := q.notifier.Critical(msg); err != nil {
+		q.logger.LogErrorf("problem sending critical notification for file=%s: %v", file.Filename, err)
+	}
+	return nil
+}
+
+// retry parses a quarantined file again and processes it once it's readable.
+func (q *Quarantine) retry(file *admin.QuarantinedFile, contents []byte) error {
+	now := time.Now()
+
+	parsed, err := ach.NewReader(bytes.NewReader(contents)).Read()
+	if isUnparseable(err) {
+		if uerr := q.repo.updateQuarantinedFile(file.QuarantineID, admin.QUARANTINED, err.Error(), now); uerr != nil {
+			return uerr
+		}
+		file.Error, file.Retried = err.Error(), &now
+		return route.InvalidRequest.New("problem parsing file: %v", err)
+	}
+
+	if err := q.processors.HandleAll(&parsed); err != nil {
+		return route.Internal.New("processing %s error: %v", file.Filename, err)
+	}
+	if err := q.repo.updateQuarantinedFile(file.QuarantineID, admin.REPROCESSED, file.Error, now); err != nil {
+		return err
+	}
+	file.Status, file.Retried = admin.REPROCESSED, &now
+
+	// Record the file so a copy left on the remote server isn't processed again
+	hash, size := hashContents(contents)
+	return q.repo.saveArchivedFile(ArchivedFile{
+		Filename:    file.Filename,
+		Kind:        file.Kind,
+		FileHash:    hash,
+		Size:        size,
+		ProcessedAt: now,
+	})
+}
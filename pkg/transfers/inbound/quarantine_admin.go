@@ -0,0 +1,79 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"encoding/json"
+	"net/http"
+
+	moovadmin "github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (q *Quarantine) RegisterRoutes(svc *moovadmin.Server) {
+	svc.AddHandler("/inbound/quarantine", q.getQuarantinedFiles())
+	svc.AddHandler("/inbound/quarantine/{quarantineId}/retry", q.retryQuarantinedFile())
+}
+
+func (q *Quarantine) getQuarantinedFiles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(q.cfg, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		files, err := q.repo.getQuarantinedFiles()
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if files == nil {
+			files = []*admin.QuarantinedFile{}
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(files)
+		})
+	}
+}
+
+func (q *Quarantine) retryQuarantinedFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(q.cfg, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		file, contents, err := q.repo.getQuarantinedFile(route.ReadPathID("quarantineId", r))
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if file == nil {
+			responder.Problem(route.NotFound.New("quarantined file not found"))
+			return
+		}
+		if file.Status != admin.QUARANTINED {
+			responder.Problem(route.InvalidRequest.New("file was already %s", file.Status))
+			return
+		}
+
+		if err := q.retry(file, contents); err != nil {
+			responder.Logger().LogErrorf("problem retrying quarantined file %s: %v", file.QuarantineID, err)
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(file)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/moov-io/base/log"
+)
+
+func readTestACHFile(t *testing.T) []byte {
+	t.Helper()
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+// corruptACHFile truncates the batch header so the file can't be parsed
+func corruptACHFile(bs []byte) []byte {
+	lines := strings.Split(string(bs), "\n")
+	lines[1] = lines[1][:50]
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func setupQuarantine(repo Repository, processors Processors) (*Quarantine, *notify.MockSender) {
+	notifier := &notify.MockSender{}
+	return &Quarantine{
+		cfg:        config.Empty(),
+		logger:     log.NewNopLogger(),
+		repo:       repo,
+		processors: processors,
+		notifier:   notifier,
+	}, notifier
+}
+
+func TestQuarantine(t *testing.T) {
+	agent := &upload.MockAgent{}
+	dir := testDir(t)
+
+	bs := readTestACHFile(t)
+	inboundDir := filepath.Join(dir, agent.InboundPath())
+	os.MkdirAll(inboundDir, 0777)
+	if err := ioutil.WriteFile(filepath.Join(inboundDir, "bad.ach"), corruptACHFile(bs), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(inboundDir, "good.ach"), bs, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	counter := &countingProcessor{}
+	repo := &MockRepository{}
+	quarantine, notifier := setupQuarantine(repo, SetupProcessors(counter))
+
+	// the unparseable file doesn't stop the good file from being processed
+	dl := &downloadedFiles{dir: dir}
+	if err := ProcessFiles(dl, SetupProcessors(counter), 2); err != nil {
+		t.Fatal(err)
+	}
+	if counter.handled != 1 || len(dl.processed) != 1 || len(dl.unparseable) != 1 {
+		t.Fatalf("handled=%d processed=%v unparseable=%v", counter.handled, dl.processed, dl.unparseable)
+	}
+
+	if err := quarantine.quarantineFiles(agent, dl); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Quarantined) != 1 {
+		t.Fatalf("unexpected quarantined files: %#v", repo.Quarantined)
+	}
+	file := repo.Quarantined[0].file
+	if file.Filename != "bad.ach" || file.Kind != "inbound" || file.Status != admin.QUARANTINED || file.Error == "" {
+		t.Errorf("unexpected file: %#v", file)
+	}
+	if !notifier.CriticalWasCalled() || notifier.CapturedMessage().Filename != "bad.ach" {
+		t.Errorf("expected critical notification: %#v", notifier.CapturedMessage())
+	}
+
+	// the same file downloaded again isn't quarantined twice
+	if err := quarantine.quarantineFiles(agent, dl); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Quarantined) != 1 {
+		t.Errorf("unexpected quarantined files: %#v", repo.Quarantined)
+	}
+}
+
+func TestQuarantine__routes(t *testing.T) {
+	bs := readTestACHFile(t)
+
+	counter := &countingProcessor{}
+	repo := &MockRepository{}
+	quarantine, _ := setupQuarantine(repo, SetupProcessors(counter))
+
+	svc, c := testclient.Admin(t)
+	quarantine.RegisterRoutes(svc)
+
+	files, resp, err := c.InboundApi.GetQuarantinedFiles(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(files) != 0 {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+
+	err = repo.saveQuarantinedFile(admin.QuarantinedFile{
+		QuarantineID: "quarantineID",
+		Filename:     "bad.ach",
+		Kind:         "inbound",
+		Error:        "bad record",
+		Status:       admin.QUARANTINED,
+	}, "abc123", corruptACHFile(bs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, resp, err = c.InboundApi.GetQuarantinedFiles(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(files) != 1 || files[0].QuarantineID != "quarantineID" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+
+	// the file still can't be parsed
+	_, resp, err = c.InboundApi.RetryQuarantinedFile(context.TODO(), "quarantineID")
+	if resp == nil || resp.StatusCode != http.StatusBadRequest || err == nil {
+		t.Fatalf("expected failed retry: %v", err)
+	}
+	resp.Body.Close()
+	if retried := repo.Quarantined[0].file; retried.Status != admin.QUARANTINED || retried.Retried == nil {
+		t.Errorf("unexpected file: %#v", retried)
+	}
+
+	// fix the file and retry it
+	repo.Quarantined[0].contents = bs
+	file, resp, err := c.InboundApi.RetryQuarantinedFile(context.TODO(), "quarantineID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if file.Status != admin.REPROCESSED || counter.handled != 1 {
+		t.Errorf("file=%#v handled=%d", file, counter.handled)
+	}
+	if len(repo.Files) != 1 || repo.Files[0].Filename != "bad.ach" {
+		t.Errorf("unexpected archived files: %#v", repo.Files)
+	}
+
+	// reprocessed files can't be retried again
+	_, resp, err = c.InboundApi.RetryQuarantinedFile(context.TODO(), "quarantineID")
+	if resp == nil || resp.StatusCode != http.StatusBadRequest || err == nil {
+		t.Fatalf("expected failed retry: %v", err)
+	}
+	resp.Body.Close()
+
+	_, resp, err = c.InboundApi.RetryQuarantinedFile(context.TODO(), "missing")
+	if resp == nil || resp.StatusCode != http.StatusBadRequest || err == nil {
+		t.Fatalf("expected missing file: %v", err)
+	}
+	resp.Body.Close()
+}
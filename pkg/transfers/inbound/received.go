@@ -0,0 +1,228 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/accounts"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/received"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	receivedEntriesProcessed = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "received_entries_processed",
+		Help: "Counter of forward EntryDetail records received for our routing numbers",
+	}, []string{"origin", "destination", "status"})
+)
+
+// unknownAccountReturnCode is sent back for entries to accounts we don't hold.
+const unknownAccountReturnCode = "R03" // No Account/Unable to Locate Account
+
+// receivedProcessor posts forward entries addressed to our routing numbers to the
+// Accounts service and returns entries for accounts which aren't found.
+type receivedProcessor struct {
+	logger log.Logger
+
+	rdfi config.RDFI
+	odfi config.ODFI
+
+	// routingNumbers holds our routing numbers keyed by their first 8 digits,
+	// which is how entries identify their RDFI.
+	routingNumbers map[string]string
+
+	accounts     accounts.Client
+	repo         received.Repository
+	pub          pipeline.XferPublisher
+	traceNumbers achx.TraceNumbers
+}
+
+func NewReceivedProcessor(
+	cfg *config.Config,
+	accountsClient accounts.Client,
+	repo received.Repository,
+	pub pipeline.XferPublisher,
+	traceNumbers achx.TraceNumbers,
+) *receivedProcessor {
+	pc := &receivedProcessor{
+		logger:         cfg.Logger,
+		odfi:           cfg.ODFI,
+		routingNumbers: make(map[string]string),
+		accounts:       accountsClient,
+		repo:           repo,
+		pub:            pub,
+		traceNumbers:   traceNumbers,
+	}
+	if cfg.RDFI != nil {
+		pc.rdfi = *cfg.RDFI
+		for _, rtn := range cfg.RDFI.RoutingNumbers {
+			pc.routingNumbers[achx.ABA8(rtn)] = rtn
+		}
+	}
+	return pc
+}
+
+func (pc *receivedProcessor) Type() string {
+	return "received"
+}
+
+func (pc *receivedProcessor) Handle(file *ach.File) error {
+	if len(pc.routingNumbers) == 0 {
+		return nil
+	}
+
+	var el base.ErrorList
+	for i := range file.Batches {
+		bh := file.Batches[i].GetHeader()
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			rtn, ok := pc.routingNumbers[entries[j].RDFIIdentification]
+			if !ok || !isForwardEntry(entries[j]) {
+				continue
+			}
+			if ok, _ := isPrenoteEntry(entries[j]); ok {
+				continue
+			}
+			xfer, err := pc.receiveEntry(bh, entries[j], rtn)
+			if err != nil {
+				el.Add(fmt.Errorf("traceNumber=%s: %v", entries[j].TraceNumber, err))
+				continue
+			}
+			receivedEntriesProcessed.With(
+				"origin", file.Header.ImmediateOrigin,
+				"destination", file.Header.ImmediateDestination,
+				"status", string(xfer.Status),
+			).Add(1)
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+// receiveEntry posts entry to the account it's addressed to, or returns it when no account
+// is found, and saves the outcome as a ReceivedTransfer.
+func (pc *receivedProcessor) receiveEntry(bh *ach.BatchHeader, entry *ach.EntryDetail, routingNumber string) (*client.ReceivedTransfer, error) {
+	xfer := &client.ReceivedTransfer{
+		ReceivedTransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    int32(entry.Amount),
+		},
+		TransactionCode:         int32(entry.TransactionCode),
+		StandardEntryClassCode:  bh.StandardEntryClassCode,
+		CompanyName:             strings.TrimSpace(bh.CompanyName),
+		CompanyIdentification:   strings.TrimSpace(bh.CompanyIdentification),
+		CompanyEntryDescription: strings.TrimSpace(bh.CompanyEntryDescription),
+		OdfiIdentification:      bh.ODFIIdentification,
+		RdfiRoutingNumber:       routingNumber,
+		IndividualName:          strings.TrimSpace(entry.IndividualName),
+		TraceNumber:             entry.TraceNumber,
+		Created:                 time.Now(),
+	}
+	receivedEntry := received.Entry{
+		Header:         bh,
+		Entry:          entry,
+		SettlementDate: settlementDate(bh, pc.odfi.Cutoffs, xfer.Created),
+	}
+	xfer.ReturnDeadline = received.ReturnDeadline(pc.odfi.Cutoffs, receivedEntry.SettlementDate)
+	logger := pc.logger.With(log.Fields{
+		"receivedTransferID": log.String(xfer.ReceivedTransferID),
+		"traceNumber":        log.String(entry.TraceNumber),
+	})
+
+	var acct *accounts.Account
+	if accountType := receivedAccountType(entry.TransactionCode); accountType != "" {
+		found, err := pc.accounts.SearchAccount(pc.rdfi.Organization, routingNumber, strings.TrimSpace(entry.DFIAccountNumber), accountType)
+		if err != nil {
+			return nil, err
+		}
+		acct = found
+	}
+
+	if acct == nil {
+		traceNumber, err := received.PublishReturn(pc.pub, pc.odfi, pc.traceNumbers, xfer.ReceivedTransferID, receivedEntry, unknownAccountReturnCode)
+		if err != nil {
+			return nil, err
+		}
+		xfer.Status = client.RETURNING
+		xfer.ReturnTraceNumber = traceNumber
+		xfer.ReturnCode = achx.ReturnCode(unknownAccountReturnCode)
+		logger.Logf("returning entry with %s", unknownAccountReturnCode)
+	} else {
+		purpose := accounts.ACHCredit
+		if achx.IsDebitTransactionCode(entry.TransactionCode) {
+			purpose = accounts.ACHDebit
+		}
+		tx, err := pc.accounts.PostTransaction(pc.rdfi.Organization, xfer.ReceivedTransferID, []accounts.TransactionLine{
+			{
+				AccountID: acct.ID,
+				Purpose:   purpose,
+				Amount:    entry.Amount,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		xfer.Status = client.POSTED
+		xfer.AccountID = acct.ID
+		xfer.TransactionID = tx.ID
+		logger.Logf("posted %s to accountID=%s", purpose, acct.ID)
+	}
+
+	if err := pc.repo.SaveReceivedTransfer(pc.rdfi.Organization, xfer, receivedEntry); err != nil {
+		return nil, fmt.Errorf("saving receivedTransferID=%s: %v", xfer.ReceivedTransferID, err)
+	}
+	return xfer, nil
+}
+
+// settlementDate returns the day an entry settles, which is its batch's effective entry
+// date unless that's already passed when the entry is received.
+func settlementDate(bh *ach.BatchHeader, cutoffs config.Cutoffs, receivedAt time.Time) time.Time {
+	loc := cutoffs.Location()
+	if loc == nil {
+		loc = time.UTC
+	}
+	receivedAt = receivedAt.In(loc)
+	effective, err := time.ParseInLocation("060102", bh.EffectiveEntryDate, loc) // YYMMDD
+	today := time.Date(receivedAt.Year(), receivedAt.Month(), receivedAt.Day(), 0, 0, 0, 0, loc)
+	if err != nil || effective.Before(today) {
+		return receivedAt
+	}
+	return effective
+}
+
+// isForwardEntry returns false for returns and notifications of change, which are
+// handled by other processors.
+func isForwardEntry(entry *ach.EntryDetail) bool {
+	return entry.Addenda98 == nil && entry.Addenda99 == nil &&
+		entry.Category != ach.CategoryReturn && entry.Category != ach.CategoryNOC
+}
+
+// receivedAccountType returns the Accounts service type of the account a forward entry
+// is addressed to. Only checking and savings accounts are held there.
+func receivedAccountType(code int) string {
+	switch code {
+	case ach.CheckingCredit, ach.CheckingDebit:
+		return "Checking"
+	case ach.SavingsCredit, ach.SavingsDebit:
+		return "Savings"
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/accounts"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/received"
+)
+
+func receivedConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "053200019"
+	cfg.RDFI = &config.RDFI{
+		RoutingNumbers: []string{"053200019"},
+		Organization:   "moov",
+	}
+	return cfg
+}
+
+func readReceivedFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	// the entry is a checking debit of account 12345 at 053200019
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReceived__Posted(t *testing.T) {
+	accountsClient := &accounts.MockClient{
+		Accounts: map[string]*accounts.Account{
+			"12345": {ID: "acct"},
+		},
+	}
+	repo := &received.MockRepository{}
+	pub := pipeline.NewMockPublisher()
+
+	pc := NewReceivedProcessor(receivedConfig(), accountsClient, repo, pub, nil)
+	if err := pc.Handle(readReceivedFile(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(accountsClient.Posted) != 1 {
+		t.Fatalf("unexpected transactions: %#v", accountsClient.Posted)
+	}
+	if line := accountsClient.Posted[0][0]; line.AccountID != "acct" || line.Purpose != accounts.ACHDebit || line.Amount != 10500 {
+		t.Errorf("unexpected transaction line: %#v", line)
+	}
+	if len(repo.Transfers) != 1 {
+		t.Fatalf("unexpected received transfers: %#v", repo.Transfers)
+	}
+	if xfer := repo.Transfers[0]; xfer.Status != client.POSTED || xfer.AccountID != "acct" || xfer.RdfiRoutingNumber != "053200019" {
+		t.Errorf("unexpected received transfer: %#v", xfer)
+	}
+	if xfer := repo.Transfers[0]; !xfer.ReturnDeadline.After(time.Now()) {
+		t.Errorf("unexpected return deadline: %v", xfer.ReturnDeadline)
+	}
+	if repo.Entry == nil || repo.Entry.Entry.TraceNumber != "076401255655291" || repo.Entry.SettlementDate.IsZero() {
+		t.Errorf("unexpected received entry: %#v", repo.Entry)
+	}
+	if len(pub.Xfers) != 0 {
+		t.Errorf("unexpected returns: %#v", pub.Xfers)
+	}
+}
+
+func TestReceived__UnknownAccount(t *testing.T) {
+	accountsClient := &accounts.MockClient{}
+	repo := &received.MockRepository{}
+	pub := pipeline.NewMockPublisher()
+
+	pc := NewReceivedProcessor(receivedConfig(), accountsClient, repo, pub, nil)
+	if err := pc.Handle(readReceivedFile(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(accountsClient.Posted) != 0 {
+		t.Errorf("unexpected transactions: %#v", accountsClient.Posted)
+	}
+	if len(repo.Transfers) != 1 {
+		t.Fatalf("unexpected received transfers: %#v", repo.Transfers)
+	}
+	xfer := repo.Transfers[0]
+	if xfer.Status != client.RETURNING || xfer.ReturnCode == nil || xfer.ReturnCode.Code != "R03" {
+		t.Errorf("unexpected received transfer: %#v", xfer)
+	}
+
+	published, ok := pub.Xfers[xfer.ReceivedTransferID]
+	if !ok {
+		t.Fatalf("missing return: %#v", pub.Xfers)
+	}
+	ed := published.File.Batches[0].GetEntries()[0]
+	if ed.Addenda99 == nil || ed.Addenda99.ReturnCode != "R03" || ed.TraceNumber != xfer.ReturnTraceNumber {
+		t.Errorf("unexpected return entry: %#v", ed)
+	}
+}
+
+func TestReceived__Skipped(t *testing.T) {
+	accountsClient := &accounts.MockClient{}
+	repo := &received.MockRepository{}
+
+	// another routing number
+	cfg := receivedConfig()
+	cfg.RDFI.RoutingNumbers = []string{"076401251"}
+	pc := NewReceivedProcessor(cfg, accountsClient, repo, nil, nil)
+	if err := pc.Handle(readReceivedFile(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// disabled
+	cfg.RDFI = nil
+	pc = NewReceivedProcessor(cfg, accountsClient, repo, nil, nil)
+	if err := pc.Handle(readReceivedFile(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.Transfers) != 0 {
+		t.Errorf("unexpected received transfers: %#v", repo.Transfers)
+	}
+}
+
+func TestReceived__Err(t *testing.T) {
+	accountsClient := &accounts.MockClient{Err: errors.New("bad error")}
+	repo := &received.MockRepository{}
+
+	pc := NewReceivedProcessor(receivedConfig(), accountsClient, repo, nil, nil)
+	if err := pc.Handle(readReceivedFile(t)); err == nil {
+		t.Error("expected error")
+	}
+
+	// returns need a publisher
+	accountsClient.Err = nil
+	if err := pc.Handle(readReceivedFile(t)); err == nil {
+		t.Error("expected error")
+	}
+	if len(repo.Transfers) != 0 {
+		t.Errorf("unexpected received transfers: %#v", repo.Transfers)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// ArchivedFile is an inbound or return file which has been downloaded and processed.
+type ArchivedFile struct {
+	Filename    string
+	Kind        string // "inbound" or "return"
+	FileHash    string // hex encoded SHA-256 hash of the contents
+	Size        int64
+	ArchiveKey  string // key of the copy in the archive bucket, if any
+	ProcessedAt time.Time
+}
+
+type Repository interface {
+	getArchivedFile(fileHash string) (*ArchivedFile, error)
+	saveArchivedFile(file ArchivedFile) error
+
+	getQuarantinedFiles() ([]*admin.QuarantinedFile, error)
+	getQuarantinedFile(quarantineID string) (*admin.QuarantinedFile, []byte, error)
+	getQuarantinedFileByHash(fileHash string) (*admin.QuarantinedFile, error)
+	saveQuarantinedFile(file admin.QuarantinedFile, fileHash string, contents []byte) error
+	updateQuarantinedFile(quarantineID string, status admin.QuarantinedFileStatus, errMsg string, retried time.Time) error
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+// getArchivedFile returns the most recently processed file with fileHash, or nil if
+// no such file has been processed. Uploaded files in the archive are ignored.
+func (r *sqlRepo) getArchivedFile(fileHash string) (*ArchivedFile, error) {
+	defer database.MeasureQuery("inbound", "getArchivedFile")()
+
+	query := `select filename, kind, file_hash, size, archive_key, processed_at from file_archive
+where file_hash = ? and kind <> 'outbound' order by processed_at desc limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var file ArchivedFile
+	var archiveKey *string
+	err = stmt.QueryRow(fileHash).Scan(&file.Filename, &file.Kind, &file.FileHash, &file.Size, &archiveKey, &file.ProcessedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if archiveKey != nil {
+		file.ArchiveKey = *archiveKey
+	}
+	return &file, nil
+}
+
+func (r *sqlRepo) saveArchivedFile(file ArchivedFile) error {
+	defer database.MeasureQuery("inbound", "saveArchivedFile")()
+
+	query := `insert into file_archive (filename, kind, file_hash, size, archive_key, processed_at) values (?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var archiveKey *string
+	if file.ArchiveKey != "" {
+		archiveKey = &file.ArchiveKey
+	}
+	_, err = stmt.Exec(file.Filename, file.Kind, file.FileHash, file.Size, archiveKey, file.ProcessedAt)
+	return err
+}
+
+// getQuarantinedFiles returns every file still waiting in quarantine, oldest first.
+func (r *sqlRepo) getQuarantinedFiles() ([]*admin.QuarantinedFile, error) {
+	defer database.MeasureQuery("inbound", "getQuarantinedFiles")()
+
+	query := `select quarantine_id, filename, kind, error, status, created_at, retried_at from quarantined_files
+where status = ? order by created_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(admin.QUARANTINED)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []*admin.QuarantinedFile
+	for rows.Next() {
+		var file admin.QuarantinedFile
+		if err := rows.Scan(&file.QuarantineID, &file.Filename, &file.Kind, &file.Error, &file.Status, &file.Created, &file.Retried); err != nil {
+			return nil, err
+		}
+		files = append(files, &file)
+	}
+	return files, rows.Err()
+}
+
+// getQuarantinedFile returns a quarantined file and its contents, or nil if quarantineID isn't found.
+func (r *sqlRepo) getQuarantinedFile(quarantineID string) (*admin.QuarantinedFile, []byte, error) {
+	defer database.MeasureQuery("inbound", "getQuarantinedFile")()
+
+	query := `select quarantine_id, filename, kind, error, status, created_at, retried_at, contents from quarantined_files
+where quarantine_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stmt.Close()
+
+	var file admin.QuarantinedFile
+	var contents string
+	err = stmt.QueryRow(quarantineID).Scan(&file.QuarantineID, &file.Filename, &file.Kind, &file.Error, &file.Status, &file.Created, &file.Retried, &contents)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	return &file, []byte(contents), nil
+}
+
+// getQuarantinedFileByHash returns the most recently quarantined file with fileHash, or nil
+// if no such file has been quarantined.
+func (r *sqlRepo) getQuarantinedFileByHash(fileHash string) (*admin.QuarantinedFile, error) {
+	defer database.MeasureQuery("inbound", "getQuarantinedFileByHash")()
+
+	query := `select quarantine_id, filename, kind, error, status, created_at, retried_at from quarantined_files
+where file_hash = ? order by created_at desc limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var file admin.QuarantinedFile
+	err = stmt.QueryRow(fileHash).Scan(&file.QuarantineID, &file.Filename, &file.Kind, &file.Error, &file.Status, &file.Created, &file.Retried)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *sqlRepo) saveQuarantinedFile(file admin.QuarantinedFile, fileHash string, contents []byte) error {
+	defer database.MeasureQuery("inbound", "saveQuarantinedFile")()
+
+	query := `insert into quarantined_files (quarantine_id, filename, kind, file_hash, contents, error, status, created_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(file.QuarantineID, file.Filename, file.Kind, fileHash, string(contents), file.Error, file.Status, file.Created)
+	return err
+}
+
+func (r *sqlRepo) updateQuarantinedFile(quarantineID string, status admin.QuarantinedFileStatus, errMsg string, retried time.Time) error {
+	defer database.MeasureQuery("inbound", "updateQuarantinedFile")()
+
+	query := `update quarantined_files set status = ?, error = ?, retried_at = ? where quarantine_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(status, errMsg, retried, quarantineID)
+	return err
+}
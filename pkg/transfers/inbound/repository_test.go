@@ -0,0 +1,129 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/moov-io/base"
+)
+
+func TestRepository__archivedFiles(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		file, err := repo.getArchivedFile("abc123")
+		if file != nil || err != nil {
+			t.Fatalf("file=%#v error=%v", file, err)
+		}
+
+		now := time.Now().Truncate(time.Second)
+		err = repo.saveArchivedFile(ArchivedFile{
+			Filename:    "return-WEB.ach",
+			Kind:        "return",
+			FileHash:    "abc123",
+			Size:        1024,
+			ProcessedAt: now,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file, err = repo.getArchivedFile("abc123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file == nil || file.Filename != "return-WEB.ach" || file.Kind != "return" || file.Size != 1024 {
+			t.Fatalf("unexpected file: %#v", file)
+		}
+		if file.ArchiveKey != "" || !file.ProcessedAt.Equal(now) {
+			t.Errorf("unexpected file: %#v", file)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__quarantinedFiles(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		file, err := repo.getQuarantinedFileByHash("abc123")
+		if file != nil || err != nil {
+			t.Fatalf("file=%#v error=%v", file, err)
+		}
+
+		err = repo.saveQuarantinedFile(admin.QuarantinedFile{
+			QuarantineID: base.ID(),
+			Filename:     "bad.ach",
+			Kind:         "inbound",
+			Error:        "bad record",
+			Status:       admin.QUARANTINED,
+			Created:      time.Now().Truncate(time.Second),
+		}, "abc123", []byte("contents"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files, err := repo.getQuarantinedFiles()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Filename != "bad.ach" || files[0].Retried != nil {
+			t.Fatalf("unexpected files: %#v", files)
+		}
+		quarantineID := files[0].QuarantineID
+
+		file, contents, err := repo.getQuarantinedFile(quarantineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file == nil || file.Status != admin.QUARANTINED || string(contents) != "contents" {
+			t.Fatalf("file=%#v contents=%q", file, contents)
+		}
+		if file, err := repo.getQuarantinedFileByHash("abc123"); file == nil || err != nil {
+			t.Fatalf("file=%#v error=%v", file, err)
+		}
+
+		retried := time.Now().Truncate(time.Second)
+		if err := repo.updateQuarantinedFile(quarantineID, admin.REPROCESSED, "bad record", retried); err != nil {
+			t.Fatal(err)
+		}
+		file, _, err = repo.getQuarantinedFile(quarantineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Status != admin.REPROCESSED || file.Retried == nil || !file.Retried.Equal(retried) {
+			t.Errorf("unexpected file: %#v", file)
+		}
+
+		// reprocessed files are no longer listed
+		if files, err := repo.getQuarantinedFiles(); len(files) != 0 || err != nil {
+			t.Errorf("files=%#v error=%v", files, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return NewRepo(db.DB)
+}
@@ -12,7 +12,9 @@ import (
 
 	"github.com/moov-io/ach"
 
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers"
 
 	"github.com/go-kit/kit/metrics/prometheus"
@@ -33,13 +35,15 @@ var (
 )
 
 type returnProcessor struct {
+	cfg          *config.Config
 	logger       log.Logger
 	transferRepo transfers.Repository
 }
 
-func NewReturnProcessor(logger log.Logger, transferRepo transfers.Repository) *returnProcessor {
+func NewReturnProcessor(cfg *config.Config, transferRepo transfers.Repository) *returnProcessor {
 	return &returnProcessor{
-		logger:       logger,
+		cfg:          cfg,
+		logger:       cfg.Logger,
 		transferRepo: transferRepo,
 	}
 }
@@ -102,9 +106,27 @@ func (pc *returnProcessor) processReturnEntry(fh ach.FileHeader, bh *ach.BatchHe
 		if err := SaveReturnCode(pc.transferRepo, transfer.TransferID, entry); err != nil {
 			return err
 		}
+		// Keep the return so it can be dishonored, unless it's contesting our dishonored return
+		if code := entry.Addenda99.ReturnCode; achx.IsContestedDishonoredReturnCode(code) {
+			pc.logger.Set("transferID", log.String(transfer.TransferID)).Logf("dishonored return contested with %s", code)
+		} else if err := pc.transferRepo.SaveReturnEntry(transfer.TransferID, bh, entry); err != nil {
+			return fmt.Errorf("problem saving transferID=%s return entry: %v", transfer.TransferID, err)
+		}
 		if err := pc.transferRepo.UpdateTransferStatus(transfer.TransferID, client.FAILED); err != nil {
 			return fmt.Errorf("problem marking transferID=%s as %s: %v", transfer.TransferID, client.FAILED, err)
 		}
+		// Insufficient or uncollected funds can be retried, see transfers.retries
+		retry, err := transfers.ReinitiateReturnedTransfer(pc.cfg, pc.transferRepo, transfer, entry.Addenda99.ReturnCode)
+		if err != nil {
+			return err
+		}
+		if retry != nil {
+			pc.logger.With(log.Fields{
+				"transferID": log.String(transfer.TransferID),
+				"retryID":    log.String(retry.TransferID),
+				"attempt":    log.Int(int(retry.RetryAttempt)),
+			}).Log("queued retry of returned transfer")
+		}
 		// TODO(adam): We need to update the Customer/Account from return codes
 		// R02 (Account Closed) -- mark account Disabled / Rejected / (new status)
 		// R03 (No Account)
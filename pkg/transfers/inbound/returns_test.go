@@ -8,11 +8,13 @@ import (
 	"errors"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
-	"github.com/moov-io/base/log"
 
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/transfers"
 )
 
@@ -49,7 +51,7 @@ func TestReturns__Handle(t *testing.T) {
 	}
 
 	repo := &transfers.MockRepository{}
-	processor := NewReturnProcessor(log.NewNopLogger(), repo)
+	processor := NewReturnProcessor(config.Empty(), repo)
 
 	if err := processor.Handle(file); err != nil {
 		t.Fatal(err)
@@ -73,7 +75,7 @@ func TestReturns__processReturnEntry(t *testing.T) {
 	entry := file.Batches[0].GetEntries()[0]
 
 	repo := &transfers.MockRepository{}
-	processor := NewReturnProcessor(log.NewNopLogger(), repo)
+	processor := NewReturnProcessor(config.Empty(), repo)
 
 	if err := processor.processReturnEntry(fh, bh, entry); err != nil {
 		t.Fatal(err)
@@ -85,3 +87,82 @@ func TestReturns__processReturnEntry(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestReturns__SaveReturnEntry(t *testing.T) {
+	file, _ := ach.ReadFile(filepath.Join("testdata", "bh-ed-ad-bh-ed-ad-ed-ad.ach"))
+	if len(file.Batches) != 1 {
+		t.Fatalf("batches: %#v", file.Batches)
+	}
+	bh := file.Batches[0].GetHeader()
+	bh.EffectiveEntryDate = time.Now().Format("060102") // match the Transfer
+	entry := file.Batches[0].GetEntries()[0]
+
+	repo := transfers.NewInMemoryRepo()
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     client.Amount{Currency: "USD", Value: int32(entry.Amount)},
+		Status:     client.PROCESSED,
+	}
+	if err := repo.WriteUserTransfer(base.ID(), xfer); err != nil {
+		t.Fatal(err)
+	}
+	batch, _ := ach.NewBatch(bh)
+	batch.AddEntry(&ach.EntryDetail{TraceNumber: entry.Addenda99.OriginalTrace})
+	if err := transfers.SaveTraceNumbers(repo, xfer, []*ach.File{{Batches: []ach.Batcher{batch}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewReturnProcessor(config.Empty(), repo)
+	if err := processor.processReturnEntry(ach.NewFileHeader(), bh, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	returned, err := repo.GetReturnEntry(xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returned == nil || returned.Entry.TraceNumber != entry.TraceNumber || returned.Entry.Addenda99.ReturnCode != "R02" {
+		t.Fatalf("unexpected return: %#v", returned)
+	}
+	if returned.Header.CompanyIdentification != bh.CompanyIdentification {
+		t.Errorf("unexpected header: %#v", returned.Header)
+	}
+}
+
+func TestReturns__Reinitiate(t *testing.T) {
+	file, _ := ach.ReadFile(filepath.Join("testdata", "bh-ed-ad-bh-ed-ad-ed-ad.ach"))
+	if len(file.Batches) != 1 {
+		t.Fatalf("batches: %#v", file.Batches)
+	}
+	bh := file.Batches[0].GetHeader()
+	entry := file.Batches[0].GetEntries()[0]
+	entry.Addenda99.ReturnCode = "R01"
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     client.Amount{Currency: "USD", Value: int32(entry.Amount)},
+		Status:     client.PROCESSED,
+		Created:    time.Now(),
+	}
+	repo := &transfers.MockRepository{Transfers: []*client.Transfer{xfer}}
+
+	cfg := config.Empty()
+	cfg.Transfers.Retries = &config.TransferRetries{}
+
+	processor := NewReturnProcessor(cfg, repo)
+	if err := processor.processReturnEntry(ach.NewFileHeader(), bh, entry); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Retries) != 1 || repo.Retries[0].RetryOf != xfer.TransferID {
+		t.Fatalf("unexpected retries: %#v", repo.Retries)
+	}
+
+	// other return codes aren't reinitiated
+	entry.Addenda99.ReturnCode = "R03"
+	if err := processor.processReturnEntry(ach.NewFileHeader(), bh, entry); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Retries) != 1 {
+		t.Errorf("unexpected retries: %#v", repo.Retries)
+	}
+}
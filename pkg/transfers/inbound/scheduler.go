@@ -6,18 +6,24 @@ package inbound
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/moov-io/base"
 	"github.com/moov-io/base/log"
 
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/jobs"
 	"github.com/moov-io/paygate/pkg/upload"
 )
 
 type Scheduler interface {
 	Start() error
 	Shutdown()
+
+	// UseJobs records the runs of the Scheduler in reg
+	UseJobs(reg *jobs.Registry)
 }
 
 type PeriodicScheduler struct {
@@ -31,20 +37,31 @@ type PeriodicScheduler struct {
 	agent      upload.Agent
 	downloader Downloader
 	processors Processors
+	archive    *archiver
+	quarantine *Quarantine
+
+	job *jobs.Job
 }
 
 func NewPeriodicScheduler(
 	cfg *config.Config,
 	agent upload.Agent,
 	processors Processors,
-) Scheduler {
+	repo Repository,
+	quarantine *Quarantine,
+) (Scheduler, error) {
 	if cfg.ODFI.Inbound.Interval == 0*time.Second {
 		cfg.Logger.Log("skipping inbound file processing")
-		return &MockScheduler{}
+		return &MockScheduler{}, nil
 	} else {
 		cfg.Logger.Logf("starting inbound processor with interval=%v", cfg.ODFI.Inbound.Interval)
 	}
 
+	archive, err := newArchiver(cfg.Logger, repo, cfg.ODFI.Inbound.Archive)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
 	return &PeriodicScheduler{
@@ -58,7 +75,9 @@ func NewPeriodicScheduler(
 		agent:      agent,
 		downloader: NewDownloader(cfg.Logger, cfg.ODFI.Storage),
 		processors: processors,
-	}
+		archive:    archive,
+		quarantine: quarantine,
+	}, nil
 }
 
 func (s *PeriodicScheduler) Shutdown() {
@@ -66,15 +85,24 @@ func (s *PeriodicScheduler) Shutdown() {
 		return
 	}
 	s.shutdownFunc()
+	s.archive.Close()
+}
+
+// UseJobs records the runs of the PeriodicScheduler as the "inbound.downloads" job of reg.
+func (s *PeriodicScheduler) UseJobs(reg *jobs.Registry) {
+	s.job = reg.Register("inbound.downloads")
 }
 
 func (s *PeriodicScheduler) Start() error {
+	s.job.Scheduled(time.Now().Add(s.cfg.Inbound.Interval))
 	for {
 		select {
 		case <-s.ticker.C:
-			if err := s.tick(); err != nil {
-				s.logger.LogErrorf("ERROR with inbound file processor: %v", err)
-			}
+			s.run()
+			s.job.Scheduled(time.Now().Add(s.cfg.Inbound.Interval))
+
+		case <-s.job.Triggered():
+			s.run()
 
 		case <-s.shutdown.Done():
 			s.logger.Log("scheduler shutdown")
@@ -83,10 +111,39 @@ func (s *PeriodicScheduler) Start() error {
 	}
 }
 
+func (s *PeriodicScheduler) run() {
+	err := s.tick()
+	if err != nil {
+		s.logger.LogErrorf("ERROR with inbound file processor: %v", err)
+	}
+	s.job.Done(err)
+}
+
 func (s *PeriodicScheduler) tick() error {
 	s.logger.Log("start retrieving and processing of inbound files")
 
-	dl, err := s.downloader.CopyFilesFromRemote(s.agent)
+	// Each set of remote directories is downloaded and processed on its own
+	var el base.ErrorList
+	for _, agent := range upload.Agents(s.agent) {
+		if err := s.tickAgent(agent); err != nil {
+			el.Add(err)
+		}
+	}
+	if el.Empty() {
+		return nil
+	}
+	return el
+}
+
+func (s *PeriodicScheduler) tickAgent(agent upload.Agent) error {
+	dl, err := s.downloader.CopyFilesFromRemote(agent)
+	if errors.Is(err, upload.ErrOutsideTransferWindow) {
+		s.logger.Logf("skipping inbound files: %v", err)
+		if dl != nil {
+			dl.deleteFiles()
+		}
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("ERROR: problem moving files: %v", err)
 	}
@@ -95,21 +152,32 @@ func (s *PeriodicScheduler) tick() error {
 		if s.cfg.Storage.CleanupLocalDirectory {
 			defer dl.deleteFiles()
 		} else {
-			defer dl.deleteEmptyDirs(s.agent)
+			defer dl.deleteEmptyDirs(agent)
 		}
 	}
 
-	if err := ProcessFiles(dl, s.processors); err != nil {
+	if err := s.archive.skipProcessed(agent, dl); err != nil {
+		return fmt.Errorf("ERROR: checking archived files: %v", err)
+	}
+
+	err = ProcessFiles(dl, s.processors, s.cfg.Inbound.Concurrency())
+	if aerr := s.archive.record(agent, dl); aerr != nil {
+		s.logger.LogErrorf("ERROR: archiving files: %v", aerr)
+	}
+	if qerr := s.quarantine.quarantineFiles(agent, dl); qerr != nil {
+		return fmt.Errorf("ERROR: quarantining files: %v", qerr)
+	}
+	if err != nil {
 		return fmt.Errorf("ERROR: processing files: %v", err)
 	}
 
 	if s.cfg.Storage != nil && !s.cfg.Storage.KeepRemoteFiles {
-		if err := Cleanup(s.logger, s.agent, dl); err != nil {
+		if err := Cleanup(s.logger, agent, dl); err != nil {
 			return fmt.Errorf("ERROR: deleting remote files: %v", err)
 		}
 	}
 
-	if err := CleanupEmptyFiles(s.logger, s.agent, dl, time.Now(), s.cfg.Storage.RemoveZeroByteFilesAfter); err != nil {
+	if err := CleanupEmptyFiles(s.logger, agent, dl, time.Now(), s.cfg.Storage.RemoveZeroByteFilesAfter); err != nil {
 		return fmt.Errorf("ERROR: deleting zero byte files: %v", err)
 	}
 
@@ -24,9 +24,15 @@ func TestScheduler(t *testing.T) {
 	agent := &upload.MockAgent{}
 	processors := SetupProcessors(&MockProcessor{})
 
-	schd := NewPeriodicScheduler(cfg, agent, processors)
-	if schd == nil {
-		t.Fatal("nil Scheduler")
+	repo := &MockRepository{}
+	quarantine, err := NewQuarantine(cfg, repo, processors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schd, err := NewPeriodicScheduler(cfg, agent, processors, repo, quarantine)
+	if schd == nil || err != nil {
+		t.Fatalf("nil Scheduler: %v", err)
 	}
 
 	ss, ok := schd.(*PeriodicScheduler)
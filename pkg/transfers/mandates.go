@@ -0,0 +1,227 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/moov-io/base/log"
+)
+
+// maxMandateIDLength is the size of the mandate_id column
+const maxMandateIDLength = 40
+
+func getMandateID(r *http.Request) string {
+	return route.ReadPathID("mandateID", r)
+}
+
+// CreateMandate records a receiver's authorization to debit their account. PPD debits of the
+// account reference it by mandateID until the receiver revokes it.
+func CreateMandate(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.CreateMandate
+		if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+			responder.Problem(fmt.Errorf("creating mandate: problem reading request body: %w", err))
+			return
+		}
+		if err := validateMandateRequest(req); err != nil {
+			responder.Problem(fmt.Errorf("creating mandate: invalid mandate request: %w", err))
+			return
+		}
+
+		mandate := &client.Mandate{
+			MandateID:              base.ID(),
+			CustomerID:             req.CustomerID,
+			AccountID:              req.AccountID,
+			StandardEntryClassCode: achx.StandardEntryClassCode(req.StandardEntryClassCode),
+			AuthorizedAt:           req.AuthorizedAt,
+			MaxAmount:              req.MaxAmount,
+			Status:                 client.ACTIVE,
+			Created:                time.Now(),
+		}
+		if err := repo.createMandate(responder.OrganizationID, mandate); err != nil {
+			responder.Problem(route.Internal.New("creating mandate: %v", err))
+			return
+		}
+		responder.Logger().With(log.Fields{
+			"mandateID":  log.String(mandate.MandateID),
+			"customerID": log.String(mandate.CustomerID),
+		}).Log("created mandate")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(mandate)
+		})
+	}
+}
+
+// validateMandateRequest accepts PPD and WEB authorizations. Debits referencing a mandate are
+// originated with its SEC code.
+func validateMandateRequest(req client.CreateMandate) error {
+	verr := &route.ValidationError{}
+	if req.CustomerID == "" {
+		verr.Add("customerID", "missing")
+	}
+	if req.AccountID == "" {
+		verr.Add("accountID", "missing")
+	}
+	switch achx.StandardEntryClassCode(req.StandardEntryClassCode) {
+	case ach.PPD, ach.WEB:
+	default:
+		verr.Add("standardEntryClassCode", "%s isn't PPD or WEB", req.StandardEntryClassCode)
+	}
+	if req.AuthorizedAt.IsZero() {
+		verr.Add("authorizedAt", "missing")
+	} else if req.AuthorizedAt.After(time.Now()) {
+		verr.Add("authorizedAt", "in the future")
+	}
+	if req.MaxAmount != nil {
+		if err := validateAmount(*req.MaxAmount); err != nil {
+			verr.Add("maxAmount", "%v", err)
+		}
+	}
+	return verr.Err()
+}
+
+func GetMandates(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		q := r.URL.Query()
+		status := client.MandateStatus(strings.TrimSpace(q.Get("status")))
+		mandates, err := repo.getMandates(r.Context(), responder.OrganizationID, q.Get("customerID"), status)
+		if err != nil {
+			responder.Problem(route.Internal.New("getting mandates: %v", err))
+			return
+		}
+		if mandates == nil {
+			mandates = []*client.Mandate{}
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(mandates)
+		})
+	}
+}
+
+func GetMandate(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		mandate, err := readMandate(r.Context(), repo, responder.OrganizationID, getMandateID(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(mandate)
+		})
+	}
+}
+
+// RevokeMandate records that the receiver revoked their authorization. Debits referencing
+// the mandate are rejected from then on, including those queued or retried.
+func RevokeMandate(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.RevokeMandate
+		if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+			responder.Problem(fmt.Errorf("revoking mandate: problem reading request body: %w", err))
+			return
+		}
+
+		mandateID := getMandateID(r)
+		revoked, err := repo.revokeMandate(responder.OrganizationID, mandateID, req.Reason)
+		if err != nil {
+			responder.Problem(route.Internal.New("revoking mandate: %v", err))
+			return
+		}
+		mandate, err := readMandate(r.Context(), repo, responder.OrganizationID, mandateID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if !revoked {
+			responder.Problem(route.InvalidRequest.New("mandateID=%s was already revoked", mandateID))
+			return
+		}
+		responder.Logger().Set("mandateID", log.String(mandateID)).Log("revoked mandate")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(mandate)
+		})
+	}
+}
+
+func readMandate(ctx context.Context, repo Repository, orgID string, mandateID string) (*client.Mandate, error) {
+	mandate, err := repo.getMandate(ctx, orgID, mandateID)
+	if err != nil {
+		return nil, route.Internal.New("getting mandate: %v", err)
+	}
+	if mandate == nil {
+		return nil, route.NotFound.New("mandateID=%s not found", mandateID)
+	}
+	return mandate, nil
+}
+
+// mandateSECCodes are the debits which need a receiver's authorization recorded as a mandate.
+// Debits converted from checks are authorized by the check.
+var mandateSECCodes = map[string]bool{
+	ach.PPD: true,
+	ach.WEB: true,
+}
+
+// checkMandate rejects PPD and WEB debits without an active mandate, and debits whose mandate
+// was revoked, was given for another SEC code or account, or has a maxAmount below the
+// Transfer's amount.
+func checkMandate(repo Repository, orgID string, transfer *client.Transfer, debit bool) error {
+	secCode := achx.StandardEntryClassCode(transfer.StandardEntryClassCode)
+	if transfer.MandateID == "" {
+		if debit && mandateSECCodes[secCode] {
+			return route.InvalidRequest.New("%s debits need the mandateID of the receiver's authorization", secCode)
+		}
+		return nil
+	}
+	if !debit {
+		return route.InvalidRequest.New("mandateID=%s can only be used by debits", transfer.MandateID)
+	}
+
+	mandate, err := readMandate(context.Background(), repo, orgID, transfer.MandateID)
+	if err != nil {
+		return err
+	}
+	if mandate.Status != client.ACTIVE {
+		return route.InvalidRequest.New("mandateID=%s was revoked", mandate.MandateID)
+	}
+	if mandate.StandardEntryClassCode != secCode {
+		return route.InvalidRequest.New("mandateID=%s authorizes %s debits, not %s", mandate.MandateID, mandate.StandardEntryClassCode, secCode)
+	}
+	if mandate.CustomerID != transfer.Source.CustomerID || mandate.AccountID != transfer.Source.AccountID {
+		return route.InvalidRequest.New("mandateID=%s doesn't authorize debits of accountID=%s", mandate.MandateID, transfer.Source.AccountID)
+	}
+	if max := mandate.MaxAmount; max != nil && transfer.Amount.Value > max.Value {
+		return route.InvalidRequest.New("amount of %d is more than the %d mandateID=%s authorizes", transfer.Amount.Value, max.Value, mandate.MandateID)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/antihax/optional"
+	"github.com/gorilla/mux"
+)
+
+func TestMandates__validate(t *testing.T) {
+	req := client.CreateMandate{
+		CustomerID:   base.ID(),
+		AccountID:    base.ID(),
+		AuthorizedAt: time.Now().Add(-1 * time.Hour),
+	}
+	if err := validateMandateRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.StandardEntryClassCode = "WEB"
+	req.MaxAmount = &client.Amount{Currency: "USD", Value: 5000}
+	if err := validateMandateRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.StandardEntryClassCode = "CCD"
+	if err := validateMandateRequest(req); err == nil {
+		t.Error("expected error")
+	}
+	req.StandardEntryClassCode = "PPD"
+
+	req.AuthorizedAt = time.Now().Add(time.Hour)
+	if err := validateMandateRequest(req); err == nil {
+		t.Error("expected error")
+	}
+	req.AuthorizedAt = time.Time{}
+	if err := validateMandateRequest(req); err == nil {
+		t.Error("expected error")
+	}
+	req.AuthorizedAt = time.Now()
+
+	req.MaxAmount.Value = 0
+	if err := validateMandateRequest(req); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestMandates__check(t *testing.T) {
+	mandate := &client.Mandate{
+		MandateID:              base.ID(),
+		CustomerID:             base.ID(),
+		AccountID:              base.ID(),
+		StandardEntryClassCode: "PPD",
+		MaxAmount:              &client.Amount{Currency: "USD", Value: 1000},
+		Status:                 client.ACTIVE,
+	}
+	repo := &MockRepository{
+		Mandates: []*client.Mandate{mandate},
+	}
+	xfer := &client.Transfer{
+		Amount: client.Amount{Currency: "USD", Value: 1000},
+		Source: client.Source{
+			CustomerID: mandate.CustomerID,
+			AccountID:  mandate.AccountID,
+		},
+		StandardEntryClassCode: "PPD",
+	}
+
+	// PPD and WEB debits need a mandate
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+	xfer.StandardEntryClassCode = "WEB"
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+	xfer.StandardEntryClassCode = "PPD"
+	if err := checkMandate(repo, "organization", xfer, false); err != nil {
+		t.Errorf("credits don't need a mandate: %v", err)
+	}
+	check := *xfer
+	check.StandardEntryClassCode = "BOC"
+	if err := checkMandate(repo, "organization", &check, true); err != nil {
+		t.Errorf("checks don't need a mandate: %v", err)
+	}
+
+	xfer.MandateID = mandate.MandateID
+	if err := checkMandate(repo, "organization", xfer, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMandate(repo, "organization", xfer, false); err == nil {
+		t.Error("expected error")
+	}
+
+	// a PPD authorization can't be used for WEB debits
+	xfer.StandardEntryClassCode = "WEB"
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+	xfer.StandardEntryClassCode = ""
+	if err := checkMandate(repo, "organization", xfer, true); err != nil {
+		t.Errorf("SEC code defaults to PPD: %v", err)
+	}
+
+	xfer.Amount.Value = 1001
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+	xfer.Amount.Value = 1000
+
+	xfer.Source.AccountID = base.ID()
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+	xfer.Source.AccountID = mandate.AccountID
+
+	mandate.Status = client.REVOKED
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+
+	xfer.MandateID = base.ID()
+	if err := checkMandate(repo, "organization", xfer, true); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRouter__mandates(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	// every Transfer between the test accounts debits the source
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, &organization.MockRepository{}, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	debit := client.CreateTransfer{
+		Amount: client.Amount{Currency: "USD", Value: 1245},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "gym membership",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", debit, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	mandate, resp, err := c.MandatesApi.CreateMandate(context.TODO(), "organization", client.CreateMandate{
+		CustomerID:   sourceCustomerID,
+		AccountID:    sourceAccountID,
+		AuthorizedAt: time.Now().Add(-24 * time.Hour),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if mandate.Status != client.ACTIVE || mandate.StandardEntryClassCode != "PPD" {
+		t.Errorf("unexpected mandate: %#v", mandate)
+	}
+
+	debit.MandateID = mandate.MandateID
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", debit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.MandateID != mandate.MandateID {
+		t.Errorf("unexpected mandateID: %q", xfer.MandateID)
+	}
+
+	mandates, resp, err := c.MandatesApi.GetMandates(context.TODO(), "organization", &client.GetMandatesOpts{
+		CustomerID: optional.NewString(sourceCustomerID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(mandates) != 1 || mandates[0].MandateID != mandate.MandateID {
+		t.Errorf("unexpected mandates: %#v", mandates)
+	}
+
+	revoked, resp, err := c.MandatesApi.RevokeMandate(context.TODO(), mandate.MandateID, "organization", client.RevokeMandate{
+		Reason: "membership canceled",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if revoked.Status != client.REVOKED || revoked.RevokedAt == nil || revoked.RevocationReason != "membership canceled" {
+		t.Errorf("unexpected mandate: %#v", revoked)
+	}
+
+	// revoked mandates block future debits and can't be revoked again
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", debit, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	_, resp, err = c.MandatesApi.RevokeMandate(context.TODO(), mandate.MandateID, "organization", client.RevokeMandate{}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	found, resp, err := c.MandatesApi.GetMandateByID(context.TODO(), mandate.MandateID, "organization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if found.Status != client.REVOKED {
+		t.Errorf("unexpected status: %v", found.Status)
+	}
+
+	// WEB authorizations back WEB debits only
+	webMandate, resp, err := c.MandatesApi.CreateMandate(context.TODO(), "organization", client.CreateMandate{
+		CustomerID:             sourceCustomerID,
+		AccountID:              sourceAccountID,
+		StandardEntryClassCode: "WEB",
+		AuthorizedAt:           time.Now().Add(-1 * time.Hour),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	debit.MandateID = webMandate.MandateID
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", debit, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+
+	debit.StandardEntryClassCode = "WEB"
+	xfer, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", debit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.StandardEntryClassCode != "WEB" || xfer.MandateID != webMandate.MandateID {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+
+	// other organizations can't read the mandate
+	_, resp, err = c.MandatesApi.GetMandateByID(context.TODO(), mandate.MandateID, "other", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+}
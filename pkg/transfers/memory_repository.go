@@ -0,0 +1,869 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+)
+
+// NewInMemoryRepo returns a Repository which keeps Transfers in memory. It's intended
+// for tests and demos where no database is available.
+func NewInMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		transfers: make(map[string]*memoryTransfer),
+		queue:     make(map[string]*memoryQueued),
+		reveals:   make(map[string][]string),
+		history:   make(map[string][]client.AccountHistory),
+		approvals: make(map[string]*admin.TransferApproval),
+		held:      make(map[string][]pipeline.OutboxMessage),
+		mandates:  make(map[string]*memoryMandate),
+		evidence:  make(map[string]*memoryEvidence),
+	}
+}
+
+type memoryTransfer struct {
+	orgID     string
+	transfer  client.Transfer
+	entries   []client.TransferEntry
+	returns   []ReturnEntry
+	deletedAt *time.Time
+
+	fundsAvailableAt *time.Time
+}
+
+type memoryRepo struct {
+	mu        sync.RWMutex
+	transfers map[string]*memoryTransfer
+
+	// outbox holds messages saved with Transfer changes in the order they were written
+	outbox []pipeline.OutboxMessage
+
+	// held holds the messages of each Transfer waiting for approval
+	held      map[string][]pipeline.OutboxMessage
+	approvals map[string]*admin.TransferApproval
+
+	// reveals holds who read the full account numbers of each Transfer
+	reveals map[string][]string
+
+	// history holds the details of each account by organization and accountID, oldest first
+	history map[string][]client.AccountHistory
+
+	queue map[string]*memoryQueued
+
+	mandates map[string]*memoryMandate
+	evidence map[string]*memoryEvidence
+}
+
+type memoryEvidence struct {
+	orgID    string
+	evidence client.AuthorizationEvidence
+}
+
+type memoryMandate struct {
+	orgID   string
+	mandate client.Mandate
+}
+
+type memoryQueued struct {
+	orgID       string
+	attempts    int
+	claimedAt   time.Time
+	availableAt time.Time
+	created     time.Time
+}
+
+func (r *memoryRepo) Close() error {
+	return nil
+}
+
+// find returns a non-deleted Transfer. Callers must hold r.mu
+func (r *memoryRepo) find(transferID string) *memoryTransfer {
+	if xfer, ok := r.transfers[transferID]; ok && xfer.deletedAt == nil {
+		return xfer
+	}
+	return nil
+}
+
+// copyTransfer returns a copy of xfer so callers can't modify our stored Transfer.
+func copyTransfer(xfer client.Transfer) *client.Transfer {
+	if xfer.TraceNumbers != nil {
+		xfer.TraceNumbers = append([]string(nil), xfer.TraceNumbers...)
+	}
+	if xfer.Remittance != nil {
+		rem := *xfer.Remittance
+		rem.InvoiceNumbers = append([]string(nil), rem.InvoiceNumbers...)
+		xfer.Remittance = &rem
+	}
+	if xfer.Check != nil {
+		check := *xfer.Check
+		xfer.Check = &check
+	}
+	return &xfer
+}
+
+func (r *memoryRepo) getTransfers(ctx context.Context, orgID string, params transferFilterParams) ([]*client.Transfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transfers := make([]*client.Transfer, 0)
+	for _, xfer := range r.transfers {
+		if xfer.orgID != orgID || xfer.deletedAt != nil {
+			continue
+		}
+		created := xfer.transfer.Created
+		if created.Before(params.StartDate) || created.After(params.EndDate) {
+			continue
+		}
+		if params.Status != "" && xfer.transfer.Status != params.Status {
+			continue
+		}
+		if len(params.CustomerIDs) > 0 && !containsCustomer(params.CustomerIDs, xfer.transfer) {
+			continue
+		}
+		transfers = append(transfers, copyTransfer(xfer.transfer))
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].Created.After(transfers[j].Created)
+	})
+
+	if params.Skip >= int64(len(transfers)) {
+		return make([]*client.Transfer, 0), nil
+	}
+	transfers = transfers[params.Skip:]
+	if params.Count >= 0 && params.Count < int64(len(transfers)) {
+		transfers = transfers[:params.Count]
+	}
+	return transfers, nil
+}
+
+func containsCustomer(customerIDs []string, xfer client.Transfer) bool {
+	for i := range customerIDs {
+		if xfer.Source.CustomerID == customerIDs[i] || xfer.Destination.CustomerID == customerIDs[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepo) GetTransfer(ctx context.Context, transferID string) (*client.Transfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if xfer := r.find(transferID); xfer != nil {
+		return copyTransfer(xfer.transfer), nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepo) GetUserTransfer(ctx context.Context, transferID string, orgID string) (*client.Transfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if xfer := r.find(transferID); xfer != nil && xfer.orgID == orgID {
+		return copyTransfer(xfer.transfer), nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepo) getTransferByExternalID(ctx context.Context, orgID string, externalID string) (*client.Transfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, xfer := range r.transfers {
+		if xfer.orgID == orgID && xfer.deletedAt == nil && xfer.transfer.ExternalID == externalID {
+			return copyTransfer(xfer.transfer), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryRepo) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if xfer := r.find(transferID); xfer != nil {
+		xfer.transfer.Status = status
+	}
+	return nil
+}
+
+func (r *memoryRepo) WriteUserTransfer(orgID string, transfer *client.Transfer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.transfers[transfer.TransferID]; exists {
+		return fmt.Errorf("transferID=%s already exists", transfer.TransferID)
+	}
+	if transfer.ExternalID != "" {
+		// Like the unique index of our databases deleted Transfers keep their externalID
+		for _, xfer := range r.transfers {
+			if xfer.orgID == orgID && xfer.transfer.ExternalID == transfer.ExternalID {
+				return errDuplicateExternalID
+			}
+		}
+	}
+	xfer := copyTransfer(*transfer)
+	xfer.Created = time.Now()
+	xfer.ReturnCode = nil
+	xfer.ProcessedAt = nil
+	xfer.TraceNumbers = nil
+	r.transfers[transfer.TransferID] = &memoryTransfer{
+		orgID:    orgID,
+		transfer: *xfer,
+	}
+	return nil
+}
+
+func (r *memoryRepo) WriteUserTransfers(orgID string, xfers []UserTransfer) error {
+	for i := range xfers {
+		if err := r.createUserTransfer(orgID, xfers[i].Transfer, origination{traceNumbers: xfers[i].TraceNumbers}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepo) createUserTransfer(orgID string, transfer *client.Transfer, orig origination) error {
+	if err := r.WriteUserTransfer(orgID, transfer); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveOrigination(orgID, transfer.TransferID, orig)
+	r.outbox = append(r.outbox, orig.msgs...)
+	return nil
+}
+
+func (r *memoryRepo) createTransferGroup(orgID string, transfers []*client.Transfer, origs []origination) error {
+	r.mu.RLock()
+	for i := range transfers {
+		if _, exists := r.transfers[transfers[i].TransferID]; exists {
+			r.mu.RUnlock()
+			return fmt.Errorf("transferID=%s already exists", transfers[i].TransferID)
+		}
+	}
+	r.mu.RUnlock()
+
+	for i := range transfers {
+		if err := r.createUserTransfer(orgID, transfers[i], origs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepo) getTransferGroup(ctx context.Context, orgID string, groupID string) ([]*client.Transfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var transfers []*client.Transfer
+	for _, xfer := range r.transfers {
+		if xfer.orgID == orgID && xfer.deletedAt == nil && xfer.transfer.GroupID == groupID {
+			transfers = append(transfers, copyTransfer(xfer.transfer))
+		}
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].Created.Before(transfers[j].Created)
+	})
+	return transfers, nil
+}
+
+// saveOrigination sets the trace numbers and fee of a Transfer and saves its account history.
+// Callers must hold r.mu.
+func (r *memoryRepo) saveOrigination(orgID string, transferID string, orig origination) {
+	if xfer, ok := r.transfers[transferID]; ok {
+		xfer.transfer.TraceNumbers = append([]string(nil), orig.traceNumbers...)
+		xfer.transfer.Fee = orig.fee
+	}
+	for i := range orig.history {
+		r.appendAccountHistory(orgID, orig.history[i])
+	}
+}
+
+func (r *memoryRepo) deleteUserTransfer(orgID string, transferID string, force bool, msgs []pipeline.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	xfer := r.find(transferID)
+	if xfer == nil || xfer.orgID != orgID {
+		return nil
+	}
+	if !strings.EqualFold(string(xfer.transfer.Status), string(client.PENDING)) {
+		return fmt.Errorf("transferID=%s is not in PENDING status", transferID)
+	}
+	now := time.Now()
+	xfer.deletedAt = &now
+	delete(r.queue, transferID)
+	r.outbox = append(r.outbox, msgs...)
+	return nil
+}
+
+func (r *memoryRepo) createReviewableTransfer(orgID string, transfer *client.Transfer, requestedBy string, orig origination) error {
+	if err := r.WriteUserTransfer(orgID, transfer); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveOrigination(orgID, transfer.TransferID, orig)
+	r.approvals[transfer.TransferID] = &admin.TransferApproval{
+		TransferID:  transfer.TransferID,
+		RequestedBy: requestedBy,
+		Requested:   time.Now(),
+	}
+	r.held[transfer.TransferID] = append([]pipeline.OutboxMessage(nil), orig.msgs...)
+	return nil
+}
+
+func (r *memoryRepo) GetTransferApproval(transferID string) (*admin.TransferApproval, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if approval, ok := r.approvals[transferID]; ok {
+		out := *approval
+		return &out, nil
+	}
+	return nil, nil
+}
+
+func (r *memoryRepo) approveTransfer(transferID string, approvedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	approval, ok := r.approvals[transferID]
+	if !ok || approval.Approved != nil {
+		return errApprovalConflict
+	}
+	xfer := r.find(transferID)
+	if xfer == nil || xfer.transfer.Status != client.REVIEWABLE {
+		return errApprovalConflict
+	}
+	now := time.Now()
+	approval.ApprovedBy, approval.Approved = approvedBy, &now
+	xfer.transfer.Status = client.PENDING
+
+	r.outbox = append(r.outbox, r.held[transferID]...)
+	delete(r.held, transferID)
+	return nil
+}
+
+func (r *memoryRepo) enqueueUserTransfer(orgID string, transfer *client.Transfer) error {
+	if err := r.WriteUserTransfer(orgID, transfer); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queue[transfer.TransferID] = &memoryQueued{
+		orgID:   orgID,
+		created: time.Now(),
+	}
+	return nil
+}
+
+func (r *memoryRepo) enqueueRetryTransfer(originalID string, retry *client.Transfer, availableAt time.Time) error {
+	r.mu.RLock()
+	original := r.find(originalID)
+	r.mu.RUnlock()
+	if original == nil {
+		return fmt.Errorf("transferID=%s not found", originalID)
+	}
+	if err := r.WriteUserTransfer(original.orgID, retry); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queue[retry.TransferID] = &memoryQueued{
+		orgID:       original.orgID,
+		availableAt: availableAt,
+		created:     time.Now(),
+	}
+	return nil
+}
+
+func (r *memoryRepo) claimQueuedTransfers(limit int) ([]queuedTransfer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	staleBefore := now.Add(-1 * queueClaimTTL)
+
+	var ids []string
+	for id, q := range r.queue {
+		if q.availableAt.After(now) {
+			continue
+		}
+		if q.claimedAt.IsZero() || q.claimedAt.Before(staleBefore) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.queue[ids[i]].created.Before(r.queue[ids[j]].created)
+	})
+
+	var claimed []queuedTransfer
+	for i := 0; i < len(ids) && i < limit; i++ {
+		q := r.queue[ids[i]]
+		q.claimedAt = now
+		q.attempts++
+		claimed = append(claimed, queuedTransfer{
+			transferID: ids[i],
+			orgID:      q.orgID,
+			attempts:   q.attempts,
+		})
+	}
+	return claimed, nil
+}
+
+func (r *memoryRepo) completeQueuedTransfer(transfer *client.Transfer, orig origination) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queue[transfer.TransferID]; !ok {
+		return nil
+	}
+	delete(r.queue, transfer.TransferID)
+
+	if xfer := r.find(transfer.TransferID); xfer != nil {
+		xfer.transfer.ExpectedSettlementDate = transfer.ExpectedSettlementDate
+		xfer.transfer.AvailableOn = transfer.AvailableOn
+		r.saveOrigination(xfer.orgID, transfer.TransferID, orig)
+	}
+	r.outbox = append(r.outbox, orig.msgs...)
+	return nil
+}
+
+func (r *memoryRepo) retryQueuedTransfer(transferID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if q, ok := r.queue[transferID]; ok {
+		q.claimedAt = time.Time{}
+	}
+	return nil
+}
+
+func (r *memoryRepo) failQueuedTransfer(transferID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if xfer := r.find(transferID); xfer != nil {
+		xfer.transfer.Status = client.FAILED
+	}
+	delete(r.queue, transferID)
+	return nil
+}
+
+func (r *memoryRepo) SaveReturnCode(transferID string, returnCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	xfer := r.find(transferID)
+	if xfer == nil || xfer.transfer.ReturnCode != nil {
+		return nil
+	}
+	xfer.transfer.ReturnCode = achx.ReturnCode(returnCode)
+	return nil
+}
+
+func (r *memoryRepo) saveTraceNumbers(transferID string, traceNumbers []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	xfer, ok := r.transfers[transferID]
+	if !ok {
+		return fmt.Errorf("transferID=%s not found", transferID)
+	}
+	for i := range traceNumbers {
+		for j := range xfer.transfer.TraceNumbers {
+			if xfer.transfer.TraceNumbers[j] == traceNumbers[i] {
+				return fmt.Errorf("transferID=%s already has traceNumber=%s", transferID, traceNumbers[i])
+			}
+		}
+		xfer.transfer.TraceNumbers = append(xfer.transfer.TraceNumbers, traceNumbers[i])
+	}
+	return nil
+}
+
+func (r *memoryRepo) getTraceNumbers(ctx context.Context, transferID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if xfer, ok := r.transfers[transferID]; ok {
+		return append([]string(nil), xfer.transfer.TraceNumbers...), nil
+	}
+	return nil, nil
+}
+
+func (r *memoryRepo) saveTransferEntries(transferID string, entries []client.TransferEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	xfer, ok := r.transfers[transferID]
+	if !ok {
+		return fmt.Errorf("transferID=%s not found", transferID)
+	}
+	xfer.entries = append(xfer.entries, entries...)
+	return nil
+}
+
+func (r *memoryRepo) getTransferEntries(ctx context.Context, transferID string) ([]client.TransferEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]client.TransferEntry, 0)
+	if xfer, ok := r.transfers[transferID]; ok {
+		entries = append(entries, xfer.entries...)
+	}
+	return entries, nil
+}
+
+func (r *memoryRepo) getTransferFile(ctx context.Context, transferID string) (*client.TransferFile, error) {
+	entries, err := r.getTransferEntries(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	return newTransferFile(entries), nil
+}
+
+// getFileContents returns the Transfers in each batch of filename. Upload attempts are only
+// recorded in a database, so none are returned.
+func (r *memoryRepo) getFileContents(ctx context.Context, filename string) (*admin.FileContents, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	batches := make(map[int32][]admin.FileEntry)
+	for transferID, xfer := range r.transfers {
+		for i := range xfer.entries {
+			e := xfer.entries[i]
+			if e.Filename == filename {
+				batches[e.BatchNumber] = append(batches[e.BatchNumber], admin.FileEntry{
+					TraceNumber: e.TraceNumber,
+					TransferID:  transferID,
+				})
+			}
+		}
+	}
+	if len(batches) == 0 {
+		return nil, nil
+	}
+
+	contents := &admin.FileContents{
+		Filename: filename,
+		Batches:  make([]admin.FileBatch, 0, len(batches)),
+		Uploads:  make([]admin.UploadAttempt, 0),
+	}
+	for batchNumber, entries := range batches {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].TraceNumber < entries[j].TraceNumber })
+		contents.Batches = append(contents.Batches, admin.FileBatch{BatchNumber: batchNumber, Entries: entries})
+	}
+	sort.Slice(contents.Batches, func(i, j int) bool {
+		return contents.Batches[i].BatchNumber < contents.Batches[j].BatchNumber
+	})
+	return contents, nil
+}
+
+func (r *memoryRepo) recordAccountNumberReveal(transferID, userID, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reveals[transferID] = append(r.reveals[transferID], userID)
+	return nil
+}
+
+func (r *memoryRepo) saveAccountHistory(orgID string, history client.AccountHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.appendAccountHistory(orgID, history)
+	return nil
+}
+
+// appendAccountHistory saves history unless the account's latest details are the same.
+// Callers must hold r.mu.
+func (r *memoryRepo) appendAccountHistory(orgID string, history client.AccountHistory) {
+	key := orgID + "/" + history.AccountID
+	if n := len(r.history[key]); n > 0 && sameAccountDetails(r.history[key][n-1], history) {
+		return
+	}
+	r.history[key] = append(r.history[key], history)
+}
+
+func (r *memoryRepo) getAccountHistory(ctx context.Context, orgID string, accountID string) ([]client.AccountHistory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]client.AccountHistory(nil), r.history[orgID+"/"+accountID]...), nil
+}
+
+func (r *memoryRepo) getHoldingTotals(ctx context.Context, orgID string, accountID string) (holdingTotals, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var totals holdingTotals
+	for _, xfer := range r.transfers {
+		if xfer.orgID == orgID && xfer.deletedAt == nil {
+			totals.add(accountID, &xfer.transfer)
+		}
+	}
+	return totals, nil
+}
+
+func (r *memoryRepo) createMandate(orgID string, mandate *client.Mandate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.mandates[mandate.MandateID]; exists {
+		return fmt.Errorf("mandateID=%s already exists", mandate.MandateID)
+	}
+	r.mandates[mandate.MandateID] = &memoryMandate{
+		orgID:   orgID,
+		mandate: copyMandate(*mandate),
+	}
+	return nil
+}
+
+func (r *memoryRepo) getMandate(ctx context.Context, orgID string, mandateID string) (*client.Mandate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.mandates[mandateID]
+	if !ok || m.orgID != orgID {
+		return nil, nil
+	}
+	mandate := copyMandate(m.mandate)
+	return &mandate, nil
+}
+
+func (r *memoryRepo) getMandates(ctx context.Context, orgID string, customerID string, status client.MandateStatus) ([]*client.Mandate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*client.Mandate
+	for _, m := range r.mandates {
+		if m.orgID != orgID {
+			continue
+		}
+		if customerID != "" && m.mandate.CustomerID != customerID {
+			continue
+		}
+		if status != "" && m.mandate.Status != status {
+			continue
+		}
+		mandate := copyMandate(m.mandate)
+		out = append(out, &mandate)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.After(out[j].Created)
+	})
+	return out, nil
+}
+
+func (r *memoryRepo) revokeMandate(orgID string, mandateID string, reason string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.mandates[mandateID]
+	if !ok || m.orgID != orgID || m.mandate.Status != client.ACTIVE {
+		return false, nil
+	}
+	now := time.Now()
+	m.mandate.Status = client.REVOKED
+	m.mandate.RevokedAt = &now
+	m.mandate.RevocationReason = reason
+	return true, nil
+}
+
+// copyMandate returns mandate without sharing its pointers
+func copyMandate(mandate client.Mandate) client.Mandate {
+	if mandate.MaxAmount != nil {
+		amount := *mandate.MaxAmount
+		mandate.MaxAmount = &amount
+	}
+	if mandate.RevokedAt != nil {
+		revokedAt := *mandate.RevokedAt
+		mandate.RevokedAt = &revokedAt
+	}
+	return mandate
+}
+
+func (r *memoryRepo) createEvidence(orgID string, evidence *client.AuthorizationEvidence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.evidence[evidence.EvidenceID]; exists {
+		return fmt.Errorf("evidenceID=%s already exists", evidence.EvidenceID)
+	}
+	r.evidence[evidence.EvidenceID] = &memoryEvidence{
+		orgID:    orgID,
+		evidence: *evidence,
+	}
+	return nil
+}
+
+func (r *memoryRepo) getEvidence(ctx context.Context, evidenceID string) (*client.AuthorizationEvidence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.evidence[evidenceID]
+	if !ok {
+		return nil, nil
+	}
+	evidence := e.evidence
+	return &evidence, nil
+}
+
+func (r *memoryRepo) getTransferEvidence(ctx context.Context, transferID string) ([]client.AuthorizationEvidence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var mandateID string
+	if xfer, ok := r.transfers[transferID]; ok {
+		mandateID = xfer.transfer.MandateID
+	}
+	var out []client.AuthorizationEvidence
+	for _, e := range r.evidence {
+		if e.evidence.TransferID == transferID || (mandateID != "" && e.evidence.MandateID == mandateID) {
+			out = append(out, e.evidence)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Created.Before(out[j].Created)
+	})
+	return out, nil
+}
+
+func (r *memoryRepo) SaveReturnEntry(transferID string, header *ach.BatchHeader, entry *ach.EntryDetail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if header == nil || entry == nil || entry.Addenda99 == nil {
+		return fmt.Errorf("transferID=%s missing return entry", transferID)
+	}
+	xfer, ok := r.transfers[transferID]
+	if !ok {
+		return fmt.Errorf("transferID=%s not found", transferID)
+	}
+	// keep the records so callers can't modify our stored return
+	bh, ed := parseReturnEntry(header.String(), entry.String(), entry.Addenda99.String())
+	xfer.returns = append(xfer.returns, ReturnEntry{
+		Header:  bh,
+		Entry:   ed,
+		Created: time.Now(),
+	})
+	return nil
+}
+
+func (r *memoryRepo) GetReturnEntry(transferID string) (*ReturnEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	xfer, ok := r.transfers[transferID]
+	if !ok || len(xfer.returns) == 0 {
+		return nil, nil
+	}
+	ret := xfer.returns[len(xfer.returns)-1]
+	ret.Header, ret.Entry = parseReturnEntry(ret.Header.String(), ret.Entry.String(), ret.Entry.Addenda99.String())
+	return &ret, nil
+}
+
+func (r *memoryRepo) SaveDishonoredReturnCode(transferID string, returnCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if xfer, ok := r.transfers[transferID]; ok && len(xfer.returns) > 0 {
+		xfer.returns[len(xfer.returns)-1].DishonoredReturnCode = returnCode
+	}
+	return nil
+}
+
+func (r *memoryRepo) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Match the same window as the SQL repository
+	min, max := startOfDayAndTomorrow(effectiveEntryDate)
+	min = min.Add(-5 * 24 * time.Hour)
+	max = max.Add(5 * 24 * time.Hour)
+
+	for _, xfer := range r.transfers {
+		if xfer.deletedAt != nil || xfer.transfer.Amount.Value != amount.Value || xfer.transfer.Status != client.PROCESSED {
+			continue
+		}
+		if created := xfer.transfer.Created; !created.After(min) || !created.Before(max) {
+			continue
+		}
+		for i := range xfer.transfer.TraceNumbers {
+			if xfer.transfer.TraceNumbers[i] == traceNumber {
+				return copyTransfer(xfer.transfer), nil
+			}
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepo) getAvailableTransfers(day string, limit int) ([]availableTransfer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var xfers []*memoryTransfer
+	for id := range r.transfers {
+		xfer := r.find(id)
+		if xfer == nil || xfer.fundsAvailableAt != nil || xfer.transfer.Status != client.PROCESSED {
+			continue
+		}
+		if xfer.transfer.AvailableOn != "" && xfer.transfer.AvailableOn <= day {
+			xfers = append(xfers, xfer)
+		}
+	}
+	sort.Slice(xfers, func(i, j int) bool {
+		return xfers[i].transfer.AvailableOn < xfers[j].transfer.AvailableOn
+	})
+
+	var out []availableTransfer
+	for i := 0; i < len(xfers) && i < limit; i++ {
+		out = append(out, availableTransfer{
+			transferID: xfers[i].transfer.TransferID,
+			orgID:      xfers[i].orgID,
+		})
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) setFundsAvailable(transferID string, available bool) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	xfer, ok := r.transfers[transferID]
+	if !ok || (xfer.fundsAvailableAt != nil) == available {
+		return false, nil
+	}
+	if available {
+		now := time.Now()
+		xfer.fundsAvailableAt = &now
+	} else {
+		xfer.fundsAvailableAt = nil
+	}
+	return true, nil
+}
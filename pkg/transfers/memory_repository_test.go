@@ -0,0 +1,148 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestMemoryRepository__getTransfers(t *testing.T) {
+	orgID := base.ID()
+	repo := NewInMemoryRepo()
+
+	var xfers []*client.Transfer
+	for i := 0; i < 5; i++ {
+		xfers = append(xfers, writeTransfer(t, orgID, repo))
+	}
+	writeTransfer(t, base.ID(), repo) // other organization
+	saveTraceNumbers(t, xfers[0], []string{"123", "456"}, repo)
+
+	if err := repo.UpdateTransferStatus(xfers[1].TransferID, client.FAILED); err != nil {
+		t.Fatal(err)
+	}
+
+	params := readTransferFilterParams(&http.Request{})
+	found, err := repo.getTransfers(context.Background(), orgID, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 5 {
+		t.Fatalf("got %d transfers: %#v", len(found), found)
+	}
+	for i := range found {
+		if found[i].TransferID == xfers[0].TransferID && len(found[i].TraceNumbers) != 2 {
+			t.Errorf("unexpected trace numbers: %v", found[i].TraceNumbers)
+		}
+	}
+
+	params.Status = client.FAILED
+	if found, _ := repo.getTransfers(context.Background(), orgID, params); len(found) != 1 || found[0].TransferID != xfers[1].TransferID {
+		t.Errorf("unexpected transfers: %#v", found)
+	}
+
+	params = readTransferFilterParams(&http.Request{})
+	params.CustomerIDs = []string{xfers[2].Source.CustomerID, xfers[3].Destination.CustomerID}
+	if found, _ := repo.getTransfers(context.Background(), orgID, params); len(found) != 2 {
+		t.Errorf("unexpected transfers: %#v", found)
+	}
+
+	params = readTransferFilterParams(&http.Request{})
+	params.Skip, params.Count = 1, 2
+	if found, _ := repo.getTransfers(context.Background(), orgID, params); len(found) != 2 {
+		t.Errorf("unexpected transfers: %#v", found)
+	}
+	params.Skip = 10
+	if found, _ := repo.getTransfers(context.Background(), orgID, params); len(found) != 0 {
+		t.Errorf("unexpected transfers: %#v", found)
+	}
+}
+
+func TestMemoryRepository__GetUserTransfer(t *testing.T) {
+	orgID := base.ID()
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, orgID, repo)
+
+	found, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.TransferID != xfer.TransferID {
+		t.Errorf("unexpected transfer: %v", found.TransferID)
+	}
+	if _, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, base.ID()); err != sql.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMemoryRepository__deleteUserTransfer(t *testing.T) {
+	orgID := base.ID()
+	repo := NewInMemoryRepo()
+
+	if err := repo.deleteUserTransfer(orgID, base.ID(), false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	xfer := writeTransfer(t, orgID, repo)
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetTransfer(context.Background(), xfer.TransferID); err != sql.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	xfer = writeTransfer(t, orgID, repo)
+	if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, nil); err == nil || !strings.Contains(err.Error(), "is not in PENDING status") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMemoryRepository__returns(t *testing.T) {
+	repo := NewInMemoryRepo()
+	xfer := writeTransfer(t, base.ID(), repo)
+
+	if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.saveTraceNumbers(xfer.TransferID, []string{"1234567"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.saveTraceNumbers(xfer.TransferID, []string{"1234567"}); err == nil {
+		t.Error("expected duplicate trace number error")
+	}
+
+	found, err := repo.LookupTransferFromReturn(xfer.Amount, "1234567", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.TransferID != xfer.TransferID {
+		t.Errorf("unexpected transfer: %v", found.TransferID)
+	}
+	if _, err := repo.LookupTransferFromReturn(xfer.Amount, "1234567", time.Now().Add(-30*24*time.Hour)); err != sql.ErrNoRows {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := repo.SaveReturnCode(xfer.TransferID, "R17"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveReturnCode(xfer.TransferID, "R01"); err != nil {
+		t.Fatal(err)
+	}
+	found, _ = repo.GetTransfer(context.Background(), xfer.TransferID)
+	if found.ReturnCode == nil || found.ReturnCode.Code != "R17" {
+		t.Errorf("unexpected return code: %#v", found.ReturnCode)
+	}
+}
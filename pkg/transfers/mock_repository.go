@@ -5,24 +5,42 @@
 package transfers
 
 import (
+	"context"
 	"time"
 
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 )
 
 type MockRepository struct {
 	Transfers []*client.Transfer
+	Entries   []client.TransferEntry
+	File      *client.TransferFile
+	Contents  *admin.FileContents
+	Return    *ReturnEntry
+	Messages  []pipeline.OutboxMessage
+	Queued    []queuedTransfer
+	Retries   []*client.Transfer
+	History   []client.AccountHistory
+	Approval  *admin.TransferApproval
+	Available []availableTransfer
+	Holding   holdingTotals
+	Mandates  []*client.Mandate
+	Evidence  []client.AuthorizationEvidence
 	Err       error
 }
 
-func (r *MockRepository) getTransfers(organization string, params transferFilterParams) ([]*client.Transfer, error) {
+func (r *MockRepository) getTransfers(ctx context.Context, organization string, params transferFilterParams) ([]*client.Transfer, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
 	return r.Transfers, nil
 }
 
-func (r *MockRepository) GetTransfer(id string) (*client.Transfer, error) {
+func (r *MockRepository) GetTransfer(ctx context.Context, id string) (*client.Transfer, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
@@ -32,6 +50,22 @@ func (r *MockRepository) GetTransfer(id string) (*client.Transfer, error) {
 	return nil, nil
 }
 
+func (r *MockRepository) GetUserTransfer(ctx context.Context, transferID string, orgID string) (*client.Transfer, error) {
+	return r.GetTransfer(ctx, transferID)
+}
+
+func (r *MockRepository) getTransferByExternalID(ctx context.Context, orgID string, externalID string) (*client.Transfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].ExternalID == externalID {
+			return r.Transfers[i], nil
+		}
+	}
+	return nil, nil
+}
+
 func (r *MockRepository) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
 	return r.Err
 }
@@ -40,7 +74,96 @@ func (r *MockRepository) WriteUserTransfer(organization string, transfer *client
 	return r.Err
 }
 
-func (r *MockRepository) deleteUserTransfer(organization string, transferID string) error {
+func (r *MockRepository) WriteUserTransfers(organization string, xfers []UserTransfer) error {
+	return r.Err
+}
+
+func (r *MockRepository) createUserTransfer(organization string, transfer *client.Transfer, orig origination) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Messages = append(r.Messages, orig.msgs...)
+	return nil
+}
+
+func (r *MockRepository) createTransferGroup(organization string, transfers []*client.Transfer, origs []origination) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range origs {
+		r.Messages = append(r.Messages, origs[i].msgs...)
+	}
+	return nil
+}
+
+func (r *MockRepository) getTransferGroup(ctx context.Context, organization string, groupID string) ([]*client.Transfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var transfers []*client.Transfer
+	for i := range r.Transfers {
+		if r.Transfers[i].GroupID == groupID {
+			transfers = append(transfers, r.Transfers[i])
+		}
+	}
+	return transfers, nil
+}
+
+func (r *MockRepository) deleteUserTransfer(organization string, transferID string, force bool, msgs []pipeline.OutboxMessage) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Messages = append(r.Messages, msgs...)
+	return nil
+}
+
+func (r *MockRepository) createReviewableTransfer(organization string, transfer *client.Transfer, requestedBy string, orig origination) error {
+	return r.Err
+}
+
+func (r *MockRepository) GetTransferApproval(transferID string) (*admin.TransferApproval, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Approval, nil
+}
+
+func (r *MockRepository) approveTransfer(transferID string, approvedBy string) error {
+	return r.Err
+}
+
+func (r *MockRepository) enqueueUserTransfer(organization string, transfer *client.Transfer) error {
+	return r.Err
+}
+
+func (r *MockRepository) enqueueRetryTransfer(originalID string, retry *client.Transfer, availableAt time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Retries = append(r.Retries, retry)
+	return nil
+}
+
+func (r *MockRepository) claimQueuedTransfers(limit int) ([]queuedTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Queued, nil
+}
+
+func (r *MockRepository) completeQueuedTransfer(transfer *client.Transfer, orig origination) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Messages = append(r.Messages, orig.msgs...)
+	return nil
+}
+
+func (r *MockRepository) retryQueuedTransfer(transferID string) error {
+	return r.Err
+}
+
+func (r *MockRepository) failQueuedTransfer(transferID string) error {
 	return r.Err
 }
 
@@ -52,6 +175,115 @@ func (r *MockRepository) saveTraceNumbers(transferID string, traceNumbers []stri
 	return r.Err
 }
 
+func (r *MockRepository) saveTransferEntries(transferID string, entries []client.TransferEntry) error {
+	return r.Err
+}
+
+func (r *MockRepository) getTransferEntries(ctx context.Context, transferID string) ([]client.TransferEntry, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Entries, nil
+}
+
+func (r *MockRepository) getTransferFile(ctx context.Context, transferID string) (*client.TransferFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.File, nil
+}
+
+func (r *MockRepository) getFileContents(ctx context.Context, filename string) (*admin.FileContents, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Contents, nil
+}
+
+func (r *MockRepository) recordAccountNumberReveal(transferID, userID, reason string) error {
+	return r.Err
+}
+
+func (r *MockRepository) saveAccountHistory(orgID string, history client.AccountHistory) error {
+	return r.Err
+}
+
+func (r *MockRepository) getAccountHistory(ctx context.Context, orgID string, accountID string) ([]client.AccountHistory, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.History, nil
+}
+
+func (r *MockRepository) getHoldingTotals(ctx context.Context, orgID string, accountID string) (holdingTotals, error) {
+	return r.Holding, r.Err
+}
+
+func (r *MockRepository) createMandate(orgID string, mandate *client.Mandate) error {
+	return r.Err
+}
+
+func (r *MockRepository) getMandate(ctx context.Context, orgID string, mandateID string) (*client.Mandate, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Mandates {
+		if r.Mandates[i].MandateID == mandateID {
+			return r.Mandates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) getMandates(ctx context.Context, orgID string, customerID string, status client.MandateStatus) ([]*client.Mandate, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Mandates, nil
+}
+
+func (r *MockRepository) revokeMandate(orgID string, mandateID string, reason string) (bool, error) {
+	return r.Err == nil, r.Err
+}
+
+func (r *MockRepository) createEvidence(orgID string, evidence *client.AuthorizationEvidence) error {
+	return r.Err
+}
+
+func (r *MockRepository) getEvidence(ctx context.Context, evidenceID string) (*client.AuthorizationEvidence, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Evidence {
+		if r.Evidence[i].EvidenceID == evidenceID {
+			return &r.Evidence[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) getTransferEvidence(ctx context.Context, transferID string) ([]client.AuthorizationEvidence, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Evidence, nil
+}
+
+func (r *MockRepository) SaveReturnEntry(transferID string, header *ach.BatchHeader, entry *ach.EntryDetail) error {
+	return r.Err
+}
+
+func (r *MockRepository) GetReturnEntry(transferID string) (*ReturnEntry, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Return, nil
+}
+
+func (r *MockRepository) SaveDishonoredReturnCode(transferID string, returnCode string) error {
+	return r.Err
+}
+
 func (r *MockRepository) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
 	if r.Err != nil {
 		return nil, r.Err
@@ -62,9 +294,23 @@ func (r *MockRepository) LookupTransferFromReturn(amount client.Amount, traceNum
 	return nil, nil
 }
 
-func (r *MockRepository) getTraceNumbers(transferID string) ([]string, error) {
+func (r *MockRepository) getTraceNumbers(ctx context.Context, transferID string) ([]string, error) {
 	return []string{
 		"123",
 		"245",
 	}, nil
 }
+
+func (r *MockRepository) getAvailableTransfers(day string, limit int) ([]availableTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Available, nil
+}
+
+func (r *MockRepository) setFundsAvailable(transferID string, available bool) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	return true, nil
+}
@@ -10,7 +10,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"strings"
 	"time"
 
@@ -18,6 +17,7 @@ import (
 	"github.com/moov-io/base"
 
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/jobs"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/audittrail"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/notify"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline/output"
@@ -44,12 +44,19 @@ type XferAggregator struct {
 	repo Repository
 
 	merger       XferMerging
+	shards       *Shards
 	subscription *pubsub.Subscription
 
-	cutoffCallbacks []CutoffCallback
-	cutoffTrigger   chan manuallyTriggeredCutoff
+	cutoffCallbacks    []CutoffCallback
+	completedCallbacks []CompletedCutoffCallback
+	cutoffTrigger      chan manuallyTriggeredCutoff
+	job                *jobs.Job
+
+	// uploadedFiles holds each file uploaded during the current cutoff window
+	uploadedFiles []UploadedFile
 
 	auditStorage          audittrail.Storage
+	retention             *FileRetention
 	preuploadTransformers []transform.PreUpload
 	outputFormatter       output.Formatter
 }
@@ -62,8 +69,8 @@ func NewAggregator(
 	sub *pubsub.Subscription,
 	cutoffCallbacks []CutoffCallback,
 ) (*XferAggregator, error) {
-	cfg.Logger = cfg.Logger.Set("service", log.String("XferAggregator"))
-	notifier, err := notify.NewMultiSender(cfg.Logger, cfg.Pipeline.Notifications)
+	logger := cfg.Logger.Set("service", log.String("XferAggregator"))
+	notifier, err := notify.NewMultiSender(logger, cfg.Pipeline.Notifications)
 	if err != nil {
 		return nil, err
 	}
@@ -72,23 +79,23 @@ func NewAggregator(
 	if err != nil {
 		return nil, err
 	}
-	cfg.Logger.Logf("setup %T audit storage", auditStorage)
+	logger.Logf("setup %T audit storage", auditStorage)
 
-	preuploadTransformers, err := transform.Multi(cfg.Logger, cfg.Pipeline.PreUpload)
+	preuploadTransformers, err := transform.Multi(logger, cfg.Pipeline.PreUpload)
 	if err != nil {
 		return nil, err
 	}
-	cfg.Logger.Logf("setup %#v pre-upload transformers", preuploadTransformers)
+	logger.Logf("setup %#v pre-upload transformers", preuploadTransformers)
 
 	outputFormatter, err := output.NewFormatter(cfg.Pipeline.Output)
 	if err != nil {
 		return nil, err
 	}
-	cfg.Logger.Logf("setup %T output formatter", outputFormatter)
+	logger.Logf("setup %T output formatter", outputFormatter)
 
 	return &XferAggregator{
 		cfg:                   cfg,
-		logger:                cfg.Logger,
+		logger:                logger,
 		agent:                 agent,
 		notifier:              notifier,
 		repo:                  repo,
@@ -127,12 +134,110 @@ func (xfagg *XferAggregator) processCutoffCallbacks() error {
 	return el.Err()
 }
 
+// CompletedCutoff describes the files and Transfers uploaded during a cutoff window.
+type CompletedCutoff struct {
+	When      time.Time
+	Filenames []string
+	Files     []UploadedFile
+	Transfers []ProcessedTransfer
+}
+
+// UploadedFile is a merged ACH file as it was uploaded to the ODFI.
+type UploadedFile struct {
+	Filename string
+	File     *ach.File
+
+	// Verified is true when the remote copy was compared against what was sent
+	Verified bool
+}
+
+// ProcessedTransfer holds the ACH totals of a Transfer which was uploaded.
+// Dollar amounts are in cents.
+type ProcessedTransfer struct {
+	TransferID  string
+	EntryCount  int
+	DebitTotal  int
+	CreditTotal int
+
+	// Filenames are the uploaded files which hold the Transfer's entries
+	Filenames []string
+}
+
+// uploadedFilenames returns the filename of each entry in files keyed by its trace number.
+func uploadedFilenames(files []UploadedFile) map[string]string {
+	out := make(map[string]string)
+	for i := range files {
+		if files[i].File == nil {
+			continue
+		}
+		for j := range files[i].File.Batches {
+			entries := files[i].File.Batches[j].GetEntries()
+			for k := range entries {
+				out[entries[k].TraceNumber] = files[i].Filename
+			}
+		}
+	}
+	return out
+}
+
+// CompletedCutoffCallback is a function called after cutoff processing has finished
+// and the Transfers are marked as processed.
+type CompletedCutoffCallback func(CompletedCutoff) error
+
+// OnCompletedCutoff registers fn to be called after each successful cutoff window.
+func (xfagg *XferAggregator) OnCompletedCutoff(fn CompletedCutoffCallback) {
+	xfagg.completedCallbacks = append(xfagg.completedCallbacks, fn)
+}
+
+func (xfagg *XferAggregator) processCompletedCallbacks(when time.Time, processed *processedTransfers) {
+	if xfagg == nil || len(xfagg.completedCallbacks) == 0 || processed == nil {
+		return
+	}
+
+	completed := CompletedCutoff{
+		When:  when,
+		Files: xfagg.uploadedFiles,
+	}
+	for i := range xfagg.uploadedFiles {
+		completed.Filenames = append(completed.Filenames, xfagg.uploadedFiles[i].Filename)
+	}
+	filenames := uploadedFilenames(xfagg.uploadedFiles)
+	for i := range processed.transferIDs {
+		xfer, ok := processed.entries[processed.transferIDs[i]]
+		if !ok {
+			xfer = ProcessedTransfer{TransferID: processed.transferIDs[i]}
+		}
+		xfer.Filenames = processed.filenames(xfer.TransferID, filenames)
+		completed.Transfers = append(completed.Transfers, xfer)
+	}
+	for i := range xfagg.completedCallbacks {
+		if err := xfagg.completedCallbacks[i](completed); err != nil {
+			xfagg.logger.LogErrorf("ERROR with completed cutoff callback: %v", err)
+		}
+	}
+}
+
 // receive each message of *pubsub.Subscription, detect message type
 //   - if Xfer, write into ./mergable/
 //   - if Xfer, write/rename as ./mergable/foo.ach.deleted ?
 //   - on cutoff merge files
 
+// UseJobs records each cutoff window of the XferAggregator as the "pipeline.uploads" job of reg.
+// Triggering the job runs a manual cutoff.
+func (xfagg *XferAggregator) UseJobs(reg *jobs.Registry) {
+	xfagg.job = reg.Register("pipeline.uploads")
+}
+
 func (xfagg *XferAggregator) Start(ctx context.Context, cutoffs *schedule.CutoffTimes) {
+	var renewals <-chan time.Time
+	if xfagg.shards != nil {
+		ticker := time.NewTicker(xfagg.shards.interval())
+		defer ticker.Stop()
+		renewals = ticker.C
+		xfagg.renewShards()
+	}
+	xfagg.job.Scheduled(cutoffs.Next())
+
 	for {
 		select {
 		case tt := <-cutoffs.C:
@@ -140,6 +245,10 @@ func (xfagg *XferAggregator) Start(ctx context.Context, cutoffs *schedule.Cutoff
 				xfagg.logger.LogErrorf("ERROR with cutoff callbacks: %v", err)
 			}
 			xfagg.withEachFile(tt)
+			xfagg.job.Scheduled(cutoffs.Next())
+
+		case <-renewals:
+			xfagg.renewShards()
 
 		case waiter := <-xfagg.cutoffTrigger:
 			if err := xfagg.processCutoffCallbacks(); err != nil {
@@ -147,6 +256,14 @@ func (xfagg *XferAggregator) Start(ctx context.Context, cutoffs *schedule.Cutoff
 			}
 			xfagg.manualCutoff(waiter)
 
+		case <-xfagg.job.Triggered():
+			if err := xfagg.processCutoffCallbacks(); err != nil {
+				xfagg.logger.LogErrorf("ERROR with manual cutoff callbacks: %v", err)
+			}
+			xfagg.manualCutoff(manuallyTriggeredCutoff{
+				C: make(chan error, 1),
+			})
+
 		case err := <-xfagg.await():
 			if err != nil {
 				xfagg.logger.LogErrorf("ERROR handling message: %v", err)
@@ -180,17 +297,23 @@ func (xfagg *XferAggregator) runTransformers(outgoing *ach.File) error {
 
 func (xfagg *XferAggregator) manualCutoff(waiter manuallyTriggeredCutoff) {
 	xfagg.logger.Log("starting manual cutoff window processing")
+	xfagg.uploadedFiles = nil
 
 	if processed, err := xfagg.merger.WithEachMerged(xfagg.runTransformers); err != nil {
 		xfagg.logger.LogErrorf("ERROR inside manual WithEachMerged: %v", err)
+		xfagg.job.Done(err)
 		waiter.C <- err
 	} else {
 		if err := xfagg.repo.MarkTransfersAsProcessed(processed.transferIDs); err != nil {
 			xfagg.logger.LogErrorf("ERROR marking %d transfers as processed: %v", len(processed.transferIDs), err)
+			xfagg.job.Done(err)
 			waiter.C <- err
 		} else {
+			xfagg.processCompletedCallbacks(time.Now(), processed)
+			xfagg.job.Done(nil)
 			waiter.C <- nil
 		}
+		xfagg.releaseShards()
 	}
 
 	xfagg.logger.Log("ended manual cutoff window processing")
@@ -200,13 +323,20 @@ func (xfagg *XferAggregator) withEachFile(when time.Time) {
 	window := when.Format("15:04")
 	tzname, _ := when.Zone()
 	xfagg.logger.Logf("starting %s %s cutoff window processing", window, tzname)
+	xfagg.uploadedFiles = nil
 
 	if processed, err := xfagg.merger.WithEachMerged(xfagg.runTransformers); err != nil {
 		xfagg.logger.LogErrorf("ERROR inside WithEachMerged: %v", err)
+		xfagg.job.Done(err)
 	} else {
 		if err := xfagg.repo.MarkTransfersAsProcessed(processed.transferIDs); err != nil {
 			xfagg.logger.LogErrorf("ERROR marking %d transfers as processed: %v", len(processed.transferIDs), err)
+			xfagg.job.Done(err)
+		} else {
+			xfagg.processCompletedCallbacks(when, processed)
+			xfagg.job.Done(nil)
 		}
+		xfagg.releaseShards()
 	}
 
 	xfagg.logger.Logf("ended %s %s cutoff window processing", window, tzname)
@@ -226,6 +356,12 @@ func (xfagg *XferAggregator) uploadFile(res *transform.Result) error {
 		return fmt.Errorf("problem rendering filename template: %v", err)
 	}
 
+	// Compare the file against recent uploads before it's saved or uploaded
+	fp := fingerprintFile(res.File)
+	if err := xfagg.checkDuplicates(fp); err != nil {
+		return err
+	}
+
 	var buf bytes.Buffer
 	if err := xfagg.outputFormatter.Format(&buf, res); err != nil {
 		return fmt.Errorf("problem formatting output: %v", err)
@@ -236,11 +372,25 @@ func (xfagg *XferAggregator) uploadFile(res *transform.Result) error {
 		return fmt.Errorf("problem saving file in audit record: %v", err)
 	}
 
-	// Upload our file
-	err = xfagg.agent.UploadFile(upload.File{
-		Filename: filename,
-		Contents: ioutil.NopCloser(&buf),
-	})
+	// Keep the file as it's uploaded so it can be uploaded again
+	archiveKey, err := xfagg.retention.retain(filename, buf.Bytes(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Upload our file into the directory for its batches and optionally verify the remote copy
+	agent := upload.ForFile(xfagg.agent, res.File)
+	verified, err := upload.UploadVerified(xfagg.logger, agent, filename, buf.Bytes(), xfagg.cfg.ODFI.Verification)
+	recordUploadAttempt(xfagg.logger, xfagg.repo, agent, filename, buf.Bytes(), archiveKey, verified, err)
+
+	if err == nil {
+		xfagg.uploadedFiles = append(xfagg.uploadedFiles, UploadedFile{
+			Filename: filename,
+			File:     res.File,
+			Verified: verified,
+		})
+		xfagg.saveFingerprint(filename, fp)
+	}
 
 	// Send Slack/PD or whatever notifications after the file is uploaded
 	xfagg.notifyAfterUpload(filename, res.File, err)
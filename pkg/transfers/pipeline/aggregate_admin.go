@@ -5,11 +5,11 @@
 package pipeline
 
 import (
-	"fmt"
 	"net/http"
 
 	"github.com/moov-io/base/admin"
-	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/x/route"
 )
 
 func (xfagg *XferAggregator) RegisterRoutes(svc *admin.Server) {
@@ -23,7 +23,7 @@ type manuallyTriggeredCutoff struct {
 func (xfagg *XferAggregator) triggerManualCutoff() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
-			moovhttp.Problem(w, fmt.Errorf("invalid method %s", r.Method))
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
 			return
 		}
 
@@ -35,7 +35,7 @@ func (xfagg *XferAggregator) triggerManualCutoff() http.HandlerFunc {
 
 		if err := <-waiter.C; err != nil {
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
-			moovhttp.Problem(w, err)
+			route.Problem(w, route.Internal.Wrap(err))
 		} else {
 			w.WriteHeader(http.StatusOK)
 		}
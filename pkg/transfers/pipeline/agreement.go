@@ -0,0 +1,117 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// failTransfers marks each of transferIDs as FAILED
+func (r *sqlRepo) failTransfers(transferIDs []string) error {
+	defer database.MeasureQuery("pipeline", "failTransfers")()
+
+	now := time.Now()
+	for i := range transferIDs {
+		query := `update transfers set status = ?, last_updated_at = ? where transfer_id = ? and deleted_at is null;`
+		if _, err := r.db.Exec(query, client.FAILED, now, transferIDs[i]); err != nil {
+			return fmt.Errorf("failing transferID=%s: %v", transferIDs[i], err)
+		}
+	}
+	return nil
+}
+
+// skipViolations fails the Transfers of matches whose files break the ODFI's agreement,
+// which happens when rules are added after they were created, and returns the remaining
+// matches. Files of failed Transfers are renamed like canceled Transfers so they're never merged.
+func (m *filesystemMerging) skipViolations(matches []string) ([]string, error) {
+	if m.odfi.Agreement == nil || len(matches) == 0 {
+		return matches, nil
+	}
+	now := time.Now()
+	if loc := m.odfi.Cutoffs.Location(); loc != nil {
+		now = now.In(loc)
+	}
+
+	var out, failed []string
+	for i := range matches {
+		file, err := ach.ReadFile(matches[i])
+		if err != nil {
+			// unreadable files are reported when they're merged
+			out = append(out, matches[i])
+			continue
+		}
+		if err := achx.CheckAgreement(m.odfi.Agreement, file, m.odfi.RoutingNumber, now); err != nil {
+			transferID := strings.TrimSuffix(filepath.Base(matches[i]), ".ach")
+			m.logger.With(log.Fields{
+				"transferID": log.String(transferID),
+			}).LogErrorf("failing transfer: %v", err)
+			failed = append(failed, matches[i])
+			continue
+		}
+		out = append(out, matches[i])
+	}
+	if len(failed) == 0 {
+		return matches, nil
+	}
+
+	if m.repo != nil {
+		transferIDs := make([]string, len(failed))
+		for i := range failed {
+			transferIDs[i] = strings.TrimSuffix(filepath.Base(failed[i]), ".ach")
+		}
+		if err := m.repo.failTransfers(transferIDs); err != nil {
+			return nil, err
+		}
+	}
+	for i := range failed {
+		if err := os.Rename(failed[i], failed[i]+".canceled"); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// mergeFiles merges files into as few files as possible without putting more than
+// maxEntries entries into one. Files are merged in order so same-day entries stay first.
+func mergeFiles(files []*ach.File, maxEntries int) ([]*ach.File, error) {
+	if maxEntries <= 0 {
+		return ach.MergeFiles(files)
+	}
+
+	var out, group []*ach.File
+	var entries int
+	for i := range files {
+		n := achx.AgreementEntries(files[i])
+		if len(group) > 0 && entries+n > maxEntries {
+			merged, err := ach.MergeFiles(group)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, merged...)
+			group, entries = nil, 0
+		}
+		group = append(group, files[i])
+		entries += n
+	}
+	if len(group) > 0 {
+		merged, err := ach.MergeFiles(group)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, merged...)
+	}
+	return out, nil
+}
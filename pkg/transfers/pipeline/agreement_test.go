@@ -0,0 +1,120 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func readAgreementFile(t *testing.T, traceNumber int) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Batches[0].GetEntries()[0].TraceNumber = fmt.Sprintf("07640125%07d", traceNumber)
+	if err := file.Create(); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestMerging__skipViolations(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	dir := internal.TestDir(t)
+
+	var matches []string
+	for _, transferID := range []string{writeAccountTransfer(t, repo, base.ID(), base.ID()), writeAccountTransfer(t, repo, base.ID(), base.ID())} {
+		file := readAgreementFile(t, len(matches)+1)
+		if len(matches) == 0 {
+			ed := *file.Batches[0].GetEntries()[0]
+			ed.TraceNumber = "076401250000009"
+			file.Batches[0].AddEntry(&ed)
+			if err := file.Batches[0].Create(); err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Create(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf bytes.Buffer
+		if err := ach.NewWriter(&buf).Write(file); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, transferID+".ach")
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+			t.Fatal(err)
+		}
+		matches = append(matches, path)
+	}
+
+	m := &filesystemMerging{
+		odfi: config.ODFI{
+			RoutingNumber: "076401251",
+			Agreement: &config.Agreement{
+				MaxEntriesPerFile: 1,
+			},
+		},
+		repo:   repo,
+		logger: log.NewNopLogger(),
+	}
+	out, err := m.skipViolations(matches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0] != matches[1] {
+		t.Errorf("unexpected matches: %v", out)
+	}
+	if _, err := os.Stat(matches[0] + ".canceled"); err != nil {
+		t.Errorf("expected violating file to be canceled: %v", err)
+	}
+	transferID := filepath.Base(matches[0])
+	transferID = transferID[:len(transferID)-len(".ach")]
+	if xfer := getPartialTransferModel(t, repo, transferID); xfer.Status != client.FAILED {
+		t.Errorf("unexpected status: %v", xfer.Status)
+	}
+}
+
+func TestMergeFiles__maxEntries(t *testing.T) {
+	var files []*ach.File
+	for i := 1; i <= 5; i++ {
+		files = append(files, readAgreementFile(t, i))
+	}
+
+	merged, err := mergeFiles(files, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 {
+		t.Errorf("unexpected files: %d", len(merged))
+	}
+
+	merged, err = mergeFiles(files, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 3 {
+		t.Fatalf("unexpected files: %d", len(merged))
+	}
+	for i := range merged {
+		if n := achx.AgreementEntries(merged[i]); n > 2 {
+			t.Errorf("file %d has %d entries", i, n)
+		}
+	}
+}
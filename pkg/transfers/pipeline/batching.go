@@ -0,0 +1,116 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/moov-io/ach"
+)
+
+// batchKey is what batches must share in order to be combined into one batch.
+type batchKey struct {
+	companyName        string
+	companyID          string
+	sec                string
+	description        string
+	descriptiveDate    string
+	effectiveEntryDate string
+}
+
+func keyOf(bh *ach.BatchHeader) batchKey {
+	return batchKey{
+		companyName:        bh.CompanyName,
+		companyID:          bh.CompanyIdentification,
+		sec:                bh.StandardEntryClassCode,
+		description:        bh.CompanyEntryDescription,
+		descriptiveDate:    bh.CompanyDescriptiveDate,
+		effectiveEntryDate: bh.EffectiveEntryDate,
+	}
+}
+
+// consolidateBatches combines batches of file whose entries all have trace numbers in traces
+// and share their company, SEC code and effective date. Other batches are left as-is.
+func consolidateBatches(file *ach.File, traces map[string]bool) error {
+	if file == nil || len(traces) == 0 {
+		return nil
+	}
+
+	var batches []ach.Batcher
+	targets := make(map[batchKey]ach.Batcher)
+	modified := make(map[ach.Batcher]bool)
+	for i := range file.Batches {
+		batch := file.Batches[i]
+		if !consolidatable(batch, traces) {
+			batches = append(batches, batch)
+			continue
+		}
+		key := keyOf(batch.GetHeader())
+		target, exists := targets[key]
+		if !exists {
+			targets[key] = batch
+			batches = append(batches, batch)
+			continue
+		}
+		entries := batch.GetEntries()
+		for j := range entries {
+			target.AddEntry(entries[j])
+		}
+		modified[target] = true
+	}
+	if len(modified) == 0 {
+		return nil
+	}
+
+	for batch := range modified {
+		entries := batch.GetEntries()
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].TraceNumber < entries[j].TraceNumber
+		})
+		batch.GetHeader().ServiceClassCode = serviceClassCode(entries)
+		if err := batch.Create(); err != nil {
+			return fmt.Errorf("batch %d: %v", batch.GetHeader().BatchNumber, err)
+		}
+	}
+
+	file.Batches = batches
+	for i := range file.Batches {
+		file.Batches[i].GetHeader().BatchNumber = i + 1
+	}
+	return file.Create()
+}
+
+func consolidatable(batch ach.Batcher, traces map[string]bool) bool {
+	entries := batch.GetEntries()
+	if len(entries) == 0 {
+		return false
+	}
+	for i := range entries {
+		if !traces[entries[i].TraceNumber] {
+			return false
+		}
+	}
+	return true
+}
+
+func serviceClassCode(entries []*ach.EntryDetail) int {
+	var credits, debits bool
+	for i := range entries {
+		if entries[i].CreditOrDebit() == "D" {
+			debits = true
+		} else {
+			credits = true
+		}
+	}
+	switch {
+	case credits && debits:
+		return ach.MixedDebitsAndCredits
+	case debits:
+		return ach.DebitsOnly
+	default:
+		return ach.CreditsOnly
+	}
+}
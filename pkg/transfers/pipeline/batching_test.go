@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+)
+
+func readBatchingTestFile(t *testing.T) (*ach.File, map[string]bool) {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "two-micro-deposits.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := make(map[string]bool)
+	for i := range file.Batches {
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			traces[entries[j].TraceNumber] = true
+		}
+	}
+	return file, traces
+}
+
+func TestBatching__consolidate(t *testing.T) {
+	file, traces := readBatchingTestFile(t)
+
+	if err := consolidateBatches(file, traces); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Batches) != 1 {
+		t.Fatalf("unexpected batches: %d", len(file.Batches))
+	}
+	if n := len(file.Batches[0].GetEntries()); n != 6 {
+		t.Errorf("unexpected entries: %d", n)
+	}
+	if code := file.Batches[0].GetHeader().ServiceClassCode; code != ach.MixedDebitsAndCredits {
+		t.Errorf("unexpected service class code: %d", code)
+	}
+	if file.Control.BatchCount != 1 || file.Control.TotalDebitEntryDollarAmountInFile != 120 {
+		t.Errorf("unexpected file control: %#v", file.Control)
+	}
+}
+
+func TestBatching__perTransfer(t *testing.T) {
+	file, traces := readBatchingTestFile(t)
+
+	// entries of the second batch weren't marked for consolidation
+	for _, entry := range file.Batches[1].GetEntries() {
+		delete(traces, entry.TraceNumber)
+	}
+	if err := consolidateBatches(file, traces); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Batches) != 2 {
+		t.Errorf("unexpected batches: %d", len(file.Batches))
+	}
+
+	if err := consolidateBatches(file, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Batches) != 2 {
+		t.Errorf("unexpected batches: %d", len(file.Batches))
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// blockedTransfersBatchSize limits the bind parameters of each blocked transfers query
+const blockedTransfersBatchSize = 500
+
+// cancelBlockedTransfers marks each of transferIDs which debits or credits an account with an
+// active block as CANCELED and returns their transferIDs. Blocks are saved by ./pkg/customers/blocks/
+func (r *sqlRepo) cancelBlockedTransfers(transferIDs []string) ([]string, error) {
+	defer database.MeasureQuery("pipeline", "cancelBlockedTransfers")()
+
+	var blocked []string
+	for start := 0; start < len(transferIDs); start += blockedTransfersBatchSize {
+		end := start + blockedTransfersBatchSize
+		if end > len(transferIDs) {
+			end = len(transferIDs)
+		}
+		ids := transferIDs[start:end]
+
+		query := fmt.Sprintf(`select distinct t.transfer_id from transfers as t
+inner join account_blocks as b on (b.account_id = t.source_account_id and b.direction in ('debits', 'both'))
+  or (b.account_id = t.destination_account_id and b.direction in ('credits', 'both'))
+where b.removed_at is null and t.deleted_at is null and t.transfer_id in (%s);`, database.Placeholders(len(ids)))
+
+		err := database.QueryRows(r.db, "cancelBlockedTransfers", query, database.StringArgs(ids), func(rows *sql.Rows) error {
+			var transferID string
+			if err := rows.Scan(&transferID); err != nil {
+				return err
+			}
+			blocked = append(blocked, transferID)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	for i := range blocked {
+		query := `update transfers set status = ?, last_updated_at = ? where transfer_id = ? and deleted_at is null;`
+		if _, err := r.db.Exec(query, client.CANCELED, now, blocked[i]); err != nil {
+			return nil, fmt.Errorf("canceling blocked transferID=%s: %v", blocked[i], err)
+		}
+	}
+	return blocked, nil
+}
+
+// skipBlocked cancels the Transfers of matches whose accounts were blocked after they were
+// created and returns the remaining matches. Files of blocked Transfers are renamed like
+// canceled Transfers so they're never merged.
+func (m *filesystemMerging) skipBlocked(matches []string) ([]string, error) {
+	if m.repo == nil || len(matches) == 0 {
+		return matches, nil
+	}
+
+	transferIDs := make([]string, len(matches))
+	for i := range matches {
+		transferIDs[i] = strings.TrimSuffix(filepath.Base(matches[i]), ".ach")
+	}
+	blocked, err := m.repo.cancelBlockedTransfers(transferIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocked) == 0 {
+		return matches, nil
+	}
+	skip := make(map[string]bool)
+	for i := range blocked {
+		skip[blocked[i]] = true
+	}
+
+	var out []string
+	for i := range matches {
+		if !skip[transferIDs[i]] {
+			out = append(out, matches[i])
+			continue
+		}
+		m.logger.Logf("canceled transferID=%s of a blocked account", transferIDs[i])
+		if err := os.Rename(matches[i], matches[i]+".canceled"); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
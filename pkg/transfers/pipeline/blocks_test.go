@@ -0,0 +1,109 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func writeAccountTransfer(t *testing.T, repo *sqlRepo, sourceAccountID, destinationAccountID string) string {
+	transferID := base.ID()
+	query := `insert into transfers (transfer_id, status, source_account_id, destination_account_id) values (?, ?, ?, ?);`
+	if _, err := repo.db.Exec(query, transferID, client.PENDING, sourceAccountID, destinationAccountID); err != nil {
+		t.Fatal(err)
+	}
+	return transferID
+}
+
+func writeAccountBlock(t *testing.T, repo *sqlRepo, accountID, direction string, removed bool) {
+	var removedAt *time.Time
+	if removed {
+		now := time.Now()
+		removedAt = &now
+	}
+	query := `insert into account_blocks (block_id, organization, customer_id, account_id, direction, reason, created_by, created_at, removed_at)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	if _, err := repo.db.Exec(query, base.ID(), "moov", base.ID(), accountID, direction, "stop payment", "jane", time.Now(), removedAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepository__cancelBlockedTransfers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		debitsBlocked, creditsBlocked, removed := base.ID(), base.ID(), base.ID()
+		writeAccountBlock(t, repo, debitsBlocked, "debits", false)
+		writeAccountBlock(t, repo, creditsBlocked, "credits", false)
+		writeAccountBlock(t, repo, removed, "both", true)
+
+		debit := writeAccountTransfer(t, repo, debitsBlocked, base.ID())
+		credit := writeAccountTransfer(t, repo, base.ID(), creditsBlocked)
+		allowed := []string{
+			writeAccountTransfer(t, repo, base.ID(), debitsBlocked),  // credits are allowed
+			writeAccountTransfer(t, repo, creditsBlocked, base.ID()), // debits are allowed
+			writeAccountTransfer(t, repo, removed, removed),
+		}
+
+		blocked, err := repo.cancelBlockedTransfers(append([]string{debit, credit}, allowed...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blocked) != 2 {
+			t.Fatalf("unexpected blocked transfers: %v", blocked)
+		}
+		for _, transferID := range []string{debit, credit} {
+			if xfer := getPartialTransferModel(t, repo, transferID); xfer.Status != client.CANCELED {
+				t.Errorf("transferID=%s has unexpected status: %v", transferID, xfer.Status)
+			}
+		}
+		for _, transferID := range allowed {
+			if xfer := getPartialTransferModel(t, repo, transferID); xfer.Status != client.PENDING {
+				t.Errorf("transferID=%s has unexpected status: %v", transferID, xfer.Status)
+			}
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestMerging__skipBlocked(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	dir := internal.TestDir(t)
+
+	accountID := base.ID()
+	writeAccountBlock(t, repo, accountID, "both", false)
+
+	var matches []string
+	for _, transferID := range []string{writeAccountTransfer(t, repo, accountID, base.ID()), writeAccountTransfer(t, repo, base.ID(), base.ID())} {
+		path := filepath.Join(dir, transferID+".ach")
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		matches = append(matches, path)
+	}
+
+	m := &filesystemMerging{repo: repo, logger: log.NewNopLogger()}
+	out, err := m.skipBlocked(matches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0] != matches[1] {
+		t.Errorf("unexpected matches: %v", out)
+	}
+	if _, err := os.Stat(matches[0] + ".canceled"); err != nil {
+		t.Errorf("expected blocked file to be canceled: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	duplicateFiles = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "duplicate_files_detected",
+		Help: "Counter of outbound files matching a recently uploaded file",
+	}, []string{"blocked"})
+
+	duplicateEntries = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "duplicate_entries_detected",
+		Help: "Counter of outbound entries matching another entry uploaded recently or in the same file",
+	}, nil)
+)
+
+// fingerprint identifies the entries of a file independent of the fields which change
+// when the same Transfers are originated again (trace numbers, file creation time, etc).
+type fingerprint struct {
+	FileHash    string
+	EntryHashes []string
+
+	// TraceNumbers are the trace numbers of each entry in EntryHashes
+	TraceNumbers []string
+
+	EntryCount  int
+	DebitTotal  int
+	CreditTotal int
+}
+
+func fingerprintFile(file *ach.File) fingerprint {
+	var fp fingerprint
+	if file == nil {
+		return fp
+	}
+	for i := range file.Batches {
+		bh := file.Batches[i].GetHeader()
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			fp.EntryHashes = append(fp.EntryHashes, hashEntry(bh, entries[j]))
+			fp.TraceNumbers = append(fp.TraceNumbers, entries[j].TraceNumber)
+		}
+	}
+	fp.EntryCount = len(fp.EntryHashes)
+	fp.DebitTotal = file.Control.TotalDebitEntryDollarAmountInFile
+	fp.CreditTotal = file.Control.TotalCreditEntryDollarAmountInFile
+
+	sorted := make([]string, len(fp.EntryHashes))
+	copy(sorted, fp.EntryHashes)
+	sort.Strings(sorted)
+	fp.FileHash = hash([]byte(strings.Join(sorted, "\n")))
+
+	return fp
+}
+
+// hashEntry combines the fields of an entry and its batch which settle funds, including
+// the effective date, so the same payment originated twice has the same hash.
+func hashEntry(bh *ach.BatchHeader, ed *ach.EntryDetail) string {
+	fields := []string{
+		fmt.Sprintf("%d", bh.ServiceClassCode),
+		bh.StandardEntryClassCode,
+		bh.CompanyIdentification,
+		bh.CompanyEntryDescription,
+		bh.EffectiveEntryDate,
+		bh.ODFIIdentification,
+		fmt.Sprintf("%d", ed.TransactionCode),
+		ed.RDFIIdentification + ed.CheckDigit,
+		strings.TrimSpace(ed.DFIAccountNumber),
+		fmt.Sprintf("%d", ed.Amount),
+		strings.TrimSpace(ed.IdentificationNumber),
+		strings.TrimSpace(ed.IndividualName),
+	}
+	for i := range ed.Addenda05 {
+		fields = append(fields, strings.TrimSpace(ed.Addenda05[i].PaymentRelatedInformation))
+	}
+	if ed.Addenda99 != nil {
+		fields = append(fields, ed.Addenda99.ReturnCode, ed.Addenda99.OriginalTrace)
+	}
+	return hash([]byte(strings.Join(fields, "|")))
+}
+
+// checkDuplicates compares fp against the files uploaded since the configured lookback.
+// An error is returned when fp matches an uploaded file and duplicates are blocked.
+func (xfagg *XferAggregator) checkDuplicates(fp fingerprint) error {
+	cfg := xfagg.cfg.Pipeline.Duplicates
+	if cfg == nil {
+		return nil
+	}
+	since := cfg.Since(time.Now())
+
+	filename, err := xfagg.repo.getUploadedFile(fp, since)
+	if err != nil {
+		return fmt.Errorf("problem checking for duplicate file: %v", err)
+	}
+	if filename != "" {
+		duplicateFiles.With("blocked", fmt.Sprintf("%v", cfg.Block)).Add(1)
+
+		logger := xfagg.logger.With(log.Fields{
+			"uploadedFilename": log.String(filename),
+			"entryCount":       log.Int(fp.EntryCount),
+			"debitTotal":       log.Int(fp.DebitTotal),
+			"creditTotal":      log.Int(fp.CreditTotal),
+		})
+		if cfg.Block {
+			return logger.LogErrorf("blocking duplicate of file uploaded as %s", filename).Err()
+		}
+		logger.LogErrorf("uploading duplicate of file uploaded as %s", filename)
+		return nil
+	}
+
+	uploaded, err := xfagg.repo.getUploadedEntries(fp.EntryHashes, since)
+	if err != nil {
+		return fmt.Errorf("problem checking for duplicate entries: %v", err)
+	}
+	seen := make(map[string]bool)
+	for i, entryHash := range fp.EntryHashes {
+		if filename, ok := uploaded[entryHash]; ok {
+			duplicateEntries.Add(1)
+			xfagg.logger.Warn().Logf("entry with trace number %s matches an entry uploaded in %s", fp.TraceNumbers[i], filename)
+		} else if seen[entryHash] {
+			duplicateEntries.Add(1)
+			xfagg.logger.Warn().Logf("entry with trace number %s is repeated in the file", fp.TraceNumbers[i])
+		}
+		seen[entryHash] = true
+	}
+	return nil
+}
+
+// saveFingerprint records an uploaded file so later files can be compared against it.
+func (xfagg *XferAggregator) saveFingerprint(filename string, fp fingerprint) {
+	if xfagg.cfg.Pipeline.Duplicates == nil {
+		return
+	}
+	if err := xfagg.repo.saveUploadedFile(filename, fp, time.Now()); err != nil {
+		xfagg.logger.LogErrorf("problem saving fingerprint of %s: %v", filename, err)
+	}
+}
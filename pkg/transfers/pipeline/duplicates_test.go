@@ -0,0 +1,126 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func readDuplicateFile(t *testing.T) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestDuplicates__fingerprint(t *testing.T) {
+	file := readDuplicateFile(t)
+	fp := fingerprintFile(file)
+	if fp.FileHash == "" || fp.EntryCount != 1 || fp.DebitTotal != 10500 || len(fp.EntryHashes) != 1 {
+		t.Fatalf("unexpected fingerprint: %#v", fp)
+	}
+
+	// Originating the same entries again changes trace numbers and file creation times
+	retried := readDuplicateFile(t)
+	retried.Header.FileCreationTime = "0001"
+	retried.Batches[0].GetEntries()[0].TraceNumber = "053200010000001"
+	if other := fingerprintFile(retried); other.FileHash != fp.FileHash || other.EntryHashes[0] != fp.EntryHashes[0] {
+		t.Errorf("expected matching fingerprints:\n%#v\n%#v", fp, other)
+	}
+
+	// A new effective date isn't a duplicate
+	retried.Batches[0].GetHeader().EffectiveEntryDate = "200101"
+	if other := fingerprintFile(retried); other.FileHash == fp.FileHash || other.EntryHashes[0] == fp.EntryHashes[0] {
+		t.Errorf("expected different fingerprints:\n%#v\n%#v", fp, other)
+	}
+}
+
+func TestDuplicates__checkDuplicates(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	cfg := config.Empty()
+	cfg.Pipeline.Duplicates = &config.Duplicates{
+		Lookback: time.Hour,
+	}
+	xfagg := &XferAggregator{
+		cfg:    cfg,
+		logger: log.NewNopLogger(),
+		repo:   repo,
+	}
+
+	fp := fingerprintFile(readDuplicateFile(t))
+	if err := xfagg.checkDuplicates(fp); err != nil {
+		t.Fatal(err)
+	}
+	xfagg.saveFingerprint("20200618-1020-053200019.ach", fp)
+
+	// flagged, but still uploaded
+	if err := xfagg.checkDuplicates(fp); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Pipeline.Duplicates.Block = true
+	if err := xfagg.checkDuplicates(fp); err == nil {
+		t.Error("expected error")
+	}
+
+	// disabled
+	cfg.Pipeline.Duplicates = nil
+	if err := xfagg.checkDuplicates(fp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepository__UploadedFiles(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		fp := fingerprintFile(readDuplicateFile(t))
+		since := time.Now().Add(-1 * time.Hour)
+
+		if filename, err := repo.getUploadedFile(fp, since); err != nil || filename != "" {
+			t.Fatalf("filename=%q error=%v", filename, err)
+		}
+		if err := repo.saveUploadedFile("20200618-1020-053200019.ach", fp, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		filename, err := repo.getUploadedFile(fp, since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filename != "20200618-1020-053200019.ach" {
+			t.Errorf("unexpected filename: %q", filename)
+		}
+
+		entries, err := repo.getUploadedEntries(append(fp.EntryHashes, "other"), since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[fp.EntryHashes[0]] != filename {
+			t.Errorf("unexpected entries: %#v", entries)
+		}
+
+		// outside the lookback
+		if filename, err := repo.getUploadedFile(fp, time.Now().Add(time.Hour)); err != nil || filename != "" {
+			t.Errorf("filename=%q error=%v", filename, err)
+		}
+		if entries, err := repo.getUploadedEntries(fp.EntryHashes, time.Now().Add(time.Hour)); err != nil || len(entries) != 0 {
+			t.Errorf("entries=%#v error=%v", entries, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
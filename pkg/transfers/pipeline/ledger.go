@@ -0,0 +1,345 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// Each Transfer moves through these states during a cutoff. A Transfer is planned when
+// its file is picked up for merging, written once the merged file containing it is saved
+// and uploaded after that merged file is accepted by the ODFI.
+const (
+	mergePlanned  = "planned"
+	mergeWritten  = "written"
+	mergeUploaded = "uploaded"
+
+	// mergeFenced isn't recorded. It's returned by planMerge for Transfers of shards
+	// owned by another instance, which must not be merged here.
+	mergeFenced = "fenced"
+)
+
+// mergeFence returns false when transferID belongs to a shard this instance no longer
+// holds a lease on. It's checked in the transaction recording the Transfer's merge, so
+// an instance which lost the shard doesn't upload the Transfer along with its new owner.
+// A nil mergeFence holds every Transfer.
+type mergeFence func(tx *sql.Tx, transferID string) (bool, error)
+
+func (fence mergeFence) holds(tx *sql.Tx, transferID string) (bool, error) {
+	if fence == nil {
+		return true, nil
+	}
+	return fence(tx, transferID)
+}
+
+// errMergeFenced is returned by markMergeWritten when a Transfer of the merged file
+// belongs to a shard owned by another instance.
+var errMergeFenced = errors.New("shard is owned by another instance")
+
+var (
+	inDoubtMerges = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "in_doubt_merged_transfers",
+		Help: "Gauge of transfers in a merged file which was written, but not confirmed as uploaded",
+	}, nil)
+)
+
+type mergeRecord struct {
+	TransferID     string
+	CutoffDir      string
+	MergedFilename string
+	State          string
+	UpdatedAt      time.Time
+}
+
+// planMerge records transferIDs as planned for the merge in dir. Transfers which were
+// already written or uploaded by an earlier cutoff are returned with their state and
+// must not be merged again, as are Transfers outside fence which return mergeFenced.
+func (r *sqlRepo) planMerge(dir string, transferIDs []string, fence mergeFence) (map[string]string, error) {
+	defer database.MeasureQuery("pipeline", "planMerge")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	merged := make(map[string]string)
+	for i := range transferIDs {
+		held, err := fence.holds(tx, transferIDs[i])
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !held {
+			merged[transferIDs[i]] = mergeFenced
+			continue
+		}
+
+		var state string
+		query := `select state from merged_transfers where transfer_id = ? limit 1;`
+		err = tx.QueryRow(query, transferIDs[i]).Scan(&state)
+		switch {
+		case err == sql.ErrNoRows:
+			query = `insert into merged_transfers (transfer_id, cutoff_dir, state, updated_at) values (?, ?, ?, ?);`
+			_, err = tx.Exec(query, transferIDs[i], dir, mergePlanned, now)
+
+		case err != nil:
+
+		case state == mergePlanned:
+			query = `update merged_transfers set cutoff_dir = ?, updated_at = ? where transfer_id = ? and state = ?;`
+			_, err = tx.Exec(query, dir, now, transferIDs[i], mergePlanned)
+
+		default:
+			merged[transferIDs[i]] = state
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return merged, tx.Commit()
+}
+
+// markMergeWritten records transferIDs as written into the merged file at filename. Nothing
+// is recorded and errMergeFenced is returned if any of transferIDs is outside fence.
+func (r *sqlRepo) markMergeWritten(filename string, transferIDs []string, fence mergeFence) error {
+	defer database.MeasureQuery("pipeline", "markMergeWritten")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	query := `update merged_transfers set merged_filename = ?, state = ?, updated_at = ? where transfer_id = ? and state = ?;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range transferIDs {
+		if held, err := fence.holds(tx, transferIDs[i]); err != nil || !held {
+			tx.Rollback()
+			if err == nil {
+				err = errMergeFenced
+			}
+			return err
+		}
+		if _, err := stmt.Exec(filename, mergeWritten, now, transferIDs[i], mergePlanned); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// markMergeUploaded records every Transfer written into the merged file at filename as uploaded.
+func (r *sqlRepo) markMergeUploaded(filename string) error {
+	defer database.MeasureQuery("pipeline", "markMergeUploaded")()
+
+	query := `update merged_transfers set state = ?, updated_at = ? where merged_filename = ? and state = ?;`
+	_, err := r.db.Exec(query, mergeUploaded, time.Now(), filename, mergeWritten)
+	return err
+}
+
+// getInDoubtMerges returns every Transfer which is planned or written, but not uploaded.
+func (r *sqlRepo) getInDoubtMerges() ([]mergeRecord, error) {
+	defer database.MeasureQuery("pipeline", "getInDoubtMerges")()
+
+	query := `select transfer_id, cutoff_dir, merged_filename, state, updated_at from merged_transfers where state in (?, ?) order by updated_at asc;`
+	return r.queryMerges("getInDoubtMerges", query, mergePlanned, mergeWritten)
+}
+
+// getStuckMerges returns Transfers which have been planned or written since before the given time.
+func (r *sqlRepo) getStuckMerges(before time.Time) ([]mergeRecord, error) {
+	defer database.MeasureQuery("pipeline", "getStuckMerges")()
+
+	query := `select transfer_id, cutoff_dir, merged_filename, state, updated_at from merged_transfers where state in (?, ?) and updated_at < ? order by updated_at asc;`
+	return r.queryMerges("getStuckMerges", query, mergePlanned, mergeWritten, before)
+}
+
+func (r *sqlRepo) getMerge(transferID string) (*mergeRecord, error) {
+	defer database.MeasureQuery("pipeline", "getMerge")()
+
+	query := `select transfer_id, cutoff_dir, merged_filename, state, updated_at from merged_transfers where transfer_id = ? limit 1;`
+	records, err := r.queryMerges("getMerge", query, transferID)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+func (r *sqlRepo) queryMerges(name, query string, args ...interface{}) ([]mergeRecord, error) {
+	var out []mergeRecord
+	err := database.QueryRows(r.db, name, query, args, func(rows *sql.Rows) error {
+		var rec mergeRecord
+		var filename *string
+		if err := rows.Scan(&rec.TransferID, &rec.CutoffDir, &filename, &rec.State, &rec.UpdatedAt); err != nil {
+			return err
+		}
+		if filename != nil {
+			rec.MergedFilename = *filename
+		}
+		out = append(out, rec)
+		return nil
+	})
+	return out, err
+}
+
+// markTransferUploaded records a written Transfer as uploaded after an operator confirmed it with the ODFI.
+func (r *sqlRepo) markTransferUploaded(transferID string) error {
+	defer database.MeasureQuery("pipeline", "markTransferUploaded")()
+
+	query := `update merged_transfers set state = ?, updated_at = ? where transfer_id = ? and state = ?;`
+	_, err := r.db.Exec(query, mergeUploaded, time.Now(), transferID, mergeWritten)
+	return err
+}
+
+// deleteMerge forgets a Transfer in state whose file is returned for the next cutoff.
+func (r *sqlRepo) deleteMerge(transferID string, state string) error {
+	defer database.MeasureQuery("pipeline", "deleteMerge")()
+
+	query := `delete from merged_transfers where transfer_id = ? and state = ?;`
+	_, err := r.db.Exec(query, transferID, state)
+	return err
+}
+
+// getUnprocessedUploads returns Transfers which were uploaded, but are still PENDING
+// because the cutoff stopped before marking them as processed.
+func (r *sqlRepo) getUnprocessedUploads() ([]string, error) {
+	defer database.MeasureQuery("pipeline", "getUnprocessedUploads")()
+
+	query := `select m.transfer_id from merged_transfers as m
+inner join transfers as xf on m.transfer_id = xf.transfer_id
+where m.state = ? and xf.status = ? and xf.deleted_at is null;`
+	var out []string
+	err := database.QueryRows(r.db, "getUnprocessedUploads", query, []interface{}{mergeUploaded, client.PENDING}, func(rows *sql.Rows) error {
+		var transferID string
+		if err := rows.Scan(&transferID); err != nil {
+			return err
+		}
+		out = append(out, transferID)
+		return nil
+	})
+	return out, err
+}
+
+// recoverMerges resolves Transfers left in-doubt by a cutoff which stopped part way through.
+//
+// Planned Transfers were never written into a merged file, so their files are moved back
+// to be merged in the next cutoff. Written Transfers might have been uploaded, so they're
+// only marked as uploaded when a matching file was recorded as uploaded (which requires
+// duplicate detection). Otherwise they're left for an operator and never merged again.
+func recoverMerges(logger log.Logger, repo Repository, baseDir string) error {
+	records, err := repo.getInDoubtMerges()
+	if err != nil {
+		return fmt.Errorf("problem reading in-doubt transfers: %v", err)
+	}
+
+	inDoubt := 0
+	resolved := make(map[string]bool)
+	for i := range records {
+		rec := records[i]
+		logger := logger.With(log.Fields{
+			"transferID": log.String(rec.TransferID),
+			"state":      log.String(rec.State),
+		})
+
+		switch rec.State {
+		case mergePlanned:
+			if err := restoreTransfer(rec.CutoffDir, baseDir, rec.TransferID); err != nil {
+				return fmt.Errorf("problem restoring transferID=%s: %v", rec.TransferID, err)
+			}
+			if err := repo.deleteMerge(rec.TransferID, mergePlanned); err != nil {
+				return fmt.Errorf("problem restoring transferID=%s: %v", rec.TransferID, err)
+			}
+			logger.Logf("restored planned transfer from %s for the next cutoff", rec.CutoffDir)
+
+		case mergeWritten:
+			if resolved[rec.MergedFilename] {
+				continue
+			}
+			uploaded, err := wasUploaded(repo, rec.MergedFilename)
+			if err != nil {
+				return fmt.Errorf("problem checking %s: %v", rec.MergedFilename, err)
+			}
+			if uploaded {
+				if err := repo.markMergeUploaded(rec.MergedFilename); err != nil {
+					return fmt.Errorf("problem marking %s as uploaded: %v", rec.MergedFilename, err)
+				}
+				resolved[rec.MergedFilename] = true
+				logger.Logf("found %s was uploaded", rec.MergedFilename)
+			} else {
+				inDoubt++
+				logger.LogErrorf("unable to confirm upload of %s", rec.MergedFilename)
+			}
+		}
+	}
+	inDoubtMerges.Set(float64(inDoubt))
+
+	transferIDs, err := repo.getUnprocessedUploads()
+	if err != nil {
+		return fmt.Errorf("problem reading uploaded transfers: %v", err)
+	}
+	if len(transferIDs) > 0 {
+		if err := repo.MarkTransfersAsProcessed(transferIDs); err != nil {
+			return fmt.Errorf("problem marking %d uploaded transfers as processed: %v", len(transferIDs), err)
+		}
+		logger.Logf("marked %d uploaded transfers as processed", len(transferIDs))
+	}
+	return nil
+}
+
+// restoreTransfer moves the files of a Transfer from an isolated cutoff directory back
+// into the mergable directory, unless a newer copy is already there.
+func restoreTransfer(cutoffDir, baseDir, transferID string) error {
+	if _, err := os.Stat(filepath.Join(baseDir, transferID+".ach")); err == nil {
+		return nil
+	}
+	for _, ext := range []string{".json", ".consolidate", ".ach"} {
+		src := filepath.Join(cutoffDir, transferID+ext)
+		if _, err := os.Stat(src); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if err := os.Rename(src, filepath.Join(baseDir, transferID+ext)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wasUploaded returns true when the merged file at filename matches a file saved as uploaded.
+func wasUploaded(repo Repository, filename string) (bool, error) {
+	if _, err := os.Stat(filename); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	file, err := ach.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	uploaded, err := repo.getUploadedFile(fingerprintFile(file), time.Time{})
+	return uploaded != "", err
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestLedger__states(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		transferID := base.ID()
+
+		merged, err := repo.planMerge("storage/20200102-150405", []string{transferID}, nil)
+		if err != nil || len(merged) != 0 {
+			t.Fatalf("merged=%v error=%v", merged, err)
+		}
+		// planning again doesn't skip the Transfer
+		merged, err = repo.planMerge("storage/20200102-170405", []string{transferID}, nil)
+		if err != nil || len(merged) != 0 {
+			t.Fatalf("merged=%v error=%v", merged, err)
+		}
+
+		filename := "storage/20200102-170405/uploaded/abc.ach"
+		if err := repo.markMergeWritten(filename, []string{transferID}, nil); err != nil {
+			t.Fatal(err)
+		}
+		records, err := repo.getInDoubtMerges()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 || records[0].State != mergeWritten || records[0].MergedFilename != filename {
+			t.Errorf("unexpected records: %#v", records)
+		}
+
+		if err := repo.markMergeUploaded(filename); err != nil {
+			t.Fatal(err)
+		}
+		if records, err := repo.getInDoubtMerges(); err != nil || len(records) != 0 {
+			t.Errorf("records=%#v error=%v", records, err)
+		}
+
+		// an uploaded Transfer is never merged again
+		merged, err = repo.planMerge("storage/20200103-150405", []string{transferID}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged[transferID] != mergeUploaded {
+			t.Errorf("unexpected merged: %v", merged)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestLedger__recoverMerges(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	parent := internal.TestDir(t)
+	baseDir, cutoffDir := filepath.Join(parent, "mergable"), filepath.Join(parent, "20200102-150405")
+	for _, dir := range []string{baseDir, cutoffDir} {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// planned, but never written
+	planned := base.ID()
+	for _, ext := range []string{".ach", ".json"} {
+		if err := ioutil.WriteFile(filepath.Join(cutoffDir, planned+ext), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.planMerge(cutoffDir, []string{planned}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// written, but the upload can't be confirmed
+	written := base.ID()
+	if _, err := repo.planMerge(cutoffDir, []string{written}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.markMergeWritten(filepath.Join(cutoffDir, "uploaded", "missing.ach"), []string{written}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// uploaded, but not marked as processed
+	uploaded := base.ID()
+	writeTransfer(t, repo, uploaded)
+	filename := filepath.Join(cutoffDir, "uploaded", "abc.ach")
+	if _, err := repo.planMerge(cutoffDir, []string{uploaded}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.markMergeWritten(filename, []string{uploaded}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.markMergeUploaded(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recoverMerges(log.NewNopLogger(), repo, baseDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ext := range []string{".ach", ".json"} {
+		if _, err := os.Stat(filepath.Join(baseDir, planned+ext)); err != nil {
+			t.Errorf("expected %s to be restored: %v", ext, err)
+		}
+	}
+	records, err := repo.getInDoubtMerges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].TransferID != written {
+		t.Errorf("unexpected records: %#v", records)
+	}
+	if xfer := getPartialTransferModel(t, repo, uploaded); xfer.Status != client.PROCESSED {
+		t.Errorf("unexpected status: %v", xfer.Status)
+	}
+}
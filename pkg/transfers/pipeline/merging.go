@@ -19,6 +19,7 @@ import (
 	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
 
@@ -39,28 +40,53 @@ type XferMerging interface {
 	WithEachMerged(func(*ach.File) error) (*processedTransfers, error)
 }
 
-func NewMerging(logger log.Logger, cfg config.Pipeline) (XferMerging, error) {
-	dir := filepath.Join("storage", "mergable") // default directory
-	if cfg.Merging != nil && cfg.Merging.Directory != "" {
-		dir = filepath.Join(cfg.Merging.Directory, "mergable")
-	}
+func NewMerging(logger log.Logger, cfg config.Pipeline, odfi config.ODFI, traceNumbers achx.TraceNumbers, repo Repository) (XferMerging, error) {
+	dir := mergableDir(cfg)
 	logger.Logf("using %s as mergable directory", dir)
 
 	if err := os.MkdirAll(dir, 0777); err != nil {
 		return nil, err
 	}
 
+	// Resolve Transfers from a cutoff which didn't finish before we shutdown
+	if repo != nil {
+		if err := recoverMerges(logger, repo, dir); err != nil {
+			logger.LogErrorf("ERROR recovering merged transfers: %v", err)
+		}
+	}
+
 	return &filesystemMerging{
-		baseDir: dir,
-		cfg:     cfg.Merging,
-		logger:  logger,
+		baseDir:      dir,
+		cfg:          cfg.Merging,
+		odfi:         odfi,
+		traceNumbers: traceNumbers,
+		repo:         repo,
+		logger:       logger,
 	}, nil
 }
 
+func mergableDir(cfg config.Pipeline) string {
+	if cfg.Merging != nil && cfg.Merging.Directory != "" {
+		return filepath.Join(cfg.Merging.Directory, "mergable")
+	}
+	return filepath.Join("storage", "mergable") // default directory
+}
+
 type filesystemMerging struct {
 	baseDir string
 	cfg     *config.Merging
-	logger  log.Logger
+	odfi    config.ODFI
+
+	// traceNumbers allocates trace numbers of offset entries
+	traceNumbers achx.TraceNumbers
+
+	// repo records each Transfer's progress through a cutoff, see recoverMerges
+	repo Repository
+
+	// shards fences the merge of Transfers to the shards this instance still holds, see UseShards
+	shards *Shards
+
+	logger log.Logger
 }
 
 func (m *filesystemMerging) HandleXfer(xfer Xfer) error {
@@ -70,6 +96,12 @@ func (m *filesystemMerging) HandleXfer(xfer Xfer) error {
 	if err1 != nil || err2 != nil {
 		return fmt.Errorf("problem writing transfer: %v\n problem writing ACH file: %v", err1, err2)
 	}
+	if xfer.ConsolidateBatches {
+		path := filepath.Join(m.baseDir, fmt.Sprintf("%s.consolidate", xfer.Transfer.TransferID))
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			return fmt.Errorf("problem marking transfer for consolidation: %v", err)
+		}
+	}
 
 	return nil
 }
@@ -154,21 +186,67 @@ func getNonCanceledMatches(path string) ([]string, error) {
 
 type processedTransfers struct {
 	transferIDs []string
+
+	// entries holds the totals of each Transfer's ACH file prior to merging, keyed by transferID.
+	entries map[string]ProcessedTransfer
+
+	// traceNumbers holds the trace numbers of each Transfer's entries, keyed by transferID.
+	traceNumbers map[string][]string
 }
 
-func newProcessedTransfers(matches []string) *processedTransfers {
-	processed := &processedTransfers{}
+func newProcessedTransfers(matches []string, files map[string]*ach.File) *processedTransfers {
+	processed := &processedTransfers{
+		entries:      make(map[string]ProcessedTransfer),
+		traceNumbers: make(map[string][]string),
+	}
 
 	for i := range matches {
 		// each match follows $path/$transferID.ach so we can split that
 		// and grab the transferID
 		transferID := strings.TrimSuffix(filepath.Base(matches[i]), ".ach")
 		processed.transferIDs = append(processed.transferIDs, transferID)
+
+		if file, ok := files[matches[i]]; ok {
+			processed.entries[transferID] = ProcessedTransfer{
+				TransferID:  transferID,
+				EntryCount:  countEntries(file),
+				DebitTotal:  file.Control.TotalDebitEntryDollarAmountInFile,
+				CreditTotal: file.Control.TotalCreditEntryDollarAmountInFile,
+			}
+			for j := range file.Batches {
+				entries := file.Batches[j].GetEntries()
+				for k := range entries {
+					processed.traceNumbers[transferID] = append(processed.traceNumbers[transferID], entries[k].TraceNumber)
+				}
+			}
+		}
 	}
 
 	return processed
 }
 
+// filenames returns the files holding the entries of transferID, given the filename of each
+// uploaded entry keyed by its trace number.
+func (p *processedTransfers) filenames(transferID string, uploaded map[string]string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, traceNumber := range p.traceNumbers[transferID] {
+		if filename, ok := uploaded[traceNumber]; ok && !seen[filename] {
+			seen[filename] = true
+			out = append(out, filename)
+		}
+	}
+	return out
+}
+
+func countEntries(file *ach.File) int {
+	var total int
+	for i := range file.Batches {
+		total += len(file.Batches[i].GetEntries())
+	}
+	return total
+}
+
 func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedTransfers, error) {
 	// move the current directory so it's isolated and easier to debug later on
 	dir, err := m.isolateMergableDir()
@@ -181,9 +259,25 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 	if err != nil {
 		return nil, fmt.Errorf("problem with %s glob: %v", path, err)
 	}
+	matches, err = m.skipBlocked(matches)
+	if err != nil {
+		return nil, fmt.Errorf("problem canceling blocked transfers: %v", err)
+	}
+	matches, err = m.skipViolations(matches)
+	if err != nil {
+		return nil, fmt.Errorf("problem failing transfers which violate the odfi agreement: %v", err)
+	}
+	fence := m.shards.fence(dir)
+	matches, err = m.planMerge(dir, matches, fence)
+	if err != nil {
+		return nil, fmt.Errorf("problem planning merge: %v", err)
+	}
 
 	var files []*ach.File
 	var el base.ErrorList
+	originals := make(map[string]*ach.File)
+	owners := make(map[string]string)     // transferID of each trace number
+	consolidated := make(map[string]bool) // trace numbers of entries which can share batches
 	for i := range matches {
 		file, err := ach.ReadFile(matches[i])
 		if err != nil {
@@ -191,13 +285,47 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 			continue
 		}
 		if file != nil {
+			achx.RestoreDishonoredAddenda(file)
 			files = append(files, file)
+			originals[matches[i]] = file
+
+			transferID := strings.TrimSuffix(filepath.Base(matches[i]), ".ach")
+			for j := range file.Batches {
+				entries := file.Batches[j].GetEntries()
+				for k := range entries {
+					owners[entries[k].TraceNumber] = transferID
+				}
+			}
+
+			if _, err := os.Stat(strings.TrimSuffix(matches[i], ".ach") + ".consolidate"); err == nil {
+				for j := range file.Batches {
+					entries := file.Batches[j].GetEntries()
+					for k := range entries {
+						consolidated[entries[k].TraceNumber] = true
+					}
+				}
+			}
 		}
 	}
-	files, err = ach.MergeFiles(files)
+	now := time.Now()
+	if loc := m.odfi.Cutoffs.Location(); loc != nil {
+		now = now.In(loc)
+	}
+	if missed := countMissedWindows(files, now); missed > 0 {
+		m.logger.LogErrorf("merging %d entries after their effective entry date", missed)
+	}
+
+	// Pack same-day entries into the first merged files and upload those first
+	prioritize(files)
+	var maxEntries int
+	if m.odfi.Agreement != nil {
+		maxEntries = m.odfi.Agreement.MaxEntriesPerFile
+	}
+	files, err = mergeFiles(files, maxEntries)
 	if err != nil {
 		el.Add(fmt.Errorf("unable to merge files: %v", err))
 	}
+	prioritize(files)
 
 	if len(matches) > 0 {
 		m.logger.Logf("merged %d transfers into %d files", len(matches), len(files))
@@ -216,6 +344,11 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 
 	// Write each file to our storage
 	for i := range files {
+		// Combine batches of Transfers from organizations which consolidate them
+		if err := consolidateBatches(files[i], consolidated); err != nil {
+			el.Add(fmt.Errorf("problem consolidating batches: %v", err))
+			continue
+		}
 		// Optionally Flatten Batches
 		if m.cfg != nil && m.cfg.FlattenBatches != nil {
 			if file, err := files[i].FlattenBatches(); err != nil {
@@ -224,13 +357,28 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 				files[i] = file
 			}
 		}
-		// Write our file to the mergable directory
-		if err := writeFile(dir, files[i]); err != nil {
+		// Balance the file against the ODFI's settlement account, never upload it unbalanced
+		if err := achx.AddOffsets(files[i], m.odfi.FileConfig.Offset, m.odfi.RoutingNumber, m.traceNumbers); err != nil {
+			el.Add(fmt.Errorf("problem adding offsets: %v", err))
+			continue
+		}
+		// Write our file to the mergable directory, never upload a file we haven't recorded
+		filename, err := writeFile(dir, files[i])
+		if err != nil {
 			el.Add(fmt.Errorf("problem writing merged file: %v", err))
+			continue
+		}
+		if err := m.markWritten(filename, files[i], owners, fence); err != nil {
+			el.Add(fmt.Errorf("problem recording merged file: %v", err))
+			continue
 		}
 		// Call our closure with the final file
 		if err := f(files[i]); err != nil {
 			el.Add(fmt.Errorf("problem from callback: %v", err))
+			continue
+		}
+		if err := m.markUploaded(filename); err != nil {
+			el.Add(fmt.Errorf("problem recording upload of merged file: %v", err))
 		}
 	}
 
@@ -240,16 +388,83 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) (*processedT
 		return nil, el
 	}
 
-	return newProcessedTransfers(matches), nil
+	return newProcessedTransfers(matches, originals), nil
+}
+
+// planMerge records the Transfers of matches as planned and returns the matches which
+// weren't already written into a merged file by an earlier cutoff.
+func (m *filesystemMerging) planMerge(dir string, matches []string, fence mergeFence) ([]string, error) {
+	if m.repo == nil || len(matches) == 0 {
+		return matches, nil
+	}
+
+	transferIDs := make([]string, len(matches))
+	for i := range matches {
+		transferIDs[i] = strings.TrimSuffix(filepath.Base(matches[i]), ".ach")
+	}
+	merged, err := m.repo.planMerge(dir, transferIDs, fence)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for i := range matches {
+		if state, exists := merged[transferIDs[i]]; exists {
+			if state == mergeFenced {
+				m.logger.LogErrorf("skipping transferID=%s whose shard is owned by another instance", transferIDs[i])
+			} else {
+				m.logger.LogErrorf("skipping transferID=%s which was already %s", transferIDs[i], state)
+			}
+			continue
+		}
+		out = append(out, matches[i])
+	}
+	return out, nil
+}
+
+// markWritten records the Transfers of file as written. If any of their shards was lost since
+// they were planned the file must not be uploaded. Those Transfers are left planned for the
+// shard's new owner to replay.
+func (m *filesystemMerging) markWritten(filename string, file *ach.File, owners map[string]string, fence mergeFence) error {
+	if m.repo == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var transferIDs []string
+	for i := range file.Batches {
+		entries := file.Batches[i].GetEntries()
+		for j := range entries {
+			transferID, exists := owners[entries[j].TraceNumber]
+			if exists && !seen[transferID] {
+				seen[transferID] = true
+				transferIDs = append(transferIDs, transferID)
+			}
+		}
+	}
+	if err := m.repo.markMergeWritten(filename, transferIDs, fence); err != nil {
+		if err == errMergeFenced {
+			return fmt.Errorf("skipping upload of %s: %v", filename, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *filesystemMerging) markUploaded(filename string) error {
+	if m.repo == nil {
+		return nil
+	}
+	return m.repo.markMergeUploaded(filename)
 }
 
-func writeFile(dir string, file *ach.File) error {
+func writeFile(dir string, file *ach.File) (string, error) {
 	var buf bytes.Buffer
 	if err := ach.NewWriter(&buf).Write(file); err != nil {
-		return fmt.Errorf("unable to buffer ACH file: %v", err)
+		return "", fmt.Errorf("unable to buffer ACH file: %v", err)
 	}
 	filename := filepath.Join(dir, fmt.Sprintf("%s.ach", hash(buf.Bytes())))
-	return ioutil.WriteFile(filename, buf.Bytes(), 0600)
+	return filename, ioutil.WriteFile(filename, buf.Bytes(), 0600)
 }
 
 func hash(data []byte) string {
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/internal"
 )
@@ -45,3 +46,31 @@ func TestMerging__getNonCanceledMatches(t *testing.T) {
 		t.Errorf("unexpected match: %v", matches[0])
 	}
 }
+
+func TestMerging__processedFilenames(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "two-micro-deposits.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transferID := base.ID()
+	match := filepath.Join("storage", fmt.Sprintf("%s.ach", transferID))
+	processed := newProcessedTransfers([]string{match}, map[string]*ach.File{match: file})
+
+	uploaded := uploadedFilenames([]UploadedFile{
+		{Filename: "20200529-1530-987654320.ach", File: file},
+		{Filename: "20200529-1530-987654320-2.ach", File: other},
+		{Filename: "missing.ach"},
+	})
+	filenames := processed.filenames(transferID, uploaded)
+	if len(filenames) != 1 || filenames[0] != "20200529-1530-987654320.ach" {
+		t.Errorf("unexpected filenames: %v", filenames)
+	}
+	if filenames := processed.filenames(base.ID(), uploaded); len(filenames) != 0 {
+		t.Errorf("unexpected filenames: %v", filenames)
+	}
+}
@@ -41,8 +41,21 @@ type EmailTemplateData struct {
 }
 
 var (
-	// Ensure the default template validates against our data struct
-	_ = config.DefaultEmailTemplate.Execute(ioutil.Discard, EmailTemplateData{})
+	// Ensure the default templates validate against our data struct
+	_ = func() error {
+		for _, tmpl := range config.DefaultEmailTemplates {
+			if err := tmpl.Execute(ioutil.Discard, EmailTemplateData{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+
+	// uploadSubjects are the formats of upload subjects by language
+	uploadSubjects = map[string]string{
+		"en": "%s uploaded by %s",
+		"es": "%s subido por %s",
+	}
 )
 
 func NewEmail(cfg *config.Email) (*Email, error) {
@@ -96,7 +109,7 @@ func (mailer *Email) Info(msg *Message) error {
 	if err != nil {
 		return err
 	}
-	return sendEmail(mailer.cfg, mailer.dialer, msg.Filename, contents)
+	return sendEmail(mailer.cfg, mailer.dialer, uploadSubject(mailer.cfg, msg.Filename), contents)
 }
 
 func (mailer *Email) Critical(msg *Message) error {
@@ -104,7 +117,7 @@ func (mailer *Email) Critical(msg *Message) error {
 	if err != nil {
 		return err
 	}
-	return sendEmail(mailer.cfg, mailer.dialer, msg.Filename, contents)
+	return sendEmail(mailer.cfg, mailer.dialer, uploadSubject(mailer.cfg, msg.Filename), contents)
 }
 
 func marshalEmail(cfg *config.Email, msg *Message) (string, error) {
@@ -133,11 +146,24 @@ func convertDollar(in int) float64 {
 	return float64(in) / 100.0
 }
 
-func sendEmail(cfg *config.Email, dialer *gomail.Dialer, filename, body string) error {
+// Send delivers a plain text email with the given subject to each configured recipient.
+func (mailer *Email) Send(subject, body string) error {
+	return sendEmail(mailer.cfg, mailer.dialer, subject, body)
+}
+
+func uploadSubject(cfg *config.Email, filename string) string {
+	format, exists := uploadSubjects[cfg.Language]
+	if !exists {
+		format = uploadSubjects["en"]
+	}
+	return fmt.Sprintf(format, filename, cfg.CompanyName)
+}
+
+func sendEmail(cfg *config.Email, dialer *gomail.Dialer, subject, body string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", cfg.From)
 	m.SetHeader("To", cfg.To...)
-	m.SetHeader("Subject", fmt.Sprintf("%s uploaded by %s", filename, cfg.CompanyName))
+	m.SetHeader("Subject", subject)
 	m.SetBody("text/plain", body)
 
 	if err := dialer.DialAndSend(context.Background(), m); err != nil {
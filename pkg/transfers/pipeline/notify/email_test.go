@@ -98,3 +98,28 @@ func TestEmail__marshalDefaultTemplate(t *testing.T) {
 		require.Contains(t, contents, `Total Entries: 1`, "Test: "+test.desc)
 	}
 }
+
+func TestEmail__marshalSpanishTemplate(t *testing.T) {
+	f, err := ach.ReadFile(filepath.Join("..", "..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Email{
+		CompanyName: "Moov",
+		Language:    "es",
+	}
+	contents, err := marshalEmail(cfg, &Message{Direction: Upload, File: f, Filename: "20200529-131400.ach", Hostname: "ftp.bank.com:3294"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	require.Contains(t, contents, "Se ha subido un archivo a ftp.bank.com:3294 - 20200529-131400.ach")
+	require.Contains(t, contents, `Débitos:  $105.00`)
+	require.Contains(t, contents, `Total de entradas: 1`)
+
+	require.Equal(t, "20200529-131400.ach subido por Moov", uploadSubject(cfg, "20200529-131400.ach"))
+
+	cfg.Language = ""
+	require.Equal(t, "20200529-131400.ach uploaded by Moov", uploadSubject(cfg, "20200529-131400.ach"))
+}
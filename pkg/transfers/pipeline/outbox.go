@@ -0,0 +1,318 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/jobs"
+)
+
+const (
+	outboxUpload = "upload"
+	outboxCancel = "cancel"
+
+	outboxInterval  = 1 * time.Second
+	outboxBatchSize = 100
+)
+
+// OutboxMessage is a message for the XferPublisher which is saved in the same database
+// transaction as the change it describes. An Outbox publishes them once committed.
+type OutboxMessage struct {
+	Upload *Xfer             `json:"upload,omitempty"`
+	Cancel *CanceledTransfer `json:"cancel,omitempty"`
+}
+
+// UploadMessages returns an OutboxMessage for each file of a Transfer, see PublishFiles.
+func UploadMessages(orgID string, xfer *client.Transfer, files []*ach.File, consolidate bool) []OutboxMessage {
+	var msgs []OutboxMessage
+	for i := range files {
+		msgs = append(msgs, OutboxMessage{
+			Upload: &Xfer{
+				Transfer:           xfer,
+				File:               files[i],
+				ConsolidateBatches: consolidate,
+				Organization:       orgID,
+			},
+		})
+	}
+	return msgs
+}
+
+// CancelMessage returns an OutboxMessage which cancels a Transfer.
+func CancelMessage(transferID string) OutboxMessage {
+	return OutboxMessage{
+		Cancel: &CanceledTransfer{
+			TransferID: transferID,
+		},
+	}
+}
+
+func (msg OutboxMessage) kind() (string, string, error) {
+	switch {
+	case msg.Upload != nil && msg.Upload.Transfer != nil:
+		return outboxUpload, msg.Upload.Transfer.TransferID, nil
+	case msg.Cancel != nil:
+		return outboxCancel, msg.Cancel.TransferID, nil
+	}
+	return "", "", errors.New("empty outbox message")
+}
+
+// WriteOutbox saves messages as part of tx so they're only published if tx commits.
+func WriteOutbox(tx *sql.Tx, msgs []OutboxMessage) error {
+	return writeOutbox(tx, msgs, nil)
+}
+
+// HoldOutbox saves messages as part of tx like WriteOutbox, but they aren't published
+// until their Transfer is released with ReleaseOutbox.
+func HoldOutbox(tx *sql.Tx, msgs []OutboxMessage) error {
+	now := time.Now()
+	return writeOutbox(tx, msgs, &now)
+}
+
+// ReleaseOutbox allows the held messages of a Transfer to be published once tx commits.
+func ReleaseOutbox(tx *sql.Tx, transferID string) error {
+	_, err := tx.Exec(`update pipeline_outbox set held_at = null where transfer_id = ? and held_at is not null;`, transferID)
+	return err
+}
+
+func writeOutbox(tx *sql.Tx, msgs []OutboxMessage, heldAt *time.Time) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	query := `insert into pipeline_outbox (message_id, transfer_id, kind, body, created_at, held_at) values (?, ?, ?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for i := range msgs {
+		kind, transferID, err := msgs[i].kind()
+		if err != nil {
+			return err
+		}
+		var body []byte
+		if kind == outboxUpload {
+			body, err = json.Marshal(msgs[i].Upload)
+		} else {
+			body, err = json.Marshal(msgs[i].Cancel)
+		}
+		if err != nil {
+			return fmt.Errorf("transferID=%s json encode: %v", transferID, err)
+		}
+		if _, err := stmt.Exec(base.ID(), transferID, kind, string(body), now, heldAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Outbox publishes messages saved with WriteOutbox in the order they were written, which is
+// kept by the sequence_id column as messages of one transaction share their created_at.
+//
+// Messages are delivered at least once. A message which was published before the
+// instance stopped, but not marked as dispatched, is published again. Downstream
+// processors de-duplicate files by their TransferID already.
+type Outbox struct {
+	logger log.Logger
+	db     *sql.DB
+	pub    XferPublisher
+
+	interval time.Duration
+
+	job *jobs.Job
+}
+
+func NewOutbox(logger log.Logger, db *sql.DB, pub XferPublisher) *Outbox {
+	return &Outbox{
+		logger:   logger,
+		db:       db,
+		pub:      pub,
+		interval: outboxInterval,
+	}
+}
+
+// UseJobs records the runs of the Outbox as the "pipeline.outbox" job of reg.
+func (o *Outbox) UseJobs(reg *jobs.Registry) {
+	if o == nil {
+		return
+	}
+	o.job = reg.Register("pipeline.outbox")
+}
+
+// Start dispatches messages until ctx is canceled.
+func (o *Outbox) Start(ctx context.Context) {
+	if o == nil {
+		return
+	}
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	o.job.Scheduled(time.Now().Add(o.interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			o.run()
+			o.job.Scheduled(time.Now().Add(o.interval))
+
+		case <-o.job.Triggered():
+			o.run()
+
+		case <-ctx.Done():
+			o.logger.Log("outbox shutdown")
+			return
+		}
+	}
+}
+
+func (o *Outbox) run() {
+	_, err := o.Dispatch()
+	if err != nil {
+		o.logger.LogErrorf("ERROR dispatching outbox messages: %v", err)
+	}
+	o.job.Done(err)
+}
+
+type outboxRow struct {
+	messageID string
+	kind      string
+	body      string
+}
+
+// Dispatch publishes every undispatched message, except those which are held, and returns
+// how many were published.
+// It stops at the first message which fails so later messages aren't published ahead of it.
+func (o *Outbox) Dispatch() (int, error) {
+	dispatched := 0
+	for {
+		rows, err := o.pending()
+		if err != nil {
+			return dispatched, err
+		}
+		for i := range rows {
+			if err := o.publish(rows[i]); err != nil {
+				return dispatched, fmt.Errorf("messageID=%s: %v", rows[i].messageID, err)
+			}
+			if err := o.markDispatched(rows[i].messageID); err != nil {
+				return dispatched, fmt.Errorf("messageID=%s: %v", rows[i].messageID, err)
+			}
+			dispatched++
+		}
+		if len(rows) < outboxBatchSize {
+			return dispatched, nil
+		}
+	}
+}
+
+func (o *Outbox) pending() ([]outboxRow, error) {
+	defer database.MeasureQuery("pipeline", "pendingOutbox")()
+
+	query := `select message_id, kind, body from pipeline_outbox where dispatched_at is null and held_at is null order by sequence_id asc limit ?;`
+	stmt, err := o.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err := rows.Scan(&row.messageID, &row.kind, &row.body); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func (o *Outbox) publish(row outboxRow) error {
+	switch row.kind {
+	case outboxUpload:
+		var xfer Xfer
+		if err := json.Unmarshal([]byte(row.body), &xfer); err != nil {
+			return fmt.Errorf("json decode: %v", err)
+		}
+		return o.pub.Upload(xfer)
+
+	case outboxCancel:
+		var cancel CanceledTransfer
+		if err := json.Unmarshal([]byte(row.body), &cancel); err != nil {
+			return fmt.Errorf("json decode: %v", err)
+		}
+		return o.pub.Cancel(cancel)
+	}
+	return fmt.Errorf("unknown kind %q", row.kind)
+}
+
+// ReplayParams selects the published messages which are replayed. Messages are selected by
+// their Transfer, the time they were created or both.
+type ReplayParams struct {
+	TransferID string    `json:"transferID"`
+	StartDate  time.Time `json:"startDate"`
+	EndDate    time.Time `json:"endDate"`
+}
+
+// Replay marks the published messages matching params as undispatched, so they're published
+// again in the order they were written. It returns how many messages will be replayed.
+// Messages which were archived can't be replayed.
+func (o *Outbox) Replay(params ReplayParams) (int, error) {
+	defer database.MeasureQuery("pipeline", "replayOutbox")()
+
+	query := `update pipeline_outbox set dispatched_at = null where dispatched_at is not null and held_at is null`
+	var args []interface{}
+	if params.TransferID != "" {
+		query += ` and transfer_id = ?`
+		args = append(args, params.TransferID)
+	}
+	if !params.StartDate.IsZero() {
+		query += ` and created_at >= ?`
+		args = append(args, params.StartDate)
+	}
+	if !params.EndDate.IsZero() {
+		query += ` and created_at < ?`
+		args = append(args, params.EndDate)
+	}
+
+	res, err := o.db.Exec(query+";", args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (o *Outbox) markDispatched(messageID string) error {
+	defer database.MeasureQuery("pipeline", "markDispatched")()
+
+	query := `update pipeline_outbox set dispatched_at = ? where message_id = ? and dispatched_at is null;`
+	stmt, err := o.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now(), messageID)
+	return err
+}
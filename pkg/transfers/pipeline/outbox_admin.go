@@ -0,0 +1,61 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (o *Outbox) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/pipeline/outbox/replay", o.replayMessages())
+}
+
+type replayedMessages struct {
+	Replayed int `json:"replayed"`
+}
+
+func (o *Outbox) replayMessages() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodPost {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		var req ReplayParams
+		if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+			route.Problem(w, err)
+			return
+		}
+		verr := &route.ValidationError{}
+		if req.TransferID == "" && req.StartDate.IsZero() {
+			// Replaying every message is never what an operator wants
+			verr.Add("transferID", "transferID or startDate is required")
+		}
+		if !req.StartDate.IsZero() && !req.EndDate.IsZero() && !req.EndDate.After(req.StartDate) {
+			verr.Add("endDate", "must be after startDate")
+		}
+		if err := verr.Err(); err != nil {
+			route.Problem(w, err)
+			return
+		}
+
+		n, err := o.Replay(req)
+		if err != nil {
+			o.logger.LogErrorf("ERROR replaying outbox messages: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+		o.logger.Logf("replaying %d outbox messages for transferID=%q from %v to %v", n, req.TransferID, req.StartDate, req.EndDate)
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(replayedMessages{Replayed: n})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/jobs"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+var (
+	outboxMessagesArchived = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "outbox_messages_archived",
+		Help: "Counter of published outbox messages removed after their retention",
+	}, nil)
+)
+
+// ArchivedOutboxMessage is a published message as it's written into the archive bucket,
+// one JSON object per line.
+type ArchivedOutboxMessage struct {
+	MessageID  string          `json:"messageID"`
+	TransferID string          `json:"transferID"`
+	Kind       string          `json:"kind"`
+	Body       json.RawMessage `json:"body"`
+	Created    time.Time       `json:"created"`
+	Dispatched time.Time       `json:"dispatched"`
+}
+
+// OutboxArchiver removes published messages older than pipeline.outbox.retention, after
+// copying them into a bucket when pipeline.outbox.archive is configured.
+type OutboxArchiver struct {
+	logger log.Logger
+	db     *sql.DB
+	cfg    *config.Outbox
+	bucket *blob.Bucket
+
+	job *jobs.Job
+}
+
+// NewOutboxArchiver returns nil when pipeline.outbox isn't configured, which keeps every message.
+func NewOutboxArchiver(logger log.Logger, db *sql.DB, cfg *config.Outbox) (*OutboxArchiver, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	arc := &OutboxArchiver{
+		logger: logger,
+		db:     db,
+		cfg:    cfg,
+	}
+	if cfg.Archive != nil {
+		bucket, err := blob.OpenBucket(context.Background(), cfg.Archive.BucketURI)
+		if err != nil {
+			return nil, fmt.Errorf("opening outbox archive bucket: %v", err)
+		}
+		arc.bucket = bucket
+	}
+	return arc, nil
+}
+
+// UseJobs records the runs of the OutboxArchiver as the "pipeline.outbox-archive" job of reg.
+func (arc *OutboxArchiver) UseJobs(reg *jobs.Registry) {
+	if arc == nil {
+		return
+	}
+	arc.job = reg.Register("pipeline.outbox-archive")
+}
+
+// Start archives messages every pipeline.outbox.interval until ctx is canceled.
+func (arc *OutboxArchiver) Start(ctx context.Context) {
+	if arc == nil {
+		return
+	}
+	interval := arc.cfg.ArchiveInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	arc.job.Scheduled(time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			arc.run()
+			arc.job.Scheduled(time.Now().Add(interval))
+
+		case <-arc.job.Triggered():
+			arc.run()
+
+		case <-ctx.Done():
+			arc.logger.Log("outbox archiver shutdown")
+			if arc.bucket != nil {
+				arc.bucket.Close()
+			}
+			return
+		}
+	}
+}
+
+func (arc *OutboxArchiver) run() {
+	n, err := arc.Archive(time.Now())
+	if err != nil {
+		arc.logger.LogErrorf("ERROR archiving outbox messages: %v", err)
+	}
+	if n > 0 {
+		arc.logger.Logf("archived %d outbox messages", n)
+	}
+	arc.job.Done(err)
+}
+
+// Archive removes the published messages created before their retention as of now and
+// returns how many were removed. Each page of messages is written into the bucket as one
+// object before it's deleted, so messages are never removed without a copy.
+func (arc *OutboxArchiver) Archive(now time.Time) (int, error) {
+	before := now.Add(-1 * arc.cfg.Retention)
+
+	archived := 0
+	for {
+		msgs, err := arc.expired(before)
+		if err != nil {
+			return archived, err
+		}
+		if len(msgs) == 0 {
+			return archived, nil
+		}
+		if err := arc.write(msgs); err != nil {
+			return archived, err
+		}
+		if err := arc.delete(msgs); err != nil {
+			return archived, err
+		}
+		archived += len(msgs)
+		outboxMessagesArchived.Add(float64(len(msgs)))
+
+		if len(msgs) < outboxBatchSize {
+			return archived, nil
+		}
+	}
+}
+
+func (arc *OutboxArchiver) expired(before time.Time) ([]ArchivedOutboxMessage, error) {
+	defer database.MeasureQuery("pipeline", "expiredOutbox")()
+
+	query := `select message_id, transfer_id, kind, body, created_at, dispatched_at from pipeline_outbox
+where dispatched_at is not null and created_at < ? order by sequence_id asc limit ?;`
+	rows, err := arc.db.Query(query, before, outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ArchivedOutboxMessage
+	for rows.Next() {
+		var msg ArchivedOutboxMessage
+		var body string
+		if err := rows.Scan(&msg.MessageID, &msg.TransferID, &msg.Kind, &body, &msg.Created, &msg.Dispatched); err != nil {
+			return nil, err
+		}
+		msg.Body = json.RawMessage(body)
+		out = append(out, msg)
+	}
+	return out, rows.Err()
+}
+
+// write copies msgs into the bucket under outbox/YYYY-MM-DD/ named after the first message
+func (arc *OutboxArchiver) write(msgs []ArchivedOutboxMessage) error {
+	if arc.bucket == nil {
+		return nil
+	}
+	key := fmt.Sprintf("outbox/%s/%s.jsonl", msgs[0].Created.Format("2006-01-02"), msgs[0].MessageID)
+	w, err := arc.bucket.NewWriter(context.Background(), key, nil)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := range msgs {
+		if err := enc.Encode(msgs[i]); err != nil {
+			w.Close()
+			return fmt.Errorf("writing %s: %v", key, err)
+		}
+	}
+	return w.Close()
+}
+
+func (arc *OutboxArchiver) delete(msgs []ArchivedOutboxMessage) error {
+	defer database.MeasureQuery("pipeline", "deleteOutbox")()
+
+	messageIDs := make([]interface{}, len(msgs))
+	for i := range msgs {
+		messageIDs[i] = msgs[i].MessageID
+	}
+	query := fmt.Sprintf(`delete from pipeline_outbox where message_id in (%s) and dispatched_at is not null;`, database.Placeholders(len(messageIDs)))
+	_, err := arc.db.Exec(query, messageIDs...)
+	return err
+}
@@ -0,0 +1,258 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+)
+
+func writeOutboxMessage(t *testing.T, repo *sqlRepo, transferID string, created time.Time) {
+	t.Helper()
+
+	tx, err := repo.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteOutbox(tx, []OutboxMessage{CancelMessage(transferID)}); err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`update pipeline_outbox set created_at = ? where transfer_id = ?;`, created, transferID); err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// orderedPublisher records the transferIDs of published messages in the order they arrive
+type orderedPublisher struct {
+	published []string
+}
+
+func (pub *orderedPublisher) Upload(xfer Xfer) error {
+	pub.published = append(pub.published, "upload:"+xfer.Transfer.TransferID)
+	return nil
+}
+
+func (pub *orderedPublisher) Cancel(msg CanceledTransfer) error {
+	pub.published = append(pub.published, "cancel:"+msg.TransferID)
+	return nil
+}
+
+func (pub *orderedPublisher) Shutdown(ctx context.Context) {}
+
+func TestOutbox__DispatchOrder(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		pub := &orderedPublisher{}
+		outbox := NewOutbox(log.NewNopLogger(), repo.db, pub)
+
+		// every message of a transaction has the same created_at, so their order can't come from it
+		var msgs []OutboxMessage
+		var expected []string
+		for i := 0; i < 20; i++ {
+			transferID := base.ID()
+			msgs = append(msgs, OutboxMessage{Upload: &Xfer{Transfer: &client.Transfer{TransferID: transferID}}}, CancelMessage(transferID))
+			expected = append(expected, "upload:"+transferID, "cancel:"+transferID)
+		}
+		tx, err := repo.db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteOutbox(tx, msgs); err != nil {
+			tx.Rollback()
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := outbox.Dispatch(); err != nil || n != len(expected) {
+			t.Fatalf("dispatched %d messages: %v", n, err)
+		}
+		for i := range expected {
+			if pub.published[i] != expected[i] {
+				t.Fatalf("message #%d was %s, expected %s", i, pub.published[i], expected[i])
+			}
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestOutbox__Replay(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		pub := NewMockPublisher()
+		outbox := NewOutbox(log.NewNopLogger(), repo.db, pub)
+
+		now := time.Now()
+		first, second := base.ID(), base.ID()
+		writeOutboxMessage(t, repo, first, now.Add(-48*time.Hour))
+		writeOutboxMessage(t, repo, second, now.Add(-1*time.Hour))
+		if n, err := outbox.Dispatch(); err != nil || n != 2 {
+			t.Fatalf("dispatched %d messages: %v", n, err)
+		}
+
+		// replay one Transfer
+		if n, err := outbox.Replay(ReplayParams{TransferID: first}); err != nil || n != 1 {
+			t.Fatalf("replayed %d messages: %v", n, err)
+		}
+		delete(pub.Cancels, first)
+		if n, err := outbox.Dispatch(); err != nil || n != 1 {
+			t.Fatalf("dispatched %d messages: %v", n, err)
+		}
+		if _, ok := pub.Cancels[first]; !ok {
+			t.Error("expected replayed cancel")
+		}
+
+		// replay a time range
+		params := ReplayParams{
+			StartDate: now.Add(-2 * time.Hour),
+			EndDate:   now,
+		}
+		if n, err := outbox.Replay(params); err != nil || n != 1 {
+			t.Fatalf("replayed %d messages: %v", n, err)
+		}
+		if n, err := outbox.Dispatch(); err != nil || n != 1 {
+			t.Fatalf("dispatched %d messages: %v", n, err)
+		}
+
+		// undispatched messages aren't counted
+		writeOutboxMessage(t, repo, base.ID(), now)
+		if n, err := outbox.Replay(ReplayParams{StartDate: now.Add(-1 * time.Minute)}); err != nil || n != 0 {
+			t.Fatalf("replayed %d messages: %v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestOutboxArchiver(t *testing.T) {
+	if arc, err := NewOutboxArchiver(log.NewNopLogger(), nil, nil); err != nil || arc != nil {
+		t.Fatalf("archiver=%#v error=%v", arc, err)
+	}
+
+	repo := setupSQLiteDB(t)
+	pub := NewMockPublisher()
+	outbox := NewOutbox(log.NewNopLogger(), repo.db, pub)
+
+	now := time.Now()
+	old, recent, pending := base.ID(), base.ID(), base.ID()
+	writeOutboxMessage(t, repo, old, now.Add(-48*time.Hour))
+	writeOutboxMessage(t, repo, recent, now.Add(-1*time.Hour))
+	if n, err := outbox.Dispatch(); err != nil || n != 2 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+	// messages which weren't published are kept
+	writeOutboxMessage(t, repo, pending, now.Add(-48*time.Hour))
+
+	arc, err := NewOutboxArchiver(log.NewNopLogger(), repo.db, &config.Outbox{
+		Retention: 24 * time.Hour,
+		Archive: &config.OutboxArchive{
+			BucketURI: "mem://",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arc.bucket.Close()
+
+	if n, err := arc.Archive(now); err != nil || n != 1 {
+		t.Fatalf("archived %d messages: %v", n, err)
+	}
+	if n, err := arc.Archive(now); err != nil || n != 0 {
+		t.Fatalf("archived %d messages: %v", n, err)
+	}
+
+	var remaining []string
+	rows, err := repo.db.Query(`select transfer_id from pipeline_outbox order by created_at asc;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transferID string
+		if err := rows.Scan(&transferID); err != nil {
+			t.Fatal(err)
+		}
+		remaining = append(remaining, transferID)
+	}
+	if len(remaining) != 2 || remaining[0] != pending || remaining[1] != recent {
+		t.Errorf("unexpected messages: %v", remaining)
+	}
+
+	// read the archived message
+	iter := arc.bucket.List(nil)
+	obj, err := iter.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := arc.bucket.NewReader(context.Background(), obj.Key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	var archived []ArchivedOutboxMessage
+	for scanner.Scan() {
+		var msg ArchivedOutboxMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		archived = append(archived, msg)
+	}
+	if len(archived) != 1 || archived[0].TransferID != old || archived[0].Kind != outboxCancel {
+		t.Errorf("unexpected archived messages: %#v", archived)
+	}
+}
+
+func TestOutbox__routes(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	outbox := NewOutbox(log.NewNopLogger(), repo.db, NewMockPublisher())
+
+	svc, c := testclient.Admin(t)
+	outbox.RegisterRoutes(svc)
+
+	transferID := base.ID()
+	writeOutboxMessage(t, repo, transferID, time.Now())
+	if n, err := outbox.Dispatch(); err != nil || n != 1 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+
+	replayed, resp, err := c.TransfersApi.ReplayOutbox(context.TODO(), admin.ReplayOutbox{TransferID: transferID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if replayed.Replayed != 1 {
+		t.Errorf("unexpected replay: %#v", replayed)
+	}
+
+	// replaying every message isn't allowed
+	_, resp, err = c.TransfersApi.ReplayOutbox(context.TODO(), admin.ReplayOutbox{})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
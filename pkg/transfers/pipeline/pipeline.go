@@ -12,6 +12,14 @@ import (
 type Xfer struct {
 	Transfer *client.Transfer `json:"transfer"`
 	File     *ach.File        `json:"file"`
+
+	// Organization owns the Transfer and is used to partition Transfers when sharding.
+	// It's only set on messages written to the outbox.
+	Organization string `json:"organization,omitempty"`
+
+	// ConsolidateBatches allows the Transfer's batches to be combined with others
+	// sharing their company, SEC code and effective date when files are merged.
+	ConsolidateBatches bool `json:"consolidateBatches,omitempty"`
 }
 
 type CanceledTransfer struct {
@@ -0,0 +1,55 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	missedWindowEntries = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "pipeline_entries_missed_window",
+		Help: "Counter of entries merged after their effective entry date by whether they were same-day",
+	}, []string{"sameDay"})
+)
+
+// prioritize sorts files with same-day batches ahead of the others, otherwise keeping
+// their order. Same-day files are merged first so they fill the earliest merged files,
+// which are then uploaded first.
+func prioritize(files []*ach.File) {
+	sort.SliceStable(files, func(i, j int) bool {
+		return achx.HasSameDay(files[i]) && !achx.HasSameDay(files[j])
+	})
+}
+
+// countMissedWindows records entries of files whose effective entry date is before the day
+// of now, which means they weren't merged during the cutoffs they were intended for.
+func countMissedWindows(files []*ach.File, now time.Time) int {
+	today := now.Format("060102")
+
+	var missed int
+	for i := range files {
+		for j := range files[i].Batches {
+			bh := files[i].Batches[j].GetHeader()
+			// EffectiveEntryDate is YYMMDD, so it sorts as a string
+			if bh == nil || bh.EffectiveEntryDate == "" || bh.EffectiveEntryDate >= today {
+				continue
+			}
+			n := len(files[i].Batches[j].GetEntries())
+			missedWindowEntries.With("sameDay", strconv.FormatBool(achx.IsSameDay(bh))).Add(float64(n))
+			missed += n
+		}
+	}
+	return missed
+}
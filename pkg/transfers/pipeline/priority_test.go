@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/achx"
+)
+
+func readPriorityTestFile(t *testing.T, descriptiveDate string) *ach.File {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Batches[0].GetHeader().CompanyDescriptiveDate = descriptiveDate
+	return file
+}
+
+func TestPriority__prioritize(t *testing.T) {
+	standard1 := readPriorityTestFile(t, "200730")
+	standard2 := readPriorityTestFile(t, "200730")
+	sameDay := readPriorityTestFile(t, "SD1300")
+
+	files := []*ach.File{standard1, sameDay, standard2}
+	prioritize(files)
+
+	if files[0] != sameDay || files[1] != standard1 || files[2] != standard2 {
+		t.Errorf("unexpected order: %v", files)
+	}
+	if !achx.HasSameDay(files[0]) {
+		t.Error("expected same-day file first")
+	}
+}
+
+func TestPriority__countMissedWindows(t *testing.T) {
+	now := time.Date(2008, time.July, 30, 10, 0, 0, 0, time.UTC)
+
+	// ppd-debit.ach has an effective entry date of 2008-07-30
+	file := readPriorityTestFile(t, "SD1300")
+	if n := countMissedWindows([]*ach.File{file}, now); n != 0 {
+		t.Errorf("unexpected %d missed entries", n)
+	}
+	if n := countMissedWindows([]*ach.File{file}, now.Add(24*time.Hour)); n != 1 {
+		t.Errorf("unexpected %d missed entries", n)
+	}
+}
@@ -36,6 +36,16 @@ func NewPublisher(cfg config.Pipeline) (XferPublisher, error) {
 // All files are attempted to be published as downstream processors
 // are expected to de-duplicate files.
 func PublishFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File) error {
+	return publishFiles(pub, xfer, files, false)
+}
+
+// PublishConsolidatedFiles is PublishFiles for organizations whose Transfers are
+// consolidated into shared batches when files are merged.
+func PublishConsolidatedFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File) error {
+	return publishFiles(pub, xfer, files, true)
+}
+
+func publishFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File, consolidate bool) error {
 	if pub == nil {
 		return nil
 	}
@@ -43,8 +53,9 @@ func PublishFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File) e
 	var el base.ErrorList
 	for i := range files {
 		xf := Xfer{
-			File:     files[i],
-			Transfer: xfer,
+			File:               files[i],
+			Transfer:           xfer,
+			ConsolidateBatches: consolidate,
 		}
 		if err := pub.Upload(xf); err != nil {
 			el.Add(err)
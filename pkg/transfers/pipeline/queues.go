@@ -0,0 +1,160 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	workQueueItems = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "work_queue_items",
+		Help: "Gauge of items waiting in each work queue",
+	}, []string{"queue"})
+
+	workQueueOldestAge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "work_queue_oldest_age_seconds",
+		Help: "Gauge of how long the oldest item of each work queue has been waiting",
+	}, []string{"queue"})
+)
+
+// WorkQueue summarizes the items waiting on one step of PayGate.
+type WorkQueue struct {
+	Count  int        `json:"count"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+
+	// OldestAge is how many seconds the oldest item has been waiting
+	OldestAge int64 `json:"oldestAge"`
+}
+
+// WorkQueues lists the work which PayGate hasn't finished yet so the backlog can be
+// watched from dashboards. Unlike StuckWork every item is included regardless of its age.
+type WorkQueues struct {
+	Checked time.Time `json:"checked"`
+
+	// Originations are Transfers accepted with 202 Accepted which aren't originated yet
+	Originations WorkQueue `json:"originations"`
+
+	// Outbox are messages saved with Transfers which aren't published to the aggregator yet
+	Outbox WorkQueue `json:"outbox"`
+
+	// Merges are entries in the mergable directory waiting for the next cutoff
+	Merges WorkQueue `json:"merges"`
+
+	// Uploads are merged files which weren't confirmed as uploaded
+	Uploads WorkQueue `json:"uploads"`
+
+	// Returns are downloaded return files which are quarantined until they're retried
+	Returns WorkQueue `json:"returns"`
+}
+
+// Queues reads the size and oldest item of each work queue and updates the work_queue_items
+// and work_queue_oldest_age_seconds metrics.
+func (rec *Recovery) Queues() (*WorkQueues, error) {
+	queues := &WorkQueues{
+		Checked: time.Now(),
+	}
+
+	var err error
+	if queues.Originations, err = rec.repo.getWorkQueue(
+		`select count(*) from transfer_queue;`,
+		`select created_at from transfer_queue order by created_at asc limit 1;`,
+	); err != nil {
+		return nil, fmt.Errorf("problem reading queued transfers: %v", err)
+	}
+	if queues.Outbox, err = rec.repo.getWorkQueue(
+		`select count(*) from pipeline_outbox where dispatched_at is null and held_at is null;`,
+		`select created_at from pipeline_outbox where dispatched_at is null and held_at is null order by created_at asc limit 1;`,
+	); err != nil {
+		return nil, fmt.Errorf("problem reading outbox: %v", err)
+	}
+	if queues.Uploads, err = rec.repo.getWorkQueue(
+		`select count(distinct merged_filename) from merged_transfers where state = ?;`,
+		`select updated_at from merged_transfers where state = ? order by updated_at asc limit 1;`,
+		mergeWritten,
+	); err != nil {
+		return nil, fmt.Errorf("problem reading written merges: %v", err)
+	}
+	if queues.Returns, err = rec.repo.getWorkQueue(
+		`select count(*) from quarantined_files where kind = ? and status = ?;`,
+		`select created_at from quarantined_files where kind = ? and status = ? order by created_at asc limit 1;`,
+		"return", admin.QUARANTINED,
+	); err != nil {
+		return nil, fmt.Errorf("problem reading quarantined returns: %v", err)
+	}
+	if queues.Merges, err = mergableQueue(rec.baseDir); err != nil {
+		return nil, fmt.Errorf("problem reading mergable directory: %v", err)
+	}
+
+	for name, queue := range map[string]*WorkQueue{
+		"originations": &queues.Originations,
+		"outbox":       &queues.Outbox,
+		"merges":       &queues.Merges,
+		"uploads":      &queues.Uploads,
+		"returns":      &queues.Returns,
+	} {
+		if queue.Oldest != nil {
+			queue.OldestAge = int64(queues.Checked.Sub(*queue.Oldest).Seconds())
+		}
+		workQueueItems.With("queue", name).Set(float64(queue.Count))
+		workQueueOldestAge.With("queue", name).Set(float64(queue.OldestAge))
+	}
+
+	return queues, nil
+}
+
+// mergableQueue counts the Transfer files in dir, which is missing until the first one is written.
+func mergableQueue(dir string) (WorkQueue, error) {
+	var queue WorkQueue
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return queue, nil
+		}
+		return queue, err
+	}
+	for i := range infos {
+		if infos[i].IsDir() || !strings.EqualFold(filepath.Ext(infos[i].Name()), ".ach") {
+			continue
+		}
+		queue.Count++
+		if mod := infos[i].ModTime(); queue.Oldest == nil || mod.Before(*queue.Oldest) {
+			queue.Oldest = &mod
+		}
+	}
+	return queue, nil
+}
+
+// getWorkQueue reads the size of a queue with countQuery and when its oldest item was
+// added with oldestQuery. Both queries are given the same args.
+func (r *sqlRepo) getWorkQueue(countQuery, oldestQuery string, args ...interface{}) (WorkQueue, error) {
+	defer database.MeasureQuery("pipeline", "getWorkQueue")()
+
+	var queue WorkQueue
+	if err := r.db.QueryRow(countQuery, args...).Scan(&queue.Count); err != nil {
+		return queue, err
+	}
+	var oldest time.Time
+	if err := r.db.QueryRow(oldestQuery, args...).Scan(&oldest); err != nil {
+		if err == sql.ErrNoRows {
+			return queue, nil
+		}
+		return queue, err
+	}
+	queue.Oldest = &oldest
+	return queue, nil
+}
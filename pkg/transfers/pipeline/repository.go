@@ -9,36 +9,94 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/util"
 )
 
 type Repository interface {
 	MarkTransfersAsProcessed(transferIDs []string) error
+
+	getUploadedFile(fp fingerprint, since time.Time) (string, error)
+	getUploadedEntries(entryHashes []string, since time.Time) (map[string]string, error)
+	saveUploadedFile(filename string, fp fingerprint, when time.Time) error
+
+	saveUploadAttempt(attempt admin.UploadAttempt, archiveKey string) error
+	getRetainedFile(filename string) (*admin.UploadAttempt, string, error)
+	getExpiredRetainedFiles(before time.Time, limit int) ([]string, error)
+	clearRetainedFile(archiveKey string) error
+
+	planMerge(dir string, transferIDs []string, fence mergeFence) (map[string]string, error)
+	markMergeWritten(filename string, transferIDs []string, fence mergeFence) error
+	markMergeUploaded(filename string) error
+	getInDoubtMerges() ([]mergeRecord, error)
+	getStuckMerges(before time.Time) ([]mergeRecord, error)
+	getMerge(transferID string) (*mergeRecord, error)
+	markTransferUploaded(transferID string) error
+	deleteMerge(transferID string, state string) error
+	getUnprocessedUploads() ([]string, error)
+
+	getUnmergedTransfers(before time.Time) ([]StuckTransfer, error)
+	getStuckMicroDeposits(before time.Time, batchSize int) ([]StuckMicroDeposit, error)
+
+	getWorkQueue(countQuery, oldestQuery string, args ...interface{}) (WorkQueue, error)
+
+	cancelBlockedTransfers(transferIDs []string) ([]string, error)
+	failTransfers(transferIDs []string) error
 }
 
-func NewRepo(db *sql.DB) *sqlRepo {
-	return &sqlRepo{db: db}
+func NewRepo(db *sql.DB, cutoffs config.Cutoffs) *sqlRepo {
+	loc := cutoffs.Location()
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &sqlRepo{db: db, location: loc}
 }
 
 type sqlRepo struct {
 	db *sql.DB
+
+	// location is the timezone of the ODFI's cutoffs, used to find the banking day
+	// Transfers are uploaded on.
+	location *time.Location
 }
 
 // MarkTransfersAsProcessed updates the status for transfers and micro-deposits to PROCESSED.
 // This signals that the underlying ACH file has been uploaded to the ODFI.
 //
+// Transfers settle on their expected settlement date unless they're uploaded after it, in which
+// case the ODFI settles them on the banking day of the upload and their funds are available
+// as many banking days later as they were held for.
+//
 // It would be nicer to share this repository between ./pkg/transfers/ and
 // ./pkg/validation/microdeposits/, but there are cyclic dependencies if it's put into either
 // package.
 func (r *sqlRepo) MarkTransfersAsProcessed(transferIDs []string) error {
+	defer database.MeasureQuery("pipeline", "MarkTransfersAsProcessed")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 
 	now := time.Now()
+	uploaded := uploadBankingDay(now.In(r.location)).Format(util.YYMMDDTimeFormat)
+
+	datesQuery := `select expected_settlement_date, available_on from transfers where transfer_id = ? and deleted_at is null limit 1;`
+	datesStmt, err := tx.Prepare(datesQuery)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer datesStmt.Close()
 
-	transferQuery := `update transfers set status = ?, processed_at = ? where transfer_id = ? and deleted_at is null`
+	transferQuery := `update transfers set status = ?, processed_at = ?, actual_settlement_date = ?, available_on = ?
+where transfer_id = ? and deleted_at is null`
 	transferStmt, err := tx.Prepare(transferQuery)
 	if err != nil {
 		tx.Rollback()
@@ -56,7 +114,14 @@ func (r *sqlRepo) MarkTransfersAsProcessed(transferIDs []string) error {
 	defer microStmt.Close()
 
 	for i := range transferIDs {
-		row, err := transferStmt.Exec(client.PROCESSED, now, transferIDs[i])
+		var expected, availableOn *string
+		if err := datesStmt.QueryRow(transferIDs[i]).Scan(&expected, &availableOn); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("transferID=%s not found / updated: %v", transferIDs[i], err)
+		}
+		settled, available := settlementDates(expected, availableOn, uploaded)
+
+		row, err := transferStmt.Exec(client.PROCESSED, now, settled, available, transferIDs[i])
 		if err != nil && err != sql.ErrNoRows {
 			tx.Rollback()
 			return err
@@ -76,3 +141,114 @@ func (r *sqlRepo) MarkTransfersAsProcessed(transferIDs []string) error {
 
 	return tx.Commit()
 }
+
+// settlementDates returns the actual settlement date and funds availability of a Transfer
+// uploaded on the banking day uploaded. Late uploads keep the Transfer's hold, counted in
+// banking days from the day it settles.
+func settlementDates(expected, availableOn *string, uploaded string) (string, *string) {
+	if expected == nil || *expected < uploaded {
+		if expected != nil && availableOn != nil {
+			shifted := achx.AddBankingDays(uploaded, achx.BankingDaysBetween(*expected, *availableOn))
+			return uploaded, &shifted
+		}
+		return uploaded, availableOn
+	}
+	return *expected, availableOn
+}
+
+// uploadBankingDay returns the banking day of an upload at when. Files uploaded on weekends
+// or holidays are processed by the ODFI on the next banking day.
+func uploadBankingDay(when time.Time) base.Time {
+	day := base.NewTime(when)
+	if !day.IsBankingDay() {
+		day = day.AddBankingDay(1)
+	}
+	return day
+}
+
+// getUploadedFile returns the filename of a file uploaded since the given time with the
+// same entries and totals as fp, or an empty string if there's none.
+func (r *sqlRepo) getUploadedFile(fp fingerprint, since time.Time) (string, error) {
+	defer database.MeasureQuery("pipeline", "getUploadedFile")()
+
+	query := `select filename from uploaded_files
+where file_hash = ? and entry_count = ? and debit_total = ? and credit_total = ? and uploaded_at >= ?
+order by uploaded_at desc limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var filename string
+	err = stmt.QueryRow(fp.FileHash, fp.EntryCount, fp.DebitTotal, fp.CreditTotal, since).Scan(&filename)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return filename, nil
+}
+
+// uploadedEntriesBatchSize limits the bind parameters of each uploaded entries query
+const uploadedEntriesBatchSize = 500
+
+// getUploadedEntries returns the filename each of entryHashes was uploaded in since the given time.
+func (r *sqlRepo) getUploadedEntries(entryHashes []string, since time.Time) (map[string]string, error) {
+	defer database.MeasureQuery("pipeline", "getUploadedEntries")()
+
+	out := make(map[string]string)
+	for start := 0; start < len(entryHashes); start += uploadedEntriesBatchSize {
+		end := start + uploadedEntriesBatchSize
+		if end > len(entryHashes) {
+			end = len(entryHashes)
+		}
+		hashes := entryHashes[start:end]
+
+		query := fmt.Sprintf(`select entry_hash, filename from uploaded_entries where entry_hash in (%s) and uploaded_at >= ?;`, database.Placeholders(len(hashes)))
+		args := append(database.StringArgs(hashes), since)
+
+		err := database.QueryRows(r.db, "getUploadedEntries", query, args, func(rows *sql.Rows) error {
+			var entryHash, filename string
+			if err := rows.Scan(&entryHash, &filename); err != nil {
+				return err
+			}
+			out[entryHash] = filename
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) saveUploadedFile(filename string, fp fingerprint, when time.Time) error {
+	defer database.MeasureQuery("pipeline", "saveUploadedFile")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into uploaded_files (filename, file_hash, entry_count, debit_total, credit_total, uploaded_at) values (?, ?, ?, ?, ?, ?);`
+	_, err = tx.Exec(query, filename, fp.FileHash, fp.EntryCount, fp.DebitTotal, fp.CreditTotal, when)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	entryStmt, err := tx.Prepare(`insert into uploaded_entries (filename, entry_hash, uploaded_at) values (?, ?, ?);`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer entryStmt.Close()
+
+	for i := range fp.EntryHashes {
+		if _, err := entryStmt.Exec(filename, fp.EntryHashes[i], when); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
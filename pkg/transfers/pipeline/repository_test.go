@@ -7,10 +7,13 @@ package pipeline
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/util"
 )
 
 func TestRepository__MarkMicroDepositsAsProcessed(t *testing.T) {
@@ -78,18 +81,84 @@ func TestRepository__MarkTransfersProcessed(t *testing.T) {
 	check(t, setupMySQLeDB(t))
 }
 
+func TestRepository__MarkTransfersProcessedSettlementDate(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		future, past, unknown := base.ID(), base.ID(), base.ID()
+		writeTransfer(t, repo, future)
+		writeTransfer(t, repo, past)
+		writeTransfer(t, repo, unknown)
+		setExpectedSettlementDate(t, repo, future, "2099-01-02")
+		setExpectedSettlementDate(t, repo, past, "2020-01-02")
+
+		if err := repo.MarkTransfersAsProcessed([]string{future, past, unknown}); err != nil {
+			t.Fatal(err)
+		}
+
+		today := uploadBankingDay(time.Now().In(repo.location)).Format(util.YYMMDDTimeFormat)
+		if date := getActualSettlementDate(t, repo, future); date != "2099-01-02" {
+			t.Errorf("unexpected settlement date: %q", date)
+		}
+		// Transfers uploaded after their expected date settle on the day of upload
+		if date := getActualSettlementDate(t, repo, past); date != today {
+			t.Errorf("unexpected settlement date: %q", date)
+		}
+		if date := getActualSettlementDate(t, repo, unknown); date != today {
+			t.Errorf("unexpected settlement date: %q", date)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestSettlementDates(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	// on time uploads keep their dates
+	settled, available := settlementDates(str("2020-11-09"), str("2020-11-12"), "2020-11-06")
+	if settled != "2020-11-09" || available == nil || *available != "2020-11-12" {
+		t.Errorf("settled=%s available=%v", settled, available)
+	}
+
+	// late uploads keep their hold of two banking days
+	settled, available = settlementDates(str("2020-11-06"), str("2020-11-10"), "2020-11-09")
+	if settled != "2020-11-09" || available == nil || *available != "2020-11-12" {
+		t.Errorf("settled=%s available=%v", settled, available)
+	}
+
+	settled, available = settlementDates(nil, nil, "2020-11-09")
+	if settled != "2020-11-09" || available != nil {
+		t.Errorf("settled=%s available=%v", settled, available)
+	}
+}
+
+func TestUploadBankingDay(t *testing.T) {
+	// Saturday
+	when := time.Date(2020, time.August, 15, 10, 30, 0, 0, time.UTC)
+	if day := uploadBankingDay(when); day.Format(util.YYMMDDTimeFormat) != "2020-08-17" {
+		t.Errorf("unexpected day: %v", day)
+	}
+	// Monday
+	when = time.Date(2020, time.August, 17, 10, 30, 0, 0, time.UTC)
+	if day := uploadBankingDay(when); day.Format(util.YYMMDDTimeFormat) != "2020-08-17" {
+		t.Errorf("unexpected day: %v", day)
+	}
+}
+
 func setupSQLiteDB(t *testing.T) *sqlRepo {
 	db := database.CreateTestSqliteDB(t)
 	t.Cleanup(func() { db.Close() })
 
-	return NewRepo(db.DB)
+	return NewRepo(db.DB, config.Cutoffs{})
 }
 
 func setupMySQLeDB(t *testing.T) *sqlRepo {
 	db := database.CreateTestMySQLDB(t)
 	t.Cleanup(func() { db.Close() })
 
-	return NewRepo(db.DB)
+	return NewRepo(db.DB, config.Cutoffs{})
 }
 
 func writeMicroDeposit(t *testing.T, repo *sqlRepo, microDepositID, transferID string) {
@@ -150,6 +219,25 @@ func getMicroDepositStatus(t *testing.T, repo *sqlRepo, microDepositID string) c
 	return status
 }
 
+func setExpectedSettlementDate(t *testing.T, repo *sqlRepo, transferID, date string) {
+	query := `update transfers set expected_settlement_date = ? where transfer_id = ?;`
+	if _, err := repo.db.Exec(query, date, transferID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getActualSettlementDate(t *testing.T, repo *sqlRepo, transferID string) string {
+	query := `select actual_settlement_date from transfers where transfer_id = ? limit 1;`
+	var date *string
+	if err := repo.db.QueryRow(query, transferID).Scan(&date); err != nil {
+		t.Fatal(err)
+	}
+	if date == nil {
+		return ""
+	}
+	return *date
+}
+
 func getPartialTransferModel(t *testing.T, repo *sqlRepo, transferID string) client.Transfer {
 	query := `select status, processed_at from transfers where transfer_id = ? limit 1;`
 	stmt, err := repo.db.Prepare(query)
@@ -0,0 +1,279 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/jobs"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+)
+
+var (
+	retainedFilesExpired = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "retained_files_expired",
+		Help: "Counter of uploaded files deleted after pipeline.fileRetention.retention",
+	}, nil)
+)
+
+// retentionBatchSize is how many retained files are deleted per query
+const retentionBatchSize = 100
+
+// FileRetention keeps each uploaded file in a bucket for pipeline.fileRetention.retention so
+// it can be uploaded again, such as when the ODFI claims they never received it.
+type FileRetention struct {
+	logger log.Logger
+	repo   Repository
+	cfg    *config.FileRetention
+	bucket *blob.Bucket
+
+	agent        upload.Agent
+	verification *config.Verification
+
+	job *jobs.Job
+}
+
+// NewFileRetention returns nil when pipeline.fileRetention isn't configured, in which case
+// uploaded files aren't kept and can't be uploaded again.
+func NewFileRetention(cfg *config.Config, repo Repository, agent upload.Agent) (*FileRetention, error) {
+	if cfg.Pipeline.FileRetention == nil {
+		return nil, nil
+	}
+	bucket, err := blob.OpenBucket(context.Background(), cfg.Pipeline.FileRetention.BucketURI)
+	if err != nil {
+		return nil, fmt.Errorf("opening file retention bucket: %v", err)
+	}
+	return &FileRetention{
+		logger:       cfg.Logger.Set("service", log.String("FileRetention")),
+		repo:         repo,
+		cfg:          cfg.Pipeline.FileRetention,
+		bucket:       bucket,
+		agent:        agent,
+		verification: cfg.ODFI.Verification,
+	}, nil
+}
+
+// UseFileRetention keeps each file the XferAggregator uploads in ret.
+func (xfagg *XferAggregator) UseFileRetention(ret *FileRetention) {
+	xfagg.retention = ret
+}
+
+// UseJobs records the runs of the FileRetention as the "pipeline.file-retention" job of reg.
+func (ret *FileRetention) UseJobs(reg *jobs.Registry) {
+	if ret == nil {
+		return
+	}
+	ret.job = reg.Register("pipeline.file-retention")
+}
+
+// Start deletes expired files every pipeline.fileRetention.interval until ctx is canceled.
+func (ret *FileRetention) Start(ctx context.Context) {
+	if ret == nil {
+		return
+	}
+	interval := ret.cfg.ExpireInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	ret.job.Scheduled(time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			ret.run()
+			ret.job.Scheduled(time.Now().Add(interval))
+
+		case <-ret.job.Triggered():
+			ret.run()
+
+		case <-ctx.Done():
+			ret.logger.Log("file retention shutdown")
+			ret.bucket.Close()
+			return
+		}
+	}
+}
+
+func (ret *FileRetention) run() {
+	n, err := ret.Expire(time.Now())
+	if err != nil {
+		ret.logger.LogErrorf("ERROR deleting retained files: %v", err)
+	}
+	if n > 0 {
+		ret.logger.Logf("deleted %d retained files", n)
+	}
+	ret.job.Done(err)
+}
+
+// Expire deletes the files first uploaded before their retention as of now and returns how
+// many were deleted. Their upload attempts stay in the file archive.
+func (ret *FileRetention) Expire(now time.Time) (int, error) {
+	before := now.Add(-1 * ret.cfg.Retention)
+
+	expired := 0
+	for {
+		keys, err := ret.repo.getExpiredRetainedFiles(before, retentionBatchSize)
+		if err != nil {
+			return expired, err
+		}
+		for i := range keys {
+			if err := ret.bucket.Delete(context.Background(), keys[i]); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+				return expired, fmt.Errorf("deleting %s: %v", keys[i], err)
+			}
+			if err := ret.repo.clearRetainedFile(keys[i]); err != nil {
+				return expired, err
+			}
+			expired++
+			retainedFilesExpired.Add(1)
+		}
+		if len(keys) < retentionBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// retain copies contents into the bucket and returns its key, or an empty key when ret is nil.
+func (ret *FileRetention) retain(filename string, contents []byte, when time.Time) (string, error) {
+	if ret == nil {
+		return "", nil
+	}
+	key := fmt.Sprintf("outbound/%s/%s", when.Format("2006-01-02"), filename)
+	if err := ret.bucket.WriteAll(context.Background(), key, contents, nil); err != nil {
+		return "", fmt.Errorf("problem retaining file: %v", err)
+	}
+	return key, nil
+}
+
+// reupload uploads the retained copy of filename again and records the attempt. It returns
+// nil without an error when no copy of filename is retained.
+func (ret *FileRetention) reupload(filename string) (*admin.UploadAttempt, error) {
+	prev, key, err := ret.repo.getRetainedFile(filename)
+	if err != nil || prev == nil {
+		return nil, err
+	}
+	contents, err := ret.bucket.ReadAll(context.Background(), key)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", key, err)
+	}
+
+	// Upload into the same directory as before
+	agent := ret.agent
+	for _, a := range upload.Agents(ret.agent) {
+		if a.OutboundPath() == prev.OutboundPath {
+			agent = a
+			break
+		}
+	}
+	verified, err := upload.UploadVerified(ret.logger, agent, filename, contents, ret.verification)
+	return recordUploadAttempt(ret.logger, ret.repo, agent, filename, contents, key, verified, err), err
+}
+
+// recordUploadAttempt saves an upload of contents into the file archive. Failures are
+// logged rather than returned as the file was already uploaded, or not, by then.
+func recordUploadAttempt(logger log.Logger, repo Repository, agent upload.Agent, filename string, contents []byte, archiveKey string, verified bool, uploadErr error) *admin.UploadAttempt {
+	sum := sha256.Sum256(contents)
+	attempt := &admin.UploadAttempt{
+		Filename:     filename,
+		FileHash:     hex.EncodeToString(sum[:]),
+		Size:         int64(len(contents)),
+		OutboundPath: agent.OutboundPath(),
+		Verified:     verified,
+		AttemptedAt:  time.Now(),
+	}
+	if uploadErr != nil {
+		attempt.Error = uploadErr.Error()
+	}
+	if err := repo.saveUploadAttempt(*attempt, archiveKey); err != nil {
+		logger.LogErrorf("problem recording upload attempt of %s: %v", filename, err)
+	}
+	return attempt
+}
+
+func (r *sqlRepo) saveUploadAttempt(attempt admin.UploadAttempt, archiveKey string) error {
+	defer database.MeasureQuery("pipeline", "saveUploadAttempt")()
+
+	query := `insert into file_archive (filename, kind, file_hash, size, archive_key, outbound_path, error, verified, processed_at) values (?, 'outbound', ?, ?, ?, ?, ?, ?, ?);`
+	var key, errMsg *string
+	if archiveKey != "" {
+		key = &archiveKey
+	}
+	if attempt.Error != "" {
+		errMsg = &attempt.Error
+	}
+	_, err := r.db.Exec(query, attempt.Filename, attempt.FileHash, attempt.Size, key, attempt.OutboundPath, errMsg, attempt.Verified, attempt.AttemptedAt)
+	return err
+}
+
+// getRetainedFile returns the latest upload attempt of filename whose copy is still retained
+// along with the key of that copy, or nil if there's none.
+func (r *sqlRepo) getRetainedFile(filename string) (*admin.UploadAttempt, string, error) {
+	defer database.MeasureQuery("pipeline", "getRetainedFile")()
+
+	query := `select filename, file_hash, size, outbound_path, error, verified, archive_key, processed_at from file_archive
+where filename = ? and kind = 'outbound' and archive_key is not null order by processed_at desc limit 1;`
+
+	var attempt admin.UploadAttempt
+	var archiveKey string
+	var outboundPath, errMsg *string
+	err := r.db.QueryRow(query, filename).Scan(&attempt.Filename, &attempt.FileHash, &attempt.Size, &outboundPath, &errMsg, &attempt.Verified, &archiveKey, &attempt.AttemptedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	if outboundPath != nil {
+		attempt.OutboundPath = *outboundPath
+	}
+	if errMsg != nil {
+		attempt.Error = *errMsg
+	}
+	return &attempt, archiveKey, nil
+}
+
+// getExpiredRetainedFiles returns the keys of retained files first uploaded before before.
+func (r *sqlRepo) getExpiredRetainedFiles(before time.Time, limit int) ([]string, error) {
+	defer database.MeasureQuery("pipeline", "getExpiredRetainedFiles")()
+
+	query := `select archive_key from file_archive where kind = 'outbound' and archive_key is not null
+group by archive_key having min(processed_at) < ? limit ?;`
+	rows, err := r.db.Query(query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// clearRetainedFile forgets the retained copy at archiveKey once it's deleted.
+func (r *sqlRepo) clearRetainedFile(archiveKey string) error {
+	defer database.MeasureQuery("pipeline", "clearRetainedFile")()
+
+	query := `update file_archive set archive_key = null where kind = 'outbound' and archive_key = ?;`
+	_, err := r.db.Exec(query, archiveKey)
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (ret *FileRetention) RegisterRoutes(svc *admin.Server) {
+	if ret == nil {
+		return
+	}
+	svc.AddHandler("/files/{filename}/re-upload", ret.reuploadFile())
+}
+
+func (ret *FileRetention) reuploadFile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodPost {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		filename := route.ReadPathID("filename", r)
+		userID := moovhttp.GetUserID(r)
+		if userID == "" {
+			route.Problem(w, route.InvalidRequest.New("missing X-User-ID"))
+			return
+		}
+
+		attempt, err := ret.reupload(filename)
+		if attempt == nil {
+			if err != nil {
+				route.Problem(w, route.Internal.Wrap(err))
+			} else {
+				route.Problem(w, route.NotFound.New("retained file %s not found", filename))
+			}
+			return
+		}
+		logger := ret.logger.With(log.Fields{
+			"filename": log.String(filename),
+			"userID":   log.String(userID),
+		})
+		if err != nil {
+			logger.LogErrorf("ERROR uploading retained file again: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+		logger.Log("uploaded retained file again")
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(attempt)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base/log"
+	"gocloud.dev/blob"
+)
+
+func TestFileRetention(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	agent := &upload.MockAgent{}
+
+	bucket, err := blob.OpenBucket(context.Background(), "mem://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bucket.Close()
+
+	ret := &FileRetention{
+		logger: log.NewNopLogger(),
+		repo:   repo,
+		cfg:    &config.FileRetention{BucketURI: "mem://", Retention: 24 * time.Hour},
+		bucket: bucket,
+		agent:  agent,
+	}
+
+	// the first upload failed
+	contents := []byte("101 ...")
+	key, err := ret.retain("20201015-0900-987654320.ach", contents, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordUploadAttempt(log.NewNopLogger(), repo, agent, "20201015-0900-987654320.ach", contents, key, false, errors.New("connection reset"))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/files/{filename}/re-upload", ret.reuploadFile())
+
+	reupload := func(filename, userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/files/"+filename+"/re-upload", nil)
+		req.Header.Set("X-User-ID", userID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := reupload("20201015-0900-987654320.ach", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+	if w := reupload("20201015-0900-987654320.ach", "jane"); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	if agent.Uploads != 1 || agent.UploadedFile == nil {
+		t.Fatalf("uploads=%d", agent.Uploads)
+	}
+	if bs, _ := ioutil.ReadAll(agent.UploadedFile.Contents); string(bs) != string(contents) {
+		t.Errorf("unexpected contents: %q", bs)
+	}
+
+	// each attempt is in the file archive
+	var attempts, failures int
+	if err := repo.db.QueryRow(`select count(*), count(error) from file_archive where kind = 'outbound';`).Scan(&attempts, &failures); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || failures != 1 {
+		t.Errorf("attempts=%d failures=%d", attempts, failures)
+	}
+
+	// nothing has expired yet
+	if n, err := ret.Expire(time.Now()); n != 0 || err != nil {
+		t.Fatalf("expired=%d error=%v", n, err)
+	}
+	if n, err := ret.Expire(time.Now().Add(48 * time.Hour)); n != 1 || err != nil {
+		t.Fatalf("expired=%d error=%v", n, err)
+	}
+	if exists, _ := bucket.Exists(context.Background(), key); exists {
+		t.Errorf("%s wasn't deleted", key)
+	}
+	if w := reupload("20201015-0900-987654320.ach", "jane"); w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+}
@@ -0,0 +1,457 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ownedShards = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "pipeline_shards_owned",
+		Help: "Gauge of pipeline shards owned by this instance",
+	}, nil)
+)
+
+// Shards partitions Transfers across PayGate instances. Each instance merges and uploads
+// only the Transfers of shards it holds a lease on in the pipeline_shards table.
+//
+// Instances heartbeat into pipeline_instances and claim unowned or expired shards up to
+// an even share. Shards beyond an instance's share are released after a cutoff, once its
+// mergable directory is empty. Transfers are replayed from the outbox when a shard is
+// claimed, so messages received while nobody owned it aren't lost. Leases are checked again
+// in the transactions recording each merge, so an instance which lost a shard part way
+// through a cutoff doesn't upload its Transfers along with the new owner.
+//
+// Files published outside the outbox (e.g. micro-deposits and returns) have no organization
+// and always belong to shard 0, which a running instance never releases.
+type Shards struct {
+	cfg        *config.Sharding
+	logger     log.Logger
+	db         *sql.DB
+	instanceID string
+
+	seeded bool
+
+	mu    sync.RWMutex
+	owned map[int]bool
+}
+
+// NewShards returns nil when sharding isn't configured. A nil *Shards owns every Transfer.
+func NewShards(logger log.Logger, cfg *config.Sharding, db *sql.DB) *Shards {
+	if cfg == nil {
+		return nil
+	}
+	return &Shards{
+		cfg:        cfg,
+		logger:     logger.Set("service", log.String("shards")),
+		db:         db,
+		instanceID: cfg.Instance(),
+		owned:      make(map[int]bool),
+	}
+}
+
+// ShardOf returns the shard xfer is partitioned into.
+func (s *Shards) ShardOf(xfer Xfer) int {
+	if xfer.Organization == "" {
+		return 0
+	}
+	key := xfer.Organization
+	if s.cfg.By == "routingNumber" {
+		key = rdfiRoutingNumber(xfer)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(s.cfg.Shards))
+}
+
+func rdfiRoutingNumber(xfer Xfer) string {
+	if xfer.File == nil {
+		return ""
+	}
+	for i := range xfer.File.Batches {
+		entries := xfer.File.Batches[i].GetEntries()
+		if len(entries) > 0 {
+			return entries[0].RDFIIdentification
+		}
+	}
+	return ""
+}
+
+// Owns returns true if xfer belongs to a shard this instance holds.
+func (s *Shards) Owns(xfer Xfer) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.owned[s.ShardOf(xfer)]
+}
+
+// Owned returns the shards this instance holds in ascending order.
+func (s *Shards) Owned() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []int
+	for shard := range s.owned {
+		out = append(out, shard)
+	}
+	sort.Ints(out)
+	return out
+}
+
+func (s *Shards) setOwned(shards []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.owned = make(map[int]bool)
+	for i := range shards {
+		s.owned[shards[i]] = true
+	}
+	ownedShards.Set(float64(len(shards)))
+}
+
+// interval returns how often leases are renewed, which is well within their duration.
+func (s *Shards) interval() time.Duration {
+	return s.cfg.Lease() / 3
+}
+
+// Renew heartbeats this instance, extends its leases and claims unowned or expired
+// shards up to an even share. It returns the shards which were claimed.
+func (s *Shards) Renew() ([]int, error) {
+	now := time.Now()
+	expires := now.Add(s.cfg.Lease())
+
+	if err := s.heartbeat(now); err != nil {
+		return nil, fmt.Errorf("heartbeat: %v", err)
+	}
+	if err := s.seed(now); err != nil {
+		return nil, fmt.Errorf("seeding shards: %v", err)
+	}
+
+	query := `update pipeline_shards set lease_expires_at = ? where owner = ?;`
+	if _, err := s.db.Exec(query, expires, s.instanceID); err != nil {
+		return nil, fmt.Errorf("renewing leases: %v", err)
+	}
+	owned, err := s.getOwned()
+	if err != nil {
+		return nil, err
+	}
+	if lost := s.lost(owned); len(lost) > 0 {
+		s.logger.LogErrorf("lost shards %v to another instance", lost)
+	}
+
+	share, err := s.share(now)
+	if err != nil {
+		return nil, err
+	}
+	var claimed []int
+	for shard := 0; shard < s.cfg.Shards && len(owned) < share; shard++ {
+		if containsShard(owned, shard) {
+			continue
+		}
+		query := `update pipeline_shards set owner = ?, lease_expires_at = ? where shard = ? and (owner = '' or lease_expires_at < ?);`
+		res, err := s.db.Exec(query, s.instanceID, expires, shard, now)
+		if err != nil {
+			return claimed, fmt.Errorf("claiming shard %d: %v", shard, err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			owned = append(owned, shard)
+			claimed = append(claimed, shard)
+		}
+	}
+	sort.Ints(owned)
+	s.setOwned(owned)
+
+	if len(claimed) > 0 {
+		s.logger.Logf("claimed shards %v, now owning %v", claimed, owned)
+	}
+	return claimed, nil
+}
+
+// Release gives up the shards beyond an even share so other instances can claim them.
+// It must only be called when the mergable directory holds no Transfers of those shards.
+func (s *Shards) Release() ([]int, error) {
+	share, err := s.share(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	owned := s.Owned()
+	if len(owned) <= share {
+		return nil, nil
+	}
+
+	// Keep the lowest shards, so shard 0 stays with a running instance
+	released := owned[share:]
+	query := `update pipeline_shards set owner = '' where shard = ? and owner = ?;`
+	for i := range released {
+		if _, err := s.db.Exec(query, released[i], s.instanceID); err != nil {
+			return nil, fmt.Errorf("releasing shard %d: %v", released[i], err)
+		}
+	}
+	s.setOwned(owned[:share])
+	s.logger.Logf("released shards %v, now owning %v", released, owned[:share])
+
+	return released, nil
+}
+
+func (s *Shards) heartbeat(now time.Time) error {
+	defer database.MeasureQuery("pipeline", "shardHeartbeat")()
+
+	res, err := s.db.Exec(`update pipeline_instances set heartbeat_at = ? where instance_id = ?;`, now, s.instanceID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = s.db.Exec(`insert into pipeline_instances (instance_id, heartbeat_at) values (?, ?);`, s.instanceID, now)
+	if err != nil && database.UniqueViolation(err) {
+		return nil
+	}
+	return err
+}
+
+// seed creates a row for each shard, which other instances might be doing at the same time.
+func (s *Shards) seed(now time.Time) error {
+	if s.seeded {
+		return nil
+	}
+	query := `insert into pipeline_shards (shard, owner, lease_expires_at) values (?, '', ?);`
+	for shard := 0; shard < s.cfg.Shards; shard++ {
+		if _, err := s.db.Exec(query, shard, now); err != nil && !database.UniqueViolation(err) {
+			return err
+		}
+	}
+	s.seeded = true
+	return nil
+}
+
+func (s *Shards) getOwned() ([]int, error) {
+	defer database.MeasureQuery("pipeline", "getOwnedShards")()
+
+	query := `select shard from pipeline_shards where owner = ? and shard < ? order by shard asc;`
+	var out []int
+	err := database.QueryRows(s.db, "getOwnedShards", query, []interface{}{s.instanceID, s.cfg.Shards}, func(rows *sql.Rows) error {
+		var shard int
+		if err := rows.Scan(&shard); err != nil {
+			return err
+		}
+		out = append(out, shard)
+		return nil
+	})
+	return out, err
+}
+
+// share returns how many shards each live instance should own.
+func (s *Shards) share(now time.Time) (int, error) {
+	var live int
+	query := `select count(*) from pipeline_instances where heartbeat_at > ?;`
+	if err := s.db.QueryRow(query, now.Add(-1*s.cfg.Lease())).Scan(&live); err != nil {
+		return 0, fmt.Errorf("counting instances: %v", err)
+	}
+	if live < 1 {
+		live = 1
+	}
+	return (s.cfg.Shards + live - 1) / live, nil
+}
+
+func (s *Shards) lost(owned []int) []int {
+	var out []int
+	for _, shard := range s.Owned() {
+		if !containsShard(owned, shard) {
+			out = append(out, shard)
+		}
+	}
+	return out
+}
+
+func containsShard(shards []int, shard int) bool {
+	for i := range shards {
+		if shards[i] == shard {
+			return true
+		}
+	}
+	return false
+}
+
+// fence returns the mergeFence of Transfers merged from dir, which holds the Transfers of
+// shards this instance owns with an unexpired lease.
+func (s *Shards) fence(dir string) mergeFence {
+	if s == nil {
+		return nil
+	}
+	return func(tx *sql.Tx, transferID string) (bool, error) {
+		return s.holds(tx, transferID, filepath.Join(dir, fmt.Sprintf("%s.ach", transferID)))
+	}
+}
+
+// holds returns true if this instance owns the shard of transferID, whose ACH file is at path,
+// and its lease hasn't expired.
+func (s *Shards) holds(tx *sql.Tx, transferID, path string) (bool, error) {
+	var xfer Xfer
+	query := `select organization from transfers where transfer_id = ? limit 1;`
+	if err := tx.QueryRow(query, transferID).Scan(&xfer.Organization); err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("reading organization of transferID=%s: %v", transferID, err)
+	}
+	if xfer.Organization != "" && s.cfg.By == "routingNumber" {
+		file, err := ach.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("reading %s: %v", path, err)
+		}
+		xfer.File = file
+	}
+
+	var n int
+	query = `select count(*) from pipeline_shards where shard = ? and owner = ? and lease_expires_at > ?;`
+	if err := tx.QueryRow(query, s.ShardOf(xfer), s.instanceID, time.Now()).Scan(&n); err != nil {
+		return false, fmt.Errorf("reading lease of transferID=%s: %v", transferID, err)
+	}
+	return n > 0, nil
+}
+
+// Replay hands merger the PENDING Transfers of shards which haven't been merged yet.
+// Their messages could have been received by every instance while nobody owned the shard.
+// Transfers only planned by the shard's previous owner are replayed too, as its fence
+// keeps them from being uploaded.
+func (s *Shards) Replay(merger XferMerging, shards []int) (int, error) {
+	if len(shards) == 0 {
+		return 0, nil
+	}
+	defer database.MeasureQuery("pipeline", "replayShards")()
+
+	query := `select o.body from pipeline_outbox as o
+inner join transfers as xf on o.transfer_id = xf.transfer_id
+left join merged_transfers as m on o.transfer_id = m.transfer_id
+where o.kind = ? and xf.status = ? and xf.deleted_at is null and (m.transfer_id is null or m.state = ?)`
+	args := []interface{}{outboxUpload, client.PENDING, mergePlanned}
+
+	// Shards of organizations are computed here, so only read their Transfers
+	if s.cfg.By != "routingNumber" {
+		orgs, err := s.replayOrganizations(shards)
+		if err != nil {
+			return 0, err
+		}
+		if len(orgs) == 0 {
+			return 0, nil
+		}
+		query += fmt.Sprintf(" and xf.organization in (%s)", database.Placeholders(len(orgs)))
+		args = append(args, database.StringArgs(orgs)...)
+	}
+	query += " order by o.sequence_id asc;"
+
+	var xfers []Xfer
+	err := database.QueryRows(s.db, "replayShards", query, args, func(rows *sql.Rows) error {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return err
+		}
+		var xfer Xfer
+		if err := json.Unmarshal([]byte(body), &xfer); err != nil {
+			return fmt.Errorf("json decode: %v", err)
+		}
+		if containsShard(shards, s.ShardOf(xfer)) {
+			xfers = append(xfers, xfer)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range xfers {
+		if err := merger.HandleXfer(xfers[i]); err != nil {
+			return i, fmt.Errorf("transferID=%s: %v", xfers[i].Transfer.TransferID, err)
+		}
+	}
+	return len(xfers), nil
+}
+
+// replayOrganizations returns the organizations in shards with PENDING Transfers to replay.
+func (s *Shards) replayOrganizations(shards []int) ([]string, error) {
+	query := `select distinct xf.organization from transfers as xf
+left join merged_transfers as m on xf.transfer_id = m.transfer_id
+where xf.status = ? and xf.deleted_at is null and (m.transfer_id is null or m.state = ?);`
+	var out []string
+	err := database.QueryRows(s.db, "replayOrganizations", query, []interface{}{client.PENDING, mergePlanned}, func(rows *sql.Rows) error {
+		var orgID string
+		if err := rows.Scan(&orgID); err != nil {
+			return err
+		}
+		if containsShard(shards, s.ShardOf(Xfer{Organization: orgID})) {
+			out = append(out, orgID)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// shardedMerging drops Xfers of shards owned by other instances.
+type shardedMerging struct {
+	XferMerging
+
+	shards *Shards
+}
+
+func (m *shardedMerging) HandleXfer(xfer Xfer) error {
+	if !m.shards.Owns(xfer) {
+		return nil
+	}
+	return m.XferMerging.HandleXfer(xfer)
+}
+
+// UseShards limits the aggregator to Transfers of the shards this instance owns.
+func (xfagg *XferAggregator) UseShards(shards *Shards) {
+	if shards == nil {
+		return
+	}
+	xfagg.shards = shards
+	if m, ok := xfagg.merger.(*filesystemMerging); ok {
+		m.shards = shards
+	}
+	xfagg.merger = &shardedMerging{XferMerging: xfagg.merger, shards: shards}
+}
+
+func (xfagg *XferAggregator) renewShards() {
+	if xfagg.shards == nil {
+		return
+	}
+	claimed, err := xfagg.shards.Renew()
+	if err != nil {
+		xfagg.logger.LogErrorf("ERROR renewing shards: %v", err)
+	}
+	if n, err := xfagg.shards.Replay(xfagg.merger, claimed); err != nil {
+		xfagg.logger.LogErrorf("ERROR replaying transfers of shards %v: %v", claimed, err)
+	} else if n > 0 {
+		xfagg.logger.Logf("replayed %d transfers of shards %v", n, claimed)
+	}
+}
+
+// releaseShards is called after a cutoff when the mergable directory was emptied.
+func (xfagg *XferAggregator) releaseShards() {
+	if xfagg.shards == nil {
+		return
+	}
+	if _, err := xfagg.shards.Release(); err != nil {
+		xfagg.logger.LogErrorf("ERROR releasing shards: %v", err)
+	}
+}
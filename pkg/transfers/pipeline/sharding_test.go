@@ -0,0 +1,208 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestShards__nil(t *testing.T) {
+	shards := NewShards(log.NewNopLogger(), nil, nil)
+	if shards != nil {
+		t.Fatalf("unexpected shards: %#v", shards)
+	}
+	if !shards.Owns(Xfer{Organization: base.ID()}) {
+		t.Error("nil Shards should own every transfer")
+	}
+}
+
+func TestShards__ShardOf(t *testing.T) {
+	shards := NewShards(log.NewNopLogger(), &config.Sharding{Shards: 8}, nil)
+
+	if n := shards.ShardOf(Xfer{}); n != 0 {
+		t.Errorf("unexpected shard: %d", n)
+	}
+	xfer := Xfer{Organization: "moov"}
+	if a, b := shards.ShardOf(xfer), shards.ShardOf(xfer); a != b || a < 0 || a >= 8 {
+		t.Errorf("unexpected shards: %d and %d", a, b)
+	}
+}
+
+func TestShards__Renew(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	cfg := func(id string) *config.Sharding {
+		return &config.Sharding{Shards: 4, InstanceID: id}
+	}
+	a := NewShards(log.NewNopLogger(), cfg("a"), repo.db)
+	b := NewShards(log.NewNopLogger(), cfg("b"), repo.db)
+
+	if claimed, err := a.Renew(); err != nil || !reflect.DeepEqual(claimed, []int{0, 1, 2, 3}) {
+		t.Fatalf("claimed=%v error=%v", claimed, err)
+	}
+	if claimed, err := b.Renew(); err != nil || len(claimed) != 0 {
+		t.Fatalf("claimed=%v error=%v", claimed, err)
+	}
+
+	// a gives up its extra shards after a cutoff for b to claim
+	if released, err := a.Release(); err != nil || !reflect.DeepEqual(released, []int{2, 3}) {
+		t.Fatalf("released=%v error=%v", released, err)
+	}
+	if claimed, err := b.Renew(); err != nil || !reflect.DeepEqual(claimed, []int{2, 3}) {
+		t.Fatalf("claimed=%v error=%v", claimed, err)
+	}
+	if claimed, err := a.Renew(); err != nil || len(claimed) != 0 {
+		t.Fatalf("claimed=%v error=%v", claimed, err)
+	}
+	if owned := a.Owned(); !reflect.DeepEqual(owned, []int{0, 1}) {
+		t.Errorf("unexpected owned: %v", owned)
+	}
+	if !a.Owns(Xfer{}) || b.Owns(Xfer{}) {
+		t.Error("expected a to own shard 0")
+	}
+
+	// b takes over shards whose lease expired
+	if _, err := repo.db.Exec(`update pipeline_shards set lease_expires_at = ? where owner = ?;`, time.Now().Add(-1*time.Hour), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.db.Exec(`update pipeline_instances set heartbeat_at = ? where instance_id = ?;`, time.Now().Add(-1*time.Hour), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := b.Renew(); err != nil || !reflect.DeepEqual(claimed, []int{0, 1}) {
+		t.Fatalf("claimed=%v error=%v", claimed, err)
+	}
+}
+
+func TestShards__Replay(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	shards := NewShards(log.NewNopLogger(), &config.Sharding{Shards: 4, InstanceID: "a"}, repo.db)
+
+	transferID := base.ID()
+	writeShardedTransfer(t, repo, transferID, "moov")
+
+	tx, err := repo.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []OutboxMessage{
+		{Upload: &Xfer{Transfer: &client.Transfer{TransferID: transferID}, Organization: "moov"}},
+	}
+	if err := WriteOutbox(tx, msgs); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	shard := shards.ShardOf(Xfer{Organization: "moov"})
+	merger := &MockXferMerging{}
+	if n, err := shards.Replay(merger, []int{(shard + 1) % 4}); err != nil || n != 0 {
+		t.Errorf("n=%d error=%v", n, err)
+	}
+	if n, err := shards.Replay(merger, []int{shard}); err != nil || n != 1 {
+		t.Errorf("n=%d error=%v", n, err)
+	}
+	if merger.LatestXfer == nil || merger.LatestXfer.Transfer.TransferID != transferID {
+		t.Errorf("unexpected xfer: %#v", merger.LatestXfer)
+	}
+
+	// planned Transfers are replayed as their previous owner can't upload them
+	if _, err := repo.planMerge("storage/20200102-150405", []string{transferID}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := shards.Replay(merger, []int{shard}); err != nil || n != 1 {
+		t.Errorf("n=%d error=%v", n, err)
+	}
+
+	// written Transfers aren't replayed
+	if err := repo.markMergeWritten("storage/20200102-150405/uploaded/abc.ach", []string{transferID}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := shards.Replay(merger, []int{shard}); err != nil || n != 0 {
+		t.Errorf("n=%d error=%v", n, err)
+	}
+}
+
+func writeShardedTransfer(t *testing.T, repo *sqlRepo, transferID, orgID string) {
+	t.Helper()
+
+	writeOldTransfer(t, repo, transferID, time.Now())
+	if _, err := repo.db.Exec(`update transfers set organization = ? where transfer_id = ?;`, orgID, transferID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShards__fence(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	cfg := func(id string) *config.Sharding {
+		return &config.Sharding{Shards: 1, InstanceID: id}
+	}
+	a := NewShards(log.NewNopLogger(), cfg("a"), repo.db)
+	b := NewShards(log.NewNopLogger(), cfg("b"), repo.db)
+
+	if _, err := a.Renew(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Renew(); err != nil {
+		t.Fatal(err)
+	}
+
+	planned, fenced := base.ID(), base.ID()
+	writeShardedTransfer(t, repo, planned, "moov")
+	writeShardedTransfer(t, repo, fenced, "moov")
+
+	dir := "storage/20200102-150405"
+	if merged, err := repo.planMerge(dir, []string{planned}, b.fence(dir)); err != nil || merged[planned] != mergeFenced {
+		t.Fatalf("merged=%v error=%v", merged, err)
+	}
+	if merged, err := repo.planMerge(dir, []string{planned}, a.fence(dir)); err != nil || len(merged) != 0 {
+		t.Fatalf("merged=%v error=%v", merged, err)
+	}
+
+	// a's lease expires before the merged file is written, so it's not uploaded
+	if _, err := repo.db.Exec(`update pipeline_shards set lease_expires_at = ? where owner = ?;`, time.Now().Add(-1*time.Hour), "a"); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "uploaded", "abc.ach")
+	if err := repo.markMergeWritten(filename, []string{planned}, a.fence(dir)); err != errMergeFenced {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec, err := repo.getMerge(planned); err != nil || rec == nil || rec.State != mergePlanned {
+		t.Fatalf("merge=%#v error=%v", rec, err)
+	}
+	if merged, err := repo.planMerge(dir, []string{fenced}, a.fence(dir)); err != nil || merged[fenced] != mergeFenced {
+		t.Fatalf("merged=%v error=%v", merged, err)
+	}
+	if rec, err := repo.getMerge(fenced); err != nil || rec != nil {
+		t.Fatalf("merge=%#v error=%v", rec, err)
+	}
+}
+
+func TestShards__merging(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	shards := NewShards(log.NewNopLogger(), &config.Sharding{Shards: 1, InstanceID: "a"}, repo.db)
+	mock := &MockXferMerging{}
+	merger := &shardedMerging{XferMerging: mock, shards: shards}
+
+	xfer := Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, Organization: "moov"}
+	if err := merger.HandleXfer(xfer); err != nil || mock.LatestXfer != nil {
+		t.Fatalf("xfer=%#v error=%v", mock.LatestXfer, err)
+	}
+
+	if _, err := shards.Renew(); err != nil {
+		t.Fatal(err)
+	}
+	if err := merger.HandleXfer(xfer); err != nil || mock.LatestXfer == nil {
+		t.Fatalf("xfer=%#v error=%v", mock.LatestXfer, err)
+	}
+}
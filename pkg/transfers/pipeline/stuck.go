@@ -0,0 +1,239 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	stuckTransfers = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "stuck_transfers",
+		Help: "Gauge of transfers without progress through the pipeline",
+	}, []string{"state"})
+
+	stuckMicroDeposits = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "stuck_micro_deposits",
+		Help: "Gauge of PENDING micro-deposits without progress through the pipeline",
+	}, nil)
+)
+
+// StuckWork lists work which hasn't made progress through the pipeline since StuckBefore.
+type StuckWork struct {
+	StuckBefore time.Time `json:"stuckBefore"`
+
+	// Planned are Transfers picked up by a cutoff which were never written into a merged file
+	Planned []StuckTransfer `json:"planned"`
+
+	// Written are Transfers in a merged file which wasn't confirmed as uploaded
+	Written []StuckTransfer `json:"written"`
+
+	// Unmerged are PENDING Transfers which were never picked up by a cutoff
+	Unmerged []StuckTransfer `json:"unmerged"`
+
+	MicroDeposits []StuckMicroDeposit `json:"microDeposits"`
+}
+
+type StuckTransfer struct {
+	TransferID     string    `json:"transferID"`
+	CutoffDir      string    `json:"cutoffDir,omitempty"`
+	MergedFilename string    `json:"mergedFilename,omitempty"`
+	Since          time.Time `json:"since"`
+}
+
+type StuckMicroDeposit struct {
+	MicroDepositID string    `json:"microDepositID"`
+	Since          time.Time `json:"since"`
+}
+
+// Recovery finds stuck work in the pipeline and lets an operator resolve Transfers
+// whose merged file might have been uploaded.
+type Recovery struct {
+	cfg    *config.Recovery
+	logger log.Logger
+	repo   Repository
+
+	// baseDir is the mergable directory Transfers wait in until a cutoff
+	baseDir string
+}
+
+func NewRecovery(cfg *config.Config, repo Repository) *Recovery {
+	return &Recovery{
+		cfg:     cfg.Pipeline.Recovery,
+		logger:  cfg.Logger,
+		repo:    repo,
+		baseDir: mergableDir(cfg.Pipeline),
+	}
+}
+
+// Scan returns the work without progress within the configured duration and updates the
+// stuck_transfers and stuck_micro_deposits metrics.
+func (rec *Recovery) Scan() (*StuckWork, error) {
+	work := &StuckWork{
+		StuckBefore:   rec.cfg.StuckBefore(time.Now()),
+		Planned:       []StuckTransfer{},
+		Written:       []StuckTransfer{},
+		Unmerged:      []StuckTransfer{},
+		MicroDeposits: []StuckMicroDeposit{},
+	}
+
+	records, err := rec.repo.getStuckMerges(work.StuckBefore)
+	if err != nil {
+		return nil, fmt.Errorf("problem reading stuck merges: %v", err)
+	}
+	for i := range records {
+		xfer := StuckTransfer{
+			TransferID:     records[i].TransferID,
+			CutoffDir:      records[i].CutoffDir,
+			MergedFilename: records[i].MergedFilename,
+			Since:          records[i].UpdatedAt,
+		}
+		if records[i].State == mergePlanned {
+			work.Planned = append(work.Planned, xfer)
+		} else {
+			work.Written = append(work.Written, xfer)
+		}
+	}
+
+	unmerged, err := rec.repo.getUnmergedTransfers(work.StuckBefore)
+	if err != nil {
+		return nil, fmt.Errorf("problem reading unmerged transfers: %v", err)
+	}
+	for i := range unmerged {
+		// Transfers still in the mergable directory are picked up by the next cutoff
+		if _, err := os.Stat(filepath.Join(rec.baseDir, unmerged[i].TransferID+".ach")); err == nil {
+			continue
+		}
+		work.Unmerged = append(work.Unmerged, unmerged[i])
+	}
+
+	micro, err := rec.repo.getStuckMicroDeposits(work.StuckBefore, rec.cfg.Limit())
+	if err != nil {
+		return nil, fmt.Errorf("problem reading stuck micro-deposits: %v", err)
+	}
+	work.MicroDeposits = append(work.MicroDeposits, micro...)
+
+	stuckTransfers.With("state", mergePlanned).Set(float64(len(work.Planned)))
+	stuckTransfers.With("state", mergeWritten).Set(float64(len(work.Written)))
+	stuckTransfers.With("state", "unmerged").Set(float64(len(work.Unmerged)))
+	stuckMicroDeposits.Set(float64(len(work.MicroDeposits)))
+
+	return work, nil
+}
+
+var (
+	errMergeNotFound = errors.New("merged transfer not found")
+	errMergeNotStuck = errors.New("merged transfer is not written")
+)
+
+// Resolve settles a written Transfer after an operator checked with the ODFI. Transfers
+// which were uploaded are marked as processed, otherwise their file is moved back to be
+// merged in the next cutoff.
+func (rec *Recovery) Resolve(transferID string, uploaded bool) error {
+	record, err := rec.repo.getMerge(transferID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return errMergeNotFound
+	}
+	if record.State != mergeWritten {
+		return errMergeNotStuck
+	}
+
+	if uploaded {
+		if err := rec.repo.markTransferUploaded(transferID); err != nil {
+			return err
+		}
+		if err := rec.repo.MarkTransfersAsProcessed([]string{transferID}); err != nil {
+			return err
+		}
+		rec.logger.Logf("transferID=%s marked as uploaded in %s", transferID, record.MergedFilename)
+		return nil
+	}
+
+	if err := restoreTransfer(record.CutoffDir, rec.baseDir, transferID); err != nil {
+		return fmt.Errorf("problem restoring transferID=%s: %v", transferID, err)
+	}
+	if err := rec.repo.deleteMerge(transferID, mergeWritten); err != nil {
+		return err
+	}
+	rec.logger.Logf("transferID=%s restored from %s for the next cutoff", transferID, record.CutoffDir)
+	return nil
+}
+
+func (r *sqlRepo) getUnmergedTransfers(before time.Time) ([]StuckTransfer, error) {
+	defer database.MeasureQuery("pipeline", "getUnmergedTransfers")()
+
+	query := `select xf.transfer_id, xf.created_at from transfers as xf
+left join merged_transfers as m on xf.transfer_id = m.transfer_id
+where m.transfer_id is null and xf.status = ? and xf.created_at < ? and xf.deleted_at is null
+order by xf.created_at asc;`
+	var out []StuckTransfer
+	err := database.QueryRows(r.db, "getUnmergedTransfers", query, []interface{}{client.PENDING, before}, func(rows *sql.Rows) error {
+		var xfer StuckTransfer
+		if err := rows.Scan(&xfer.TransferID, &xfer.Since); err != nil {
+			return err
+		}
+		out = append(out, xfer)
+		return nil
+	})
+	return out, err
+}
+
+// getStuckMicroDeposits pages through PENDING micro-deposits created before the given time
+// with a keyset cursor on (created_at, micro_deposit_id), so each query reads at most
+// batchSize rows from the micro_deposits_status_created_at_idx index.
+func (r *sqlRepo) getStuckMicroDeposits(before time.Time, batchSize int) ([]StuckMicroDeposit, error) {
+	defer database.MeasureQuery("pipeline", "getStuckMicroDeposits")()
+
+	first := `select micro_deposit_id, created_at from micro_deposits
+where status = ? and created_at < ? and deleted_at is null
+order by created_at asc, micro_deposit_id asc limit ?;`
+	next := `select micro_deposit_id, created_at from micro_deposits
+where status = ? and created_at < ? and deleted_at is null
+and (created_at > ? or (created_at = ? and micro_deposit_id > ?))
+order by created_at asc, micro_deposit_id asc limit ?;`
+
+	var out []StuckMicroDeposit
+	for {
+		query, args := first, []interface{}{client.PENDING, before, batchSize}
+		if n := len(out); n > 0 {
+			last := out[n-1]
+			query = next
+			args = []interface{}{client.PENDING, before, last.Since, last.Since, last.MicroDepositID, batchSize}
+		}
+
+		var page int
+		err := database.QueryRows(r.db, "getStuckMicroDeposits", query, args, func(rows *sql.Rows) error {
+			var micro StuckMicroDeposit
+			if err := rows.Scan(&micro.MicroDepositID, &micro.Since); err != nil {
+				return err
+			}
+			out = append(out, micro)
+			page++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if page < batchSize {
+			return out, nil
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (rec *Recovery) RegisterRoutes(svc *admin.Server) {
+	svc.AddHandler("/pipeline/stuck", rec.getStuckWork())
+	svc.AddHandler("/pipeline/queues", rec.getWorkQueues())
+	svc.AddHandler("/pipeline/merged-transfers/{transferId}", rec.resolveMergedTransfer())
+}
+
+func (rec *Recovery) getStuckWork() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodGet {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		work, err := rec.Scan()
+		if err != nil {
+			rec.logger.LogErrorf("ERROR scanning for stuck work: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(work)
+	}
+}
+
+func (rec *Recovery) getWorkQueues() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodGet {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		queues, err := rec.Queues()
+		if err != nil {
+			rec.logger.LogErrorf("ERROR reading work queues: %v", err)
+			route.Problem(w, route.Internal.Wrap(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(queues)
+	}
+}
+
+type resolveMergedTransfer struct {
+	Uploaded *bool `json:"uploaded"`
+}
+
+func (rec *Recovery) resolveMergedTransfer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodPut {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		var req resolveMergedTransfer
+		if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+			route.Problem(w, err)
+			return
+		}
+		if req.Uploaded == nil {
+			verr := &route.ValidationError{}
+			verr.Add("uploaded", "missing uploaded")
+			route.Problem(w, verr.Err())
+			return
+		}
+
+		transferID := route.ReadPathID("transferId", r)
+		switch err := rec.Resolve(transferID, *req.Uploaded); err {
+		case nil:
+			w.WriteHeader(http.StatusOK)
+		case errMergeNotFound:
+			route.Problem(w, route.NotFound.New("transferID=%s: %v", transferID, err))
+		case errMergeNotStuck:
+			route.Problem(w, route.Conflict.New("transferID=%s: %v", transferID, err))
+		default:
+			rec.logger.LogErrorf("ERROR resolving transferID=%s: %v", transferID, err)
+			route.Problem(w, route.Internal.Wrap(err))
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/internal"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+)
+
+func writeOldTransfer(t *testing.T, repo *sqlRepo, transferID string, created time.Time) {
+	t.Helper()
+
+	query := `insert into transfers (transfer_id, status, created_at) values (?, ?, ?);`
+	if _, err := repo.db.Exec(query, transferID, client.PENDING, created); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setupRecovery(t *testing.T) (*Recovery, *sqlRepo) {
+	t.Helper()
+
+	cfg := config.Empty()
+	cfg.Pipeline.Merging = &config.Merging{
+		Directory: internal.TestDir(t),
+	}
+	if err := os.MkdirAll(mergableDir(cfg.Pipeline), 0777); err != nil {
+		t.Fatal(err)
+	}
+	repo := setupSQLiteDB(t)
+	return NewRecovery(cfg, repo), repo
+}
+
+func TestRecovery__Scan(t *testing.T) {
+	rec, repo := setupRecovery(t)
+	old := time.Now().Add(-48 * time.Hour)
+
+	// never merged
+	unmerged := base.ID()
+	writeOldTransfer(t, repo, unmerged, old)
+
+	// waiting in the mergable directory for the next cutoff
+	waiting := base.ID()
+	writeOldTransfer(t, repo, waiting, old)
+	if err := ioutil.WriteFile(filepath.Join(rec.baseDir, waiting+".ach"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// created recently
+	writeOldTransfer(t, repo, base.ID(), time.Now())
+
+	// written, but not confirmed as uploaded
+	written := base.ID()
+	writeOldTransfer(t, repo, written, old)
+	if _, err := repo.planMerge("storage/20200102-150405", []string{written}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.markMergeWritten("storage/20200102-150405/uploaded/abc.ach", []string{written}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.db.Exec(`update merged_transfers set updated_at = ? where transfer_id = ?;`, old, written); err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := rec.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(work.Unmerged) != 1 || work.Unmerged[0].TransferID != unmerged {
+		t.Errorf("unexpected unmerged: %#v", work.Unmerged)
+	}
+	if len(work.Written) != 1 || work.Written[0].TransferID != written {
+		t.Errorf("unexpected written: %#v", work.Written)
+	}
+	if len(work.Planned) != 0 || len(work.MicroDeposits) != 0 {
+		t.Errorf("unexpected stuck work: %#v", work)
+	}
+}
+
+func TestRecovery__ScanMicroDeposits(t *testing.T) {
+	rec, repo := setupRecovery(t)
+	rec.cfg = &config.Recovery{BatchSize: 2}
+
+	// micro-deposits sharing a created_at are paged by their ID
+	old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	created := []time.Time{old, old, old, old.Add(time.Minute), old.Add(2 * time.Minute), time.Now()}
+	for i := range created {
+		query := `insert into micro_deposits (micro_deposit_id, status, created_at) values (?, ?, ?);`
+		if _, err := repo.db.Exec(query, base.ID(), client.PENDING, created[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	work, err := rec.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(work.MicroDeposits) != 5 {
+		t.Fatalf("unexpected %d micro-deposits: %#v", len(work.MicroDeposits), work.MicroDeposits)
+	}
+	seen := make(map[string]bool)
+	for i := range work.MicroDeposits {
+		if seen[work.MicroDeposits[i].MicroDepositID] {
+			t.Errorf("duplicate micro-deposit: %#v", work.MicroDeposits[i])
+		}
+		seen[work.MicroDeposits[i].MicroDepositID] = true
+	}
+}
+
+func TestRecovery__Resolve(t *testing.T) {
+	rec, repo := setupRecovery(t)
+	cutoffDir := filepath.Join(filepath.Dir(rec.baseDir), "20200102-150405")
+	if err := os.MkdirAll(cutoffDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(transferID string) {
+		writeOldTransfer(t, repo, transferID, time.Now())
+		if err := ioutil.WriteFile(filepath.Join(cutoffDir, transferID+".ach"), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.planMerge(cutoffDir, []string{transferID}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.markMergeWritten(filepath.Join(cutoffDir, "uploaded", "abc.ach"), []string{transferID}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uploaded, requeued := base.ID(), base.ID()
+	write(uploaded)
+	write(requeued)
+
+	if err := rec.Resolve(uploaded, true); err != nil {
+		t.Fatal(err)
+	}
+	if xfer := getPartialTransferModel(t, repo, uploaded); xfer.Status != client.PROCESSED {
+		t.Errorf("unexpected status: %v", xfer.Status)
+	}
+	if err := rec.Resolve(uploaded, true); err != errMergeNotStuck {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := rec.Resolve(requeued, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rec.baseDir, requeued+".ach")); err != nil {
+		t.Errorf("expected file to be restored: %v", err)
+	}
+	if err := rec.Resolve(requeued, false); err != errMergeNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRecovery__routes(t *testing.T) {
+	rec, repo := setupRecovery(t)
+	svc, c := testclient.Admin(t)
+	rec.RegisterRoutes(svc)
+
+	transferID := base.ID()
+	writeOldTransfer(t, repo, transferID, time.Now().Add(-48*time.Hour))
+
+	work, resp, err := c.TransfersApi.GetStuckWork(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(work.Unmerged) != 1 || work.Unmerged[0].TransferID != transferID {
+		t.Errorf("unexpected work: %#v", work)
+	}
+
+	queues, resp, err := c.TransfersApi.GetWorkQueues(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if queues.Originations.Count != 0 || queues.Checked.IsZero() {
+		t.Errorf("unexpected queues: %#v", queues)
+	}
+
+	resp, err = c.TransfersApi.ResolveMergedTransfer(context.TODO(), transferID, admin.ResolveMergedTransfer{Uploaded: true})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
+
+func TestRecovery__Queues(t *testing.T) {
+	rec, repo := setupRecovery(t)
+	old := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+
+	queues, err := rec.Queues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queues.Merges.Count != 0 || queues.Uploads.Count != 0 || queues.Uploads.Oldest != nil {
+		t.Errorf("unexpected queues: %#v", queues)
+	}
+
+	// waiting in the mergable directory for the next cutoff
+	for i := 0; i < 2; i++ {
+		if err := ioutil.WriteFile(filepath.Join(rec.baseDir, base.ID()+".ach"), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// two transfers written into one merged file
+	written := []string{base.ID(), base.ID()}
+	if _, err := repo.planMerge("storage/20200102-150405", written, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.markMergeWritten("storage/20200102-150405/uploaded/abc.ach", written, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.db.Exec(`update merged_transfers set updated_at = ?;`, old); err != nil {
+		t.Fatal(err)
+	}
+
+	query := `insert into pipeline_outbox (message_id, transfer_id, kind, body, created_at) values (?, ?, ?, ?, ?);`
+	if _, err := repo.db.Exec(query, base.ID(), base.ID(), outboxCancel, "{}", old); err != nil {
+		t.Fatal(err)
+	}
+
+	queues, err = rec.Queues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queues.Merges.Count != 2 || queues.Merges.Oldest == nil {
+		t.Errorf("unexpected merges: %#v", queues.Merges)
+	}
+	if queues.Uploads.Count != 1 || queues.Uploads.Oldest == nil || !queues.Uploads.Oldest.Equal(old) {
+		t.Errorf("unexpected uploads: %#v", queues.Uploads)
+	}
+	if queues.Uploads.OldestAge < 3600 {
+		t.Errorf("unexpected oldest age: %d", queues.Uploads.OldestAge)
+	}
+	if queues.Outbox.Count != 1 || queues.Originations.Count != 0 || queues.Returns.Count != 0 {
+		t.Errorf("unexpected queues: %#v", queues)
+	}
+}
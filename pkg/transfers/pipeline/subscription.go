@@ -20,17 +20,17 @@ func NewSubscription(cfg *config.Config) (*pubsub.Subscription, error) {
 		return nil, errors.New("nil Config")
 	}
 	if cfg.Pipeline.Stream != nil {
-		return createStreamSubscription(cfg.Pipeline.Stream)
+		return createStreamSubscription(cfg.Pipeline.Stream, cfg.Pipeline.Sharding)
 	}
 	return nil, errors.New("unknown Pipeline config")
 }
 
-func createStreamSubscription(cfg *config.StreamPipeline) (*pubsub.Subscription, error) {
+func createStreamSubscription(cfg *config.StreamPipeline, sharding *config.Sharding) (*pubsub.Subscription, error) {
 	if cfg.InMem != nil {
 		return createInmemSubscription(cfg.InMem.URL)
 	}
 	if cfg.Kafka != nil {
-		return createKafkaSubscription(cfg.Kafka)
+		return createKafkaSubscription(cfg.Kafka, sharding)
 	}
 
 	return nil, fmt.Errorf("unknown %#v", cfg)
@@ -40,9 +40,15 @@ func createInmemSubscription(url string) (*pubsub.Subscription, error) {
 	return stream.Subscription(context.TODO(), url)
 }
 
-func createKafkaSubscription(cfg *config.KafkaPipeline) (*pubsub.Subscription, error) {
+func createKafkaSubscription(cfg *config.KafkaPipeline, sharding *config.Sharding) (*pubsub.Subscription, error) {
 	// kafkapubsub.MinimalConfig returns a minimal sarama.Config required for kafkapubsub
 	config := kafkapubsub.MinimalConfig()
 
-	return stream.KafkaSubscription(cfg.Brokers, config, cfg.Group, []string{cfg.Topic}, nil)
+	// Sharded instances each read every message and keep the Transfers of their shards
+	group := cfg.Group
+	if sharding != nil {
+		group = fmt.Sprintf("%s-%s", cfg.Group, sharding.Instance())
+	}
+
+	return stream.KafkaSubscription(cfg.Brokers, config, group, []string{cfg.Topic}, nil)
 }
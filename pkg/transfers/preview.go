@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/x/route"
+)
+
+// PreviewTransfer responds with how the entry of a draft Transfer will appear on the receiver's
+// statement. Account numbers aren't shown on statements, so they're never decrypted.
+func PreviewTransfer(cfg *config.Config, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.CreateTransfer
+		err := route.DecodeJSON(r, &req, route.DisallowUnknownFields)
+		if err != nil {
+			responder.Problem(fmt.Errorf("previewing transfer: problem reading request body: %w", err))
+			return
+		}
+		if err := validateTransferRequest(req); err != nil {
+			responder.Problem(fmt.Errorf("previewing transfer: invalid transfer request: %w", err))
+			return
+		}
+
+		var source achx.Source
+		source.Customer, source.Account, err = previewParty(customersClient, responder.OrganizationID, req.Source.CustomerID, req.Source.AccountID)
+		if err != nil {
+			responder.Problem(route.Unavailable.New("previewing transfer: error reading source: %v", err))
+			return
+		}
+		var destination achx.Destination
+		destination.Customer, destination.Account, err = previewParty(customersClient, responder.OrganizationID, req.Destination.CustomerID, req.Destination.AccountID)
+		if err != nil {
+			responder.Problem(route.Unavailable.New("previewing transfer: error reading destination: %v", err))
+			return
+		}
+		if source.Account.RoutingNumber != cfg.ODFI.RoutingNumber && destination.Account.RoutingNumber != cfg.ODFI.RoutingNumber {
+			responder.Problem(route.InvalidRequest.New("previewing transfer: neither account is at routing number %s", cfg.ODFI.RoutingNumber))
+			return
+		}
+
+		xfer := &client.Transfer{
+			Amount:                   req.Amount,
+			Source:                   req.Source,
+			Destination:              req.Destination,
+			Description:              req.Description,
+			SameDay:                  req.SameDay,
+			StandardEntryClassCode:   achx.StandardEntryClassCode(req.StandardEntryClassCode),
+			Check:                    req.Check,
+			CompanyEntryDescription:  req.CompanyEntryDescription,
+			CompanyDiscretionaryData: req.CompanyDiscretionaryData,
+		}
+		opts := achx.Options{
+			ODFIRoutingNumber:  cfg.ODFI.RoutingNumber,
+			Gateway:            cfg.ODFI.Gateway,
+			FileConfig:         cfg.ODFI.FileConfig,
+			CutoffTimezone:     cfg.ODFI.Cutoffs.Location(),
+			EffectiveEntryDate: fundflow.EffectiveEntryDate(cfg.ODFI, time.Now(), req.SameDay),
+		}
+		preview := achx.PreviewStatement(opts, xfer, source, destination)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(preview)
+		})
+	}
+}
+
+// previewParty reads the Customer and Account whose names and routing number are written
+// into a Transfer's batch and entry.
+func previewParty(client customers.Client, organization, customerID, accountID string) (moovcustomers.Customer, moovcustomers.Account, error) {
+	cust, err := client.Lookup(organization, customerID, "requestID")
+	if err != nil {
+		return moovcustomers.Customer{}, moovcustomers.Account{}, err
+	}
+	if cust == nil || cust.CustomerID == "" {
+		return moovcustomers.Customer{}, moovcustomers.Account{}, fmt.Errorf("customerID=%s is not found", customerID)
+	}
+	acct, err := client.FindAccount(organization, customerID, accountID)
+	if acct == nil || acct.AccountID == "" || err != nil {
+		return *cust, moovcustomers.Account{}, fmt.Errorf("accountID=%s not found for customerID=%s error=%v", accountID, customerID, err)
+	}
+	return *cust, *acct, nil
+}
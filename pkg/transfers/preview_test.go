@@ -0,0 +1,85 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRouter__previewTransfer(t *testing.T) {
+	customersClient := mockCustomersClient()
+	customersClient.Accounts[destinationAccountID].RoutingNumber = "123456780"
+
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description:             "test transfer",
+		CompanyEntryDescription: "PAYROLL",
+	}
+	preview, resp, err := c.TransfersApi.PreviewTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if preview.CompanyName != "John Doe" || preview.CompanyEntryDescription != "PAYROLL" || preview.IndividualName != "Jane Doe" {
+		t.Errorf("unexpected preview: %#v", preview)
+	}
+	if preview.StandardEntryClassCode != "PPD" || preview.EffectiveEntryDate == "" {
+		t.Errorf("unexpected preview: %#v", preview)
+	}
+
+	// invalid drafts are rejected
+	opts.Description = ""
+	_, resp, err = c.TransfersApi.PreviewTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	// unknown accounts
+	opts.Description = "test transfer"
+	opts.Destination.AccountID = base.ID()
+	_, resp, err = c.TransfersApi.PreviewTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+}
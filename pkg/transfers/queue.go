@@ -0,0 +1,222 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/jobs"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queuedOriginations = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "transfer_queue_originations",
+		Help: "Counter of queued Transfers by whether they were originated, retried or failed",
+	}, []string{"result"})
+)
+
+const (
+	queueBatchSize = 100
+
+	// queueClaimTTL is how long a worker has to originate Transfers it claimed
+	queueClaimTTL = 5 * time.Minute
+
+	// maxQueueAttempts bounds how often a Transfer with retriable errors is originated
+	maxQueueAttempts = 5
+)
+
+type queuedTransfer struct {
+	transferID string
+	orgID      string
+	attempts   int
+}
+
+// Queue originates Transfers which were accepted with 202 Accepted and retries of
+// returned Transfers once their delay has passed.
+//
+// Errors looking up accounts or the organization's config are retried until maxQueueAttempts,
+// other errors mark the Transfer as failed. A Transfer's messages are saved in the same
+// transaction it's removed from the queue, so its files are only published once.
+type Queue struct {
+	cfg    *config.Config
+	logger log.Logger
+
+	repo             Repository
+	orgRepo          organization.Repository
+	customersClient  customers.Client
+	accountDecryptor accounts.Decryptor
+	fundStrategy     fundflow.Strategy
+
+	job *jobs.Job
+}
+
+// NewQueue returns nil unless Transfers are configured to be originated asynchronously
+// or returned Transfers are reinitiated.
+func NewQueue(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+) *Queue {
+	if cfg.Transfers.Async == nil && cfg.Transfers.Retries == nil {
+		return nil
+	}
+	return &Queue{
+		cfg:              cfg,
+		logger:           cfg.Logger.Set("service", log.String("transfer-queue")),
+		repo:             repo,
+		orgRepo:          orgRepo,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+		fundStrategy:     fundStrategy,
+	}
+}
+
+// UseJobs records the runs of the Queue as the "transfers.queue" job of reg.
+func (q *Queue) UseJobs(reg *jobs.Registry) {
+	if q == nil {
+		return
+	}
+	q.job = reg.Register("transfers.queue")
+}
+
+// Start originates queued Transfers until ctx is canceled.
+func (q *Queue) Start(ctx context.Context) {
+	if q == nil {
+		return
+	}
+	interval := q.cfg.Transfers.Async.PollInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	q.job.Scheduled(time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			q.drain(ctx)
+			q.job.Scheduled(time.Now().Add(interval))
+
+		case <-q.job.Triggered():
+			q.drain(ctx)
+
+		case <-ctx.Done():
+			q.logger.Log("transfer queue shutdown")
+			return
+		}
+	}
+}
+
+// drain processes the queue while full batches are claimed so bursts drain quickly.
+func (q *Queue) drain(ctx context.Context) {
+	for {
+		n, err := q.Process()
+		if err != nil {
+			q.logger.LogErrorf("ERROR processing transfer queue: %v", err)
+		}
+		if err != nil || n < queueBatchSize || ctx.Err() != nil {
+			q.job.Done(err)
+			return
+		}
+	}
+}
+
+// Process claims queued Transfers and originates them with the configured number of
+// workers. It returns how many were claimed.
+func (q *Queue) Process() (int, error) {
+	items, err := q.repo.claimQueuedTransfers(queueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	work := make(chan queuedTransfer)
+	var wg sync.WaitGroup
+	for i := 0; i < q.cfg.Transfers.Async.Concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				q.process(item)
+			}
+		}()
+	}
+	for i := range items {
+		work <- items[i]
+	}
+	close(work)
+	wg.Wait()
+
+	return len(items), nil
+}
+
+func (q *Queue) process(item queuedTransfer) {
+	logger := q.logger.Set("transferID", log.String(item.transferID))
+
+	transfer, err := q.repo.GetUserTransfer(context.Background(), item.transferID, item.orgID)
+	if err != nil && err != sql.ErrNoRows {
+		logger.LogErrorf("ERROR reading transfer: %v", err)
+		q.retry(logger, item)
+		return
+	}
+	if transfer == nil {
+		// The Transfer was deleted, so only drop it from the queue
+		if err := q.repo.failQueuedTransfer(item.transferID); err != nil {
+			logger.LogErrorf("ERROR removing deleted transfer from queue: %v", err)
+		}
+		return
+	}
+
+	orig, err := originateTransfer(q.cfg, q.repo, q.orgRepo, q.customersClient, q.accountDecryptor, q.fundStrategy, item.orgID, transfer)
+	if err != nil {
+		logger.LogErrorf("ERROR originating transfer: %v", err)
+		if route.ErrorCodeOf(err).Retriable {
+			q.retry(logger, item)
+		} else {
+			q.fail(logger, item)
+		}
+		return
+	}
+	if err := q.repo.completeQueuedTransfer(transfer, orig); err != nil {
+		logger.LogErrorf("ERROR writing transfer: %v", err)
+		q.retry(logger, item)
+		return
+	}
+	queuedOriginations.With("result", "originated").Add(1)
+	logger.Log("originated queued transfer")
+}
+
+func (q *Queue) retry(logger log.Logger, item queuedTransfer) {
+	if item.attempts >= maxQueueAttempts {
+		q.fail(logger, item)
+		return
+	}
+	if err := q.repo.retryQueuedTransfer(item.transferID); err != nil {
+		logger.LogErrorf("ERROR releasing transfer for retry: %v", err)
+		return
+	}
+	queuedOriginations.With("result", "retried").Add(1)
+}
+
+func (q *Queue) fail(logger log.Logger, item queuedTransfer) {
+	if err := q.repo.failQueuedTransfer(item.transferID); err != nil {
+		logger.LogErrorf("ERROR failing transfer: %v", err)
+		return
+	}
+	queuedOriginations.With("result", "failed").Add(1)
+}
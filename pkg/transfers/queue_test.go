@@ -0,0 +1,287 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+
+	"github.com/gorilla/mux"
+)
+
+func asyncConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.Transfers.Async = &config.TransfersAsync{}
+	return cfg
+}
+
+func setupQueue(t *testing.T, repo Repository, customersClient customers.Client) *Queue {
+	t.Helper()
+
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{ach.NewFile()}}
+	queue := NewQueue(asyncConfig(), repo, orgRepo, customersClient, mockDecryptor, strategy)
+	if queue == nil {
+		t.Fatal("nil Queue")
+	}
+	return queue
+}
+
+func enqueueTransfer(t *testing.T, orgID string, repo Repository) *client.Transfer {
+	t.Helper()
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+	if err := repo.enqueueUserTransfer(orgID, xfer); err != nil {
+		t.Fatal(err)
+	}
+	return xfer
+}
+
+func outboxMessages(t *testing.T, repo Repository, transferID string) int {
+	t.Helper()
+
+	switch r := repo.(type) {
+	case *sqlRepo:
+		var n int
+		if err := r.db.QueryRow(`select count(*) from pipeline_outbox where transfer_id = ?;`, transferID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	case *memoryRepo:
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return len(r.outbox)
+	}
+	t.Fatalf("unexpected repository: %T", repo)
+	return 0
+}
+
+func TestQueue__nil(t *testing.T) {
+	queue := NewQueue(config.Empty(), repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	if queue != nil {
+		t.Fatalf("unexpected Queue: %#v", queue)
+	}
+	queue.Start(context.Background())
+}
+
+func TestQueue__Process(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		queue := setupQueue(t, repo, mockCustomersClient())
+
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+		if n := outboxMessages(t, repo, xfer.TransferID); n != 0 {
+			t.Fatalf("unexpected %d messages", n)
+		}
+
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		found, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.PENDING {
+			t.Errorf("unexpected status: %v", found.Status)
+		}
+		if n := outboxMessages(t, repo, xfer.TransferID); n != 1 {
+			t.Errorf("unexpected %d messages", n)
+		}
+
+		// nothing is left in the queue
+		if n, err := queue.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestQueue__ProcessSettlementDate(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		bh := ach.NewBatchHeader()
+		bh.EffectiveEntryDate = "200817"
+		file := ach.NewFile()
+		file.AddBatch(ach.NewBatchPPD(bh))
+
+		cfg := asyncConfig()
+		cfg.Transfers.Availability = &config.TransferAvailability{DebitHoldDays: 3, CreditHoldDays: 1}
+
+		strategy := &fundflow.MockStrategy{Files: []*ach.File{file}}
+		queue := NewQueue(cfg, repo, orgRepo, mockCustomersClient(), mockDecryptor, strategy)
+
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		found, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.ExpectedSettlementDate != "2020-08-17" || found.ActualSettlementDate != "" {
+			t.Errorf("unexpected settlement dates: %#v", found)
+		}
+		// the destination isn't at our ODFI, so it's credited
+		if found.AvailableOn != "2020-08-18" {
+			t.Errorf("unexpected availableOn: %q", found.AvailableOn)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestQueue__ProcessFailed(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		customersClient := mockCustomersClient()
+		customersClient.Accounts[destinationAccountID].Status = moovcustomers.ACCOUNTSTATUS_NONE
+
+		queue := setupQueue(t, repo, customersClient)
+
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		found, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.FAILED {
+			t.Errorf("unexpected status: %v", found.Status)
+		}
+		if n := outboxMessages(t, repo, xfer.TransferID); n != 0 {
+			t.Errorf("unexpected %d messages", n)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestQueue__ProcessRetry(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		customersClient := mockCustomersClient()
+		customersClient.Err = errors.New("bad error")
+
+		queue := setupQueue(t, repo, customersClient)
+
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+
+		for i := 0; i < maxQueueAttempts; i++ {
+			if n, err := queue.Process(); err != nil || n != 1 {
+				t.Fatalf("attempt %d: n=%d error=%v", i+1, n, err)
+			}
+		}
+		found, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.FAILED {
+			t.Errorf("unexpected status: %v", found.Status)
+		}
+		if n, err := queue.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestQueue__deleted(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		queue := setupQueue(t, repo, mockCustomersClient())
+
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+		if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := queue.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRouter__createUserTransferAsync(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	r := mux.NewRouter()
+	router := NewRouter(asyncConfig(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	if xfer.TransferID == "" || xfer.Status != client.PENDING {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+
+	// the Transfer is saved and queued
+	if items, err := repo.claimQueuedTransfers(10); err != nil || len(items) != 1 {
+		t.Errorf("items=%#v error=%v", items, err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// extendedReturnCodes are unauthorized consumer debits which may be returned for
+// 60 days after settlement instead of two banking days.
+var extendedReturnCodes = map[string]bool{
+	"R05": true, // Unauthorized Debit to Consumer Account Using Corporate SEC Code
+	"R07": true, // Authorization Revoked by Customer
+	"R10": true, // Customer Advises Not Authorized
+	"R11": true, // Customer Advises Entry Not in Accordance with the Terms of the Authorization
+	"R29": true, // Corporate Customer Advises Not Authorized
+	"R51": true, // Item is Ineligible, Notice Not Provided, Signature Not Genuine, or Item Altered
+}
+
+// IsExtendedReturnCode returns true for codes which can be used up to 60 days after
+// an entry settles.
+func IsExtendedReturnCode(code string) bool {
+	return extendedReturnCodes[strings.ToUpper(code)]
+}
+
+// ReturnDeadline is when a return of an entry which settled on settlement needs to be
+// uploaded, which is the last cutoff window of the second banking day after settlement.
+func ReturnDeadline(cutoffs config.Cutoffs, settlement time.Time) time.Time {
+	loc := cutoffs.Location()
+	if loc == nil {
+		loc = time.UTC
+	}
+	when := base.NewTime(settlement.In(loc))
+	return lastCutoff(cutoffs, when.AddBankingDay(2).Time, loc)
+}
+
+// ExtendedReturnDeadline is the deadline for extended return codes, which is the last
+// cutoff window of the second banking day after the 60th day following settlement.
+func ExtendedReturnDeadline(cutoffs config.Cutoffs, settlement time.Time) time.Time {
+	return ReturnDeadline(cutoffs, settlement.AddDate(0, 0, 60))
+}
+
+// NextCutoff returns the first cutoff window after when on a banking day.
+func NextCutoff(cutoffs config.Cutoffs, when time.Time) time.Time {
+	loc := cutoffs.Location()
+	if loc == nil {
+		loc = time.UTC
+	}
+	day := base.NewTime(when.In(loc))
+	if !day.IsBankingDay() {
+		day = day.AddBankingDay(1)
+	}
+	for _, window := range sortedWindows(cutoffs) {
+		if cutoff := atWindow(day.Time, window, loc); cutoff.After(when) {
+			return cutoff
+		}
+	}
+	next := day.AddBankingDay(1)
+	if windows := sortedWindows(cutoffs); len(windows) > 0 {
+		return atWindow(next.Time, windows[0], loc)
+	}
+	return endOfDay(next.Time, loc)
+}
+
+// lastCutoff returns the final cutoff window on day, or the end of day without windows.
+func lastCutoff(cutoffs config.Cutoffs, day time.Time, loc *time.Location) time.Time {
+	windows := sortedWindows(cutoffs)
+	if len(windows) == 0 {
+		return endOfDay(day, loc)
+	}
+	return atWindow(day, windows[len(windows)-1], loc)
+}
+
+func sortedWindows(cutoffs config.Cutoffs) []string {
+	windows := make([]string, len(cutoffs.Windows))
+	copy(windows, cutoffs.Windows)
+	sort.Strings(windows)
+	return windows
+}
+
+func atWindow(day time.Time, window string, loc *time.Location) time.Time {
+	hm, err := time.Parse("15:04", window)
+	if err != nil {
+		return endOfDay(day, loc)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hm.Hour(), hm.Minute(), 0, 0, loc)
+}
+
+func endOfDay(day time.Time, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, loc)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func newYork(t *testing.T) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestReturnDeadline(t *testing.T) {
+	loc := newYork(t)
+	cutoffs := config.Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"16:20", "10:30"},
+	}
+
+	// Thursday, so two banking days later is Monday
+	settlement := time.Date(2020, time.June, 18, 9, 0, 0, 0, loc)
+	deadline := ReturnDeadline(cutoffs, settlement)
+	if expected := time.Date(2020, time.June, 22, 16, 20, 0, 0, loc); !deadline.Equal(expected) {
+		t.Errorf("deadline=%v expected %v", deadline, expected)
+	}
+
+	extended := ExtendedReturnDeadline(cutoffs, settlement)
+	if expected := time.Date(2020, time.August, 19, 16, 20, 0, 0, loc); !extended.Equal(expected) {
+		t.Errorf("unexpected extended deadline: %v", extended)
+	}
+
+	// without windows the deadline is the end of the day
+	deadline = ReturnDeadline(config.Cutoffs{Timezone: "America/New_York"}, settlement)
+	if expected := time.Date(2020, time.June, 22, 23, 59, 59, 0, loc); !deadline.Equal(expected) {
+		t.Errorf("deadline=%v expected %v", deadline, expected)
+	}
+}
+
+func TestNextCutoff(t *testing.T) {
+	loc := newYork(t)
+	cutoffs := config.Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"10:30", "16:20"},
+	}
+
+	next := NextCutoff(cutoffs, time.Date(2020, time.June, 18, 12, 0, 0, 0, loc))
+	if expected := time.Date(2020, time.June, 18, 16, 20, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected %v", next, expected)
+	}
+
+	// after the last window on a Friday
+	next = NextCutoff(cutoffs, time.Date(2020, time.June, 19, 17, 0, 0, 0, loc))
+	if expected := time.Date(2020, time.June, 22, 10, 30, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected %v", next, expected)
+	}
+
+	// on a Saturday
+	next = NextCutoff(cutoffs, time.Date(2020, time.June, 20, 9, 0, 0, 0, loc))
+	if expected := time.Date(2020, time.June, 22, 10, 30, 0, 0, loc); !next.Equal(expected) {
+		t.Errorf("next=%v expected %v", next, expected)
+	}
+}
+
+func TestIsExtendedReturnCode(t *testing.T) {
+	if !IsExtendedReturnCode("r10") || !IsExtendedReturnCode("R05") {
+		t.Error("expected extended return codes")
+	}
+	if IsExtendedReturnCode("R01") {
+		t.Error("R01 isn't extended")
+	}
+}
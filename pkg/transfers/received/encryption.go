@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/moov-io/customers/pkg/secrets"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// fieldCipher encrypts the individual name and entry detail of received transfers, which
+// hold the receiver's name and account number. Individual names are also hashed so they
+// can be searched without decrypting every row.
+type fieldCipher struct {
+	keeper  *secrets.StringKeeper
+	hashKey []byte
+}
+
+func newFieldCipher(cfg *config.FieldEncryption) (*fieldCipher, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	keeper, err := secrets.OpenLocal(cfg.Symmetric.KeyURI)
+	if err != nil {
+		return nil, err
+	}
+	return &fieldCipher{
+		keeper:  secrets.NewStringKeeper(keeper, 5*time.Second),
+		hashKey: []byte(cfg.HashKey),
+	}, nil
+}
+
+func (c *fieldCipher) encrypt(value string) (string, error) {
+	return c.keeper.EncryptString(value)
+}
+
+func (c *fieldCipher) decrypt(value string) (string, error) {
+	return c.keeper.DecryptString(value)
+}
+
+// hash returns the hex encoded HMAC-SHA256 of value, ignoring case and surrounding spaces.
+func (c *fieldCipher) hash(value string) string {
+	mac := hmac.New(sha256.New, c.hashKey)
+	mac.Write([]byte(normalizeName(value)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalizeName(value string) string {
+	return strings.ToUpper(strings.TrimSpace(value))
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockRepository struct {
+	Transfers []*client.ReceivedTransfer
+	Entry     *Entry
+	Err       error
+}
+
+func (r *MockRepository) SaveReceivedTransfer(orgID string, xfer *client.ReceivedTransfer, entry Entry) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Transfers = append(r.Transfers, xfer)
+	r.Entry = &entry
+	return nil
+}
+
+func (r *MockRepository) getReceivedTransfers(orgID string, params filterParams) ([]*client.ReceivedTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Transfers, nil
+}
+
+func (r *MockRepository) getReceivedTransfer(orgID string, receivedTransferID string) (*client.ReceivedTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].ReceivedTransferID == receivedTransferID {
+			return r.Transfers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) getReceivedEntry(receivedTransferID string) (*Entry, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Entry, nil
+}
+
+func (r *MockRepository) saveReturn(receivedTransferID string, returnCode string, returnTraceNumber string, deadline time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].ReceivedTransferID == receivedTransferID {
+			r.Transfers[i].Status = client.RETURNING
+			r.Transfers[i].ReturnCode = &client.ReturnCode{Code: returnCode}
+			r.Transfers[i].ReturnTraceNumber = returnTraceNumber
+			r.Transfers[i].ReturnDeadline = deadline
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) getPendingReturns() ([]*client.ReceivedTransfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []*client.ReceivedTransfer
+	for i := range r.Transfers {
+		if r.Transfers[i].Status == client.RETURNING {
+			out = append(out, r.Transfers[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) markReturnsUploaded(receivedTransferIDs []string, when time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Transfers {
+		for j := range receivedTransferIDs {
+			if r.Transfers[i].ReceivedTransferID == receivedTransferIDs[j] && r.Transfers[i].Status == client.RETURNING {
+				r.Transfers[i].Status = client.RETURNED
+				r.Transfers[i].ReturnedAt = &when
+			}
+		}
+	}
+	return nil
+}
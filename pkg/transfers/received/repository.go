@@ -0,0 +1,375 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+type Repository interface {
+	// SaveReceivedTransfer records an entry received for orgID once it's posted or returned.
+	SaveReceivedTransfer(orgID string, xfer *client.ReceivedTransfer, entry Entry) error
+
+	getReceivedTransfers(orgID string, params filterParams) ([]*client.ReceivedTransfer, error)
+	getReceivedTransfer(orgID string, receivedTransferID string) (*client.ReceivedTransfer, error)
+	getReceivedEntry(receivedTransferID string) (*Entry, error)
+
+	saveReturn(receivedTransferID string, returnCode string, returnTraceNumber string, deadline time.Time) error
+	getPendingReturns() ([]*client.ReceivedTransfer, error)
+	markReturnsUploaded(receivedTransferIDs []string, when time.Time) error
+}
+
+// Entry holds the records a ReceivedTransfer was read from, which are needed to return it.
+type Entry struct {
+	Header *ach.BatchHeader
+	Entry  *ach.EntryDetail
+
+	// SettlementDate is when the entry settled, which return deadlines are counted from.
+	SettlementDate time.Time
+}
+
+func NewRepo(db *sql.DB) *sqlRepo {
+	return &sqlRepo{db: db}
+}
+
+// NewEncryptedRepo returns a Repository which encrypts the individual name and entry detail
+// of received transfers it saves. Transfers saved before encryption was enabled are read as-is.
+func NewEncryptedRepo(db *sql.DB, cfg *config.FieldEncryption) (*sqlRepo, error) {
+	cipher, err := newFieldCipher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRepo{db: db, cipher: cipher}, nil
+}
+
+type sqlRepo struct {
+	db *sql.DB
+
+	// cipher is nil unless field encryption is configured
+	cipher *fieldCipher
+
+	// replica serves list queries when a read replica is configured
+	replica *database.Replica
+}
+
+// UseReadReplica sends list queries to replica while it's reachable.
+func (r *sqlRepo) UseReadReplica(replica *database.Replica) {
+	r.replica = replica
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+const receivedTransferColumns = `received_transfer_id, amount_currency, amount_value, status, transaction_code, standard_entry_class_code, company_name, company_identification, company_entry_description, odfi_identification, rdfi_routing_number, individual_name, account_id, transaction_id, trace_number, return_code, return_trace_number, return_deadline, returned_at, created_at, individual_name_hash`
+
+func (r *sqlRepo) SaveReceivedTransfer(orgID string, xfer *client.ReceivedTransfer, entry Entry) error {
+	defer database.MeasureQuery("received", "SaveReceivedTransfer")()
+
+	if entry.Header == nil || entry.Entry == nil {
+		return fmt.Errorf("receivedTransferID=%s missing entry", xfer.ReceivedTransferID)
+	}
+
+	query := `insert into received_transfers (organization, batch_header, entry_detail, settlement_date, ` + receivedTransferColumns + `)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("received transfer prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	individualName, entryDetail := xfer.IndividualName, entry.Entry.String()
+	var nameHash *string
+	if r.cipher != nil {
+		if individualName, err = r.cipher.encrypt(individualName); err != nil {
+			return fmt.Errorf("encrypting individual name: %v", err)
+		}
+		if entryDetail, err = r.cipher.encrypt(entryDetail); err != nil {
+			return fmt.Errorf("encrypting entry detail: %v", err)
+		}
+		hash := r.cipher.hash(xfer.IndividualName)
+		nameHash = &hash
+	}
+
+	var returnCode *string
+	if xfer.ReturnCode != nil {
+		returnCode = &xfer.ReturnCode.Code
+	}
+	_, err = stmt.Exec(
+		orgID,
+		entry.Header.String(),
+		entryDetail,
+		entry.SettlementDate,
+		xfer.ReceivedTransferID,
+		xfer.Amount.Currency,
+		xfer.Amount.Value,
+		xfer.Status,
+		xfer.TransactionCode,
+		xfer.StandardEntryClassCode,
+		xfer.CompanyName,
+		xfer.CompanyIdentification,
+		xfer.CompanyEntryDescription,
+		xfer.OdfiIdentification,
+		xfer.RdfiRoutingNumber,
+		individualName,
+		nullable(xfer.AccountID),
+		nullable(xfer.TransactionID),
+		xfer.TraceNumber,
+		returnCode,
+		nullable(xfer.ReturnTraceNumber),
+		xfer.ReturnDeadline,
+		xfer.ReturnedAt,
+		xfer.Created,
+		nameHash,
+	)
+	if err != nil {
+		return fmt.Errorf("received transfer insert: %v", err)
+	}
+	return nil
+}
+
+func (r *sqlRepo) getReceivedTransfers(orgID string, params filterParams) ([]*client.ReceivedTransfer, error) {
+	defer database.MeasureQuery("received", "getReceivedTransfers")()
+
+	var query strings.Builder
+	query.WriteString("select " + receivedTransferColumns + " from received_transfers where organization = ? ")
+
+	args := []interface{}{orgID}
+	if string(params.Status) != "" {
+		query.WriteString("and status = ? ")
+		args = append(args, params.Status)
+	}
+	if params.IndividualName != "" {
+		if r.cipher != nil {
+			query.WriteString("and individual_name_hash = ? ")
+			args = append(args, r.cipher.hash(params.IndividualName))
+		} else {
+			query.WriteString("and upper(individual_name) = ? ")
+			args = append(args, normalizeName(params.IndividualName))
+		}
+	}
+	query.WriteString("order by created_at desc limit ? offset ?;")
+	args = append(args, params.Count, params.Skip)
+
+	xfers := make([]*client.ReceivedTransfer, 0) // allocate array so JSON marshal is [] instead of null
+	err := database.QueryRows(r.replica.Reader(r.db), "getReceivedTransfers", query.String(), args, func(rows *sql.Rows) error {
+		xfer, err := r.scanReceivedTransfer(rows)
+		if err != nil {
+			return err
+		}
+		xfers = append(xfers, xfer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return xfers, nil
+}
+
+func (r *sqlRepo) getReceivedTransfer(orgID string, receivedTransferID string) (*client.ReceivedTransfer, error) {
+	defer database.MeasureQuery("received", "getReceivedTransfer")()
+
+	query := `select ` + receivedTransferColumns + ` from received_transfers
+where received_transfer_id = ? and organization = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	xfer, err := r.scanReceivedTransfer(stmt.QueryRow(receivedTransferID, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return xfer, nil
+}
+
+func (r *sqlRepo) getReceivedEntry(receivedTransferID string) (*Entry, error) {
+	defer database.MeasureQuery("received", "getReceivedEntry")()
+
+	query := `select batch_header, entry_detail, settlement_date, individual_name_hash from received_transfers where received_transfer_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var header, entry string
+	var settlement time.Time
+	var nameHash *string
+	if err := stmt.QueryRow(receivedTransferID).Scan(&header, &entry, &settlement, &nameHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if nameHash != nil {
+		if entry, err = r.decrypt(entry); err != nil {
+			return nil, fmt.Errorf("decrypting entry detail of receivedTransferID=%s: %v", receivedTransferID, err)
+		}
+	}
+	return parseEntry(header, entry, settlement), nil
+}
+
+// decrypt reads a field of a received transfer which was saved encrypted.
+func (r *sqlRepo) decrypt(value string) (string, error) {
+	if r.cipher == nil {
+		return "", errors.New("field encryption isn't configured")
+	}
+	return r.cipher.decrypt(value)
+}
+
+func parseEntry(header, entry string, settlement time.Time) *Entry {
+	bh := ach.NewBatchHeader()
+	bh.Parse(header)
+
+	ed := ach.NewEntryDetail()
+	ed.Parse(entry)
+
+	return &Entry{
+		Header:         bh,
+		Entry:          ed,
+		SettlementDate: settlement,
+	}
+}
+
+// saveReturn marks a posted ReceivedTransfer as returning by deadline.
+func (r *sqlRepo) saveReturn(receivedTransferID string, returnCode string, returnTraceNumber string, deadline time.Time) error {
+	defer database.MeasureQuery("received", "saveReturn")()
+
+	query := `update received_transfers set status = ?, return_code = ?, return_trace_number = ?, return_deadline = ?
+where received_transfer_id = ? and status = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(client.RETURNING, returnCode, returnTraceNumber, deadline, receivedTransferID, client.POSTED)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("received transfer isn't posted")
+	}
+	return nil
+}
+
+// getPendingReturns reads every ReceivedTransfer whose return hasn't been uploaded.
+func (r *sqlRepo) getPendingReturns() ([]*client.ReceivedTransfer, error) {
+	defer database.MeasureQuery("received", "getPendingReturns")()
+
+	query := `select ` + receivedTransferColumns + ` from received_transfers where status = ? order by return_deadline asc;`
+
+	var xfers []*client.ReceivedTransfer
+	err := database.QueryRows(r.db, "getPendingReturns", query, []interface{}{client.RETURNING}, func(rows *sql.Rows) error {
+		xfer, err := r.scanReceivedTransfer(rows)
+		if err != nil {
+			return err
+		}
+		xfers = append(xfers, xfer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return xfers, nil
+}
+
+// markReturnsUploaded marks the returning ReceivedTransfers as returned at when.
+func (r *sqlRepo) markReturnsUploaded(receivedTransferIDs []string, when time.Time) error {
+	defer database.MeasureQuery("received", "markReturnsUploaded")()
+
+	if len(receivedTransferIDs) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf(`update received_transfers set status = ?, returned_at = ?
+where status = ? and received_transfer_id in (%s);`, database.Placeholders(len(receivedTransferIDs)))
+
+	args := []interface{}{client.RETURNED, when, client.RETURNING}
+	args = append(args, database.StringArgs(receivedTransferIDs)...)
+
+	_, err := r.db.Exec(query, args...)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanReceivedTransfer reads a row of receivedTransferColumns from either *sql.Row or *sql.Rows.
+// Rows saved with an individual name hash were encrypted.
+func (r *sqlRepo) scanReceivedTransfer(row scanner) (*client.ReceivedTransfer, error) {
+	var accountID, transactionID, returnCode, returnTraceNumber, nameHash *string
+	xfer := &client.ReceivedTransfer{}
+	err := row.Scan(
+		&xfer.ReceivedTransferID,
+		&xfer.Amount.Currency,
+		&xfer.Amount.Value,
+		&xfer.Status,
+		&xfer.TransactionCode,
+		&xfer.StandardEntryClassCode,
+		&xfer.CompanyName,
+		&xfer.CompanyIdentification,
+		&xfer.CompanyEntryDescription,
+		&xfer.OdfiIdentification,
+		&xfer.RdfiRoutingNumber,
+		&xfer.IndividualName,
+		&accountID,
+		&transactionID,
+		&xfer.TraceNumber,
+		&returnCode,
+		&returnTraceNumber,
+		&xfer.ReturnDeadline,
+		&xfer.ReturnedAt,
+		&xfer.Created,
+		&nameHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if nameHash != nil {
+		if xfer.IndividualName, err = r.decrypt(xfer.IndividualName); err != nil {
+			return nil, fmt.Errorf("decrypting individual name of receivedTransferID=%s: %v", xfer.ReceivedTransferID, err)
+		}
+	}
+	if accountID != nil {
+		xfer.AccountID = *accountID
+	}
+	if transactionID != nil {
+		xfer.TransactionID = *transactionID
+	}
+	if returnTraceNumber != nil {
+		xfer.ReturnTraceNumber = *returnTraceNumber
+	}
+	if returnCode != nil {
+		xfer.ReturnCode = achx.ReturnCode(*returnCode)
+	}
+	return xfer, nil
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
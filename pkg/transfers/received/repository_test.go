@@ -0,0 +1,287 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func TestRepository__ReceivedTransfers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		posted := mockReceivedTransfer()
+		if err := repo.SaveReceivedTransfer(orgID, posted, readEntry(t)); err != nil {
+			t.Fatal(err)
+		}
+		returned := mockReceivedTransfer()
+		returned.Status = client.RETURNED
+		returned.AccountID, returned.TransactionID = "", ""
+		returned.ReturnCode = &client.ReturnCode{Code: "R03"}
+		returned.ReturnTraceNumber = "121042880000001"
+		if err := repo.SaveReceivedTransfer(orgID, returned, readEntry(t)); err != nil {
+			t.Fatal(err)
+		}
+
+		xfers, err := repo.getReceivedTransfers(orgID, filterParams{Count: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(xfers) != 2 {
+			t.Fatalf("unexpected received transfers: %#v", xfers)
+		}
+
+		xfers, err = repo.getReceivedTransfers(orgID, filterParams{Status: client.RETURNED, Count: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(xfers) != 1 || xfers[0].ReceivedTransferID != returned.ReceivedTransferID {
+			t.Fatalf("unexpected received transfers: %#v", xfers)
+		}
+		if rc := xfers[0].ReturnCode; rc == nil || rc.Code != "R03" || rc.Reason == "" {
+			t.Errorf("unexpected return code: %#v", rc)
+		}
+		if xfers[0].AccountID != "" || xfers[0].ReturnTraceNumber != returned.ReturnTraceNumber {
+			t.Errorf("unexpected received transfer: %#v", xfers[0])
+		}
+
+		xfer, err := repo.getReceivedTransfer(orgID, posted.ReceivedTransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xfer == nil || xfer.AccountID != posted.AccountID || xfer.TransactionID != posted.TransactionID {
+			t.Errorf("unexpected received transfer: %#v", xfer)
+		}
+		if xfer.Amount.Value != 10500 || xfer.TransactionCode != 22 || xfer.ReturnCode != nil {
+			t.Errorf("unexpected received transfer: %#v", xfer)
+		}
+		if !xfer.ReturnDeadline.Equal(posted.ReturnDeadline) || xfer.ReturnedAt != nil {
+			t.Errorf("unexpected received transfer: %#v", xfer)
+		}
+
+		// other organizations don't see it
+		xfer, err = repo.getReceivedTransfer(base.ID(), posted.ReceivedTransferID)
+		if err != nil || xfer != nil {
+			t.Errorf("unexpected received transfer=%#v error=%v", xfer, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__Returns(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		orgID := base.ID()
+		xfer := mockReceivedTransfer()
+		if err := repo.SaveReceivedTransfer(orgID, xfer, readEntry(t)); err != nil {
+			t.Fatal(err)
+		}
+
+		entry, err := repo.getReceivedEntry(xfer.ReceivedTransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || entry.Header.CompanyName != "companyname" || entry.Entry.TraceNumber != "076401255655291" || entry.Entry.Amount != 10500 {
+			t.Fatalf("unexpected entry: %#v", entry)
+		}
+		if entry, err := repo.getReceivedEntry(base.ID()); err != nil || entry != nil {
+			t.Errorf("unexpected entry=%#v error=%v", entry, err)
+		}
+
+		deadline := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		if err := repo.saveReturn(xfer.ReceivedTransferID, "R10", "053200010000001", deadline); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.saveReturn(xfer.ReceivedTransferID, "R10", "053200010000001", deadline); err == nil {
+			t.Error("expected error returning twice")
+		}
+
+		pending, err := repo.getPendingReturns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != 1 || pending[0].Status != client.RETURNING || !pending[0].ReturnDeadline.Equal(deadline) {
+			t.Fatalf("unexpected pending returns: %#v", pending)
+		}
+
+		when := time.Now().Truncate(time.Second)
+		if err := repo.markReturnsUploaded([]string{xfer.ReceivedTransferID, base.ID()}, when); err != nil {
+			t.Fatal(err)
+		}
+		if pending, err := repo.getPendingReturns(); err != nil || len(pending) != 0 {
+			t.Errorf("unexpected pending=%#v error=%v", pending, err)
+		}
+		found, err := repo.getReceivedTransfer(orgID, xfer.ReceivedTransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.RETURNED || found.ReturnedAt == nil || !found.ReturnedAt.Equal(when) {
+			t.Errorf("unexpected received transfer: %#v", found)
+		}
+		if found.ReturnCode == nil || found.ReturnCode.Code != "R10" {
+			t.Errorf("unexpected return code: %#v", found.ReturnCode)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__Encryption(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.FieldEncryption{
+		Symmetric: &config.Symmetric{
+			KeyURI: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("1"), 32)),
+		},
+		HashKey: "secret",
+	}
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		plaintext := &sqlRepo{db: repo.db}
+		encrypted, err := NewEncryptedRepo(repo.db, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// saved before encryption was enabled
+		orgID := base.ID()
+		legacy := mockReceivedTransfer()
+		if err := plaintext.SaveReceivedTransfer(orgID, legacy, readEntry(t)); err != nil {
+			t.Fatal(err)
+		}
+		xfer := mockReceivedTransfer()
+		if err := encrypted.SaveReceivedTransfer(orgID, xfer, readEntry(t)); err != nil {
+			t.Fatal(err)
+		}
+
+		var individualName, entryDetail string
+		query := `select individual_name, entry_detail from received_transfers where received_transfer_id = ?;`
+		if err := repo.db.QueryRow(query, xfer.ReceivedTransferID).Scan(&individualName, &entryDetail); err != nil {
+			t.Fatal(err)
+		}
+		if individualName == xfer.IndividualName || strings.Contains(entryDetail, "Bachman") {
+			t.Errorf("individual_name=%q entry_detail=%q aren't encrypted", individualName, entryDetail)
+		}
+
+		found, err := encrypted.getReceivedTransfer(orgID, xfer.ReceivedTransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.IndividualName != "Bachman Eric" {
+			t.Errorf("unexpected individual name: %q", found.IndividualName)
+		}
+		if found, err := encrypted.getReceivedTransfer(orgID, legacy.ReceivedTransferID); err != nil || found.IndividualName != "Bachman Eric" {
+			t.Errorf("unexpected received transfer=%#v error=%v", found, err)
+		}
+		entry, err := encrypted.getReceivedEntry(xfer.ReceivedTransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Entry.TraceNumber != "076401255655291" || entry.Entry.DFIAccountNumber == "" {
+			t.Errorf("unexpected entry: %#v", entry.Entry)
+		}
+
+		// only encrypted transfers are found by their hash
+		xfers, err := encrypted.getReceivedTransfers(orgID, filterParams{IndividualName: " bachman eric", Count: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(xfers) != 1 || xfers[0].ReceivedTransferID != xfer.ReceivedTransferID {
+			t.Errorf("unexpected received transfers: %#v", xfers)
+		}
+		if xfers, err := encrypted.getReceivedTransfers(orgID, filterParams{IndividualName: "other", Count: 10}); err != nil || len(xfers) != 0 {
+			t.Errorf("unexpected received transfers=%#v error=%v", xfers, err)
+		}
+
+		// without encryption configured the encrypted transfer can't be read
+		if _, err := plaintext.getReceivedTransfer(orgID, xfer.ReceivedTransferID); err == nil {
+			t.Error("expected error")
+		}
+		xfers, err = plaintext.getReceivedTransfers(orgID, filterParams{IndividualName: "BACHMAN ERIC", Count: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(xfers) != 1 || xfers[0].ReceivedTransferID != legacy.ReceivedTransferID {
+			t.Errorf("unexpected received transfers: %#v", xfers)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo := &sqlRepo{db: db.DB}
+	t.Cleanup(func() { repo.Close() })
+
+	return repo
+}
+
+func mockReceivedTransfer() *client.ReceivedTransfer {
+	return &client.ReceivedTransfer{
+		ReceivedTransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    10500,
+		},
+		Status:                  client.POSTED,
+		TransactionCode:         22,
+		StandardEntryClassCode:  "PPD",
+		CompanyName:             "companyname",
+		CompanyIdentification:   "origid",
+		CompanyEntryDescription: "CHECKPAYMT",
+		OdfiIdentification:      "07640125",
+		RdfiRoutingNumber:       "053200019",
+		IndividualName:          "Bachman Eric",
+		AccountID:               base.ID(),
+		TransactionID:           base.ID(),
+		TraceNumber:             "076401255655291",
+		ReturnDeadline:          time.Now().Add(24 * time.Hour).Truncate(time.Second),
+		Created:                 time.Now().Truncate(time.Second),
+	}
+}
+
+func readEntry(t *testing.T) Entry {
+	t.Helper()
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Entry{
+		Header:         file.Batches[0].GetHeader(),
+		Entry:          file.Batches[0].GetEntries()[0],
+		SettlementDate: time.Now().Truncate(time.Second),
+	}
+}
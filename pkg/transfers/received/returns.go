@@ -0,0 +1,125 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	returnsAtRisk = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "received_returns_at_risk",
+		Help: "Counter of returns of received entries which won't be uploaded before their deadline",
+	}, []string{"code"})
+)
+
+// PublishReturn sends a return of entry with code to be uploaded in the next cutoff window.
+// The pipeline merges files by their Transfer, so the return is published under the
+// ReceivedTransfer's ID. The return's trace number is allocated from traceNumbers, or
+// random when it's nil, and returned.
+func PublishReturn(pub pipeline.XferPublisher, odfi config.ODFI, traceNumbers achx.TraceNumbers, receivedTransferID string, entry Entry, code string) (string, error) {
+	if pub == nil {
+		return "", errors.New("unable to return entry without a pipeline publisher")
+	}
+	file, err := achx.ReturnEntry(receivedTransferID, returnOptions(odfi, traceNumbers), entry.Header, entry.Entry, code)
+	if err != nil {
+		return "", fmt.Errorf("creating return: %v", err)
+	}
+	placeholder := &client.Transfer{TransferID: receivedTransferID}
+	if err := pipeline.PublishFiles(pub, placeholder, []*ach.File{file}); err != nil {
+		return "", fmt.Errorf("publishing return: %v", err)
+	}
+	return file.Batches[0].GetEntries()[0].TraceNumber, nil
+}
+
+// returnOptions returns how returns of received entries are originated, which settle
+// on the next banking day.
+func returnOptions(cfg config.ODFI, traceNumbers achx.TraceNumbers) achx.Options {
+	when := base.NewTime(time.Now().In(cfg.Cutoffs.Location()))
+	return achx.Options{
+		ODFIRoutingNumber:  cfg.RoutingNumber,
+		Gateway:            cfg.Gateway,
+		FileConfig:         cfg.FileConfig,
+		CutoffTimezone:     cfg.Cutoffs.Location(),
+		EffectiveEntryDate: when.AddBankingDay(1),
+		TraceNumbers:       traceNumbers,
+	}
+}
+
+// ReturnTracker marks returns of ReceivedTransfers as returned once they're uploaded and
+// alerts on returns which won't be uploaded before their deadline.
+type ReturnTracker struct {
+	logger  log.Logger
+	cutoffs config.Cutoffs
+	repo    Repository
+}
+
+func NewReturnTracker(cfg *config.Config, repo Repository) *ReturnTracker {
+	return &ReturnTracker{
+		logger:  cfg.Logger.Set("service", log.String("received-returns")),
+		cutoffs: cfg.ODFI.Cutoffs,
+		repo:    repo,
+	}
+}
+
+// HandleCutoff is a pipeline.CompletedCutoffCallback which marks the uploaded returns
+// and checks the deadlines of those still waiting for a cutoff.
+func (rt *ReturnTracker) HandleCutoff(cutoff pipeline.CompletedCutoff) error {
+	receivedTransferIDs := make([]string, len(cutoff.Transfers))
+	for i := range cutoff.Transfers {
+		receivedTransferIDs[i] = cutoff.Transfers[i].TransferID
+	}
+	if err := rt.repo.markReturnsUploaded(receivedTransferIDs, cutoff.When); err != nil {
+		return fmt.Errorf("marking uploaded returns: %v", err)
+	}
+	return rt.checkDeadlines(cutoff.When)
+}
+
+func (rt *ReturnTracker) checkDeadlines(now time.Time) error {
+	pending, err := rt.repo.getPendingReturns()
+	if err != nil {
+		return fmt.Errorf("reading pending returns: %v", err)
+	}
+	for i := range pending {
+		alertAtRisk(rt.logger, rt.cutoffs, pending[i], now)
+	}
+	return nil
+}
+
+// alertAtRisk logs an error when the next cutoff window after now is past the deadline
+// of xfer's return.
+func alertAtRisk(logger log.Logger, cutoffs config.Cutoffs, xfer *client.ReceivedTransfer, now time.Time) bool {
+	next := NextCutoff(cutoffs, now)
+	if !next.After(xfer.ReturnDeadline) {
+		return false
+	}
+	var code string
+	if xfer.ReturnCode != nil {
+		code = xfer.ReturnCode.Code
+	}
+	returnsAtRisk.With("code", code).Add(1)
+
+	logger.With(log.Fields{
+		"receivedTransferID": log.String(xfer.ReceivedTransferID),
+		"returnCode":         log.String(code),
+		"returnDeadline":     log.String(xfer.ReturnDeadline.Format(time.RFC3339)),
+		"nextCutoff":         log.String(next.Format(time.RFC3339)),
+	}).LogErrorf("return at risk of missing its deadline")
+	return true
+}
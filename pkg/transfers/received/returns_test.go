@@ -0,0 +1,64 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+)
+
+func TestReturnTracker__HandleCutoff(t *testing.T) {
+	uploaded, waiting := mockReceivedTransfer(), mockReceivedTransfer()
+	uploaded.Status, waiting.Status = client.RETURNING, client.RETURNING
+	repo := &MockRepository{
+		Transfers: []*client.ReceivedTransfer{uploaded, waiting},
+	}
+
+	rt := NewReturnTracker(mockConfig(), repo)
+	when := time.Now()
+	err := rt.HandleCutoff(pipeline.CompletedCutoff{
+		When: when,
+		Transfers: []pipeline.ProcessedTransfer{
+			{TransferID: uploaded.ReceivedTransferID},
+			{TransferID: base.ID()},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uploaded.Status != client.RETURNED || uploaded.ReturnedAt == nil || !uploaded.ReturnedAt.Equal(when) {
+		t.Errorf("unexpected received transfer: %#v", uploaded)
+	}
+	if waiting.Status != client.RETURNING || waiting.ReturnedAt != nil {
+		t.Errorf("unexpected received transfer: %#v", waiting)
+	}
+}
+
+func TestReturns__alertAtRisk(t *testing.T) {
+	cutoffs := config.Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"16:20"},
+	}
+	xfer := mockReceivedTransfer()
+	xfer.ReturnCode = &client.ReturnCode{Code: "R01"}
+
+	now := time.Now()
+	xfer.ReturnDeadline = ReturnDeadline(cutoffs, now)
+	if alertAtRisk(log.NewNopLogger(), cutoffs, xfer, now) {
+		t.Error("expected return to be on time")
+	}
+
+	xfer.ReturnDeadline = now.Add(-1 * time.Minute)
+	if !alertAtRisk(log.NewNopLogger(), cutoffs, xfer, now) {
+		t.Error("expected return to be at risk")
+	}
+}
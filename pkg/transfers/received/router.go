@@ -0,0 +1,240 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	moovhttp "github.com/moov-io/base/http"
+
+	"github.com/moov-io/paygate/pkg/accounts"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base/log"
+)
+
+type Router struct {
+	GetReceivedTransfers    http.HandlerFunc
+	GetReceivedTransferByID http.HandlerFunc
+	ReturnReceivedTransfer  http.HandlerFunc
+}
+
+func NewRouter(cfg *config.Config, repo Repository, accountsClient accounts.Client, pub pipeline.XferPublisher, traceNumbers achx.TraceNumbers) *Router {
+	if cfg.RDFI == nil {
+		return &Router{
+			GetReceivedTransfers:    NotImplemented(cfg),
+			GetReceivedTransferByID: NotImplemented(cfg),
+			ReturnReceivedTransfer:  NotImplemented(cfg),
+		}
+	}
+	return &Router{
+		GetReceivedTransfers:    GetReceivedTransfers(cfg, repo),
+		GetReceivedTransferByID: GetReceivedTransferByID(cfg, repo),
+		ReturnReceivedTransfer:  ReturnReceivedTransfer(cfg, repo, accountsClient, pub, traceNumbers),
+	}
+}
+
+func (c *Router) RegisterRoutes(r *mux.Router) {
+	r.Methods("GET").Path("/received-transfers").HandlerFunc(c.GetReceivedTransfers)
+	r.Methods("GET").Path("/received-transfers/{receivedTransferID}").HandlerFunc(c.GetReceivedTransferByID)
+	r.Methods("POST").Path("/received-transfers/{receivedTransferID}/return").HandlerFunc(c.ReturnReceivedTransfer)
+}
+
+type filterParams struct {
+	Status client.ReceivedTransferStatus
+
+	// IndividualName matches received transfers by their exact individual name, ignoring case
+	IndividualName string
+
+	Count int64
+	Skip  int64
+}
+
+func readFilterParams(r *http.Request) (filterParams, error) {
+	params := filterParams{
+		Count: 100,
+	}
+	skip, count, _, err := moovhttp.GetSkipAndCount(r)
+	if err != nil {
+		return params, err
+	}
+	params.Count = int64(count)
+	params.Skip = int64(skip)
+
+	if s := strings.TrimSpace(r.URL.Query().Get("status")); s != "" {
+		params.Status = client.ReceivedTransferStatus(strings.ToLower(s))
+		switch params.Status {
+		case client.POSTED, client.RETURNED:
+		default:
+			verr := &route.ValidationError{}
+			verr.Add("status", "unknown status %q", s)
+			return params, verr.Err()
+		}
+	}
+	params.IndividualName = strings.TrimSpace(r.URL.Query().Get("individualName"))
+	return params, nil
+}
+
+func GetReceivedTransfers(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		params, err := readFilterParams(r)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		xfers, err := repo.getReceivedTransfers(responder.OrganizationID, params)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(xfers)
+		})
+	}
+}
+
+func GetReceivedTransferByID(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		xfer, err := repo.getReceivedTransfer(responder.OrganizationID, route.ReadPathID("receivedTransferID", r))
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("received transfer not found"))
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(xfer)
+		})
+	}
+}
+
+// ReturnReceivedTransfer reverses the posting of a ReceivedTransfer and sends it back to
+// its ODFI in the next cutoff window.
+func ReturnReceivedTransfer(cfg *config.Config, repo Repository, accountsClient accounts.Client, pub pipeline.XferPublisher, traceNumbers achx.TraceNumbers) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var request client.CreateReceivedTransferReturn
+		if err := route.DecodeJSON(r, &request, route.DisallowUnknownFields); err != nil {
+			responder.Problem(err)
+			return
+		}
+		code := strings.ToUpper(request.ReturnCode)
+		if err := validateReturnCode(code); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		receivedTransferID := route.ReadPathID("receivedTransferID", r)
+		xfer, err := repo.getReceivedTransfer(responder.OrganizationID, receivedTransferID)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if xfer == nil {
+			responder.Problem(route.NotFound.New("received transfer not found"))
+			return
+		}
+		if xfer.Status != client.POSTED {
+			responder.Problem(route.InvalidRequest.New("received transfer is %s", xfer.Status))
+			return
+		}
+		entry, err := repo.getReceivedEntry(receivedTransferID)
+		if err != nil || entry == nil {
+			responder.Problem(route.Internal.New("reading received entry: %v", err))
+			return
+		}
+
+		now := time.Now()
+		deadline := xfer.ReturnDeadline
+		if IsExtendedReturnCode(code) {
+			deadline = ExtendedReturnDeadline(cfg.ODFI.Cutoffs, entry.SettlementDate)
+		}
+		if now.After(deadline) {
+			responder.Problem(route.InvalidRequest.New("return deadline of %s has passed", deadline.Format(time.RFC3339)))
+			return
+		}
+
+		// Reverse the posting before the return is sent
+		purpose := accounts.ACHDebit
+		if achx.IsDebitTransactionCode(int(xfer.TransactionCode)) {
+			purpose = accounts.ACHCredit
+		}
+		_, err = accountsClient.PostTransaction(cfg.RDFI.Organization, receivedTransferID+"-return", []accounts.TransactionLine{
+			{
+				AccountID: xfer.AccountID,
+				Purpose:   purpose,
+				Amount:    int(xfer.Amount.Value),
+			},
+		})
+		if err != nil {
+			responder.Problem(route.Unavailable.New("reversing posting: %v", err))
+			return
+		}
+
+		traceNumber, err := PublishReturn(pub, cfg.ODFI, traceNumbers, receivedTransferID, *entry, code)
+		if err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+		if err := repo.saveReturn(receivedTransferID, code, traceNumber, deadline); err != nil {
+			responder.Problem(route.Internal.Wrap(err))
+			return
+		}
+
+		xfer.Status = client.RETURNING
+		xfer.ReturnTraceNumber = traceNumber
+		xfer.ReturnDeadline = deadline
+		xfer.ReturnCode = achx.ReturnCode(code)
+		alertAtRisk(responder.Logger(), cfg.ODFI.Cutoffs, xfer, now)
+
+		responder.Logger().With(log.Fields{
+			"receivedTransferID": log.String(receivedTransferID),
+			"returnCode":         log.String(code),
+			"traceNumber":        log.String(traceNumber),
+		}).Log("returning received transfer")
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(xfer)
+		})
+	}
+}
+
+func validateReturnCode(code string) error {
+	verr := &route.ValidationError{}
+	if ach.LookupReturnCode(code) == nil {
+		verr.Add("returnCode", "unknown return code %q", code)
+	} else if achx.IsDishonoredReturnCode(code) || achx.IsContestedDishonoredReturnCode(code) {
+		verr.Add("returnCode", "%s can't be used to return a received transfer", code)
+	}
+	return verr.Err()
+}
+
+func NotImplemented(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Problem(route.Disabled.New("receiving transfers is disabled via config"))
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package received
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/accounts"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/gorilla/mux"
+)
+
+func mockConfig() *config.Config {
+	cfg := config.Empty()
+	cfg.ODFI.RoutingNumber = "053200019"
+	cfg.RDFI = &config.RDFI{
+		RoutingNumbers: []string{"053200019"},
+		Organization:   "moov",
+	}
+	return cfg
+}
+
+func TestRouter__NotImplemented(t *testing.T) {
+	r := mux.NewRouter()
+	NewRouter(config.Empty(), &MockRepository{}, &accounts.MockClient{}, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/received-transfers", nil)
+	req.Header.Set("X-Organization", base.ID())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status %d: %v", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "receiving transfers is disabled") {
+		t.Errorf("unexpected error: %v", w.Body.String())
+	}
+}
+
+func TestRouter__GetReceivedTransfers(t *testing.T) {
+	repo := &MockRepository{
+		Transfers: []*client.ReceivedTransfer{mockReceivedTransfer()},
+	}
+	r := mux.NewRouter()
+	NewRouter(mockConfig(), repo, &accounts.MockClient{}, nil, nil).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	xfers, resp, err := c.TransfersApi.GetReceivedTransfers(context.TODO(), "moov", &client.GetReceivedTransfersOpts{
+		Status: optional.NewInterface(client.POSTED),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(xfers) != 1 || xfers[0].ReceivedTransferID != repo.Transfers[0].ReceivedTransferID {
+		t.Errorf("unexpected received transfers: %#v", xfers)
+	}
+}
+
+func TestRouter__GetReceivedTransfersStatus(t *testing.T) {
+	r := mux.NewRouter()
+	NewRouter(mockConfig(), &MockRepository{}, &accounts.MockClient{}, nil, nil).RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/received-transfers?status=pending", nil)
+	req.Header.Set("X-Organization", "moov")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	w.Flush()
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status %d: %v", w.Code, w.Body.String())
+	}
+}
+
+func TestRouter__GetReceivedTransferByID(t *testing.T) {
+	repo := &MockRepository{
+		Transfers: []*client.ReceivedTransfer{mockReceivedTransfer()},
+	}
+	r := mux.NewRouter()
+	NewRouter(mockConfig(), repo, &accounts.MockClient{}, nil, nil).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	receivedTransferID := repo.Transfers[0].ReceivedTransferID
+	xfer, resp, err := c.TransfersApi.GetReceivedTransferByID(context.TODO(), receivedTransferID, "moov", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.ReceivedTransferID != receivedTransferID || xfer.Status != client.POSTED {
+		t.Errorf("unexpected received transfer: %#v", xfer)
+	}
+
+	_, resp, err = c.TransfersApi.GetReceivedTransferByID(context.TODO(), base.ID(), "moov", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
+
+func TestRouter__ReturnReceivedTransfer(t *testing.T) {
+	xfer := mockReceivedTransfer()
+	xfer.AccountID = "acct"
+	entry := readEntry(t)
+	repo := &MockRepository{
+		Transfers: []*client.ReceivedTransfer{xfer},
+		Entry:     &entry,
+	}
+	accountsClient := &accounts.MockClient{}
+	pub := pipeline.NewMockPublisher()
+
+	r := mux.NewRouter()
+	NewRouter(mockConfig(), repo, accountsClient, pub, nil).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	returned, resp, err := c.TransfersApi.ReturnReceivedTransfer(context.TODO(), xfer.ReceivedTransferID, "moov", client.CreateReceivedTransferReturn{
+		ReturnCode: "r01",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if returned.Status != client.RETURNING || returned.ReturnCode == nil || returned.ReturnCode.Code != "R01" {
+		t.Errorf("unexpected received transfer: %#v", returned)
+	}
+
+	// the credit is debited back out of the account
+	if len(accountsClient.Posted) != 1 {
+		t.Fatalf("unexpected transactions: %#v", accountsClient.Posted)
+	}
+	if line := accountsClient.Posted[0][0]; line.AccountID != "acct" || line.Purpose != accounts.ACHDebit || line.Amount != 10500 {
+		t.Errorf("unexpected transaction line: %#v", line)
+	}
+
+	published, ok := pub.Xfers[xfer.ReceivedTransferID]
+	if !ok {
+		t.Fatalf("missing return: %#v", pub.Xfers)
+	}
+	ed := published.File.Batches[0].GetEntries()[0]
+	if ed.Addenda99 == nil || ed.Addenda99.ReturnCode != "R01" || ed.TraceNumber != returned.ReturnTraceNumber {
+		t.Errorf("unexpected return entry: %#v", ed)
+	}
+
+	// the transfer can only be returned once
+	_, resp, err = c.TransfersApi.ReturnReceivedTransfer(context.TODO(), xfer.ReceivedTransferID, "moov", client.CreateReceivedTransferReturn{
+		ReturnCode: "R01",
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
+
+func TestRouter__ReturnReceivedTransferErr(t *testing.T) {
+	setup := func(t *testing.T, xfer *client.ReceivedTransfer) (*client.APIClient, *accounts.MockClient) {
+		entry := readEntry(t)
+		repo := &MockRepository{
+			Transfers: []*client.ReceivedTransfer{xfer},
+			Entry:     &entry,
+		}
+		accountsClient := &accounts.MockClient{}
+
+		r := mux.NewRouter()
+		NewRouter(mockConfig(), repo, accountsClient, pipeline.NewMockPublisher(), nil).RegisterRoutes(r)
+		return testclient.New(t, r), accountsClient
+	}
+	returnXfer := func(t *testing.T, c *client.APIClient, receivedTransferID, code string) error {
+		_, resp, err := c.TransfersApi.ReturnReceivedTransfer(context.TODO(), receivedTransferID, "moov", client.CreateReceivedTransferReturn{
+			ReturnCode: code,
+		}, nil)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("unknown code", func(t *testing.T) {
+		xfer := mockReceivedTransfer()
+		c, accountsClient := setup(t, xfer)
+		if err := returnXfer(t, c, xfer.ReceivedTransferID, "R99"); err == nil {
+			t.Error("expected error")
+		}
+		if err := returnXfer(t, c, xfer.ReceivedTransferID, "R68"); err == nil {
+			t.Error("expected error")
+		}
+		if len(accountsClient.Posted) != 0 {
+			t.Errorf("unexpected transactions: %#v", accountsClient.Posted)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c, _ := setup(t, mockReceivedTransfer())
+		if err := returnXfer(t, c, base.ID(), "R01"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("deadline passed", func(t *testing.T) {
+		xfer := mockReceivedTransfer()
+		xfer.ReturnDeadline = time.Now().Add(-1 * time.Hour)
+		c, accountsClient := setup(t, xfer)
+		if err := returnXfer(t, c, xfer.ReceivedTransferID, "R01"); err == nil {
+			t.Error("expected error")
+		}
+		if len(accountsClient.Posted) != 0 {
+			t.Errorf("unexpected transactions: %#v", accountsClient.Posted)
+		}
+
+		// unauthorized debits can be returned for longer
+		if err := returnXfer(t, c, xfer.ReceivedTransferID, "R10"); err != nil {
+			t.Fatal(err)
+		}
+		if xfer.Status != client.RETURNING {
+			t.Errorf("unexpected status: %v", xfer.Status)
+		}
+	})
+}
@@ -5,28 +5,141 @@
 package transfers
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
 
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 )
 
 type Repository interface {
-	getTransfers(orgID string, params transferFilterParams) ([]*client.Transfer, error)
-	GetTransfer(id string) (*client.Transfer, error)
+	getTransfers(ctx context.Context, orgID string, params transferFilterParams) ([]*client.Transfer, error)
+	GetTransfer(ctx context.Context, id string) (*client.Transfer, error)
+	GetUserTransfer(ctx context.Context, transferID string, orgID string) (*client.Transfer, error)
+	// getTransferByExternalID returns the Transfer of orgID created with externalID, or nil if there's none
+	getTransferByExternalID(ctx context.Context, orgID string, externalID string) (*client.Transfer, error)
 	UpdateTransferStatus(transferID string, status client.TransferStatus) error
 	WriteUserTransfer(orgID string, transfer *client.Transfer) error
-	deleteUserTransfer(orgID string, transferID string) error
+	WriteUserTransfers(orgID string, xfers []UserTransfer) error
+	createUserTransfer(orgID string, transfer *client.Transfer, orig origination) error
+	// createTransferGroup saves the Transfers of a split payment with their originations in one
+	// transaction, so either every Transfer is saved or none are
+	createTransferGroup(orgID string, transfers []*client.Transfer, origs []origination) error
+	// getTransferGroup returns the Transfers of a split payment, oldest first
+	getTransferGroup(ctx context.Context, orgID string, groupID string) ([]*client.Transfer, error)
+	// deleteUserTransfer removes a PENDING Transfer and saves msgs, unless it's part of in-flight
+	// micro-deposits. With force those micro-deposits are failed and their other PENDING Transfers
+	// removed as well.
+	deleteUserTransfer(orgID string, transferID string, force bool, msgs []pipeline.OutboxMessage) error
+
+	// createReviewableTransfer saves a Transfer like createUserTransfer, but holds its messages
+	// until another user approves it with approveTransfer
+	createReviewableTransfer(orgID string, transfer *client.Transfer, requestedBy string, orig origination) error
+	// GetTransferApproval returns nil for Transfers which didn't need approval
+	GetTransferApproval(transferID string) (*admin.TransferApproval, error)
+	// approveTransfer moves a REVIEWABLE Transfer to PENDING and releases its messages
+	approveTransfer(transferID string, approvedBy string) error
+
+	// enqueueUserTransfer saves a Transfer without files for a Queue to originate
+	enqueueUserTransfer(orgID string, transfer *client.Transfer) error
+	// enqueueRetryTransfer saves a retry in the organization of the original Transfer
+	// for a Queue to originate once availableAt has passed
+	enqueueRetryTransfer(originalID string, retry *client.Transfer, availableAt time.Time) error
+	claimQueuedTransfers(limit int) ([]queuedTransfer, error)
+	// completeQueuedTransfer saves the origination, expected settlement date and funds availability of an originated Transfer
+	completeQueuedTransfer(transfer *client.Transfer, orig origination) error
+	retryQueuedTransfer(transferID string) error
+	failQueuedTransfer(transferID string) error
 
 	SaveReturnCode(transferID string, returnCode string) error
 	saveTraceNumbers(transferID string, traceNumbers []string) error
-	getTraceNumbers(transferID string) ([]string, error)
+	getTraceNumbers(ctx context.Context, transferID string) ([]string, error)
+
+	saveTransferEntries(transferID string, entries []client.TransferEntry) error
+	getTransferEntries(ctx context.Context, transferID string) ([]client.TransferEntry, error)
+	// getTransferFile returns the file a Transfer's entries were merged into, or nil until it's uploaded
+	getTransferFile(ctx context.Context, transferID string) (*client.TransferFile, error)
+	// getFileContents returns the Transfers in each batch of an uploaded file and every attempt to
+	// upload it, or nil if there's no such file
+	getFileContents(ctx context.Context, filename string) (*admin.FileContents, error)
+	// recordAccountNumberReveal saves who read the full account numbers of a Transfer's entries
+	recordAccountNumberReveal(transferID, userID, reason string) error
+
+	// saveAccountHistory saves the details of an account unless they match its latest saved details
+	saveAccountHistory(orgID string, history client.AccountHistory) error
+	// getAccountHistory returns each saved details of an account, oldest first
+	getAccountHistory(ctx context.Context, orgID string, accountID string) ([]client.AccountHistory, error)
+
+	// getHoldingTotals sums the collections into and payouts from an organization's holding account
+	getHoldingTotals(ctx context.Context, orgID string, accountID string) (holdingTotals, error)
+
+	// createMandate saves a receiver's authorization to debit their account
+	createMandate(orgID string, mandate *client.Mandate) error
+	// getMandate returns nil if orgID has no such mandate
+	getMandate(ctx context.Context, orgID string, mandateID string) (*client.Mandate, error)
+	// getMandates returns the mandates of orgID, newest first, limited to customerID and status when they're set
+	getMandates(ctx context.Context, orgID string, customerID string, status client.MandateStatus) ([]*client.Mandate, error)
+	// revokeMandate records that the receiver revoked an ACTIVE mandate and returns false
+	// when it was already revoked
+	revokeMandate(orgID string, mandateID string, reason string) (bool, error)
+
+	// createEvidence saves evidence attached to a mandate or Transfer of orgID
+	createEvidence(orgID string, evidence *client.AuthorizationEvidence) error
+	// getEvidence returns nil if there's no such evidence
+	getEvidence(ctx context.Context, evidenceID string) (*client.AuthorizationEvidence, error)
+	// getTransferEvidence returns the evidence attached to a Transfer and to the mandate it references, oldest first
+	getTransferEvidence(ctx context.Context, transferID string) ([]client.AuthorizationEvidence, error)
+
+	SaveReturnEntry(transferID string, header *ach.BatchHeader, entry *ach.EntryDetail) error
+	GetReturnEntry(transferID string) (*ReturnEntry, error)
+	SaveDishonoredReturnCode(transferID string, returnCode string) error
 
 	LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error)
+
+	// getAvailableTransfers returns up to limit processed Transfers whose funds are available
+	// on day (as YYYY-MM-DD) and weren't recorded as available
+	getAvailableTransfers(day string, limit int) ([]availableTransfer, error)
+	// setFundsAvailable records or clears that a Transfer's funds are available and returns
+	// false when it already was
+	setFundsAvailable(transferID string, available bool) (bool, error)
+}
+
+// UserTransfer is a Transfer and the trace numbers of its ACH files, see WriteUserTransfers.
+type UserTransfer struct {
+	Transfer     *client.Transfer
+	TraceNumbers []string
+}
+
+// origination is what originating a Transfer produced: the trace numbers of its files, the
+// messages which publish them and the details of its accounts. It's saved in one transaction
+// with the Transfer, so nothing is left behind when saving fails.
+type origination struct {
+	traceNumbers []string
+	msgs         []pipeline.OutboxMessage
+	history      []client.AccountHistory
+	fee          *client.TransferFee
+}
+
+// ReturnEntry is the latest return received for one of a Transfer's entries.
+type ReturnEntry struct {
+	Header *ach.BatchHeader
+	Entry  *ach.EntryDetail
+
+	// DishonoredReturnCode is set once the return has been dishonored.
+	DishonoredReturnCode string
+
+	Created time.Time
 }
 
 func NewRepo(db *sql.DB) *sqlRepo {
@@ -35,6 +148,14 @@ func NewRepo(db *sql.DB) *sqlRepo {
 
 type sqlRepo struct {
 	db *sql.DB
+
+	// replica serves list queries when a read replica is configured
+	replica *database.Replica
+}
+
+// UseReadReplica sends list queries to replica while it's reachable.
+func (r *sqlRepo) UseReadReplica(replica *database.Replica) {
+	r.replica = replica
 }
 
 func (r *sqlRepo) Close() error {
@@ -44,9 +165,13 @@ func (r *sqlRepo) Close() error {
 	return r.db.Close()
 }
 
-func (r *sqlRepo) getTransfers(orgID string, params transferFilterParams) ([]*client.Transfer, error) {
+const transferColumns = `transfer_id, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, processed_at, created_at, remittance, standard_entry_class_code, check_details, company_entry_description, company_discretionary_data, retry_of, retry_attempt, expected_settlement_date, actual_settlement_date, available_on, external_id, group_id, mandate_id`
+
+func (r *sqlRepo) getTransfers(ctx context.Context, orgID string, params transferFilterParams) ([]*client.Transfer, error) {
+	defer database.MeasureQuery("transfers", "getTransfers")()
+
 	var query strings.Builder
-	query.WriteString("select transfer_id from transfers where ")
+	query.WriteString("select " + transferColumns + " from transfers where ")
 
 	var args []interface{}
 	query.WriteString("organization = ? and created_at >= ? and created_at <= ? and deleted_at is null ")
@@ -59,71 +184,94 @@ func (r *sqlRepo) getTransfers(orgID string, params transferFilterParams) ([]*cl
 
 	if len(params.CustomerIDs) > 0 {
 		s := fmt.Sprintf(
-			"and ( source_customer_id in (?%[1]s) or destination_customer_id in (?%[1]s) ) ",
-			strings.Repeat(",?", len(params.CustomerIDs)-1),
+			"and ( source_customer_id in (%[1]s) or destination_customer_id in (%[1]s) ) ",
+			database.Placeholders(len(params.CustomerIDs)),
 		)
 		query.WriteString(s)
-		for i := 0; i < len(params.CustomerIDs)*2; i++ {
-			args = append(args, params.CustomerIDs[i%len(params.CustomerIDs)])
-		}
+		args = append(args, database.StringArgs(params.CustomerIDs)...)
+		args = append(args, database.StringArgs(params.CustomerIDs)...)
 	}
 
 	query.WriteString("order by created_at desc limit ? offset ?;")
 	args = append(args, params.Count, params.Skip)
 
-	stmt, err := r.db.Prepare(query.String())
+	transfers := make([]*client.Transfer, 0) // allocate array so JSON marshal is [] instead of null
+	err := database.QueryRowsContext(ctx, r.replica.Reader(r.db), "getTransfers", query.String(), args, func(rows *sql.Rows) error {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return err
+		}
+		transfers = append(transfers, transfer)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
-
-	rows, err := stmt.Query(args...)
-	if err != nil {
+	if err := r.loadTraceNumbers(ctx, transfers); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if err := r.loadFees(ctx, transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
 
-	var transferIDs []string
-	transfers := make([]*client.Transfer, 0) // allocate array so JSON marshal is [] instead of null
+// GetUserTransfer reads a Transfer only if it belongs to orgID.
+func (r *sqlRepo) GetUserTransfer(ctx context.Context, transferID string, orgID string) (*client.Transfer, error) {
+	defer database.MeasureQuery("transfers", "GetUserTransfer")()
 
-	for rows.Next() {
-		var row string
-		if err := rows.Scan(&row); err != nil {
-			return transfers, fmt.Errorf("getTransfers scan: %v", err)
-		}
-		if row != "" {
-			transferIDs = append(transferIDs, row)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return transfers, fmt.Errorf("getTransfers: rows.Err=%v", err)
-	}
+	return r.getUserTransfer(ctx, transferID, orgID)
+}
 
-	// read each transferID
-	for i := range transferIDs {
-		t, err := r.getUserTransfer(transferIDs[i], orgID)
-		if err == nil && t.TransferID != "" {
-			transfers = append(transfers, t)
+func (r *sqlRepo) getTransferByExternalID(ctx context.Context, orgID string, externalID string) (*client.Transfer, error) {
+	defer database.MeasureQuery("transfers", "getTransferByExternalID")()
+
+	query := `select transfer_id from transfers where organization = ? and external_id = ? and deleted_at is null limit 1`
+	var transferID string
+	if err := r.db.QueryRowContext(ctx, query, orgID, externalID).Scan(&transferID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
 		}
+		return nil, err
 	}
-	return transfers, rows.Err()
+	return r.getUserTransfer(ctx, transferID, orgID)
 }
 
-func (r *sqlRepo) getUserTransfer(transferID string, orgID string) (*client.Transfer, error) {
-	query := `select transfer_id, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, processed_at, created_at
+func (r *sqlRepo) getUserTransfer(ctx context.Context, transferID string, orgID string) (*client.Transfer, error) {
+	query := `select ` + transferColumns + `
 from transfers
 where transfer_id = ? and organization = ? and deleted_at is null
 limit 1`
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	var returnCode *string
-	transfer := &client.Transfer{}
+	transfer, err := scanTransfer(stmt.QueryRowContext(ctx, transferID, orgID))
+	if err != nil || transfer.TransferID == "" {
+		return nil, err
+	}
+	if err := r.loadTraceNumbers(ctx, []*client.Transfer{transfer}); err != nil {
+		return nil, err
+	}
+	if err := r.loadFees(ctx, []*client.Transfer{transfer}); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	err = stmt.QueryRow(transferID, orgID).Scan(
+// scanTransfer reads a row of transferColumns from either *sql.Row or *sql.Rows.
+func scanTransfer(row scanner) (*client.Transfer, error) {
+	var returnCode, remittance, secCode, check, entryDescription, discretionaryData, retryOf *string
+	var expectedSettlement, actualSettlement, availableOn, externalID, groupID, mandateID *string
+	var retryAttempt *int32
+	transfer := &client.Transfer{}
+	err := row.Scan(
 		&transfer.TransferID,
 		&transfer.Amount.Currency,
 		&transfer.Amount.Value,
@@ -137,48 +285,152 @@ limit 1`
 		&returnCode,
 		&transfer.ProcessedAt,
 		&transfer.Created,
+		&remittance,
+		&secCode,
+		&check,
+		&entryDescription,
+		&discretionaryData,
+		&retryOf,
+		&retryAttempt,
+		&expectedSettlement,
+		&actualSettlement,
+		&availableOn,
+		&externalID,
+		&groupID,
+		&mandateID,
 	)
-	if transfer.TransferID == "" || err != nil {
+	if err != nil {
 		return nil, err
 	}
+	if remittance != nil && *remittance != "" {
+		if err := json.Unmarshal([]byte(*remittance), &transfer.Remittance); err != nil {
+			return nil, fmt.Errorf("transferID=%s reading remittance: %v", transfer.TransferID, err)
+		}
+	}
+	if secCode != nil {
+		transfer.StandardEntryClassCode = *secCode
+	}
+	if check != nil && *check != "" {
+		if err := json.Unmarshal([]byte(*check), &transfer.Check); err != nil {
+			return nil, fmt.Errorf("transferID=%s reading check details: %v", transfer.TransferID, err)
+		}
+	}
+	if entryDescription != nil {
+		transfer.CompanyEntryDescription = *entryDescription
+	}
+	if discretionaryData != nil {
+		transfer.CompanyDiscretionaryData = *discretionaryData
+	}
+	if retryOf != nil {
+		transfer.RetryOf = *retryOf
+	}
+	if retryAttempt != nil {
+		transfer.RetryAttempt = *retryAttempt
+	}
+	if expectedSettlement != nil {
+		transfer.ExpectedSettlementDate = *expectedSettlement
+	}
+	if actualSettlement != nil {
+		transfer.ActualSettlementDate = *actualSettlement
+	}
+	if availableOn != nil {
+		transfer.AvailableOn = *availableOn
+	}
+	if externalID != nil {
+		transfer.ExternalID = *externalID
+	}
+	if groupID != nil {
+		transfer.GroupID = *groupID
+	}
+	if mandateID != nil {
+		transfer.MandateID = *mandateID
+	}
+	if returnCode != nil {
+		transfer.ReturnCode = achx.ReturnCode(*returnCode)
+	}
+	return transfer, nil
+}
 
-	// query the trace table
-	// append the transfer if any tracenums
-	traceNumbers, err := r.getTraceNumbers(transferID)
+// loadTraceNumbers sets TraceNumbers on each Transfer with a single query.
+func (r *sqlRepo) loadTraceNumbers(ctx context.Context, transfers []*client.Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	transferIDs := make([]string, len(transfers))
+	for i := range transfers {
+		transferIDs[i] = transfers[i].TransferID
+	}
+	traceNumbers, err := r.getTraceNumbersByTransfer(ctx, transferIDs)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	for i := range traceNumbers {
-		transfer.TraceNumbers = append(transfer.TraceNumbers, traceNumbers[i])
+	for i := range transfers {
+		transfers[i].TraceNumbers = traceNumbers[transfers[i].TransferID]
 	}
-	if returnCode != nil {
-		if rc := ach.LookupReturnCode(*returnCode); rc != nil {
-			transfer.ReturnCode = &client.ReturnCode{
-				Code:        rc.Code,
-				Reason:      rc.Reason,
-				Description: rc.Description,
+	return nil
+}
+
+// loadFees sets Fee on each Transfer which was charged one with a single query.
+func (r *sqlRepo) loadFees(ctx context.Context, transfers []*client.Transfer) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+	transferIDs := make([]string, len(transfers))
+	for i := range transfers {
+		transferIDs[i] = transfers[i].TransferID
+	}
+	query := fmt.Sprintf(`select transfer_id, amount_currency, amount_value, collection, customer_id, account_id, trace_number
+from transfer_fees where transfer_id in (%s)`, database.Placeholders(len(transferIDs)))
+
+	fees := make(map[string]*client.TransferFee)
+	err := database.QueryRowsContext(ctx, r.db, "getFees", query, database.StringArgs(transferIDs), func(rows *sql.Rows) error {
+		var transferID string
+		var customerID, accountID, traceNumber *string
+		fee := &client.TransferFee{}
+		if err := rows.Scan(&transferID, &fee.Amount.Currency, &fee.Amount.Value, &fee.Collection, &customerID, &accountID, &traceNumber); err != nil {
+			return err
+		}
+		if customerID != nil && accountID != nil {
+			fee.Account = &client.Destination{
+				CustomerID: *customerID,
+				AccountID:  *accountID,
 			}
 		}
+		if traceNumber != nil {
+			fee.TraceNumber = *traceNumber
+		}
+		fees[transferID] = fee
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	return transfer, nil
+	for i := range transfers {
+		transfers[i].Fee = fees[transfers[i].TransferID]
+	}
+	return nil
 }
 
-func (r *sqlRepo) GetTransfer(transferID string) (*client.Transfer, error) {
+func (r *sqlRepo) GetTransfer(ctx context.Context, transferID string) (*client.Transfer, error) {
+	defer database.MeasureQuery("transfers", "GetTransfer")()
+
 	query := `select organization from transfers where transfer_id = ? and deleted_at is null limit 1`
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
 	orgID := ""
-	if err := stmt.QueryRow(transferID).Scan(&orgID); err != nil {
+	if err := stmt.QueryRowContext(ctx, transferID).Scan(&orgID); err != nil {
 		return nil, err
 	}
-	return r.getUserTransfer(transferID, orgID)
+	return r.getUserTransfer(ctx, transferID, orgID)
 }
 
 func (r *sqlRepo) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
+	defer database.MeasureQuery("transfers", "UpdateTransferStatus")()
+
 	query := `update transfers set status = ? where transfer_id = ? and deleted_at is null`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
@@ -190,179 +442,1262 @@ func (r *sqlRepo) UpdateTransferStatus(transferID string, status client.Transfer
 	return err
 }
 
-func (r *sqlRepo) WriteUserTransfer(orgID string, transfer *client.Transfer) error {
-	query := `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+// errDuplicateExternalID is returned when a Transfer is saved with an externalID the
+// organization already used.
+var errDuplicateExternalID = errors.New("externalID was already used")
 
-	_, err = stmt.Exec(
-		transfer.TransferID,
-		orgID,
-		transfer.Amount.Currency,
-		transfer.Amount.Value,
-		transfer.Source.CustomerID,
-		transfer.Source.AccountID,
-		transfer.Destination.CustomerID,
-		transfer.Destination.AccountID,
-		transfer.Description,
-		transfer.Status,
-		transfer.SameDay,
-		time.Now(),
-	)
-	return err
-}
+func (r *sqlRepo) WriteUserTransfer(orgID string, transfer *client.Transfer) error {
+	defer database.MeasureQuery("transfers", "WriteUserTransfer")()
 
-func (r *sqlRepo) deleteUserTransfer(orgID string, transferID string) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-
-	query := `select status from transfers where transfer_id = ? and organization = ? and deleted_at is null limit 1;`
-	stmt, err := tx.Prepare(query)
-	if err != nil {
+	if err := insertTransfer(tx, orgID, transfer); err != nil {
 		tx.Rollback()
 		return err
 	}
-	defer stmt.Close()
+	return tx.Commit()
+}
 
-	var status string
-	if err := stmt.QueryRow(transferID, orgID).Scan(&status); err != nil {
-		tx.Rollback()
-		if err == sql.ErrNoRows {
-			return nil
-		}
+// WriteUserTransfers saves several Transfers with their trace numbers in one transaction,
+// reusing prepared statements for each row.
+func (r *sqlRepo) WriteUserTransfers(orgID string, xfers []UserTransfer) error {
+	defer database.MeasureQuery("transfers", "WriteUserTransfers")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
 		return err
 	}
-	if !strings.EqualFold(status, string(client.PENDING)) {
-		tx.Rollback()
-		return fmt.Errorf("transferID=%s is not in PENDING status", transferID)
-	}
 
-	query = `update transfers set deleted_at = ?
-where transfer_id = ? and organization = ? and status = ? and deleted_at is null`
-	stmt, err = tx.Prepare(query)
+	xferStmt, err := tx.Prepare(insertTransferQuery)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	defer stmt.Close()
+	defer xferStmt.Close()
 
-	_, err = stmt.Exec(time.Now(), transferID, orgID, client.PENDING)
+	traceStmt, err := tx.Prepare(insertTraceNumberQuery)
 	if err != nil {
 		tx.Rollback()
-		if err == sql.ErrNoRows {
-			return nil
-		}
 		return err
 	}
+	defer traceStmt.Close()
 
+	now := time.Now()
+	for i := range xfers {
+		args, err := insertTransferArgs(orgID, xfers[i].Transfer, now)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := xferStmt.Exec(args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("transferID=%s: %v", xfers[i].Transfer.TransferID, err)
+		}
+		for j := range xfers[i].TraceNumbers {
+			if _, err := traceStmt.Exec(xfers[i].Transfer.TransferID, xfers[i].TraceNumbers[j]); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("transferID=%s trace number: %v", xfers[i].Transfer.TransferID, err)
+			}
+		}
+	}
 	return tx.Commit()
 }
 
-func (r *sqlRepo) SaveReturnCode(transferID string, returnCode string) error {
-	query := `update transfers set return_code = ? where transfer_id = ? and return_code is null and deleted_at is null`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+// createUserTransfer saves a Transfer with its origination in one transaction, so the messages
+// are only sent for saved Transfers and account history isn't left behind when saving fails.
+func (r *sqlRepo) createUserTransfer(orgID string, transfer *client.Transfer, orig origination) error {
+	defer database.MeasureQuery("transfers", "createUserTransfer")()
 
-	_, err = stmt.Exec(returnCode, transferID)
-	if err == sql.ErrNoRows {
+	return database.WithTx(context.Background(), r.db, func(tx *sql.Tx) error {
+		if err := insertTransfer(tx, orgID, transfer); err != nil {
+			return err
+		}
+		if err := insertOrigination(tx, orgID, transfer.TransferID, orig); err != nil {
+			return err
+		}
+		return pipeline.WriteOutbox(tx, orig.msgs)
+	})
+}
+
+func (r *sqlRepo) createTransferGroup(orgID string, transfers []*client.Transfer, origs []origination) error {
+	defer database.MeasureQuery("transfers", "createTransferGroup")()
+
+	return database.WithTx(context.Background(), r.db, func(tx *sql.Tx) error {
+		var msgs []pipeline.OutboxMessage
+		for i := range transfers {
+			if err := insertTransfer(tx, orgID, transfers[i]); err != nil {
+				return err
+			}
+			if err := insertOrigination(tx, orgID, transfers[i].TransferID, origs[i]); err != nil {
+				return err
+			}
+			msgs = append(msgs, origs[i].msgs...)
+		}
+		return pipeline.WriteOutbox(tx, msgs)
+	})
+}
+
+func (r *sqlRepo) getTransferGroup(ctx context.Context, orgID string, groupID string) ([]*client.Transfer, error) {
+	defer database.MeasureQuery("transfers", "getTransferGroup")()
+
+	query := `select ` + transferColumns + `
+from transfers
+where organization = ? and group_id = ? and deleted_at is null
+order by created_at asc`
+
+	var transfers []*client.Transfer
+	err := database.QueryRowsContext(ctx, r.db, "getTransferGroup", query, []interface{}{orgID, groupID}, func(rows *sql.Rows) error {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return err
+		}
+		transfers = append(transfers, transfer)
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return err
+	if err := r.loadTraceNumbers(ctx, transfers); err != nil {
+		return nil, err
+	}
+	if err := r.loadFees(ctx, transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
 }
 
-func (r *sqlRepo) saveTraceNumbers(transferID string, traceNumbers []string) error {
-	query := `insert into transfer_trace_numbers(transfer_id, trace_number) values (?, ?);`
-	tx, err := r.db.Begin()
-	if err != nil {
+func (r *sqlRepo) createReviewableTransfer(orgID string, transfer *client.Transfer, requestedBy string, orig origination) error {
+	defer database.MeasureQuery("transfers", "createReviewableTransfer")()
+
+	return database.WithTx(context.Background(), r.db, func(tx *sql.Tx) error {
+		if err := insertTransfer(tx, orgID, transfer); err != nil {
+			return err
+		}
+		query := `insert into transfer_approvals (transfer_id, organization, requested_by, requested_at) values (?, ?, ?, ?);`
+		if _, err := tx.Exec(query, transfer.TransferID, orgID, requestedBy, time.Now()); err != nil {
+			return err
+		}
+		if err := insertOrigination(tx, orgID, transfer.TransferID, orig); err != nil {
+			return err
+		}
+		return pipeline.HoldOutbox(tx, orig.msgs)
+	})
+}
+
+// insertOrigination saves the trace numbers, account history and fee of an originated Transfer.
+// Messages are saved by callers as they're held for some Transfers.
+func insertOrigination(tx *sql.Tx, orgID string, transferID string, orig origination) error {
+	if err := insertTraceNumbers(tx, transferID, orig.traceNumbers); err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare(query)
-	if err != nil {
-		tx.Rollback()
+	if err := insertFee(tx, orgID, transferID, orig.fee); err != nil {
 		return err
 	}
-	for i := range traceNumbers {
-		if _, err := stmt.Exec(transferID, traceNumbers[i]); err != nil {
-			tx.Rollback()
+	for i := range orig.history {
+		if err := insertAccountHistory(tx, orgID, orig.history[i]); err != nil {
 			return err
 		}
 	}
-	return tx.Commit()
+	return nil
 }
 
-func (r *sqlRepo) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
-	// To match returned files we take a few values which are assumed to uniquely identify a Transfer.
-	// traceNumber, per NACHA guidelines, should be globally unique (routing number + random value),
-	// but we are going to filter to only select Transfers created within a few days of the EffectiveEntryDate
-	// to avoid updating really old (or future, I suppose) objects.
-	query := `select xf.transfer_id, xf.organization from transfers as xf
-inner join transfer_trace_numbers trace on xf.transfer_id = trace.transfer_id
-where xf.amount_value = ? and trace.trace_number = ? and xf.status = ? and (xf.created_at > ? and xf.created_at < ?) and xf.deleted_at is null limit 1`
+func (r *sqlRepo) GetTransferApproval(transferID string) (*admin.TransferApproval, error) {
+	defer database.MeasureQuery("transfers", "GetTransferApproval")()
 
+	query := `select requested_by, requested_at, approved_by, approved_at from transfer_approvals where transfer_id = ? limit 1;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	transferId, orgID := "", ""
-	min, max := startOfDayAndTomorrow(effectiveEntryDate)
-	// Only include Transfer objects within 5 calendar days of the EffectiveEntryDate
-	min = min.Add(-5 * 24 * time.Hour)
-	max = max.Add(5 * 24 * time.Hour)
-
-	row := stmt.QueryRow(amount.Value, traceNumber, client.PROCESSED, min, max)
-	if err := row.Scan(&transferId, &orgID); err != nil {
+	approval := admin.TransferApproval{TransferID: transferID}
+	var approvedBy *string
+	err = stmt.QueryRow(transferID).Scan(&approval.RequestedBy, &approval.Requested, &approvedBy, &approval.Approved)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
 		return nil, err
 	}
-
-	return r.getUserTransfer(transferId, orgID)
-}
-
-// startOfDayAndTomorrow returns two time.Time values from a given time.Time value.
-// The first is at the start of the same day as provided and the second is exactly 24 hours
-// after the first.
-func startOfDayAndTomorrow(in time.Time) (time.Time, time.Time) {
-	start := in.Truncate(24 * time.Hour)
-	return start, start.Add(24 * time.Hour)
+	if approvedBy != nil {
+		approval.ApprovedBy = *approvedBy
+	}
+	return &approval, nil
 }
 
-func (r *sqlRepo) getTraceNumbers(transferID string) ([]string, error) {
-	var traceNumbers []string
-	query := `select trace_number from transfer_trace_numbers
-where transfer_id = ?`
+func (r *sqlRepo) approveTransfer(transferID string, approvedBy string) error {
+	defer database.MeasureQuery("transfers", "approveTransfer")()
 
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer stmt.Close()
-
-	args := []interface{}{transferID}
-	rows, err := stmt.Query(args...)
+	query := `update transfer_approvals set approved_by = ?, approved_at = ? where transfer_id = ? and approved_at is null;`
+	res, err := tx.Exec(query, approvedBy, time.Now(), transferID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var row string
-		if err := rows.Scan(&row); err != nil {
-			return traceNumbers, fmt.Errorf("getTraceNumbers scan: %v", err)
-		}
-		if row != "" {
-			traceNumbers = append(traceNumbers, row)
-		}
+		tx.Rollback()
+		return err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if n, _ := res.RowsAffected(); n != 1 {
+		tx.Rollback()
+		return errApprovalConflict
 	}
-	return traceNumbers, nil
+	query = `update transfers set status = ? where transfer_id = ? and status = ? and deleted_at is null;`
+	res, err = tx.Exec(query, client.PENDING, transferID, client.REVIEWABLE)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		tx.Rollback()
+		return errApprovalConflict
+	}
+	if err := pipeline.ReleaseOutbox(tx, transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const insertTransferQuery = `insert into transfers (transfer_id, organization, amount_currency, amount_value, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, remittance, standard_entry_class_code, check_details, company_entry_description, company_discretionary_data, retry_of, retry_attempt, expected_settlement_date, available_on, external_id, group_id, mandate_id, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+func insertTransfer(tx *sql.Tx, orgID string, transfer *client.Transfer) error {
+	args, err := insertTransferArgs(orgID, transfer, time.Now())
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(insertTransferQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(args...)
+	if err != nil && transfer.ExternalID != "" && database.UniqueViolation(err) {
+		return errDuplicateExternalID
+	}
+	return err
+}
+
+func insertTransferArgs(orgID string, transfer *client.Transfer, created time.Time) ([]interface{}, error) {
+	var remittance, check, secCode, entryDescription, discretionaryData, retryOf, expectedSettlement, availableOn, externalID, groupID, mandateID *string
+	var retryAttempt *int32
+	if transfer.Remittance != nil {
+		bs, err := json.Marshal(transfer.Remittance)
+		if err != nil {
+			return nil, err
+		}
+		v := string(bs)
+		remittance = &v
+	}
+	if transfer.Check != nil {
+		bs, err := json.Marshal(transfer.Check)
+		if err != nil {
+			return nil, err
+		}
+		v := string(bs)
+		check = &v
+	}
+	if transfer.StandardEntryClassCode != "" {
+		secCode = &transfer.StandardEntryClassCode
+	}
+	if transfer.CompanyEntryDescription != "" {
+		entryDescription = &transfer.CompanyEntryDescription
+	}
+	if transfer.CompanyDiscretionaryData != "" {
+		discretionaryData = &transfer.CompanyDiscretionaryData
+	}
+	if transfer.RetryOf != "" {
+		retryOf, retryAttempt = &transfer.RetryOf, &transfer.RetryAttempt
+	}
+	if transfer.ExpectedSettlementDate != "" {
+		expectedSettlement = &transfer.ExpectedSettlementDate
+	}
+	if transfer.AvailableOn != "" {
+		availableOn = &transfer.AvailableOn
+	}
+	if transfer.ExternalID != "" {
+		externalID = &transfer.ExternalID
+	}
+	if transfer.GroupID != "" {
+		groupID = &transfer.GroupID
+	}
+	if transfer.MandateID != "" {
+		mandateID = &transfer.MandateID
+	}
+
+	return []interface{}{
+		transfer.TransferID,
+		orgID,
+		transfer.Amount.Currency,
+		transfer.Amount.Value,
+		transfer.Source.CustomerID,
+		transfer.Source.AccountID,
+		transfer.Destination.CustomerID,
+		transfer.Destination.AccountID,
+		transfer.Description,
+		transfer.Status,
+		transfer.SameDay,
+		remittance,
+		secCode,
+		check,
+		entryDescription,
+		discretionaryData,
+		retryOf,
+		retryAttempt,
+		expectedSettlement,
+		availableOn,
+		externalID,
+		groupID,
+		mandateID,
+		created,
+	}, nil
+}
+
+// deleteUserTransfer removes a PENDING Transfer and saves msgs in the same transaction.
+func (r *sqlRepo) deleteUserTransfer(orgID string, transferID string, force bool, msgs []pipeline.OutboxMessage) error {
+	defer database.MeasureQuery("transfers", "deleteUserTransfer")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `select status from transfers where transfer_id = ? and organization = ? and deleted_at is null limit 1;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	var status string
+	if err := stmt.QueryRow(transferID, orgID).Scan(&status); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if !strings.EqualFold(status, string(client.PENDING)) {
+		tx.Rollback()
+		return fmt.Errorf("transferID=%s is not in PENDING status", transferID)
+	}
+
+	blockers, err := getInFlightMicroDeposits(tx, transferID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	transferIDs := []string{transferID}
+	if len(blockers) > 0 {
+		if !force {
+			tx.Rollback()
+			return &dependentsError{transferID: transferID, microDeposits: blockers}
+		}
+		for _, micro := range blockers {
+			for _, id := range micro.transferIDs {
+				if id != transferID {
+					transferIDs = append(transferIDs, id)
+					msgs = append(msgs, pipeline.CancelMessage(id))
+				}
+			}
+			query = `update micro_deposits set status = ? where micro_deposit_id = ? and deleted_at is null;`
+			if _, err := tx.Exec(query, client.FAILED, micro.microDepositID); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	query = `update transfers set deleted_at = ?
+where transfer_id = ? and organization = ? and status = ? and deleted_at is null`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, id := range transferIDs {
+		if _, err := stmt.Exec(now, id, orgID, client.PENDING); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// Queued Transfers are never originated once deleted
+		if _, err := tx.Exec(`delete from transfer_queue where transfer_id = ?;`, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := pipeline.WriteOutbox(tx, msgs); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// inFlightMicroDeposits are micro-deposits which haven't failed yet along with their PENDING Transfers.
+type inFlightMicroDeposits struct {
+	microDepositID string
+	transferIDs    []string
+}
+
+// dependentsError is returned when a Transfer can't be deleted without orphaning the
+// micro-deposits it was originated for.
+type dependentsError struct {
+	transferID    string
+	microDeposits []inFlightMicroDeposits
+}
+
+func (e *dependentsError) Error() string {
+	var blockers []string
+	for _, micro := range e.microDeposits {
+		blockers = append(blockers, fmt.Sprintf("microDepositID=%s (pending transfers: %s)", micro.microDepositID, strings.Join(micro.transferIDs, ", ")))
+	}
+	return fmt.Sprintf("transferID=%s is part of in-flight micro-deposits, delete with force=true to fail them: %s", e.transferID, strings.Join(blockers, "; "))
+}
+
+func getInFlightMicroDeposits(tx *sql.Tx, transferID string) ([]inFlightMicroDeposits, error) {
+	query := `select md.micro_deposit_id from micro_deposit_transfers mdt
+inner join micro_deposits md on md.micro_deposit_id = mdt.micro_deposit_id
+where mdt.transfer_id = ? and md.status <> ? and md.deleted_at is null;`
+	rows, err := tx.Query(query, transferID, client.FAILED)
+	if err != nil {
+		return nil, err
+	}
+	var out []inFlightMicroDeposits
+	for rows.Next() {
+		var micro inFlightMicroDeposits
+		if err := rows.Scan(&micro.microDepositID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, micro)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `select t.transfer_id from micro_deposit_transfers mdt
+inner join transfers t on t.transfer_id = mdt.transfer_id
+where mdt.micro_deposit_id = ? and t.status = ? and t.deleted_at is null order by t.transfer_id;`
+	for i := range out {
+		rows, err := tx.Query(query, out[i].microDepositID, client.PENDING)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out[i].transferIDs = append(out[i].transferIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) enqueueUserTransfer(orgID string, transfer *client.Transfer) error {
+	defer database.MeasureQuery("transfers", "enqueueUserTransfer")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertTransfer(tx, orgID, transfer); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query := `insert into transfer_queue (transfer_id, organization, attempts, created_at) values (?, ?, 0, ?);`
+	if _, err := tx.Exec(query, transfer.TransferID, orgID, time.Now()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) enqueueRetryTransfer(originalID string, retry *client.Transfer, availableAt time.Time) error {
+	defer database.MeasureQuery("transfers", "enqueueRetryTransfer")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	var orgID string
+	query := `select organization from transfers where transfer_id = ? and deleted_at is null limit 1;`
+	if err := tx.QueryRow(query, originalID).Scan(&orgID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("reading transferID=%s organization: %v", originalID, err)
+	}
+	if err := insertTransfer(tx, orgID, retry); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `insert into transfer_queue (transfer_id, organization, attempts, available_at, created_at) values (?, ?, 0, ?, ?);`
+	if _, err := tx.Exec(query, retry.TransferID, orgID, availableAt, time.Now()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// claimQueuedTransfers returns up to limit queued Transfers which aren't claimed by another
+// worker. Claims older than queueClaimTTL were left by a worker which stopped and are taken over.
+// Retries aren't claimed until they're available.
+func (r *sqlRepo) claimQueuedTransfers(limit int) ([]queuedTransfer, error) {
+	defer database.MeasureQuery("transfers", "claimQueuedTransfers")()
+
+	now := time.Now()
+	staleBefore := now.Add(-1 * queueClaimTTL)
+
+	query := `select transfer_id, organization, attempts from transfer_queue
+where (claimed_at is null or claimed_at < ?) and (available_at is null or available_at <= ?) order by created_at asc limit ?;`
+	var candidates []queuedTransfer
+	err := database.QueryRows(r.db, "queued transfers", query, []interface{}{staleBefore, now, limit}, func(rows *sql.Rows) error {
+		var item queuedTransfer
+		if err := rows.Scan(&item.transferID, &item.orgID, &item.attempts); err != nil {
+			return err
+		}
+		candidates = append(candidates, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Another instance could claim the same rows, so only keep those we updated
+	query = `update transfer_queue set claimed_at = ?, attempts = attempts + 1
+where transfer_id = ? and (claimed_at is null or claimed_at < ?);`
+	var claimed []queuedTransfer
+	for i := range candidates {
+		res, err := r.db.Exec(query, now, candidates[i].transferID, staleBefore)
+		if err != nil {
+			return claimed, fmt.Errorf("claiming transferID=%s: %v", candidates[i].transferID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			candidates[i].attempts++
+			claimed = append(claimed, candidates[i])
+		}
+	}
+	return claimed, nil
+}
+
+// completeQueuedTransfer removes the Transfer from the queue in the same transaction as its
+// messages are saved, so files are published once even if a claim is taken over.
+func (r *sqlRepo) completeQueuedTransfer(transfer *client.Transfer, orig origination) error {
+	defer database.MeasureQuery("transfers", "completeQueuedTransfer")()
+
+	return database.WithTx(context.Background(), r.db, func(tx *sql.Tx) error {
+		var orgID string
+		query := `select organization from transfers where transfer_id = ? limit 1;`
+		if err := tx.QueryRow(query, transfer.TransferID).Scan(&orgID); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`delete from transfer_queue where transfer_id = ?;`, transfer.TransferID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			// The Transfer was deleted or originated by another worker
+			return nil
+		}
+		if transfer.ExpectedSettlementDate != "" {
+			var availableOn *string
+			if transfer.AvailableOn != "" {
+				availableOn = &transfer.AvailableOn
+			}
+			query := `update transfers set expected_settlement_date = ?, available_on = ? where transfer_id = ?;`
+			if _, err := tx.Exec(query, transfer.ExpectedSettlementDate, availableOn, transfer.TransferID); err != nil {
+				return err
+			}
+		}
+		if err := insertOrigination(tx, orgID, transfer.TransferID, orig); err != nil {
+			return err
+		}
+		return pipeline.WriteOutbox(tx, orig.msgs)
+	})
+}
+
+func (r *sqlRepo) retryQueuedTransfer(transferID string) error {
+	defer database.MeasureQuery("transfers", "retryQueuedTransfer")()
+
+	_, err := r.db.Exec(`update transfer_queue set claimed_at = null where transfer_id = ?;`, transferID)
+	return err
+}
+
+func (r *sqlRepo) failQueuedTransfer(transferID string) error {
+	defer database.MeasureQuery("transfers", "failQueuedTransfer")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `update transfers set status = ? where transfer_id = ? and deleted_at is null;`
+	if _, err := tx.Exec(query, client.FAILED, transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`delete from transfer_queue where transfer_id = ?;`, transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getAvailableTransfers(day string, limit int) ([]availableTransfer, error) {
+	defer database.MeasureQuery("transfers", "getAvailableTransfers")()
+
+	query := `select transfer_id, organization from transfers
+where status = ? and available_on <= ? and funds_available_at is null and deleted_at is null order by available_on asc limit ?;`
+	var out []availableTransfer
+	err := database.QueryRows(r.db, "available transfers", query, []interface{}{client.PROCESSED, day, limit}, func(rows *sql.Rows) error {
+		var item availableTransfer
+		if err := rows.Scan(&item.transferID, &item.orgID); err != nil {
+			return err
+		}
+		out = append(out, item)
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) setFundsAvailable(transferID string, available bool) (bool, error) {
+	defer database.MeasureQuery("transfers", "setFundsAvailable")()
+
+	query := `update transfers set funds_available_at = ? where transfer_id = ? and funds_available_at is null;`
+	args := []interface{}{time.Now(), transferID}
+	if !available {
+		query = `update transfers set funds_available_at = null where transfer_id = ? and funds_available_at is not null;`
+		args = []interface{}{transferID}
+	}
+	res, err := r.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *sqlRepo) SaveReturnCode(transferID string, returnCode string) error {
+	defer database.MeasureQuery("transfers", "SaveReturnCode")()
+
+	query := `update transfers set return_code = ? where transfer_id = ? and return_code is null and deleted_at is null`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(returnCode, transferID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+func (r *sqlRepo) saveTraceNumbers(transferID string, traceNumbers []string) error {
+	defer database.MeasureQuery("transfers", "saveTraceNumbers")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertTraceNumbers(tx, transferID, traceNumbers); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const insertTraceNumberQuery = `insert into transfer_trace_numbers(transfer_id, trace_number) values (?, ?);`
+
+func insertTraceNumbers(tx *sql.Tx, transferID string, traceNumbers []string) error {
+	stmt, err := tx.Prepare(insertTraceNumberQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range traceNumbers {
+		if _, err := stmt.Exec(transferID, traceNumbers[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertFee saves the fee charged on a Transfer, which is the ledger's posting when it's
+// collected as one.
+func insertFee(tx *sql.Tx, orgID string, transferID string, fee *client.TransferFee) error {
+	if fee == nil {
+		return nil
+	}
+	var customerID, accountID, traceNumber *string
+	if fee.Account != nil {
+		customerID, accountID = &fee.Account.CustomerID, &fee.Account.AccountID
+	}
+	if fee.TraceNumber != "" {
+		traceNumber = &fee.TraceNumber
+	}
+	query := `insert into transfer_fees (transfer_id, organization, amount_currency, amount_value, collection, customer_id, account_id, trace_number, created_at)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := tx.Exec(query, transferID, orgID, fee.Amount.Currency, fee.Amount.Value, fee.Collection, customerID, accountID, traceNumber, time.Now())
+	return err
+}
+
+func (r *sqlRepo) LookupTransferFromReturn(amount client.Amount, traceNumber string, effectiveEntryDate time.Time) (*client.Transfer, error) {
+	defer database.MeasureQuery("transfers", "LookupTransferFromReturn")()
+
+	// To match returned files we take a few values which are assumed to uniquely identify a Transfer.
+	// traceNumber, per NACHA guidelines, should be globally unique (routing number + random value),
+	// but we are going to filter to only select Transfers created within a few days of the EffectiveEntryDate
+	// to avoid updating really old (or future, I suppose) objects.
+	query := `select xf.transfer_id, xf.organization from transfers as xf
+inner join transfer_trace_numbers trace on xf.transfer_id = trace.transfer_id
+where xf.amount_value = ? and trace.trace_number = ? and xf.status = ? and (xf.created_at > ? and xf.created_at < ?) and xf.deleted_at is null limit 1`
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	transferId, orgID := "", ""
+	min, max := startOfDayAndTomorrow(effectiveEntryDate)
+	// Only include Transfer objects within 5 calendar days of the EffectiveEntryDate
+	min = min.Add(-5 * 24 * time.Hour)
+	max = max.Add(5 * 24 * time.Hour)
+
+	row := stmt.QueryRow(amount.Value, traceNumber, client.PROCESSED, min, max)
+	if err := row.Scan(&transferId, &orgID); err != nil {
+		return nil, err
+	}
+
+	return r.getUserTransfer(context.Background(), transferId, orgID)
+}
+
+// startOfDayAndTomorrow returns two time.Time values from a given time.Time value.
+// The first is at the start of the same day as provided and the second is exactly 24 hours
+// after the first.
+func startOfDayAndTomorrow(in time.Time) (time.Time, time.Time) {
+	start := in.Truncate(24 * time.Hour)
+	return start, start.Add(24 * time.Hour)
+}
+
+func (r *sqlRepo) getTraceNumbers(ctx context.Context, transferID string) ([]string, error) {
+	defer database.MeasureQuery("transfers", "getTraceNumbers")()
+
+	traceNumbers, err := r.getTraceNumbersByTransfer(ctx, []string{transferID})
+	if err != nil {
+		return nil, err
+	}
+	return traceNumbers[transferID], nil
+}
+
+func (r *sqlRepo) getTraceNumbersByTransfer(ctx context.Context, transferIDs []string) (map[string][]string, error) {
+	query := fmt.Sprintf(`select transfer_id, trace_number from transfer_trace_numbers
+where transfer_id in (%s)`, database.Placeholders(len(transferIDs)))
+
+	out := make(map[string][]string)
+	err := database.QueryRowsContext(ctx, r.db, "getTraceNumbers", query, database.StringArgs(transferIDs), func(rows *sql.Rows) error {
+		var transferID, traceNumber string
+		if err := rows.Scan(&transferID, &traceNumber); err != nil {
+			return err
+		}
+		if traceNumber != "" {
+			out[transferID] = append(out[transferID], traceNumber)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) saveTransferEntries(transferID string, entries []client.TransferEntry) error {
+	defer database.MeasureQuery("transfers", "saveTransferEntries")()
+
+	query := `insert into transfer_entries(transfer_id, filename, batch_number, trace_number, entry_detail, addenda, created_at) values (?, ?, ?, ?, ?, ?, ?);`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i := range entries {
+		e := entries[i]
+		addenda := strings.Join(e.Addenda, "\n")
+		if _, err := stmt.Exec(transferID, e.Filename, e.BatchNumber, e.TraceNumber, e.EntryDetail, addenda, e.UploadedAt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getTransferEntries(ctx context.Context, transferID string) ([]client.TransferEntry, error) {
+	defer database.MeasureQuery("transfers", "getTransferEntries")()
+
+	query := `select filename, batch_number, trace_number, entry_detail, addenda, created_at from transfer_entries
+where transfer_id = ? order by created_at, filename, batch_number, trace_number`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]client.TransferEntry, 0)
+	for rows.Next() {
+		var e client.TransferEntry
+		var addenda string
+		if err := rows.Scan(&e.Filename, &e.BatchNumber, &e.TraceNumber, &e.EntryDetail, &addenda, &e.UploadedAt); err != nil {
+			return nil, err
+		}
+		e.Addenda = make([]string, 0)
+		if addenda != "" {
+			e.Addenda = strings.Split(addenda, "\n")
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *sqlRepo) getTransferFile(ctx context.Context, transferID string) (*client.TransferFile, error) {
+	entries, err := r.getTransferEntries(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	file := newTransferFile(entries)
+	if file == nil {
+		return nil, nil
+	}
+
+	uploads, err := r.getUploadAttempts(ctx, file.Filename)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := false
+	for i := range uploads {
+		if uploads[i].Error != "" {
+			continue
+		}
+		if !uploaded {
+			file.UploadedAt = uploads[i].AttemptedAt
+			uploaded = true
+		}
+		file.Acknowledged = file.Acknowledged || uploads[i].Verified
+	}
+	return file, nil
+}
+
+func (r *sqlRepo) getFileContents(ctx context.Context, filename string) (*admin.FileContents, error) {
+	defer database.MeasureQuery("transfers", "getFileContents")()
+
+	query := `select e.batch_number, e.trace_number, e.transfer_id, m.micro_deposit_id from transfer_entries as e
+left join micro_deposit_transfers as m on e.transfer_id = m.transfer_id
+where e.filename = ? order by e.batch_number, e.trace_number`
+
+	contents := &admin.FileContents{
+		Filename: filename,
+		Batches:  make([]admin.FileBatch, 0),
+	}
+	err := database.QueryRowsContext(ctx, r.db, "getFileContents", query, []interface{}{filename}, func(rows *sql.Rows) error {
+		var batchNumber int32
+		var entry admin.FileEntry
+		var microDepositID *string
+		if err := rows.Scan(&batchNumber, &entry.TraceNumber, &entry.TransferID, &microDepositID); err != nil {
+			return err
+		}
+		if microDepositID != nil {
+			entry.MicroDepositID = *microDepositID
+		}
+		if n := len(contents.Batches); n == 0 || contents.Batches[n-1].BatchNumber != batchNumber {
+			contents.Batches = append(contents.Batches, admin.FileBatch{BatchNumber: batchNumber})
+		}
+		batch := &contents.Batches[len(contents.Batches)-1]
+		batch.Entries = append(batch.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contents.Uploads, err = r.getUploadAttempts(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(contents.Batches) == 0 && len(contents.Uploads) == 0 {
+		return nil, nil
+	}
+	return contents, nil
+}
+
+// getUploadAttempts returns each attempt to upload filename as recorded by the pipeline, oldest first.
+func (r *sqlRepo) getUploadAttempts(ctx context.Context, filename string) ([]admin.UploadAttempt, error) {
+	defer database.MeasureQuery("transfers", "getUploadAttempts")()
+
+	query := `select file_hash, size, outbound_path, error, verified, processed_at from file_archive
+where filename = ? and kind = 'outbound' order by processed_at`
+
+	attempts := make([]admin.UploadAttempt, 0)
+	err := database.QueryRowsContext(ctx, r.db, "getUploadAttempts", query, []interface{}{filename}, func(rows *sql.Rows) error {
+		attempt := admin.UploadAttempt{Filename: filename}
+		var outboundPath, errMsg *string
+		if err := rows.Scan(&attempt.FileHash, &attempt.Size, &outboundPath, &errMsg, &attempt.Verified, &attempt.AttemptedAt); err != nil {
+			return err
+		}
+		if outboundPath != nil {
+			attempt.OutboundPath = *outboundPath
+		}
+		if errMsg != nil {
+			attempt.Error = *errMsg
+		}
+		attempts = append(attempts, attempt)
+		return nil
+	})
+	return attempts, err
+}
+
+func (r *sqlRepo) recordAccountNumberReveal(transferID, userID, reason string) error {
+	defer database.MeasureQuery("transfers", "recordAccountNumberReveal")()
+
+	query := `insert into account_number_reveals(reveal_id, transfer_id, user_id, reason, created_at) values (?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, base.ID(), transferID, userID, reason, time.Now())
+	return err
+}
+
+func (r *sqlRepo) saveAccountHistory(orgID string, history client.AccountHistory) error {
+	defer database.MeasureQuery("transfers", "saveAccountHistory")()
+
+	return database.WithTx(context.Background(), r.db, func(tx *sql.Tx) error {
+		return insertAccountHistory(tx, orgID, history)
+	})
+}
+
+// insertAccountHistory saves history unless the account's latest details are the same.
+func insertAccountHistory(tx *sql.Tx, orgID string, history client.AccountHistory) error {
+	query := `select customer_id, holder_name, routing_number, masked_account_number, account_type from account_history
+where organization = ? and account_id = ? order by effective_from desc limit 1;`
+	var latest client.AccountHistory
+	err := tx.QueryRow(query, orgID, history.AccountID).Scan(&latest.CustomerID, &latest.HolderName, &latest.RoutingNumber, &latest.MaskedAccountNumber, &latest.Type)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && sameAccountDetails(latest, history) {
+		return nil
+	}
+
+	query = `insert into account_history (history_id, organization, customer_id, account_id, holder_name, routing_number,
+masked_account_number, account_type, transfer_id, effective_from) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err = tx.Exec(query, base.ID(), orgID, history.CustomerID, history.AccountID, history.HolderName, history.RoutingNumber,
+		history.MaskedAccountNumber, history.Type, history.TransferID, history.EffectiveFrom)
+	return err
+}
+
+func (r *sqlRepo) getAccountHistory(ctx context.Context, orgID string, accountID string) ([]client.AccountHistory, error) {
+	defer database.MeasureQuery("transfers", "getAccountHistory")()
+
+	query := `select customer_id, account_id, holder_name, routing_number, masked_account_number, account_type, transfer_id, effective_from
+from account_history where organization = ? and account_id = ? order by effective_from;`
+
+	var out []client.AccountHistory
+	err := database.QueryRowsContext(ctx, r.db, "account history", query, []interface{}{orgID, accountID}, func(rows *sql.Rows) error {
+		var h client.AccountHistory
+		if err := rows.Scan(&h.CustomerID, &h.AccountID, &h.HolderName, &h.RoutingNumber, &h.MaskedAccountNumber, &h.Type, &h.TransferID, &h.EffectiveFrom); err != nil {
+			return err
+		}
+		out = append(out, h)
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) getHoldingTotals(ctx context.Context, orgID string, accountID string) (holdingTotals, error) {
+	defer database.MeasureQuery("transfers", "getHoldingTotals")()
+
+	query := `select
+coalesce(sum(case when destination_account_id = ? and status = ? then amount_value else 0 end), 0),
+coalesce(sum(case when destination_account_id = ? and status in (?, ?) then amount_value else 0 end), 0),
+coalesce(sum(case when source_account_id = ? and status not in (?, ?) then amount_value else 0 end), 0)
+from transfers where organization = ? and (source_account_id = ? or destination_account_id = ?) and deleted_at is null;`
+
+	var totals holdingTotals
+	err := r.db.QueryRowContext(ctx, query,
+		accountID, client.PROCESSED,
+		accountID, client.PENDING, client.REVIEWABLE,
+		accountID, client.FAILED, client.CANCELED,
+		orgID, accountID, accountID,
+	).Scan(&totals.collected, &totals.pendingCollections, &totals.paidOut)
+	return totals, err
+}
+
+const mandateColumns = `mandate_id, customer_id, account_id, standard_entry_class_code, authorized_at,
+max_amount_currency, max_amount_value, status, revoked_at, revocation_reason, created_at`
+
+func (r *sqlRepo) createMandate(orgID string, mandate *client.Mandate) error {
+	defer database.MeasureQuery("transfers", "createMandate")()
+
+	var currency *string
+	var value *int32
+	if mandate.MaxAmount != nil {
+		currency, value = &mandate.MaxAmount.Currency, &mandate.MaxAmount.Value
+	}
+	query := `insert into mandates (mandate_id, organization, customer_id, account_id, standard_entry_class_code, authorized_at,
+max_amount_currency, max_amount_value, status, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, mandate.MandateID, orgID, mandate.CustomerID, mandate.AccountID, mandate.StandardEntryClassCode,
+		mandate.AuthorizedAt, currency, value, mandate.Status, mandate.Created)
+	return err
+}
+
+func (r *sqlRepo) getMandate(ctx context.Context, orgID string, mandateID string) (*client.Mandate, error) {
+	defer database.MeasureQuery("transfers", "getMandate")()
+
+	query := `select ` + mandateColumns + ` from mandates where organization = ? and mandate_id = ? limit 1;`
+	mandate, err := scanMandate(r.db.QueryRowContext(ctx, query, orgID, mandateID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return mandate, err
+}
+
+func (r *sqlRepo) getMandates(ctx context.Context, orgID string, customerID string, status client.MandateStatus) ([]*client.Mandate, error) {
+	defer database.MeasureQuery("transfers", "getMandates")()
+
+	query := `select ` + mandateColumns + ` from mandates where organization = ?`
+	args := []interface{}{orgID}
+	if customerID != "" {
+		query += ` and customer_id = ?`
+		args = append(args, customerID)
+	}
+	if status != "" {
+		query += ` and status = ?`
+		args = append(args, status)
+	}
+	query += ` order by created_at desc;`
+
+	var out []*client.Mandate
+	err := database.QueryRowsContext(ctx, r.db, "mandates", query, args, func(rows *sql.Rows) error {
+		mandate, err := scanMandate(rows)
+		if err != nil {
+			return err
+		}
+		out = append(out, mandate)
+		return nil
+	})
+	return out, err
+}
+
+// scanMandate reads a row of mandateColumns from either *sql.Row or *sql.Rows.
+func scanMandate(row scanner) (*client.Mandate, error) {
+	var currency, reason *string
+	var value *int32
+	mandate := &client.Mandate{}
+	err := row.Scan(
+		&mandate.MandateID,
+		&mandate.CustomerID,
+		&mandate.AccountID,
+		&mandate.StandardEntryClassCode,
+		&mandate.AuthorizedAt,
+		&currency,
+		&value,
+		&mandate.Status,
+		&mandate.RevokedAt,
+		&reason,
+		&mandate.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if currency != nil && value != nil {
+		mandate.MaxAmount = &client.Amount{
+			Currency: *currency,
+			Value:    *value,
+		}
+	}
+	if reason != nil {
+		mandate.RevocationReason = *reason
+	}
+	return mandate, nil
+}
+
+func (r *sqlRepo) revokeMandate(orgID string, mandateID string, reason string) (bool, error) {
+	defer database.MeasureQuery("transfers", "revokeMandate")()
+
+	query := `update mandates set status = ?, revoked_at = ?, revocation_reason = ? where organization = ? and mandate_id = ? and status = ?;`
+	res, err := r.db.Exec(query, client.REVOKED, time.Now(), reason, orgID, mandateID, client.ACTIVE)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+const evidenceColumns = `evidence_id, mandate_id, transfer_id, content_type, filename, size, url, created_at`
+
+func (r *sqlRepo) createEvidence(orgID string, evidence *client.AuthorizationEvidence) error {
+	defer database.MeasureQuery("transfers", "createEvidence")()
+
+	// Evidence is attached to either a mandate or a Transfer
+	var mandateID, transferID *string
+	if evidence.MandateID != "" {
+		mandateID = &evidence.MandateID
+	}
+	if evidence.TransferID != "" {
+		transferID = &evidence.TransferID
+	}
+	query := `insert into authorization_evidence (evidence_id, organization, mandate_id, transfer_id, content_type, filename, size, url, created_at)
+values (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := r.db.Exec(query, evidence.EvidenceID, orgID, mandateID, transferID,
+		evidence.ContentType, evidence.Filename, evidence.Size, evidence.Url, evidence.Created)
+	return err
+}
+
+func (r *sqlRepo) getEvidence(ctx context.Context, evidenceID string) (*client.AuthorizationEvidence, error) {
+	defer database.MeasureQuery("transfers", "getEvidence")()
+
+	query := `select ` + evidenceColumns + ` from authorization_evidence where evidence_id = ? limit 1;`
+	evidence, err := scanEvidence(r.db.QueryRowContext(ctx, query, evidenceID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return evidence, err
+}
+
+func (r *sqlRepo) getTransferEvidence(ctx context.Context, transferID string) ([]client.AuthorizationEvidence, error) {
+	defer database.MeasureQuery("transfers", "getTransferEvidence")()
+
+	query := `select ` + evidenceColumns + ` from authorization_evidence
+where transfer_id = ? or mandate_id = (select mandate_id from transfers where transfer_id = ?) order by created_at;`
+
+	var out []client.AuthorizationEvidence
+	err := database.QueryRowsContext(ctx, r.db, "authorization evidence", query, []interface{}{transferID, transferID}, func(rows *sql.Rows) error {
+		evidence, err := scanEvidence(rows)
+		if err != nil {
+			return err
+		}
+		out = append(out, *evidence)
+		return nil
+	})
+	return out, err
+}
+
+// scanEvidence reads a row of evidenceColumns from either *sql.Row or *sql.Rows.
+func scanEvidence(row scanner) (*client.AuthorizationEvidence, error) {
+	var mandateID, transferID *string
+	evidence := &client.AuthorizationEvidence{}
+	err := row.Scan(
+		&evidence.EvidenceID,
+		&mandateID,
+		&transferID,
+		&evidence.ContentType,
+		&evidence.Filename,
+		&evidence.Size,
+		&evidence.Url,
+		&evidence.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if mandateID != nil {
+		evidence.MandateID = *mandateID
+	}
+	if transferID != nil {
+		evidence.TransferID = *transferID
+	}
+	return evidence, nil
+}
+
+func (r *sqlRepo) SaveReturnEntry(transferID string, header *ach.BatchHeader, entry *ach.EntryDetail) error {
+	defer database.MeasureQuery("transfers", "SaveReturnEntry")()
+
+	if header == nil || entry == nil || entry.Addenda99 == nil {
+		return fmt.Errorf("transferID=%s missing return entry", transferID)
+	}
+
+	query := `insert into transfer_returns(transfer_id, batch_header, entry_detail, addenda, created_at) values (?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(transferID, header.String(), entry.String(), entry.Addenda99.String(), time.Now())
+	return err
+}
+
+func (r *sqlRepo) GetReturnEntry(transferID string) (*ReturnEntry, error) {
+	defer database.MeasureQuery("transfers", "GetReturnEntry")()
+
+	query := `select batch_header, entry_detail, addenda, dishonored_return_code, created_at from transfer_returns
+where transfer_id = ? order by created_at desc limit 1`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var header, entry, addenda string
+	var dishonoredReturnCode *string
+	ret := &ReturnEntry{}
+	if err := stmt.QueryRow(transferID).Scan(&header, &entry, &addenda, &dishonoredReturnCode, &ret.Created); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if dishonoredReturnCode != nil {
+		ret.DishonoredReturnCode = *dishonoredReturnCode
+	}
+	ret.Header, ret.Entry = parseReturnEntry(header, entry, addenda)
+	return ret, nil
+}
+
+// parseReturnEntry reads the records of a return saved by SaveReturnEntry.
+func parseReturnEntry(header, entry, addenda string) (*ach.BatchHeader, *ach.EntryDetail) {
+	bh := ach.NewBatchHeader()
+	bh.Parse(header)
+
+	ed := ach.NewEntryDetail()
+	ed.Parse(entry)
+	ed.Category = ach.CategoryReturn
+	ed.Addenda99 = ach.NewAddenda99()
+	ed.Addenda99.Parse(addenda)
+
+	return bh, ed
+}
+
+func (r *sqlRepo) SaveDishonoredReturnCode(transferID string, returnCode string) error {
+	defer database.MeasureQuery("transfers", "SaveDishonoredReturnCode")()
+
+	query := `update transfer_returns set dishonored_return_code = ? where transfer_id = ? and created_at = (
+  select max(created_at) from (select created_at from transfer_returns where transfer_id = ?) as latest);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(returnCode, transferID, transferID)
+	return err
 }
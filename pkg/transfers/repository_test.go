@@ -5,15 +5,22 @@
 package transfers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
 
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 )
 
 func TestRepository__getTransfers(t *testing.T) {
@@ -22,7 +29,7 @@ func TestRepository__getTransfers(t *testing.T) {
 	writeTransfer(t, orgID, repo)
 
 	params := readTransferFilterParams(&http.Request{})
-	xfers, err := repo.getTransfers(orgID, params)
+	xfers, err := repo.getTransfers(context.Background(), orgID, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -35,6 +42,27 @@ func TestRepository__getTransfers(t *testing.T) {
 	}
 }
 
+func TestRepository__canceledContext(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+	transfer := writeTransfer(t, orgID, repo)
+
+	// queries stop once the client has gone away
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	params := readTransferFilterParams(&http.Request{})
+	if _, err := repo.getTransfers(ctx, orgID, params); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := repo.GetUserTransfer(ctx, transfer.TransferID, orgID); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := repo.getTransferEntries(ctx, transfer.TransferID); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestRepository__getTransfersWithTraceNumbers(t *testing.T) {
 	orgID := base.ID()
 	repo := setupSQLiteDB(t)
@@ -46,7 +74,7 @@ func TestRepository__getTransfersWithTraceNumbers(t *testing.T) {
 	saveTraceNumbers(t, transfer, traceNumbers, repo)
 
 	params := readTransferFilterParams(&http.Request{})
-	xfers, err := repo.getTransfers(orgID, params)
+	xfers, err := repo.getTransfers(context.Background(), orgID, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -82,7 +110,7 @@ func TestRepository__getTransfersByStatus(t *testing.T) {
 
 	params := readTransferFilterParams(&http.Request{})
 	params.Status = wantStatus
-	xfers, err := repo.getTransfers(orgID, params)
+	xfers, err := repo.getTransfers(context.Background(), orgID, params)
 	if err != nil {
 		t.Fatalf("getting transfers: %v", err)
 	}
@@ -116,7 +144,7 @@ func TestRepository__getTransfersWithCustomerIDs(t *testing.T) {
 		xfers[len(xfers)-2].Source.CustomerID,
 		xfers[len(xfers)-1].Source.CustomerID,
 	}
-	got, err := repo.getTransfers(orgID, params)
+	got, err := repo.getTransfers(context.Background(), orgID, params)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -144,7 +172,7 @@ func TestRepository__UpdateTransferStatus(t *testing.T) {
 	repo := setupSQLiteDB(t)
 
 	xfer := writeTransfer(t, orgID, repo)
-	xfer, err := repo.GetTransfer(xfer.TransferID)
+	xfer, err := repo.GetTransfer(context.Background(), xfer.TransferID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -156,7 +184,7 @@ func TestRepository__UpdateTransferStatus(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	xfer, err = repo.GetTransfer(xfer.TransferID)
+	xfer, err = repo.GetTransfer(context.Background(), xfer.TransferID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -170,7 +198,7 @@ func TestRepository__WriteUserTransfer(t *testing.T) {
 	repo := setupSQLiteDB(t)
 
 	xfer := writeTransfer(t, orgID, repo)
-	if tt, err := repo.GetTransfer(xfer.TransferID); err != nil {
+	if tt, err := repo.GetTransfer(context.Background(), xfer.TransferID); err != nil {
 		t.Fatal(err)
 	} else {
 		if tt.TransferID == "" {
@@ -179,18 +207,173 @@ func TestRepository__WriteUserTransfer(t *testing.T) {
 	}
 }
 
+func TestRepository__WriteUserTransfers(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		var xfers []UserTransfer
+		for i := 0; i < 3; i++ {
+			xfers = append(xfers, UserTransfer{
+				Transfer: &client.Transfer{
+					TransferID:  base.ID(),
+					Amount:      client.Amount{Currency: "USD", Value: int32(i + 1)},
+					Description: "micro-deposit",
+					Status:      client.PENDING,
+				},
+				TraceNumbers: []string{fmt.Sprintf("12345678000000%d", i)},
+			})
+		}
+		if err := repo.WriteUserTransfers(orgID, xfers); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range xfers {
+			found, err := repo.GetUserTransfer(context.Background(), xfers[i].Transfer.TransferID, orgID)
+			if err != nil || found == nil {
+				t.Fatalf("transfer=%#v error=%v", found, err)
+			}
+			traces, err := repo.getTraceNumbers(context.Background(), found.TransferID)
+			if err != nil || len(traces) != 1 || traces[0] != xfers[i].TraceNumbers[0] {
+				t.Errorf("traces=%v error=%v", traces, err)
+			}
+		}
+
+		// a duplicate Transfer rolls back every Transfer
+		dup := []UserTransfer{
+			{Transfer: &client.Transfer{TransferID: base.ID(), Status: client.PENDING}},
+			{Transfer: xfers[0].Transfer},
+		}
+		if err := repo.WriteUserTransfers(orgID, dup); err == nil {
+			t.Fatal("expected error")
+		}
+		if found, _ := repo.GetTransfer(context.Background(), dup[0].Transfer.TransferID); found != nil {
+			t.Errorf("unexpected transfer: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__WriteUserTransferRemittance(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	xfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      client.Amount{Currency: "USD", Value: 1245},
+		Description: "invoices",
+		Status:      client.PENDING,
+		Remittance: &client.Remittance{
+			InvoiceNumbers: []string{"1001", "1002"},
+			Memo:           "March services",
+		},
+	}
+	if err := repo.WriteUserTransfer(orgID, xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.GetTransfer(context.Background(), xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Remittance == nil || len(found.Remittance.InvoiceNumbers) != 2 || found.Remittance.Memo != "March services" {
+		t.Errorf("unexpected remittance: %#v", found.Remittance)
+	}
+}
+
+func TestRepository__WriteUserTransferCheck(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	xfer := &client.Transfer{
+		TransferID:             base.ID(),
+		Amount:                 client.Amount{Currency: "USD", Value: 1245},
+		Description:            "check",
+		Status:                 client.PENDING,
+		StandardEntryClassCode: "POP",
+		Check: &client.CheckDetails{
+			SerialNumber:  "1005",
+			TerminalCity:  "PHIL",
+			TerminalState: "PA",
+		},
+		CompanyEntryDescription:  "PURCHASE",
+		CompanyDiscretionaryData: "STORE 12",
+	}
+	if err := repo.WriteUserTransfer(orgID, xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.GetTransfer(context.Background(), xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.StandardEntryClassCode != "POP" {
+		t.Errorf("unexpected SEC code: %q", found.StandardEntryClassCode)
+	}
+	if found.Check == nil || found.Check.SerialNumber != "1005" || found.Check.TerminalState != "PA" {
+		t.Errorf("unexpected check: %#v", found.Check)
+	}
+	if found.CompanyEntryDescription != "PURCHASE" || found.CompanyDiscretionaryData != "STORE 12" {
+		t.Errorf("CompanyEntryDescription=%q CompanyDiscretionaryData=%q", found.CompanyEntryDescription, found.CompanyDiscretionaryData)
+	}
+}
+
+func TestRepository__ReturnEntry(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bh := file.Batches[0].GetHeader()
+	entry := file.Batches[0].GetEntries()[0]
+
+	check := func(t *testing.T, repo Repository) {
+		xfer := writeTransfer(t, base.ID(), repo)
+
+		if ret, err := repo.GetReturnEntry(xfer.TransferID); err != nil || ret != nil {
+			t.Fatalf("unexpected return=%#v error=%v", ret, err)
+		}
+		if err := repo.SaveReturnEntry(xfer.TransferID, bh, entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SaveDishonoredReturnCode(xfer.TransferID, "R68"); err != nil {
+			t.Fatal(err)
+		}
+
+		ret, err := repo.GetReturnEntry(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret == nil || ret.DishonoredReturnCode != "R68" {
+			t.Fatalf("unexpected return: %#v", ret)
+		}
+		if ret.Header.String() != bh.String() || ret.Entry.String() != entry.String() {
+			t.Errorf("unexpected records:\n%s\n%s", ret.Header.String(), ret.Entry.String())
+		}
+		if ret.Entry.Addenda99 == nil || ret.Entry.Addenda99.String() != entry.Addenda99.String() {
+			t.Errorf("unexpected addenda: %#v", ret.Entry.Addenda99)
+		}
+	}
+
+	t.Run("sqlite", func(t *testing.T) {
+		check(t, setupSQLiteDB(t))
+	})
+	t.Run("memory", func(t *testing.T) {
+		check(t, NewInMemoryRepo())
+	})
+}
+
 func TestRepository__deleteUserTransfer(t *testing.T) {
 	orgID := base.ID()
 	transferID := base.ID()
 	repo := setupSQLiteDB(t)
 
-	if err := repo.deleteUserTransfer(orgID, transferID); err != nil {
+	if err := repo.deleteUserTransfer(orgID, transferID, false, nil); err != nil {
 		t.Fatal(err)
 	}
 
 	// Write a PENDING transfer and delete it
 	xfer := writeTransfer(t, orgID, repo)
-	if err := repo.deleteUserTransfer(orgID, xfer.TransferID); err != nil {
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, nil); err != nil {
 		t.Fatal(err)
 	}
 
@@ -199,7 +382,7 @@ func TestRepository__deleteUserTransfer(t *testing.T) {
 	if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
 		t.Fatal(err)
 	}
-	if err := repo.deleteUserTransfer(orgID, xfer.TransferID); err != nil {
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, nil); err != nil {
 		if !strings.Contains(err.Error(), "is not in PENDING status") {
 			t.Fatal(err)
 		}
@@ -208,6 +391,230 @@ func TestRepository__deleteUserTransfer(t *testing.T) {
 	}
 }
 
+func TestRepository__deleteUserTransferMicroDeposits(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	credit, debit := writeTransfer(t, orgID, repo), writeTransfer(t, orgID, repo)
+	microDepositID := base.ID()
+	if _, err := repo.db.Exec(`insert into micro_deposits (micro_deposit_id, status, created_at) values (?, ?, ?);`, microDepositID, client.PENDING, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{credit.TransferID, debit.TransferID} {
+		if _, err := repo.db.Exec(`insert into micro_deposit_transfers (micro_deposit_id, transfer_id) values (?, ?);`, microDepositID, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the micro-deposits block deleting one of their Transfers
+	err := repo.deleteUserTransfer(orgID, credit.TransferID, false, nil)
+	var derr *dependentsError
+	if !errors.As(err, &derr) || len(derr.microDeposits) != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(err.Error(), microDepositID) || !strings.Contains(err.Error(), debit.TransferID) {
+		t.Errorf("blockers missing from error: %v", err)
+	}
+	if xfer, err := repo.GetTransfer(context.Background(), credit.TransferID); err != nil || xfer == nil {
+		t.Fatalf("transfer=%#v error=%v", xfer, err)
+	}
+
+	// force fails the micro-deposits and deletes both Transfers
+	if err := repo.deleteUserTransfer(orgID, credit.TransferID, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{credit.TransferID, debit.TransferID} {
+		if xfer, _ := repo.GetTransfer(context.Background(), id); xfer != nil {
+			t.Errorf("transferID=%s wasn't deleted", id)
+		}
+	}
+	var status string
+	if err := repo.db.QueryRow(`select status from micro_deposits where micro_deposit_id = ?;`, microDepositID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != string(client.FAILED) {
+		t.Errorf("unexpected micro-deposit status: %s", status)
+	}
+
+	// the other Transfer is canceled along with it
+	pub := pipeline.NewMockPublisher()
+	if n, err := pipeline.NewOutbox(log.NewNopLogger(), repo.db, pub).Dispatch(); err != nil || n != 1 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+	if _, ok := pub.Cancels[debit.TransferID]; !ok {
+		t.Error("expected cancel")
+	}
+}
+
+func TestRepository__ExternalID(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		if xfer, err := repo.getTransferByExternalID(context.Background(), orgID, "invoice-1001"); err != nil || xfer != nil {
+			t.Fatalf("transfer=%#v error=%v", xfer, err)
+		}
+
+		xfer := &client.Transfer{
+			TransferID: base.ID(),
+			Amount: client.Amount{
+				Currency: "USD",
+				Value:    1245,
+			},
+			Description: "payroll",
+			Status:      client.PENDING,
+			Created:     time.Now(),
+			ExternalID:  "invoice-1001",
+		}
+		if err := repo.WriteUserTransfer(orgID, xfer); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.getTransferByExternalID(context.Background(), orgID, "invoice-1001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.TransferID != xfer.TransferID || found.ExternalID != "invoice-1001" {
+			t.Fatalf("unexpected transfer: %#v", found)
+		}
+
+		// externalIDs are unique within each organization
+		dup := *xfer
+		dup.TransferID = base.ID()
+		if err := repo.createUserTransfer(orgID, &dup, origination{}); err != errDuplicateExternalID {
+			t.Errorf("expected duplicate externalID: %v", err)
+		}
+		dup.TransferID = base.ID()
+		if err := repo.WriteUserTransfer(base.ID(), &dup); err != nil {
+			t.Errorf("other organization: %v", err)
+		}
+
+		// deleted Transfers keep their externalID
+		if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, nil); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := repo.getTransferByExternalID(context.Background(), orgID, "invoice-1001"); err != nil || found != nil {
+			t.Errorf("transfer=%#v error=%v", found, err)
+		}
+		dup.TransferID = base.ID()
+		if err := repo.WriteUserTransfer(orgID, &dup); err != errDuplicateExternalID {
+			t.Errorf("expected duplicate externalID: %v", err)
+		}
+
+		// Transfers without an externalID aren't limited
+		writeTransfer(t, orgID, repo)
+		writeTransfer(t, orgID, repo)
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRepository__createUserTransfer(t *testing.T) {
+	orgID := base.ID()
+	repo := setupSQLiteDB(t)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1245,
+		},
+		Description: "payroll",
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+	traces := traceNumbers([]*ach.File{file})
+	msgs := pipeline.UploadMessages(orgID, xfer, []*ach.File{file}, false)
+	history := client.AccountHistory{
+		AccountID:     base.ID(),
+		CustomerID:    base.ID(),
+		HolderName:    "Jane Doe",
+		RoutingNumber: "987654320",
+		Type:          "checking",
+		TransferID:    xfer.TransferID,
+		EffectiveFrom: time.Now(),
+	}
+	fee := &client.TransferFee{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    25,
+		},
+		Collection: client.POSTING,
+		Account: &client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+	}
+	orig := origination{
+		traceNumbers: traces,
+		msgs:         msgs,
+		history:      []client.AccountHistory{history},
+		fee:          fee,
+	}
+	if err := repo.createUserTransfer(orgID, xfer, orig); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.getTraceNumbers(context.Background(), xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(traces) {
+		t.Errorf("unexpected trace numbers: %v", found)
+	}
+	saved, err := repo.GetUserTransfer(context.Background(), xfer.TransferID, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Fee == nil || saved.Fee.Amount.Value != 25 || saved.Fee.Collection != client.POSTING || *saved.Fee.Account != *fee.Account {
+		t.Errorf("unexpected fee: %#v", saved.Fee)
+	}
+
+	// writing the Transfer again fails and leaves no messages or account history behind
+	history.HolderName = "Jane Smith"
+	if err := repo.createUserTransfer(orgID, xfer, origination{msgs: msgs, history: []client.AccountHistory{history}}); err == nil {
+		t.Fatal("expected error")
+	}
+	accountHistory, err := repo.getAccountHistory(context.Background(), orgID, history.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accountHistory) != 1 || accountHistory[0].HolderName != "Jane Doe" {
+		t.Errorf("unexpected account history: %#v", accountHistory)
+	}
+
+	pub := pipeline.NewMockPublisher()
+	outbox := pipeline.NewOutbox(log.NewNopLogger(), repo.db, pub)
+	if n, err := outbox.Dispatch(); err != nil || n != 1 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+	upload, ok := pub.Xfers[xfer.TransferID]
+	if !ok || upload.File == nil || len(upload.File.Batches) != len(file.Batches) {
+		t.Errorf("unexpected upload: %#v", upload)
+	}
+
+	// messages are only published once
+	if n, err := outbox.Dispatch(); err != nil || n != 0 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+
+	// the cancel is published with the delete
+	if err := repo.deleteUserTransfer(orgID, xfer.TransferID, false, []pipeline.OutboxMessage{pipeline.CancelMessage(xfer.TransferID)}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := outbox.Dispatch(); err != nil || n != 1 {
+		t.Fatalf("dispatched %d messages: %v", n, err)
+	}
+	if _, ok := pub.Cancels[xfer.TransferID]; !ok {
+		t.Error("expected cancel")
+	}
+}
+
 func setupSQLiteDB(t *testing.T) *sqlRepo {
 	db := database.CreateTestSqliteDB(t)
 	t.Cleanup(func() { db.Close() })
@@ -279,12 +686,12 @@ func TestTransfers__SaveReturnCode(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		xfer, err := repo.GetTransfer(xfer.TransferID)
+		xfer, err := repo.GetTransfer(context.Background(), xfer.TransferID)
 		if err != nil {
 			t.Fatal(err)
 		}
 		if xfer.ReturnCode.Code != returnCode {
-			t.Errorf("xfer.ReturnCode=%q", xfer.ReturnCode)
+			t.Errorf("xfer.ReturnCode=%#v", xfer.ReturnCode)
 		}
 	}
 
@@ -342,3 +749,227 @@ func TestStartOfDayAndTomorrow(t *testing.T) {
 		t.Errorf("max - min = %v", v)
 	}
 }
+
+func TestRepository__createTransferGroup(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID, groupID := base.ID(), base.ID()
+		var transfers []*client.Transfer
+		for i := 0; i < 2; i++ {
+			transfers = append(transfers, &client.Transfer{
+				TransferID: base.ID(),
+				Amount: client.Amount{
+					Currency: "USD",
+					Value:    int32(1000 + i),
+				},
+				Description: "payroll",
+				Status:      client.PENDING,
+				Created:     time.Now().Add(time.Duration(i) * time.Second),
+				GroupID:     groupID,
+			})
+		}
+		if err := repo.createTransferGroup(orgID, transfers, make([]origination, len(transfers))); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.getTransferGroup(context.Background(), orgID, groupID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 2 || found[0].TransferID != transfers[0].TransferID || found[1].GroupID != groupID {
+			t.Fatalf("unexpected transfers: %#v", found)
+		}
+
+		// other organizations can't read the group
+		if found, err := repo.getTransferGroup(context.Background(), base.ID(), groupID); err != nil || len(found) != 0 {
+			t.Errorf("transfers=%#v error=%v", found, err)
+		}
+
+		// groups with a Transfer which already exists aren't saved
+		fresh := *transfers[1]
+		fresh.TransferID = base.ID()
+		fresh.GroupID = base.ID()
+		again := []*client.Transfer{&fresh, transfers[0]}
+		if err := repo.createTransferGroup(orgID, again, make([]origination, len(again))); err == nil {
+			t.Fatal("expected error")
+		}
+		if found, err := repo.getTransferGroup(context.Background(), orgID, again[0].GroupID); err != nil || len(found) != 0 {
+			t.Errorf("transfers=%#v error=%v", found, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRepository__getHoldingTotals(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+
+		collection := writeTransfer(t, orgID, repo)
+		if err := repo.UpdateTransferStatus(collection.TransferID, client.PROCESSED); err != nil {
+			t.Fatal(err)
+		}
+		holding := collection.Destination.AccountID
+		writeTransfer(t, base.ID(), repo) // other organizations aren't counted
+
+		payout := *collection
+		payout.TransferID = base.ID()
+		payout.Source = client.Source{CustomerID: collection.Destination.CustomerID, AccountID: holding}
+		payout.Destination = client.Destination{CustomerID: base.ID(), AccountID: base.ID()}
+		payout.Amount.Value = 300
+		payout.Status = client.PENDING
+		if err := repo.WriteUserTransfer(orgID, &payout); err != nil {
+			t.Fatal(err)
+		}
+
+		// deleted Transfers aren't counted
+		deleted := payout
+		deleted.TransferID = base.ID()
+		if err := repo.WriteUserTransfer(orgID, &deleted); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.deleteUserTransfer(orgID, deleted.TransferID, false, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		totals, err := repo.getHoldingTotals(context.Background(), orgID, holding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if totals.collected != 1245 || totals.pendingCollections != 0 || totals.paidOut != 300 {
+			t.Errorf("unexpected totals: %#v", totals)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRepository__mandates(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		mandate := &client.Mandate{
+			MandateID:              base.ID(),
+			CustomerID:             base.ID(),
+			AccountID:              base.ID(),
+			StandardEntryClassCode: "WEB",
+			AuthorizedAt:           time.Now().Add(-1 * time.Hour).Truncate(time.Second),
+			MaxAmount:              &client.Amount{Currency: "USD", Value: 5000},
+			Status:                 client.ACTIVE,
+			Created:                time.Now().Truncate(time.Second),
+		}
+		if err := repo.createMandate(orgID, mandate); err != nil {
+			t.Fatal(err)
+		}
+		other := *mandate
+		other.MandateID = base.ID()
+		other.CustomerID = base.ID()
+		other.MaxAmount = nil
+		if err := repo.createMandate(orgID, &other); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.getMandate(context.Background(), orgID, mandate.MandateID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.MaxAmount == nil || found.MaxAmount.Value != 5000 || found.StandardEntryClassCode != "WEB" {
+			t.Fatalf("unexpected mandate: %#v", found)
+		}
+		if found, _ := repo.getMandate(context.Background(), base.ID(), mandate.MandateID); found != nil {
+			t.Errorf("found mandate of another organization: %#v", found)
+		}
+
+		mandates, err := repo.getMandates(context.Background(), orgID, mandate.CustomerID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mandates) != 1 || mandates[0].MandateID != mandate.MandateID {
+			t.Errorf("unexpected mandates: %#v", mandates)
+		}
+
+		if revoked, err := repo.revokeMandate(orgID, mandate.MandateID, "closed account"); err != nil || !revoked {
+			t.Fatalf("revoked=%v: %v", revoked, err)
+		}
+		if revoked, err := repo.revokeMandate(orgID, mandate.MandateID, "closed account"); err != nil || revoked {
+			t.Errorf("revoked again=%v: %v", revoked, err)
+		}
+
+		mandates, err = repo.getMandates(context.Background(), orgID, "", client.REVOKED)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mandates) != 1 || mandates[0].RevokedAt == nil || mandates[0].RevocationReason != "closed account" {
+			t.Errorf("unexpected mandates: %#v", mandates)
+		}
+		if mandates, _ := repo.getMandates(context.Background(), orgID, "", client.ACTIVE); len(mandates) != 1 || mandates[0].MaxAmount != nil {
+			t.Errorf("unexpected mandates: %#v", mandates)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRepository__evidence(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		xfer := writeTransfer(t, orgID, repo)
+
+		mandateEvidence := &client.AuthorizationEvidence{
+			EvidenceID: base.ID(),
+			MandateID:  base.ID(),
+			Url:        "https://example.com/signup/123",
+			Created:    time.Now().Add(-1 * time.Hour).Truncate(time.Second),
+		}
+		if err := repo.createEvidence(orgID, mandateEvidence); err != nil {
+			t.Fatal(err)
+		}
+		transferEvidence := &client.AuthorizationEvidence{
+			EvidenceID:  base.ID(),
+			TransferID:  xfer.TransferID,
+			ContentType: "application/pdf",
+			Filename:    "authorization.pdf",
+			Size:        1024,
+			Created:     time.Now().Truncate(time.Second),
+		}
+		if err := repo.createEvidence(orgID, transferEvidence); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.getEvidence(context.Background(), transferEvidence.EvidenceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.TransferID != xfer.TransferID || found.ContentType != "application/pdf" || found.Size != 1024 || found.MandateID != "" {
+			t.Fatalf("unexpected evidence: %#v", found)
+		}
+		if found, err := repo.getEvidence(context.Background(), base.ID()); err != nil || found != nil {
+			t.Errorf("unexpected evidence=%#v: %v", found, err)
+		}
+
+		// evidence of the mandate is only listed once the Transfer references it
+		evidence, err := repo.getTransferEvidence(context.Background(), xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(evidence) != 1 || evidence[0].EvidenceID != transferEvidence.EvidenceID {
+			t.Errorf("unexpected evidence: %#v", evidence)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
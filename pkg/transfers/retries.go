@@ -0,0 +1,92 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	reinitiatedTransfers = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "reinitiated_transfers",
+		Help: "Counter of returned Transfers queued to be reinitiated",
+	}, []string{"code"})
+)
+
+const (
+	// retryEntryDescription is the Company Entry Description NACHA requires on reinitiated entries
+	retryEntryDescription = "RETRY PYMT"
+
+	// reinitiateWindow is how long after the original entry NACHA allows it to be reinitiated
+	reinitiateWindow = 180 * 24 * time.Hour
+)
+
+// ReinitiateReturnedTransfer queues a retry of transfer when returnCode allows it to be
+// reinitiated (R01 or R09) and transfers.retries has attempts left. The retry copies the
+// original Transfer, links to it with RetryOf and is originated after the configured delay.
+// It returns nil when transfer isn't retried.
+func ReinitiateReturnedTransfer(cfg *config.Config, repo Repository, transfer *client.Transfer, returnCode string) (*client.Transfer, error) {
+	if transfer == nil || transfer.Check != nil {
+		// Returned check conversions are re-presented as RCK entries instead
+		return nil, nil
+	}
+	if code := achx.ReturnCode(returnCode); code == nil || !code.Reinitiate {
+		return nil, nil
+	}
+	if int(transfer.RetryAttempt) >= cfg.Transfers.Retries.MaxAttempts() {
+		return nil, nil
+	}
+
+	original := transfer
+	if transfer.RetryOf != "" {
+		xfer, err := repo.GetTransfer(context.Background(), transfer.RetryOf)
+		if err != nil {
+			return nil, fmt.Errorf("reading original transferID=%s: %v", transfer.RetryOf, err)
+		}
+		original = xfer
+	}
+	if time.Since(original.Created) > reinitiateWindow {
+		return nil, nil
+	}
+
+	retry := &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      original.Amount,
+		Source:      original.Source,
+		Destination: original.Destination,
+		Description: original.Description,
+		Status:      client.PENDING,
+		SameDay:     original.SameDay,
+		Remittance:  original.Remittance,
+		Created:     time.Now(),
+
+		StandardEntryClassCode: original.StandardEntryClassCode,
+
+		CompanyEntryDescription:  retryEntryDescription,
+		CompanyDiscretionaryData: original.CompanyDiscretionaryData,
+
+		RetryOf:      original.TransferID,
+		RetryAttempt: transfer.RetryAttempt + 1,
+		MandateID:    original.MandateID,
+	}
+	availableAt := time.Now().Add(cfg.Transfers.Retries.RetryDelay())
+	if err := repo.enqueueRetryTransfer(transfer.TransferID, retry, availableAt); err != nil {
+		return nil, fmt.Errorf("queueing retry of transferID=%s: %v", transfer.TransferID, err)
+	}
+	reinitiatedTransfers.With("code", returnCode).Add(1)
+
+	return retry, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func retriesConfig(delay time.Duration) *config.Config {
+	cfg := config.Empty()
+	cfg.Transfers.Retries = &config.TransferRetries{
+		Delay: delay,
+	}
+	return cfg
+}
+
+func TestReinitiateReturnedTransfer(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+		xfer.Status = client.FAILED
+
+		cfg := retriesConfig(time.Hour)
+		retry, err := ReinitiateReturnedTransfer(cfg, repo, xfer, "R01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry == nil || retry.RetryOf != xfer.TransferID || retry.RetryAttempt != 1 {
+			t.Fatalf("unexpected retry: %#v", retry)
+		}
+
+		found, err := repo.GetUserTransfer(context.Background(), retry.TransferID, orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.RetryOf != xfer.TransferID || found.RetryAttempt != 1 || found.Status != client.PENDING {
+			t.Errorf("unexpected retry: %#v", found)
+		}
+		if found.CompanyEntryDescription != "RETRY PYMT" || found.Amount != xfer.Amount || found.Destination != xfer.Destination {
+			t.Errorf("unexpected retry: %#v", found)
+		}
+
+		// The second retry is still linked to the original Transfer
+		retry, err = ReinitiateReturnedTransfer(cfg, repo, found, "R09")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry == nil || retry.RetryOf != xfer.TransferID || retry.RetryAttempt != 2 {
+			t.Fatalf("unexpected retry: %#v", retry)
+		}
+
+		// NACHA rules allow two retries
+		if retry, err := ReinitiateReturnedTransfer(cfg, repo, retry, "R01"); err != nil || retry != nil {
+			t.Errorf("retry=%#v error=%v", retry, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestReinitiateReturnedTransfer__skipped(t *testing.T) {
+	repo := &MockRepository{}
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Created:    time.Now(),
+	}
+
+	// retries aren't configured
+	if retry, err := ReinitiateReturnedTransfer(config.Empty(), repo, xfer, "R01"); err != nil || retry != nil {
+		t.Errorf("retry=%#v error=%v", retry, err)
+	}
+
+	cfg := retriesConfig(0)
+	for _, code := range []string{"R02", "R10", "invalid"} {
+		if retry, err := ReinitiateReturnedTransfer(cfg, repo, xfer, code); err != nil || retry != nil {
+			t.Errorf("%s: retry=%#v error=%v", code, retry, err)
+		}
+	}
+
+	// returns after 180 days can't be reinitiated
+	xfer.Created = time.Now().Add(-181 * 24 * time.Hour)
+	if retry, err := ReinitiateReturnedTransfer(cfg, repo, xfer, "R01"); err != nil || retry != nil {
+		t.Errorf("retry=%#v error=%v", retry, err)
+	}
+	if len(repo.Retries) != 0 {
+		t.Errorf("unexpected retries: %#v", repo.Retries)
+	}
+
+	xfer.Created = time.Now()
+	if retry, err := ReinitiateReturnedTransfer(cfg, repo, xfer, "R01"); err != nil || retry == nil {
+		t.Errorf("retry=%#v error=%v", retry, err)
+	}
+	if len(repo.Retries) != 1 {
+		t.Errorf("unexpected retries: %#v", repo.Retries)
+	}
+}
+
+func TestQueue__ProcessReinitiated(t *testing.T) {
+	// Retries are originated from the queue even when Transfers aren't accepted asynchronously
+	if queue := NewQueue(retriesConfig(0), repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy); queue == nil {
+		t.Fatal("nil Queue")
+	}
+
+	check := func(t *testing.T, repo Repository) {
+		queue := setupQueue(t, repo, mockCustomersClient())
+
+		orgID := base.ID()
+		xfer := enqueueTransfer(t, orgID, repo)
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+
+		// Retries aren't originated until their delay has passed
+		retry, err := ReinitiateReturnedTransfer(retriesConfig(time.Hour), repo, xfer, "R01")
+		if err != nil || retry == nil {
+			t.Fatalf("retry=%#v error=%v", retry, err)
+		}
+		if n, err := queue.Process(); err != nil || n != 0 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+
+		retry, err = ReinitiateReturnedTransfer(retriesConfig(time.Millisecond), repo, xfer, "R01")
+		if err != nil || retry == nil {
+			t.Fatalf("retry=%#v error=%v", retry, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		if n := outboxMessages(t, repo, retry.TransferID); n == 0 {
+			t.Error("expected retry to be originated")
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
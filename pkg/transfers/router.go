@@ -5,10 +5,12 @@
 package transfers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/moov-io/base"
 	moovhttp "github.com/moov-io/base/http"
 
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
@@ -36,14 +39,29 @@ type Router struct {
 	Logger log.Logger
 	Repo   Repository
 
-	Publisher pipeline.XferPublisher
-
 	LimitChecker limiter.Checker
 
 	GetTransfers       http.HandlerFunc
 	CreateTransfer     http.HandlerFunc
+	PreviewTransfer    http.HandlerFunc
+	CreateSplit        http.HandlerFunc
+	GetSplit           http.HandlerFunc
 	GetUserTransfer    http.HandlerFunc
 	DeleteUserTransfer http.HandlerFunc
+	GetTransferEntries http.HandlerFunc
+	GetTransferFile    http.HandlerFunc
+	GetAccountHistory  http.HandlerFunc
+	GetHoldingBalance  http.HandlerFunc
+
+	CreateMandate http.HandlerFunc
+	GetMandates   http.HandlerFunc
+	GetMandate    http.HandlerFunc
+	RevokeMandate http.HandlerFunc
+
+	// Evidence is nil unless transfers.evidence is configured
+	Evidence            *EvidenceStorage
+	AddMandateEvidence  http.HandlerFunc
+	AddTransferEvidence http.HandlerFunc
 }
 
 func NewRouter(
@@ -53,7 +71,6 @@ func NewRouter(
 	customersClient customers.Client,
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
-	pub pipeline.XferPublisher,
 ) *Router {
 	limitChecker, err := limiter.New(cfg.Transfers.Limits)
 	if err != nil {
@@ -61,23 +78,56 @@ func NewRouter(
 		panic(err)
 	}
 	cfg.Logger.Logf("setup %T limit checker", limitChecker)
+	evidence, err := NewEvidenceStorage(cfg.Transfers.Evidence)
+	if err != nil {
+		err = cfg.Logger.LogErrorf("problem creating evidence storage: %v", err).Err()
+		panic(err)
+	}
 	return &Router{
-		Logger:    cfg.Logger,
-		Repo:      repo,
-		Publisher: pub,
+		Logger: cfg.Logger,
+		Repo:   repo,
 
 		GetTransfers:       GetTransfers(cfg, repo),
-		CreateTransfer:     CreateTransfer(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, pub, limitChecker),
+		CreateTransfer:     CreateTransfer(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, limitChecker),
+		PreviewTransfer:    PreviewTransfer(cfg, customersClient),
+		CreateSplit:        CreateTransferSplit(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, limitChecker),
+		GetSplit:           GetTransferSplit(cfg, repo),
 		GetUserTransfer:    GetUserTransfer(cfg, repo),
-		DeleteUserTransfer: DeleteUserTransfer(cfg, repo, pub),
+		DeleteUserTransfer: DeleteUserTransfer(cfg, repo),
+		GetTransferEntries: GetTransferEntries(cfg, repo),
+		GetTransferFile:    GetTransferFile(cfg, repo),
+		GetAccountHistory:  GetAccountHistory(cfg, repo),
+		GetHoldingBalance:  GetHoldingBalance(cfg, repo, orgRepo),
+
+		CreateMandate: CreateMandate(cfg, repo),
+		GetMandates:   GetMandates(cfg, repo),
+		GetMandate:    GetMandate(cfg, repo),
+		RevokeMandate: RevokeMandate(cfg, repo),
+
+		Evidence:            evidence,
+		AddMandateEvidence:  AddMandateEvidence(cfg, repo, evidence),
+		AddTransferEvidence: AddTransferEvidence(cfg, repo, evidence),
 	}
 }
 
 func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("GET").Path("/transfers").HandlerFunc(c.GetTransfers)
 	r.Methods("POST").Path("/transfers").HandlerFunc(c.CreateTransfer)
+	r.Methods("POST").Path("/transfers/preview").HandlerFunc(c.PreviewTransfer)
+	r.Methods("POST").Path("/transfers/splits").HandlerFunc(c.CreateSplit)
+	r.Methods("GET").Path("/transfers/splits/{groupID}").HandlerFunc(c.GetSplit)
 	r.Methods("GET").Path("/transfers/{transferID}").HandlerFunc(c.GetUserTransfer)
 	r.Methods("DELETE").Path("/transfers/{transferID}").HandlerFunc(c.DeleteUserTransfer)
+	r.Methods("GET").Path("/transfers/{transferID}/ach").HandlerFunc(c.GetTransferEntries)
+	r.Methods("GET").Path("/transfers/{transferID}/file").HandlerFunc(c.GetTransferFile)
+	r.Methods("POST").Path("/transfers/{transferID}/evidence").HandlerFunc(c.AddTransferEvidence)
+	r.Methods("GET").Path("/accounts/{accountID}/history").HandlerFunc(c.GetAccountHistory)
+	r.Methods("GET").Path("/holding/balance").HandlerFunc(c.GetHoldingBalance)
+	r.Methods("GET").Path("/mandates").HandlerFunc(c.GetMandates)
+	r.Methods("POST").Path("/mandates").HandlerFunc(c.CreateMandate)
+	r.Methods("GET").Path("/mandates/{mandateID}").HandlerFunc(c.GetMandate)
+	r.Methods("POST").Path("/mandates/{mandateID}/revoke").HandlerFunc(c.RevokeMandate)
+	r.Methods("POST").Path("/mandates/{mandateID}/evidence").HandlerFunc(c.AddMandateEvidence)
 }
 
 func getTransferID(r *http.Request) string {
@@ -143,9 +193,9 @@ func GetTransfers(cfg *config.Config, repo Repository) http.HandlerFunc {
 			responder.Problem(err)
 			return
 		}
-		xfers, err := repo.getTransfers(responder.OrganizationID, params)
+		xfers, err := repo.getTransfers(r.Context(), responder.OrganizationID, params)
 		if err != nil {
-			responder.Problem(err)
+			responder.Problem(route.Internal.Wrap(err))
 			return
 		}
 
@@ -165,19 +215,18 @@ func CreateTransfer(
 	customersClient customers.Client,
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
-	pub pipeline.XferPublisher,
 	limitChecker limiter.Checker,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 
 		var req client.CreateTransfer
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			responder.Problem(fmt.Errorf("creating transfer: problem reading request body: %v", err))
+		if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+			responder.Problem(fmt.Errorf("creating transfer: problem reading request body: %w", err))
 			return
 		}
 		if err := validateTransferRequest(req); err != nil {
-			responder.Problem(fmt.Errorf("creating transfer: invalid transfer request: %v", err))
+			responder.Problem(fmt.Errorf("creating transfer: invalid transfer request: %w", err))
 			return
 		}
 
@@ -189,72 +238,127 @@ func CreateTransfer(
 			Description: req.Description,
 			Status:      client.PENDING,
 			SameDay:     req.SameDay,
+			Remittance:  req.Remittance,
 			Created:     time.Now(),
+
+			StandardEntryClassCode: achx.StandardEntryClassCode(req.StandardEntryClassCode),
+			Check:                  req.Check,
+
+			CompanyEntryDescription:  req.CompanyEntryDescription,
+			CompanyDiscretionaryData: req.CompanyDiscretionaryData,
+
+			ExternalID: req.ExternalID,
+			MandateID:  req.MandateID,
+		}
+		logger := responder.Logger().Set("transferID", log.String(transfer.TransferID))
+
+		// Requests repeated with the same externalID return the Transfer created first
+		if transfer.ExternalID != "" {
+			existing, err := repo.getTransferByExternalID(r.Context(), responder.OrganizationID, transfer.ExternalID)
+			if err != nil {
+				responder.Problem(route.Internal.New("creating transfer: error reading externalID: %v", err))
+				return
+			}
+			if existing != nil {
+				respondExistingTransfer(responder, existing)
+				return
+			}
+		}
+
+		// Organizations originate transfers once they've saved the due-diligence we require
+		if err := organization.CheckDueDiligence(cfg.Organization.DueDiligence, orgRepo, responder.OrganizationID); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if transfer.SameDay && !cfg.Features.SameDayTransfers.EnabledFor(responder.OrganizationID) {
+			responder.Problem(route.Disabled.New("creating transfer: same-day transfers aren't enabled for this organization"))
+			return
+		}
+
+		// Limits and approvals count the fee charged along with the Transfer
+		fee, err := transferFee(cfg, orgRepo, customersClient, responder.OrganizationID, transfer)
+		if err != nil {
+			responder.Problem(fmt.Errorf("creating transfer: %w", err))
+			return
 		}
-		cfg.Logger = cfg.Logger.Set("transferID", log.String(transfer.TransferID))
 
 		// Check transfer limits
 		if limitChecker != nil {
-			if err := limitChecker.Accept(responder.OrganizationID, transfer); err != nil {
+			if err := limitChecker.Accept(responder.OrganizationID, withFee(transfer, fee)); err != nil {
 				responder.Problem(err)
 				return
 			}
 		}
 
-		// Save our Transfer to the database
-		if err := repo.WriteUserTransfer(responder.OrganizationID, transfer); err != nil {
-			responder.Problem(fmt.Errorf("creating transfer: error writing user transfr: %v", err))
+		if fundStrategy == nil {
+			responder.Problem(route.Disabled.New("no fundflow strategy configured, unable to originate ACH files"))
+			return
+		}
+		if err := checkHoldingPayout(r.Context(), repo, orgRepo, responder.OrganizationID, transfer.Source, int64(transfer.Amount.Value)); err != nil {
+			responder.Problem(fmt.Errorf("creating transfer: %w", err))
 			return
 		}
 
-		// According to our strategy create (originate) ACH files to be published somewhere
-		if fundStrategy != nil {
-			source, err := GetFundflowSource(customersClient, accountDecryptor, req.Source, responder.OrganizationID)
-			if err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error getting fundflow source: %v", err))
-				return
-			}
-			destination, err := GetFundflowDestination(customersClient, accountDecryptor, req.Destination, responder.OrganizationID)
-			if err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error getting destination: %v", err))
-				return
-			}
-			if err := customers.AcceptableAccountStatus(&destination.Account); err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: unaccepted account status: %v", err))
+		// Large Transfers are held as REVIEWABLE until another user approves them
+		var requestedBy string
+		if cfg.Transfers.Approvals.Required(int64(transfer.Amount.Value) + int64(fee)) {
+			requestedBy = moovhttp.GetUserID(r)
+			if requestedBy == "" {
+				responder.Problem(route.InvalidRequest.New("creating transfer: X-User-ID is required for transfers which need approval"))
 				return
 			}
+			transfer.Status = client.REVIEWABLE
+		}
 
-			var companyID string
-			orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
-			if err != nil {
-				responder.Problem(fmt.Errorf("getting org config: error getting config: %v", err))
+		// Save the Transfer for a Queue worker to originate, clients read it for its status.
+		// Transfers which need approval are originated now so their files are held with them.
+		async := cfg.Transfers.Async != nil && cfg.Features.AsyncTransfers.EnabledFor(responder.OrganizationID)
+		if async && requestedBy == "" {
+			if err := repo.enqueueUserTransfer(responder.OrganizationID, transfer); err != nil {
+				if errors.Is(err, errDuplicateExternalID) {
+					duplicateExternalID(r.Context(), responder, repo, transfer.ExternalID)
+					return
+				}
+				responder.Problem(route.Internal.New("creating transfer: error queueing user transfer: %v", err))
 				return
 			}
-			if orgConfig != nil {
-				companyID = orgConfig.CompanyIdentification
-			} else {
-				companyID = cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification
-			}
+			logger.Log("queued transfer")
 
-			files, err := fundStrategy.Originate(companyID, transfer, source, destination)
-			if err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error originating file: %v", err))
-				return
-			}
-			if err := SaveTraceNumbers(repo, transfer, files); err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error saving trace numbers: %v", err))
-				return
-			}
-			if err := pipeline.PublishFiles(pub, transfer, files); err != nil {
-				responder.Problem(fmt.Errorf("creating transfer: error publishing files: %v", err))
-				return
-			}
+			responder.Respond(func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(transfer)
+			})
+			return
+		}
+
+		orig, err := originateTransfer(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, responder.OrganizationID, transfer)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		// Save our Transfer to the database along with the messages which publish its files
+		if requestedBy != "" {
+			err = repo.createReviewableTransfer(responder.OrganizationID, transfer, requestedBy, orig)
 		} else {
-			responder.Problem(errors.New("no fundflow strategy configured, unable to originate ACH files"))
+			err = repo.createUserTransfer(responder.OrganizationID, transfer, orig)
+		}
+		if errors.Is(err, errDuplicateExternalID) {
+			duplicateExternalID(r.Context(), responder, repo, transfer.ExternalID)
+			return
+		}
+		if err != nil {
+			responder.Problem(route.Internal.New("creating transfer: error writing user transfer: %v", err))
 			return
 		}
 
-		cfg.Logger.Log("successfully created transfer")
+		if requestedBy != "" {
+			logger.With(log.Fields{
+				"requestedBy": log.String(requestedBy),
+			}).Log("created transfer waiting for approval")
+		} else {
+			logger.Log("successfully created transfer")
+		}
 
 		responder.Respond(func(w http.ResponseWriter) {
 			w.WriteHeader(http.StatusOK)
@@ -263,34 +367,183 @@ func CreateTransfer(
 	}
 }
 
+// respondExistingTransfer responds with the Transfer created earlier with the same externalID
+func respondExistingTransfer(responder *route.Responder, transfer *client.Transfer) {
+	responder.Logger().With(log.Fields{
+		"transferID": log.String(transfer.TransferID),
+		"externalID": log.String(transfer.ExternalID),
+	}).Log("returning existing transfer for externalID")
+
+	responder.Respond(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(transfer)
+	})
+}
+
+// duplicateExternalID responds to a request which lost the race to save externalID with the
+// Transfer saved by the other request. Deleted Transfers keep their externalID, so it can't be reused.
+func duplicateExternalID(ctx context.Context, responder *route.Responder, repo Repository, externalID string) {
+	existing, err := repo.getTransferByExternalID(ctx, responder.OrganizationID, externalID)
+	if err != nil {
+		responder.Problem(route.Internal.New("creating transfer: error reading externalID: %v", err))
+		return
+	}
+	if existing == nil {
+		responder.Problem(route.Conflict.New("creating transfer: externalID=%s was used by a deleted transfer", externalID))
+		return
+	}
+	respondExistingTransfer(responder, existing)
+}
+
+// originateTransfer creates (originates) the ACH files of transfer according to our strategy
+// and returns their trace numbers with the messages which publish them and the details of
+// both accounts, for saving with the Transfer. Errors from looking up accounts and the
+// organization's config are retriable.
+func originateTransfer(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	orgID string,
+	transfer *client.Transfer,
+) (origination, error) {
+	source, err := GetFundflowSource(customersClient, accountDecryptor, transfer.Source, orgID)
+	if err != nil {
+		return origination{}, route.Unavailable.New("creating transfer: error getting fundflow source: %v", err)
+	}
+	destination, err := GetFundflowDestination(customersClient, accountDecryptor, transfer.Destination, orgID)
+	if err != nil {
+		return origination{}, route.Unavailable.New("creating transfer: error getting destination: %v", err)
+	}
+	if err := customers.AcceptableAccountStatus(&destination.Account); err != nil {
+		return origination{}, fmt.Errorf("creating transfer: unaccepted account status: %v", err)
+	}
+	orgConfig, err := orgRepo.GetConfig(orgID)
+	if err != nil {
+		return origination{}, route.Internal.New("getting org config: error getting config: %v", err)
+	}
+	var ofacThreshold float32
+	if orgConfig != nil {
+		ofacThreshold = orgConfig.OfacMatchThreshold
+	}
+	for _, customerID := range []string{transfer.Source.CustomerID, transfer.Destination.CustomerID} {
+		if err := customers.CheckOnboarding(cfg.Customers.Onboarding, customersClient, orgID, customerID, transfer.TransferID, ofacThreshold); err != nil {
+			if errors.Is(err, customers.ErrOnboarding) {
+				return origination{}, fmt.Errorf("creating transfer: %v", err)
+			}
+			return origination{}, route.Unavailable.New("creating transfer: error checking customer onboarding: %v", err)
+		}
+	}
+
+	// Transfers crediting our ODFI debit the source customer's account
+	debit := destination.Account.RoutingNumber == cfg.ODFI.RoutingNumber
+	if err := checkMandate(repo, orgID, transfer, debit); err != nil {
+		return origination{}, fmt.Errorf("creating transfer: %w", err)
+	}
+
+	var companyID string
+	if orgConfig != nil {
+		companyID = orgConfig.CompanyIdentification
+	} else {
+		companyID = cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification
+	}
+
+	files, err := fundStrategy.Originate(companyID, transfer, source, destination)
+	if err != nil {
+		return origination{}, fmt.Errorf("creating transfer: error originating file: %w", err)
+	}
+	transfer.ExpectedSettlementDate = achx.SettlementDate(files)
+
+	transfer.AvailableOn = achx.AddBankingDays(transfer.ExpectedSettlementDate, holdDays(cfg.Transfers.Availability, orgConfig, debit))
+
+	// The fee's entry is traced by the fee rather than the Transfer, so read trace numbers before it's added
+	traces := traceNumbers(files)
+	transfer.Fee, err = chargeFee(cfg, customersClient, accountDecryptor, fundStrategy, orgID, companyID, orgConfig, transfer, source, debit, files)
+	if err != nil {
+		return origination{}, err
+	}
+
+	consolidate := orgConfig != nil && orgConfig.BatchingStrategy == client.CONSOLIDATED
+	return origination{
+		traceNumbers: traces,
+		msgs:         pipeline.UploadMessages(orgID, transfer, files, consolidate),
+		history:      transferAccountHistory(transfer, source, destination),
+		fee:          transfer.Fee,
+	}, nil
+}
+
 func SaveTraceNumbers(repo Repository, xfer *client.Transfer, files []*ach.File) error {
-	var traceNumbers []string
+	return repo.saveTraceNumbers(xfer.TransferID, traceNumbers(files))
+}
+
+// NewUserTransfer returns a UserTransfer with the trace numbers of files.
+func NewUserTransfer(xfer *client.Transfer, files []*ach.File) UserTransfer {
+	return UserTransfer{
+		Transfer:     xfer,
+		TraceNumbers: traceNumbers(files),
+	}
+}
+
+func traceNumbers(files []*ach.File) []string {
+	var out []string
 	for i := range files {
 		for j := range files[i].Batches {
 			entries := files[i].Batches[j].GetEntries()
 			for k := range entries {
-				traceNumbers = append(traceNumbers, entries[k].TraceNumber)
+				out = append(out, entries[k].TraceNumber)
 			}
 		}
 	}
-	return repo.saveTraceNumbers(xfer.TransferID, traceNumbers)
+	return out
 }
 
+// maxExternalIDLength is the size of the external_id column
+const maxExternalIDLength = 100
+
 func validateTransferRequest(req client.CreateTransfer) error {
-	if req.Source.CustomerID == "" || req.Source.AccountID == "" {
-		return errors.New("incomplete source")
+	verr := &route.ValidationError{}
+	if req.Source.CustomerID == "" {
+		verr.Add("source.customerID", "missing")
 	}
-	if req.Destination.CustomerID == "" || req.Destination.AccountID == "" {
-		return errors.New("incomplete destination")
+	if req.Source.AccountID == "" {
+		verr.Add("source.accountID", "missing")
+	}
+	if req.Destination.CustomerID == "" {
+		verr.Add("destination.customerID", "missing")
+	}
+	if req.Destination.AccountID == "" {
+		verr.Add("destination.accountID", "missing")
 	}
 	if err := validateAmount(req.Amount); err != nil {
-		return err
+		verr.Add("amount", "%v", err)
 	}
 	if req.Description == "" {
-		return errors.New("missing description")
+		verr.Add("description", "missing")
 	}
-
-	return nil
+	secCode := achx.StandardEntryClassCode(req.StandardEntryClassCode)
+	if err := achx.ValidateStandardEntryClassCode(secCode); err != nil {
+		verr.Add("standardEntryClassCode", "%v", err)
+	} else if err := achx.ValidateCheck(secCode, req.Check, req.Amount); err != nil {
+		verr.Add("check", "%v", err)
+	}
+	if err := achx.ValidateRemittance(req.Remittance, secCode); err != nil {
+		verr.Add("remittance", "%v", err)
+	}
+	if err := achx.ValidateCompanyEntryDescription(req.CompanyEntryDescription, secCode); err != nil {
+		verr.Add("companyEntryDescription", "%v", err)
+	}
+	if err := achx.ValidateCompanyDiscretionaryData(req.CompanyDiscretionaryData); err != nil {
+		verr.Add("companyDiscretionaryData", "%v", err)
+	}
+	if len(req.ExternalID) > maxExternalIDLength {
+		verr.Add("externalID", "longer than %d characters", maxExternalIDLength)
+	}
+	if len(req.MandateID) > maxMandateIDLength {
+		verr.Add("mandateID", "longer than %d characters", maxMandateIDLength)
+	}
+	return verr.Err()
 }
 
 func validateAmount(amount client.Amount) error {
@@ -371,7 +624,7 @@ func GetUserTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 
-		xfer, err := repo.GetTransfer(getTransferID(r))
+		xfer, err := repo.GetTransfer(r.Context(), getTransferID(r))
 		if err != nil {
 			responder.Problem(err)
 			return
@@ -384,24 +637,21 @@ func GetUserTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
 	}
 }
 
-func DeleteUserTransfer(cfg *config.Config, repo Repository, pub pipeline.XferPublisher) http.HandlerFunc {
+func DeleteUserTransfer(cfg *config.Config, repo Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 
 		transferID := getTransferID(r)
-		if err := repo.deleteUserTransfer(responder.OrganizationID, transferID); err != nil {
-			responder.Problem(err)
-			return
-		}
-
-		if pub != nil {
-			msg := pipeline.CanceledTransfer{
-				TransferID: transferID,
-			}
-			if err := pub.Cancel(msg); err != nil {
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+		msgs := []pipeline.OutboxMessage{pipeline.CancelMessage(transferID)}
+		if err := repo.deleteUserTransfer(responder.OrganizationID, transferID, force, msgs); err != nil {
+			var derr *dependentsError
+			if errors.As(err, &derr) {
+				responder.Problem(route.Conflict.Wrap(err))
+			} else {
 				responder.Problem(err)
-				return
 			}
+			return
 		}
 
 		responder.Respond(func(w http.ResponseWriter) {
@@ -6,6 +6,7 @@ package transfers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -24,8 +25,8 @@ import (
 	"github.com/moov-io/paygate/pkg/organization"
 	"github.com/moov-io/paygate/pkg/testclient"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
-	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
 
 	"github.com/gorilla/mux"
 )
@@ -63,8 +64,6 @@ var (
 
 	orgRepo = &organization.MockRepository{}
 
-	fakePublisher = pipeline.NewMockPublisher()
-
 	mockStrategy = &fundflow.MockStrategy{}
 
 	mockDecryptor = &accounts.MockDecryptor{Number: "12345"}
@@ -136,7 +135,7 @@ func TestRouter__getUserTransfers(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -156,7 +155,7 @@ func TestRouter__createUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -189,11 +188,79 @@ func TestRouter__createUserTransfer(t *testing.T) {
 	}
 }
 
+func TestRouter__createUserTransferExternalID(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+		ExternalID:  "invoice-1001",
+	}
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if xfer.ExternalID != "invoice-1001" {
+		t.Errorf("unexpected externalID: %q", xfer.ExternalID)
+	}
+
+	// the same externalID returns the existing Transfer
+	opts.Amount.Value = 5000
+	again, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if again.TransferID != xfer.TransferID || again.Amount.Value != 1244 {
+		t.Errorf("unexpected transfer: %#v", again)
+	}
+
+	// deleted Transfers can't have their externalID reused
+	if err := repo.deleteUserTransfer("organization", xfer.TransferID, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, resp, _ = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	transfers, err := repo.getTransfers(context.Background(), "organization", transferFilterParams{
+		StartDate: time.Now().Add(-time.Hour),
+		EndDate:   time.Now().Add(time.Hour),
+		Count:     100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 0 {
+		t.Errorf("unexpected transfers: %#v", transfers)
+	}
+}
+
 func TestRouter__createUserTransfersInvalidAmount(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -219,7 +286,7 @@ func TestRouter__createUserTransferMissingFundflowStrategy(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, nil, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, nil)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -255,11 +322,215 @@ func TestRouter__createUserTransferMissingFundflowStrategy(t *testing.T) {
 	defer resp.Body.Close()
 }
 
+func TestRouter__createUserTransferForbidden(t *testing.T) {
+	// Errors from the fundflow strategy keep their HTTP status, like blocked accounts
+	strategy := &fundflow.MockStrategy{Err: route.Forbidden.New("debits of accountID=%s are blocked", sourceAccountID)}
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, strategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
+
+func TestRouter__createUserTransferMissingDueDiligence(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Organization.DueDiligence = &config.DueDiligence{
+		RequiredFields: []string{"taxIDToken", "website"},
+	}
+	orgRepo := &organization.MockRepository{
+		DueDiligence: &client.OriginatorDueDiligence{TaxIDToken: "tok_123"},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	if e, ok := err.(client.GenericOpenAPIError); !ok || !strings.Contains(fmt.Sprintf("%#v", e.Model()), "website") {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	orgRepo.DueDiligence.Website = "https://moov.io"
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if xfer.TransferID == "" {
+		t.Errorf("missing Transfer=%#v", xfer)
+	}
+}
+
+func TestRouter__createUserTransferFeatures(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Transfers.Async = &config.TransfersAsync{}
+	cfg.Features.AsyncTransfers = &config.Feature{
+		Organizations: []string{"pilot"},
+	}
+	cfg.Features.SameDayTransfers = &config.Feature{
+		Organizations: []string{"pilot"},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, &MockRepository{}, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+		SameDay:     true,
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// organizations outside of the pilot originate Transfers now
+	opts.SameDay = false
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+
+	// while the pilot's are queued
+	opts.SameDay = true
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "pilot", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
+
+func TestRouter__createUserTransferOnboarding(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Customers.Onboarding = &config.Onboarding{RequireDisclaimers: true}
+
+	customersClient := mockCustomersClient()
+	customersClient.CustomerDisclaimers = []moovcustomers.Disclaimer{{DisclaimerID: base.ID()}}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    1244,
+		},
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	defer resp.Body.Close()
+
+	if e, ok := err.(client.GenericOpenAPIError); !ok || !strings.Contains(fmt.Sprintf("%#v", e.Model()), "has not accepted disclaimerID") {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	customersClient.CustomerDisclaimers[0].AcceptedAt = time.Now()
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if xfer.TransferID == "" {
+		t.Errorf("missing Transfer=%#v", xfer)
+	}
+}
+
 func TestRouter__MissingSource(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -288,7 +559,7 @@ func TestRouter__MissingDestination(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -317,6 +588,134 @@ func TestRouter__MissingDestination(t *testing.T) {
 	}
 }
 
+func TestRouter__validateTransferRequest(t *testing.T) {
+	req := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    112,
+		},
+		Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Description: "test transfer",
+	}
+	if err := validateTransferRequest(req); err != nil {
+		t.Errorf("expected no error: %v", err)
+	}
+
+	req.Source.AccountID = ""
+	req.Amount.Value = 0
+	req.Description = ""
+
+	var verr *route.ValidationError
+	if err := validateTransferRequest(req); !errors.As(err, &verr) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(verr.Fields) != 3 {
+		t.Errorf("unexpected fields: %#v", verr.Fields)
+	}
+}
+
+func TestRouter__validateTransferRequestRemittance(t *testing.T) {
+	req := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    112,
+		},
+		Source:      client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Description: "test transfer",
+		Remittance: &client.Remittance{
+			InvoiceNumbers: []string{"1001"},
+			Memo:           "March services",
+		},
+	}
+	if err := validateTransferRequest(req); err != nil {
+		t.Errorf("expected no error: %v", err)
+	}
+
+	// PPD entries have one 80 character addenda
+	req.Remittance.Memo = strings.Repeat("A", 80)
+
+	var verr *route.ValidationError
+	if err := validateTransferRequest(req); !errors.As(err, &verr) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(verr.Fields) != 1 || verr.Fields[0].Field != "remittance" {
+		t.Errorf("unexpected fields: %#v", verr.Fields)
+	}
+}
+
+func TestRouter__validateTransferRequestCheck(t *testing.T) {
+	req := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    112,
+		},
+		Source:                 client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination:            client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Description:            "test transfer",
+		StandardEntryClassCode: "ARC",
+		Check:                  &client.CheckDetails{SerialNumber: "1005"},
+	}
+	if err := validateTransferRequest(req); err != nil {
+		t.Errorf("expected no error: %v", err)
+	}
+
+	// ARC entries can't have a remittance
+	req.Remittance = &client.Remittance{Memo: "March services"}
+
+	var verr *route.ValidationError
+	if err := validateTransferRequest(req); !errors.As(err, &verr) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(verr.Fields) != 1 || verr.Fields[0].Field != "remittance" {
+		t.Errorf("unexpected fields: %#v", verr.Fields)
+	}
+
+	req.Remittance = nil
+	req.Check = nil
+	if err := validateTransferRequest(req); !errors.As(err, &verr) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(verr.Fields) != 1 || verr.Fields[0].Field != "check" {
+		t.Errorf("unexpected fields: %#v", verr.Fields)
+	}
+}
+
+func TestRouter__validateTransferRequestCompanyFields(t *testing.T) {
+	req := client.CreateTransfer{
+		Amount: client.Amount{
+			Currency: "USD",
+			Value:    112,
+		},
+		Source:                   client.Source{CustomerID: base.ID(), AccountID: base.ID()},
+		Destination:              client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+		Description:              "test transfer",
+		CompanyEntryDescription:  "PAYROLL",
+		CompanyDiscretionaryData: "MARCH",
+	}
+	if err := validateTransferRequest(req); err != nil {
+		t.Errorf("expected no error: %v", err)
+	}
+
+	req.CompanyEntryDescription = "PAYROLL MARCH"
+	req.CompanyDiscretionaryData = strings.Repeat("A", 21)
+
+	var verr *route.ValidationError
+	if err := validateTransferRequest(req); !errors.As(err, &verr) {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(verr.Fields) != 2 || verr.Fields[0].Field != "companyEntryDescription" || verr.Fields[1].Field != "companyDiscretionaryData" {
+		t.Errorf("unexpected fields: %#v", verr.Fields)
+	}
+
+	req.CompanyEntryDescription, req.CompanyDiscretionaryData = "PAYROLL", "MARCH"
+	req.ExternalID = strings.Repeat("A", 101)
+	if err := validateTransferRequest(req); !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != "externalID" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestRouter__validateAmount(t *testing.T) {
 	amt := client.Amount{
 		Currency: "USD",
@@ -351,7 +750,7 @@ func TestRouter__getUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -369,9 +768,10 @@ func TestRouter__getUserTransfer(t *testing.T) {
 
 func TestRouter__deleteUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
+	repo := &MockRepository{}
 
 	r := mux.NewRouter()
-	router := NewRouter(config.Empty(), repoWithTransfer, orgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(config.Empty(), repo, orgRepo, customersClient, mockDecryptor, mockStrategy)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -381,4 +781,25 @@ func TestRouter__deleteUserTransfer(t *testing.T) {
 		t.Fatal(err)
 	}
 	resp.Body.Close()
+
+	if n := len(repo.Messages); n != 1 {
+		t.Fatalf("unexpected %d messages", n)
+	}
+	if msg := repo.Messages[0]; msg.Cancel == nil || msg.Cancel.TransferID != "transferID" {
+		t.Errorf("unexpected message: %#v", msg)
+	}
+
+	// Transfers of in-flight micro-deposits are blocked
+	repo.Err = &dependentsError{
+		transferID:    "transferID",
+		microDeposits: []inFlightMicroDeposits{{microDepositID: "microDepositID", transferIDs: []string{"transferID"}}},
+	}
+	resp, err = c.TransfersApi.DeleteTransferByID(context.TODO(), "transferID", "organization", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected status: %d", resp.StatusCode)
+	}
 }
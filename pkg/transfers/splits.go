@@ -0,0 +1,253 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/limiter"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/moov-io/base/log"
+)
+
+// maxSplitReceivers is the most receivers one split payment can credit
+const maxSplitReceivers = 100
+
+func getGroupID(r *http.Request) string {
+	return route.ReadPathID("groupID", r)
+}
+
+// CreateTransferSplit originates a Transfer from the source to each receiver of a split payment.
+// The Transfers share a groupID and are saved together, so either every receiver is paid or none are.
+func CreateTransferSplit(
+	cfg *config.Config,
+	repo Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	limitChecker limiter.Checker,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		var req client.CreateTransferSplit
+		if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+			responder.Problem(fmt.Errorf("creating split: problem reading request body: %w", err))
+			return
+		}
+		if err := validateSplitRequest(req); err != nil {
+			responder.Problem(fmt.Errorf("creating split: invalid split request: %w", err))
+			return
+		}
+
+		groupID := base.ID()
+		logger := responder.Logger().Set("groupID", log.String(groupID))
+
+		var transfers []*client.Transfer
+		var total int32
+		for _, receiver := range req.Receivers {
+			xfer := splitTransferRequest(req, receiver)
+			transfers = append(transfers, &client.Transfer{
+				TransferID:  base.ID(),
+				Amount:      xfer.Amount,
+				Source:      xfer.Source,
+				Destination: xfer.Destination,
+				Description: xfer.Description,
+				Status:      client.PENDING,
+				SameDay:     xfer.SameDay,
+				Created:     time.Now(),
+				GroupID:     groupID,
+
+				StandardEntryClassCode: achx.StandardEntryClassCode(xfer.StandardEntryClassCode),
+			})
+			total += receiver.Amount.Value
+		}
+
+		if err := organization.CheckDueDiligence(cfg.Organization.DueDiligence, orgRepo, responder.OrganizationID); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if req.SameDay && !cfg.Features.SameDayTransfers.EnabledFor(responder.OrganizationID) {
+			responder.Problem(route.Disabled.New("creating split: same-day transfers aren't enabled for this organization"))
+			return
+		}
+		// Limits and approvals count the fee charged along with each Transfer
+		var fees int64
+		for i := range transfers {
+			fee, err := transferFee(cfg, orgRepo, customersClient, responder.OrganizationID, transfers[i])
+			if err != nil {
+				responder.Problem(fmt.Errorf("receivers[%d]: creating transfer: %w", i, err))
+				return
+			}
+			if limitChecker != nil {
+				if err := limitChecker.Accept(responder.OrganizationID, withFee(transfers[i], fee)); err != nil {
+					responder.Problem(err)
+					return
+				}
+			}
+			fees += int64(fee)
+		}
+		if fundStrategy == nil {
+			responder.Problem(route.Disabled.New("no fundflow strategy configured, unable to originate ACH files"))
+			return
+		}
+		if err := checkHoldingPayout(r.Context(), repo, orgRepo, responder.OrganizationID, req.Source, int64(total)); err != nil {
+			responder.Problem(fmt.Errorf("creating split: %w", err))
+			return
+		}
+
+		// Approvals hold a single Transfer, so split payments which would need one are refused
+		// rather than leaving some receivers paid while others wait.
+		if cfg.Transfers.Approvals.Required(int64(total) + fees) {
+			responder.Problem(route.InvalidRequest.New("creating split: a total of %d with fees of %d needs approval, which isn't supported for splits", total, fees))
+			return
+		}
+
+		origs := make([]origination, len(transfers))
+		for i := range transfers {
+			orig, err := originateTransfer(cfg, repo, orgRepo, customersClient, accountDecryptor, fundStrategy, responder.OrganizationID, transfers[i])
+			if err != nil {
+				responder.Problem(fmt.Errorf("receivers[%d]: %w", i, err))
+				return
+			}
+			origs[i] = orig
+		}
+
+		if err := repo.createTransferGroup(responder.OrganizationID, transfers, origs); err != nil {
+			responder.Problem(route.Internal.New("creating split: error writing transfers: %v", err))
+			return
+		}
+		logger.With(log.Fields{
+			"transfers": log.Int(len(transfers)),
+		}).Log("successfully created split")
+
+		group := newTransferGroup(groupID, transfers)
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(group)
+		})
+	}
+}
+
+// validateSplitRequest checks each receiver as a Transfer from the split's source. The source
+// is checked once rather than for each receiver.
+func validateSplitRequest(req client.CreateTransferSplit) error {
+	verr := &route.ValidationError{}
+	if req.Source.CustomerID == "" {
+		verr.Add("source.customerID", "missing")
+	}
+	if req.Source.AccountID == "" {
+		verr.Add("source.accountID", "missing")
+	}
+	switch n := len(req.Receivers); {
+	case n == 0:
+		verr.Add("receivers", "missing")
+	case n > maxSplitReceivers:
+		verr.Add("receivers", "%d is more than the %d allowed", n, maxSplitReceivers)
+	}
+	for i, receiver := range req.Receivers {
+		if receiver.Amount.Currency != req.Receivers[0].Amount.Currency {
+			verr.Add(fmt.Sprintf("receivers[%d].amount", i), "currency %s differs from %s", receiver.Amount.Currency, req.Receivers[0].Amount.Currency)
+		}
+
+		var rerr *route.ValidationError
+		if err := validateTransferRequest(splitTransferRequest(req, receiver)); errors.As(err, &rerr) {
+			for _, field := range rerr.Fields {
+				if strings.HasPrefix(field.Field, "source.") {
+					continue
+				}
+				verr.Add(fmt.Sprintf("receivers[%d].%s", i, field.Field), "%s", field.Message)
+			}
+		}
+	}
+	return verr.Err()
+}
+
+// splitTransferRequest returns the Transfer which pays receiver its part of req
+func splitTransferRequest(req client.CreateTransferSplit, receiver client.SplitReceiver) client.CreateTransfer {
+	description := receiver.Description
+	if description == "" {
+		description = req.Description
+	}
+	return client.CreateTransfer{
+		Amount:      receiver.Amount,
+		Source:      req.Source,
+		Destination: receiver.Destination,
+		Description: description,
+		SameDay:     req.SameDay,
+	}
+}
+
+func GetTransferSplit(cfg *config.Config, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+
+		groupID := getGroupID(r)
+		transfers, err := repo.getTransferGroup(r.Context(), responder.OrganizationID, groupID)
+		if err != nil {
+			responder.Problem(route.Internal.New("getting split: %v", err))
+			return
+		}
+		if len(transfers) == 0 {
+			responder.Problem(route.NotFound.New("groupID=%s not found", groupID))
+			return
+		}
+
+		group := newTransferGroup(groupID, transfers)
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(group)
+		})
+	}
+}
+
+// newTransferGroup totals the Transfers of a split payment and reads their aggregate status.
+func newTransferGroup(groupID string, transfers []*client.Transfer) client.TransferGroup {
+	group := client.TransferGroup{
+		GroupID: groupID,
+		Status:  groupStatus(transfers),
+	}
+	for i := range transfers {
+		group.Amount.Currency = transfers[i].Amount.Currency
+		group.Amount.Value += transfers[i].Amount.Value
+		group.Transfers = append(group.Transfers, *transfers[i])
+	}
+	return group
+}
+
+// groupStatus is FAILED once any Transfer failed, REVIEWABLE or PENDING while any Transfer
+// waits on either, CANCELED when every Transfer was canceled and PROCESSED otherwise.
+func groupStatus(transfers []*client.Transfer) client.TransferStatus {
+	counts := make(map[client.TransferStatus]int)
+	for i := range transfers {
+		counts[transfers[i].Status]++
+	}
+	switch {
+	case counts[client.FAILED] > 0:
+		return client.FAILED
+	case counts[client.REVIEWABLE] > 0:
+		return client.REVIEWABLE
+	case counts[client.PENDING] > 0:
+		return client.PENDING
+	case counts[client.CANCELED] == len(transfers):
+		return client.CANCELED
+	}
+	return client.PROCESSED
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRouter__createTransferSplit(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	r := mux.NewRouter()
+	router := NewRouter(config.Empty(), repo, orgRepo, mockCustomersClient(), mockDecryptor, mockStrategy)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	destination := client.Destination{
+		CustomerID: destinationCustomerID,
+		AccountID:  destinationAccountID,
+	}
+	opts := client.CreateTransferSplit{
+		Source: client.Source{
+			CustomerID: sourceCustomerID,
+			AccountID:  sourceAccountID,
+		},
+		Receivers: []client.SplitReceiver{
+			{Destination: destination, Amount: client.Amount{Currency: "USD", Value: 1000}},
+			{Destination: destination, Amount: client.Amount{Currency: "USD", Value: 250}, Description: "commission"},
+		},
+		Description: "marketplace sale",
+	}
+	group, resp, err := c.TransfersApi.CreateTransferSplit(context.TODO(), "organization", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if group.GroupID == "" || group.Status != client.PENDING || group.Amount.Value != 1250 || len(group.Transfers) != 2 {
+		t.Fatalf("unexpected group: %#v", group)
+	}
+	if xfer := group.Transfers[1]; xfer.GroupID != group.GroupID || xfer.Description != "commission" || xfer.Amount.Value != 250 {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+	if group.Transfers[0].Description != "marketplace sale" {
+		t.Errorf("unexpected description: %q", group.Transfers[0].Description)
+	}
+
+	found, resp, err := c.TransfersApi.GetTransferSplit(context.TODO(), group.GroupID, "organization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if found.GroupID != group.GroupID || found.Amount.Value != 1250 || len(found.Transfers) != 2 {
+		t.Errorf("unexpected group: %#v", found)
+	}
+
+	// unknown groups
+	_, resp, err = c.TransfersApi.GetTransferSplit(context.TODO(), base.ID(), "organization", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+
+	// unknown receivers fail the whole split
+	opts.Receivers[1].Destination.AccountID = base.ID()
+	_, resp, err = c.TransfersApi.CreateTransferSplit(context.TODO(), "organization", opts, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bogus HTTP status: %d", resp.StatusCode)
+	}
+	if n := len(repo.transfers); n != 2 {
+		t.Errorf("expected only the first split's transfers: %d", n)
+	}
+}
+
+func TestTransfers__validateSplitRequest(t *testing.T) {
+	req := client.CreateTransferSplit{
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Receivers: []client.SplitReceiver{
+			{
+				Destination: client.Destination{CustomerID: base.ID(), AccountID: base.ID()},
+				Amount:      client.Amount{Currency: "USD", Value: 1000},
+			},
+		},
+		Description: "marketplace sale",
+	}
+	if err := validateSplitRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Receivers = append(req.Receivers, client.SplitReceiver{
+		Amount: client.Amount{Currency: "EUR", Value: 100},
+	})
+	err := validateSplitRequest(req)
+	verr, ok := err.(*route.ValidationError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, f := range verr.Fields {
+		fields[f.Field] = true
+	}
+	if !fields["receivers[1].amount"] || !fields["receivers[1].destination.customerID"] || fields["receivers[0].amount"] {
+		t.Errorf("unexpected fields: %#v", verr.Fields)
+	}
+
+	req.Receivers = nil
+	if err := validateSplitRequest(req); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestTransfers__groupStatus(t *testing.T) {
+	group := func(statuses ...client.TransferStatus) []*client.Transfer {
+		var out []*client.Transfer
+		for i := range statuses {
+			out = append(out, &client.Transfer{Status: statuses[i]})
+		}
+		return out
+	}
+	cases := []struct {
+		transfers []*client.Transfer
+		expected  client.TransferStatus
+	}{
+		{group(client.PROCESSED, client.PROCESSED), client.PROCESSED},
+		{group(client.PROCESSED, client.PENDING), client.PENDING},
+		{group(client.REVIEWABLE, client.PENDING), client.REVIEWABLE},
+		{group(client.PROCESSED, client.FAILED, client.PENDING), client.FAILED},
+		{group(client.CANCELED, client.CANCELED), client.CANCELED},
+		{group(client.CANCELED, client.PROCESSED), client.PROCESSED},
+	}
+	for i := range cases {
+		if status := groupStatus(cases[i].transfers); status != cases[i].expected {
+			t.Errorf("#%d: expected %s but got %s", i, cases[i].expected, status)
+		}
+	}
+}
@@ -19,6 +19,11 @@ type Agent interface {
 	UploadFile(f File) error
 	Delete(path string) error
 
+	// FileSize and ReadFile return the size and contents of a remote file, which are
+	// used to verify uploads.
+	FileSize(path string) (int64, error)
+	ReadFile(path string) (*File, error)
+
 	InboundPath() string
 	OutboundPath() string
 	ReturnPath() string
@@ -29,15 +34,74 @@ type Agent interface {
 }
 
 func New(logger log.Logger, cfg config.ODFI) (Agent, error) {
+	return NewWithCredentials(logger, cfg, nil)
+}
+
+// NewWithCredentials returns an Agent whose FTP or SFTP connections log in with the latest
+// credentials of store. A nil store logs in with the credentials of cfg.
+func NewWithCredentials(logger log.Logger, cfg config.ODFI, store *CredentialStore) (Agent, error) {
+	logger = logger.Set("package", log.String("upload"))
+	agent, err := newConfiguredAgent(logger, cfg, store)
+	if len(cfg.PathSets) == 0 {
+		return agent, err
+	}
+	return withPathSets(logger, agent, cfg, store, err)
+}
+
+func newConfiguredAgent(logger log.Logger, cfg config.ODFI, store *CredentialStore) (Agent, error) {
 	if cfg.FTP != nil {
-		return newFTPTransferAgent(logger, cfg)
+		agent, err := newFTPTransferAgent(logger, cfg, store)
+		if agent == nil {
+			return agent, err // keep the typed nil so Close() is safe to call
+		}
+		return wrap(logger, agent, cfg, err)
 	}
 	if cfg.SFTP != nil {
-		return newSFTPTransferAgent(logger, cfg)
+		agent, err := newSFTPTransferAgent(logger, cfg, store)
+		if agent == nil {
+			return agent, err // keep the typed nil so Close() is safe to call
+		}
+		return wrap(logger, agent, cfg, err)
+	}
+	if cfg.AS2 != nil {
+		agent, err := newAS2TransferAgent(logger, cfg)
+		if agent == nil {
+			return agent, err // keep the typed nil so Close() is safe to call
+		}
+		return wrap(logger, agent, cfg, err)
+	}
+	if cfg.HTTPS != nil {
+		agent, err := newHTTPSTransferAgent(logger, cfg)
+		if agent == nil {
+			return agent, err // keep the typed nil so Close() is safe to call
+		}
+		return wrap(logger, agent, cfg, err)
 	}
 	return nil, errors.New("upload: unknown Agent type")
 }
 
+func wrap(logger log.Logger, agent Agent, cfg config.ODFI, err error) (Agent, error) {
+	wrapped, werr := withWindows(logger, agent, cfg)
+	if werr != nil {
+		return agent, werr
+	}
+	return withFaults(logger, wrapped, cfg.Faults), err
+}
+
+func withWindows(logger log.Logger, agent Agent, cfg config.ODFI) (Agent, error) {
+	if cfg.Throttle == nil || len(cfg.Throttle.Windows) == 0 {
+		return agent, nil
+	}
+	return newWindowedAgent(logger, agent, cfg)
+}
+
+func withFaults(logger log.Logger, agent Agent, cfg *config.Faults) Agent {
+	if cfg == nil {
+		return agent
+	}
+	return newFaultyAgent(logger, agent, cfg)
+}
+
 func Type(cfg config.ODFI) string {
 	if cfg.FTP != nil {
 		return "ftp"
@@ -45,5 +109,11 @@ func Type(cfg config.ODFI) string {
 	if cfg.SFTP != nil {
 		return "sftp"
 	}
+	if cfg.AS2 != nil {
+		return "as2"
+	}
+	if cfg.HTTPS != nil {
+		return "https"
+	}
 	return "unknown"
 }
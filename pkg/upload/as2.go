@@ -0,0 +1,735 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	as2AgentUp = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "as2_agent_up",
+		Help: "Status of AS2 agent connection",
+	}, []string{"hostname"})
+)
+
+const (
+	// maxAS2MessageSize limits the messages and MDNs read from the ODFI.
+	maxAS2MessageSize = 100 * 1024 * 1024
+
+	dispositionProcessed = "automatic-action/MDN-sent-automatically; processed"
+)
+
+// AS2TransferAgent sends files to the ODFI as signed and encrypted AS2 messages, verifying
+// the synchronous MDN returned for each one. Files the ODFI sends are received by an HTTP
+// server and kept in a local directory until they're processed.
+type AS2TransferAgent struct {
+	cfg    config.ODFI
+	logger log.Logger
+
+	url    *url.URL
+	client *http.Client
+	server *http.Server
+
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	partner *x509.Certificate
+}
+
+func newAS2TransferAgent(logger log.Logger, cfg config.ODFI) (*AS2TransferAgent, error) {
+	if cfg.AS2 == nil {
+		return nil, errors.New("nil AS2 config")
+	}
+	u, err := url.Parse(cfg.AS2.URL)
+	if err != nil {
+		return nil, fmt.Errorf("as2: invalid url: %v", err)
+	}
+	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), u.Hostname()); err != nil {
+		return nil, fmt.Errorf("as2: %s is not whitelisted: %v", u.Hostname(), err)
+	}
+
+	agent := &AS2TransferAgent{
+		cfg:    cfg,
+		logger: logger,
+		url:    u,
+	}
+	agent.cert, err = readCertificate(cfg.AS2.CertificateFile)
+	if err != nil {
+		return nil, fmt.Errorf("as2: %v", err)
+	}
+	agent.partner, err = readCertificate(cfg.AS2.PartnerCertificateFile)
+	if err != nil {
+		return nil, fmt.Errorf("as2: %v", err)
+	}
+	agent.key, err = readPrivateKey(cfg.AS2.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("as2: %v", err)
+	}
+
+	for _, dir := range []string{cfg.InboundPath, cfg.ReturnPath} {
+		if err := os.MkdirAll(agent.storagePath(dir), 0700); err != nil {
+			return nil, fmt.Errorf("as2: creating storage directory: %v", err)
+		}
+	}
+
+	agent.client = &http.Client{
+		Timeout:   cfg.AS2.Timeout(),
+		Transport: throttledTransport(newBandwidthLimiter(cfg.Throttle)),
+	}
+
+	if cfg.AS2.BindAddress != "" {
+		agent.server = &http.Server{
+			Addr:    cfg.AS2.BindAddress,
+			Handler: agent,
+		}
+		go func() {
+			logger.Logf("as2: listening on %s", cfg.AS2.BindAddress)
+			if err := agent.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.LogErrorf("as2: server: %v", err)
+			}
+		}()
+	}
+
+	return agent, nil
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(bs)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(bs)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("reading private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported %T private key", key)
+	}
+	return rsaKey, nil
+}
+
+// storagePath returns where path is kept under StorageDirectory. Paths are cleaned so
+// they can't refer to files outside of StorageDirectory.
+func (agent *AS2TransferAgent) storagePath(path string) string {
+	return filepath.Join(agent.cfg.AS2.StorageDirectory, filepath.Clean("/"+path))
+}
+
+func (agent *AS2TransferAgent) GetInboundFiles() ([]File, error) {
+	return agent.readFiles(agent.cfg.InboundPath)
+}
+
+func (agent *AS2TransferAgent) GetReturnFiles() ([]File, error) {
+	return agent.readFiles(agent.cfg.ReturnPath)
+}
+
+func (agent *AS2TransferAgent) readFiles(dir string) ([]File, error) {
+	infos, err := ioutil.ReadDir(agent.storagePath(dir))
+	if err != nil {
+		return nil, fmt.Errorf("as2: readdir %s: %v", dir, err)
+	}
+	var files []File
+	for i := range infos {
+		if infos[i].IsDir() || infos[i].Size() == 0 {
+			continue
+		}
+		fd, err := os.Open(agent.storagePath(filepath.Join(dir, infos[i].Name())))
+		if err != nil {
+			for j := range files {
+				files[j].Close()
+			}
+			return nil, fmt.Errorf("as2: open %s: %v", infos[i].Name(), err)
+		}
+		files = append(files, File{
+			Filename: infos[i].Name(),
+			Contents: fd,
+		})
+	}
+	return files, nil
+}
+
+func (agent *AS2TransferAgent) Delete(path string) error {
+	if err := os.Remove(agent.storagePath(path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("as2: delete %s: %v", path, err)
+	}
+	return nil
+}
+
+func (agent *AS2TransferAgent) FileSize(path string) (int64, error) {
+	info, err := os.Stat(agent.storagePath(path))
+	if err != nil {
+		return 0, fmt.Errorf("as2: stat %s: %v", path, err)
+	}
+	return info.Size(), nil
+}
+
+func (agent *AS2TransferAgent) ReadFile(path string) (*File, error) {
+	fd, err := os.Open(agent.storagePath(path))
+	if err != nil {
+		return nil, fmt.Errorf("as2: open %s: %v", path, err)
+	}
+	return &File{
+		Filename: filepath.Base(path),
+		Contents: fd,
+	}, nil
+}
+
+// UploadFile sends f to the ODFI and checks the MDN it returns acknowledges the
+// file was processed with the same MIC we computed.
+func (agent *AS2TransferAgent) UploadFile(f File) error {
+	defer f.Close()
+
+	contents, err := ioutil.ReadAll(f.Contents)
+	if err != nil {
+		return fmt.Errorf("as2: reading %s: %v", f.Filename, err)
+	}
+	filename := filepath.Base(f.Filename)
+
+	// Sign a MIME entity of the file and encrypt that for the ODFI
+	entity := newMIMEEntity(filename, contents)
+	mic := computeMIC(entity, "sha-256")
+	contentType, signed, err := agent.signEntity(entity)
+	if err != nil {
+		return fmt.Errorf("as2: signing %s: %v", filename, err)
+	}
+	encrypted, err := encryptFor(append([]byte("Content-Type: "+contentType+"\r\n\r\n"), signed...), agent.partner)
+	if err != nil {
+		return fmt.Errorf("as2: encrypting %s: %v", filename, err)
+	}
+
+	req, err := http.NewRequest("POST", agent.url.String(), bytes.NewReader(encrypted))
+	if err != nil {
+		return err
+	}
+	messageID := fmt.Sprintf("<%s@%s>", base.ID(), agent.cfg.AS2.From)
+	agent.setHeaders(req.Header, messageID)
+	req.Header.Set("Content-Type", `application/pkcs7-mime; smime-type=enveloped-data; name="smime.p7m"`)
+	req.Header.Set("Content-Transfer-Encoding", "binary")
+	req.Header.Set("Content-Disposition", `attachment; filename="smime.p7m"`)
+	req.Header.Set("Subject", filename)
+	req.Header.Set("Disposition-Notification-To", agent.cfg.AS2.From)
+	req.Header.Set("Disposition-Notification-Options", "signed-receipt-protocol=optional, pkcs7-signature; signed-receipt-micalg=optional, sha-256")
+
+	resp, err := agent.client.Do(req)
+	agent.record(err)
+	if err != nil {
+		return fmt.Errorf("as2: sending %s: %v", filename, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAS2MessageSize))
+	if err != nil {
+		return fmt.Errorf("as2: reading MDN of %s: %v", filename, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("as2: sending %s: unexpected HTTP status %s", filename, resp.Status)
+	}
+	if err := agent.checkMDN(resp.Header.Get("Content-Type"), body, mic); err != nil {
+		return fmt.Errorf("as2: MDN of %s: %v", filename, err)
+	}
+
+	agent.logger.With(config.Debug).Logf("as2: uploaded %s as %s", filename, messageID)
+	return nil
+}
+
+func (agent *AS2TransferAgent) setHeaders(h http.Header, messageID string) {
+	h.Set("AS2-Version", "1.2")
+	h.Set("AS2-From", quoteAS2Name(agent.cfg.AS2.From))
+	h.Set("AS2-To", quoteAS2Name(agent.cfg.AS2.To))
+	h.Set("Message-ID", messageID)
+	h.Set("MIME-Version", "1.0")
+	h.Set("Date", time.Now().UTC().Format(time.RFC1123Z))
+}
+
+// quoteAS2Name quotes AS2 identifiers with spaces as RFC 4130 requires.
+func quoteAS2Name(name string) string {
+	if strings.ContainsAny(name, " \t") {
+		return fmt.Sprintf("%q", name)
+	}
+	return name
+}
+
+func unquoteAS2Name(name string) string {
+	return strings.Trim(strings.TrimSpace(name), `"`)
+}
+
+// checkMDN verifies the signature of a signed MDN and that it reports the message was
+// processed with the expected MIC.
+func (agent *AS2TransferAgent) checkMDN(contentType string, body []byte, mic string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q: %v", contentType, err)
+	}
+	entity := append([]byte("Content-Type: "+contentType+"\r\n\r\n"), body...)
+	if mediaType == "multipart/signed" {
+		entity, err = agent.verifySigned(body, params["boundary"])
+		if err != nil {
+			return err
+		}
+	}
+
+	header, content, err := readEntity(entity)
+	if err != nil {
+		return err
+	}
+	mediaType, params, err = mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return fmt.Errorf("unexpected content type %q", header.Get("Content-Type"))
+	}
+	parts, err := splitMultipart(content, params["boundary"])
+	if err != nil {
+		return err
+	}
+	for i := range parts {
+		h, body, err := readEntity(parts[i])
+		if err != nil {
+			return err
+		}
+		if mt, _, _ := mime.ParseMediaType(h.Get("Content-Type")); mt != "message/disposition-notification" {
+			continue
+		}
+		fields, _, err := readEntity(append(bytes.TrimLeft(body, "\r\n"), "\r\n\r\n"...))
+		if err != nil {
+			return fmt.Errorf("reading disposition: %v", err)
+		}
+		disposition := fields.Get("Disposition")
+		if !processedDisposition(disposition) {
+			return fmt.Errorf("not processed: %s", disposition)
+		}
+		received := strings.TrimSpace(strings.SplitN(fields.Get("Received-Content-MIC"), ",", 2)[0])
+		expected := strings.TrimSpace(strings.SplitN(mic, ",", 2)[0])
+		if received != expected {
+			return fmt.Errorf("received MIC %q doesn't match %q", received, expected)
+		}
+		return nil
+	}
+	return errors.New("no disposition notification found")
+}
+
+func processedDisposition(disposition string) bool {
+	parts := strings.SplitN(disposition, ";", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	status := strings.ToLower(strings.TrimSpace(parts[1]))
+	return status == "processed" || strings.HasPrefix(status, "processed/warning")
+}
+
+// verifySigned checks the signature of a multipart/signed body and returns the entity
+// which was signed.
+func (agent *AS2TransferAgent) verifySigned(body []byte, boundary string) ([]byte, error) {
+	parts, err := splitMultipart(body, boundary)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("found %d parts in signed entity", len(parts))
+	}
+	header, sig, err := readEntity(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	sig, err = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), sig)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyDetached(parts[0], sig, agent.partner); err != nil {
+		return nil, fmt.Errorf("verifying signature: %v", err)
+	}
+	return parts[0], nil
+}
+
+// signEntity wraps entity in a multipart/signed entity and returns its content type and body.
+func (agent *AS2TransferAgent) signEntity(entity []byte) (string, []byte, error) {
+	sig, err := signDetached(entity, agent.cert, agent.key)
+	if err != nil {
+		return "", nil, err
+	}
+	boundary := "----=_Part_" + base.ID()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.Write(entity)
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	buf.WriteString("Content-Type: application/pkcs7-signature; name=smime.p7s\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("Content-Disposition: attachment; filename=smime.p7s\r\n\r\n")
+	buf.WriteString(wrapBase64(sig))
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+
+	contentType := fmt.Sprintf(`multipart/signed; protocol="application/pkcs7-signature"; micalg=sha-256; boundary="%s"`, boundary)
+	return contentType, buf.Bytes(), nil
+}
+
+// dispositionError is a problem receiving a message which is reported in its MDN.
+type dispositionError struct {
+	modifier string
+	err      error
+}
+
+func (e *dispositionError) Error() string {
+	return fmt.Sprintf("%s: %v", e.modifier, e.err)
+}
+
+// ServeHTTP receives AS2 messages from the ODFI and responds with a synchronous MDN.
+func (agent *AS2TransferAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxAS2MessageSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dir := agent.cfg.InboundPath
+	if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/return") {
+		dir = agent.cfg.ReturnPath
+	}
+
+	disposition := dispositionProcessed
+	filename, mic, err := agent.receive(r.Header, body, dir)
+	if err != nil {
+		agent.logger.LogErrorf("as2: problem receiving message %s: %v", r.Header.Get("Message-ID"), err)
+		modifier := "unexpected-processing-error"
+		var derr *dispositionError
+		if errors.As(err, &derr) {
+			modifier = derr.modifier
+		}
+		disposition += "/error: " + modifier
+	} else {
+		agent.logger.Logf("as2: received %s as %s", r.Header.Get("Message-ID"), filename)
+	}
+
+	if r.Header.Get("Disposition-Notification-To") == "" {
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+
+	options := r.Header.Get("Disposition-Notification-Options")
+	contentType, mdn, err := agent.newMDN(r.Header.Get("Message-ID"), mic, disposition, strings.Contains(options, "signed-receipt-protocol"))
+	if err != nil {
+		agent.logger.LogErrorf("as2: problem creating MDN for %s: %v", r.Header.Get("Message-ID"), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	agent.setHeaders(w.Header(), fmt.Sprintf("<%s@%s>", base.ID(), agent.cfg.AS2.From))
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(mdn)
+}
+
+// receive decrypts and verifies a message, saves the file it contains into dir and returns
+// the saved filename and MIC of the signed entity.
+func (agent *AS2TransferAgent) receive(h http.Header, body []byte, dir string) (string, string, error) {
+	if from, to := unquoteAS2Name(h.Get("AS2-From")), unquoteAS2Name(h.Get("AS2-To")); from != agent.cfg.AS2.To || to != agent.cfg.AS2.From {
+		return "", "", &dispositionError{"authentication-failed", fmt.Errorf("unexpected AS2-From=%q AS2-To=%q", from, to)}
+	}
+
+	contentType := h.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", "", &dispositionError{"unexpected-processing-error", err}
+	}
+	if mediaType != "application/pkcs7-mime" && mediaType != "application/x-pkcs7-mime" {
+		return "", "", &dispositionError{"decryption-failed", fmt.Errorf("unencrypted %s message", mediaType)}
+	}
+	body, err = decodeTransferEncoding(h.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return "", "", &dispositionError{"decryption-failed", err}
+	}
+	entity, err := decrypt(body, agent.key)
+	if err != nil {
+		return "", "", &dispositionError{"decryption-failed", err}
+	}
+
+	header, content, err := readEntity(entity)
+	if err != nil {
+		return "", "", &dispositionError{"unexpected-processing-error", err}
+	}
+	mediaType, params, err = mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/signed" {
+		return "", "", &dispositionError{"authentication-failed", errors.New("unsigned message")}
+	}
+	signed, err := agent.verifySigned(content, params["boundary"])
+	if err != nil {
+		return "", "", &dispositionError{"authentication-failed", err}
+	}
+	mic := computeMIC(signed, requestedMICAlgorithm(h.Get("Disposition-Notification-Options")))
+
+	header, content, err = readEntity(signed)
+	if err != nil {
+		return "", mic, &dispositionError{"unexpected-processing-error", err}
+	}
+	content, err = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), content)
+	if err != nil {
+		return "", mic, &dispositionError{"unexpected-processing-error", err}
+	}
+
+	filename := fmt.Sprintf("as2-%s.ach", base.ID())
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = filepath.Base(params["filename"])
+	}
+	path := agent.storagePath(filepath.Join(dir, filename))
+	if _, err := os.Stat(path); err == nil {
+		// keep both copies of a file which was sent twice
+		filename = fmt.Sprintf("%s-%s", time.Now().Format("20060102150405"), filename)
+		path = agent.storagePath(filepath.Join(dir, filename))
+	}
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		return "", mic, &dispositionError{"unexpected-processing-error", err}
+	}
+	return filename, mic, nil
+}
+
+// newMDN returns the content type and body of a message disposition notification.
+func (agent *AS2TransferAgent) newMDN(messageID, mic, disposition string, sign bool) (string, []byte, error) {
+	boundary := "----=_Report_" + base.ID()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/plain\r\n\r\n")
+	fmt.Fprintf(&buf, "MDN for message %s\r\n", messageID)
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	buf.WriteString("Content-Type: message/disposition-notification\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 7bit\r\n\r\n")
+	buf.WriteString("Reporting-UA: paygate\r\n")
+	fmt.Fprintf(&buf, "Original-Recipient: rfc822; %s\r\n", quoteAS2Name(agent.cfg.AS2.From))
+	fmt.Fprintf(&buf, "Final-Recipient: rfc822; %s\r\n", quoteAS2Name(agent.cfg.AS2.From))
+	fmt.Fprintf(&buf, "Original-Message-ID: %s\r\n", messageID)
+	if mic != "" {
+		fmt.Fprintf(&buf, "Received-Content-MIC: %s\r\n", mic)
+	}
+	fmt.Fprintf(&buf, "Disposition: %s\r\n", disposition)
+	fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+
+	contentType := fmt.Sprintf(`multipart/report; report-type=disposition-notification; boundary="%s"`, boundary)
+	if !sign {
+		return contentType, buf.Bytes(), nil
+	}
+	return agent.signEntity(append([]byte("Content-Type: "+contentType+"\r\n\r\n"), buf.Bytes()...))
+}
+
+func newMIMEEntity(filename string, contents []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("Content-Type: application/octet-stream\r\n")
+	buf.WriteString("Content-Transfer-Encoding: binary\r\n")
+	fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=%q\r\n\r\n", filename)
+	buf.Write(contents)
+	return buf.Bytes()
+}
+
+// computeMIC returns the base64 encoded digest of entity with its algorithm as used in MDNs.
+func computeMIC(entity []byte, algorithm string) string {
+	if algorithm == "sha1" {
+		sum := sha1.Sum(entity)
+		return base64.StdEncoding.EncodeToString(sum[:]) + ", sha1"
+	}
+	sum := sha256.Sum256(entity)
+	return base64.StdEncoding.EncodeToString(sum[:]) + ", sha-256"
+}
+
+// requestedMICAlgorithm returns the first supported signed-receipt-micalg of options.
+func requestedMICAlgorithm(options string) string {
+	for _, option := range strings.Split(options, ";") {
+		kv := strings.SplitN(strings.TrimSpace(option), "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(kv[0], "signed-receipt-micalg") {
+			continue
+		}
+		for _, alg := range strings.Split(kv[1], ",") {
+			switch alg = strings.ToLower(strings.TrimSpace(alg)); alg {
+			case "sha-256", "sha256":
+				return "sha-256"
+			case "sha1", "sha-1":
+				return "sha1"
+			}
+		}
+	}
+	return "sha-256"
+}
+
+// readEntity splits a MIME entity into its headers and body.
+func readEntity(entity []byte) (textproto.MIMEHeader, []byte, error) {
+	r := bufio.NewReader(bytes.NewReader(entity))
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, nil, fmt.Errorf("reading MIME headers: %v", err)
+	}
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, body, nil
+}
+
+// splitMultipart returns each raw part, with headers, of a multipart body. Parts are
+// returned as they were sent so signatures over them can be verified.
+func splitMultipart(body []byte, boundary string) ([][]byte, error) {
+	if boundary == "" {
+		return nil, errors.New("missing multipart boundary")
+	}
+	delim := []byte("--" + boundary)
+
+	idx := bytes.Index(body, delim)
+	if idx < 0 {
+		return nil, errors.New("multipart boundary not found")
+	}
+	rest := body[idx+len(delim):]
+
+	var parts [][]byte
+	for !bytes.HasPrefix(rest, []byte("--")) {
+		nl := bytes.IndexByte(rest, '\n')
+		if nl < 0 {
+			return nil, errors.New("truncated multipart body")
+		}
+		rest = rest[nl+1:]
+
+		next := bytes.Index(rest, delim)
+		if next < 0 {
+			return nil, errors.New("missing closing multipart boundary")
+		}
+		// the line break before a boundary belongs to the boundary
+		part := rest[:next]
+		if bytes.HasSuffix(part, []byte("\r\n")) {
+			part = part[:len(part)-2]
+		} else if bytes.HasSuffix(part, []byte("\n")) {
+			part = part[:len(part)-1]
+		}
+		parts = append(parts, part)
+		rest = rest[next+len(delim):]
+	}
+	return parts, nil
+}
+
+func decodeTransferEncoding(encoding string, body []byte) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "", " ", "").Replace(string(body)))
+	case "quoted-printable":
+		return ioutil.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	}
+	return body, nil
+}
+
+func wrapBase64(bs []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(bs)
+	var buf strings.Builder
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	return buf.String()
+}
+
+func (agent *AS2TransferAgent) Ping() error {
+	if agent == nil {
+		return errors.New("nil AS2TransferAgent")
+	}
+	host := agent.url.Host
+	if agent.url.Port() == "" {
+		port := "443"
+		if agent.url.Scheme == "http" {
+			port = "80"
+		}
+		host = net.JoinHostPort(agent.url.Hostname(), port)
+	}
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	agent.record(err)
+	if err != nil {
+		return fmt.Errorf("as2: ping %v", err)
+	}
+	return conn.Close()
+}
+
+func (agent *AS2TransferAgent) record(err error) {
+	if agent == nil || agent.url == nil {
+		return
+	}
+	if err != nil {
+		as2AgentUp.With("hostname", agent.url.Hostname()).Set(0)
+	} else {
+		as2AgentUp.With("hostname", agent.url.Hostname()).Set(1)
+	}
+}
+
+func (agent *AS2TransferAgent) Close() error {
+	if agent == nil || agent.server == nil {
+		return nil
+	}
+	return agent.server.Close()
+}
+
+func (agent *AS2TransferAgent) InboundPath() string {
+	return agent.cfg.InboundPath
+}
+
+func (agent *AS2TransferAgent) OutboundPath() string {
+	return agent.cfg.OutboundPath
+}
+
+func (agent *AS2TransferAgent) ReturnPath() string {
+	return agent.cfg.ReturnPath
+}
+
+func (agent *AS2TransferAgent) Hostname() string {
+	if agent == nil || agent.url == nil {
+		return ""
+	}
+	return agent.url.Host
+}
@@ -0,0 +1,530 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// This file implements the parts of CMS (RFC 5652) AS2 needs: detached RSA signatures
+// and RSA key transport with AES or 3DES content encryption.
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidDigestSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+	oidEncryptionRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSignatureSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3   = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int                        `asn1:"default:1"`
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     rawCertificates        `asn1:"optional,tag:0"`
+	CRLs             []pkix.CertificateList `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo           `asn1:"set"`
+}
+
+type rawCertificates struct {
+	Raw asn1.RawContent
+}
+
+type signerInfo struct {
+	Version                   int `asn1:"default:1"`
+	IssuerAndSerialNumber     issuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   []attribute `asn1:"optional,omitempty,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes []attribute `asn1:"optional,omitempty,tag:1"`
+}
+
+type issuerAndSerial struct {
+	IssuerName   asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []recipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type recipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  issuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"tag:0,optional"`
+}
+
+// signDetached returns a DER encoded CMS SignedData over content which doesn't include content.
+func signDetached(content []byte, cert *x509.Certificate, key *rsa.PrivateKey) ([]byte, error) {
+	digest := sha256.Sum256(content)
+
+	var attrs []attribute
+	for _, attr := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidAttributeContentType, oidData},
+		{oidAttributeMessageDigest, digest[:]},
+		{oidAttributeSigningTime, time.Now().UTC()},
+	} {
+		bs, err := asn1.Marshal(attr.value)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attribute{
+			Type:  attr.oid,
+			Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bs},
+		})
+	}
+	// DER sorts the elements of a SET OF by their encoding
+	sort.Slice(attrs, func(i, j int) bool {
+		a, _ := asn1.Marshal(attrs[i])
+		b, _ := asn1.Marshal(attrs[j])
+		return bytes.Compare(a, b) < 0
+	})
+	signedAttrs, err := marshalAttributeSet(attrs)
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(signedAttrs)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, attrsDigest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: cert.Raw})
+	if err != nil {
+		return nil, err
+	}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA256}},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     rawCertificates{Raw: certs},
+		SignerInfos: []signerInfo{
+			{
+				Version:                   1,
+				IssuerAndSerialNumber:     issuerAndSerial{IssuerName: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+				DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256},
+				AuthenticatedAttributes:   attrs,
+				DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidEncryptionRSA, Parameters: asn1.NullRawValue},
+				EncryptedDigest:           signature,
+			},
+		},
+	}
+	inner, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// verifyDetached checks the CMS SignedData in sig is a signature over content by cert.
+func verifyDetached(content, sig []byte, cert *x509.Certificate) error {
+	var sd signedData
+	if err := parseContentInfo(sig, oidSignedData, &sd); err != nil {
+		return err
+	}
+	if len(sd.SignerInfos) == 0 {
+		return errors.New("no signers")
+	}
+	var err error
+	for i := range sd.SignerInfos {
+		if err = verifySigner(content, sd.SignerInfos[i], cert); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func verifySigner(content []byte, signer signerInfo, cert *x509.Certificate) error {
+	h, hashType, err := digestFor(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	h.Write(content)
+	contentDigest := h.Sum(nil)
+
+	signed := contentDigest
+	if len(signer.AuthenticatedAttributes) > 0 {
+		var digest []byte
+		for _, attr := range signer.AuthenticatedAttributes {
+			if attr.Type.Equal(oidAttributeMessageDigest) {
+				if _, err := asn1.Unmarshal(attr.Value.Bytes, &digest); err != nil {
+					return fmt.Errorf("reading message digest: %v", err)
+				}
+			}
+		}
+		if !bytes.Equal(digest, contentDigest) {
+			return errors.New("message digest doesn't match content")
+		}
+		signedAttrs, err := marshalAttributeSet(signer.AuthenticatedAttributes)
+		if err != nil {
+			return err
+		}
+		h.Reset()
+		h.Write(signedAttrs)
+		signed = h.Sum(nil)
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported %T public key", cert.PublicKey)
+	}
+	if err := rsa.VerifyPKCS1v15(pub, hashType, signed, signer.EncryptedDigest); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	return nil
+}
+
+func digestFor(oid asn1.ObjectIdentifier) (hash.Hash, crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidDigestSHA256), oid.Equal(oidSignatureSHA256):
+		return sha256.New(), crypto.SHA256, nil
+	case oid.Equal(oidDigestSHA1), oid.Equal(oidSignatureSHA1):
+		return sha1.New(), crypto.SHA1, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported digest algorithm %v", oid)
+}
+
+// marshalAttributeSet returns the SET OF encoding of attrs, which is what's signed.
+func marshalAttributeSet(attrs []attribute) ([]byte, error) {
+	var buf bytes.Buffer
+	for i := range attrs {
+		bs, err := asn1.Marshal(attrs[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bs)
+	}
+	return asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: buf.Bytes()})
+}
+
+// encryptFor returns a DER encoded CMS EnvelopedData of content encrypted with AES-256 for cert.
+func encryptFor(content []byte, cert *x509.Certificate) ([]byte, error) {
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported %T public key", cert.PublicKey)
+	}
+
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padded := pkcs7Pad(content, aes.BlockSize)
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+	if err != nil {
+		return nil, err
+	}
+
+	ed := envelopedData{
+		Version: 0,
+		RecipientInfos: []recipientInfo{
+			{
+				Version:                0,
+				IssuerAndSerialNumber:  issuerAndSerial{IssuerName: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+				KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidEncryptionRSA, Parameters: asn1.NullRawValue},
+				EncryptedKey:           encryptedKey,
+			},
+		},
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidAES256CBC,
+				Parameters: asn1.RawValue{Tag: asn1.TagOctetString, Bytes: iv},
+			},
+			EncryptedContent: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: encrypted},
+		},
+	}
+	inner, err := asn1.Marshal(ed)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// decrypt returns the content of a CMS EnvelopedData encrypted for key.
+func decrypt(data []byte, key *rsa.PrivateKey) ([]byte, error) {
+	var ed envelopedData
+	if err := parseContentInfo(data, oidEnvelopedData, &ed); err != nil {
+		return nil, err
+	}
+	if len(ed.RecipientInfos) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	var contentKey []byte
+	var err error
+	for i := range ed.RecipientInfos {
+		contentKey, err = rsa.DecryptPKCS1v15(rand.Reader, key, ed.RecipientInfos[i].EncryptedKey)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decrypting content key: %v", err)
+	}
+
+	eci := ed.EncryptedContentInfo
+	var block cipher.Block
+	switch alg := eci.ContentEncryptionAlgorithm.Algorithm; {
+	case alg.Equal(oidAES128CBC), alg.Equal(oidAES256CBC):
+		block, err = aes.NewCipher(contentKey)
+	case alg.Equal(oidDESEDE3):
+		block, err = des.NewTripleDESCipher(contentKey)
+	default:
+		return nil, fmt.Errorf("unsupported content encryption algorithm %v", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	iv := eci.ContentEncryptionAlgorithm.Parameters.Bytes
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("invalid initialization vector")
+	}
+
+	encrypted, err := octets(eci.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) == 0 || len(encrypted)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted content length")
+	}
+	content := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(content, encrypted)
+	return pkcs7Unpad(content, block.BlockSize())
+}
+
+// octets returns the bytes of a primitive or constructed (chunked) OCTET STRING.
+func octets(raw asn1.RawValue) ([]byte, error) {
+	if !raw.IsCompound {
+		return raw.Bytes, nil
+	}
+	var out []byte
+	rest := raw.Bytes
+	for len(rest) > 0 {
+		var chunk asn1.RawValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &chunk)
+		if err != nil {
+			return nil, err
+		}
+		bs, err := octets(chunk)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bs...)
+	}
+	return out, nil
+}
+
+func parseContentInfo(data []byte, contentType asn1.ObjectIdentifier, out interface{}) error {
+	der, err := berToDER(data)
+	if err != nil {
+		return err
+	}
+	var info contentInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return fmt.Errorf("reading content info: %v", err)
+	}
+	if !info.ContentType.Equal(contentType) {
+		return fmt.Errorf("unexpected content type %v", info.ContentType)
+	}
+	if _, err := asn1.Unmarshal(info.Content.Bytes, out); err != nil {
+		return fmt.Errorf("reading %v: %v", contentType, err)
+	}
+	return nil
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty content")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-n], nil
+}
+
+// berToDER rewrites the indefinite lengths some AS2 servers send as definite lengths
+// so encoding/asn1 can read them.
+func berToDER(ber []byte) ([]byte, error) {
+	der, rest, err := readBER(ber)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimRight(rest, "\x00")) > 0 {
+		return nil, errors.New("ber: trailing data")
+	}
+	return der, nil
+}
+
+func readBER(b []byte) ([]byte, []byte, error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("ber: truncated")
+	}
+	pos := 1
+	if b[0]&0x1f == 0x1f { // high tag number
+		for pos < len(b) && b[pos]&0x80 != 0 {
+			pos++
+		}
+		pos++
+	}
+	if pos >= len(b) {
+		return nil, nil, errors.New("ber: truncated tag")
+	}
+	tag := b[:pos]
+	constructed := b[0]&0x20 != 0
+
+	l := b[pos]
+	pos++
+	if l == 0x80 {
+		if !constructed {
+			return nil, nil, errors.New("ber: indefinite length on primitive value")
+		}
+		var children []byte
+		rest := b[pos:]
+		for {
+			if len(rest) < 2 {
+				return nil, nil, errors.New("ber: missing end of contents")
+			}
+			if rest[0] == 0 && rest[1] == 0 {
+				rest = rest[2:]
+				break
+			}
+			child, r, err := readBER(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, child...)
+			rest = r
+		}
+		return encodeTLV(tag, children), rest, nil
+	}
+
+	length := int(l)
+	if l&0x80 != 0 {
+		n := int(l & 0x7f)
+		if n > 4 || pos+n > len(b) {
+			return nil, nil, errors.New("ber: invalid length")
+		}
+		length = 0
+		for _, c := range b[pos : pos+n] {
+			length = length<<8 | int(c)
+		}
+		pos += n
+	}
+	if length < 0 || pos+length > len(b) {
+		return nil, nil, errors.New("ber: truncated value")
+	}
+	content := b[pos : pos+length]
+	if constructed {
+		var children []byte
+		for rest := content; len(rest) > 0; {
+			child, r, err := readBER(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, child...)
+			rest = r
+		}
+		content = children
+	}
+	return encodeTLV(tag, content), b[pos+length:], nil
+}
+
+func encodeTLV(tag, content []byte) []byte {
+	out := append([]byte{}, tag...)
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	default:
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(out, 0x80|byte(len(length)))
+		out = append(out, length...)
+	}
+	return append(out, content...)
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func writeTestCertificate(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func as2Config(dir, url, from, to, cert, key, partner string) config.ODFI {
+	return config.ODFI{
+		InboundPath:  "inbound",
+		OutboundPath: "outbound",
+		ReturnPath:   "returned",
+		AS2: &config.AS2{
+			URL:                    url,
+			From:                   from,
+			To:                     to,
+			CertificateFile:        cert,
+			PrivateKeyFile:         key,
+			PartnerCertificateFile: partner,
+			StorageDirectory:       filepath.Join(dir, from),
+		},
+	}
+}
+
+// setupAS2 returns an agent for paygate and a server running the ODFI's agent
+func setupAS2(t *testing.T) (*AS2TransferAgent, *AS2TransferAgent, *httptest.Server) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "as2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	ourCert, ourKey := writeTestCertificate(t, dir, "paygate")
+	odfiCert, odfiKey := writeTestCertificate(t, dir, "odfi")
+
+	odfi, err := newAS2TransferAgent(log.NewNopLogger(), as2Config(dir, "http://127.0.0.1:1", "odfi", "paygate", odfiCert, odfiKey, ourCert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(odfi)
+	t.Cleanup(server.Close)
+
+	agent, err := newAS2TransferAgent(log.NewNopLogger(), as2Config(dir, server.URL, "paygate", "odfi", ourCert, ourKey, odfiCert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return agent, odfi, server
+}
+
+func TestAS2__uploadFile(t *testing.T) {
+	agent, odfi, _ := setupAS2(t)
+
+	err := agent.UploadFile(File{
+		Filename: "ppd-debit.ach",
+		Contents: ioutil.NopCloser(strings.NewReader("101 contents\r\n")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := odfi.GetInboundFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Filename != "ppd-debit.ach" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	bs, _ := ioutil.ReadAll(files[0].Contents)
+	files[0].Close()
+	if string(bs) != "101 contents\r\n" {
+		t.Errorf("unexpected contents: %q", bs)
+	}
+
+	if size, err := odfi.FileSize("inbound/ppd-debit.ach"); err != nil || size != 14 {
+		t.Errorf("size=%d error=%v", size, err)
+	}
+	if err := odfi.Delete("inbound/ppd-debit.ach"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := odfi.GetInboundFiles(); len(files) != 0 {
+		t.Errorf("unexpected files: %#v", files)
+	}
+}
+
+func TestAS2__returnFiles(t *testing.T) {
+	agent, odfi, server := setupAS2(t)
+
+	u := *agent.url
+	u.Path = "/return"
+	agent.url = &u
+	err := agent.UploadFile(File{
+		Filename: "return.ach",
+		Contents: ioutil.NopCloser(strings.NewReader("101 returned")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := odfi.GetReturnFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Filename != "return.ach" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	files[0].Close()
+
+	if agent.Hostname() != strings.TrimPrefix(server.URL, "http://") {
+		t.Errorf("unexpected hostname: %s", agent.Hostname())
+	}
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAS2__untrustedPartner(t *testing.T) {
+	agent, odfi, _ := setupAS2(t)
+
+	// sign with a certificate the ODFI doesn't know
+	dir := filepath.Dir(agent.cfg.AS2.CertificateFile)
+	certPath, keyPath := writeTestCertificate(t, dir, "other")
+	agent.cert, _ = readCertificate(certPath)
+	agent.key, _ = readPrivateKey(keyPath)
+
+	err := agent.UploadFile(File{
+		Filename: "ppd-debit.ach",
+		Contents: ioutil.NopCloser(strings.NewReader("101 contents")),
+	})
+	if err == nil || !strings.Contains(err.Error(), "not processed") {
+		t.Fatalf("expected error: %v", err)
+	}
+	if files, _ := odfi.GetInboundFiles(); len(files) != 0 {
+		t.Errorf("unexpected files: %#v", files)
+	}
+}
+
+func TestAS2__storagePath(t *testing.T) {
+	agent := &AS2TransferAgent{
+		cfg: config.ODFI{
+			AS2: &config.AS2{StorageDirectory: "/var/as2"},
+		},
+	}
+	if path := agent.storagePath("../../etc/passwd"); path != "/var/as2/etc/passwd" {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if path := agent.storagePath("inbound/file.ach"); path != "/var/as2/inbound/file.ach" {
+		t.Errorf("unexpected path: %s", path)
+	}
+}
+
+func TestAS2__smime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "as2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath, keyPath := writeTestCertificate(t, dir, "paygate")
+	cert, err := readCertificate(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := readPrivateKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("Content-Type: application/octet-stream\r\n\r\nhello, world")
+	sig, err := signDetached(content, cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyDetached(content, sig, cert); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyDetached(append(content, '!'), sig, cert); err == nil {
+		t.Error("expected error with modified content")
+	}
+
+	encrypted, err := encryptFor(content, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := decrypt(encrypted, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, decrypted) {
+		t.Errorf("unexpected contents: %q", decrypted)
+	}
+}
+
+func TestAS2__berToDER(t *testing.T) {
+	// indefinite length SEQUENCE holding a constructed OCTET STRING
+	ber := []byte{0x30, 0x80, 0x24, 0x80, 0x04, 0x01, 'a', 0x04, 0x01, 'b', 0x00, 0x00, 0x00, 0x00}
+	der, err := berToDER(ber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x30, 0x08, 0x24, 0x06, 0x04, 0x01, 'a', 0x04, 0x01, 'b'}
+	if !bytes.Equal(der, expected) {
+		t.Errorf("unexpected DER: %x", der)
+	}
+
+	if _, err := berToDER([]byte{0x30, 0x80, 0x04}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAS2__splitMultipart(t *testing.T) {
+	body := []byte("preamble\r\n--b\r\nContent-Type: text/plain\r\n\r\none\r\n--b\r\n\r\ntwo\r\n--b--\r\n")
+	parts, err := splitMultipart(body, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || string(parts[0]) != "Content-Type: text/plain\r\n\r\none" || string(parts[1]) != "\r\ntwo" {
+		t.Errorf("unexpected parts: %q", parts)
+	}
+
+	if _, err := splitMultipart(body, "missing"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/customers/pkg/secrets"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// Credentials are what FTP and SFTP agents log in with.
+type Credentials struct {
+	Password                   string `json:"password,omitempty"`
+	ClientPrivateKey           string `json:"clientPrivateKey,omitempty"`
+	ClientPrivateKeyPassphrase string `json:"clientPrivateKeyPassphrase,omitempty"`
+}
+
+// CredentialStore decrypts the FTP or SFTP credentials of the config and saves credentials
+// rotated at runtime encrypted in the database. It also keeps the SFTP host key pinned from
+// the admin server. Agents read the latest credentials and host key each time they connect,
+// so every instance uses them once it reconnects.
+type CredentialStore struct {
+	db     *sql.DB
+	keeper *secrets.StringKeeper // nil unless odfi.credentials is configured
+	cfg    config.ODFI
+
+	// rotations is incremented when this instance rotates the credentials or host key,
+	// which makes its agents reconnect before their next call
+	rotations uint64
+}
+
+// NewCredentialStore returns nil unless odfi.credentials or an SFTP agent is configured, in
+// which case agents log in with the plaintext credentials of the config. Without
+// odfi.credentials the credentials of the config are plaintext and can't be rotated.
+func NewCredentialStore(db *sql.DB, cfg config.ODFI) (*CredentialStore, error) {
+	if cfg.Credentials == nil && cfg.SFTP == nil {
+		return nil, nil
+	}
+	store := &CredentialStore{
+		db:  db,
+		cfg: cfg,
+	}
+	if cfg.Credentials != nil {
+		keeper, err := secrets.OpenLocal(cfg.Credentials.Symmetric.KeyURI)
+		if err != nil {
+			return nil, err
+		}
+		store.keeper = secrets.NewStringKeeper(keeper, 5*time.Second)
+	}
+	// Fail on startup rather than each connection when the config can't be decrypted
+	if _, _, err := store.configured(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// login returns the credentials agents log in with: the latest rotated credentials or
+// those of the config. A nil CredentialStore returns the plaintext credentials of cfg.
+func (s *CredentialStore) login(cfg config.ODFI) (Credentials, error) {
+	if s == nil {
+		switch {
+		case cfg.FTP != nil:
+			return Credentials{Password: cfg.FTP.Password}, nil
+		case cfg.SFTP != nil:
+			return Credentials{
+				Password:                   cfg.SFTP.Password,
+				ClientPrivateKey:           cfg.SFTP.ClientPrivateKey,
+				ClientPrivateKeyPassphrase: cfg.SFTP.ClientPrivateKeyPassphrase,
+			}, nil
+		}
+		return Credentials{}, nil
+	}
+	if s.keeper == nil {
+		creds, _, err := s.configured()
+		return creds, err
+	}
+
+	defer database.MeasureQuery("upload", "getCredentials")()
+
+	query := `select password, client_private_key, coalesce(client_private_key_passphrase, '') from upload_credentials order by created_at desc limit 1;`
+	var password, key, passphrase string
+	if err := s.db.QueryRow(query).Scan(&password, &key, &passphrase); err != nil {
+		if err == sql.ErrNoRows {
+			creds, _, err := s.configured()
+			return creds, err
+		}
+		return Credentials{}, err
+	}
+	return s.decrypt(password, key, passphrase)
+}
+
+// configured returns the credentials of the config and the type of agent using them.
+func (s *CredentialStore) configured() (Credentials, string, error) {
+	switch {
+	case s.cfg.FTP != nil:
+		creds, err := s.decrypt(s.cfg.FTP.Password, "", "")
+		return creds, "ftp", err
+	case s.cfg.SFTP != nil:
+		creds, err := s.decrypt(s.cfg.SFTP.Password, s.cfg.SFTP.ClientPrivateKey, s.cfg.SFTP.ClientPrivateKeyPassphrase)
+		return creds, "sftp", err
+	}
+	return Credentials{}, "", fmt.Errorf("credentials aren't used by %s agents", Type(s.cfg))
+}
+
+// decrypt returns the plaintext credentials, which are returned as-is without a keeper.
+func (s *CredentialStore) decrypt(password, key, passphrase string) (Credentials, error) {
+	if s.keeper == nil {
+		return Credentials{Password: password, ClientPrivateKey: key, ClientPrivateKeyPassphrase: passphrase}, nil
+	}
+	var creds Credentials
+	var err error
+	if password != "" {
+		if creds.Password, err = s.keeper.DecryptString(password); err != nil {
+			return creds, fmt.Errorf("decrypting password: %v", err)
+		}
+	}
+	if key != "" {
+		if creds.ClientPrivateKey, err = s.keeper.DecryptString(key); err != nil {
+			return creds, fmt.Errorf("decrypting client private key: %v", err)
+		}
+	}
+	if passphrase != "" {
+		if creds.ClientPrivateKeyPassphrase, err = s.keeper.DecryptString(passphrase); err != nil {
+			return creds, fmt.Errorf("decrypting client private key passphrase: %v", err)
+		}
+	}
+	return creds, nil
+}
+
+// Rotate saves creds encrypted and makes the agents of this instance reconnect with them
+// once their current call finishes.
+func (s *CredentialStore) Rotate(creds Credentials, rotatedBy string) error {
+	if s.keeper == nil {
+		return errors.New("odfi.credentials isn't configured")
+	}
+	defer database.MeasureQuery("upload", "rotateCredentials")()
+
+	var password, key, passphrase string
+	var err error
+	if creds.Password != "" {
+		if password, err = s.keeper.EncryptString(creds.Password); err != nil {
+			return fmt.Errorf("encrypting password: %v", err)
+		}
+	}
+	if creds.ClientPrivateKey != "" {
+		if key, err = s.keeper.EncryptString(creds.ClientPrivateKey); err != nil {
+			return fmt.Errorf("encrypting client private key: %v", err)
+		}
+	}
+	if creds.ClientPrivateKeyPassphrase != "" {
+		if passphrase, err = s.keeper.EncryptString(creds.ClientPrivateKeyPassphrase); err != nil {
+			return fmt.Errorf("encrypting client private key passphrase: %v", err)
+		}
+	}
+
+	query := `insert into upload_credentials (credential_id, password, client_private_key, client_private_key_passphrase, rotated_by, created_at) values (?, ?, ?, ?, ?, ?);`
+	if _, err := s.db.Exec(query, base.ID(), password, key, passphrase, rotatedBy, time.Now()); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.rotations, 1)
+	return nil
+}
+
+// seen returns how many times this instance rotated the credentials or host key, which agents
+// save when they connect.
+func (s *CredentialStore) seen() uint64 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&s.rotations)
+}
+
+// rotated returns true when this instance rotated the credentials or host key since an agent
+// connected after seen rotations.
+func (s *CredentialStore) rotated(seen uint64) bool {
+	return s.seen() != seen
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+func (s *CredentialStore) RegisterRoutes(logger log.Logger, svc *admin.Server) {
+	if s == nil {
+		return
+	}
+	if s.keeper != nil {
+		svc.AddHandler("/upload/credentials", s.rotateCredentials(logger))
+	}
+	if s.cfg.SFTP != nil {
+		svc.AddHandler("/upload/host-key", s.hostKeyHandler(logger))
+	}
+}
+
+func (s *CredentialStore) rotateCredentials(logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodPut {
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+			return
+		}
+
+		var creds Credentials
+		if err := route.DecodeJSON(r, &creds, route.DisallowUnknownFields); err != nil {
+			route.Problem(w, err)
+			return
+		}
+		userID := moovhttp.GetUserID(r)
+
+		verr := &route.ValidationError{}
+		if userID == "" {
+			verr.Add("X-User-ID", "missing")
+		}
+		_, agentType, _ := s.configured()
+		switch agentType {
+		case "ftp":
+			if creds.Password == "" {
+				verr.Add("password", "missing")
+			}
+			if creds.ClientPrivateKey != "" || creds.ClientPrivateKeyPassphrase != "" {
+				verr.Add("clientPrivateKey", "isn't used by ftp")
+			}
+		case "sftp":
+			if creds.Password == "" && creds.ClientPrivateKey == "" {
+				verr.Add("password", "missing password or clientPrivateKey")
+			}
+			if creds.ClientPrivateKey != "" {
+				if _, err := readSigner(creds.ClientPrivateKey, creds.ClientPrivateKeyPassphrase); err != nil {
+					verr.Add("clientPrivateKey", "%v", err)
+				}
+			}
+		}
+		if err := verr.Err(); err != nil {
+			route.Problem(w, err)
+			return
+		}
+
+		if err := s.Rotate(creds, userID); err != nil {
+			route.Problem(w, route.Internal.New("saving credentials: %v", err))
+			return
+		}
+		logger.With(log.Fields{
+			"userID":           log.String(userID),
+			"password":         log.Bool(creds.Password != ""),
+			"clientPrivateKey": log.Bool(creds.ClientPrivateKey != ""),
+		}).Logf("rotated %s credentials", agentType)
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+type pinHostKeyRequest struct {
+	HostPublicKey string `json:"hostPublicKey"`
+}
+
+func (s *CredentialStore) hostKeyHandler(logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		switch r.Method {
+		case http.MethodGet:
+			key, err := s.hostKey(s.cfg)
+			if err != nil {
+				route.Problem(w, route.Internal.New("reading host key: %v", err))
+				return
+			}
+			json.NewEncoder(w).Encode(key)
+
+		case http.MethodPut:
+			var req pinHostKeyRequest
+			if err := route.DecodeJSON(r, &req, route.DisallowUnknownFields); err != nil {
+				route.Problem(w, err)
+				return
+			}
+			userID := moovhttp.GetUserID(r)
+
+			verr := &route.ValidationError{}
+			if userID == "" {
+				verr.Add("X-User-ID", "missing")
+			}
+			if req.HostPublicKey == "" {
+				verr.Add("hostPublicKey", "missing")
+			} else if _, _, err := parseHostKey(req.HostPublicKey); err != nil {
+				verr.Add("hostPublicKey", "%v", err)
+			}
+			if err := verr.Err(); err != nil {
+				route.Problem(w, err)
+				return
+			}
+
+			if err := s.PinHostKey(req.HostPublicKey, userID); err != nil {
+				route.Problem(w, route.Internal.New("saving host key: %v", err))
+				return
+			}
+			key, err := s.hostKey(s.cfg)
+			if err != nil {
+				route.Problem(w, route.Internal.New("reading host key: %v", err))
+				return
+			}
+			logger.With(log.Fields{
+				"userID":      log.String(userID),
+				"fingerprint": log.String(key.Fingerprint),
+			}).Log("pinned sftp host key")
+
+			json.NewEncoder(w).Encode(key)
+
+		default:
+			route.Problem(w, route.MethodNotAllowed.New("invalid method %s", r.Method))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/moov-io/base/log"
+)
+
+func setupCredentialStore(t *testing.T) *CredentialStore {
+	t.Helper()
+
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.ODFI{
+		SFTP: &config.SFTP{Hostname: "sftp.bank.com:22"},
+		Credentials: &config.UploadCredentials{
+			Symmetric: &config.Symmetric{KeyURI: "base64key://MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI="},
+		},
+	}
+	store, err := NewCredentialStore(db.DB, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the config holds ciphertext from the keeper
+	password, err := store.keeper.EncryptString("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.cfg.SFTP.Password = password
+	return store
+}
+
+func TestCredentialStore(t *testing.T) {
+	var store *CredentialStore
+	if creds, err := store.login(config.ODFI{FTP: &config.FTP{Password: "plain"}}); err != nil || creds.Password != "plain" {
+		t.Fatalf("creds=%#v error=%v", creds, err)
+	}
+	if store.rotated(0) {
+		t.Error("nil store rotated")
+	}
+
+	store = setupCredentialStore(t)
+	creds, err := store.login(store.cfg)
+	if err != nil || creds.Password != "first" {
+		t.Fatalf("creds=%#v error=%v", creds, err)
+	}
+
+	seen := store.seen()
+	if err := store.Rotate(Credentials{Password: "second"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if !store.rotated(seen) {
+		t.Error("expected rotation")
+	}
+	creds, err = store.login(store.cfg)
+	if err != nil || creds.Password != "second" {
+		t.Fatalf("creds=%#v error=%v", creds, err)
+	}
+
+	// saved credentials are encrypted
+	var password string
+	if err := store.db.QueryRow(`select password from upload_credentials;`).Scan(&password); err != nil {
+		t.Fatal(err)
+	}
+	if password == "" || password == "second" {
+		t.Errorf("unexpected password: %q", password)
+	}
+}
+
+func TestCredentialStore__rotateRoute(t *testing.T) {
+	store := setupCredentialStore(t)
+	handler := store.rotateCredentials(log.NewNopLogger())
+
+	req := httptest.NewRequest("PUT", "/upload/credentials", bytes.NewReader([]byte(`{"clientPrivateKey":"bad key"}`)))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+
+	req = httptest.NewRequest("PUT", "/upload/credentials", bytes.NewReader([]byte(`{"password":"second"}`)))
+	req.Header.Set("X-User-ID", "operator")
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	if creds, err := store.login(store.cfg); err != nil || creds.Password != "second" {
+		t.Errorf("creds=%#v error=%v", creds, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// tempFile is a downloaded file stored on disk which is removed once closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); rerr != nil && err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// downloadToTemp copies r into a temporary file so remote files aren't held in memory.
+// A nil ReadCloser is returned when r is empty.
+func downloadToTemp(r io.Reader) (io.ReadCloser, error) {
+	fd, err := ioutil.TempFile("", "paygate-download-")
+	if err != nil {
+		return nil, err
+	}
+	f := &tempFile{File: fd}
+
+	n, err := io.Copy(f, r)
+	if n == 0 || err != nil {
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("n=%d error=%v", n, err)
+		}
+		return nil, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// downloadFunc retrieves a remote file by name with the connection a worker owns.
+// A nil File is returned for names which aren't files.
+type downloadFunc func(worker int, name string) (*File, error)
+
+// downloadAll runs download over names with the given number of workers. Files are
+// returned in the order of names and on any error every downloaded file is closed.
+func downloadAll(names []string, workers int, download downloadFunc) ([]File, error) {
+	if workers > len(names) {
+		workers = len(names)
+	}
+	results := make([]*File, len(names))
+	errs := make([]error, len(names))
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := range work {
+				results[i], errs[i] = download(worker, names[i])
+			}
+		}(w)
+	}
+	for i := range names {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	var firstErr error
+	for i := range errs {
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+	var files []File
+	for i := range results {
+		if results[i] == nil {
+			continue
+		}
+		if firstErr != nil {
+			results[i].Close()
+			continue
+		}
+		files = append(files, *results[i])
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return files, nil
+}
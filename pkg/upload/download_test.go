@@ -0,0 +1,77 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDownloadToTemp(t *testing.T) {
+	r, err := downloadToTemp(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := r.(*tempFile).Name()
+	bs, _ := ioutil.ReadAll(r)
+	if string(bs) != "hello" {
+		t.Errorf("unexpected contents: %q", bs)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed: %v", path, err)
+	}
+
+	// empty files are skipped
+	r, err = downloadToTemp(strings.NewReader(""))
+	if r != nil || err != nil {
+		t.Errorf("r=%v error=%v", r, err)
+	}
+}
+
+func TestDownloadAll(t *testing.T) {
+	names := []string{"a.ach", "b.ach", "dir", "c.ach", "d.ach"}
+
+	var calls int32
+	files, err := downloadAll(names, 3, func(worker int, name string) (*File, error) {
+		atomic.AddInt32(&calls, 1)
+		if worker < 0 || worker >= 3 {
+			return nil, fmt.Errorf("unexpected worker %d", worker)
+		}
+		if name == "dir" {
+			return nil, nil
+		}
+		return &File{Filename: name, Contents: ioutil.NopCloser(strings.NewReader(name))}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 5 || len(files) != 4 {
+		t.Fatalf("calls=%d files=%d", calls, len(files))
+	}
+	for i, name := range []string{"a.ach", "b.ach", "c.ach", "d.ach"} {
+		if files[i].Filename != name {
+			t.Errorf("files[%d]=%s", i, files[i].Filename)
+		}
+	}
+
+	// any error fails the whole download
+	files, err = downloadAll(names, 2, func(_ int, name string) (*File, error) {
+		if name == "c.ach" {
+			return nil, errors.New("bad thing")
+		}
+		return &File{Filename: name, Contents: ioutil.NopCloser(strings.NewReader(name))}, nil
+	})
+	if err == nil || len(files) != 0 {
+		t.Errorf("files=%d error=%v", len(files), err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// faultyAgent wraps an Agent and injects latency, partial uploads and connection
+// resets so operators can verify retries and alerting before using a production ODFI.
+type faultyAgent struct {
+	Agent
+
+	cfg    *config.Faults
+	logger log.Logger
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newFaultyAgent(logger log.Logger, agent Agent, cfg *config.Faults) *faultyAgent {
+	logger = logger.Set("service", log.String("upload-faults"))
+	logger.Warn().Logf("injecting faults into %s agent: %#v", agent.Hostname(), cfg)
+
+	return &faultyAgent{
+		Agent:  agent,
+		cfg:    cfg,
+		logger: logger,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (a *faultyAgent) float64() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rand.Float64()
+}
+
+// inject sleeps for a random latency and possibly returns a connection reset.
+func (a *faultyAgent) inject(op string) error {
+	if a.cfg.MaxLatency > 0 {
+		time.Sleep(time.Duration(a.float64() * float64(a.cfg.MaxLatency)))
+	}
+	if a.cfg.ConnectionResetRate > 0 && a.float64() < a.cfg.ConnectionResetRate {
+		a.logger.Logf("injecting connection reset into %s", op)
+		return &net.OpError{
+			Op:  op,
+			Net: "tcp",
+			Err: os.NewSyscallError("read", syscall.ECONNRESET),
+		}
+	}
+	return nil
+}
+
+func (a *faultyAgent) GetInboundFiles() ([]File, error) {
+	if err := a.inject("GetInboundFiles"); err != nil {
+		return nil, err
+	}
+	return a.Agent.GetInboundFiles()
+}
+
+func (a *faultyAgent) GetReturnFiles() ([]File, error) {
+	if err := a.inject("GetReturnFiles"); err != nil {
+		return nil, err
+	}
+	return a.Agent.GetReturnFiles()
+}
+
+func (a *faultyAgent) UploadFile(f File) error {
+	if err := a.inject("UploadFile"); err != nil {
+		return err
+	}
+	if a.cfg.PartialUploadRate <= 0 || a.float64() >= a.cfg.PartialUploadRate {
+		return a.Agent.UploadFile(f)
+	}
+
+	// Upload a truncated copy of the file, like a connection that dropped mid-transfer
+	bs, err := ioutil.ReadAll(f.Contents)
+	if err != nil {
+		return err
+	}
+	n := int(a.float64() * float64(len(bs)))
+	a.logger.Logf("injecting partial upload of %s (%d of %d bytes)", f.Filename, n, len(bs))
+
+	err = a.Agent.UploadFile(File{
+		Filename: f.Filename,
+		Contents: ioutil.NopCloser(bytes.NewReader(bs[:n])),
+	})
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("partial upload of %s: wrote %d of %d bytes", f.Filename, n, len(bs))
+}
+
+func (a *faultyAgent) FileSize(path string) (int64, error) {
+	if err := a.inject("FileSize"); err != nil {
+		return 0, err
+	}
+	return a.Agent.FileSize(path)
+}
+
+func (a *faultyAgent) ReadFile(path string) (*File, error) {
+	if err := a.inject("ReadFile"); err != nil {
+		return nil, err
+	}
+	return a.Agent.ReadFile(path)
+}
+
+func (a *faultyAgent) Delete(path string) error {
+	if err := a.inject("Delete"); err != nil {
+		return err
+	}
+	return a.Agent.Delete(path)
+}
+
+func (a *faultyAgent) Ping() error {
+	if err := a.inject("Ping"); err != nil {
+		return err
+	}
+	return a.Agent.Ping()
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestFaultyAgent__passthrough(t *testing.T) {
+	mock := &MockAgent{}
+	agent := newFaultyAgent(log.NewNopLogger(), mock, &config.Faults{})
+
+	if err := agent.UploadFile(File{Filename: "a.ach", Contents: ioutil.NopCloser(strings.NewReader("hello"))}); err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := ioutil.ReadAll(mock.UploadedFile.Contents)
+	if string(bs) != "hello" {
+		t.Errorf("unexpected upload: %q", bs)
+	}
+	if err := agent.Ping(); err != nil {
+		t.Error(err)
+	}
+	if agent.OutboundPath() != "outbound/" {
+		t.Errorf("unexpected path: %s", agent.OutboundPath())
+	}
+}
+
+func TestFaultyAgent__connectionReset(t *testing.T) {
+	agent := newFaultyAgent(log.NewNopLogger(), &MockAgent{}, &config.Faults{
+		ConnectionResetRate: 1.0,
+	})
+
+	err := agent.Ping()
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := agent.GetInboundFiles(); err == nil {
+		t.Error("expected error")
+	}
+	if err := agent.Delete("foo.ach"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestFaultyAgent__partialUpload(t *testing.T) {
+	mock := &MockAgent{}
+	agent := newFaultyAgent(log.NewNopLogger(), mock, &config.Faults{
+		PartialUploadRate: 1.0,
+	})
+	agent.rand = rand.New(rand.NewSource(1))
+
+	contents := strings.Repeat("a", 1000)
+	err := agent.UploadFile(File{Filename: "a.ach", Contents: ioutil.NopCloser(strings.NewReader(contents))})
+	if err == nil || !strings.Contains(err.Error(), "partial upload of a.ach") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	bs, _ := ioutil.ReadAll(mock.UploadedFile.Contents)
+	if len(bs) >= len(contents) {
+		t.Errorf("expected a partial file, got %d bytes", len(bs))
+	}
+}
+
+func TestFaultyAgent__latency(t *testing.T) {
+	agent := newFaultyAgent(log.NewNopLogger(), &MockAgent{}, &config.Faults{
+		MaxLatency: 20 * time.Millisecond,
+	})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := agent.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("latency exceeded MaxLatency: %v", time.Since(start))
+	}
+}
@@ -5,13 +5,13 @@
 package upload
 
 import (
-	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -22,6 +22,7 @@ import (
 	"github.com/jlaffaye/ftp"
 	"github.com/moov-io/base/log"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 )
 
 var (
@@ -33,22 +34,28 @@ var (
 
 // FTPTransferAgent is an FTP implementation of a Agent
 type FTPTransferAgent struct {
-	conn   *ftp.ServerConn
-	cfg    config.ODFI
-	logger log.Logger
-	mu     sync.Mutex // protects all read/write methods
+	conn    *ftp.ServerConn
+	cfg     config.ODFI
+	limiter *rate.Limiter
+	logger  log.Logger
+	mu      sync.Mutex // protects all read/write methods
+
+	credentials *CredentialStore
+	rotations   uint64 // credential rotations seen when conn was opened
 }
 
 // TODO(adam): What sort of metrics should we collect? Just each operation into a histogram?
 // If so we could wrap those in an Agent shim with Prometheus
 
-func newFTPTransferAgent(logger log.Logger, cfg config.ODFI) (*FTPTransferAgent, error) {
+func newFTPTransferAgent(logger log.Logger, cfg config.ODFI, credentials *CredentialStore) (*FTPTransferAgent, error) {
 	if cfg.FTP == nil {
 		return nil, errors.New("nil FTP config")
 	}
 	agent := &FTPTransferAgent{
-		cfg:    cfg,
-		logger: logger,
+		cfg:         cfg,
+		limiter:     newBandwidthLimiter(cfg.Throttle),
+		logger:      logger,
+		credentials: credentials,
 	}
 
 	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), cfg.FTP.Hostname); err != nil {
@@ -72,19 +79,41 @@ func (agent *FTPTransferAgent) connection() (*ftp.ServerConn, error) {
 
 	if agent.conn != nil {
 		// Verify the connection works and f not drop through and reconnect
-		if err := agent.conn.NoOp(); err == nil {
+		if agent.credentials.rotated(agent.rotations) {
+			// Log in again with the rotated credentials
+			agent.logger.Info().Log("ftp: reconnecting with rotated credentials")
+			agent.conn.Quit()
+		} else if err := agent.conn.NoOp(); err == nil {
 			return agent.conn, nil
 		} else {
 			// Our connection is having issues, so retry connecting
 			agent.conn.Quit()
 		}
+		agent.conn = nil
+	}
+
+	rotations := agent.credentials.seen()
+	conn, err := agent.dial()
+	if err != nil {
+		return nil, err
 	}
+	agent.conn, agent.rotations = conn, rotations
 
+	return agent.conn, nil
+}
+
+// dial opens and logs into a new connection with the remote server.
+func (agent *FTPTransferAgent) dial() (*ftp.ServerConn, error) {
 	// Setup our FTP connection
 	opts := []ftp.DialOption{
 		ftp.DialWithTimeout(agent.cfg.FTP.Timeout()),
 		ftp.DialWithDisabledEPSV(agent.cfg.FTP.DisableEPSV()),
 	}
+	if agent.limiter != nil {
+		opts = append(opts, ftp.DialWithDialFunc(func(network, address string) (net.Conn, error) {
+			return dialThrottled(network, address, agent.cfg.FTP.Timeout(), agent.limiter)
+		}))
+	}
 	tlsOpt, err := tlsDialOption(agent.cfg.FTP.CAFile())
 	if err != nil {
 		return nil, err
@@ -98,12 +127,16 @@ func (agent *FTPTransferAgent) connection() (*ftp.ServerConn, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := conn.Login(agent.cfg.FTP.Username, agent.cfg.FTP.Password); err != nil {
+	creds, err := agent.credentials.login(agent.cfg)
+	if err != nil {
+		conn.Quit()
 		return nil, err
 	}
-	agent.conn = conn
-
-	return agent.conn, nil
+	if err := conn.Login(agent.cfg.FTP.Username, creds.Password); err != nil {
+		conn.Quit()
+		return nil, err
+	}
+	return conn, nil
 }
 
 func tlsDialOption(caFilePath string) (*ftp.DialOption, error) {
@@ -209,6 +242,60 @@ func (agent *FTPTransferAgent) Delete(path string) error {
 	return conn.Delete(path)
 }
 
+func (agent *FTPTransferAgent) FileSize(path string) (int64, error) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	return agent.fileSize(path)
+}
+
+func (agent *FTPTransferAgent) fileSize(path string) (int64, error) {
+	conn, err := agent.connection()
+	if err != nil {
+		return 0, err
+	}
+	if size, err := conn.FileSize(path); err == nil {
+		return size, nil
+	}
+
+	// Not every server supports SIZE, so fallback to listing the file
+	entries, err := conn.List(path)
+	if err != nil {
+		return 0, fmt.Errorf("FTP: problem listing %s: %v", path, err)
+	}
+	for i := range entries {
+		if entries[i].Name == filepath.Base(path) && entries[i].Type == ftp.EntryTypeFile {
+			return int64(entries[i].Size), nil
+		}
+	}
+	return 0, fmt.Errorf("FTP: %s not found", path)
+}
+
+func (agent *FTPTransferAgent) ReadFile(path string) (*File, error) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	conn, err := agent.connection()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := conn.Retr(path)
+	if err != nil {
+		return nil, fmt.Errorf("FTP: problem retrieving %s: %v", path, err)
+	}
+	r, err := agent.readResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("FTP: problem reading %s: %v", path, err)
+	}
+	if r == nil {
+		r = ioutil.NopCloser(strings.NewReader(""))
+	}
+	return &File{
+		Filename: filepath.Base(path),
+		Contents: r,
+	}, nil
+}
+
 // uploadFile saves the content of File at the given filename in the OutboundPath directory
 //
 // The File's contents will always be closed
@@ -218,6 +305,26 @@ func (agent *FTPTransferAgent) UploadFile(f File) error {
 	agent.mu.Lock()
 	defer agent.mu.Unlock()
 
+	if agent.cfg.ResumableUploads == nil {
+		return agent.storFrom(f.Filename, f.Contents, 0)
+	}
+
+	contents, err := ioutil.ReadAll(f.Contents)
+	if err != nil {
+		return fmt.Errorf("FTP: problem reading %s: %v", f.Filename, err)
+	}
+	write := func(offset int64, r io.Reader) error {
+		return agent.storFrom(f.Filename, r, offset)
+	}
+	size := func() (int64, error) {
+		return agent.fileSize(filepath.Join(agent.cfg.OutboundPath, filepath.Base(f.Filename)))
+	}
+	return resumeUpload(agent.logger, "ftp", agent.cfg.ResumableUploads, f.Filename, contents, write, size)
+}
+
+// storFrom writes r into the file at the given filename in the OutboundPath directory
+// starting at offset.
+func (agent *FTPTransferAgent) storFrom(filename string, r io.Reader, offset int64) error {
 	conn, err := agent.connection()
 	if err != nil {
 		return err
@@ -238,9 +345,11 @@ func (agent *FTPTransferAgent) UploadFile(f File) error {
 		}
 	}(wd)
 
+	agent.logger.With(config.Debug).Logf("FTP: uploading %s to %s from offset %d", filename, agent.cfg.OutboundPath, offset)
+
 	// Write file contents into path
-	// Take the base of f.Filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
-	return conn.Stor(filepath.Base(f.Filename), f.Contents)
+	// Take the base of filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
+	return conn.StorFrom(filepath.Base(filename), r, uint64(offset))
 }
 
 func (agent *FTPTransferAgent) GetInboundFiles() ([]File, error) {
@@ -279,43 +388,56 @@ func (agent *FTPTransferAgent) readFiles(path string) ([]File, error) {
 	if err != nil {
 		return nil, err
 	}
-	var files []File
-	for i := range items {
-		resp, err := conn.Retr(items[i])
+
+	// Each worker downloads over its own connection as the FTP client isn't goroutine-safe
+	workers := agent.cfg.Inbound.Concurrency()
+	if workers > len(items) {
+		workers = len(items)
+	}
+	conns := []*ftp.ServerConn{conn}
+	defer func() {
+		for i := 1; i < len(conns); i++ {
+			conns[i].Quit()
+		}
+	}()
+	for len(conns) < workers {
+		c, err := agent.dial()
 		if err != nil {
-			return nil, fmt.Errorf("problem retrieving %s: %v", items[i], err)
+			return nil, fmt.Errorf("problem opening download connection: %v", err)
+		}
+		conns = append(conns, c)
+		if err := c.ChangeDir(path); err != nil {
+			return nil, err
 		}
+	}
 
+	return downloadAll(items, len(conns), func(worker int, name string) (*File, error) {
+		resp, err := conns[worker].Retr(name)
+		if err != nil {
+			return nil, fmt.Errorf("problem retrieving %s: %v", name, err)
+		}
 		r, err := agent.readResponse(resp)
 		if err != nil {
-			return nil, fmt.Errorf("problem reading %s: %v", items[i], err)
+			return nil, fmt.Errorf("problem reading %s: %v", name, err)
 		}
-		if r != nil {
-			files = append(files, File{
-				Filename: items[i],
-				Contents: r,
-			})
+		if r == nil {
+			return nil, nil
 		}
-	}
-	return files, nil
+		return &File{
+			Filename: name,
+			Contents: r,
+		}, nil
+	})
 }
 
 func (*FTPTransferAgent) readResponse(resp *ftp.Response) (io.ReadCloser, error) {
 	defer resp.Close()
 
-	var buf bytes.Buffer
-	n, err := io.Copy(&buf, resp)
 	// If there was nothing downloaded and no error then assume it's a directory.
 	//
 	// The FTP client doesn't have a STAT command, so we can't quite ensure this
 	// was a directory.
 	//
 	// See https://github.com/moov-io/paygate/issues/494
-	if n == 0 && err == nil {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("n=%d error=%v", n, err)
-	}
-	return ioutil.NopCloser(&buf), nil
+	return downloadToTemp(resp)
 }
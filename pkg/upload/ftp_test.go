@@ -21,7 +21,7 @@ import (
 	"github.com/moov-io/base"
 
 	"github.com/moov-io/paygate/pkg/config"
- "github.com/moov-io/paygate/pkg/httptest"
+	mhttptest "github.com/moov-io/paygate/pkg/httptest"
 	"github.com/moov-io/paygate/pkg/util"
 
 	"github.com/jlaffaye/ftp"
@@ -31,7 +31,7 @@ import (
 )
 
 var (
-	portSource = 30000	rand.NewSource(time.Now().Unix())
+	portSource = rand.NewSource(time.Now().Unix())
 
 	rootFTPPath = filepath.Join("..", "..", "testdata", "ftp-server")
 )
@@ -40,7 +40,7 @@ func port() int {
 	return int(30000 + (portSource.Int63() % 9999))
 }
 
-func createTestFTPServer(t *testing.T) (*server.Paygate.app, error) {
+func createTestFTPServer(t *testing.T) (*server.Server, error) {
 	t.Helper()
 	if testing.Short() {
 		t.Skip("skipping due to -short")
@@ -54,14 +54,14 @@ func createTestFTPServer(t *testing.T) (*server.Paygate.app, error) {
 	opts := &server.ServerOpts{
 		Auth: &server.SimpleAuth{
 			Name:     "moov",
-			Password: "p******d",
+			Password: "password",
 		},
 		Factory: &file.DriverFactory{
 			RootPath: rootFTPPath,
 			Perm:     server.NewSimplePerm("test", "test"),
 		},
 		Hostname: "localhost",
-		Port: 22    port(),
+		Port:     port(),
 		Logger:   &server.DiscardLogger{},
 	}
 	svc := server.NewServer(opts)
@@ -79,9 +79,9 @@ func createTestFTPServer(t *testing.T) (*server.Paygate.app, error) {
 
 func TestFTPConfig__String(t *testing.T) {
 	cfg := &config.FTP{
-		Hostname: "Github.com",
-		Username: "Jarule369",
-		Password: "Element8899!",
+		Hostname: "host",
+		Username: "user",
+		Password: "pass",
 	}
 	if !strings.Contains(cfg.String(), "Password=p**s") {
 		t.Error(cfg.String())
@@ -158,7 +158,7 @@ func createTestFTPAgent(t *testing.T) (*server.Server, *FTPTransferAgent) {
 			Password: auth.Password,
 		},
 	}
-	agent, err := newFTPTransferAgent(log.NewNopLogger(), cfg)
+	agent, err := newFTPTransferAgent(log.NewNopLogger(), cfg, nil)
 	if err != nil {
 		svc.Shutdown()
 		t.Fatalf("problem creating Agent: %v", err)
@@ -0,0 +1,118 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/moov-io/paygate/internal/sshx"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+
+	"github.com/moov-io/base"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKey is the SFTP host key agents verify the server against.
+type HostKey struct {
+	HostPublicKey string    `json:"hostPublicKey"`
+	Fingerprint   string    `json:"fingerprint"`
+	UpdatedBy     string    `json:"updatedBy,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt,omitempty"`
+}
+
+// hostKey returns the host key pinned from the admin server, or the hostPublicKey of the config
+// until one is. A nil CredentialStore returns the host key of cfg.
+func (s *CredentialStore) hostKey(cfg config.ODFI) (HostKey, error) {
+	if cfg.SFTP == nil {
+		return HostKey{}, nil
+	}
+	key := HostKey{HostPublicKey: cfg.SFTP.HostPublicKey}
+	if s != nil {
+		defer database.MeasureQuery("upload", "getHostKey")()
+
+		query := `select host_public_key, updated_by, created_at from upload_host_keys order by created_at desc limit 1;`
+		err := s.db.QueryRow(query).Scan(&key.HostPublicKey, &key.UpdatedBy, &key.UpdatedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return key, err
+		}
+	}
+	if key.HostPublicKey != "" {
+		pubKey, _, err := parseHostKey(key.HostPublicKey)
+		if err != nil {
+			return key, err
+		}
+		key.Fingerprint = ssh.FingerprintSHA256(pubKey)
+	}
+	return key, nil
+}
+
+// PinHostKey saves hostPublicKey and makes the SFTP agents of this instance reconnect and
+// verify the server against it once their current call finishes.
+func (s *CredentialStore) PinHostKey(hostPublicKey string, updatedBy string) error {
+	if _, _, err := parseHostKey(hostPublicKey); err != nil {
+		return err
+	}
+
+	defer database.MeasureQuery("upload", "pinHostKey")()
+
+	query := `insert into upload_host_keys (host_key_id, host_public_key, updated_by, created_at) values (?, ?, ?, ?);`
+	if _, err := s.db.Exec(query, base.ID(), hostPublicKey, updatedBy, time.Now()); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.rotations, 1)
+	return nil
+}
+
+// parseHostKey reads a known_hosts entry, whose hosts are returned, and otherwise an
+// authorized_keys entry or raw public key.
+func parseHostKey(raw string) (ssh.PublicKey, []string, error) {
+	if _, hosts, pubKey, _, _, err := ssh.ParseKnownHosts([]byte(raw)); err == nil {
+		return pubKey, hosts, nil
+	}
+	pubKey, err := sshx.ReadPubKey([]byte(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem parsing ssh public key: %v", err)
+	}
+	return pubKey, nil, nil
+}
+
+// hostKeyCallback verifies the server's key against the host public key of cfg. Servers aren't
+// verified without one unless cfg.SFTP.StrictHostKeyChecking is set.
+func hostKeyCallback(cfg *config.SFTP) (ssh.HostKeyCallback, error) {
+	if cfg.HostPublicKey == "" {
+		if cfg.StrictHostKeyChecking {
+			return nil, errors.New("strictHostKeyChecking requires a host public key")
+		}
+		return nil, nil
+	}
+	pubKey, hosts, err := parseHostKey(cfg.HostPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return ssh.FixedHostKey(pubKey), nil
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		addr := knownhosts.Normalize(hostname)
+		for i := range hosts {
+			if knownhosts.Normalize(hosts[i]) != addr {
+				continue
+			}
+			if !bytes.Equal(key.Marshal(), pubKey.Marshal()) {
+				return fmt.Errorf("host key mismatch for %s: got %s", hostname, ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}
+		return fmt.Errorf("%s isn't a host of the known_hosts entry", hostname)
+	}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	key, other := testHostKey(t), testHostKey(t)
+
+	// without a host key servers aren't verified unless it's strict
+	if cb, err := hostKeyCallback(&config.SFTP{}); cb != nil || err != nil {
+		t.Fatalf("unexpected callback: %v", err)
+	}
+	if _, err := hostKeyCallback(&config.SFTP{StrictHostKeyChecking: true}); err == nil {
+		t.Error("expected error")
+	}
+
+	// authorized_keys entry
+	cb, err := hostKeyCallback(&config.SFTP{HostPublicKey: string(ssh.MarshalAuthorizedKey(key))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("sftp.bank.com:22", nil, key); err != nil {
+		t.Error(err)
+	}
+	if err := cb("sftp.bank.com:22", nil, other); err == nil {
+		t.Error("expected error")
+	}
+
+	// known_hosts entry
+	cb, err = hostKeyCallback(&config.SFTP{
+		HostPublicKey:         knownhosts.Line([]string{"sftp.bank.com:2222"}, key),
+		StrictHostKeyChecking: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cb("sftp.bank.com:2222", nil, key); err != nil {
+		t.Error(err)
+	}
+	if err := cb("sftp.bank.com:2222", nil, other); err == nil || !strings.Contains(err.Error(), "host key mismatch") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := cb("sftp.other.com:2222", nil, key); err == nil {
+		t.Error("expected error")
+	}
+
+	if _, err := hostKeyCallback(&config.SFTP{HostPublicKey: "bad key material"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestCredentialStore__pinHostKey(t *testing.T) {
+	store := setupCredentialStore(t)
+	key := testHostKey(t)
+	store.cfg.SFTP.HostPublicKey = string(ssh.MarshalAuthorizedKey(key))
+
+	hostKey, err := store.hostKey(store.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hostKey.Fingerprint != ssh.FingerprintSHA256(key) || hostKey.UpdatedBy != "" {
+		t.Errorf("unexpected host key: %#v", hostKey)
+	}
+
+	handler := store.hostKeyHandler(log.NewNopLogger())
+
+	req := httptest.NewRequest("PUT", "/upload/host-key", strings.NewReader(`{"hostPublicKey":"bad key material"}`))
+	req.Header.Set("X-User-ID", "operator")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+
+	// the bank rotated their host key
+	rotated := testHostKey(t)
+	body, _ := json.Marshal(pinHostKeyRequest{HostPublicKey: knownhosts.Line([]string{"sftp.bank.com"}, rotated)})
+	seen := store.seen()
+
+	req = httptest.NewRequest("PUT", "/upload/host-key", bytes.NewReader(body))
+	req.Header.Set("X-User-ID", "operator")
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	if !store.rotated(seen) {
+		t.Error("expected agents to reconnect")
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/upload/host-key", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+	if err := json.NewDecoder(w.Body).Decode(&hostKey); err != nil {
+		t.Fatal(err)
+	}
+	if hostKey.Fingerprint != ssh.FingerprintSHA256(rotated) || hostKey.UpdatedBy != "operator" {
+		t.Errorf("unexpected host key: %#v", hostKey)
+	}
+}
+
+func TestSFTP__readSignerPassphrase(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//nolint:staticcheck
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(priv), []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := string(pem.EncodeToMemory(block))
+
+	if _, err := readSigner(raw, ""); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := readSigner(raw, "wrong"); err == nil {
+		t.Error("expected error")
+	}
+	if sig, err := readSigner(raw, "secret"); sig == nil || err != nil {
+		t.Fatalf("Signer=%v error=%v", sig, err)
+	}
+}
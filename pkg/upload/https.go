@@ -0,0 +1,301 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+var (
+	httpsAgentUp = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "https_agent_up",
+		Help: "Status of HTTPS agent connection",
+	}, []string{"hostname"})
+)
+
+// HTTPSTransferAgent exchanges files with an ODFI's REST API. Remote paths are appended
+// to the configured URL:
+//
+//	GET    {url}/{inboundPath}             lists files as [{"filename": "..."}]
+//	GET    {url}/{inboundPath}/{filename}  downloads a file
+//	HEAD   {url}/{inboundPath}/{filename}  returns a file's Content-Length
+//	DELETE {url}/{inboundPath}/{filename}  removes a processed file
+//	POST   {url}/{outboundPath}/{filename} uploads a file
+type HTTPSTransferAgent struct {
+	cfg    config.ODFI
+	logger log.Logger
+
+	url    *url.URL
+	client *http.Client
+}
+
+// remoteFile is an entry in the ODFI's listing of files
+type remoteFile struct {
+	Filename string `json:"filename"`
+}
+
+func newHTTPSTransferAgent(logger log.Logger, cfg config.ODFI) (*HTTPSTransferAgent, error) {
+	if cfg.HTTPS == nil {
+		return nil, errors.New("nil HTTPS config")
+	}
+	u, err := url.Parse(cfg.HTTPS.URL)
+	if err != nil {
+		return nil, fmt.Errorf("https: invalid url: %v", err)
+	}
+	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), u.Hostname()); err != nil {
+		return nil, fmt.Errorf("https: %s is not whitelisted: %v", u.Hostname(), err)
+	}
+
+	transport := throttledTransport(newBandwidthLimiter(cfg.Throttle))
+	transport.TLSClientConfig, err = httpsTLSConfig(cfg.HTTPS)
+	if err != nil {
+		return nil, fmt.Errorf("https: %v", err)
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.HTTPS.Timeout(),
+	}
+	if oauth := cfg.HTTPS.OAuth2; oauth != nil {
+		creds := &clientcredentials.Config{
+			ClientID:     oauth.ClientID,
+			ClientSecret: oauth.ClientSecret,
+			TokenURL:     oauth.TokenURL,
+			Scopes:       oauth.Scopes,
+		}
+		// tokens are requested with the same TLS settings and throttling
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+		client = creds.Client(ctx)
+		client.Timeout = cfg.HTTPS.Timeout()
+	}
+
+	return &HTTPSTransferAgent{
+		cfg:    cfg,
+		logger: logger,
+		url:    u,
+		client: client,
+	}, nil
+}
+
+func httpsTLSConfig(cfg *config.HTTPS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.CAFile != "" {
+		bs, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", cfg.CAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if pool == nil || err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bs) {
+			return nil, fmt.Errorf("problem with AppendCertsFromPEM from %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCertificateFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertificateFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// remoteURL returns the URL of a remote path, which is cleaned so it can't refer to
+// anything outside of the configured URL.
+func (agent *HTTPSTransferAgent) remoteURL(remotePath string) string {
+	u := *agent.url
+	u.Path = path.Join("/", u.Path, path.Clean("/"+remotePath))
+	return u.String()
+}
+
+// do sends a request for remotePath and returns the response if it was successful.
+func (agent *HTTPSTransferAgent) do(method, remotePath string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, agent.remoteURL(remotePath), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	resp, err := agent.client.Do(req)
+	agent.record(err)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp, fmt.Errorf("%s %s: unexpected HTTP status %s: %s", method, remotePath, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (agent *HTTPSTransferAgent) record(err error) {
+	if agent == nil || agent.url == nil {
+		return
+	}
+	if err != nil {
+		httpsAgentUp.With("hostname", agent.url.Hostname()).Set(0)
+	} else {
+		httpsAgentUp.With("hostname", agent.url.Hostname()).Set(1)
+	}
+}
+
+func (agent *HTTPSTransferAgent) GetInboundFiles() ([]File, error) {
+	return agent.readFiles(agent.cfg.InboundPath)
+}
+
+func (agent *HTTPSTransferAgent) GetReturnFiles() ([]File, error) {
+	return agent.readFiles(agent.cfg.ReturnPath)
+}
+
+func (agent *HTTPSTransferAgent) readFiles(dir string) ([]File, error) {
+	listing, err := agent.list(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for i := range listing {
+		file, err := agent.ReadFile(path.Join(dir, listing[i].Filename))
+		if err != nil {
+			for j := range files {
+				files[j].Close()
+			}
+			return nil, err
+		}
+		if file.Contents == nil {
+			continue // skip empty files
+		}
+		files = append(files, *file)
+	}
+	return files, nil
+}
+
+func (agent *HTTPSTransferAgent) list(dir string) ([]remoteFile, error) {
+	resp, err := agent.do("GET", dir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("https: listing %s: %v", dir, err)
+	}
+	defer resp.Body.Close()
+
+	var files []remoteFile
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, fmt.Errorf("https: reading listing of %s: %v", dir, err)
+	}
+	return files, nil
+}
+
+func (agent *HTTPSTransferAgent) UploadFile(f File) error {
+	defer f.Close()
+
+	remotePath := path.Join(agent.cfg.OutboundPath, filepath.Base(f.Filename))
+	resp, err := agent.do("POST", remotePath, f.Contents)
+	if err != nil {
+		return fmt.Errorf("https: uploading %s: %v", f.Filename, err)
+	}
+	resp.Body.Close()
+
+	agent.logger.With(config.Debug).Logf("https: uploaded %s to %s", f.Filename, agent.cfg.OutboundPath)
+	return nil
+}
+
+func (agent *HTTPSTransferAgent) Delete(remotePath string) error {
+	resp, err := agent.do("DELETE", remotePath, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil // already deleted
+	}
+	if err != nil {
+		return fmt.Errorf("https: delete %s: %v", remotePath, err)
+	}
+	return resp.Body.Close()
+}
+
+func (agent *HTTPSTransferAgent) FileSize(remotePath string) (int64, error) {
+	resp, err := agent.do("HEAD", remotePath, nil)
+	if err != nil {
+		return 0, fmt.Errorf("https: size of %s: %v", remotePath, err)
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("https: size of %s: missing Content-Length", remotePath)
+	}
+	return resp.ContentLength, nil
+}
+
+func (agent *HTTPSTransferAgent) ReadFile(remotePath string) (*File, error) {
+	resp, err := agent.do("GET", remotePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("https: reading %s: %v", remotePath, err)
+	}
+	defer resp.Body.Close()
+
+	contents, err := downloadToTemp(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("https: reading %s: %v", remotePath, err)
+	}
+	return &File{
+		Filename: path.Base(remotePath),
+		Contents: contents,
+	}, nil
+}
+
+func (agent *HTTPSTransferAgent) Ping() error {
+	if agent == nil {
+		return errors.New("nil HTTPSTransferAgent")
+	}
+	_, err := agent.list(agent.cfg.InboundPath)
+	return err
+}
+
+func (agent *HTTPSTransferAgent) Close() error {
+	if agent == nil || agent.client == nil {
+		return nil
+	}
+	agent.client.CloseIdleConnections()
+	return nil
+}
+
+func (agent *HTTPSTransferAgent) InboundPath() string {
+	return agent.cfg.InboundPath
+}
+
+func (agent *HTTPSTransferAgent) OutboundPath() string {
+	return agent.cfg.OutboundPath
+}
+
+func (agent *HTTPSTransferAgent) ReturnPath() string {
+	return agent.cfg.ReturnPath
+}
+
+func (agent *HTTPSTransferAgent) Hostname() string {
+	if agent == nil || agent.url == nil {
+		return ""
+	}
+	return agent.url.Host
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// testODFIAPI stores files in memory and requires an OAuth2 access token
+type testODFIAPI struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (api *testODFIAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if r.URL.Path == "/oauth2/token" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/ach/")
+	switch r.Method {
+	case "GET":
+		if bs, exists := api.files[path]; exists {
+			w.Write(bs)
+			return
+		}
+		var listing []remoteFile
+		for name := range api.files {
+			if filepath.Dir(name) == path {
+				listing = append(listing, remoteFile{Filename: filepath.Base(name)})
+			}
+		}
+		json.NewEncoder(w).Encode(listing)
+
+	case "HEAD":
+		bs, exists := api.files[path]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(bs)))
+
+	case "POST":
+		bs, _ := ioutil.ReadAll(r.Body)
+		api.files[path] = bs
+		w.WriteHeader(http.StatusCreated)
+
+	case "DELETE":
+		if _, exists := api.files[path]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(api.files, path)
+	}
+}
+
+func setupHTTPS(t *testing.T) (*HTTPSTransferAgent, *testODFIAPI) {
+	t.Helper()
+
+	api := &testODFIAPI{
+		files: map[string][]byte{
+			"inbound/ppd-debit.ach": []byte("101 inbound"),
+			"returned/return.ach":   []byte("101 returned"),
+		},
+	}
+	server := httptest.NewTLSServer(api)
+	t.Cleanup(server.Close)
+
+	dir, err := ioutil.TempDir("", "https")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// trust the test server's certificate
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	agent, err := newHTTPSTransferAgent(log.NewNopLogger(), config.ODFI{
+		InboundPath:  "inbound",
+		OutboundPath: "outbound",
+		ReturnPath:   "returned",
+		HTTPS: &config.HTTPS{
+			URL:    server.URL + "/ach",
+			CAFile: caFile,
+			OAuth2: &config.OAuth2{
+				TokenURL:     server.URL + "/oauth2/token",
+				ClientID:     "paygate",
+				ClientSecret: "secret",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return agent, api
+}
+
+func TestHTTPS__getFiles(t *testing.T) {
+	agent, _ := setupHTTPS(t)
+
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := agent.GetInboundFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Filename != "ppd-debit.ach" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	bs, _ := ioutil.ReadAll(files[0].Contents)
+	files[0].Close()
+	if string(bs) != "101 inbound" {
+		t.Errorf("unexpected contents: %q", bs)
+	}
+
+	files, err = agent.GetReturnFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Filename != "return.ach" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	files[0].Close()
+}
+
+func TestHTTPS__uploadFile(t *testing.T) {
+	agent, api := setupHTTPS(t)
+
+	err := agent.UploadFile(File{
+		Filename: "20200601-0.ach",
+		Contents: ioutil.NopCloser(strings.NewReader("101 outbound")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs := api.files["outbound/20200601-0.ach"]; string(bs) != "101 outbound" {
+		t.Errorf("unexpected contents: %q", bs)
+	}
+
+	// check the upload like verification does
+	if size, err := agent.FileSize("outbound/20200601-0.ach"); err != nil || size != 12 {
+		t.Errorf("size=%d error=%v", size, err)
+	}
+	file, err := agent.ReadFile("outbound/20200601-0.ach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if err := agent.Delete("outbound/20200601-0.ach"); err != nil {
+		t.Fatal(err)
+	}
+	if err := agent.Delete("outbound/20200601-0.ach"); err != nil {
+		t.Errorf("expected missing file to be ignored: %v", err)
+	}
+	if _, err := agent.FileSize("outbound/20200601-0.ach"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHTTPS__unauthorized(t *testing.T) {
+	agent, _ := setupHTTPS(t)
+
+	cfg := agent.cfg
+	cfg.HTTPS.OAuth2 = nil
+	agent, err := newHTTPSTransferAgent(log.NewNopLogger(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = agent.GetInboundFiles()
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("expected error: %v", err)
+	}
+}
+
+func TestHTTPS__remoteURL(t *testing.T) {
+	agent, _ := setupHTTPS(t)
+	if u := agent.remoteURL("../inbound/file.ach"); !strings.HasSuffix(u, "/ach/inbound/file.ach") {
+		t.Errorf("unexpected URL: %s", u)
+	}
+	if u := agent.remoteURL("inbound/file.ach"); !strings.HasSuffix(u, "/ach/inbound/file.ach") {
+		t.Errorf("unexpected URL: %s", u)
+	}
+}
@@ -6,7 +6,9 @@ package upload
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
+	"path/filepath"
 	"sync"
 )
 
@@ -15,8 +17,14 @@ type MockAgent struct {
 	ReturnFiles  []File
 	UploadedFile *File        // non-nil on file upload
 	DeletedFile  string       // filepath of last deleted file
+	Uploads      int          // count of UploadFile calls
 	mu           sync.RWMutex // protects all fields
 
+	// RemoteContents overrides what FileSize and ReadFile return for UploadedFile,
+	// like a server which truncated the upload.
+	RemoteContents []byte
+	uploaded       []byte
+
 	Err error
 }
 
@@ -42,9 +50,43 @@ func (a *MockAgent) UploadFile(f File) error {
 	bs, _ := ioutil.ReadAll(f.Contents)
 	a.UploadedFile = &f
 	a.UploadedFile.Contents = ioutil.NopCloser(bytes.NewReader(bs))
+	a.uploaded = bs
+	a.Uploads++
 	return nil
 }
 
+func (a *MockAgent) remoteContents(path string) ([]byte, error) {
+	if a.UploadedFile == nil || filepath.Base(path) != filepath.Base(a.UploadedFile.Filename) {
+		return nil, errors.New("file not found")
+	}
+	if a.RemoteContents != nil {
+		return a.RemoteContents, nil
+	}
+	return a.uploaded, nil
+}
+
+func (a *MockAgent) FileSize(path string) (int64, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	bs, err := a.remoteContents(path)
+	return int64(len(bs)), err
+}
+
+func (a *MockAgent) ReadFile(path string) (*File, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	bs, err := a.remoteContents(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Filename: filepath.Base(path),
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	}, nil
+}
+
 func (a *MockAgent) Delete(path string) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
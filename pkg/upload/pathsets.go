@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base/log"
+)
+
+// pathSetAgent exchanges files with the ODFI's default directories and each of its
+// labeled PathSets. The embedded Agent uses the default directories.
+type pathSetAgent struct {
+	Agent
+
+	sets []pathSet
+}
+
+type pathSet struct {
+	label   string
+	sameDay bool
+	agent   Agent
+}
+
+func withPathSets(logger log.Logger, agent Agent, cfg config.ODFI, store *CredentialStore, err error) (Agent, error) {
+	psa := &pathSetAgent{Agent: agent}
+	for i := range cfg.PathSets {
+		set := cfg.PathSets[i]
+		setAgent, serr := newConfiguredAgent(logger.Set("pathSet", log.String(set.Label)), set.ODFI(cfg), store)
+		if serr != nil && err == nil {
+			err = fmt.Errorf("path set %s: %v", set.Label, serr)
+		}
+		psa.sets = append(psa.sets, pathSet{
+			label:   set.Label,
+			sameDay: set.SameDay,
+			agent:   setAgent,
+		})
+	}
+	return psa, err
+}
+
+func (psa *pathSetAgent) Ping() error {
+	if err := psa.Agent.Ping(); err != nil {
+		return err
+	}
+	for i := range psa.sets {
+		if err := psa.sets[i].agent.Ping(); err != nil {
+			return fmt.Errorf("path set %s: %v", psa.sets[i].label, err)
+		}
+	}
+	return nil
+}
+
+func (psa *pathSetAgent) Close() error {
+	err := psa.Agent.Close()
+	for i := range psa.sets {
+		if cerr := psa.sets[i].agent.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Agents returns an Agent for each set of remote directories agent exchanges files
+// with, starting with the default InboundPath, OutboundPath and ReturnPath.
+func Agents(agent Agent) []Agent {
+	psa, ok := agent.(*pathSetAgent)
+	if !ok {
+		return []Agent{agent}
+	}
+	agents := []Agent{psa.Agent}
+	for i := range psa.sets {
+		agents = append(agents, psa.sets[i].agent)
+	}
+	return agents
+}
+
+// ForFile returns the Agent whose OutboundPath file should be uploaded into. Files with
+// any same-day batches go to the PathSet receiving same-day files, if there is one.
+func ForFile(agent Agent, file *ach.File) Agent {
+	psa, ok := agent.(*pathSetAgent)
+	if !ok || file == nil {
+		return agent
+	}
+	if achx.HasSameDay(file) {
+		for i := range psa.sets {
+			if psa.sets[i].sameDay {
+				return psa.sets[i].agent
+			}
+		}
+	}
+	return psa.Agent
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moov-io/ach"
+)
+
+func sameDayFile(t *testing.T, descriptiveDate string) *ach.File {
+	t.Helper()
+
+	bh := ach.NewBatchHeader()
+	bh.StandardEntryClassCode = ach.PPD
+	bh.CompanyDescriptiveDate = descriptiveDate
+	batch, err := ach.NewBatch(bh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := ach.NewFile()
+	file.AddBatch(batch)
+	return file
+}
+
+func TestPathSets(t *testing.T) {
+	standard, sameDay, other := &MockAgent{}, &MockAgent{}, &MockAgent{}
+	agent := &pathSetAgent{
+		Agent: standard,
+		sets: []pathSet{
+			{label: "other", agent: other},
+			{label: "same-day", sameDay: true, agent: sameDay},
+		},
+	}
+
+	agents := Agents(agent)
+	if len(agents) != 3 || agents[0] != standard || agents[1] != other || agents[2] != sameDay {
+		t.Errorf("unexpected agents: %#v", agents)
+	}
+	if agents := Agents(standard); len(agents) != 1 || agents[0] != standard {
+		t.Errorf("unexpected agents: %#v", agents)
+	}
+
+	if a := ForFile(agent, sameDayFile(t, "SD1300")); a != sameDay {
+		t.Errorf("unexpected agent for same-day file: %#v", a)
+	}
+	if a := ForFile(agent, sameDayFile(t, "200601")); a != standard {
+		t.Errorf("unexpected agent for standard file: %#v", a)
+	}
+	if a := ForFile(standard, sameDayFile(t, "SD1300")); a != standard {
+		t.Errorf("unexpected agent without path sets: %#v", a)
+	}
+
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	other.Err = errors.New("bad thing")
+	if err := agent.Ping(); err == nil {
+		t.Error("expected error")
+	}
+	if err := agent.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
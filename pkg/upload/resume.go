@@ -0,0 +1,58 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	uploadResumes = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "upload_resumes",
+		Help: "Counter of interrupted uploads resumed from the bytes stored remotely",
+	}, []string{"type"})
+)
+
+// writeFromFunc stores the bytes of r in the remote file starting at offset.
+type writeFromFunc func(offset int64, r io.Reader) error
+
+// sizeFunc returns how many bytes of the remote file are stored.
+type sizeFunc func() (int64, error)
+
+// resumeUpload writes contents and, when the write is interrupted, continues from the
+// last complete chunk stored on the remote server. Remote sizes which can't be read
+// or don't fit contents restart the upload from the beginning.
+func resumeUpload(logger log.Logger, agentType string, cfg *config.ResumableUploads, filename string, contents []byte, write writeFromFunc, size sizeFunc) error {
+	chunk, total := cfg.Chunk(), int64(len(contents))
+
+	var offset int64
+	for attempt := 0; ; attempt++ {
+		err := write(offset, bytes.NewReader(contents[offset:]))
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.Resumes() {
+			return fmt.Errorf("upload of %s failed after %d resumes: %v", filename, attempt, err)
+		}
+
+		stored, serr := size()
+		if serr != nil || stored > total {
+			logger.Warn().Logf("restarting upload of %s: remote size=%d error=%v", filename, stored, serr)
+			stored = 0
+		}
+		offset = stored - (stored % chunk)
+
+		uploadResumes.With("type", agentType).Add(1)
+		logger.Warn().Logf("resuming upload of %s at byte %d of %d after: %v", filename, offset, total, err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+// flakyRemote stores writes like a remote server whose connection drops after
+// a number of bytes are written.
+type flakyRemote struct {
+	stored  []byte
+	offsets []int64
+
+	// dropAfter are the bytes written in each call before the connection drops
+	dropAfter []int
+}
+
+func (r *flakyRemote) write(offset int64, rd io.Reader) error {
+	r.offsets = append(r.offsets, offset)
+
+	bs, _ := ioutil.ReadAll(rd)
+	r.stored = append(r.stored[:offset], bs...)
+
+	if len(r.dropAfter) > 0 {
+		n := r.dropAfter[0]
+		r.dropAfter = r.dropAfter[1:]
+		r.stored = r.stored[:int(offset)+n]
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func (r *flakyRemote) size() (int64, error) {
+	return int64(len(r.stored)), nil
+}
+
+func TestResumeUpload(t *testing.T) {
+	contents := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	remote := &flakyRemote{
+		dropAfter: []int{13, 10},
+	}
+	cfg := &config.ResumableUploads{ChunkSize: 4}
+
+	err := resumeUpload(log.NewNopLogger(), "ftp", cfg, "a.ach", contents, remote.write, remote.size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(remote.stored) != string(contents) {
+		t.Errorf("unexpected remote file: %q", remote.stored)
+	}
+
+	// 13 bytes resume from the third chunk, then 12+10 bytes from the fifth
+	expected := []int64{0, 12, 20}
+	if len(remote.offsets) != len(expected) {
+		t.Fatalf("unexpected offsets: %v", remote.offsets)
+	}
+	for i := range expected {
+		if remote.offsets[i] != expected[i] {
+			t.Errorf("offsets[%d]=%d expected %d", i, remote.offsets[i], expected[i])
+		}
+	}
+}
+
+func TestResumeUpload__maxResumes(t *testing.T) {
+	contents := []byte("0123456789")
+	remote := &flakyRemote{
+		dropAfter: []int{1, 1, 1},
+	}
+	cfg := &config.ResumableUploads{ChunkSize: 1, MaxResumes: 2}
+
+	err := resumeUpload(log.NewNopLogger(), "sftp", cfg, "a.ach", contents, remote.write, remote.size)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(remote.offsets) != 3 {
+		t.Errorf("unexpected offsets: %v", remote.offsets)
+	}
+}
+
+func TestResumeUpload__sizeErr(t *testing.T) {
+	contents := []byte("0123456789")
+	remote := &flakyRemote{
+		dropAfter: []int{8},
+	}
+	size := func() (int64, error) {
+		return 0, errors.New("bad error")
+	}
+
+	err := resumeUpload(log.NewNopLogger(), "ftp", &config.ResumableUploads{}, "a.ach", contents, remote.write, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remote.offsets) != 2 || remote.offsets[1] != 0 {
+		t.Errorf("expected restart from zero: %v", remote.offsets)
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/moov-io/paygate/internal/sshx"
 	"github.com/moov-io/paygate/pkg/config"
 
 	"github.com/go-kit/kit/metrics/prometheus"
@@ -25,6 +24,7 @@ import (
 	"github.com/pkg/sftp"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/time/rate"
 )
 
 var (
@@ -35,15 +35,19 @@ var (
 )
 
 type SFTPTransferAgent struct {
-	conn   *ssh.Client
-	client *sftp.Client
-	cfg    config.ODFI
-	logger log.Logger
-	mu     sync.Mutex // protects all read/write methods
+	conn    *ssh.Client
+	client  *sftp.Client
+	cfg     config.ODFI
+	limiter *rate.Limiter
+	logger  log.Logger
+	mu      sync.Mutex // protects all read/write methods
+
+	credentials *CredentialStore
+	rotations   uint64 // credential rotations seen when client was opened
 }
 
-func newSFTPTransferAgent(logger log.Logger, cfg config.ODFI) (*SFTPTransferAgent, error) {
-	agent := &SFTPTransferAgent{cfg: cfg, logger: logger}
+func newSFTPTransferAgent(logger log.Logger, cfg config.ODFI, credentials *CredentialStore) (*SFTPTransferAgent, error) {
+	agent := &SFTPTransferAgent{cfg: cfg, limiter: newBandwidthLimiter(cfg.Throttle), logger: logger, credentials: credentials}
 
 	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), cfg.SFTP.Hostname); err != nil {
 		return nil, fmt.Errorf("sftp: %s is not whitelisted: %v", cfg.SFTP.Hostname, err)
@@ -65,19 +69,38 @@ func (agent *SFTPTransferAgent) connection() (*sftp.Client, error) {
 
 	if agent.client != nil {
 		// Verify the connection works and if not drop through and reconnect
-		if _, err := agent.client.Getwd(); err == nil {
+		if agent.credentials.rotated(agent.rotations) {
+			// Log in again with the rotated credentials
+			agent.logger.Info().Log("sftp: reconnecting with rotated credentials")
+			agent.client.Close()
+			agent.conn.Close()
+		} else if _, err := agent.client.Getwd(); err == nil {
 			return agent.client, nil
 		} else {
 			// Our connection is having issues, so retry connecting
 			agent.client.Close()
 		}
+		agent.client = nil
 	}
 
-	conn, stdin, stdout, err := sftpConnect(agent.logger, agent.cfg)
+	rotations := agent.credentials.seen()
+	creds, err := agent.credentials.login(agent.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("upload: %v", err)
+	}
+	hostKey, err := agent.credentials.hostKey(agent.cfg)
 	if err != nil {
 		return nil, fmt.Errorf("upload: %v", err)
 	}
-	agent.conn = conn
+	cfg, sftpCfg := agent.cfg, *agent.cfg.SFTP
+	sftpCfg.HostPublicKey = hostKey.HostPublicKey
+	cfg.SFTP = &sftpCfg
+
+	conn, stdin, stdout, err := sftpConnect(agent.logger, cfg, creds, agent.limiter)
+	if err != nil {
+		return nil, fmt.Errorf("upload: %v", err)
+	}
+	agent.conn, agent.rotations = conn, rotations
 
 	// Setup our SFTP client
 	var opts = []sftp.ClientOption{
@@ -96,13 +119,13 @@ func (agent *SFTPTransferAgent) connection() (*sftp.Client, error) {
 }
 
 var (
-	hostKeyCallbackOnce sync.Once
-	hostKeyCallback     = func(logger log.Logger) {
-		logger.Warn().Logf("sftp: WARNING!!! Insecure default of skipping SFTP host key validation. Please set sftp_configs.host_public_key")
+	hostKeyCallbackOnce    sync.Once
+	insecureHostKeyWarning = func(logger log.Logger) {
+		logger.Warn().Logf("sftp: WARNING!!! Insecure default of skipping SFTP host key validation. Please set odfi.sftp.hostPublicKey")
 	}
 )
 
-func sftpConnect(logger log.Logger, cfg config.ODFI) (*ssh.Client, io.WriteCloser, io.Reader, error) {
+func sftpConnect(logger log.Logger, cfg config.ODFI, creds Credentials, limiter *rate.Limiter) (*ssh.Client, io.WriteCloser, io.Reader, error) {
 	if cfg.SFTP == nil {
 		return nil, nil, nil, errors.New("nil config or sftp config")
 	}
@@ -120,38 +143,48 @@ func sftpConnect(logger log.Logger, cfg config.ODFI) (*ssh.Client, io.WriteClose
 	}
 	conf.SetDefaults()
 
-	if cfg.SFTP.HostPublicKey != "" {
-		pubKey, err := sshx.ReadPubKey([]byte(cfg.SFTP.HostPublicKey))
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("problem parsing ssh public key: %v", err)
-		}
-		conf.HostKeyCallback = ssh.FixedHostKey(pubKey)
+	callback, err := hostKeyCallback(cfg.SFTP)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("sftpConnect: %v", err)
+	}
+	if callback != nil {
+		conf.HostKeyCallback = callback
 	} else {
 		hostKeyCallbackOnce.Do(func() {
-			hostKeyCallback(logger)
+			insecureHostKeyWarning(logger)
 		})
 		//nolint:gosec
 		conf.HostKeyCallback = ssh.InsecureIgnoreHostKey() // insecure default
 	}
-	switch {
-	case cfg.SFTP.Password != "":
-		conf.Auth = append(conf.Auth, ssh.Password(cfg.SFTP.Password))
-	case cfg.SFTP.ClientPrivateKey != "":
-		signer, err := readSigner(cfg.SFTP.ClientPrivateKey)
+
+	// Offer the client private key before the password when both are set
+	key := creds.ClientPrivateKey
+	if key == "" && cfg.SFTP.ClientPrivateKeyFile != "" {
+		bs, err := ioutil.ReadFile(cfg.SFTP.ClientPrivateKeyFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("sftpConnect: failed to read client private key file: %v", err)
+		}
+		key = string(bs)
+	}
+	if key != "" {
+		signer, err := readSigner(key, creds.ClientPrivateKeyPassphrase)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("sftpConnect: failed to read client private key: %v", err)
 		}
 		conf.Auth = append(conf.Auth, ssh.PublicKeys(signer))
-	default:
+	}
+	if creds.Password != "" {
+		conf.Auth = append(conf.Auth, ssh.Password(creds.Password))
+	}
+	if len(conf.Auth) == 0 {
 		return nil, nil, nil, fmt.Errorf("sftpConnect: no auth method provided for routingNumber=%s", cfg.RoutingNumber)
 	}
 
 	// Connect to the remote server
 	var client *ssh.Client
-	var err error
 	for i := 0; i < 3; i++ {
 		if client == nil {
-			client, err = ssh.Dial("tcp", cfg.SFTP.Hostname, conf) // retry connection
+			client, err = sshDial(cfg.SFTP.Hostname, conf, limiter) // retry connection
 			time.Sleep(250 * time.Millisecond)
 		}
 	}
@@ -182,12 +215,33 @@ func sftpConnect(logger log.Logger, cfg config.ODFI) (*ssh.Client, io.WriteClose
 	return client, pw, pr, nil
 }
 
-func readSigner(raw string) (ssh.Signer, error) {
+// sshDial connects like ssh.Dial, but limits the connection's bandwidth when limiter is non-nil.
+func sshDial(addr string, conf *ssh.ClientConfig, limiter *rate.Limiter) (*ssh.Client, error) {
+	conn, err := dialThrottled("tcp", addr, conf.Timeout, limiter)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, conf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// readSigner parses a PEM or base64 encoded private key, which is decrypted with passphrase when it's set.
+func readSigner(raw string, passphrase string) (ssh.Signer, error) {
+	parse := func(pem []byte) (ssh.Signer, error) {
+		if passphrase != "" {
+			return ssh.ParsePrivateKeyWithPassphrase(pem, []byte(passphrase))
+		}
+		return ssh.ParsePrivateKey(pem)
+	}
 	decoded, err := base64.StdEncoding.DecodeString(raw)
 	if len(decoded) > 0 && err == nil {
-		return ssh.ParsePrivateKey(decoded)
+		return parse(decoded)
 	}
-	return ssh.ParsePrivateKey([]byte(raw))
+	return parse([]byte(raw))
 }
 
 func (agent *SFTPTransferAgent) Ping() error {
@@ -276,6 +330,49 @@ func (agent *SFTPTransferAgent) Delete(path string) error {
 	return nil // not found
 }
 
+func (agent *SFTPTransferAgent) FileSize(path string) (int64, error) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	return agent.fileSize(path)
+}
+
+func (agent *SFTPTransferAgent) fileSize(path string) (int64, error) {
+	conn, err := agent.connection()
+	if err != nil {
+		return 0, err
+	}
+	info, err := conn.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("sftp: stat %s: %v", path, err)
+	}
+	return info.Size(), nil
+}
+
+func (agent *SFTPTransferAgent) ReadFile(path string) (*File, error) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	conn, err := agent.connection()
+	if err != nil {
+		return nil, err
+	}
+	fd, err := conn.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: open %s: %v", path, err)
+	}
+	defer fd.Close()
+
+	var buf bytes.Buffer
+	if n, err := io.Copy(&buf, fd); err != nil {
+		return nil, fmt.Errorf("sftp: read (n=%d) %s: %v", n, path, err)
+	}
+	return &File{
+		Filename: filepath.Base(path),
+		Contents: ioutil.NopCloser(&buf),
+	}, nil
+}
+
 // uploadFile saves the content of File at the given filename in the OutboundPath directory
 //
 // The File's contents will always be closed
@@ -299,21 +396,64 @@ func (agent *SFTPTransferAgent) UploadFile(f File) error {
 	}
 
 	// Take the base of f.Filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
-	fd, err := conn.Create(filepath.Join(agent.cfg.OutboundPath, filepath.Base(f.Filename)))
+	path := filepath.Join(agent.cfg.OutboundPath, filepath.Base(f.Filename))
+
+	if agent.cfg.ResumableUploads == nil {
+		return agent.writeFrom(path, f.Contents, 0)
+	}
+
+	contents, err := ioutil.ReadAll(f.Contents)
 	if err != nil {
-		return fmt.Errorf("sftp: problem creating %s: %v", f.Filename, err)
+		return fmt.Errorf("sftp: problem reading %s: %v", f.Filename, err)
 	}
-	n, err := io.Copy(fd, f.Contents)
-	if n == 0 || err != nil {
+	write := func(offset int64, r io.Reader) error {
+		return agent.writeFrom(path, r, offset)
+	}
+	size := func() (int64, error) {
+		return agent.fileSize(path)
+	}
+	return resumeUpload(agent.logger, "sftp", agent.cfg.ResumableUploads, f.Filename, contents, write, size)
+}
+
+// writeFrom writes r into the file at path starting at offset. Without an offset the
+// file is created or truncated first.
+func (agent *SFTPTransferAgent) writeFrom(path string, r io.Reader, offset int64) error {
+	conn, err := agent.connection()
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Base(path)
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	fd, err := conn.OpenFile(path, flags)
+	if err != nil {
+		return fmt.Errorf("sftp: problem creating %s: %v", filename, err)
+	}
+	if _, err := fd.Seek(offset, io.SeekStart); err != nil {
+		fd.Close()
+		return fmt.Errorf("sftp: problem seeking %s to %d: %v", filename, offset, err)
+	}
+	var n int64
+	if agent.cfg.ResumableUploads != nil {
+		n, err = io.CopyBuffer(fd, r, make([]byte, agent.cfg.ResumableUploads.Chunk()))
+	} else {
+		n, err = io.Copy(fd, r)
+	}
+	if (n == 0 && offset == 0) || err != nil {
 		fd.Close()
-		return fmt.Errorf("sftp: problem copying (n=%d) %s: %v", n, f.Filename, err)
+		return fmt.Errorf("sftp: problem copying (n=%d) %s: %v", n, filename, err)
 	}
 	if err := fd.Close(); err != nil {
-		return fmt.Errorf("sftp: problem closing %s: %v", f.Filename, err)
+		return fmt.Errorf("sftp: problem closing %s: %v", filename, err)
 	}
 	if err := fd.Chmod(0600); err != nil {
-		return fmt.Errorf("sftp: problem chmod %s: %v", f.Filename, err)
+		return fmt.Errorf("sftp: problem chmod %s: %v", filename, err)
 	}
+	agent.logger.With(config.Debug).Logf("sftp: uploaded %s (%d bytes from offset %d) to %s", filename, n, offset, agent.cfg.OutboundPath)
 	return nil
 }
 
@@ -339,39 +479,44 @@ func (agent *SFTPTransferAgent) readFiles(dir string) ([]File, error) {
 		return nil, fmt.Errorf("sftp: readdir %s: %v", dir, err)
 	}
 
-	var files []File
+	var names []string
 	for i := range infos {
-		fd, err := conn.Open(filepath.Join(dir, infos[i].Name()))
+		if !infos[i].IsDir() {
+			names = append(names, infos[i].Name())
+		}
+	}
+
+	// sftp.Client supports concurrent requests, so each worker shares the connection
+	return downloadAll(names, agent.cfg.Inbound.Concurrency(), func(_ int, name string) (*File, error) {
+		fd, err := conn.Open(filepath.Join(dir, name))
 		if err != nil {
-			return nil, fmt.Errorf("sftp: open %s: %v", infos[i].Name(), err)
+			return nil, fmt.Errorf("sftp: open %s: %v", name, err)
 		}
+		defer fd.Close()
 
 		// skip this file descriptor if it's a directory - we only reading one level deep
 		info, err := fd.Stat()
 		if err != nil {
-			fd.Close()
-			return nil, fmt.Errorf("sftp: stat %s: %v", infos[i].Name(), err)
+			return nil, fmt.Errorf("sftp: stat %s: %v", name, err)
 		}
 		if info.IsDir() {
-			fd.Close()
-			continue
+			return nil, nil
 		}
 
-		// download the remote file to our local directory
-		var buf bytes.Buffer
-		if n, err := io.Copy(&buf, fd); n == 0 || err != nil {
-			fd.Close()
-			if err != nil && !strings.Contains(err.Error(), sftp.ErrInternalInconsistency.Error()) {
-				return nil, fmt.Errorf("sftp: read (n=%d) %s: %v", n, infos[i].Name(), err)
+		// download the remote file to a local temporary file
+		r, err := downloadToTemp(fd)
+		if err != nil {
+			if !strings.Contains(err.Error(), sftp.ErrInternalInconsistency.Error()) {
+				return nil, fmt.Errorf("sftp: read %s: %v", name, err)
 			}
-			return nil, fmt.Errorf("sftp: read (n=%d) on %s", n, infos[i].Name())
-		} else {
-			fd.Close()
+			return nil, fmt.Errorf("sftp: read on %s", name)
 		}
-		files = append(files, File{
-			Filename: infos[i].Name(),
-			Contents: ioutil.NopCloser(&buf),
-		})
-	}
-	return files, nil
+		if r == nil {
+			return nil, fmt.Errorf("sftp: read (n=0) on %s", name)
+		}
+		return &File{
+			Filename: name,
+			Contents: r,
+		}, nil
+	})
 }
@@ -149,7 +149,7 @@ func newAgent(host, user, pass, passFile string) (*SFTPTransferAgent, error) {
 	} else {
 		cfg.SFTP.ClientPrivateKey = passFile
 	}
-	return newSFTPTransferAgent(log.NewNopLogger(), cfg)
+	return newSFTPTransferAgent(log.NewNopLogger(), cfg, nil)
 }
 
 func cp(from, to string) error {
@@ -327,14 +327,14 @@ AAA7eoZ9AEHflUeuLn9QJI/r0hyQQLEtrpwv6rDT1GCWaLII5HJ6NUFVf4TTcqxo
 wg/HcAJWY60xZTJDFN+Qfx8ZQvBEin6c2/h+zZi5IVY=
 -----END RSA PRIVATE KEY-----`
 
-	sig, err := readSigner(raw)
+	sig, err := readSigner(raw, "")
 	if sig == nil || err != nil {
 		t.Fatalf("Signer=%v error=%v", sig, err)
 	}
 
 	// base64 Encoded
 	raw = base64.StdEncoding.EncodeToString([]byte(raw))
-	sig, err = readSigner(raw)
+	sig, err = readSigner(raw, "")
 	if sig == nil || err != nil {
 		t.Fatalf("Signer=%v error=%v", sig, err)
 	}
@@ -345,7 +345,7 @@ func TestSFTP__sftpConnect(t *testing.T) {
 		SFTP: &config.SFTP{
 			Username: "foo",
 		},
-	})
+	}, Credentials{}, nil)
 	if client != nil || err == nil {
 		t.Errorf("client=%v err=%v", client, err)
 	}
@@ -355,7 +355,7 @@ func TestSFTP__sftpConnect(t *testing.T) {
 		SFTP: &config.SFTP{
 			HostPublicKey: "bad key material",
 		},
-	})
+	}, Credentials{}, nil)
 	if err == nil {
 		t.Errorf("expected error")
 	}
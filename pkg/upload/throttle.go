@@ -0,0 +1,189 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+	"golang.org/x/time/rate"
+)
+
+// ErrOutsideTransferWindow is returned when files are uploaded or downloaded outside
+// of the ODFI's configured transfer windows.
+var ErrOutsideTransferWindow = errors.New("outside of transfer windows")
+
+type transferWindow struct {
+	start, end time.Duration // since midnight
+}
+
+// contains returns true if the time of day of when falls within the window.
+func (w transferWindow) contains(when time.Time) bool {
+	offset := time.Duration(when.Hour())*time.Hour + time.Duration(when.Minute())*time.Minute
+	if w.start <= w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end // crosses midnight
+}
+
+func parseTransferWindows(windows []string) ([]transferWindow, error) {
+	var out []transferWindow
+	for i := range windows {
+		parts := strings.Split(windows[i], "-")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("window %q is not a start-end range", windows[i])
+		}
+		start, err := time.Parse("15:04", strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %v", windows[i], err)
+		}
+		end, err := time.Parse("15:04", strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %v", windows[i], err)
+		}
+		out = append(out, transferWindow{
+			start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+			end:   time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+		})
+	}
+	return out, nil
+}
+
+// windowedAgent wraps an Agent and rejects uploads and downloads outside of the
+// transfer windows an ODFI allows.
+type windowedAgent struct {
+	Agent
+
+	loc     *time.Location
+	windows []transferWindow
+	now     func() time.Time
+}
+
+func newWindowedAgent(logger log.Logger, agent Agent, cfg config.ODFI) (*windowedAgent, error) {
+	loc := cfg.Throttle.Location(cfg.Cutoffs.Location())
+	if loc == nil {
+		return nil, fmt.Errorf("throttle: unknown timezone for %s", agent.Hostname())
+	}
+	windows, err := parseTransferWindows(cfg.Throttle.Windows)
+	if err != nil {
+		return nil, fmt.Errorf("throttle: %v", err)
+	}
+	logger.Logf("transferring files with %s during %s %s", agent.Hostname(), strings.Join(cfg.Throttle.Windows, ","), loc)
+
+	return &windowedAgent{
+		Agent:   agent,
+		loc:     loc,
+		windows: windows,
+		now:     time.Now,
+	}, nil
+}
+
+func (a *windowedAgent) open(op string) error {
+	now := a.now().In(a.loc)
+	for i := range a.windows {
+		if a.windows[i].contains(now) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s at %s: %w", op, now.Format("15:04 MST"), ErrOutsideTransferWindow)
+}
+
+func (a *windowedAgent) GetInboundFiles() ([]File, error) {
+	if err := a.open("GetInboundFiles"); err != nil {
+		return nil, err
+	}
+	return a.Agent.GetInboundFiles()
+}
+
+func (a *windowedAgent) GetReturnFiles() ([]File, error) {
+	if err := a.open("GetReturnFiles"); err != nil {
+		return nil, err
+	}
+	return a.Agent.GetReturnFiles()
+}
+
+func (a *windowedAgent) UploadFile(f File) error {
+	if err := a.open("UploadFile"); err != nil {
+		f.Close()
+		return err
+	}
+	return a.Agent.UploadFile(f)
+}
+
+// newBandwidthLimiter returns a limiter of bytes per second shared by every connection
+// an agent makes, or nil if bandwidth isn't limited.
+func newBandwidthLimiter(cfg *config.Throttle) *rate.Limiter {
+	if cfg == nil || cfg.BytesPerSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(cfg.BytesPerSecond), int(cfg.BytesPerSecond))
+}
+
+// dialThrottled opens a TCP connection whose reads and writes wait on limiter.
+func dialThrottled(network, address string, timeout time.Duration, limiter *rate.Limiter) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil || limiter == nil {
+		return conn, err
+	}
+	return &throttledConn{Conn: conn, limiter: limiter}, nil
+}
+
+// throttledTransport returns an HTTP transport whose connections wait on limiter.
+func throttledTransport(limiter *rate.Limiter) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if limiter != nil {
+		transport.DialContext = func(_ context.Context, network, address string) (net.Conn, error) {
+			return dialThrottled(network, address, 30*time.Second, limiter)
+		}
+	}
+	return transport
+}
+
+type throttledConn struct {
+	net.Conn
+
+	limiter *rate.Limiter
+}
+
+func (c *throttledConn) Read(b []byte) (int, error) {
+	if len(b) > c.limiter.Burst() {
+		b = b[:c.limiter.Burst()]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		if werr := c.limiter.WaitN(context.Background(), n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > c.limiter.Burst() {
+			chunk = chunk[:c.limiter.Burst()]
+		}
+		if err := c.limiter.WaitN(context.Background(), len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestTransferWindow__contains(t *testing.T) {
+	windows, err := parseTransferWindows([]string{"08:00-17:30", "22:00-02:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2020, time.June, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	if !windows[0].contains(at(8, 0)) || !windows[0].contains(at(17, 29)) {
+		t.Error("expected daytime window to be open")
+	}
+	if windows[0].contains(at(7, 59)) || windows[0].contains(at(17, 30)) {
+		t.Error("expected daytime window to be closed")
+	}
+	if !windows[1].contains(at(23, 0)) || !windows[1].contains(at(1, 0)) {
+		t.Error("expected overnight window to be open")
+	}
+	if windows[1].contains(at(2, 0)) || windows[1].contains(at(12, 0)) {
+		t.Error("expected overnight window to be closed")
+	}
+
+	if _, err := parseTransferWindows([]string{"08:00"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestWindowedAgent(t *testing.T) {
+	mock := &MockAgent{}
+	agent, err := newWindowedAgent(log.NewNopLogger(), mock, config.ODFI{
+		Throttle: &config.Throttle{
+			Timezone: "America/New_York",
+			Windows:  []string{"09:00-17:00"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+
+	agent.now = func() time.Time {
+		return time.Date(2020, time.June, 1, 18, 0, 0, 0, ny)
+	}
+	err = agent.UploadFile(File{Filename: "a.ach", Contents: ioutil.NopCloser(strings.NewReader("hello"))})
+	if !errors.Is(err, ErrOutsideTransferWindow) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := agent.GetReturnFiles(); !errors.Is(err, ErrOutsideTransferWindow) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if mock.Uploads != 0 {
+		t.Errorf("unexpected uploads: %d", mock.Uploads)
+	}
+	if err := agent.Ping(); err != nil {
+		t.Errorf("Ping should pass through: %v", err)
+	}
+
+	agent.now = func() time.Time {
+		return time.Date(2020, time.June, 1, 10, 0, 0, 0, ny)
+	}
+	err = agent.UploadFile(File{Filename: "a.ach", Contents: ioutil.NopCloser(strings.NewReader("hello"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mock.Uploads != 1 {
+		t.Errorf("unexpected uploads: %d", mock.Uploads)
+	}
+}
+
+func TestThrottledConn(t *testing.T) {
+	if newBandwidthLimiter(nil) != nil || newBandwidthLimiter(&config.Throttle{}) != nil {
+		t.Fatal("expected no limiter")
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &throttledConn{
+		Conn:    client,
+		limiter: newBandwidthLimiter(&config.Throttle{BytesPerSecond: 1000}),
+	}
+
+	go ioutil.ReadAll(server)
+
+	// The first 1000 bytes are allowed immediately, the next 500 wait about half a second
+	start := time.Now()
+	n, err := conn.Write(make([]byte, 1500))
+	if n != 1500 || err != nil {
+		t.Fatalf("n=%d error=%v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("write wasn't throttled: %v", elapsed)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	uploadVerifications = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "upload_verifications",
+		Help: "Counter of uploaded files compared against the remote copy",
+	}, []string{"result"})
+)
+
+// UploadVerified uploads contents as filename in the agent's OutboundPath and compares the
+// remote copy against what was sent. Files which don't match are uploaded again up to
+// cfg.Retries times. Without cfg the file is uploaded once and never verified.
+func UploadVerified(logger log.Logger, agent Agent, filename string, contents []byte, cfg *config.Verification) (bool, error) {
+	upload := func() error {
+		return agent.UploadFile(File{
+			Filename: filename,
+			Contents: ioutil.NopCloser(bytes.NewReader(contents)),
+		})
+	}
+	if cfg == nil {
+		return false, upload()
+	}
+
+	path := filepath.Join(agent.OutboundPath(), filepath.Base(filename))
+	var err error
+	for attempt := 0; attempt <= cfg.Retries; attempt++ {
+		if err = upload(); err != nil {
+			uploadVerifications.With("result", "error").Add(1)
+			return false, err
+		}
+		if err = Verify(agent, path, contents, cfg.Checksum); err == nil {
+			uploadVerifications.With("result", "verified").Add(1)
+			logger.With(config.Debug).Logf("verified upload of %s", filename)
+			return true, nil
+		}
+		uploadVerifications.With("result", "mismatch").Add(1)
+		logger.LogErrorf("problem verifying upload of %s (attempt %d of %d): %v", filename, attempt+1, cfg.Retries+1, err)
+	}
+	return false, fmt.Errorf("unable to verify upload of %s: %v", filename, err)
+}
+
+// Verify compares the remote file at path against contents. The file's size is compared
+// unless checksum is set, which downloads the file and compares its SHA-256 hash.
+func Verify(agent Agent, path string, contents []byte, checksum bool) error {
+	if !checksum {
+		size, err := agent.FileSize(path)
+		if err != nil {
+			return err
+		}
+		if size != int64(len(contents)) {
+			return fmt.Errorf("remote file is %d bytes, but %d bytes were sent", size, len(contents))
+		}
+		return nil
+	}
+
+	file, err := agent.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	remote, err := ioutil.ReadAll(file.Contents)
+	if err != nil {
+		return fmt.Errorf("problem reading remote file: %v", err)
+	}
+	if expected, actual := checksumOf(contents), checksumOf(remote); expected != actual {
+		return fmt.Errorf("remote file has checksum %s (%d bytes), but %s (%d bytes) was sent", actual, len(remote), expected, len(contents))
+	}
+	return nil
+}
+
+func checksumOf(data []byte) string {
+	ss := sha256.Sum256(data)
+	return hex.EncodeToString(ss[:])
+}
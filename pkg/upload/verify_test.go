@@ -0,0 +1,76 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/moov-io/base/log"
+)
+
+func TestUploadVerified(t *testing.T) {
+	agent := &MockAgent{}
+	contents := []byte("hello, world")
+
+	// without verification
+	verified, err := UploadVerified(log.NewNopLogger(), agent, "a.ach", contents, nil)
+	if err != nil || verified {
+		t.Fatalf("verified=%v error=%v", verified, err)
+	}
+
+	// size and checksum
+	for _, checksum := range []bool{false, true} {
+		cfg := &config.Verification{Checksum: checksum}
+		verified, err = UploadVerified(log.NewNopLogger(), agent, "a.ach", contents, cfg)
+		if err != nil || !verified {
+			t.Fatalf("checksum=%v verified=%v error=%v", checksum, verified, err)
+		}
+	}
+	bs, _ := ioutil.ReadAll(agent.UploadedFile.Contents)
+	if string(bs) != "hello, world" {
+		t.Errorf("unexpected upload: %q", bs)
+	}
+}
+
+func TestUploadVerified__retries(t *testing.T) {
+	agent := &MockAgent{
+		RemoteContents: []byte("hello"), // truncated
+	}
+	cfg := &config.Verification{Retries: 2}
+
+	verified, err := UploadVerified(log.NewNopLogger(), agent, "a.ach", []byte("hello, world"), cfg)
+	if err == nil || verified {
+		t.Fatalf("verified=%v error=%v", verified, err)
+	}
+	if agent.Uploads != 3 {
+		t.Errorf("unexpected uploads: %d", agent.Uploads)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	agent := &MockAgent{
+		RemoteContents: []byte("hello, WORLD"), // same size, different contents
+	}
+	contents := []byte("hello, world")
+	if _, err := UploadVerified(log.NewNopLogger(), agent, "a.ach", contents, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// sizes match, but checksums don't
+	if err := Verify(agent, "outbound/a.ach", contents, false); err != nil {
+		t.Error(err)
+	}
+	if err := Verify(agent, "outbound/a.ach", contents, true); err == nil {
+		t.Error("expected error")
+	}
+
+	// missing file
+	if err := Verify(agent, "outbound/b.ach", contents, false); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+// NewInMemoryRepo returns a Repository which keeps micro-deposits in memory. It's intended
+// for tests and demos where no database is available.
+func NewInMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		micros:    make(map[string]client.MicroDeposits),
+		byAccount: make(map[string]string),
+		queue:     make(map[string]*memoryQueued),
+		watched:   make(map[string]watchedVerification),
+		attempts:  make(map[string]int),
+		locker:    database.NewInMemoryLocker(),
+	}
+}
+
+type memoryRepo struct {
+	mu        sync.RWMutex
+	micros    map[string]client.MicroDeposits
+	byAccount map[string]string // accountID -> microDepositID
+	queue     map[string]*memoryQueued
+	watched   map[string]watchedVerification // microDepositID -> notified state
+	attempts  map[string]int                 // microDepositID -> confirmation attempts
+	locker    database.Locker
+}
+
+type memoryQueued struct {
+	organization string
+	attempts     int
+	claimedAt    time.Time
+	created      time.Time
+}
+
+func (r *memoryRepo) Close() error {
+	return nil
+}
+
+func (r *memoryRepo) getMicroDeposits(ctx context.Context, microDepositID string) (*client.MicroDeposits, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	micro, ok := r.micros[microDepositID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	micro.TransferIDs = append([]string(nil), micro.TransferIDs...)
+	micro.Amounts = append([]client.Amount(nil), micro.Amounts...)
+	return &micro, nil
+}
+
+func (r *memoryRepo) getAccountMicroDeposits(ctx context.Context, accountID string) (*client.MicroDeposits, error) {
+	r.mu.RLock()
+	microDepositID, ok := r.byAccount[accountID]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.getMicroDeposits(ctx, microDepositID)
+}
+
+func (r *memoryRepo) writeMicroDeposits(organization string, micro *client.MicroDeposits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.micros[micro.MicroDepositID]; exists {
+		return fmt.Errorf("micro-deposits write: microDepositID=%s already exists", micro.MicroDepositID)
+	}
+	// destination_account_id is unique in the SQL repository
+	if _, exists := r.byAccount[micro.Destination.AccountID]; exists {
+		return fmt.Errorf("micro-deposits write: accountID=%s already has micro-deposits", micro.Destination.AccountID)
+	}
+
+	stored := *micro
+	stored.TransferIDs = append([]string(nil), micro.TransferIDs...)
+	stored.Amounts = append([]client.Amount(nil), micro.Amounts...)
+	r.micros[micro.MicroDepositID] = stored
+	r.byAccount[micro.Destination.AccountID] = micro.MicroDepositID
+	r.watched[micro.MicroDepositID] = watchedVerification{
+		microDepositID: micro.MicroDepositID,
+		organization:   organization,
+		state:          client.VERIFICATION_UNVERIFIED,
+	}
+	return nil
+}
+
+func (r *memoryRepo) lockAccount(accountID string) (database.Unlock, error) {
+	return r.locker.TryLock(accountID, accountLockTTL)
+}
+
+func (r *memoryRepo) enqueueMicroDeposits(organization string, micro *client.MicroDeposits) error {
+	r.mu.RLock()
+	_, exists := r.byAccount[micro.Destination.AccountID]
+	r.mu.RUnlock()
+	if exists {
+		return errAccountMicroDeposits
+	}
+	if err := r.writeMicroDeposits(organization, micro); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queue[micro.MicroDepositID] = &memoryQueued{
+		organization: organization,
+		created:      micro.Created,
+	}
+	return nil
+}
+
+func (r *memoryRepo) claimQueuedMicroDeposits(limit int) ([]queuedMicroDeposits, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	staleBefore := now.Add(-1 * queueClaimTTL)
+
+	var ids []string
+	for id, q := range r.queue {
+		if q.claimedAt.IsZero() || q.claimedAt.Before(staleBefore) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.queue[ids[i]].created.Before(r.queue[ids[j]].created)
+	})
+
+	var claimed []queuedMicroDeposits
+	for i := 0; i < len(ids) && i < limit; i++ {
+		q := r.queue[ids[i]]
+		stale := !q.claimedAt.IsZero()
+		q.claimedAt = now
+		q.attempts++
+		claimed = append(claimed, queuedMicroDeposits{
+			microDepositID: ids[i],
+			organization:   q.organization,
+			attempts:       q.attempts,
+			stale:          stale,
+		})
+	}
+	return claimed, nil
+}
+
+func (r *memoryRepo) completeQueuedMicroDeposits(micro *client.MicroDeposits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.micros[micro.MicroDepositID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	stored.Amounts = append([]client.Amount(nil), micro.Amounts...)
+	stored.TransferIDs = append([]string(nil), micro.TransferIDs...)
+	r.micros[micro.MicroDepositID] = stored
+	delete(r.queue, micro.MicroDepositID)
+	return nil
+}
+
+func (r *memoryRepo) retryQueuedMicroDeposits(microDepositID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if q, ok := r.queue[microDepositID]; ok {
+		q.claimedAt = time.Time{}
+	}
+	return nil
+}
+
+func (r *memoryRepo) failQueuedMicroDeposits(microDepositID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if micro, ok := r.micros[microDepositID]; ok {
+		micro.Status = client.FAILED
+		r.micros[microDepositID] = micro
+	}
+	delete(r.queue, microDepositID)
+	return nil
+}
+
+func (r *memoryRepo) getWatchedVerifications() ([]watchedVerification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []watchedVerification
+	for _, item := range r.watched {
+		switch item.state {
+		case client.VERIFICATION_UNVERIFIED, client.VERIFICATION_MICRO_DEPOSITS_SENT, client.VERIFICATION_AWAITING_CONFIRMATION:
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) updateNotifiedState(microDepositID string, from, to client.VerificationState) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.watched[microDepositID]
+	if !ok || item.state != from {
+		return false, nil
+	}
+	item.state = to
+	r.watched[microDepositID] = item
+	return true, nil
+}
+
+func (r *memoryRepo) useConfirmationAttempt(microDepositID string, max int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.micros[microDepositID]; !ok || r.attempts[microDepositID] >= max {
+		return 0, errNoConfirmationAttempts
+	}
+	r.attempts[microDepositID]++
+	return r.attempts[microDepositID], nil
+}
@@ -6,10 +6,16 @@ package microdeposits
 
 import (
 	"crypto/rand"
+	"errors"
+	"fmt"
 	"math/big"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
@@ -21,6 +27,7 @@ import (
 func createMicroDeposits(
 	cfg config.MicroDeposits,
 	organization string,
+	orgConfig *client.OrganizationConfiguration,
 	companyIdentification string,
 	src fundflow.Source,
 	dest fundflow.Destination,
@@ -29,48 +36,144 @@ func createMicroDeposits(
 	strategy fundflow.Strategy,
 	pub pipeline.XferPublisher,
 ) (*client.MicroDeposits, error) {
+	micro := newMicroDeposits(client.Destination{
+		CustomerID: dest.Customer.CustomerID,
+		AccountID:  dest.Account.AccountID,
+	})
+	err := originateMicroDeposits(cfg, micro, organization, orgConfig, companyIdentification, src, dest, repo, accountDecryptor, strategy, pub)
+	return micro, err
+}
 
-	amt1, amt2 := getMicroDepositAmounts()
-
-	micro := &client.MicroDeposits{
+// newMicroDeposits returns PENDING micro-deposits for dest without amounts or Transfers.
+func newMicroDeposits(dest client.Destination) *client.MicroDeposits {
+	return &client.MicroDeposits{
 		MicroDepositID: base.ID(),
-		Destination: client.Destination{
-			CustomerID: dest.Customer.CustomerID,
-			AccountID:  dest.Account.AccountID,
-		},
-		Amounts: []client.Amount{amt1, amt2},
-		Status:  client.PENDING,
-		Created: time.Now(),
+		Destination:    dest,
+		TransferIDs:    []string{},
+		Amounts:        []client.Amount{},
+		Status:         client.PENDING,
+		Created:        time.Now(),
 	}
+}
+
+// organizationConfig returns cfg with the features which aren't enabled for organization turned off.
+func organizationConfig(cfg config.MicroDeposits, features config.Features, organization string) config.MicroDeposits {
+	cfg.SameDay = cfg.SameDay && features.SameDayMicroDeposits.EnabledFor(organization)
+	return cfg
+}
+
+// originateMicroDeposits picks the amounts of micro, then saves and publishes its Transfers.
+// The entries use the micro-deposit descriptor of orgConfig when it's set.
+func originateMicroDeposits(
+	cfg config.MicroDeposits,
+	micro *client.MicroDeposits,
+	organization string,
+	orgConfig *client.OrganizationConfiguration,
+	companyIdentification string,
+	src fundflow.Source,
+	dest fundflow.Destination,
+	repo transfers.Repository,
+	accountDecryptor accounts.Decryptor,
+	strategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+) error {
+	amt1, amt2 := getMicroDepositAmounts()
+	micro.Amounts = []client.Amount{amt1, amt2}
+
+	desc := newDescriptor(cfg, orgConfig, dest)
 
 	// originate two credits
-	if xfer, err := originate(cfg, organization, companyIdentification, amt1, src, dest, repo, strategy, pub); err != nil {
-		return nil, err
-	} else {
-		micro.TransferIDs = append(micro.TransferIDs, xfer.TransferID)
+	xfer1, files1, err := originate(cfg, desc, companyIdentification, amt1, src, dest, strategy)
+	if err != nil {
+		return err
 	}
-	if xfer, err := originate(cfg, organization, companyIdentification, amt2, src, dest, repo, strategy, pub); err != nil {
-		return nil, err
-	} else {
-		micro.TransferIDs = append(micro.TransferIDs, xfer.TransferID)
+	xfer2, files2, err := originate(cfg, desc, companyIdentification, amt2, src, dest, strategy)
+	if err != nil {
+		return err
 	}
 
 	// originate the debit
-	src, dest, err := flipSourceDest(organization, src, dest, accountDecryptor)
+	src, dest, err = flipSourceDest(organization, src, dest, accountDecryptor)
 	if err != nil {
-		return micro, err
+		return err
 	}
 	sum := client.Amount{
 		Currency: "USD",
 		Value:    amt1.Value + amt2.Value,
 	}
-	if xfer, err := originate(cfg, organization, companyIdentification, sum, src, dest, repo, strategy, pub); err != nil {
-		return micro, err
-	} else {
-		// Add the Transfer onto the MicroDeposit
-		micro.TransferIDs = append(micro.TransferIDs, xfer.TransferID)
+	xfer3, files3, err := originate(cfg, desc, companyIdentification, sum, src, dest, strategy)
+	if err != nil {
+		return err
+	}
+	// Add the Transfers onto the MicroDeposit
+	micro.TransferIDs = []string{xfer1.TransferID, xfer2.TransferID, xfer3.TransferID}
+
+	// Save each Transfer with its trace numbers in one write
+	err = repo.WriteUserTransfers(organization, []transfers.UserTransfer{
+		transfers.NewUserTransfer(xfer1, files1),
+		transfers.NewUserTransfer(xfer2, files2),
+		transfers.NewUserTransfer(xfer3, files3),
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.DebitSweep {
+		// The combined file is published under the first credit's Transfer
+		file, err := sweepFile(append(append(files1, files2...), files3...))
+		if err != nil {
+			return err
+		}
+		return pipeline.PublishFiles(pub, xfer1, []*ach.File{file})
+	}
+
+	if err := pipeline.PublishFiles(pub, xfer1, files1); err != nil {
+		return err
+	}
+	if err := pipeline.PublishFiles(pub, xfer2, files2); err != nil {
+		return err
+	}
+	return pipeline.PublishFiles(pub, xfer3, files3)
+}
+
+// descriptor holds what an organization's customers see on their statement for micro-deposits.
+type descriptor struct {
+	companyEntryDescription string
+	individualName          string
+
+	// account is verified by the micro-deposits, only its entries are renamed
+	routingNumber string
+	accountNumber string
+}
+
+func newDescriptor(cfg config.MicroDeposits, orgConfig *client.OrganizationConfiguration, dest fundflow.Destination) descriptor {
+	desc := descriptor{
+		routingNumber: dest.Account.RoutingNumber,
+		accountNumber: dest.AccountNumber,
+	}
+	if orgConfig != nil {
+		desc.companyEntryDescription = orgConfig.MicroDepositEntryDescription
+		desc.individualName = orgConfig.MicroDepositIndividualName
+	}
+	return desc
+}
+
+// setIndividualName overrides the Individual Name of entries posted to the verified account.
+// Offsetting entries keep the name of the ODFI's account.
+func (desc descriptor) setIndividualName(files []*ach.File) {
+	if desc.individualName == "" {
+		return
+	}
+	for i := range files {
+		for j := range files[i].Batches {
+			entries := files[i].Batches[j].GetEntries()
+			for k := range entries {
+				if entries[k].RDFIIdentification == achx.ABA8(desc.routingNumber) && strings.TrimSpace(entries[k].DFIAccountNumber) == desc.accountNumber {
+					entries[k].IndividualName = desc.individualName
+				}
+			}
+		}
 	}
-	return micro, nil
 }
 
 func getMicroDepositAmounts() (client.Amount, client.Amount) {
@@ -84,33 +187,63 @@ func getMicroDepositAmounts() (client.Amount, client.Amount) {
 	return random(), random()
 }
 
+// originate returns a Transfer and the ACH files for it, which callers save and publish.
 func originate(
 	cfg config.MicroDeposits,
-	organization string,
+	desc descriptor,
 	companyIdentification string,
 	amt client.Amount,
 	source fundflow.Source,
 	destination fundflow.Destination,
-	transferRepo transfers.Repository,
 	fundStrategy fundflow.Strategy,
-	pub pipeline.XferPublisher,
-) (*client.Transfer, error) {
+) (*client.Transfer, []*ach.File, error) {
 	xfer := microDepositTransfer(amt, source, destination, cfg.Description, cfg.SameDay)
+	xfer.CompanyEntryDescription = desc.companyEntryDescription
 
-	// Save our Transfer to the database
-	if err := transferRepo.WriteUserTransfer(organization, xfer); err != nil {
-		return nil, err
-	}
-
-	// Originate ACH file(s) and send off to our Transfer publisher
+	// Originate ACH file(s) for our Transfer publisher
 	files, err := fundStrategy.Originate(companyIdentification, xfer, source, destination)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	desc.setIndividualName(files)
+	xfer.ExpectedSettlementDate = achx.SettlementDate(files)
+	return xfer, files, nil
+}
+
+// sweepFile moves the entries of every file into the first file's batch. The batch
+// then holds the credits and the debit which offsets them.
+func sweepFile(files []*ach.File) (*ach.File, error) {
+	if len(files) == 0 || len(files[0].Batches) == 0 {
+		return nil, errors.New("no micro-deposit batch to sweep into")
+	}
+	file := files[0]
+	batch := file.Batches[0]
+	for i := range files {
+		for j := range files[i].Batches {
+			if i == 0 && j == 0 {
+				continue
+			}
+			entries := files[i].Batches[j].GetEntries()
+			for k := range entries {
+				batch.AddEntry(entries[k])
+			}
+		}
+	}
+	file.Batches = file.Batches[:1]
+
+	// NACHA requires entries in ascending trace number order
+	entries := batch.GetEntries()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].TraceNumber < entries[j].TraceNumber
+	})
+	batch.GetHeader().ServiceClassCode = ach.MixedDebitsAndCredits
+	if err := batch.Create(); err != nil {
+		return nil, fmt.Errorf("micro-deposit sweep batch: %v", err)
 	}
-	if err := pipeline.PublishFiles(pub, xfer, files); err != nil {
-		return nil, err
+	if err := file.Create(); err != nil {
+		return nil, fmt.Errorf("micro-deposit sweep file: %v", err)
 	}
-	return xfer, nil
+	return file, nil
 }
 
 func flipSourceDest(organization string, src fundflow.Source, dest fundflow.Destination, accountDecryptor accounts.Decryptor) (fundflow.Source, fundflow.Destination, error) {
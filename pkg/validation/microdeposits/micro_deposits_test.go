@@ -5,6 +5,7 @@
 package microdeposits
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -75,10 +76,10 @@ func TestMicroDeposits__createMicroDeposits(t *testing.T) {
 		Number: "12345",
 	}
 	pub := pipeline.NewMockPublisher()
-	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI)
+	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI, nil)
 
 	companyID := "MoovZZZZZZ"
-	micro, err := createMicroDeposits(*cfg.Validation.MicroDeposits, organization, companyID, src, dest, repo, decryptor, strategy, pub)
+	micro, err := createMicroDeposits(*cfg.Validation.MicroDeposits, organization, nil, companyID, src, dest, repo, decryptor, strategy, pub)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -87,7 +88,7 @@ func TestMicroDeposits__createMicroDeposits(t *testing.T) {
 	}
 
 	for i := range micro.TransferIDs {
-		xfer, err := repo.GetTransfer(micro.TransferIDs[i])
+		xfer, err := repo.GetTransfer(context.Background(), micro.TransferIDs[i])
 		if xfer == nil || err != nil {
 			t.Fatalf("transferID=%s error=%v", micro.TransferIDs[i], err)
 		}
@@ -134,6 +135,101 @@ func TestMicroDeposits__createMicroDeposits(t *testing.T) {
 	}
 }
 
+func TestMicroDeposits__createMicroDepositsSweep(t *testing.T) {
+	cfg := mockConfig()
+	cfg.ODFI.RoutingNumber = "123456780"
+	cfg.Validation.MicroDeposits.DebitSweep = true
+	organization := base.ID()
+
+	src, dest := createTestSource(cfg.ODFI), createTestDestination()
+
+	repo := transfers.NewInMemoryRepo()
+	decryptor := &accounts.MockDecryptor{
+		Number: "12345",
+	}
+	pub := pipeline.NewMockPublisher()
+	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI, nil)
+
+	micro, err := createMicroDeposits(*cfg.Validation.MicroDeposits, organization, nil, "MoovZZZZZZ", src, dest, repo, decryptor, strategy, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(micro.TransferIDs); n != 3 {
+		t.Fatalf("got %d micro-deposit transfers: %#v", n, micro)
+	}
+
+	// all three entries are published in one batch under the first credit
+	if len(pub.Xfers) != 1 {
+		t.Fatalf("unexpected published files: %#v", pub.Xfers)
+	}
+	xfer, ok := pub.Xfers[micro.TransferIDs[0]]
+	if !ok || len(xfer.File.Batches) != 1 {
+		t.Fatalf("unexpected file: %#v", xfer)
+	}
+	if err := xfer.File.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	batch := xfer.File.Batches[0]
+	if n := len(batch.GetEntries()); n != 3 {
+		t.Fatalf("got %d entries", n)
+	}
+	if scc := batch.GetHeader().ServiceClassCode; scc != ach.MixedDebitsAndCredits {
+		t.Errorf("unexpected ServiceClassCode: %d", scc)
+	}
+	if ctrl := batch.GetControl(); ctrl.TotalCreditEntryDollarAmount != ctrl.TotalDebitEntryDollarAmount {
+		t.Errorf("credits=%d debits=%d", ctrl.TotalCreditEntryDollarAmount, ctrl.TotalDebitEntryDollarAmount)
+	}
+
+	// each Transfer keeps the trace number of its entry
+	for i := range micro.TransferIDs {
+		found, err := repo.GetTransfer(context.Background(), micro.TransferIDs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found.TraceNumbers) != 1 {
+			t.Errorf("transferID=%s trace numbers: %v", found.TransferID, found.TraceNumbers)
+		}
+	}
+}
+
+func TestMicroDeposits__createMicroDepositsDescriptor(t *testing.T) {
+	cfg := mockConfig()
+	cfg.ODFI.RoutingNumber = "123456780"
+
+	src, dest := createTestSource(cfg.ODFI), createTestDestination()
+
+	repo := transfers.NewInMemoryRepo()
+	decryptor := &accounts.MockDecryptor{
+		Number: "12345",
+	}
+	pub := pipeline.NewMockPublisher()
+	strategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI, nil)
+
+	orgConfig := &client.OrganizationConfiguration{
+		MicroDepositEntryDescription: "ACCTVERIFY",
+		MicroDepositIndividualName:   "ACME VERIFY",
+	}
+	micro, err := createMicroDeposits(*cfg.Validation.MicroDeposits, base.ID(), orgConfig, "MoovZZZZZZ", src, dest, repo, decryptor, strategy, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range micro.TransferIDs {
+		xfer, ok := pub.Xfers[micro.TransferIDs[i]]
+		if !ok || len(xfer.File.Batches) != 1 {
+			t.Fatalf("unexpected file: %#v", xfer)
+		}
+		if desc := xfer.File.Batches[0].GetHeader().CompanyEntryDescription; desc != "ACCTVERIFY" {
+			t.Errorf("CompanyEntryDescription=%q", desc)
+		}
+		entries := xfer.File.Batches[0].GetEntries()
+		for j := range entries {
+			if entries[j].IndividualName != "ACME VERIFY" {
+				t.Errorf("IndividualName=%q", entries[j].IndividualName)
+			}
+		}
+	}
+}
+
 func createTestSource(odfi config.ODFI) fundflow.Source {
 	return fundflow.Source{
 		Customer: customers.Customer{
@@ -166,3 +262,21 @@ func createTestDestination() fundflow.Destination {
 		AccountNumber: "12345",
 	}
 }
+
+func TestMicroDeposits__organizationConfig(t *testing.T) {
+	cfg := config.MicroDeposits{SameDay: true}
+	features := config.Features{
+		SameDayMicroDeposits: &config.Feature{
+			Organizations: []string{"pilot"},
+		},
+	}
+	if conf := organizationConfig(cfg, features, "pilot"); !conf.SameDay {
+		t.Error("expected same-day micro-deposits")
+	}
+	if conf := organizationConfig(cfg, features, "other"); conf.SameDay {
+		t.Error("unexpected same-day micro-deposits")
+	}
+	if conf := organizationConfig(cfg, config.Features{}, "other"); !conf.SameDay {
+		t.Error("expected same-day micro-deposits")
+	}
+}
@@ -5,28 +5,93 @@
 package microdeposits
 
 import (
+	"context"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
 )
 
 type mockRepository struct {
 	Micro *client.MicroDeposits
 	Err   error
+
+	LockErr error
+
+	Queued []queuedMicroDeposits
+
+	Watched []watchedVerification
+
+	Attempts int
 }
 
-func (r *mockRepository) getMicroDeposits(microDepositID string) (*client.MicroDeposits, error) {
+func (r *mockRepository) getMicroDeposits(ctx context.Context, microDepositID string) (*client.MicroDeposits, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
 	return r.Micro, nil
 }
 
-func (r *mockRepository) getAccountMicroDeposits(accountID string) (*client.MicroDeposits, error) {
+func (r *mockRepository) getAccountMicroDeposits(ctx context.Context, accountID string) (*client.MicroDeposits, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
 	return r.Micro, nil
 }
 
-func (r *mockRepository) writeMicroDeposits(micro *client.MicroDeposits) error {
+func (r *mockRepository) writeMicroDeposits(organization string, micro *client.MicroDeposits) error {
+	return r.Err
+}
+
+func (r *mockRepository) lockAccount(accountID string) (database.Unlock, error) {
+	if r.LockErr != nil {
+		return nil, r.LockErr
+	}
+	return func() error { return nil }, nil
+}
+
+func (r *mockRepository) enqueueMicroDeposits(organization string, micro *client.MicroDeposits) error {
+	return r.Err
+}
+
+func (r *mockRepository) claimQueuedMicroDeposits(limit int) ([]queuedMicroDeposits, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Queued, nil
+}
+
+func (r *mockRepository) completeQueuedMicroDeposits(micro *client.MicroDeposits) error {
+	return r.Err
+}
+
+func (r *mockRepository) retryQueuedMicroDeposits(microDepositID string) error {
+	return r.Err
+}
+
+func (r *mockRepository) failQueuedMicroDeposits(microDepositID string) error {
 	return r.Err
 }
+
+func (r *mockRepository) getWatchedVerifications() ([]watchedVerification, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Watched, nil
+}
+
+func (r *mockRepository) updateNotifiedState(microDepositID string, from, to client.VerificationState) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	return true, nil
+}
+
+func (r *mockRepository) useConfirmationAttempt(microDepositID string, max int) (int, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	if r.Attempts >= max {
+		return 0, errNoConfirmationAttempts
+	}
+	r.Attempts++
+	return r.Attempts, nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base/log"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/jobs"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queuedInitiations = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "micro_deposit_queue_initiations",
+		Help: "Counter of queued micro-deposits by whether they were initiated, retried or failed",
+	}, []string{"result"})
+)
+
+const (
+	queueBatchSize = 25
+
+	// queueClaimTTL is how long a worker has to initiate micro-deposits it claimed
+	queueClaimTTL = 5 * time.Minute
+
+	// maxQueueAttempts bounds how often looking up the accounts of micro-deposits is retried
+	maxQueueAttempts = 5
+)
+
+type queuedMicroDeposits struct {
+	microDepositID string
+	organization   string
+	attempts       int
+
+	// stale is true when the claim of a previous worker expired
+	stale bool
+}
+
+// Queue initiates micro-deposits which were accepted with 202 Accepted.
+//
+// Looking up the source and destination accounts is retried until maxQueueAttempts.
+// Once Transfers are originated a failure isn't retried, as their files could already
+// be published, and the micro-deposits are marked as failed. The same is done for claims
+// which expired, which means the worker holding them stopped mid-way.
+type Queue struct {
+	cfg                   config.MicroDeposits
+	features              config.Features
+	logger                log.Logger
+	companyIdentification string
+
+	repo            Repository
+	transferRepo    transfers.Repository
+	orgRepo         organization.Repository
+	customersClient customers.Client
+	decryptor       accounts.Decryptor
+	strategy        fundflow.Strategy
+	pub             pipeline.XferPublisher
+
+	job *jobs.Job
+}
+
+// NewQueue returns nil unless micro-deposits are configured to be initiated asynchronously.
+func NewQueue(
+	cfg *config.Config,
+	repo Repository,
+	transferRepo transfers.Repository,
+	orgRepo organization.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	pub pipeline.XferPublisher,
+) *Queue {
+	if cfg.Validation.MicroDeposits == nil || cfg.Validation.MicroDeposits.Async == nil {
+		return nil
+	}
+	return &Queue{
+		cfg:                   *cfg.Validation.MicroDeposits,
+		features:              cfg.Features,
+		logger:                cfg.Logger.Set("service", log.String("micro-deposits")),
+		companyIdentification: cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification,
+		repo:                  repo,
+		transferRepo:          transferRepo,
+		orgRepo:               orgRepo,
+		customersClient:       customersClient,
+		decryptor:             accountDecryptor,
+		strategy:              fundStrategy,
+		pub:                   pub,
+	}
+}
+
+// UseJobs records the runs of the Queue as the "microdeposits.queue" job of reg.
+func (q *Queue) UseJobs(reg *jobs.Registry) {
+	if q == nil {
+		return
+	}
+	q.job = reg.Register("microdeposits.queue")
+}
+
+// Start initiates queued micro-deposits until ctx is canceled.
+func (q *Queue) Start(ctx context.Context) {
+	if q == nil {
+		return
+	}
+	interval := q.cfg.Async.PollInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	q.job.Scheduled(time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			q.run()
+			q.job.Scheduled(time.Now().Add(interval))
+
+		case <-q.job.Triggered():
+			q.run()
+
+		case <-ctx.Done():
+			q.logger.Log("micro-deposit queue shutdown")
+			return
+		}
+	}
+}
+
+func (q *Queue) run() {
+	_, err := q.Process()
+	if err != nil {
+		q.logger.LogErrorf("ERROR processing micro-deposit queue: %v", err)
+	}
+	q.job.Done(err)
+}
+
+// Process claims queued micro-deposits and initiates them with the configured number of
+// workers. It returns how many were claimed.
+func (q *Queue) Process() (int, error) {
+	items, err := q.repo.claimQueuedMicroDeposits(queueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	work := make(chan queuedMicroDeposits)
+	var wg sync.WaitGroup
+	for i := 0; i < q.cfg.Async.Concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				q.process(item)
+			}
+		}()
+	}
+	for i := range items {
+		work <- items[i]
+	}
+	close(work)
+	wg.Wait()
+
+	return len(items), nil
+}
+
+func (q *Queue) process(item queuedMicroDeposits) {
+	logger := q.logger.Set("microDepositID", log.String(item.microDepositID))
+
+	if item.stale {
+		logger.LogErrorf("ERROR micro-deposits were claimed by a worker which stopped, their Transfers may have been published")
+		q.fail(logger, item)
+		return
+	}
+
+	micro, err := q.repo.getMicroDeposits(context.Background(), item.microDepositID)
+	if err != nil {
+		logger.LogErrorf("ERROR reading micro-deposits: %v", err)
+		q.retry(logger, item)
+		return
+	}
+
+	src, err := getMicroDepositSource(q.cfg, q.customersClient, q.decryptor)
+	if err != nil {
+		logger.LogErrorf("ERROR getting micro-deposit source: %v", err)
+		q.retry(logger, item)
+		return
+	}
+	dest, err := transfers.GetFundflowDestination(q.customersClient, q.decryptor, micro.Destination, item.organization)
+	if err != nil {
+		logger.LogErrorf("ERROR getting micro-deposit destination: %v", err)
+		q.retry(logger, item)
+		return
+	}
+	if src.Account.RoutingNumber == dest.Account.RoutingNumber {
+		logger.LogError(errors.New("not initiating micro-deposits for account at ODFI"))
+		q.fail(logger, item)
+		return
+	}
+	if err := acceptableAccountStatus(dest.Account); err != nil {
+		logger.LogErrorf("destination account: %v", err)
+		q.fail(logger, item)
+		return
+	}
+
+	orgConfig, err := q.orgRepo.GetConfig(item.organization)
+	if err != nil {
+		logger.LogErrorf("ERROR getting organization config: %v", err)
+		q.retry(logger, item)
+		return
+	}
+
+	conf := organizationConfig(q.cfg, q.features, item.organization)
+	err = originateMicroDeposits(conf, micro, item.organization, orgConfig, q.companyIdentification, src, dest, q.transferRepo, q.decryptor, q.strategy, q.pub)
+	if err != nil {
+		logger.LogErrorf("ERROR creating micro-deposits: %v", err)
+		q.fail(logger, item)
+		return
+	}
+	if err := q.repo.completeQueuedMicroDeposits(micro); err != nil {
+		// The claim expires and the micro-deposits are failed then
+		logger.LogErrorf("ERROR writing micro-deposits: %v", err)
+		return
+	}
+	queuedInitiations.With("result", "initiated").Add(1)
+	logger.Log("initiated queued micro-deposits")
+}
+
+func (q *Queue) retry(logger log.Logger, item queuedMicroDeposits) {
+	if item.attempts >= maxQueueAttempts {
+		q.fail(logger, item)
+		return
+	}
+	if err := q.repo.retryQueuedMicroDeposits(item.microDepositID); err != nil {
+		logger.LogErrorf("ERROR releasing micro-deposits for retry: %v", err)
+		return
+	}
+	queuedInitiations.With("result", "retried").Add(1)
+}
+
+func (q *Queue) fail(logger log.Logger, item queuedMicroDeposits) {
+	if err := q.repo.failQueuedMicroDeposits(item.microDepositID); err != nil {
+		logger.LogErrorf("ERROR failing micro-deposits: %v", err)
+		return
+	}
+	queuedInitiations.With("result", "failed").Add(1)
+}
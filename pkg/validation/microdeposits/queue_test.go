@@ -0,0 +1,182 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers"
+
+	"github.com/gorilla/mux"
+)
+
+func asyncConfig() *config.Config {
+	cfg := mockConfig()
+	cfg.Validation.MicroDeposits.Async = &config.MicroDepositsAsync{}
+	return cfg
+}
+
+func setupQueue(t *testing.T, repo Repository, customersClient customers.Client) *Queue {
+	t.Helper()
+
+	queue := NewQueue(asyncConfig(), repo, &transfers.MockRepository{}, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	if queue == nil {
+		t.Fatal("nil Queue")
+	}
+	return queue
+}
+
+func enqueueMicroDeposits(t *testing.T, repo Repository) *client.MicroDeposits {
+	t.Helper()
+
+	micro := newMicroDeposits(client.Destination{
+		CustomerID: destinationCustomerID,
+		AccountID:  destinationAccountID,
+	})
+	if err := repo.enqueueMicroDeposits(base.ID(), micro); err != nil {
+		t.Fatal(err)
+	}
+	return micro
+}
+
+func TestQueue__nil(t *testing.T) {
+	queue := NewQueue(mockConfig(), &mockRepository{}, mockTransferRepo, mockOrgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher)
+	if queue != nil {
+		t.Fatalf("unexpected Queue: %#v", queue)
+	}
+	queue.Start(context.Background())
+}
+
+func TestQueue__Process(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		queue := setupQueue(t, repo, mockCustomersClient())
+		micro := enqueueMicroDeposits(t, repo)
+
+		if err := repo.enqueueMicroDeposits(base.ID(), micro); err != errAccountMicroDeposits {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		found, err := repo.getMicroDeposits(context.Background(), micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found.TransferIDs) != 3 || len(found.Amounts) != 2 || found.Status != client.PENDING {
+			t.Errorf("unexpected micro-deposits: %#v", found)
+		}
+
+		// nothing is left in the queue
+		if n, err := queue.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestQueue__ProcessFailed(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		customersClient := mockCustomersClient()
+		customersClient.Accounts[destinationAccountID].Status = moovcustomers.ACCOUNTSTATUS_VALIDATED
+
+		queue := setupQueue(t, repo, customersClient)
+		micro := enqueueMicroDeposits(t, repo)
+
+		if n, err := queue.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		found, err := repo.getMicroDeposits(context.Background(), micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found.TransferIDs) != 0 || found.Status != client.FAILED {
+			t.Errorf("unexpected micro-deposits: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestQueue__ProcessRetry(t *testing.T) {
+	check := func(t *testing.T, repo Repository) {
+		customersClient := mockCustomersClient()
+		customersClient.Err = errors.New("bad error")
+
+		queue := setupQueue(t, repo, customersClient)
+		micro := enqueueMicroDeposits(t, repo)
+
+		for i := 0; i < maxQueueAttempts; i++ {
+			if n, err := queue.Process(); err != nil || n != 1 {
+				t.Fatalf("attempt %d: n=%d error=%v", i+1, n, err)
+			}
+		}
+		found, err := repo.getMicroDeposits(context.Background(), micro.MicroDepositID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.FAILED {
+			t.Errorf("unexpected status: %v", found.Status)
+		}
+		if n, err := queue.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRouter__InitiateMicroDepositsAsync(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	r := mux.NewRouter()
+	router := NewRouter(asyncConfig(), repo, mockTransferRepo, mockOrgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	req := client.CreateMicroDeposits{
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+	}
+	micro, resp, err := c.ValidationApi.InitiateMicroDeposits(context.TODO(), base.ID(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	if micro.MicroDepositID == "" || micro.Status != client.PENDING || len(micro.TransferIDs) != 0 {
+		t.Errorf("unexpected micro-deposits: %#v", micro)
+	}
+
+	// the account already has micro-deposits
+	_, resp, err = c.ValidationApi.InitiateMicroDeposits(context.TODO(), base.ID(), req)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
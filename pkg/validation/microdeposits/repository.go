@@ -5,26 +5,68 @@
 package microdeposits
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
 )
 
 type Repository interface {
 	// TODO(adam): lookup a micro-deposit from transferID, for return handling
 
-	getMicroDeposits(microDepositID string) (*client.MicroDeposits, error)
-	getAccountMicroDeposits(accountID string) (*client.MicroDeposits, error)
-	writeMicroDeposits(micro *client.MicroDeposits) error
+	getMicroDeposits(ctx context.Context, microDepositID string) (*client.MicroDeposits, error)
+	getAccountMicroDeposits(ctx context.Context, accountID string) (*client.MicroDeposits, error)
+	writeMicroDeposits(organization string, micro *client.MicroDeposits) error
+
+	// lockAccount keeps other instances from initiating micro-deposits for accountID
+	lockAccount(accountID string) (database.Unlock, error)
+
+	// enqueueMicroDeposits saves micro without Transfers for a Queue to initiate
+	enqueueMicroDeposits(organization string, micro *client.MicroDeposits) error
+	claimQueuedMicroDeposits(limit int) ([]queuedMicroDeposits, error)
+	// completeQueuedMicroDeposits saves the amounts and Transfers of initiated micro-deposits
+	completeQueuedMicroDeposits(micro *client.MicroDeposits) error
+	retryQueuedMicroDeposits(microDepositID string) error
+	failQueuedMicroDeposits(microDepositID string) error
+
+	// getWatchedVerifications returns micro-deposits whose last notified verification state
+	// can still change
+	getWatchedVerifications() ([]watchedVerification, error)
+	// updateNotifiedState records state as notified if the last notified state is still from
+	updateNotifiedState(microDepositID string, from, to client.VerificationState) (bool, error)
+
+	// useConfirmationAttempt counts an attempt to confirm the amounts of micro-deposits and
+	// returns how many have been made, or errNoConfirmationAttempts once max have been made
+	useConfirmationAttempt(microDepositID string, max int) (int, error)
 }
 
+// watchedVerification is the verification state of micro-deposits last sent to the webhook.
+type watchedVerification struct {
+	microDepositID string
+	organization   string
+	state          client.VerificationState
+}
+
+// errAccountMicroDeposits is returned when an account already has micro-deposits
+var errAccountMicroDeposits = errors.New("account already has micro-deposits")
+
+// errNoConfirmationAttempts is returned when every attempt to confirm micro-deposits has been made
+var errNoConfirmationAttempts = errors.New("no micro-deposit confirmation attempts left")
+
+// accountLockTTL bounds how long an account stays locked if the instance holding it stops
+const accountLockTTL = 1 * time.Minute
+
 func NewRepo(db *sql.DB) *sqlRepo {
-	return &sqlRepo{db: db}
+	return &sqlRepo{db: db, locker: database.NewLocker(db)}
 }
 
 type sqlRepo struct {
-	db *sql.DB
+	db     *sql.DB
+	locker database.Locker
 }
 
 func (r *sqlRepo) Close() error {
@@ -34,17 +76,19 @@ func (r *sqlRepo) Close() error {
 	return r.db.Close()
 }
 
-func (r *sqlRepo) getMicroDeposits(microDepositID string) (*client.MicroDeposits, error) {
+func (r *sqlRepo) getMicroDeposits(ctx context.Context, microDepositID string) (*client.MicroDeposits, error) {
+	defer database.MeasureQuery("microdeposits", "getMicroDeposits")()
+
 	query := `select micro_deposit_id, destination_customer_id, destination_account_id, status, processed_at, created_at from micro_deposits
 where micro_deposit_id = ? and deleted_at is null limit 1;`
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("micro-deposit prepare: %v", err)
 	}
 	defer stmt.Close()
 
 	var micro client.MicroDeposits
-	if err := stmt.QueryRow(microDepositID).Scan(
+	if err := stmt.QueryRowContext(ctx, microDepositID).Scan(
 		&micro.MicroDepositID,
 		&micro.Destination.CustomerID,
 		&micro.Destination.AccountID,
@@ -58,84 +102,71 @@ where micro_deposit_id = ? and deleted_at is null limit 1;`
 		return nil, fmt.Errorf("micro-deposit scan: %v", err)
 	}
 
-	micro.TransferIDs, err = r.getMicroDepositTransferIDs(microDepositID)
+	micro.TransferIDs, err = r.getMicroDepositTransferIDs(ctx, microDepositID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Read out the amounts
 	query = `select amount_currency, amount_value from micro_deposit_amounts where micro_deposit_id = ?;`
-	stmt, err = r.db.Prepare(query)
-	if err != nil {
-		return nil, fmt.Errorf("micro-deposit amounts prepare: %v", err)
-	}
-	rows, err := stmt.Query(microDepositID)
-	if err != nil {
-		return nil, fmt.Errorf("micro-deposit amounts query: %v", err)
-	}
-	for rows.Next() {
+	err = database.QueryRowsContext(ctx, r.db, "micro-deposit amounts", query, []interface{}{microDepositID}, func(rows *sql.Rows) error {
 		var amt client.Amount
 		if err := rows.Scan(&amt.Currency, &amt.Value); err != nil {
-			return nil, fmt.Errorf("micro-deposit amount scan: %v", err)
+			return err
 		}
 		micro.Amounts = append(micro.Amounts, amt)
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &micro, nil
 }
 
-func (r *sqlRepo) getMicroDepositTransferIDs(microDepositID string) ([]string, error) {
+func (r *sqlRepo) getMicroDepositTransferIDs(ctx context.Context, microDepositID string) ([]string, error) {
 	query := `select transfer_id from micro_deposit_transfers where micro_deposit_id = ?;`
-	stmt, err := r.db.Prepare(query)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-
-	rows, err := stmt.Query(microDepositID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
 
 	var transferIDs []string
-	for rows.Next() {
+	err := database.QueryRowsContext(ctx, r.db, "micro-deposit transfers", query, []interface{}{microDepositID}, func(rows *sql.Rows) error {
 		var transferID string
 		if err := rows.Scan(&transferID); err != nil {
-			return nil, err
+			return err
 		}
 		transferIDs = append(transferIDs, transferID)
-	}
-	if err := rows.Err(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	return transferIDs, nil
 }
 
-func (r *sqlRepo) getAccountMicroDeposits(accountID string) (*client.MicroDeposits, error) {
+func (r *sqlRepo) getAccountMicroDeposits(ctx context.Context, accountID string) (*client.MicroDeposits, error) {
+	defer database.MeasureQuery("microdeposits", "getAccountMicroDeposits")()
+
 	query := `select micro_deposit_id from micro_deposits where destination_account_id = ? and deleted_at is null limit 1;`
-	stmt, err := r.db.Prepare(query)
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
 	var microDepositID string
-	if err := stmt.QueryRow(accountID).Scan(&microDepositID); err != nil {
+	if err := stmt.QueryRowContext(ctx, accountID).Scan(&microDepositID); err != nil {
 		return nil, err
 	}
-	return r.getMicroDeposits(microDepositID)
+	return r.getMicroDeposits(ctx, microDepositID)
 }
 
-func (r *sqlRepo) writeMicroDeposits(micro *client.MicroDeposits) error {
+func (r *sqlRepo) writeMicroDeposits(organization string, micro *client.MicroDeposits) error {
+	defer database.MeasureQuery("microdeposits", "writeMicroDeposits")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	if err := r.writeMicroDeposit(tx, micro); err != nil {
+	if err := r.writeMicroDeposit(tx, organization, micro); err != nil {
 		tx.Rollback()
 		return fmt.Errorf("micro-deposits write: %v", err)
 	}
@@ -153,15 +184,15 @@ func (r *sqlRepo) writeMicroDeposits(micro *client.MicroDeposits) error {
 	return tx.Commit()
 }
 
-func (r *sqlRepo) writeMicroDeposit(tx *sql.Tx, micro *client.MicroDeposits) error {
-	query := `insert into micro_deposits (micro_deposit_id, destination_customer_id, destination_account_id, status, created_at) values (?, ?, ?, ?, ?);`
+func (r *sqlRepo) writeMicroDeposit(tx *sql.Tx, organization string, micro *client.MicroDeposits) error {
+	query := `insert into micro_deposits (micro_deposit_id, organization, destination_customer_id, destination_account_id, status, notified_state, created_at) values (?, ?, ?, ?, ?, ?, ?);`
 	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(micro.MicroDepositID, micro.Destination.CustomerID, micro.Destination.AccountID, micro.Status, micro.Created)
+	_, err = stmt.Exec(micro.MicroDepositID, organization, micro.Destination.CustomerID, micro.Destination.AccountID, micro.Status, client.VERIFICATION_UNVERIFIED, micro.Created)
 	if err != nil {
 		return err
 	}
@@ -199,3 +230,180 @@ func (r *sqlRepo) writeMicroDepositTransferIDs(tx *sql.Tx, microDepositID string
 	}
 	return nil
 }
+
+func (r *sqlRepo) lockAccount(accountID string) (database.Unlock, error) {
+	return r.locker.TryLock(fmt.Sprintf("micro-deposits/accounts/%s", accountID), accountLockTTL)
+}
+
+func (r *sqlRepo) enqueueMicroDeposits(organization string, micro *client.MicroDeposits) error {
+	defer database.MeasureQuery("microdeposits", "enqueueMicroDeposits")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := r.writeMicroDeposit(tx, organization, micro); err != nil {
+		tx.Rollback()
+		if database.UniqueViolation(err) {
+			return errAccountMicroDeposits
+		}
+		return fmt.Errorf("micro-deposits write: %v", err)
+	}
+
+	query := `insert into micro_deposit_queue (micro_deposit_id, organization, attempts, created_at) values (?, ?, 0, ?);`
+	if _, err := tx.Exec(query, micro.MicroDepositID, organization, micro.Created); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("micro-deposits enqueue: %v", err)
+	}
+	return tx.Commit()
+}
+
+// claimQueuedMicroDeposits returns up to limit queued micro-deposits which aren't claimed by
+// another worker. Claims older than queueClaimTTL are taken over and returned as stale.
+func (r *sqlRepo) claimQueuedMicroDeposits(limit int) ([]queuedMicroDeposits, error) {
+	defer database.MeasureQuery("microdeposits", "claimQueuedMicroDeposits")()
+
+	now := time.Now()
+	staleBefore := now.Add(-1 * queueClaimTTL)
+
+	query := `select micro_deposit_id, organization, attempts, claimed_at from micro_deposit_queue
+where claimed_at is null or claimed_at < ? order by created_at asc limit ?;`
+	var candidates []queuedMicroDeposits
+	err := database.QueryRows(r.db, "queued micro-deposits", query, []interface{}{staleBefore, limit}, func(rows *sql.Rows) error {
+		var item queuedMicroDeposits
+		var claimedAt *time.Time
+		if err := rows.Scan(&item.microDepositID, &item.organization, &item.attempts, &claimedAt); err != nil {
+			return err
+		}
+		item.stale = claimedAt != nil
+		candidates = append(candidates, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Another instance could claim the same rows, so only keep those we updated
+	query = `update micro_deposit_queue set claimed_at = ?, attempts = attempts + 1
+where micro_deposit_id = ? and (claimed_at is null or claimed_at < ?);`
+	var claimed []queuedMicroDeposits
+	for i := range candidates {
+		res, err := r.db.Exec(query, now, candidates[i].microDepositID, staleBefore)
+		if err != nil {
+			return claimed, fmt.Errorf("claiming microDepositID=%s: %v", candidates[i].microDepositID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			candidates[i].attempts++
+			claimed = append(claimed, candidates[i])
+		}
+	}
+	return claimed, nil
+}
+
+func (r *sqlRepo) completeQueuedMicroDeposits(micro *client.MicroDeposits) error {
+	defer database.MeasureQuery("microdeposits", "completeQueuedMicroDeposits")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := r.writeMicroDepositAmounts(tx, micro.MicroDepositID, micro.Amounts); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("micro-deposits write amounts: %v", err)
+	}
+	if err := r.writeMicroDepositTransferIDs(tx, micro.MicroDepositID, micro.TransferIDs); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("micro-deposits: write transferIDs: %v", err)
+	}
+	if _, err := tx.Exec(`delete from micro_deposit_queue where micro_deposit_id = ?;`, micro.MicroDepositID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("micro-deposits dequeue: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) retryQueuedMicroDeposits(microDepositID string) error {
+	defer database.MeasureQuery("microdeposits", "retryQueuedMicroDeposits")()
+
+	_, err := r.db.Exec(`update micro_deposit_queue set claimed_at = null where micro_deposit_id = ?;`, microDepositID)
+	return err
+}
+
+func (r *sqlRepo) failQueuedMicroDeposits(microDepositID string) error {
+	defer database.MeasureQuery("microdeposits", "failQueuedMicroDeposits")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	query := `update micro_deposits set status = ? where micro_deposit_id = ? and deleted_at is null;`
+	if _, err := tx.Exec(query, client.FAILED, microDepositID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("micro-deposits update status: %v", err)
+	}
+	if _, err := tx.Exec(`delete from micro_deposit_queue where micro_deposit_id = ?;`, microDepositID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("micro-deposits dequeue: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getWatchedVerifications() ([]watchedVerification, error) {
+	defer database.MeasureQuery("microdeposits", "getWatchedVerifications")()
+
+	// Micro-deposits written before organizations were saved are never watched
+	query := `select micro_deposit_id, organization, notified_state from micro_deposits
+where notified_state in (?, ?, ?) and organization is not null and deleted_at is null;`
+	args := []interface{}{
+		client.VERIFICATION_UNVERIFIED, client.VERIFICATION_MICRO_DEPOSITS_SENT, client.VERIFICATION_AWAITING_CONFIRMATION,
+	}
+	var out []watchedVerification
+	err := database.QueryRows(r.db, "watched verifications", query, args, func(rows *sql.Rows) error {
+		var item watchedVerification
+		if err := rows.Scan(&item.microDepositID, &item.organization, &item.state); err != nil {
+			return err
+		}
+		out = append(out, item)
+		return nil
+	})
+	return out, err
+}
+
+func (r *sqlRepo) updateNotifiedState(microDepositID string, from, to client.VerificationState) (bool, error) {
+	defer database.MeasureQuery("microdeposits", "updateNotifiedState")()
+
+	query := `update micro_deposits set notified_state = ? where micro_deposit_id = ? and notified_state = ?;`
+	res, err := r.db.Exec(query, to, microDepositID, from)
+	if err != nil {
+		return false, fmt.Errorf("micro-deposits update notified state: %v", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *sqlRepo) useConfirmationAttempt(microDepositID string, max int) (int, error) {
+	defer database.MeasureQuery("microdeposits", "useConfirmationAttempt")()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	query := `update micro_deposits set confirmation_attempts = confirmation_attempts + 1
+where micro_deposit_id = ? and confirmation_attempts < ? and deleted_at is null;`
+	res, err := tx.Exec(query, microDepositID, max)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("micro-deposits confirmation attempt: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return 0, errNoConfirmationAttempts
+	}
+	var attempts int
+	query = `select confirmation_attempts from micro_deposits where micro_deposit_id = ?;`
+	if err := tx.QueryRow(query, microDepositID).Scan(&attempts); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("micro-deposits confirmation attempts: %v", err)
+	}
+	return attempts, tx.Commit()
+}
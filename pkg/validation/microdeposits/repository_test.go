@@ -5,6 +5,7 @@
 package microdeposits
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
@@ -17,9 +18,9 @@ import (
 func TestRepository__getMicroDeposits(t *testing.T) {
 	t.Parallel()
 
-	check := func(t *testing.T, repo *sqlRepo) {
+	check := func(t *testing.T, repo Repository) {
 		micro := writeMicroDeposits(t, repo)
-		micro, err := repo.getMicroDeposits(micro.MicroDepositID)
+		micro, err := repo.getMicroDeposits(context.Background(), micro.MicroDepositID)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -27,7 +28,7 @@ func TestRepository__getMicroDeposits(t *testing.T) {
 			t.Error("missing MicroDeposit")
 		}
 
-		micro, err = repo.getMicroDeposits(base.ID())
+		micro, err = repo.getMicroDeposits(context.Background(), base.ID())
 		if err != sql.ErrNoRows {
 			t.Error(err)
 		}
@@ -38,14 +39,15 @@ func TestRepository__getMicroDeposits(t *testing.T) {
 
 	check(t, setupSQLiteDB(t))
 	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
 }
 
 func TestRepository__getAccountMicroDeposits(t *testing.T) {
 	t.Parallel()
 
-	check := func(t *testing.T, repo *sqlRepo) {
+	check := func(t *testing.T, repo Repository) {
 		micro := writeMicroDeposits(t, repo)
-		micro, err := repo.getAccountMicroDeposits(micro.Destination.AccountID)
+		micro, err := repo.getAccountMicroDeposits(context.Background(), micro.Destination.AccountID)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -53,7 +55,7 @@ func TestRepository__getAccountMicroDeposits(t *testing.T) {
 			t.Error("missing MicroDeposit")
 		}
 
-		micro, err = repo.getAccountMicroDeposits(base.ID())
+		micro, err = repo.getAccountMicroDeposits(context.Background(), base.ID())
 		if err != sql.ErrNoRows {
 			t.Error(err)
 		}
@@ -64,6 +66,39 @@ func TestRepository__getAccountMicroDeposits(t *testing.T) {
 
 	check(t, setupSQLiteDB(t))
 	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
+}
+
+func TestRepository__useConfirmationAttempt(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo Repository) {
+		micro := writeMicroDeposits(t, repo)
+		for i := 1; i <= 2; i++ {
+			attempts, err := repo.useConfirmationAttempt(micro.MicroDepositID, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts != i {
+				t.Errorf("attempts=%d expected %d", attempts, i)
+			}
+		}
+		if _, err := repo.useConfirmationAttempt(micro.MicroDepositID, 2); err != errNoConfirmationAttempts {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// a higher limit allows more attempts
+		if attempts, err := repo.useConfirmationAttempt(micro.MicroDepositID, 3); err != nil || attempts != 3 {
+			t.Errorf("attempts=%d error=%v", attempts, err)
+		}
+		if _, err := repo.useConfirmationAttempt(base.ID(), 2); err != errNoConfirmationAttempts {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+	check(t, NewInMemoryRepo())
 }
 
 func setupSQLiteDB(t *testing.T) *sqlRepo {
@@ -103,7 +138,7 @@ func writeMicroDeposits(t *testing.T, repo Repository) *client.MicroDeposits {
 		Status:  client.PENDING,
 		Created: time.Now(),
 	}
-	if err := repo.writeMicroDeposits(micro); err != nil {
+	if err := repo.writeMicroDeposits(base.ID(), micro); err != nil {
 		t.Fatal(err)
 	}
 	return micro
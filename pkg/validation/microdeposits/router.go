@@ -5,11 +5,14 @@
 package microdeposits
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -20,6 +23,8 @@ import (
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/organization"
 	"github.com/moov-io/paygate/pkg/transfers"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
@@ -30,12 +35,16 @@ type Router struct {
 	InitiateMicroDeposits   http.HandlerFunc
 	GetMicroDeposits        http.HandlerFunc
 	GetAccountMicroDeposits http.HandlerFunc
+	GetAccountVerification  http.HandlerFunc
+
+	ConfirmAccountMicroDeposits http.HandlerFunc
 }
 
 func NewRouter(
 	cfg *config.Config,
 	repo Repository,
 	transferRepo transfers.Repository,
+	orgRepo organization.Repository,
 	customersClient customers.Client,
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
@@ -46,6 +55,9 @@ func NewRouter(
 			InitiateMicroDeposits:   NotImplemented(cfg),
 			GetMicroDeposits:        NotImplemented(cfg),
 			GetAccountMicroDeposits: NotImplemented(cfg),
+			GetAccountVerification:  NotImplemented(cfg),
+
+			ConfirmAccountMicroDeposits: NotImplemented(cfg),
 		}
 	}
 
@@ -55,9 +67,12 @@ func NewRouter(
 	companyIdentification := cfg.ODFI.FileConfig.BatchHeader.CompanyIdentification
 
 	return &Router{
-		InitiateMicroDeposits:   InitiateMicroDeposits(cfg, companyIdentification, repo, transferRepo, customersClient, accountDecryptor, fundStrategy, pub),
-		GetMicroDeposits:        GetMicroDeposits(cfg, repo),
-		GetAccountMicroDeposits: GetAccountMicroDeposits(cfg, repo),
+		InitiateMicroDeposits:   InitiateMicroDeposits(cfg, companyIdentification, repo, transferRepo, orgRepo, customersClient, accountDecryptor, fundStrategy, pub),
+		GetMicroDeposits:        GetMicroDeposits(cfg, repo, transferRepo),
+		GetAccountMicroDeposits: GetAccountMicroDeposits(cfg, repo, transferRepo),
+		GetAccountVerification:  GetAccountVerification(cfg, repo, transferRepo, customersClient),
+
+		ConfirmAccountMicroDeposits: ConfirmAccountMicroDeposits(cfg, repo, transferRepo),
 	}
 }
 
@@ -65,6 +80,8 @@ func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("POST").Path("/micro-deposits").HandlerFunc(c.InitiateMicroDeposits)
 	r.Methods("GET").Path("/micro-deposits/{microDepositID}").HandlerFunc(c.GetMicroDeposits)
 	r.Methods("GET").Path("/accounts/{accountID}/micro-deposits").HandlerFunc(c.GetAccountMicroDeposits)
+	r.Methods("GET").Path("/accounts/{accountID}/micro-deposits/verification").HandlerFunc(c.GetAccountVerification)
+	r.Methods("POST").Path("/accounts/{accountID}/micro-deposits/confirm").HandlerFunc(c.ConfirmAccountMicroDeposits)
 }
 
 func InitiateMicroDeposits(
@@ -72,56 +89,97 @@ func InitiateMicroDeposits(
 	companyIdentification string,
 	repo Repository,
 	transferRepo transfers.Repository,
+	orgRepo organization.Repository,
 	customersClient customers.Client,
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
 	pub pipeline.XferPublisher,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		conf := *cfg.Validation.MicroDeposits
-		cfg.Logger = cfg.Logger.Set("service", log.String("micro-deposits"))
-
 		responder := route.NewResponder(cfg, w, r)
+		conf := organizationConfig(*cfg.Validation.MicroDeposits, cfg.Features, responder.OrganizationID)
+		logger := responder.Logger().Set("service", log.String("micro-deposits"))
 		responder.Respond(func(w http.ResponseWriter) {
 			var req client.CreateMicroDeposits
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if err := route.DecodeJSON(r, &req); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if err := validateCreateRequest(req); err != nil {
 				responder.Problem(err)
 				return
 			}
 
+			// Queue the micro-deposits for a Queue worker to initiate, clients poll for their status
+			if conf.Async != nil && cfg.Features.AsyncMicroDeposits.EnabledFor(responder.OrganizationID) {
+				micro := newMicroDeposits(req.Destination)
+				if err := repo.enqueueMicroDeposits(responder.OrganizationID, micro); err != nil {
+					if err == errAccountMicroDeposits {
+						responder.Problem(route.Conflict.New("accountID=%s already has micro-deposits", req.Destination.AccountID))
+					} else {
+						logger.LogErrorf("ERROR queueing micro-deposits: %v", err)
+						responder.Problem(route.Internal.Wrap(err))
+					}
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(micro)
+				return
+			}
+
 			src, err := getMicroDepositSource(conf, customersClient, accountDecryptor)
 			if err != nil {
-				cfg.Logger.LogErrorf("ERROR getting micro-deposit source: %v", err)
-				responder.Problem(err)
+				logger.LogErrorf("ERROR getting micro-deposit source: %v", err)
+				responder.Problem(route.Unavailable.Wrap(err))
 				return
 			}
 			dest, err := transfers.GetFundflowDestination(customersClient, accountDecryptor, req.Destination, responder.OrganizationID)
 			if err != nil {
-				cfg.Logger.LogErrorf("ERROR getting micro-deposit destination: %v", err)
-				responder.Problem(err)
+				logger.LogErrorf("ERROR getting micro-deposit destination: %v", err)
+				responder.Problem(route.Unavailable.Wrap(err))
 				return
 			}
 			if src.Account.RoutingNumber == dest.Account.RoutingNumber {
 				err = errors.New("not initiating micro-deposits for account at ODFI")
-				cfg.Logger.LogError(err)
+				logger.LogError(err)
 				responder.Problem(err)
 				return
 			}
 			if err := acceptableAccountStatus(dest.Account); err != nil {
-				cfg.Logger.LogErrorf("destination account: %v", err)
+				logger.LogErrorf("destination account: %v", err)
 				responder.Problem(err)
 				return
 			}
 
-			micro, err := createMicroDeposits(conf, responder.OrganizationID, companyIdentification, src, dest, transferRepo, accountDecryptor, fundStrategy, pub)
+			// Only one instance initiates micro-deposits for an account at a time
+			unlock, err := repo.lockAccount(dest.Account.AccountID)
 			if err != nil {
-				cfg.Logger.LogErrorf("ERROR creating micro-deposits: %v", err)
-				responder.Problem(err)
+				if err == database.ErrLocked {
+					responder.Problem(route.Conflict.New("micro-deposits are already being initiated for accountID=%s", dest.Account.AccountID))
+				} else {
+					logger.LogErrorf("ERROR locking account: %v", err)
+					responder.Problem(route.Internal.Wrap(err))
+				}
 				return
 			}
-			if err := repo.writeMicroDeposits(micro); err != nil {
-				cfg.Logger.LogErrorf("ERROR writing micro-deposits: %v", err)
-				responder.Problem(err)
+			defer unlock()
+
+			orgConfig, err := orgRepo.GetConfig(responder.OrganizationID)
+			if err != nil {
+				logger.LogErrorf("ERROR getting organization config: %v", err)
+				responder.Problem(route.Internal.Wrap(err))
+				return
+			}
+			micro, err := createMicroDeposits(conf, responder.OrganizationID, orgConfig, companyIdentification, src, dest, transferRepo, accountDecryptor, fundStrategy, pub)
+			if err != nil {
+				logger.LogErrorf("ERROR creating micro-deposits: %v", err)
+				responder.Problem(route.Internal.Wrap(err))
+				return
+			}
+			logger = logger.Set("microDepositID", log.String(micro.MicroDepositID))
+			if err := repo.writeMicroDeposits(responder.OrganizationID, micro); err != nil {
+				logger.LogErrorf("ERROR writing micro-deposits: %v", err)
+				responder.Problem(route.Internal.Wrap(err))
 				return
 			}
 
@@ -131,6 +189,17 @@ func InitiateMicroDeposits(
 	}
 }
 
+func validateCreateRequest(req client.CreateMicroDeposits) error {
+	verr := &route.ValidationError{}
+	if req.Destination.CustomerID == "" {
+		verr.Add("destination.customerID", "missing")
+	}
+	if req.Destination.AccountID == "" {
+		verr.Add("destination.accountID", "missing")
+	}
+	return verr.Err()
+}
+
 func getMicroDepositSource(cfg config.MicroDeposits, customersClient customers.Client, accountDecryptor accounts.Decryptor) (fundflow.Source, error) {
 	return transfers.GetFundflowSource(customersClient, accountDecryptor, client.Source{
 		CustomerID: cfg.Source.CustomerID,
@@ -145,7 +214,7 @@ func acceptableAccountStatus(acct moovcustomers.Account) error {
 	return fmt.Errorf("accountID=%s is un unacceptable status: %v", acct.AccountID, acct.Status)
 }
 
-func GetMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFunc {
+func GetMicroDeposits(cfg *config.Config, repo Repository, transferRepo transfers.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 		responder.Respond(func(w http.ResponseWriter) {
@@ -155,9 +224,17 @@ func GetMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFunc {
 				return
 			}
 
-			micro, err := repo.getMicroDeposits(microDepositID)
+			micro, err := repo.getMicroDeposits(r.Context(), microDepositID)
 			if err != nil && err != sql.ErrNoRows {
-				cfg.Logger.LogErrorf("ERROR getting micro-deposits: %v", err)
+				responder.Logger().Set("microDepositID", log.String(microDepositID)).LogErrorf("ERROR getting micro-deposits: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if err := loadTransfers(r.Context(), transferRepo, responder.OrganizationID, micro); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if err := impersonatedAmounts(cfg, responder, r, micro); err != nil {
 				responder.Problem(err)
 				return
 			}
@@ -168,7 +245,10 @@ func GetMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFunc {
 	}
 }
 
-func GetAccountMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFunc {
+// GetAccountMicroDeposits returns the micro-deposits of an account with their amounts masked, as
+// the account holder confirms them with ConfirmAccountMicroDeposits. Amounts are only revealed
+// to impersonating admin users.
+func GetAccountMicroDeposits(cfg *config.Config, repo Repository, transferRepo transfers.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
 		responder.Respond(func(w http.ResponseWriter) {
@@ -178,9 +258,20 @@ func GetAccountMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFu
 				return
 			}
 
-			micro, err := repo.getAccountMicroDeposits(accountID)
+			micro, err := repo.getAccountMicroDeposits(r.Context(), accountID)
 			if err != nil && err != sql.ErrNoRows {
-				cfg.Logger.LogErrorf("ERROR getting accountID=%s micro-deposits: %v", accountID, err)
+				responder.Logger().Set("accountID", log.String(accountID)).LogErrorf("ERROR getting micro-deposits: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if err := loadTransfers(r.Context(), transferRepo, responder.OrganizationID, micro); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if micro != nil && route.Impersonator(r) == "" {
+				maskAmounts(micro)
+			}
+			if err := impersonatedAmounts(cfg, responder, r, micro); err != nil {
 				responder.Problem(err)
 				return
 			}
@@ -191,9 +282,157 @@ func GetAccountMicroDeposits(cfg *config.Config, repo Repository) http.HandlerFu
 	}
 }
 
+// ConfirmAccountMicroDeposits compares the amounts entered by the account holder against the
+// micro-deposits sent to an account. Each comparison uses one of a limited number of attempts
+// so the amounts can't be guessed.
+func ConfirmAccountMicroDeposits(cfg *config.Config, repo Repository, transferRepo transfers.Repository) http.HandlerFunc {
+	maxAttempts := cfg.Validation.MicroDeposits.Verification.Attempts()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			accountID := route.ReadPathID("accountID", r)
+			if accountID == "" {
+				responder.Problem(errors.New("missing accountID"))
+				return
+			}
+			logger := responder.Logger().Set("accountID", log.String(accountID))
+
+			var req client.ConfirmMicroDeposits
+			if err := route.DecodeJSON(r, &req); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if len(req.Amounts) == 0 {
+				verr := &route.ValidationError{}
+				verr.Add("amounts", "missing")
+				responder.Problem(verr.Err())
+				return
+			}
+
+			micro, err := repo.getAccountMicroDeposits(r.Context(), accountID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					responder.Problem(route.NotFound.New("micro-deposits not found"))
+				} else {
+					logger.LogErrorf("ERROR getting micro-deposits: %v", err)
+					responder.Problem(route.Internal.Wrap(err))
+				}
+				return
+			}
+			if err := loadTransfers(r.Context(), transferRepo, responder.OrganizationID, micro); err != nil {
+				responder.Problem(err)
+				return
+			}
+			if len(micro.Amounts) == 0 {
+				responder.Problem(route.InvalidRequest.New("micro-deposits for accountID=%s have not been sent", accountID))
+				return
+			}
+
+			attempts, err := repo.useConfirmationAttempt(micro.MicroDepositID, maxAttempts)
+			if err != nil {
+				if err == errNoConfirmationAttempts {
+					responder.Problem(route.Forbidden.New("no attempts to confirm micro-deposits for accountID=%s are left", accountID))
+				} else {
+					logger.LogErrorf("ERROR using micro-deposit confirmation attempt: %v", err)
+					responder.Problem(route.Internal.Wrap(err))
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(client.MicroDepositConfirmation{
+				AccountID:         accountID,
+				Confirmed:         sameAmounts(micro.Amounts, req.Amounts),
+				AttemptsRemaining: int32(maxAttempts - attempts),
+			})
+		})
+	}
+}
+
+// sameAmounts returns true when entered has each of the sent amounts, in any order.
+func sameAmounts(sent, entered []client.Amount) bool {
+	if len(sent) == 0 || len(sent) != len(entered) {
+		return false
+	}
+	a, b := sortedAmounts(sent), sortedAmounts(entered)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedAmounts(amounts []client.Amount) []client.Amount {
+	out := make([]client.Amount, len(amounts))
+	copy(out, amounts)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Currency != out[j].Currency {
+			return out[i].Currency < out[j].Currency
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// loadTransfers sets the trace numbers, upload time and any return of each Transfer
+// created for micro. Accounts are left out as the source is the ODFI's account.
+func loadTransfers(ctx context.Context, transferRepo transfers.Repository, organization string, micro *client.MicroDeposits) error {
+	if micro == nil {
+		return nil
+	}
+	for i := range micro.TransferIDs {
+		xfer, err := transferRepo.GetUserTransfer(ctx, micro.TransferIDs[i], organization)
+		if err != nil && err != sql.ErrNoRows {
+			return route.Internal.Wrap(err)
+		}
+		if xfer == nil {
+			return route.NotFound.New("micro-deposits not found")
+		}
+		micro.Transfers = append(micro.Transfers, client.MicroDepositTransfer{
+			TransferID:   xfer.TransferID,
+			Amount:       xfer.Amount,
+			Status:       xfer.Status,
+			TraceNumbers: xfer.TraceNumbers,
+			ProcessedAt:  xfer.ProcessedAt,
+			ReturnCode:   xfer.ReturnCode,
+		})
+	}
+	return nil
+}
+
+// impersonatedAmounts masks the amounts of micro when an admin user reads it as the organization,
+// as they're all that's needed to verify the account. Admin users reveal them with ?revealAmounts=true
+// when admin.impersonation.revealMicroDepositAmounts is set, which is logged.
+func impersonatedAmounts(cfg *config.Config, responder *route.Responder, r *http.Request, micro *client.MicroDeposits) error {
+	if micro == nil || route.Impersonator(r) == "" {
+		return nil
+	}
+	if reveal, _ := strconv.ParseBool(r.URL.Query().Get("revealAmounts")); !reveal {
+		maskAmounts(micro)
+		return nil
+	}
+	if cfg.Admin.Impersonation == nil || !cfg.Admin.Impersonation.RevealMicroDepositAmounts {
+		return route.Forbidden.New("revealing micro-deposit amounts is disabled via config")
+	}
+	responder.Logger().Set("microDepositID", log.String(micro.MicroDepositID)).Log("revealed micro-deposit amounts")
+	return nil
+}
+
+// maskAmounts sets each amount of micro to zero, keeping their currency.
+func maskAmounts(micro *client.MicroDeposits) {
+	for i := range micro.Amounts {
+		micro.Amounts[i].Value = 0
+	}
+	for i := range micro.Transfers {
+		micro.Transfers[i].Amount.Value = 0
+	}
+}
+
 func NotImplemented(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(cfg, w, r)
-		responder.Problem(errors.New("micro-deposits are disabled via config"))
+		responder.Problem(route.Disabled.New("micro-deposits are disabled via config"))
 	}
 }
@@ -6,6 +6,7 @@ package microdeposits
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -20,10 +21,13 @@ import (
 	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/organization"
 	"github.com/moov-io/paygate/pkg/testclient"
 	"github.com/moov-io/paygate/pkg/transfers"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/x/route"
 
 	"github.com/gorilla/mux"
 )
@@ -60,6 +64,8 @@ var (
 	mockStrategy = &fundflow.MockStrategy{}
 
 	mockDecryptor = &accounts.MockDecryptor{Number: "12345"}
+
+	mockOrgRepo = &organization.MockRepository{}
 )
 
 func mockCustomersClient() *customers.MockClient {
@@ -138,7 +144,7 @@ func TestRouter__NotImplemented(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	req := httptest.NewRequest("GET", fmt.Sprintf("/micro-deposits/%s", base.ID()), nil)
@@ -165,7 +171,7 @@ func TestRouter__InitiateMicroDeposits(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -191,13 +197,40 @@ func TestRouter__InitiateMicroDeposits(t *testing.T) {
 	}
 }
 
+func TestRouter__InitiateMicroDepositsLocked(t *testing.T) {
+	cfg := mockConfig()
+	customersClient := mockCustomersClient()
+	repo := &mockRepository{LockErr: database.ErrLocked}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	_, resp, err := c.ValidationApi.InitiateMicroDeposits(context.TODO(), base.ID(), client.CreateMicroDeposits{
+		Destination: client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+}
+
 func TestRouter__InitiateMicroDepositsErr(t *testing.T) {
 	cfg := mockConfig()
 	customersClient := mockCustomersClient()
 	repo := &mockRepository{Err: errors.New("bad request")}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -219,7 +252,7 @@ func TestRouter__GetMicroDeposits(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -243,7 +276,7 @@ func TestRouter__GetMicroDepositsEmpty(t *testing.T) {
 	repo := &mockRepository{}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -267,7 +300,7 @@ func TestRouter__GetMicroDepositsErr(t *testing.T) {
 	repo := &mockRepository{Err: errors.New("bad error")}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -289,7 +322,7 @@ func TestRouter__GetAccountMicroDeposits(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -304,6 +337,96 @@ func TestRouter__GetAccountMicroDeposits(t *testing.T) {
 	if micro.MicroDepositID == "" {
 		t.Error("missing MicroDeposit")
 	}
+
+	// amounts are confirmed by the account holder, so they're not returned
+	if len(micro.Amounts) != 2 || micro.Amounts[0].Value != 0 || micro.Amounts[1].Value != 0 {
+		t.Errorf("unexpected amounts: %#v", micro.Amounts)
+	}
+	for i := range micro.Transfers {
+		if micro.Transfers[i].Amount.Value != 0 {
+			t.Errorf("unexpected transfer amount: %#v", micro.Transfers[i])
+		}
+	}
+}
+
+func TestRouter__ConfirmAccountMicroDeposits(t *testing.T) {
+	cfg := mockConfig()
+	cfg.Validation.MicroDeposits.Verification = &config.MicroDepositVerification{
+		MaxAttempts: 2,
+	}
+
+	orgID := base.ID()
+	transferRepo := transfers.NewInMemoryRepo()
+	micro := mockMicroDeposit()
+	for i := range micro.TransferIDs {
+		err := transferRepo.WriteUserTransfer(orgID, &client.Transfer{
+			TransferID: micro.TransferIDs[i],
+			Amount:     micro.Amounts[i],
+			Status:     client.PENDING,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := NewInMemoryRepo()
+	if err := repo.writeMicroDeposits(orgID, micro); err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, transferRepo, mockOrgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+	confirm := func(values ...int32) (client.MicroDepositConfirmation, error) {
+		var req client.ConfirmMicroDeposits
+		for i := range values {
+			req.Amounts = append(req.Amounts, client.Amount{Currency: "USD", Value: values[i]})
+		}
+		conf, resp, err := c.ValidationApi.ConfirmAccountMicroDeposits(context.TODO(), micro.Destination.AccountID, orgID, req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return conf, err
+	}
+
+	conf, err := confirm(2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Confirmed || conf.AttemptsRemaining != 1 {
+		t.Errorf("unexpected confirmation: %#v", conf)
+	}
+
+	// the order of amounts doesn't matter
+	conf, err = confirm(5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conf.Confirmed || conf.AttemptsRemaining != 0 || conf.AccountID != micro.Destination.AccountID {
+		t.Errorf("unexpected confirmation: %#v", conf)
+	}
+
+	// every attempt has been used
+	_, err = confirm(2, 5)
+	var oerr client.GenericOpenAPIError
+	if !errors.As(err, &oerr) || !strings.Contains(string(oerr.Body()), "no attempts") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// amounts are required
+	if _, err := confirm(); err == nil {
+		t.Error("expected error")
+	}
+
+	// micro-deposits from another organization are not found
+	_, resp, err := c.ValidationApi.ConfirmAccountMicroDeposits(context.TODO(), micro.Destination.AccountID, base.ID(), client.ConfirmMicroDeposits{
+		Amounts: micro.Amounts,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
 }
 
 func TestRouter__GetAccountMicroDepositsEmpty(t *testing.T) {
@@ -313,7 +436,7 @@ func TestRouter__GetAccountMicroDepositsEmpty(t *testing.T) {
 	repo := &mockRepository{}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -337,7 +460,7 @@ func TestRouter__GetAccountMicroDepositsErr(t *testing.T) {
 	repo := &mockRepository{Err: errors.New("bad error")}
 
 	r := mux.NewRouter()
-	router := NewRouter(cfg, repo, mockTransferRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(cfg, repo, mockTransferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -349,3 +472,123 @@ func TestRouter__GetAccountMicroDepositsErr(t *testing.T) {
 	}
 	resp.Body.Close()
 }
+
+func TestRouter__GetMicroDepositsTransfers(t *testing.T) {
+	cfg := mockConfig()
+	customersClient := mockCustomersClient()
+
+	orgID := base.ID()
+	micro := mockMicroDeposit()
+	transferRepo := transfers.NewInMemoryRepo()
+	for i := range micro.TransferIDs {
+		err := transferRepo.WriteUserTransfer(orgID, &client.Transfer{
+			TransferID: micro.TransferIDs[i],
+			Amount:     micro.Amounts[i],
+			Status:     client.PENDING,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := transferRepo.SaveReturnCode(micro.TransferIDs[1], "R03"); err != nil {
+		t.Fatal(err)
+	}
+	repo := &mockRepository{Micro: micro}
+
+	r := mux.NewRouter()
+	router := NewRouter(cfg, repo, transferRepo, mockOrgRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	found, resp, err := c.ValidationApi.GetMicroDeposits(context.TODO(), micro.MicroDepositID, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(found.Transfers) != 2 {
+		t.Fatalf("unexpected transfers: %#v", found.Transfers)
+	}
+	if xfer := found.Transfers[1]; xfer.TransferID != micro.TransferIDs[1] || xfer.ReturnCode == nil || xfer.ReturnCode.Code != "R03" {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+
+	// micro-deposits from another organization are not found
+	repo.Micro.Transfers = nil
+	_, resp, err = c.ValidationApi.GetAccountMicroDeposits(context.TODO(), micro.Destination.AccountID, base.ID())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	resp.Body.Close()
+}
+
+func TestRouter__GetMicroDepositsImpersonated(t *testing.T) {
+	cfg := mockConfig()
+	cfg.Admin.Impersonation = &config.Impersonation{
+		Users: []string{"jane"},
+	}
+
+	orgID := base.ID()
+	transferRepo := transfers.NewInMemoryRepo()
+	micro := mockMicroDeposit()
+	for i := range micro.TransferIDs {
+		err := transferRepo.WriteUserTransfer(orgID, &client.Transfer{
+			TransferID: micro.TransferIDs[i],
+			Amount:     micro.Amounts[i],
+			Status:     client.PENDING,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(t *testing.T, query string) (*client.MicroDeposits, *httptest.ResponseRecorder) {
+		t.Helper()
+
+		found := mockMicroDeposit()
+		found.MicroDepositID, found.TransferIDs = micro.MicroDepositID, micro.TransferIDs
+
+		r := mux.NewRouter()
+		router := NewRouter(cfg, &mockRepository{Micro: found}, transferRepo, mockOrgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher)
+		router.RegisterRoutes(r)
+
+		req := httptest.NewRequest("GET", "/micro-deposits/"+micro.MicroDepositID+query, nil)
+		req.Header.Set("X-Organization", orgID)
+		req = req.WithContext(route.WithImpersonator(req.Context(), "jane"))
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		w.Flush()
+
+		var out client.MicroDeposits
+		json.NewDecoder(w.Body).Decode(&out)
+		return &out, w
+	}
+
+	// amounts are masked by default
+	out, w := get(t, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus HTTP status: %d: %s", w.Code, w.Body.String())
+	}
+	if len(out.Amounts) != 2 || out.Amounts[0].Value != 0 || out.Amounts[0].Currency != "USD" {
+		t.Errorf("unexpected amounts: %#v", out.Amounts)
+	}
+	if len(out.Transfers) != 2 || out.Transfers[1].Amount.Value != 0 {
+		t.Errorf("unexpected transfers: %#v", out.Transfers)
+	}
+
+	// revealing them has to be enabled
+	if _, w := get(t, "?revealAmounts=true"); w.Code != http.StatusForbidden {
+		t.Errorf("bogus HTTP status: %d: %s", w.Code, w.Body.String())
+	}
+
+	cfg.Admin.Impersonation.RevealMicroDepositAmounts = true
+	out, w = get(t, "?revealAmounts=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("bogus HTTP status: %d: %s", w.Code, w.Body.String())
+	}
+	if len(out.Amounts) != 2 || out.Amounts[0].Value != 2 || out.Transfers[1].Amount.Value != 5 {
+		t.Errorf("unexpected micro-deposits: %#v", out)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/client/paygate"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/jobs"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/moov-io/base/log"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	verificationWebhooks = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "micro_deposit_verification_webhooks",
+		Help: "Counter of verification state webhooks by state and whether they were delivered",
+	}, []string{"state", "result"})
+)
+
+// getVerification returns the verification state of an account from its micro-deposits, their
+// Transfers and the account's status in Moov Customers. Confirming the amounts happens in
+// Moov Customers, which marks the account as validated.
+func getVerification(cfg *config.MicroDepositVerification, accountID string, micro *client.MicroDeposits, acct *moovcustomers.Account, now time.Time) client.MicroDepositVerification {
+	out := client.MicroDepositVerification{
+		AccountID: accountID,
+		State:     client.VERIFICATION_UNVERIFIED,
+	}
+	if micro == nil {
+		return out
+	}
+	out.MicroDepositID = micro.MicroDepositID
+
+	if acct != nil && strings.EqualFold(string(acct.Status), string(moovcustomers.ACCOUNTSTATUS_VALIDATED)) {
+		out.State = client.VERIFICATION_VERIFIED
+		return out
+	}
+	if micro.Status == client.FAILED {
+		out.State = client.VERIFICATION_FAILED
+		return out
+	}
+	for i := range micro.Transfers {
+		if micro.Transfers[i].ReturnCode != nil || micro.Transfers[i].Status == client.FAILED {
+			out.State = client.VERIFICATION_FAILED
+			return out
+		}
+	}
+	if micro.Status != client.PROCESSED || micro.ProcessedAt == nil {
+		out.State = client.VERIFICATION_MICRO_DEPOSITS_SENT
+		return out
+	}
+
+	expiresAt := micro.ProcessedAt.Add(cfg.Expiry())
+	out.ExpiresAt = &expiresAt
+	if now.After(expiresAt) {
+		out.State = client.VERIFICATION_EXPIRED
+	} else {
+		out.State = client.VERIFICATION_AWAITING_CONFIRMATION
+	}
+	return out
+}
+
+// readVerification loads the Transfers of micro and its account before returning the verification state.
+func readVerification(
+	ctx context.Context,
+	cfg *config.MicroDepositVerification,
+	transferRepo transfers.Repository,
+	customersClient customers.Client,
+	organization string,
+	micro *client.MicroDeposits,
+) (client.MicroDepositVerification, error) {
+	if err := loadTransfers(ctx, transferRepo, organization, micro); err != nil {
+		return client.MicroDepositVerification{}, err
+	}
+	acct, err := customersClient.FindAccount(organization, micro.Destination.CustomerID, micro.Destination.AccountID)
+	if err != nil {
+		return client.MicroDepositVerification{}, route.Unavailable.Wrap(err)
+	}
+	return getVerification(cfg, micro.Destination.AccountID, micro, acct, time.Now()), nil
+}
+
+func GetAccountVerification(cfg *config.Config, repo Repository, transferRepo transfers.Repository, customersClient customers.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(cfg, w, r)
+		responder.Respond(func(w http.ResponseWriter) {
+			accountID := route.ReadPathID("accountID", r)
+			if accountID == "" {
+				responder.Problem(errors.New("missing accountID"))
+				return
+			}
+			logger := responder.Logger().Set("accountID", log.String(accountID))
+
+			verification := client.MicroDepositVerification{
+				AccountID: accountID,
+				State:     client.VERIFICATION_UNVERIFIED,
+			}
+			micro, err := repo.getAccountMicroDeposits(r.Context(), accountID)
+			if err != nil && err != sql.ErrNoRows {
+				logger.LogErrorf("ERROR getting micro-deposits: %v", err)
+				responder.Problem(err)
+				return
+			}
+			if micro != nil {
+				verification, err = readVerification(r.Context(), cfg.Validation.MicroDeposits.Verification, transferRepo, customersClient, responder.OrganizationID, micro)
+				if err != nil {
+					logger.LogErrorf("ERROR reading verification: %v", err)
+					responder.Problem(err)
+					return
+				}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(verification)
+		})
+	}
+}
+
+// verificationEvent is the body POSTed to the webhook for each change of verification state.
+type verificationEvent struct {
+	Type          string                          `json:"type"`
+	Organization  string                          `json:"organization"`
+	PreviousState client.VerificationState        `json:"previousState"`
+	Verification  client.MicroDepositVerification `json:"verification"`
+	Branding      *paygate.Branding               `json:"branding,omitempty"`
+}
+
+const verificationEventType = paygate.EventMicroDepositVerification
+
+// Watcher checks micro-deposits for changes of their verification state and POSTs each
+// change to the configured webhook.
+//
+// The last state sent is saved with the micro-deposits and updated before the webhook is
+// called, so only one instance sends each change. Changes the webhook doesn't accept are
+// reverted and sent again on the next check.
+type Watcher struct {
+	cfg    *config.MicroDepositVerification
+	logger log.Logger
+
+	repo            Repository
+	transferRepo    transfers.Repository
+	orgRepo         organization.Repository
+	customersClient customers.Client
+
+	client *http.Client
+
+	job *jobs.Job
+}
+
+// NewWatcher returns nil unless a verification webhook is configured.
+func NewWatcher(cfg *config.Config, repo Repository, transferRepo transfers.Repository, orgRepo organization.Repository, customersClient customers.Client) *Watcher {
+	if cfg.Validation.MicroDeposits == nil || cfg.Validation.MicroDeposits.Verification == nil {
+		return nil
+	}
+	if cfg.Validation.MicroDeposits.Verification.Webhook == nil {
+		return nil
+	}
+	return &Watcher{
+		cfg:             cfg.Validation.MicroDeposits.Verification,
+		logger:          cfg.Logger.Set("service", log.String("micro-deposit-verification")),
+		repo:            repo,
+		transferRepo:    transferRepo,
+		orgRepo:         orgRepo,
+		customersClient: customersClient,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// UseJobs records the runs of the Watcher as the "microdeposits.verification" job of reg.
+func (wt *Watcher) UseJobs(reg *jobs.Registry) {
+	if wt == nil {
+		return
+	}
+	wt.job = reg.Register("microdeposits.verification")
+}
+
+// Start checks for changes of verification state until ctx is canceled.
+func (wt *Watcher) Start(ctx context.Context) {
+	if wt == nil {
+		return
+	}
+	interval := wt.cfg.Webhook.PollInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	wt.job.Scheduled(time.Now().Add(interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			wt.run()
+			wt.job.Scheduled(time.Now().Add(interval))
+
+		case <-wt.job.Triggered():
+			wt.run()
+
+		case <-ctx.Done():
+			wt.logger.Log("micro-deposit verification watcher shutdown")
+			return
+		}
+	}
+}
+
+func (wt *Watcher) run() {
+	_, err := wt.Process()
+	if err != nil {
+		wt.logger.LogErrorf("ERROR checking micro-deposit verifications: %v", err)
+	}
+	wt.job.Done(err)
+}
+
+// Process sends a webhook for each micro-deposits whose verification state changed and
+// returns how many were sent.
+func (wt *Watcher) Process() (int, error) {
+	items, err := wt.repo.getWatchedVerifications()
+	if err != nil {
+		return 0, err
+	}
+	var sent int
+	for i := range items {
+		if wt.process(items[i]) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (wt *Watcher) process(item watchedVerification) bool {
+	logger := wt.logger.Set("microDepositID", log.String(item.microDepositID))
+
+	micro, err := wt.repo.getMicroDeposits(context.Background(), item.microDepositID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.LogErrorf("ERROR getting micro-deposits: %v", err)
+		}
+		return false
+	}
+	verification, err := readVerification(context.Background(), wt.cfg, wt.transferRepo, wt.customersClient, item.organization, micro)
+	if err != nil {
+		logger.LogErrorf("ERROR reading verification: %v", err)
+		return false
+	}
+	if verification.State == item.state {
+		return false
+	}
+	orgConfig, err := wt.orgRepo.GetConfig(item.organization)
+	if err != nil {
+		logger.LogErrorf("ERROR reading organization branding: %v", err)
+		return false
+	}
+
+	// Another instance could notice the same change, only the one which records it sends the webhook
+	if updated, err := wt.repo.updateNotifiedState(item.microDepositID, item.state, verification.State); err != nil || !updated {
+		if err != nil {
+			logger.LogErrorf("ERROR recording verification state: %v", err)
+		}
+		return false
+	}
+
+	err = wt.send(verificationEvent{
+		Type:          verificationEventType,
+		Organization:  item.organization,
+		PreviousState: item.state,
+		Verification:  verification,
+		Branding:      paygate.NewBranding(orgConfig),
+	})
+	if err != nil {
+		logger.LogErrorf("ERROR sending %s verification webhook: %v", verification.State, err)
+		verificationWebhooks.With("state", string(verification.State), "result", "failed").Add(1)
+
+		if _, err := wt.repo.updateNotifiedState(item.microDepositID, verification.State, item.state); err != nil {
+			logger.LogErrorf("ERROR reverting verification state: %v", err)
+		}
+		return false
+	}
+	verificationWebhooks.With("state", string(verification.State), "result", "sent").Add(1)
+	logger.Logf("sent verification webhook for %s to %s", item.state, verification.State)
+	return true
+}
+
+func (wt *Watcher) send(event verificationEvent) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(event); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", wt.cfg.Webhook.Endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Organization", event.Organization)
+	if secret := wt.cfg.Webhook.Secret; secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(paygate.WebhookTimestampHeader, timestamp)
+		req.Header.Set(paygate.WebhookSignatureHeader, paygate.SignWebhook(secret, timestamp, body.Bytes()))
+	}
+
+	resp, err := wt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	return nil
+}
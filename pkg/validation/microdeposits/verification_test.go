@@ -0,0 +1,190 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package microdeposits
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/pkg/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/organization"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/gorilla/mux"
+)
+
+func TestVerification__getVerification(t *testing.T) {
+	now := time.Now()
+	acct := &moovcustomers.Account{Status: moovcustomers.ACCOUNTSTATUS_NONE}
+
+	if v := getVerification(nil, destinationAccountID, nil, nil, now); v.State != client.VERIFICATION_UNVERIFIED {
+		t.Errorf("unexpected state: %v", v.State)
+	}
+
+	micro := mockMicroDeposit()
+	if v := getVerification(nil, destinationAccountID, micro, acct, now); v.State != client.VERIFICATION_MICRO_DEPOSITS_SENT {
+		t.Errorf("unexpected state: %v", v.State)
+	}
+
+	processedAt := now.Add(-1 * time.Hour)
+	micro.Status, micro.ProcessedAt = client.PROCESSED, &processedAt
+	v := getVerification(nil, destinationAccountID, micro, acct, now)
+	if v.State != client.VERIFICATION_AWAITING_CONFIRMATION || v.ExpiresAt == nil {
+		t.Errorf("unexpected verification: %#v", v)
+	}
+	if !v.ExpiresAt.Equal(processedAt.Add(config.DefaultMicroDepositExpiry)) {
+		t.Errorf("unexpected expiresAt: %v", v.ExpiresAt)
+	}
+
+	cfg := &config.MicroDepositVerification{ExpiresAfter: time.Minute}
+	if v := getVerification(cfg, destinationAccountID, micro, acct, now); v.State != client.VERIFICATION_EXPIRED {
+		t.Errorf("unexpected state: %v", v.State)
+	}
+
+	micro.Transfers = []client.MicroDepositTransfer{{ReturnCode: &client.ReturnCode{Code: "R03"}}}
+	if v := getVerification(nil, destinationAccountID, micro, acct, now); v.State != client.VERIFICATION_FAILED {
+		t.Errorf("unexpected state: %v", v.State)
+	}
+
+	acct.Status = moovcustomers.ACCOUNTSTATUS_VALIDATED
+	if v := getVerification(nil, destinationAccountID, micro, acct, now); v.State != client.VERIFICATION_VERIFIED {
+		t.Errorf("unexpected state: %v", v.State)
+	}
+}
+
+func TestRouter__GetAccountVerification(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	r := mux.NewRouter()
+	router := NewRouter(mockConfig(), repo, mockTransferRepo, mockOrgRepo, mockCustomersClient(), mockDecryptor, mockStrategy, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	verification, resp, err := c.ValidationApi.GetAccountVerification(context.TODO(), destinationAccountID, base.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if verification.AccountID != destinationAccountID || verification.State != client.VERIFICATION_UNVERIFIED {
+		t.Errorf("unexpected verification: %#v", verification)
+	}
+
+	micro := newMicroDeposits(client.Destination{
+		CustomerID: destinationCustomerID,
+		AccountID:  destinationAccountID,
+	})
+	if err := repo.writeMicroDeposits(base.ID(), micro); err != nil {
+		t.Fatal(err)
+	}
+
+	verification, resp, err = c.ValidationApi.GetAccountVerification(context.TODO(), destinationAccountID, base.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if verification.MicroDepositID != micro.MicroDepositID || verification.State != client.VERIFICATION_MICRO_DEPOSITS_SENT {
+		t.Errorf("unexpected verification: %#v", verification)
+	}
+}
+
+func TestWatcher__nil(t *testing.T) {
+	watcher := NewWatcher(mockConfig(), &mockRepository{}, mockTransferRepo, mockOrgRepo, mockCustomersClient())
+	if watcher != nil {
+		t.Fatalf("unexpected Watcher: %#v", watcher)
+	}
+	watcher.Start(context.Background())
+}
+
+func TestWatcher__Process(t *testing.T) {
+	status := http.StatusOK
+	var events []verificationEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event verificationEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events = append(events, event)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	check := func(t *testing.T, repo Repository) {
+		events = nil
+		status = http.StatusOK
+
+		cfg := mockConfig()
+		cfg.Validation.MicroDeposits.Verification = &config.MicroDepositVerification{
+			Webhook: &config.MicroDepositWebhook{
+				Endpoint: server.URL,
+			},
+		}
+		customersClient := mockCustomersClient()
+		orgRepo := organization.NewInMemoryRepo()
+		orgRepo.UpdateConfig("organization", &client.OrganizationConfiguration{
+			CompanyIdentification: "MOOVZZZZZZ",
+			DisplayName:           "Acme Payroll",
+			SupportEmail:          "support@acme.com",
+		})
+		watcher := NewWatcher(cfg, repo, mockTransferRepo, orgRepo, customersClient)
+
+		micro := newMicroDeposits(client.Destination{
+			CustomerID: destinationCustomerID,
+			AccountID:  destinationAccountID,
+		})
+		if err := repo.writeMicroDeposits("organization", micro); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := watcher.Process(); err != nil || n != 1 {
+			t.Fatalf("n=%d error=%v", n, err)
+		}
+		if len(events) != 1 || events[0].Organization != "organization" || events[0].PreviousState != client.VERIFICATION_UNVERIFIED {
+			t.Fatalf("unexpected events: %#v", events)
+		}
+		if events[0].Verification.State != client.VERIFICATION_MICRO_DEPOSITS_SENT {
+			t.Errorf("unexpected verification: %#v", events[0].Verification)
+		}
+		if b := events[0].Branding; b == nil || b.DisplayName != "Acme Payroll" || b.SupportEmail != "support@acme.com" || b.LogoURL != "" {
+			t.Errorf("unexpected branding: %#v", b)
+		}
+
+		// nothing changed
+		if n, err := watcher.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+
+		// the webhook fails, so the change is sent again
+		customersClient.Accounts[destinationAccountID].Status = moovcustomers.ACCOUNTSTATUS_VALIDATED
+		status = http.StatusInternalServerError
+		if n, err := watcher.Process(); err != nil || n != 0 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+		status = http.StatusOK
+		if n, err := watcher.Process(); err != nil || n != 1 {
+			t.Errorf("n=%d error=%v", n, err)
+		}
+		if len(events) != 3 || events[2].Verification.State != client.VERIFICATION_VERIFIED {
+			t.Fatalf("unexpected events: %#v", events)
+		}
+
+		// verified accounts aren't watched anymore
+		if items, err := repo.getWatchedVerifications(); err != nil || len(items) != 0 {
+			t.Errorf("items=%#v error=%v", items, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, NewInMemoryRepo())
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// DefaultMaxBodySize is used when http.maxBodySize isn't configured.
+const DefaultMaxBodySize int64 = 1 << 20 // 1MB
+
+// ErrBodyTooLarge is returned when a request body exceeds the configured limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// MaxBodySize returns middleware which stops reading request bodies after the
+// configured number of bytes.
+func MaxBodySize(cfg *config.Config) mux.MiddlewareFunc {
+	limit := cfg.Http.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidationError lists every invalid field of a request so clients can fix
+// them all at once.
+type ValidationError struct {
+	Fields []client.FieldError
+}
+
+// Add records field as invalid.
+func (e *ValidationError) Add(field string, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, client.FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Err returns e if any fields were added and nil otherwise.
+func (e *ValidationError) Err() error {
+	if e == nil || len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	var buf strings.Builder
+	for i := range e.Fields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%s: %s", e.Fields[i].Field, e.Fields[i].Message)
+	}
+	return buf.String()
+}
+
+// DecodeOption changes how DecodeJSON reads a request body.
+type DecodeOption func(*json.Decoder)
+
+// DisallowUnknownFields rejects bodies with fields that aren't in the target struct.
+func DisallowUnknownFields(dec *json.Decoder) {
+	dec.DisallowUnknownFields()
+}
+
+// DecodeJSON reads a JSON object from the request body into v. Malformed values are
+// returned as a *ValidationError and oversized bodies as ErrBodyTooLarge.
+func DecodeJSON(r *http.Request, v interface{}, opts ...DecodeOption) error {
+	if r.Body == nil {
+		return errors.New("missing request body")
+	}
+	dec := json.NewDecoder(r.Body)
+	for i := range opts {
+		opts[i](dec)
+	}
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+
+	verr := &ValidationError{}
+	switch {
+	case errors.As(err, &typeErr):
+		verr.Add(typeErr.Field, "expected %s but found %s", typeErr.Type, typeErr.Value)
+	case errors.As(err, &syntaxErr):
+		verr.Add("", "invalid JSON at offset %d: %v", syntaxErr.Offset, err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		verr.Add(strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`), "unknown field")
+	case strings.Contains(err.Error(), "request body too large"):
+		// http.MaxBytesReader doesn't return a typed error
+		return ErrBodyTooLarge
+	case err == io.EOF:
+		return errors.New("missing request body")
+	default:
+		return err
+	}
+	return verr
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+type bodyTestRequest struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var body bodyTestRequest
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "foo", "count": 2}`))
+	if err := DecodeJSON(req, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Name != "foo" || body.Count != 2 {
+		t.Errorf("unexpected body: %#v", body)
+	}
+
+	// unknown fields are allowed by default
+	req = httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "foo", "other": true}`))
+	if err := DecodeJSON(req, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "foo", "other": true}`))
+	err := DecodeJSON(req, &body, DisallowUnknownFields)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "other" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(`{"count": "two"}`))
+	err = DecodeJSON(req, &body)
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "count" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(`{"name": `))
+	if err := DecodeJSON(req, &body); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	verr := &ValidationError{}
+	if err := verr.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	verr.Add("source.customerID", "missing")
+	verr.Add("amount", "invalid amount: %d", 0)
+	if err := verr.Err(); err == nil {
+		t.Fatal("expected error")
+	}
+	if msg := verr.Error(); msg != "source.customerID: missing, amount: invalid amount: 0" {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Http.MaxBodySize = 16
+
+	router := mux.NewRouter()
+	router.Use(MaxBodySize(cfg))
+	router.Methods("POST").Path("/test").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responder := NewResponder(cfg, w, r)
+
+		var body bodyTestRequest
+		if err := DecodeJSON(r, &body, DisallowUnknownFields); err != nil {
+			responder.Problem(err)
+			return
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/test", strings.NewReader(body)))
+		w.Flush()
+		return w
+	}
+
+	if w := send(`{"name": "foo"}`); w.Code != http.StatusOK {
+		t.Errorf("got %d", w.Code)
+	}
+	if w := send(`{"name": "a much longer name"}`); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %d", w.Code)
+	}
+
+	w := send(`{"extra": 1}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got %d", w.Code)
+	}
+	var resp client.Error
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != "validation_failed" || resp.Field != "extra" {
+		t.Errorf("unexpected fields: %#v", resp.Fields)
+	}
+}
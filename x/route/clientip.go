@@ -0,0 +1,43 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+// ClientIP returns the address a request was sent from. X-Forwarded-For is only read when
+// the request came through one of http.trustedProxies, in which case the closest address
+// which isn't a trusted proxy is returned. Addresses added by clients are never trusted.
+func ClientIP(cfg config.HTTP, r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !cfg.TrustedProxy(ip) {
+		return ip
+	}
+
+	var forwarded []string
+	for _, v := range r.Header["X-Forwarded-For"] {
+		forwarded = append(forwarded, strings.Split(v, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if addr == nil {
+			break
+		}
+		ip = addr
+		if !cfg.TrustedProxy(ip) {
+			break
+		}
+	}
+	return ip
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/transfers", nil)
+	req.RemoteAddr = "10.0.0.5:41234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+
+	// forwarded addresses aren't read without trusted proxies
+	cfg := config.HTTP{}
+	if ip := ClientIP(cfg, req); ip.String() != "10.0.0.5" {
+		t.Errorf("unexpected IP: %v", ip)
+	}
+
+	// the closest untrusted address is used, not what the client added
+	cfg.TrustedProxies = []string{"10.0.0.0/8"}
+	if ip := ClientIP(cfg, req); ip.String() != "198.51.100.7" {
+		t.Errorf("unexpected IP: %v", ip)
+	}
+
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "198.51.100.0/24"}
+	if ip := ClientIP(cfg, req); ip.String() != "203.0.113.9" {
+		t.Errorf("unexpected IP: %v", ip)
+	}
+
+	// requests from untrusted addresses keep their own address
+	req.RemoteAddr = "192.0.2.1:41234"
+	if ip := ClientIP(cfg, req); ip.String() != "192.0.2.1" {
+		t.Errorf("unexpected IP: %v", ip)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// ErrorCode is a machine-readable code returned in client.Error along with
+// the HTTP status it's returned with. Most codes keep the 400 Bad Request that
+// PayGate has always returned so existing clients aren't broken.
+type ErrorCode struct {
+	Code      string
+	Status    int
+	Retriable bool
+}
+
+var (
+	InvalidRequest      = ErrorCode{Code: "invalid_request", Status: http.StatusBadRequest}
+	ValidationFailed    = ErrorCode{Code: "validation_failed", Status: http.StatusBadRequest}
+	MethodNotAllowed    = ErrorCode{Code: "method_not_allowed", Status: http.StatusBadRequest}
+	MissingOrganization = ErrorCode{Code: "missing_organization", Status: http.StatusBadRequest}
+	NotFound            = ErrorCode{Code: "not_found", Status: http.StatusBadRequest}
+	Disabled            = ErrorCode{Code: "disabled", Status: http.StatusBadRequest}
+	BodyTooLarge        = ErrorCode{Code: "body_too_large", Status: http.StatusRequestEntityTooLarge}
+	RateLimited         = ErrorCode{Code: "rate_limited", Status: http.StatusTooManyRequests, Retriable: true}
+	Conflict            = ErrorCode{Code: "conflict", Status: http.StatusConflict}
+	Forbidden           = ErrorCode{Code: "forbidden", Status: http.StatusForbidden}
+	Unauthorized        = ErrorCode{Code: "unauthorized", Status: http.StatusUnauthorized}
+	Internal            = ErrorCode{Code: "internal_error", Status: http.StatusBadRequest, Retriable: true}
+
+	// Unavailable is used when a dependency (such as the Customers service) fails.
+	Unavailable = ErrorCode{Code: "unavailable", Status: http.StatusBadRequest, Retriable: true}
+)
+
+// Wrap attaches the code to err. A nil err returns nil.
+func (c ErrorCode) Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: c, err: err}
+}
+
+// New returns an error with the code and a formatted message.
+func (c ErrorCode) New(format string, args ...interface{}) error {
+	return c.Wrap(fmt.Errorf(format, args...))
+}
+
+type codedError struct {
+	code ErrorCode
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// ErrorCodeOf returns the code err is returned with. Errors without a code are
+// treated as InvalidRequest.
+func ErrorCodeOf(err error) ErrorCode {
+	var coded *codedError
+	var verr *ValidationError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.As(err, &verr):
+		return ValidationFailed
+	case errors.Is(err, ErrBodyTooLarge):
+		return BodyTooLarge
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFound
+	}
+	return InvalidRequest
+}
+
+// Problem writes err to w as a client.Error with its code and HTTP status.
+func Problem(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+	code := ErrorCodeOf(err)
+
+	resp := client.Error{
+		Error:     err.Error(),
+		Code:      code.Code,
+		Message:   err.Error(),
+		Retriable: code.Retriable,
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		resp.Fields = verr.Fields
+		if len(verr.Fields) == 1 {
+			resp.Field = verr.Fields[0].Field
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code.Status)
+	json.NewEncoder(w).Encode(resp)
+}
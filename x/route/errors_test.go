@@ -0,0 +1,74 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	verr := &ValidationError{}
+	verr.Add("description", "missing")
+
+	cases := map[error]ErrorCode{
+		errors.New("bad"):            InvalidRequest,
+		Internal.New("write failed"): Internal,
+		fmt.Errorf("creating transfer: %w", Unavailable.Wrap(errors.New("timeout"))): Unavailable,
+		fmt.Errorf("invalid request: %w", verr):                                      ValidationFailed,
+		ErrBodyTooLarge:                                                              BodyTooLarge,
+		sql.ErrNoRows:                                                                NotFound,
+	}
+	for err, expected := range cases {
+		if code := ErrorCodeOf(err); code != expected {
+			t.Errorf("%v: got %s expected %s", err, code.Code, expected.Code)
+		}
+	}
+
+	if err := Internal.Wrap(nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	Problem(w, Unavailable.New("customers: timeout"))
+	w.Flush()
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got %d", w.Code)
+	}
+	var resp client.Error
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != "unavailable" || !resp.Retriable || resp.Message != "customers: timeout" || resp.Error != resp.Message {
+		t.Errorf("unexpected error: %#v", resp)
+	}
+
+	// validation errors list every field
+	verr := &ValidationError{}
+	verr.Add("source.customerID", "missing")
+	verr.Add("amount", "invalid amount: 0")
+
+	w = httptest.NewRecorder()
+	Problem(w, verr)
+	w.Flush()
+
+	resp = client.Error{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != "validation_failed" || resp.Retriable || len(resp.Fields) != 2 || resp.Field != "" {
+		t.Errorf("unexpected error: %#v", resp)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/text/language"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// Languages are the languages error messages can be returned in, the first is the default.
+var Languages = []language.Tag{language.English, language.Spanish}
+
+var languageMatcher = language.NewMatcher(Languages)
+
+// Catalog holds the message of each error code by language, such as Catalog["es"]["not_found"].
+type Catalog map[string]map[string]string
+
+// DefaultCatalog translates the message of errors into each language besides English, where
+// errors keep their detailed message.
+var DefaultCatalog = Catalog{
+	"es": {
+		InvalidRequest.Code:      "La solicitud no es válida",
+		ValidationFailed.Code:    "Uno o más campos de la solicitud no son válidos",
+		MethodNotAllowed.Code:    "Método no permitido",
+		MissingOrganization.Code: "Falta la organización",
+		NotFound.Code:            "No se encontró el recurso",
+		Disabled.Code:            "Esta función está deshabilitada",
+		BodyTooLarge.Code:        "El cuerpo de la solicitud es demasiado grande",
+		RateLimited.Code:         "Demasiadas solicitudes, inténtelo de nuevo más tarde",
+		Conflict.Code:            "El recurso fue modificado por otra solicitud",
+		Forbidden.Code:           "No tiene permiso para realizar esta acción",
+		Unauthorized.Code:        "No autorizado",
+		Internal.Code:            "Ocurrió un error interno, inténtelo de nuevo",
+		Unavailable.Code:         "Un servicio necesario no está disponible, inténtelo de nuevo",
+	},
+}
+
+// ErrorCodes are the codes a Catalog can have messages for.
+var ErrorCodes = []ErrorCode{
+	InvalidRequest, ValidationFailed, MethodNotAllowed, MissingOrganization, NotFound, Disabled,
+	BodyTooLarge, RateLimited, Conflict, Forbidden, Unauthorized, Internal, Unavailable,
+}
+
+// message returns the message of code in lang, preferring overrides over c.
+func (c Catalog) message(overrides Catalog, lang, code string) string {
+	if msg := overrides[lang][code]; msg != "" {
+		return msg
+	}
+	return c[lang][code]
+}
+
+// NegotiateLanguage returns the base language, such as "es", of Languages which best matches
+// the Accept-Language header of r.
+func NegotiateLanguage(r *http.Request) string {
+	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	tag, _, _ := languageMatcher.Match(tags...)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// Localize returns middleware which replaces the message of errors with their message from
+// catalog in the language of the request's Accept-Language header. The error field is left
+// as it is for logs and clients which match on it. overrides, when set, returns the catalog
+// of the request's organization and is only called for errors.
+func Localize(catalog Catalog, overrides func(r *http.Request) Catalog) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := NegotiateLanguage(r)
+			lw := &localizedWriter{ResponseWriter: w}
+			next.ServeHTTP(lw, r)
+			if !lw.buffered {
+				return
+			}
+			var orgCatalog Catalog
+			if overrides != nil {
+				orgCatalog = overrides(r)
+			}
+			lw.translate(catalog, orgCatalog, lang)
+		})
+	}
+}
+
+// localizedWriter holds back the body of error responses so their message can be translated.
+type localizedWriter struct {
+	http.ResponseWriter
+
+	status   int
+	buffered bool
+	body     bytes.Buffer
+}
+
+func (w *localizedWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status >= 400 {
+		w.buffered = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *localizedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffered {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *localizedWriter) Flush() {
+	if w.buffered {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// translate writes the held back error with its message in lang. Bodies which aren't a
+// client.Error with a code are written unchanged.
+func (w *localizedWriter) translate(catalog, overrides Catalog, lang string) {
+	body := w.body.Bytes()
+
+	var problem client.Error
+	if err := json.Unmarshal(body, &problem); err == nil && problem.Code != "" {
+		if msg := catalog.message(overrides, lang, problem.Code); msg != "" {
+			problem.Message = msg
+
+			var buf bytes.Buffer
+			if err := json.NewEncoder(&buf).Encode(problem); err == nil {
+				body = buf.Bytes()
+				w.Header().Set("Content-Language", lang)
+			}
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                      "en",
+		"es":                    "es",
+		"es-MX,es;q=0.9":        "es",
+		"fr-CA,en;q=0.8":        "en",
+		"de":                    "en",
+		"en-US,es;q=0.5":        "en",
+		"fr;q=0.9,es-419;q=0.8": "es",
+	}
+	for header, expected := range cases {
+		req := httptest.NewRequest("GET", "/transfers", nil)
+		req.Header.Set("Accept-Language", header)
+		if lang := NegotiateLanguage(req); lang != expected {
+			t.Errorf("%q: got %s expected %s", header, lang, expected)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	handler := Localize(DefaultCatalog, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Problem(w, NotFound.New("transfer not found"))
+	}))
+
+	// Spanish
+	req := httptest.NewRequest("GET", "/transfers/foo", nil)
+	req.Header.Set("Accept-Language", "es-MX")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := readProblem(t, w)
+	if resp.Message != DefaultCatalog["es"][NotFound.Code] {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+	if resp.Error != "transfer not found" || resp.Code != NotFound.Code {
+		t.Errorf("unexpected error: %#v", resp)
+	}
+	if lang := w.Header().Get("Content-Language"); lang != "es" {
+		t.Errorf("Content-Language=%q", lang)
+	}
+
+	// English keeps the detailed message
+	req = httptest.NewRequest("GET", "/transfers/foo", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp = readProblem(t, w)
+	if resp.Message != "transfer not found" {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+	if lang := w.Header().Get("Content-Language"); lang != "" {
+		t.Errorf("Content-Language=%q", lang)
+	}
+}
+
+func TestLocalize__overrides(t *testing.T) {
+	overrides := func(r *http.Request) Catalog {
+		return Catalog{
+			"en": {NotFound.Code: "We couldn't find that"},
+		}
+	}
+	handler := Localize(DefaultCatalog, overrides)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Problem(w, NotFound.New("transfer not found"))
+	}))
+
+	req := httptest.NewRequest("GET", "/transfers/foo", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := readProblem(t, w)
+	if resp.Message != "We couldn't find that" {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+
+	// Spanish falls back to DefaultCatalog
+	req.Header.Set("Accept-Language", "es")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp = readProblem(t, w)
+	if resp.Message != DefaultCatalog["es"][NotFound.Code] {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+}
+
+func TestLocalize__passthrough(t *testing.T) {
+	handler := Localize(DefaultCatalog, func(r *http.Request) Catalog {
+		t.Fatal("unexpected overrides lookup")
+		return nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message":"hello"}`))
+	}))
+
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set("Accept-Language", "es")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"message":"hello"}` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func readProblem(t *testing.T, w *httptest.ResponseRecorder) client.Error {
+	t.Helper()
+
+	var resp client.Error
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"context"
+	"net/http"
+)
+
+type impersonatorKey struct{}
+
+// WithImpersonator marks requests made from the admin server on behalf of an organization.
+// The admin user is kept so handlers can log who made the request.
+func WithImpersonator(ctx context.Context, adminUserID string) context.Context {
+	return context.WithValue(ctx, impersonatorKey{}, adminUserID)
+}
+
+// Impersonator returns the admin user who made r on behalf of an organization, or an empty
+// string for requests made by the organization itself. Middleware which guards the public
+// API skips impersonated requests as they were authorized on the admin server.
+func Impersonator(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	userID, _ := r.Context().Value(impersonatorKey{}).(string)
+	return userID
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	moovhttp "github.com/moov-io/base/http"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+var (
+	rateLimitedRequests = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "http_rate_limited_requests",
+		Help: "Counter of HTTP requests rejected by rate limiting",
+	}, []string{"key"})
+
+	// idleLimiterTimeout is how long a limiter is kept after its last request
+	idleLimiterTimeout = 10 * time.Minute
+)
+
+// RateLimit returns middleware which rejects requests with 429 Too Many Requests once
+// an organization or user exceeds their configured limits.
+func RateLimit(cfg *config.Config) mux.MiddlewareFunc {
+	limits := cfg.Http.RateLimit
+	if limits == nil || (limits.Organization == nil && limits.User == nil) {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	orgs := newKeyedLimiter(limits.Organization)
+	users := newKeyedLimiter(limits.User)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if Impersonator(r) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			now := time.Now()
+			orgID, userID := FindOrganization(cfg.Organization, r), moovhttp.GetUserID(r)
+
+			orgReservation := orgs.reserve(orgID, now)
+			userReservation := users.reserve(userID, now)
+
+			key, delay := "organization", orgReservation.delay(now)
+			if d := userReservation.delay(now); d > delay {
+				key, delay = "user", d
+			}
+			if delay <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Give back the tokens as this request isn't served
+			orgReservation.cancel(now)
+			userReservation.cancel(now)
+
+			rateLimitedRequests.With("key", key).Add(1)
+			cfg.Logger.With(config.Debug).With(log.Fields{
+				"organization": log.String(orgID),
+				"userID":       log.String(userID),
+			}).Logf("rate limited %s %s by %s", r.Method, r.URL.Path, key)
+
+			tooManyRequests(w, key, delay)
+		})
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, key string, delay time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(delay.Seconds()))))
+	Problem(w, RateLimited.New("%s rate limit exceeded", key))
+}
+
+// keyedLimiter holds a token bucket for each key. Buckets are removed once they've
+// been idle for idleLimiterTimeout.
+type keyedLimiter struct {
+	limit *config.Limit
+
+	mu        sync.Mutex
+	limiters  map[string]*idleLimiter
+	lastSweep time.Time
+}
+
+type idleLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+func newKeyedLimiter(limit *config.Limit) *keyedLimiter {
+	return &keyedLimiter{
+		limit:    limit,
+		limiters: make(map[string]*idleLimiter),
+	}
+}
+
+type reservation struct {
+	*rate.Reservation
+}
+
+// reserve takes a token for key. Requests without a key or limit are never limited.
+func (l *keyedLimiter) reserve(key string, now time.Time) reservation {
+	if l.limit == nil || key == "" {
+		return reservation{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, lim := range l.limiters {
+			if now.Sub(lim.lastSeen) > idleLimiterTimeout {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	lim, exists := l.limiters[key]
+	if !exists {
+		lim = &idleLimiter{
+			Limiter: rate.NewLimiter(rate.Limit(l.limit.RequestsPerSecond), l.limit.Burst),
+		}
+		l.limiters[key] = lim
+	}
+	lim.lastSeen = now
+
+	return reservation{lim.ReserveN(now, 1)}
+}
+
+func (r reservation) delay(now time.Time) time.Duration {
+	if r.Reservation == nil {
+		return 0
+	}
+	if !r.OK() {
+		return time.Second
+	}
+	return r.DelayFrom(now)
+}
+
+func (r reservation) cancel(now time.Time) {
+	if r.Reservation != nil {
+		r.CancelAt(now)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func rateLimitedRouter(cfg *config.Config) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(RateLimit(cfg))
+	router.Methods("GET").Path("/test").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return router
+}
+
+func rateLimitedRequest(router *mux.Router, orgID, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Organization", orgID)
+	req.Header.Set("X-User-ID", userID)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	return w
+}
+
+func TestRateLimit__Organization(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Http.RateLimit = &config.RateLimit{
+		Organization: &config.Limit{RequestsPerSecond: 0.1, Burst: 2},
+	}
+	router := rateLimitedRouter(cfg)
+
+	for i := 0; i < 2; i++ {
+		if w := rateLimitedRequest(router, "foo", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d", i, w.Code)
+		}
+	}
+
+	w := rateLimitedRequest(router, "foo", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("got %d", w.Code)
+	}
+	if v := w.Header().Get("Retry-After"); v != "10" {
+		t.Errorf("Retry-After: %q", v)
+	}
+
+	// other organizations have their own limit
+	if w := rateLimitedRequest(router, "bar", ""); w.Code != http.StatusOK {
+		t.Errorf("got %d", w.Code)
+	}
+}
+
+func TestRateLimit__User(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Http.RateLimit = &config.RateLimit{
+		Organization: &config.Limit{RequestsPerSecond: 100, Burst: 100},
+		User:         &config.Limit{RequestsPerSecond: 1, Burst: 1},
+	}
+	router := rateLimitedRouter(cfg)
+
+	if w := rateLimitedRequest(router, "foo", "alice"); w.Code != http.StatusOK {
+		t.Errorf("got %d", w.Code)
+	}
+	if w := rateLimitedRequest(router, "foo", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("got %d", w.Code)
+	}
+	if w := rateLimitedRequest(router, "foo", "bob"); w.Code != http.StatusOK {
+		t.Errorf("got %d", w.Code)
+	}
+}
+
+func TestRateLimit__Disabled(t *testing.T) {
+	router := rateLimitedRouter(config.Empty())
+	for i := 0; i < 10; i++ {
+		if w := rateLimitedRequest(router, "foo", "alice"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: got %d", i, w.Code)
+		}
+	}
+}
+
+func TestKeyedLimiter__sweep(t *testing.T) {
+	limiter := newKeyedLimiter(&config.Limit{RequestsPerSecond: 1, Burst: 1})
+	now := time.Now()
+
+	limiter.reserve("foo", now)
+	limiter.reserve("bar", now.Add(2*time.Minute))
+	if n := len(limiter.limiters); n != 2 {
+		t.Errorf("got %d limiters", n)
+	}
+
+	limiter.reserve("bar", now.Add(idleLimiterTimeout+2*time.Minute))
+	if _, exists := limiter.limiters["foo"]; exists {
+		t.Error("expected idle limiter to be removed")
+	}
+}
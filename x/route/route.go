@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/moov-io/base"
 	moovhttp "github.com/moov-io/base/http"
 	"github.com/moov-io/base/idempotent"
 	"github.com/moov-io/base/idempotent/lru"
@@ -47,13 +48,14 @@ type Responder struct {
 
 func NewResponder(cfg *config.Config, w http.ResponseWriter, r *http.Request) *Responder {
 	resp := &Responder{
-		OrganizationID: findOrg(cfg.Organization, r),
-		XRequestID:     moovhttp.GetRequestID(r),
-		logger:         cfg.Logger,
+		OrganizationID: FindOrganization(cfg.Organization, r),
+		XRequestID:     util.Or(moovhttp.GetRequestID(r), base.ID()),
 		request:        r,
 	}
+	resp.logger = requestLogger(cfg.Logger, resp, r)
 	resp.setSpan()
-	writer, err := wrapResponseWriter(cfg.Logger, w, r)
+	w.Header().Set("X-Request-ID", resp.XRequestID)
+	writer, err := wrapResponseWriter(resp.logger, w, r)
 	resp.writer = writer
 	if err != nil {
 		resp.Problem(err)
@@ -61,7 +63,30 @@ func NewResponder(cfg *config.Config, w http.ResponseWriter, r *http.Request) *R
 	return resp
 }
 
-func findOrg(cfg config.Organization, r *http.Request) string {
+// requestLogger returns a logger with the request ID, organization and user of a request.
+func requestLogger(logger log.Logger, resp *Responder, r *http.Request) log.Logger {
+	fields := log.Fields{
+		"requestID":    log.String(resp.XRequestID),
+		"organization": log.String(resp.OrganizationID),
+	}
+	if userID := moovhttp.GetUserID(r); userID != "" {
+		fields["userID"] = log.String(userID)
+	}
+	if impersonator := Impersonator(r); impersonator != "" {
+		fields["impersonator"] = log.String(impersonator)
+	}
+	return logger.With(fields)
+}
+
+// Logger returns a logger for the current request. Handlers should add entity IDs
+// (such as transferID) to it instead of modifying the shared config logger.
+func (r *Responder) Logger() log.Logger {
+	return r.logger
+}
+
+// FindOrganization returns the organization a request is made for, read from the configured
+// header or the default organization.
+func FindOrganization(cfg config.Organization, r *http.Request) string {
 	discovered := r.Header.Get(util.Or(cfg.Header, "X-Organization"))
 	return util.Or(discovered, cfg.Default)
 }
@@ -81,8 +106,7 @@ func (r *Responder) Problem(err error) {
 		return
 	}
 	r.finishSpan()
-	r.writer.Header().Set("Content-Type", "application/json; charset=utf-8")
-	moovhttp.Problem(r.writer, err)
+	Problem(r.writer, err)
 }
 
 func wrapResponseWriter(logger log.Logger, w http.ResponseWriter, r *http.Request) (*moovhttp.ResponseWriter, error) {
@@ -25,7 +25,7 @@ func TestOrganization(t *testing.T) {
 	req.Header.Set("X-Organization", "foo")
 
 	cfg := config.Empty()
-	orgID := findOrg(cfg.Organization, req)
+	orgID := FindOrganization(cfg.Organization, req)
 	if orgID != "foo" {
 		t.Errorf("got %q", orgID)
 	}
@@ -33,7 +33,7 @@ func TestOrganization(t *testing.T) {
 	// blank out
 	cfg.Organization.Default = "bar"
 	req.Header.Set("X-Organization", "")
-	orgID = findOrg(cfg.Organization, req)
+	orgID = FindOrganization(cfg.Organization, req)
 	if orgID != "bar" {
 		t.Errorf("got %q", orgID)
 	}
@@ -64,6 +64,40 @@ func TestRoute(t *testing.T) {
 	}
 }
 
+func TestRoute__RequestID(t *testing.T) {
+	cfg := config.Empty()
+
+	router := mux.NewRouter()
+	router.Methods("GET").Path("/test").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responder := NewResponder(cfg, w, r)
+		responder.Logger().Log("test: response")
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+
+	// generated when missing
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	if id := w.Header().Get("X-Request-ID"); id == "" {
+		t.Error("expected generated X-Request-ID")
+	}
+
+	// echo the caller's ID
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "foo")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+
+	if id := w.Header().Get("X-Request-ID"); id != "foo" {
+		t.Errorf("X-Request-ID=%q", id)
+	}
+}
+
 func TestRoute__problem(t *testing.T) {
 	cfg := config.Empty()
 
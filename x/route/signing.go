@@ -0,0 +1,180 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/moov-io/base/log"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+const (
+	// SignatureHeader holds the hex encoded HMAC-SHA256 signature of a request.
+	SignatureHeader = "X-Signature"
+
+	// SignatureTimestampHeader holds the unix time (in seconds) a request was signed at.
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+var (
+	signatureRejections = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Name: "http_signature_rejections",
+		Help: "Counter of HTTP requests rejected for a missing, invalid or replayed signature",
+	}, []string{"reason"})
+)
+
+// Sign returns the signature of a request. The signed payload is the timestamp, method,
+// path with query and body of the request each separated by a newline.
+func Sign(secret string, timestamp string, method string, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{timestamp, method, uri, ""}, "\n")))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureStore remembers the signatures of accepted requests until they expire.
+// database.SignatureStore shares them across every PayGate instance.
+type SignatureStore interface {
+	// Add records signature for orgID until expires. It returns false if signature was already recorded.
+	Add(orgID, signature string, expires time.Time) (bool, error)
+}
+
+// RequestSigning returns middleware which rejects requests with 401 Unauthorized from
+// organizations listed in http.signing unless they carry a valid signature. Signatures are
+// only accepted once so captured requests can't be replayed. They're kept in store, or in
+// memory for a single instance when store is nil.
+func RequestSigning(cfg *config.Config, store SignatureStore) mux.MiddlewareFunc {
+	signing := cfg.Http.Signing
+	if signing == nil || len(signing.Organizations) == 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	tolerance := signing.Window()
+	if store == nil {
+		store = newSignatureCache()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			orgID := FindOrganization(cfg.Organization, r)
+			secrets := signing.Secrets(orgID)
+			if len(secrets) == 0 || Impersonator(r) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reject := func(reason string, err error) {
+				signatureRejections.With("reason", reason).Add(1)
+				cfg.Logger.With(config.Debug).With(log.Fields{
+					"organization": log.String(orgID),
+				}).Logf("rejected %s %s: %v", r.Method, r.URL.Path, err)
+				Problem(w, err)
+			}
+
+			timestamp, signature := r.Header.Get(SignatureTimestampHeader), r.Header.Get(SignatureHeader)
+			if timestamp == "" || signature == "" {
+				reject("missing", Unauthorized.New("missing %s or %s header", SignatureHeader, SignatureTimestampHeader))
+				return
+			}
+			sec, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				reject("timestamp", Unauthorized.New("invalid %s header", SignatureTimestampHeader))
+				return
+			}
+			signedAt := time.Unix(sec, 0)
+			if diff := time.Since(signedAt); diff > tolerance || diff < -tolerance {
+				reject("timestamp", Unauthorized.New("%s is outside of %v", SignatureTimestampHeader, tolerance))
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				body, err = ioutil.ReadAll(r.Body)
+				if err != nil {
+					Problem(w, decodeError(err))
+					return
+				}
+				r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+
+			valid := false
+			for i := range secrets {
+				expected := Sign(secrets[i], timestamp, r.Method, r.URL.RequestURI(), body)
+				if hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				reject("signature", Unauthorized.New("invalid %s", SignatureHeader))
+				return
+			}
+			// signatures are remembered for as long as their timestamp is accepted
+			added, err := store.Add(orgID, strings.ToLower(signature), signedAt.Add(tolerance))
+			if err != nil {
+				cfg.Logger.LogErrorf("ERROR recording signature of %s: %v", orgID, err)
+				Problem(w, Internal.New("problem recording %s", SignatureHeader))
+				return
+			}
+			if !added {
+				reject("replay", Unauthorized.New("%s was already used", SignatureHeader))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// signatureCache remembers signatures in memory until they expire, so a request could be
+// replayed once against each PayGate instance.
+type signatureCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newSignatureCache() *signatureCache {
+	return &signatureCache{
+		seen: make(map[string]time.Time),
+	}
+}
+
+func (c *signatureCache) Add(orgID, signature string, expires time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	key := orgID + "/" + signature
+	if exp, exists := c.seen[key]; exists && !now.After(exp) {
+		return false, nil
+	}
+	c.seen[key] = expires
+	return true, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+var (
+	oldSecret = strings.Repeat("a", 32)
+	newSecret = strings.Repeat("b", 32)
+)
+
+func signedRouter(t *testing.T, store SignatureStore) *mux.Router {
+	cfg := config.Empty()
+	cfg.Http.Signing = &config.RequestSigning{
+		Tolerance: time.Minute,
+		Organizations: []config.SigningOrganization{
+			{Organization: "foo", Secrets: []string{oldSecret, newSecret}},
+		},
+	}
+
+	router := mux.NewRouter()
+	router.Use(RequestSigning(cfg, store))
+	router.Methods("POST").Path("/test").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// handlers can still read the body
+		bs, _ := ioutil.ReadAll(r.Body)
+		if string(bs) != `{"amount":"USD 1.00"}` {
+			t.Errorf("unexpected body: %q", string(bs))
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return router
+}
+
+func signedRequest(router *mux.Router, orgID, secret string, signedAt time.Time) (*http.Request, *httptest.ResponseRecorder) {
+	body := `{"amount":"USD 1.00"}`
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+
+	req := httptest.NewRequest("POST", "/test?foo=bar", strings.NewReader(body))
+	req.Header.Set("X-Organization", orgID)
+	if secret != "" {
+		req.Header.Set(SignatureTimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(secret, timestamp, "POST", "/test?foo=bar", []byte(body)))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	w.Flush()
+	return req, w
+}
+
+func TestRequestSigning(t *testing.T) {
+	router := signedRouter(t, nil)
+	now := time.Now()
+
+	if _, w := signedRequest(router, "foo", oldSecret, now); w.Code != http.StatusOK {
+		t.Errorf("got %d: %s", w.Code, w.Body.String())
+	}
+	if _, w := signedRequest(router, "foo", newSecret, now); w.Code != http.StatusOK {
+		t.Errorf("got %d: %s", w.Code, w.Body.String())
+	}
+
+	// organizations without secrets aren't checked
+	if _, w := signedRequest(router, "bar", "", now); w.Code != http.StatusOK {
+		t.Errorf("got %d: %s", w.Code, w.Body.String())
+	}
+
+	// nor are requests made from the admin server
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(`{"amount":"USD 1.00"}`))
+	req.Header.Set("X-Organization", "foo")
+	req = req.WithContext(WithImpersonator(req.Context(), "jane"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequestSigning__Rejected(t *testing.T) {
+	router := signedRouter(t, nil)
+	now := time.Now()
+
+	if _, w := signedRequest(router, "foo", "", now); w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned: got %d", w.Code)
+	}
+	if _, w := signedRequest(router, "foo", strings.Repeat("c", 32), now); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: got %d", w.Code)
+	}
+	if _, w := signedRequest(router, "foo", oldSecret, now.Add(-2*time.Minute)); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired: got %d", w.Code)
+	}
+	if _, w := signedRequest(router, "foo", oldSecret, now.Add(2*time.Minute)); w.Code != http.StatusUnauthorized {
+		t.Errorf("future: got %d", w.Code)
+	}
+
+	// replay a signed request
+	req, w := signedRequest(router, "foo", oldSecret, now)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+	replay := httptest.NewRequest("POST", "/test?foo=bar", strings.NewReader(`{"amount":"USD 1.00"}`))
+	replay.Header = req.Header
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, replay)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("replay: got %d", w.Code)
+	}
+}
+
+func TestRequestSigning__SharedStore(t *testing.T) {
+	store := newSignatureCache()
+	first, second := signedRouter(t, store), signedRouter(t, store)
+
+	// a request accepted by one instance can't be replayed against another
+	req, w := signedRequest(first, "foo", oldSecret, time.Now())
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body.String())
+	}
+	replay := httptest.NewRequest("POST", "/test?foo=bar", strings.NewReader(`{"amount":"USD 1.00"}`))
+	replay.Header = req.Header
+	w = httptest.NewRecorder()
+	second.ServeHTTP(w, replay)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("replay: got %d", w.Code)
+	}
+}
+
+func TestSignatureCache(t *testing.T) {
+	cache := newSignatureCache()
+
+	expires := time.Now().Add(time.Minute)
+	if added, _ := cache.Add("foo", "abc123", expires); !added {
+		t.Error("expected abc123 to be added")
+	}
+	if added, _ := cache.Add("foo", "abc123", expires); added {
+		t.Error("expected abc123 to be seen")
+	}
+	if added, _ := cache.Add("bar", "abc123", expires); !added {
+		t.Error("expected abc123 to be added for bar")
+	}
+
+	// expired signatures are swept
+	if added, _ := cache.Add("foo", "def456", time.Now().Add(-1*time.Second)); !added {
+		t.Error("expected def456 to be added")
+	}
+	cache.lastSweep = time.Time{}
+	if added, _ := cache.Add("foo", "def456", expires); !added {
+		t.Error("expected def456 to expire")
+	}
+	if len(cache.seen) != 3 {
+		t.Errorf("expected sweep: %v", cache.seen)
+	}
+}
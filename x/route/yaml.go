@@ -0,0 +1,108 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// IsYAML returns true if contentType, from a Content-Type or Accept header, is
+// one of the YAML media types.
+func IsYAML(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "yaml")
+}
+
+// WantsYAML returns true if the request asked for a YAML response, either with
+// ?format=yaml or its Accept header.
+func WantsYAML(r *http.Request) bool {
+	if strings.EqualFold(r.URL.Query().Get("format"), "yaml") {
+		return true
+	}
+	return IsYAML(r.Header.Get("Accept"))
+}
+
+// DecodeYAML reads a YAML document from the request body into v. Fields are matched
+// by their json names so the same models are used for JSON and YAML, and errors are
+// returned like DecodeJSON.
+func DecodeYAML(r *http.Request, v interface{}, opts ...DecodeOption) error {
+	if r.Body == nil {
+		return errors.New("missing request body")
+	}
+	bs, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return decodeError(err)
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		verr := &ValidationError{}
+		verr.Add("", "invalid YAML: %v", err)
+		return verr
+	}
+	if doc == nil {
+		return errors.New("missing request body")
+	}
+	bs, err = json.Marshal(jsonValue(doc))
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	for i := range opts {
+		opts[i](dec)
+	}
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+// jsonValue converts the maps yaml.v2 decodes into ones encoding/json accepts.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			out[fmt.Sprintf("%v", k)] = jsonValue(value)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonValue(v[i])
+		}
+		return v
+	}
+	return v
+}
+
+// WriteYAML encodes v as YAML using its json field names and writes it with status.
+// Nothing is written if v can't be encoded.
+func WriteYAML(w http.ResponseWriter, status int, v interface{}) error {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// JSON is valid YAML, decoding into a MapSlice keeps the field order
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		return err
+	}
+	if bs, err = yaml.Marshal(doc); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(bs)
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package route
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type yamlTestRequest struct {
+	Name   string            `json:"name"`
+	Nested bodyTestRequest   `json:"nested"`
+	Labels map[string]string `json:"labels"`
+}
+
+func TestDecodeYAML(t *testing.T) {
+	var body yamlTestRequest
+	req := httptest.NewRequest("PUT", "/", strings.NewReader("name: foo\nnested:\n  count: 2\nlabels:\n  1: one\n"))
+	if err := DecodeYAML(req, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Name != "foo" || body.Nested.Count != 2 || body.Labels["1"] != "one" {
+		t.Errorf("unexpected body: %#v", body)
+	}
+
+	// unknown fields
+	req = httptest.NewRequest("PUT", "/", strings.NewReader("name: foo\nother: true\n"))
+	err := DecodeYAML(req, &body, DisallowUnknownFields)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Fields[0].Field != "other" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	// wrong types
+	req = httptest.NewRequest("PUT", "/", strings.NewReader("nested:\n  count: many\n"))
+	if err := DecodeYAML(req, &body); !errors.As(err, &verr) {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	// invalid and empty documents
+	req = httptest.NewRequest("PUT", "/", strings.NewReader("name: [foo"))
+	if err := DecodeYAML(req, &body); !errors.As(err, &verr) {
+		t.Errorf("unexpected error: %#v", err)
+	}
+	req = httptest.NewRequest("PUT", "/", strings.NewReader(""))
+	if err := DecodeYAML(req, &body); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := WriteYAML(w, 200, yamlTestRequest{
+		Name:   "foo",
+		Nested: bodyTestRequest{Name: "bar", Count: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); !IsYAML(ct) {
+		t.Errorf("unexpected Content-Type: %q", ct)
+	}
+	expected := "name: foo\nnested:\n  name: bar\n  count: 2\nlabels: null\n"
+	if v := w.Body.String(); v != expected {
+		t.Errorf("unexpected body:\n%s", v)
+	}
+
+	// round trip
+	var body yamlTestRequest
+	req := httptest.NewRequest("PUT", "/", strings.NewReader(w.Body.String()))
+	if err := DecodeYAML(req, &body, DisallowUnknownFields); err != nil {
+		t.Fatal(err)
+	}
+	if body.Name != "foo" || body.Nested.Name != "bar" {
+		t.Errorf("unexpected body: %#v", body)
+	}
+}
+
+func TestWantsYAML(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if WantsYAML(req) {
+		t.Error("expected JSON")
+	}
+	req.Header.Set("Accept", "application/x-yaml")
+	if !WantsYAML(req) {
+		t.Error("expected YAML")
+	}
+	if !WantsYAML(httptest.NewRequest("GET", "/?format=yaml", nil)) {
+		t.Error("expected YAML")
+	}
+}
@@ -19,13 +19,15 @@ import (
 type CutoffTimes struct {
 	C chan time.Time
 
-	sched *cron.Cron
+	sched     *cron.Cron
+	locations map[cron.EntryID]*time.Location
 }
 
 func ForCutoffTimes(tz string, timestamps []string) (*CutoffTimes, error) {
 	ct := &CutoffTimes{
-		C:     make(chan time.Time),
-		sched: cron.New(),
+		C:         make(chan time.Time),
+		sched:     cron.New(),
+		locations: make(map[cron.EntryID]*time.Location),
 	}
 	if err := ct.registerCutoffs(tz, timestamps); err != nil {
 		return nil, err
@@ -46,6 +48,32 @@ func (ct *CutoffTimes) Stop() {
 	}
 }
 
+// Next returns the earliest cutoff time which sends on C, skipping weekends and holidays.
+// The zero time.Time is returned when there are no cutoff times.
+func (ct *CutoffTimes) Next() time.Time {
+	var next time.Time
+	if ct == nil || ct.sched == nil {
+		return next
+	}
+	now := time.Now()
+	for _, entry := range ct.sched.Entries() {
+		location := ct.locations[entry.ID]
+		when := entry.Schedule.Next(now.In(location))
+		for i := 0; i < 14; i++ {
+			t := base.Now(location)
+			t.Time = when
+			if !t.IsWeekend() && t.IsBankingDay() {
+				break
+			}
+			when = entry.Schedule.Next(when)
+		}
+		if next.IsZero() || when.Before(next) {
+			next = when
+		}
+	}
+	return next
+}
+
 func (ct *CutoffTimes) maybeTick(location *time.Location) {
 	now := base.Now(location)
 	if !now.IsWeekend() && now.IsBankingDay() {
@@ -81,9 +109,12 @@ func (ct *CutoffTimes) register(tz string, timestamp string) error {
 		location = time.UTC
 	}
 	schedule := fmt.Sprintf(`%s %d %d * * *`, zone, when.Minute(), when.Hour())
-	ct.sched.AddFunc(schedule, func() {
+	id, err := ct.sched.AddFunc(schedule, func() {
 		ct.maybeTick(location)
 	})
-
+	if err != nil {
+		return err
+	}
+	ct.locations[id] = location
 	return nil
 }
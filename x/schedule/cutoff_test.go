@@ -49,3 +49,30 @@ func TestCutoffTimesErr(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestCutoffTimes__Next(t *testing.T) {
+	cutoffs, err := ForCutoffTimes("America/New_York", []string{"16:20", "09:30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cutoffs.Stop()
+
+	next := cutoffs.Next()
+	if !next.After(time.Now()) || next.After(time.Now().Add(7*24*time.Hour)) {
+		t.Errorf("unexpected next cutoff: %v", next)
+	}
+	nyc, _ := time.LoadLocation("America/New_York")
+	when := base.Now(nyc)
+	when.Time = next.In(nyc)
+	if when.IsWeekend() || !when.IsBankingDay() {
+		t.Errorf("next cutoff on %v isn't a banking day", next)
+	}
+	if hm := when.Format("15:04"); hm != "16:20" && hm != "09:30" {
+		t.Errorf("unexpected next cutoff: %v", next)
+	}
+
+	var none *CutoffTimes
+	if !none.Next().IsZero() {
+		t.Error("expected zero time")
+	}
+}